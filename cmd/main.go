@@ -1,10 +1,12 @@
 package main
 
 import (
+	"context"
 	"flag"
 	"fmt"
 	"net/http"
 	"os"
+	"path/filepath"
 	goruntime "runtime"
 	"time"
 
@@ -12,8 +14,11 @@ import (
 	metricsserver "sigs.k8s.io/controller-runtime/pkg/metrics/server"
 
 	"github.com/codeready-toolchain/host-operator/controllers/deactivation"
+	"github.com/codeready-toolchain/host-operator/controllers/featuretoggle"
 	"github.com/codeready-toolchain/host-operator/controllers/masteruserrecord"
+	"github.com/codeready-toolchain/host-operator/controllers/membercluster"
 	"github.com/codeready-toolchain/host-operator/controllers/notification"
+	"github.com/codeready-toolchain/host-operator/controllers/notificationtemplates"
 	"github.com/codeready-toolchain/host-operator/controllers/nstemplatetier"
 	"github.com/codeready-toolchain/host-operator/controllers/nstemplatetierrevisioncleanup"
 	"github.com/codeready-toolchain/host-operator/controllers/socialevent"
@@ -26,6 +31,7 @@ import (
 	"github.com/codeready-toolchain/host-operator/controllers/spacerequest"
 	"github.com/codeready-toolchain/host-operator/controllers/toolchainconfig"
 	"github.com/codeready-toolchain/host-operator/controllers/toolchainstatus"
+	"github.com/codeready-toolchain/host-operator/controllers/usererasure"
 	"github.com/codeready-toolchain/host-operator/controllers/usersignup"
 	"github.com/codeready-toolchain/host-operator/controllers/usersignupcleanup"
 	"github.com/codeready-toolchain/host-operator/deploy"
@@ -34,6 +40,7 @@ import (
 	"github.com/codeready-toolchain/host-operator/pkg/cluster"
 	"github.com/codeready-toolchain/host-operator/pkg/metrics"
 	"github.com/codeready-toolchain/host-operator/pkg/segment"
+	"github.com/codeready-toolchain/host-operator/pkg/summary"
 	"github.com/codeready-toolchain/host-operator/pkg/templates/assets"
 	"github.com/codeready-toolchain/host-operator/pkg/templates/nstemplatetiers"
 	"github.com/codeready-toolchain/host-operator/pkg/templates/usertiers"
@@ -61,6 +68,8 @@ import (
 	runtimecluster "sigs.k8s.io/controller-runtime/pkg/cluster"
 	"sigs.k8s.io/controller-runtime/pkg/healthz"
 	"sigs.k8s.io/controller-runtime/pkg/log/zap"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
 	//+kubebuilder:scaffold:imports
 )
 
@@ -97,11 +106,28 @@ func printVersion() {
 //+kubebuilder:rbac:groups=monitoring.coreos.com,resources=servicemonitors,verbs=get;list;watch;update;patch;create;delete
 
 func main() { // nolint:gocyclo
+	if len(os.Args) > 1 && os.Args[1] == renderNotificationCommand {
+		os.Exit(renderNotification(os.Args[2:], os.Stdout, os.Stderr))
+	}
+
 	var metricsAddr string
 	var enableLeaderElection bool
 	var probeAddr string
+	var deliveryEventsAddr string
+	var summaryAddr string
+	var summaryCertDir string
+	var enableConfigWebhook bool
 	flag.StringVar(&metricsAddr, "metrics-bind-address", ":8080", "The address the metric endpoint binds to.")
 	flag.StringVar(&probeAddr, "health-probe-bind-address", ":8081", "The address the probe endpoint binds to.")
+	flag.StringVar(&deliveryEventsAddr, "notification-events-bind-address", "0",
+		"The address the endpoint receiving the notification delivery events binds to. Use \"0\" to disable it.")
+	flag.StringVar(&summaryAddr, "summary-bind-address", "0",
+		"The address the endpoint serving the JSON summary of the toolchain binds to. Use \"0\" to disable it.")
+	flag.StringVar(&summaryCertDir, "summary-cert-dir", filepath.Join(os.TempDir(), "k8s-summary-server", "serving-certs"),
+		"The directory which contains the serving certificate (tls.crt) and key (tls.key) of the summary endpoint.")
+	flag.BoolVar(&enableConfigWebhook, "toolchainconfig-webhook", false,
+		"Serve the admission webhook validating the ToolchainConfigs on the webhook server (port 9443). "+
+			"The serving certificates must be provided in the certificate directory of the webhook server (see config/webhook).")
 	flag.BoolVar(&enableLeaderElection, "leader-elect", false,
 		"Enable leader election for controller manager. "+
 			"Enabling this will ensure there is only one active controller manager.")
@@ -220,7 +246,17 @@ func main() { // nolint:gocyclo
 		setupLog.Error(err, "unable to start manager")
 		os.Exit(1)
 	}
-	memberClusters, err := addMemberClusters(mgr, cl, namespace, true)
+	// the circuit breakers of the requests sent to the member clusters, shared by the member clusters with namespaced and cluster-scoped caches
+	circuitBreakers := cluster.NewCircuitBreakers(func() cluster.CircuitBreakerSettings {
+		toolchainConfig := toolchainconfig.GetCachedToolchainConfig()
+		config := toolchainConfig.MemberClients()
+		return cluster.CircuitBreakerSettings{
+			FailureThreshold: config.CircuitBreakerFailureThreshold(),
+			OpenDuration:     config.CircuitBreakerOpenDuration(),
+			MaxOpenDuration:  config.CircuitBreakerMaxOpenDuration(),
+		}
+	})
+	memberClusters, err := newMemberClusters(ctx, cl, namespace, true, circuitBreakers)
 	if err != nil {
 		setupLog.Error(err, "")
 		os.Exit(1)
@@ -276,6 +312,46 @@ func main() { // nolint:gocyclo
 		setupLog.Error(err, "unable to create controller", "controller", "Notification")
 		os.Exit(1)
 	}
+	if deliveryEventsAddr != "0" {
+		if err := mgr.Add(&notification.DeliveryEventsServer{
+			Client:      mgr.GetClient(),
+			Namespace:   namespace,
+			BindAddress: deliveryEventsAddr,
+		}); err != nil {
+			setupLog.Error(err, "unable to add the notification delivery events server")
+			os.Exit(1)
+		}
+	}
+	if summaryAddr != "0" {
+		if err := mgr.Add(&summary.Server{
+			Client:      mgr.GetClient(),
+			Namespace:   namespace,
+			BindAddress: summaryAddr,
+			CertDir:     summaryCertDir,
+		}); err != nil {
+			setupLog.Error(err, "unable to add the summary server")
+			os.Exit(1)
+		}
+	}
+	if enableConfigWebhook {
+		mgr.GetWebhookServer().Register(toolchainconfig.ValidationWebhookPath, &webhook.Admission{
+			Handler: &toolchainconfig.ValidationWebhook{Decoder: admission.NewDecoder(mgr.GetScheme())},
+		})
+	}
+	if err := (&notificationtemplates.Reconciler{
+		Client:    mgr.GetClient(),
+		Namespace: namespace,
+	}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "NotificationTemplates")
+		os.Exit(1)
+	}
+	if err := (&featuretoggle.Reconciler{
+		Client:    mgr.GetClient(),
+		Namespace: namespace,
+	}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "FeatureToggle")
+		os.Exit(1)
+	}
 	if err := (&nstemplatetier.Reconciler{
 		Client: mgr.GetClient(),
 		Scheme: mgr.GetScheme(),
@@ -305,6 +381,7 @@ func main() { // nolint:gocyclo
 		VersionCheckManager: status.VersionCheckManager{GetGithubClientFunc: commonclient.NewGitHubClient},
 		GetMembersFunc:      commoncluster.GetMemberClusters,
 		Namespace:           namespace,
+		CircuitBreakers:     circuitBreakers,
 	}).SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "ToolchainStatus")
 		os.Exit(1)
@@ -328,8 +405,17 @@ func main() { // nolint:gocyclo
 		setupLog.Error(err, "unable to create controller", "controller", "UserSignupCleanup")
 		os.Exit(1)
 	}
+	if err := (&usererasure.Reconciler{
+		Client:         mgr.GetClient(),
+		Scheme:         mgr.GetScheme(),
+		Namespace:      namespace,
+		GetMembersFunc: commoncluster.GetMemberClusters,
+	}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "UserErasure")
+		os.Exit(1)
+	}
 	// init cluster scoped member cluster clients
-	clusterScopedMemberClusters, err := addMemberClusters(mgr, cl, namespace, false)
+	clusterScopedMemberClusters, err := newMemberClusters(ctx, cl, namespace, false, circuitBreakers)
 	if err != nil {
 		setupLog.Error(err, "")
 		os.Exit(1)
@@ -402,6 +488,15 @@ func main() { // nolint:gocyclo
 		setupLog.Error(err, "unable to create controller", "controller", "SocialEvent")
 		os.Exit(1)
 	}
+	if err = (&membercluster.Reconciler{
+		Client:         mgr.GetClient(),
+		Namespace:      namespace,
+		Timeout:        memberClientTimeout,
+		MemberClusters: []*cluster.MemberClusters{memberClusters, clusterScopedMemberClusters},
+	}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "MemberCluster")
+		os.Exit(1)
+	}
 	if err = (&spaceprovisionerconfig.Reconciler{
 		Client: mgr.GetClient(),
 	}).SetupWithManager(mgr); err != nil {
@@ -459,13 +554,27 @@ func main() { // nolint:gocyclo
 	}
 }
 
-func addMemberClusters(mgr ctrl.Manager, cl runtimeclient.Client, namespace string, namespacedCache bool) (map[string]cluster.Cluster, error) {
+// newMemberClusters returns the member clusters of the ToolchainClusters which exist at startup. The member clusters of the ToolchainClusters
+// created, updated or deleted later are added, re-created or removed by the MemberCluster controller.
+func newMemberClusters(ctx context.Context, cl runtimeclient.Client, namespace string, namespacedCache bool, circuitBreakers *cluster.CircuitBreakers) (*cluster.MemberClusters, error) {
 	memberConfigs, err := commoncluster.ListToolchainClusterConfigs(cl, namespace, memberClientTimeout)
 	if err != nil {
 		return nil, errors.Wrapf(err, "unable to get ToolchainCluster configs for members")
 	}
-	memberClusters := map[string]cluster.Cluster{}
+	memberClusters := cluster.NewMemberClusters(newMemberClusterFunc(ctx, namespacedCache, circuitBreakers))
 	for _, memberConfig := range memberConfigs {
+		if _, err := memberClusters.Ensure(memberConfig); err != nil {
+			return nil, err
+		}
+	}
+	return memberClusters, nil
+}
+
+// newMemberClusterFunc returns the function creating the member clusters. Their caches are started right away instead of
+// being added to the manager, so that they can be stopped when the ToolchainCluster is deleted. Their requests are sent through
+// the circuit breakers of the member clusters.
+func newMemberClusterFunc(ctx context.Context, namespacedCache bool, circuitBreakers *cluster.CircuitBreakers) cluster.NewClusterFunc {
+	return func(memberConfig *commoncluster.Config) (cluster.Cluster, context.CancelFunc, error) {
 		setupLog.Info("adding cluster for a member", "name", memberConfig.Name, "apiEndpoint", memberConfig.APIEndpoint)
 
 		memberCluster, err := runtimecluster.New(memberConfig.RestConfig, func(options *runtimecluster.Options) {
@@ -477,10 +586,7 @@ func addMemberClusters(mgr ctrl.Manager, cl runtimeclient.Client, namespace stri
 			}
 		})
 		if err != nil {
-			return nil, fmt.Errorf("unable to create member cluster definition for '%s': %w", memberConfig.Name, err)
-		}
-		if err := mgr.Add(memberCluster); err != nil {
-			return nil, fmt.Errorf("unable to add member cluster to the manager for '%s' : %w", memberConfig.Name, err)
+			return cluster.Cluster{}, nil, fmt.Errorf("unable to create member cluster definition for '%s': %w", memberConfig.Name, err)
 		}
 		// These fields need to be set when using the REST client
 		memberConfig.RestConfig.ContentConfig = rest.ContentConfig{
@@ -489,16 +595,23 @@ func addMemberClusters(mgr ctrl.Manager, cl runtimeclient.Client, namespace stri
 		}
 		restClient, err := rest.RESTClientFor(memberConfig.RestConfig)
 		if err != nil {
-			return nil, fmt.Errorf("unable to create member cluster rest client '%s' : %w", memberConfig.Name, err)
-		}
-		memberClusters[memberConfig.Name] = cluster.Cluster{
-			Config:     memberConfig,
-			Client:     memberCluster.GetClient(),
-			RESTClient: restClient,
-			Cache:      memberCluster.GetCache(),
+			return cluster.Cluster{}, nil, fmt.Errorf("unable to create member cluster rest client '%s' : %w", memberConfig.Name, err)
 		}
+		clusterCtx, stop := context.WithCancel(ctx)
+		go func() {
+			if err := memberCluster.Start(clusterCtx); err != nil {
+				setupLog.Error(err, "unable to run the member cluster", "name", memberConfig.Name)
+			}
+		}()
+		circuitBreaker := circuitBreakers.Get(memberConfig.Name)
+		return cluster.Cluster{
+			Config:         memberConfig,
+			Client:         cluster.NewCircuitBreakerClient(memberCluster.GetClient(), circuitBreaker),
+			RESTClient:     restClient,
+			Cache:          memberCluster.GetCache(),
+			CircuitBreaker: circuitBreaker,
+		}, stop, nil
 	}
-	return memberClusters, nil
 }
 
 // OutputCallDepth is the stack depth where we can find the origin of this call
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	toolchainv1alpha1 "github.com/codeready-toolchain/api/api/v1alpha1"
+	"github.com/codeready-toolchain/host-operator/controllers/notification"
+	"github.com/codeready-toolchain/host-operator/controllers/toolchainconfig"
+	"github.com/codeready-toolchain/host-operator/pkg/templates/notificationtemplates"
+)
+
+// renderNotificationCommand is the subcommand which renders a notification template, eg:
+//
+//	go run ./cmd render-notification --template-set sandbox --template userprovisioned --locale fr
+//
+// The templates are the ones embedded in the binary, ie, the ones in deploy/templates/notificationtemplates when using `go run`.
+const renderNotificationCommand = "render-notification"
+
+// sampleNotificationContext contains all the keys which can be found in the context of the notifications
+var sampleNotificationContext = map[string]string{
+	"Sub":                     "f:12345678-abcd-4e1f-9012-345678abcdef:jsmith",
+	"UserID":                  "12345678",
+	"UserName":                "jsmith",
+	"FirstName":               "John",
+	"LastName":                "Smith",
+	"CompanyName":             "Acme",
+	"UserEmail":               "jsmith@acme.com",
+	"DeactivationTimeoutDays": "30",
+	"AppName":                 "my-app",
+	"AppType":                 "Deployment",
+	"Namespace":               "jsmith-dev",
+	toolchainconfig.NotificationContextRegistrationURLKey: "https://registration.example.com",
+}
+
+// renderNotification renders the template given in the args with the sample (or the supplied) context, and writes the subject,
+// the HTML and the plain text bodies to the output directory (or to stdout). Returns the exit code of the command.
+func renderNotification(args []string, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet(renderNotificationCommand, flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	templateSetName := flags.String("template-set", notificationtemplates.SandboxTemplateSetName, "The template set of the template to render.")
+	templateName := flags.String("template", "", "The name of the template to render (eg. userprovisioned).")
+	locale := flags.String("locale", "", "The locale of the recipient (eg. fr), to render a localized variant of the template.")
+	contextFile := flags.String("context", "", "A JSON file with the context of the notification. Defaults to a sample context with all the known keys.")
+	replyTo := flags.String("reply-to", "noreply@example.com", "The reply-to address, available as {{.ReplyTo}} in the templates.")
+	outputDir := flags.String("output-dir", "", "The directory in which the <template>.subject.txt, <template>.html and <template>.txt files are written. "+
+		"Defaults to stdout.")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if *templateName == "" {
+		fmt.Fprintln(stderr, "missing --template")
+		flags.Usage()
+		return 2
+	}
+
+	context := map[string]string{}
+	if *contextFile != "" {
+		content, err := os.ReadFile(*contextFile)
+		if err != nil {
+			fmt.Fprintf(stderr, "unable to read the context: %s\n", err)
+			return 1
+		}
+		if err := json.Unmarshal(content, &context); err != nil {
+			fmt.Fprintf(stderr, "invalid context in '%s': %s\n", *contextFile, err)
+			return 1
+		}
+	} else {
+		for k, v := range sampleNotificationContext {
+			context[k] = v
+		}
+	}
+	if *locale != "" {
+		context[toolchainconfig.NotificationContextLocaleKey] = *locale
+	}
+
+	svc := &notification.BaseNotificationDeliveryService{
+		TemplateLoader: &notification.DefaultTemplateLoader{},
+		StrictContext:  true,
+	}
+	subject, html, text, err := svc.RenderNotification(&toolchainv1alpha1.Notification{
+		Spec: toolchainv1alpha1.NotificationSpec{
+			Template: *templateName,
+			Context:  context,
+		},
+	}, *templateSetName, *replyTo)
+	if err != nil {
+		fmt.Fprintf(stderr, "unable to render the template '%s' of the template set '%s': %s\n", *templateName, *templateSetName, err)
+		return 1
+	}
+
+	if *outputDir == "" {
+		fmt.Fprintf(stdout, "Subject: %s\n\n--- HTML ---\n%s\n--- Text ---\n%s\n", subject, html, text)
+		return 0
+	}
+	if err := os.MkdirAll(*outputDir, 0o755); err != nil {
+		fmt.Fprintf(stderr, "unable to create the output directory: %s\n", err)
+		return 1
+	}
+	name := *templateName
+	if *locale != "" {
+		name = notificationtemplates.LocalizedTemplateName(name, *locale)
+	}
+	for suffix, content := range map[string]string{
+		".subject.txt": subject,
+		".html":        html,
+		".txt":         text,
+	} {
+		if err := os.WriteFile(filepath.Join(*outputDir, name+suffix), []byte(content), 0o600); err != nil {
+			fmt.Fprintf(stderr, "unable to write the rendered template: %s\n", err)
+			return 1
+		}
+	}
+	return 0
+}
@@ -0,0 +1,144 @@
+package main
+
+import (
+	"bytes"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/codeready-toolchain/host-operator/deploy"
+	"github.com/codeready-toolchain/host-operator/pkg/templates/notificationtemplates"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRenderNotification(t *testing.T) {
+	t.Run("all the embedded templates", func(t *testing.T) {
+		templates := embeddedTemplates(t)
+		require.NotEmpty(t, templates)
+
+		for _, tmpl := range templates {
+			t.Run(tmpl.String(), func(t *testing.T) {
+				// given
+				outputDir := t.TempDir()
+				args := []string{"--template-set", tmpl.set, "--template", tmpl.name, "--output-dir", outputDir}
+				if tmpl.locale != "" {
+					args = append(args, "--locale", tmpl.locale)
+				}
+				stdout, stderr := &bytes.Buffer{}, &bytes.Buffer{}
+
+				// when
+				code := renderNotification(args, stdout, stderr)
+
+				// then
+				require.Equal(t, 0, code, stderr.String())
+				name := tmpl.name
+				if tmpl.locale != "" {
+					name = notificationtemplates.LocalizedTemplateName(name, tmpl.locale)
+				}
+				for _, suffix := range []string{".subject.txt", ".html"} {
+					content, err := os.ReadFile(filepath.Join(outputDir, name+suffix))
+					require.NoError(t, err)
+					assert.NotEmpty(t, strings.TrimSpace(string(content)))
+					assert.NotContains(t, string(content), "<no value>")
+				}
+			})
+		}
+	})
+
+	t.Run("to stdout", func(t *testing.T) {
+		// given
+		stdout, stderr := &bytes.Buffer{}, &bytes.Buffer{}
+
+		// when
+		code := renderNotification([]string{"--template", notificationtemplates.UserProvisionedTemplateName}, stdout, stderr)
+
+		// then
+		require.Equal(t, 0, code, stderr.String())
+		assert.Contains(t, stdout.String(), "Subject: ")
+		assert.Contains(t, stdout.String(), "--- HTML ---")
+	})
+
+	t.Run("failures", func(t *testing.T) {
+		for name, tc := range map[string]struct {
+			args          []string
+			expectedCode  int
+			expectedError string
+		}{
+			"missing template": {
+				args:          []string{"--template-set", notificationtemplates.SandboxTemplateSetName},
+				expectedCode:  2,
+				expectedError: "missing --template",
+			},
+			"unknown template": {
+				args:          []string{"--template", "unknown"},
+				expectedCode:  1,
+				expectedError: "unable to render the template 'unknown' of the template set 'sandbox'",
+			},
+			"unknown template set": {
+				args:          []string{"--template-set", "unknown", "--template", notificationtemplates.UserProvisionedTemplateName},
+				expectedCode:  1,
+				expectedError: "unable to render the template 'userprovisioned' of the template set 'unknown'",
+			},
+			"missing context file": {
+				args:          []string{"--template", notificationtemplates.UserProvisionedTemplateName, "--context", filepath.Join(t.TempDir(), "context.json")},
+				expectedCode:  1,
+				expectedError: "unable to read the context",
+			},
+		} {
+			t.Run(name, func(t *testing.T) {
+				// given
+				stdout, stderr := &bytes.Buffer{}, &bytes.Buffer{}
+
+				// when
+				code := renderNotification(tc.args, stdout, stderr)
+
+				// then
+				assert.Equal(t, tc.expectedCode, code)
+				assert.Contains(t, stderr.String(), tc.expectedError)
+			})
+		}
+	})
+}
+
+type embeddedTemplate struct {
+	set, name, locale string
+}
+
+func (t embeddedTemplate) String() string {
+	if t.locale == "" {
+		return t.set + "/" + t.name
+	}
+	return t.set + "/" + t.name + "/" + t.locale
+}
+
+// embeddedTemplates returns all the templates (and their localized variants) embedded in the binary
+func embeddedTemplates(t *testing.T) []embeddedTemplate {
+	const root = "templates/notificationtemplates"
+	var templates []embeddedTemplate
+	found := map[embeddedTemplate]bool{}
+	err := fs.WalkDir(deploy.NotificationTemplateFS, root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		// eg. templates/notificationtemplates/sandbox/userprovisioned/notification.fr.html
+		segments := strings.Split(strings.TrimPrefix(path, root+"/"), "/")
+		if len(segments) != 3 || segments[1] == notificationtemplates.PartialsDirectory {
+			return nil
+		}
+		tmpl := embeddedTemplate{set: segments[0], name: segments[1]}
+		if parts := strings.Split(segments[2], "."); len(parts) == 3 {
+			tmpl.locale = parts[1]
+		}
+		if !found[tmpl] {
+			found[tmpl] = true
+			templates = append(templates, tmpl)
+		}
+		return nil
+	})
+	require.NoError(t, err)
+	return templates
+}
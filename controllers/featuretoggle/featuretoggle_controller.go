@@ -0,0 +1,164 @@
+package featuretoggle
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"strings"
+	"time"
+
+	toolchainv1alpha1 "github.com/codeready-toolchain/api/api/v1alpha1"
+	"github.com/codeready-toolchain/host-operator/controllers/toolchainconfig"
+	"github.com/codeready-toolchain/host-operator/pkg/metrics"
+	spaceutil "github.com/codeready-toolchain/host-operator/pkg/space"
+
+	errs "github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/api/errors"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	runtimeclient "sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+// refreshPeriod is how often the number of Spaces with each feature enabled is computed again, to take the new Spaces into account
+const refreshPeriod = 5 * time.Minute
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *Reconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		Named("featuretoggle").
+		// the annotations are watched too, since they contain the targeting of the feature toggles
+		For(&toolchainv1alpha1.ToolchainConfig{}, builder.WithPredicates(predicate.Or(predicate.GenerationChangedPredicate{}, predicate.AnnotationChangedPredicate{}))).
+		Complete(r)
+}
+
+// Reconciler exports the number of Spaces with each feature enabled, and re-evaluates the feature toggles of the existing Spaces
+// when the feature toggles of the ToolchainConfig change, if enabled
+type Reconciler struct {
+	Client    runtimeclient.Client
+	Namespace string
+	// evaluatedHash is the hash of the feature toggles (and of their targeting) with which all the Spaces were successfully
+	// re-evaluated, so that they are not re-evaluated again until the feature toggles change. The new Spaces get their feature
+	// toggles when they are created.
+	evaluatedHash string
+}
+
+//+kubebuilder:rbac:groups=toolchain.dev.openshift.com,resources=toolchainconfigs,verbs=get;list;watch
+//+kubebuilder:rbac:groups=toolchain.dev.openshift.com,resources=spaces,verbs=get;list;watch;update;patch
+//+kubebuilder:rbac:groups=toolchain.dev.openshift.com,resources=usersignups,verbs=get;list;watch
+
+// Reconcile evaluates the feature toggles of all the Spaces when the feature toggles (or their targeting) changed since the latest
+// evaluation, and refreshes the number of Spaces with each feature enabled. The failures of the evaluation of some Spaces don't
+// prevent the evaluation of the other ones, nor the refresh of the metric.
+func (r *Reconciler) Reconcile(ctx context.Context, _ ctrl.Request) (ctrl.Result, error) {
+	logger := log.FromContext(ctx)
+
+	// the ToolchainConfig controller may not have loaded the change into the cache yet
+	config, err := toolchainconfig.ForceLoadToolchainConfig(r.Client)
+	if err != nil {
+		return reconcile.Result{}, errs.Wrap(err, "unable to get ToolchainConfig")
+	}
+	toggles := config.Tiers().FeatureToggles()
+	hash, err := togglesHash(toggles)
+	if err != nil {
+		return reconcile.Result{}, err
+	}
+	enabled := config.Tiers().FeatureToggleReevaluationEnabled()
+	if !enabled {
+		// evaluate all the Spaces once the re-evaluation is enabled again
+		r.evaluatedHash = ""
+	}
+	reevaluate := enabled && hash != r.evaluatedHash
+
+	spaces := &toolchainv1alpha1.SpaceList{}
+	if err := r.Client.List(ctx, spaces, runtimeclient.InNamespace(r.Namespace)); err != nil {
+		return reconcile.Result{}, errs.Wrap(err, "unable to list the Spaces")
+	}
+	counts := make(map[string]int, len(toggles))
+	for _, t := range toggles {
+		counts[t.Name()] = 0
+	}
+	updated := 0
+	var failures []error
+	for i := range spaces.Items {
+		space := &spaces.Items[i]
+		if reevaluate {
+			changed, err := r.reevaluate(ctx, space, toggles)
+			if err != nil {
+				logger.Error(err, "unable to re-evaluate the feature toggles of the Space", "space", space.Name)
+				failures = append(failures, err)
+			}
+			if changed {
+				updated++
+			}
+		}
+		for _, feature := range strings.Split(space.Annotations[toolchainv1alpha1.FeatureToggleNameAnnotationKey], ",") {
+			if _, known := counts[feature]; known {
+				counts[feature]++
+			}
+		}
+	}
+	if updated > 0 {
+		logger.Info("re-evaluated the feature toggles of the Spaces", "updated", updated)
+	}
+
+	metrics.FeatureToggleEnabledSpacesGaugeVec.Reset()
+	for name, count := range counts {
+		metrics.FeatureToggleEnabledSpacesGaugeVec.WithLabelValues(name).Set(float64(count))
+	}
+	if len(failures) > 0 {
+		// the Spaces are all evaluated again at the next attempt
+		return reconcile.Result{}, utilerrors.NewAggregate(failures)
+	}
+	if reevaluate {
+		r.evaluatedHash = hash
+	}
+	return reconcile.Result{RequeueAfter: refreshPeriod}, nil
+}
+
+// togglesHash returns the hash of the given feature toggles, including their targeting
+func togglesHash(toggles []toolchainconfig.FeatureToggle) (string, error) {
+	type toggle struct {
+		Name      string                                 `json:"name"`
+		Weight    uint                                   `json:"weight"`
+		Targeting toolchainconfig.FeatureToggleTargeting `json:"targeting"`
+	}
+	hashed := make([]toggle, 0, len(toggles))
+	for _, t := range toggles {
+		hashed = append(hashed, toggle{Name: t.Name(), Weight: t.Weight(), Targeting: t.Targeting()})
+	}
+	data, err := json.Marshal(hashed)
+	if err != nil {
+		return "", errs.Wrap(err, "unable to marshal the feature toggles")
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// reevaluate evaluates the feature toggles of the given Space again, and updates it if the enabled features changed. Only the home Spaces
+// of the users get the feature toggles, as when the Spaces are created.
+func (r *Reconciler) reevaluate(ctx context.Context, space *toolchainv1alpha1.Space, toggles []toolchainconfig.FeatureToggle) (bool, error) {
+	creator := space.Labels[toolchainv1alpha1.SpaceCreatorLabelKey]
+	if creator == "" || space.Spec.ParentSpace != "" || !space.DeletionTimestamp.IsZero() {
+		return false, nil
+	}
+	userSignup := &toolchainv1alpha1.UserSignup{}
+	if err := r.Client.Get(ctx, runtimeclient.ObjectKey{Namespace: r.Namespace, Name: creator}, userSignup); err != nil {
+		if errors.IsNotFound(err) {
+			return false, nil
+		}
+		return false, errs.Wrapf(err, "unable to get the UserSignup '%s'", creator)
+	}
+	subject := spaceutil.NewFeatureToggleSubject(userSignup, space.Spec.TierName, space.Spec.TargetCluster)
+	if !spaceutil.ApplyFeatureToggles(space, subject, toggles) {
+		return false, nil
+	}
+	if err := r.Client.Update(ctx, space); err != nil {
+		return false, errs.Wrapf(err, "unable to update the feature toggles of the Space '%s'", space.Name)
+	}
+	return true, nil
+}
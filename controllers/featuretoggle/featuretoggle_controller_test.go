@@ -0,0 +1,172 @@
+package featuretoggle
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	toolchainv1alpha1 "github.com/codeready-toolchain/api/api/v1alpha1"
+	"github.com/codeready-toolchain/host-operator/controllers/toolchainconfig"
+	"github.com/codeready-toolchain/host-operator/pkg/metrics"
+	hostconfig "github.com/codeready-toolchain/host-operator/test/config"
+	commonconfig "github.com/codeready-toolchain/toolchain-common/pkg/configuration"
+	"github.com/codeready-toolchain/toolchain-common/pkg/test"
+	testconfig "github.com/codeready-toolchain/toolchain-common/pkg/test/config"
+	spacetest "github.com/codeready-toolchain/toolchain-common/pkg/test/space"
+	commonsignup "github.com/codeready-toolchain/toolchain-common/pkg/test/usersignup"
+
+	promtestutil "github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/utils/ptr"
+	runtimeclient "sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+func TestReconcile(t *testing.T) {
+	// given
+	restore := test.SetEnvVarAndRestore(t, commonconfig.WatchNamespaceEnvVar, test.HostOperatorNs)
+	t.Cleanup(restore)
+	config := func(t *testing.T, reevaluate bool) *toolchainv1alpha1.ToolchainConfig {
+		return commonconfig.NewToolchainConfigObjWithReset(t,
+			testconfig.Tiers().
+				FeatureToggle("feature-on", ptr.To[uint](100)).
+				FeatureToggle("feature-off", ptr.To[uint](0)).
+				FeatureToggle("feature-acme", nil),
+			hostconfig.Annotation(toolchainconfig.FeatureToggleTargetingAnnotationKey, `{"feature-acme": {"emailDomains": ["acme.com"]}}`),
+			hostconfig.Annotation(toolchainconfig.FeatureToggleReevaluationEnabledAnnotationKey, fmt.Sprint(reevaluate)))
+	}
+	objects := func() []runtimeclient.Object {
+		return []runtimeclient.Object{
+			commonsignup.NewUserSignup(commonsignup.WithName("johny"), commonsignup.WithEmail("johny@acme.com")),
+			commonsignup.NewUserSignup(commonsignup.WithName("jane"), commonsignup.WithEmail("jane@redhat.com")),
+			spacetest.NewSpace(test.HostOperatorNs, "johny", spacetest.WithCreatorLabel("johny"), spacetest.WithTierName("base")),
+			spacetest.NewSpace(test.HostOperatorNs, "jane", spacetest.WithCreatorLabel("jane"),
+				spacetest.WithAnnotation(toolchainv1alpha1.FeatureToggleNameAnnotationKey, "feature-off,manual")),
+			spacetest.NewSpace(test.HostOperatorNs, "johny-sub", spacetest.WithCreatorLabel("johny"), spacetest.WithSpecParentSpace("johny")),
+			spacetest.NewSpace(test.HostOperatorNs, "orphan", spacetest.WithCreatorLabel("unknown")),
+		}
+	}
+
+	t.Run("re-evaluation disabled", func(t *testing.T) {
+		// given
+		r, cl := prepareReconcile(t, append(objects(), config(t, false))...)
+
+		// when
+		result, err := r.Reconcile(context.TODO(), reconcile.Request{})
+
+		// then
+		require.NoError(t, err)
+		assert.Equal(t, refreshPeriod, result.RequeueAfter)
+		assertFeatures(t, cl, "johny", "")
+		assertFeatures(t, cl, "jane", "feature-off,manual")
+		assertEnabledSpaces(t, map[string]int{"feature-on": 0, "feature-off": 1, "feature-acme": 0})
+	})
+
+	t.Run("re-evaluation enabled", func(t *testing.T) {
+		// given
+		r, cl := prepareReconcile(t, append(objects(), config(t, true))...)
+
+		// when
+		result, err := r.Reconcile(context.TODO(), reconcile.Request{})
+
+		// then
+		require.NoError(t, err)
+		assert.Equal(t, refreshPeriod, result.RequeueAfter)
+		assertFeatures(t, cl, "johny", "feature-on,feature-acme")
+		assertFeatures(t, cl, "jane", "feature-on,manual")
+		assertFeatures(t, cl, "johny-sub", "")
+		assertFeatures(t, cl, "orphan", "")
+		assertEnabledSpaces(t, map[string]int{"feature-on": 2, "feature-off": 0, "feature-acme": 1})
+
+		t.Run("not re-evaluated when the feature toggles didn't change", func(t *testing.T) {
+			// given
+			space := &toolchainv1alpha1.Space{}
+			require.NoError(t, cl.Get(context.TODO(), test.NamespacedName(test.HostOperatorNs, "jane"), space))
+			space.Annotations[toolchainv1alpha1.FeatureToggleNameAnnotationKey] = "manual"
+			require.NoError(t, cl.Update(context.TODO(), space))
+
+			// when
+			result, err := r.Reconcile(context.TODO(), reconcile.Request{})
+
+			// then
+			require.NoError(t, err)
+			assert.Equal(t, refreshPeriod, result.RequeueAfter)
+			assertFeatures(t, cl, "jane", "manual")
+			assertEnabledSpaces(t, map[string]int{"feature-on": 1, "feature-off": 0, "feature-acme": 1})
+
+			t.Run("re-evaluated when the targeting changed", func(t *testing.T) {
+				// given
+				toolchainConfig := &toolchainv1alpha1.ToolchainConfig{}
+				require.NoError(t, cl.Get(context.TODO(), test.NamespacedName(test.HostOperatorNs, "config"), toolchainConfig))
+				toolchainConfig.Annotations[toolchainconfig.FeatureToggleTargetingAnnotationKey] = `{"feature-acme": {"emailDomains": ["redhat.com"]}}`
+				require.NoError(t, cl.Update(context.TODO(), toolchainConfig))
+
+				// when
+				result, err := r.Reconcile(context.TODO(), reconcile.Request{})
+
+				// then
+				require.NoError(t, err)
+				assert.Equal(t, refreshPeriod, result.RequeueAfter)
+				assertFeatures(t, cl, "johny", "feature-on")
+				assertFeatures(t, cl, "jane", "feature-on,feature-acme,manual")
+				assertEnabledSpaces(t, map[string]int{"feature-on": 2, "feature-off": 0, "feature-acme": 1})
+			})
+		})
+	})
+
+	t.Run("failure", func(t *testing.T) {
+		// given
+		r, cl := prepareReconcile(t, append(objects(), config(t, true))...)
+		cl.MockGet = func(ctx context.Context, key runtimeclient.ObjectKey, obj runtimeclient.Object, opts ...runtimeclient.GetOption) error {
+			if _, ok := obj.(*toolchainv1alpha1.UserSignup); ok {
+				return fmt.Errorf("mock error")
+			}
+			return cl.Client.Get(ctx, key, obj, opts...)
+		}
+
+		// when
+		_, err := r.Reconcile(context.TODO(), reconcile.Request{})
+
+		// then
+		require.ErrorContains(t, err, "unable to get the UserSignup 'johny'")
+		require.ErrorContains(t, err, "unable to get the UserSignup 'jane'")
+		// the metric is refreshed anyway
+		assertEnabledSpaces(t, map[string]int{"feature-on": 0, "feature-off": 1, "feature-acme": 0})
+
+		t.Run("re-evaluated again at the next attempt", func(t *testing.T) {
+			// given
+			cl.MockGet = nil
+
+			// when
+			_, err := r.Reconcile(context.TODO(), reconcile.Request{})
+
+			// then
+			require.NoError(t, err)
+			assertFeatures(t, cl, "johny", "feature-on,feature-acme")
+			assertFeatures(t, cl, "jane", "feature-on,manual")
+		})
+	})
+}
+
+func prepareReconcile(t *testing.T, objects ...runtimeclient.Object) (*Reconciler, *test.FakeClient) {
+	metrics.Reset()
+	t.Cleanup(metrics.Reset)
+	cl := test.NewFakeClient(t, objects...)
+	return &Reconciler{
+		Client:    cl,
+		Namespace: test.HostOperatorNs,
+	}, cl
+}
+
+func assertFeatures(t *testing.T, cl runtimeclient.Client, spaceName, expected string) {
+	space := &toolchainv1alpha1.Space{}
+	require.NoError(t, cl.Get(context.TODO(), test.NamespacedName(test.HostOperatorNs, spaceName), space))
+	assert.Equal(t, expected, space.Annotations[toolchainv1alpha1.FeatureToggleNameAnnotationKey], "features of the Space '%s'", spaceName)
+}
+
+func assertEnabledSpaces(t *testing.T, expected map[string]int) {
+	for name, count := range expected {
+		assert.InDelta(t, float64(count), promtestutil.ToFloat64(metrics.FeatureToggleEnabledSpacesGaugeVec.WithLabelValues(name)), 0.01, "feature toggle '%s'", name)
+	}
+}
@@ -37,21 +37,25 @@ const (
 )
 
 // SetupWithManager sets up the controller with the Manager.
-func (r *Reconciler) SetupWithManager(mgr manager.Manager, memberClusters map[string]cluster.Cluster) error {
-	b := ctrl.NewControllerManagedBy(mgr).
+func (r *Reconciler) SetupWithManager(mgr manager.Manager, memberClusters *cluster.MemberClusters) error {
+	c, err := ctrl.NewControllerManagedBy(mgr).
 		For(&toolchainv1alpha1.MasterUserRecord{}, builder.WithPredicates(predicate.GenerationChangedPredicate{})).
 		Watches(&toolchainv1alpha1.SpaceBinding{}, handler.EnqueueRequestsFromMapFunc(
 			controllers.MapToOwnerByLabel(r.Namespace, toolchainv1alpha1.SpaceBindingMasterUserRecordLabelKey))).
 		Watches(&toolchainv1alpha1.Space{}, handler.EnqueueRequestsFromMapFunc(
-			MapSpaceToMasterUserRecord(r.Client)), builder.WithPredicates(predicate.GenerationChangedPredicate{}))
+			MapSpaceToMasterUserRecord(r.Client)), builder.WithPredicates(predicate.GenerationChangedPredicate{})).
+		// the requests rejected by the circuit breaker of a member cluster are retried once it lets the requests through again
+		Build(cluster.RequeueOnOpenCircuit(r))
+	if err != nil {
+		return err
+	}
 
-	// watch UserAccounts in all the member clusters
-	for _, memberCluster := range memberClusters {
-		b = b.WatchesRawSource(source.Kind[runtimeclient.Object](memberCluster.Cache, &toolchainv1alpha1.UserAccount{},
+	// watch UserAccounts in all the member clusters, including the ones added later
+	return memberClusters.AddHandler(func(memberCluster cluster.Cluster) error {
+		return c.Watch(source.Kind[runtimeclient.Object](memberCluster.Cache, &toolchainv1alpha1.UserAccount{},
 			handler.EnqueueRequestsFromMapFunc(mapper.MapByResourceName(r.Namespace)),
 		))
-	}
-	return b.Complete(r)
+	})
 }
 
 // Reconciler reconciles a MasterUserRecord object
@@ -59,7 +63,7 @@ type Reconciler struct {
 	Client         runtimeclient.Client
 	Scheme         *runtime.Scheme
 	Namespace      string
-	MemberClusters map[string]cluster.Cluster
+	MemberClusters *cluster.MemberClusters
 }
 
 //+kubebuilder:rbac:groups=toolchain.dev.openshift.com,resources=masteruserrecords,verbs=get;list;watch;create;update;patch;delete
@@ -148,7 +152,7 @@ func (r *Reconciler) ensureUserAccountsAreNotPresent(ctx context.Context, mur *t
 
 func (r *Reconciler) membersWithoutUserAccount(membersWithUserAccounts map[string]bool) map[string]cluster.Cluster {
 	membersWithout := map[string]cluster.Cluster{}
-	for memberName, memberCluster := range r.MemberClusters {
+	for memberName, memberCluster := range r.MemberClusters.All() {
 		if _, found := membersWithUserAccounts[memberName]; !found {
 			membersWithout[memberName] = memberCluster
 		}
@@ -215,7 +219,7 @@ func (r *Reconciler) addFinalizer(ctx context.Context, mur *toolchainv1alpha1.Ma
 // Returns bool as the first argument if the UserAccount was either created or updated
 func (r *Reconciler) ensureUserAccount(ctx context.Context, mur *toolchainv1alpha1.MasterUserRecord, targetCluster string) (bool, error) {
 	// get & check member cluster
-	memberCluster, found := r.MemberClusters[targetCluster]
+	memberCluster, found := r.MemberClusters.Get(targetCluster)
 	if !found {
 		return false, r.wrapErrorWithStatusUpdate(ctx, mur, r.setStatusFailed(toolchainv1alpha1.MasterUserRecordTargetClusterNotReadyReason),
 			fmt.Errorf("unknown target member cluster '%s'", targetCluster),
@@ -313,7 +317,7 @@ func (r *Reconciler) useExistingConditionOfType(condType toolchainv1alpha1.Condi
 }
 
 func (r *Reconciler) manageCleanUp(ctx context.Context, mur *toolchainv1alpha1.MasterUserRecord) (time.Duration, error) {
-	if requeue, err := r.ensureUserAccountsAreNotPresent(ctx, mur, r.MemberClusters); err != nil || requeue > 0 {
+	if requeue, err := r.ensureUserAccountsAreNotPresent(ctx, mur, r.MemberClusters.All()); err != nil || requeue > 0 {
 		return requeue, err
 	}
 	// Remove finalizer from MasterUserRecord
@@ -1458,15 +1458,11 @@ func apiScheme(t *testing.T) *runtime.Scheme {
 
 func newController(hostCl runtimeclient.Client, s *runtime.Scheme, memberCl ...ClientForCluster) Reconciler {
 	os.Setenv("WATCH_NAMESPACE", commontest.HostOperatorNs)
-	r := Reconciler{
-		Client:         hostCl,
-		Scheme:         s,
-		MemberClusters: map[string]cluster.Cluster{},
-	}
+	memberClusters := map[string]cluster.Cluster{}
 	for _, c := range memberCl {
 		name, cl := c()
 		NewMemberClusterWithClient(cl, name, corev1.ConditionTrue)
-		r.MemberClusters[name] = cluster.Cluster{
+		memberClusters[name] = cluster.Cluster{
 			Config: &commoncluster.Config{
 				Name:              name,
 				OperatorNamespace: commontest.MemberOperatorNs,
@@ -1475,5 +1471,9 @@ func newController(hostCl runtimeclient.Client, s *runtime.Scheme, memberCl ...C
 			Client: cl,
 		}
 	}
-	return r
+	return Reconciler{
+		Client:         hostCl,
+		Scheme:         s,
+		MemberClusters: cluster.NewStaticMemberClusters(memberClusters),
+	}
 }
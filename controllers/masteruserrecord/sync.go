@@ -237,6 +237,9 @@ func alignReadiness(ctx context.Context, scheme *runtime.Scheme, hostClient runt
 			if err != nil {
 				return false, err
 			}
+			if locale := userSignup.Annotations[toolchainconfig.UserSignupLocaleAnnotationKey]; locale != "" {
+				keysAndVals[toolchainconfig.NotificationContextLocaleKey] = locale
+			}
 
 			// Lookup the UserTier
 			userTier := &toolchainv1alpha1.UserTier{}
@@ -8,6 +8,7 @@ import (
 	"time"
 
 	toolchainv1alpha1 "github.com/codeready-toolchain/api/api/v1alpha1"
+	"github.com/codeready-toolchain/host-operator/controllers/toolchainconfig"
 	"github.com/codeready-toolchain/host-operator/pkg/cluster"
 	. "github.com/codeready-toolchain/host-operator/test"
 	. "github.com/codeready-toolchain/host-operator/test/notification"
@@ -249,6 +250,23 @@ func TestSyncMurStatusWithUserAccountStatusWhenUpdated(t *testing.T) {
 			HasContext("DeactivationTimeoutDays", "(unlimited)"))
 	})
 
+	t.Run("successful and ready with the locale of the user", func(t *testing.T) {
+		// given
+		localizedUserSignup := userSignup.DeepCopy()
+		localizedUserSignup.Annotations[toolchainconfig.UserSignupLocaleAnnotationKey] = "pt-BR"
+		hostClient := test.NewFakeClient(t, localizedUserSignup, userTier, mur.DeepCopy(), readyToolchainStatus)
+		sync, _ := prepareSynchronizer(t, userAccount, mur.DeepCopy(), hostClient)
+
+		// when
+		err := sync.synchronizeStatus(context.TODO())
+
+		// then
+		require.NoError(t, err)
+		OnlyOneNotificationExists(t, hostClient, mur.Name, toolchainv1alpha1.NotificationTypeProvisioned,
+			HasContext("RegistrationURL", "https://registration.crt-placeholder.com"),
+			HasContext("Locale", "pt-BR"))
+	})
+
 	t.Run("failed on the host side when status update failed", func(t *testing.T) {
 		// given
 		hostClient := test.NewFakeClient(t, mur.DeepCopy(), userSignup, userTier, readyToolchainStatus)
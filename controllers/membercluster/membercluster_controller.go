@@ -0,0 +1,83 @@
+package membercluster
+
+import (
+	"context"
+	"time"
+
+	toolchainv1alpha1 "github.com/codeready-toolchain/api/api/v1alpha1"
+	"github.com/codeready-toolchain/host-operator/pkg/cluster"
+	commoncluster "github.com/codeready-toolchain/toolchain-common/pkg/cluster"
+
+	errs "github.com/pkg/errors"
+	"github.com/redhat-cop/operator-utils/pkg/util"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	runtimeclient "sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *Reconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		Named("membercluster").
+		// no predicate, so that the periodic updates of the status of the ToolchainClusters also pick up the rotated tokens
+		For(&toolchainv1alpha1.ToolchainCluster{}).
+		Complete(r)
+}
+
+// Reconciler adds, re-creates and removes the member clusters used by the controllers when the ToolchainClusters are created,
+// updated or deleted, so that the host operator doesn't need to be restarted when a member cluster joins or leaves the toolchain
+type Reconciler struct {
+	Client    runtimeclient.Client
+	Namespace string
+	Timeout   time.Duration
+	// MemberClusters are the sets of member clusters kept in sync with the ToolchainClusters, eg. with namespaced and cluster-scoped caches
+	MemberClusters []*cluster.MemberClusters
+}
+
+//+kubebuilder:rbac:groups=toolchain.dev.openshift.com,resources=toolchainclusters,verbs=get;list;watch
+
+// Reconcile ensures that the member cluster of the ToolchainCluster is in all the sets of member clusters, with up-to-date
+// connection details, or that it is removed from them if the ToolchainCluster was deleted.
+func (r *Reconciler) Reconcile(ctx context.Context, request ctrl.Request) (ctrl.Result, error) {
+	logger := log.FromContext(ctx)
+
+	toolchainCluster := &toolchainv1alpha1.ToolchainCluster{}
+	if err := r.Client.Get(ctx, types.NamespacedName{Namespace: r.Namespace, Name: request.Name}, toolchainCluster); err != nil {
+		if errors.IsNotFound(err) {
+			r.remove(ctx, request.Name)
+			return reconcile.Result{}, nil
+		}
+		return reconcile.Result{}, errs.Wrap(err, "unable to get the current ToolchainCluster")
+	}
+	if util.IsBeingDeleted(toolchainCluster) {
+		r.remove(ctx, request.Name)
+		return reconcile.Result{}, nil
+	}
+
+	for _, memberClusters := range r.MemberClusters {
+		// the config is created for each set, since the member clusters keep (and modify) it
+		config, err := commoncluster.NewClusterConfig(r.Client, toolchainCluster, r.Timeout)
+		if err != nil {
+			return reconcile.Result{}, errs.Wrapf(err, "unable to get the config of the member cluster '%s'", toolchainCluster.Name)
+		}
+		added, err := memberClusters.Ensure(config)
+		if err != nil {
+			return reconcile.Result{}, err
+		}
+		if added {
+			logger.Info("added the member cluster", "apiEndpoint", config.APIEndpoint)
+		}
+	}
+	return reconcile.Result{}, nil
+}
+
+func (r *Reconciler) remove(ctx context.Context, name string) {
+	for _, memberClusters := range r.MemberClusters {
+		if memberClusters.Remove(name) {
+			log.FromContext(ctx).Info("removed the member cluster")
+		}
+	}
+}
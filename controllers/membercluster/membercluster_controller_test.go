@@ -0,0 +1,172 @@
+package membercluster
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	toolchainv1alpha1 "github.com/codeready-toolchain/api/api/v1alpha1"
+	"github.com/codeready-toolchain/host-operator/pkg/cluster"
+	commoncluster "github.com/codeready-toolchain/toolchain-common/pkg/cluster"
+	"github.com/codeready-toolchain/toolchain-common/pkg/test"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	runtimeclient "sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+func TestReconcile(t *testing.T) {
+	// given
+	newToolchainCluster := func(t *testing.T) (*toolchainv1alpha1.ToolchainCluster, *corev1.Secret) {
+		return test.NewToolchainCluster(t, "member1", test.HostOperatorNs, test.MemberOperatorNs, "secret",
+			test.NewClusterStatus(toolchainv1alpha1.ConditionReady, corev1.ConditionTrue), false)
+	}
+
+	t.Run("member cluster added to all the sets", func(t *testing.T) {
+		// given
+		toolchainCluster, secret := newToolchainCluster(t)
+		r, factory := prepareReconcile(t, toolchainCluster, secret)
+
+		// when
+		_, err := r.Reconcile(context.TODO(), requestFor("member1"))
+
+		// then
+		require.NoError(t, err)
+		for _, memberClusters := range r.MemberClusters {
+			memberCluster, found := memberClusters.Get("member1")
+			require.True(t, found)
+			assert.Equal(t, "https://cluster.com", memberCluster.APIEndpoint)
+			assert.Equal(t, test.MemberOperatorNs, memberCluster.OperatorNamespace)
+		}
+		assert.Equal(t, 2, factory.created)
+
+		t.Run("unchanged", func(t *testing.T) {
+			// when
+			_, err := r.Reconcile(context.TODO(), requestFor("member1"))
+
+			// then
+			require.NoError(t, err)
+			assert.Equal(t, 2, factory.created)
+			assert.Equal(t, 0, factory.stopped)
+		})
+
+		t.Run("removed when the ToolchainCluster is deleted", func(t *testing.T) {
+			// given
+			require.NoError(t, r.Client.Delete(context.TODO(), toolchainCluster))
+
+			// when
+			_, err := r.Reconcile(context.TODO(), requestFor("member1"))
+
+			// then
+			require.NoError(t, err)
+			for _, memberClusters := range r.MemberClusters {
+				_, found := memberClusters.Get("member1")
+				assert.False(t, found)
+			}
+			assert.Equal(t, 2, factory.stopped)
+		})
+	})
+
+	t.Run("member cluster removed when the ToolchainCluster is being deleted", func(t *testing.T) {
+		// given
+		toolchainCluster, secret := newToolchainCluster(t)
+		r, factory := prepareReconcile(t, toolchainCluster, secret)
+		_, err := r.Reconcile(context.TODO(), requestFor("member1"))
+		require.NoError(t, err)
+		toolchainCluster.DeletionTimestamp = &metav1.Time{Time: time.Now()}
+		toolchainCluster.Finalizers = []string{"finalizer.toolchain.dev.openshift.com"}
+		r.Client = test.NewFakeClient(t, toolchainCluster, secret)
+
+		// when
+		_, err = r.Reconcile(context.TODO(), requestFor("member1"))
+
+		// then
+		require.NoError(t, err)
+		for _, memberClusters := range r.MemberClusters {
+			_, found := memberClusters.Get("member1")
+			assert.False(t, found)
+		}
+		assert.Equal(t, 2, factory.stopped)
+	})
+
+	t.Run("failures", func(t *testing.T) {
+		t.Run("missing secret", func(t *testing.T) {
+			// given
+			toolchainCluster, _ := newToolchainCluster(t)
+			r, factory := prepareReconcile(t, toolchainCluster)
+
+			// when
+			_, err := r.Reconcile(context.TODO(), requestFor("member1"))
+
+			// then
+			require.ErrorContains(t, err, "unable to get the config of the member cluster 'member1'")
+			assert.Equal(t, 0, factory.created)
+		})
+
+		t.Run("unable to get the ToolchainCluster", func(t *testing.T) {
+			// given
+			toolchainCluster, secret := newToolchainCluster(t)
+			r, _ := prepareReconcile(t, toolchainCluster, secret)
+			cl := r.Client.(*test.FakeClient)
+			cl.MockGet = func(ctx context.Context, key runtimeclient.ObjectKey, obj runtimeclient.Object, opts ...runtimeclient.GetOption) error {
+				return fmt.Errorf("mock error")
+			}
+
+			// when
+			_, err := r.Reconcile(context.TODO(), requestFor("member1"))
+
+			// then
+			require.EqualError(t, err, "unable to get the current ToolchainCluster: mock error")
+		})
+
+		t.Run("unable to create the member cluster", func(t *testing.T) {
+			// given
+			toolchainCluster, secret := newToolchainCluster(t)
+			r, factory := prepareReconcile(t, toolchainCluster, secret)
+			factory.err = fmt.Errorf("mock error")
+
+			// when
+			_, err := r.Reconcile(context.TODO(), requestFor("member1"))
+
+			// then
+			require.EqualError(t, err, "unable to create the member cluster 'member1': mock error")
+		})
+	})
+}
+
+func prepareReconcile(t *testing.T, objects ...runtimeclient.Object) (*Reconciler, *fakeFactory) {
+	factory := &fakeFactory{}
+	return &Reconciler{
+		Client:    test.NewFakeClient(t, objects...),
+		Namespace: test.HostOperatorNs,
+		Timeout:   3 * time.Second,
+		MemberClusters: []*cluster.MemberClusters{
+			cluster.NewMemberClusters(factory.newCluster),
+			cluster.NewMemberClusters(factory.newCluster),
+		},
+	}, factory
+}
+
+func requestFor(name string) reconcile.Request {
+	return reconcile.Request{NamespacedName: test.NamespacedName(test.HostOperatorNs, name)}
+}
+
+type fakeFactory struct {
+	err     error
+	created int
+	stopped int
+}
+
+func (f *fakeFactory) newCluster(config *commoncluster.Config) (cluster.Cluster, context.CancelFunc, error) {
+	if f.err != nil {
+		return cluster.Cluster{}, nil, f.err
+	}
+	f.created++
+	return cluster.Cluster{Config: config}, func() {
+		f.stopped++
+	}, nil
+}
@@ -0,0 +1,86 @@
+package notification
+
+import (
+	"encoding/json"
+	"math/rand"
+	"time"
+
+	toolchainv1alpha1 "github.com/codeready-toolchain/api/api/v1alpha1"
+)
+
+const (
+	// DeliveryAttemptsAnnotationKey is set on a Notification whose delivery failed. The value is the JSON list of the failed attempts.
+	DeliveryAttemptsAnnotationKey = toolchainv1alpha1.LabelKeyPrefix + "delivery-attempts"
+
+	// NotificationDeadLettered is the condition type set on a Notification which won't be delivered because of a permanent error,
+	// or because all the delivery attempts failed
+	NotificationDeadLettered toolchainv1alpha1.ConditionType = "DeadLettered"
+
+	// NotificationPermanentErrorReason is the reason of the NotificationDeadLettered condition when the delivery failed with a permanent error
+	NotificationPermanentErrorReason = "PermanentError"
+	// NotificationMaxAttemptsReachedReason is the reason of the NotificationDeadLettered condition when all the delivery attempts failed
+	NotificationMaxAttemptsReachedReason = "MaxAttemptsReached"
+
+	// maxRecordedErrorLength limits the size of the errors kept in the attempt history
+	maxRecordedErrorLength = 256
+)
+
+// DeliveryAttempt is a failed attempt to deliver a notification
+type DeliveryAttempt struct {
+	Time      time.Time `json:"time"`
+	Error     string    `json:"error"`
+	Permanent bool      `json:"permanent,omitempty"`
+}
+
+// getDeliveryAttempts returns the failed delivery attempts recorded on the given notification
+func getDeliveryAttempts(notification *toolchainv1alpha1.Notification) []DeliveryAttempt {
+	v, found := notification.Annotations[DeliveryAttemptsAnnotationKey]
+	if !found {
+		return nil
+	}
+	attempts := []DeliveryAttempt{}
+	if err := json.Unmarshal([]byte(v), &attempts); err != nil {
+		return nil
+	}
+	return attempts
+}
+
+// addDeliveryAttempt records the given failed delivery attempt on the notification, and returns all the failed attempts
+func addDeliveryAttempt(notification *toolchainv1alpha1.Notification, attempt DeliveryAttempt) ([]DeliveryAttempt, error) {
+	if len(attempt.Error) > maxRecordedErrorLength {
+		attempt.Error = attempt.Error[:maxRecordedErrorLength]
+	}
+	attempts := append(getDeliveryAttempts(notification), attempt)
+	v, err := json.Marshal(attempts)
+	if err != nil {
+		return nil, err
+	}
+	if notification.Annotations == nil {
+		notification.Annotations = map[string]string{}
+	}
+	notification.Annotations[DeliveryAttemptsAnnotationKey] = string(v)
+	return attempts, nil
+}
+
+// minDeliveryBackoff returns the minimum delay before the next attempt, after the given number of failed attempts.
+// The delay doubles after each attempt, up to the given maximum.
+func minDeliveryBackoff(failedAttempts int, initial, max time.Duration) time.Duration {
+	backoff := initial
+	for i := 1; i < failedAttempts && backoff < max; i++ {
+		backoff *= 2
+	}
+	if backoff > max {
+		backoff = max
+	}
+	return backoff
+}
+
+// deliveryBackoff returns the delay before the next attempt, after the given number of failed attempts.
+// This is the minDeliveryBackoff with up to 20% of random jitter, so that the notifications which failed together are not all retried at once.
+func deliveryBackoff(failedAttempts int, initial, max time.Duration) time.Duration {
+	backoff := minDeliveryBackoff(failedAttempts, initial, max)
+	if jitter := int64(backoff) / 5; jitter > 0 {
+		backoff += time.Duration(rand.Int63n(jitter)) // nolint:gosec
+	}
+	return backoff
+}
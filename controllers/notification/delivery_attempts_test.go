@@ -0,0 +1,24 @@
+package notification
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDeliveryBackoff(t *testing.T) {
+	for attempts, expected := range map[int]time.Duration{
+		1: 30 * time.Second,
+		2: time.Minute,
+		3: 2 * time.Minute,
+		4: 4 * time.Minute,
+		5: 5 * time.Minute, // capped by the maximum backoff
+		9: 5 * time.Minute,
+	} {
+		assert.Equal(t, expected, minDeliveryBackoff(attempts, 30*time.Second, 5*time.Minute))
+		backoff := deliveryBackoff(attempts, 30*time.Second, 5*time.Minute)
+		assert.GreaterOrEqual(t, backoff, expected)
+		assert.Less(t, backoff, expected+expected/5)
+	}
+}
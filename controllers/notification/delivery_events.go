@@ -0,0 +1,179 @@
+package notification
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	toolchainv1alpha1 "github.com/codeready-toolchain/api/api/v1alpha1"
+	"github.com/codeready-toolchain/host-operator/pkg/metrics"
+	"github.com/codeready-toolchain/toolchain-common/pkg/condition"
+	"github.com/codeready-toolchain/toolchain-common/pkg/hash"
+
+	errs "github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/client-go/util/retry"
+	runtimeclient "sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+const (
+	// MessageIDAnnotationKey is set on a sent Notification, with the ID of the message returned by (or given to) the email provider
+	MessageIDAnnotationKey = toolchainv1alpha1.LabelKeyPrefix + "message-id"
+	// MessageIDHashLabelKey is set on a sent Notification, with the hash of its message ID, so that the Notification can be found
+	// when the email provider reports a delivery event for the message
+	MessageIDHashLabelKey = toolchainv1alpha1.LabelKeyPrefix + "message-id-hash"
+
+	// NotificationDelivered is the condition type set on a sent Notification once the email provider reported the outcome of the delivery
+	NotificationDelivered toolchainv1alpha1.ConditionType = "Delivered"
+
+	// NotificationDeliveredReason is the reason of the NotificationDelivered condition when the message was accepted by the recipient's server
+	NotificationDeliveredReason = "Delivered"
+	// NotificationDeliveryDeferredReason is the reason of the NotificationDelivered condition when the delivery failed temporarily
+	// and is retried by the email provider
+	NotificationDeliveryDeferredReason = "DeliveryDeferred"
+	// NotificationBouncedReason is the reason of the NotificationDelivered condition when the message was rejected by the recipient's server
+	NotificationBouncedReason = "Bounced"
+	// NotificationComplainedReason is the reason of the NotificationDelivered condition when the recipient reported the message as spam
+	NotificationComplainedReason = "Complained"
+	// NotificationRecipientSuppressedReason is the reason of the NotificationDeadLettered condition when the recipient is in the suppression list
+	NotificationRecipientSuppressedReason = "RecipientSuppressed"
+)
+
+// DeliveryEventType is the type of delivery event reported by the email provider
+type DeliveryEventType string
+
+const (
+	// DeliveryEventDelivered means that the message was accepted by the recipient's server
+	DeliveryEventDelivered DeliveryEventType = "delivered"
+	// DeliveryEventDeferred means that the delivery failed temporarily, and that the email provider will try again
+	DeliveryEventDeferred DeliveryEventType = "deferred"
+	// DeliveryEventBounced means that the message was permanently rejected by the recipient's server
+	DeliveryEventBounced DeliveryEventType = "bounced"
+	// DeliveryEventComplained means that the recipient reported the message as spam
+	DeliveryEventComplained DeliveryEventType = "complained"
+)
+
+func (t DeliveryEventType) isKnown() bool {
+	switch t {
+	case DeliveryEventDelivered, DeliveryEventDeferred, DeliveryEventBounced, DeliveryEventComplained:
+		return true
+	}
+	return false
+}
+
+// DeliveryEvent is the outcome of the delivery of a message, as reported by the email provider
+type DeliveryEvent struct {
+	MessageID string            `json:"messageID"`
+	Recipient string            `json:"recipient,omitempty"`
+	Type      DeliveryEventType `json:"event"`
+	Reason    string            `json:"reason,omitempty"`
+}
+
+// setMessageID records the ID of the message sent for the given notification
+func setMessageID(notification *toolchainv1alpha1.Notification, id string) {
+	id = normalizeMessageID(id)
+	if id == "" {
+		return
+	}
+	if notification.Annotations == nil {
+		notification.Annotations = map[string]string{}
+	}
+	notification.Annotations[MessageIDAnnotationKey] = id
+	if notification.Labels == nil {
+		notification.Labels = map[string]string{}
+	}
+	notification.Labels[MessageIDHashLabelKey] = hash.EncodeString(id)
+}
+
+// normalizeMessageID removes the angle brackets around the given message ID, which are present in the `Message-ID` header
+// but not always in the IDs reported by the email providers
+func normalizeMessageID(id string) string {
+	return strings.Trim(strings.TrimSpace(id), "<>")
+}
+
+// DeliveryEventProcessor updates the status of the Notifications with the delivery events reported by the email provider,
+// and adds the recipients of the messages which bounced or were reported as spam to the suppression list
+type DeliveryEventProcessor struct {
+	Client    runtimeclient.Client
+	Namespace string
+}
+
+// Process applies the given delivery event
+func (p *DeliveryEventProcessor) Process(ctx context.Context, event DeliveryEvent) error {
+	if !event.Type.isKnown() {
+		return fmt.Errorf("unknown delivery event '%s'", event.Type)
+	}
+	logger := log.FromContext(ctx).WithValues("message-id", event.MessageID, "event", event.Type)
+	metrics.NotificationDeliveryEventsTotal.WithLabelValues(string(event.Type)).Inc()
+
+	notifications := &toolchainv1alpha1.NotificationList{}
+	if id := normalizeMessageID(event.MessageID); id != "" {
+		if err := p.Client.List(ctx, notifications, runtimeclient.InNamespace(p.Namespace),
+			runtimeclient.MatchingLabels{MessageIDHashLabelKey: hash.EncodeString(id)}); err != nil {
+			return errs.Wrap(err, "unable to list the notifications")
+		}
+	}
+	if len(notifications.Items) == 0 {
+		// the notification may have already been deleted
+		logger.Info("no notification found for the delivery event")
+	}
+
+	recipient := event.Recipient
+	for i := range notifications.Items {
+		notification := &notifications.Items[i]
+		if recipient == "" {
+			recipient = notification.Spec.Recipient
+		}
+		if err := p.updateStatus(ctx, notification, event); err != nil {
+			return err
+		}
+	}
+
+	if (event.Type == DeliveryEventBounced || event.Type == DeliveryEventComplained) && recipient != "" {
+		logger.Info("adding the recipient to the suppression list")
+		return SuppressRecipient(ctx, p.Client, p.Namespace, recipient, string(event.Type))
+	}
+	return nil
+}
+
+// updateStatus sets the NotificationDelivered condition according to the given event
+func (p *DeliveryEventProcessor) updateStatus(ctx context.Context, notification *toolchainv1alpha1.Notification, event DeliveryEvent) error {
+	cond := toolchainv1alpha1.Condition{
+		Type:    NotificationDelivered,
+		Status:  corev1.ConditionFalse,
+		Message: event.Reason,
+	}
+	switch event.Type {
+	case DeliveryEventDelivered:
+		cond.Status = corev1.ConditionTrue
+		cond.Reason = NotificationDeliveredReason
+	case DeliveryEventDeferred:
+		cond.Reason = NotificationDeliveryDeferredReason
+	case DeliveryEventBounced:
+		cond.Reason = NotificationBouncedReason
+	case DeliveryEventComplained:
+		cond.Reason = NotificationComplainedReason
+	}
+
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		if err := p.Client.Get(ctx, runtimeclient.ObjectKeyFromObject(notification), notification); err != nil {
+			if errors.IsNotFound(err) {
+				return nil
+			}
+			return err
+		}
+		// a late notice of a temporary failure does not override the final outcome of the delivery
+		if current, found := condition.FindConditionByType(notification.Status.Conditions, NotificationDelivered); found &&
+			event.Type == DeliveryEventDeferred && current.Reason != NotificationDeliveryDeferredReason {
+			return nil
+		}
+		var updated bool
+		notification.Status.Conditions, updated = condition.AddOrUpdateStatusConditions(notification.Status.Conditions, cond)
+		if !updated {
+			return nil
+		}
+		return p.Client.Status().Update(ctx, notification)
+	})
+}
@@ -0,0 +1,227 @@
+package notification
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/codeready-toolchain/host-operator/controllers/toolchainconfig"
+
+	runtimeclient "sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+const (
+	// DeliveryEventsPath is the path of the endpoint which receives the delivery events in the generic JSON format (see DeliveryEvent).
+	// The requests are signed like the payloads of the notification webhooks, with the WebhookTimestampHeader and WebhookSignatureHeader headers.
+	DeliveryEventsPath = "/notifications/events"
+	// MailgunDeliveryEventsPath is the path of the endpoint which receives the events of the Mailgun webhooks
+	MailgunDeliveryEventsPath = "/notifications/events/mailgun"
+
+	// maxDeliveryEventAge is how old the signature of a delivery event can be, so that a captured request cannot be replayed later
+	maxDeliveryEventAge = 5 * time.Minute
+	// maxDeliveryEventSize is the maximum size of the body of a delivery event request
+	maxDeliveryEventSize = 1 << 20
+)
+
+// DeliveryEventsServer receives the delivery events (delivered, bounced, etc.) reported by the email provider, and passes them
+// to the DeliveryEventProcessor
+type DeliveryEventsServer struct {
+	Client      runtimeclient.Client
+	Namespace   string
+	BindAddress string
+}
+
+// Handler returns the handler of the delivery event endpoints
+func (s *DeliveryEventsServer) Handler() http.Handler {
+	processor := &DeliveryEventProcessor{Client: s.Client, Namespace: s.Namespace}
+	mux := http.NewServeMux()
+	mux.Handle(DeliveryEventsPath, s.handle(processor, parseDeliveryEvent))
+	mux.Handle(MailgunDeliveryEventsPath, s.handle(processor, parseMailgunDeliveryEvent))
+	return mux
+}
+
+// Start runs the server until the given context is done
+func (s *DeliveryEventsServer) Start(ctx context.Context) error {
+	logger := log.FromContext(ctx).WithName("delivery-events-server")
+	srv := &http.Server{
+		Addr:              s.BindAddress,
+		Handler:           s.Handler(),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		if err := srv.Shutdown(shutdownCtx); err != nil {
+			logger.Error(err, "unable to shut down the delivery events server")
+		}
+	}()
+	logger.Info("starting the delivery events server", "address", s.BindAddress)
+	if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+// NeedLeaderElection returns false, so that the events are received by all the replicas
+func (s *DeliveryEventsServer) NeedLeaderElection() bool {
+	return false
+}
+
+// deliveryEventParser verifies the signature of the given request with the given key, and returns the delivery event it contains,
+// or nil if the event is not a delivery event (eg. the message was opened)
+type deliveryEventParser func(signingKey string, header http.Header, body []byte) (*DeliveryEvent, error)
+
+// errInvalidSignature is returned by the deliveryEventParsers when the signature of the request is missing, invalid or expired
+var errInvalidSignature = errors.New("invalid signature")
+
+func (s *DeliveryEventsServer) handle(processor *DeliveryEventProcessor, parse deliveryEventParser) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		logger := log.FromContext(req.Context()).WithName("delivery-events-server")
+		if req.Method != http.MethodPost {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		body, err := io.ReadAll(io.LimitReader(req.Body, maxDeliveryEventSize))
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		config, err := toolchainconfig.GetToolchainConfig(s.Client)
+		if err != nil {
+			logger.Error(err, "unable to get ToolchainConfig")
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		signingKey := config.Notifications().DeliveryEventsSigningKey()
+		if signingKey == "" {
+			logger.Info("rejecting the delivery event: no signing key is configured")
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+
+		event, err := parse(signingKey, req.Header, body)
+		switch {
+		case errors.Is(err, errInvalidSignature):
+			w.WriteHeader(http.StatusForbidden)
+			return
+		case err != nil:
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		case event == nil:
+			// not an event we're interested in
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+
+		if err := processor.Process(req.Context(), *event); err != nil {
+			logger.Error(err, "unable to process the delivery event", "message-id", event.MessageID)
+			// let the email provider send the event again
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}
+}
+
+// parseDeliveryEvent parses a delivery event in the generic JSON format
+func parseDeliveryEvent(signingKey string, header http.Header, body []byte) (*DeliveryEvent, error) {
+	timestamp := header.Get(WebhookTimestampHeader)
+	signature := strings.TrimPrefix(header.Get(WebhookSignatureHeader), "sha256=")
+	if !validTimestamp(timestamp) || !hmac.Equal([]byte(signature), []byte(SignWebhookPayload(signingKey, timestamp, body))) {
+		return nil, errInvalidSignature
+	}
+	event := &DeliveryEvent{}
+	if err := json.Unmarshal(body, event); err != nil {
+		return nil, err
+	}
+	if !event.Type.isKnown() {
+		return nil, fmt.Errorf("unknown delivery event '%s'", event.Type)
+	}
+	return event, nil
+}
+
+// mailgunWebhookPayload is the payload of the Mailgun webhooks
+type mailgunWebhookPayload struct {
+	Signature struct {
+		Timestamp string `json:"timestamp"`
+		Token     string `json:"token"`
+		Signature string `json:"signature"`
+	} `json:"signature"`
+	EventData struct {
+		Event     string `json:"event"`
+		Severity  string `json:"severity"`
+		Recipient string `json:"recipient"`
+		Reason    string `json:"reason"`
+		Message   struct {
+			Headers struct {
+				MessageID string `json:"message-id"`
+			} `json:"headers"`
+		} `json:"message"`
+		DeliveryStatus struct {
+			Message     string `json:"message"`
+			Description string `json:"description"`
+		} `json:"delivery-status"`
+	} `json:"event-data"`
+}
+
+// parseMailgunDeliveryEvent parses the payload of a Mailgun webhook, whose signature is the HMAC-SHA256 of the timestamp and the token
+func parseMailgunDeliveryEvent(signingKey string, _ http.Header, body []byte) (*DeliveryEvent, error) {
+	payload := &mailgunWebhookPayload{}
+	if err := json.Unmarshal(body, payload); err != nil {
+		return nil, err
+	}
+	mac := hmac.New(sha256.New, []byte(signingKey))
+	mac.Write([]byte(payload.Signature.Timestamp))
+	mac.Write([]byte(payload.Signature.Token))
+	if !validTimestamp(payload.Signature.Timestamp) || !hmac.Equal([]byte(payload.Signature.Signature), []byte(hex.EncodeToString(mac.Sum(nil)))) {
+		return nil, errInvalidSignature
+	}
+
+	data := payload.EventData
+	event := &DeliveryEvent{
+		MessageID: data.Message.Headers.MessageID,
+		Recipient: data.Recipient,
+		Reason:    data.DeliveryStatus.Message,
+	}
+	if event.Reason == "" {
+		event.Reason = data.DeliveryStatus.Description
+	}
+	switch {
+	case data.Event == "delivered":
+		event.Type = DeliveryEventDelivered
+	case data.Event == "failed" && data.Severity == "permanent":
+		event.Type = DeliveryEventBounced
+		if event.Reason == "" {
+			event.Reason = data.Reason
+		}
+	case data.Event == "failed":
+		event.Type = DeliveryEventDeferred
+	case data.Event == "complained":
+		event.Type = DeliveryEventComplained
+	default:
+		// accepted, opened, clicked, etc.
+		return nil, nil
+	}
+	return event, nil
+}
+
+// validTimestamp returns true if the given unix time is recent enough
+func validTimestamp(timestamp string) bool {
+	seconds, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return false
+	}
+	age := time.Since(time.Unix(seconds, 0))
+	return age < maxDeliveryEventAge && age > -maxDeliveryEventAge
+}
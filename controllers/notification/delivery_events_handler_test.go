@@ -0,0 +1,252 @@
+package notification
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	toolchainv1alpha1 "github.com/codeready-toolchain/api/api/v1alpha1"
+	"github.com/codeready-toolchain/host-operator/controllers/toolchainconfig"
+	ntest "github.com/codeready-toolchain/host-operator/test/notification"
+	commonconfig "github.com/codeready-toolchain/toolchain-common/pkg/configuration"
+	"github.com/codeready-toolchain/toolchain-common/pkg/test"
+	testconfig "github.com/codeready-toolchain/toolchain-common/pkg/test/config"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	runtimeclient "sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const testSigningKey = "s3cr3t"
+
+func TestDeliveryEventsHandler(t *testing.T) {
+	// given
+	restore := test.SetEnvVarAndRestore(t, commonconfig.WatchNamespaceEnvVar, test.HostOperatorNs)
+	t.Cleanup(restore)
+	notification := &toolchainv1alpha1.Notification{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "jane-provisioned",
+			Namespace: test.HostOperatorNs,
+		},
+		Spec: toolchainv1alpha1.NotificationSpec{
+			Recipient: "jane@acme.com",
+		},
+	}
+	setMessageID(notification, "<abc@mg.foo.com>")
+
+	t.Run("generic events", func(t *testing.T) {
+		t.Run("valid signature", func(t *testing.T) {
+			// given
+			server, cl := newDeliveryEventsServer(t, notification.DeepCopy())
+			body := []byte(`{"messageID": "<abc@mg.foo.com>", "event": "bounced", "reason": "no such user"}`)
+
+			// when
+			resp := post(server, DeliveryEventsPath, body, signedHeaders(testSigningKey, time.Now(), body))
+
+			// then
+			assert.Equal(t, http.StatusOK, resp.Code)
+			ntest.AssertThatNotification(t, notification.Name, cl).
+				HasConditions(deliveryCond(corev1.ConditionFalse, NotificationBouncedReason, "no such user"))
+			assertSuppressed(t, cl, "jane@acme.com", "bounced")
+		})
+
+		t.Run("unknown event", func(t *testing.T) {
+			// given
+			server, _ := newDeliveryEventsServer(t)
+			body := []byte(`{"messageID": "abc@mg.foo.com", "event": "opened"}`)
+
+			// when
+			resp := post(server, DeliveryEventsPath, body, signedHeaders(testSigningKey, time.Now(), body))
+
+			// then
+			assert.Equal(t, http.StatusBadRequest, resp.Code)
+		})
+
+		t.Run("invalid body", func(t *testing.T) {
+			// given
+			server, _ := newDeliveryEventsServer(t)
+			body := []byte(`banana`)
+
+			// when
+			resp := post(server, DeliveryEventsPath, body, signedHeaders(testSigningKey, time.Now(), body))
+
+			// then
+			assert.Equal(t, http.StatusBadRequest, resp.Code)
+		})
+
+		for name, headers := range map[string]http.Header{
+			"missing signature": {},
+			"invalid signature": signedHeaders("other", time.Now(), []byte(`{"messageID": "abc@mg.foo.com", "event": "bounced"}`)),
+			"expired signature": signedHeaders(testSigningKey, time.Now().Add(-10*time.Minute), []byte(`{"messageID": "abc@mg.foo.com", "event": "bounced"}`)),
+		} {
+			t.Run(name, func(t *testing.T) {
+				// given
+				server, cl := newDeliveryEventsServer(t, notification.DeepCopy())
+
+				// when
+				resp := post(server, DeliveryEventsPath, []byte(`{"messageID": "abc@mg.foo.com", "event": "bounced"}`), headers)
+
+				// then
+				assert.Equal(t, http.StatusForbidden, resp.Code)
+				assertNotSuppressed(t, cl, "jane@acme.com")
+			})
+		}
+	})
+
+	t.Run("mailgun events", func(t *testing.T) {
+		for event, expected := range map[string]toolchainv1alpha1.Condition{
+			`"event": "delivered"`: deliveryCond(corev1.ConditionTrue, NotificationDeliveredReason, ""),
+			`"event": "failed", "severity": "temporary", "delivery-status": {"message": "mailbox full"}`: deliveryCond(corev1.ConditionFalse, NotificationDeliveryDeferredReason, "mailbox full"),
+			`"event": "failed", "severity": "permanent", "delivery-status": {"message": "no such user"}`: deliveryCond(corev1.ConditionFalse, NotificationBouncedReason, "no such user"),
+			`"event": "failed", "severity": "permanent", "reason": "suppress-bounce"`:                    deliveryCond(corev1.ConditionFalse, NotificationBouncedReason, "suppress-bounce"),
+			`"event": "complained"`: deliveryCond(corev1.ConditionFalse, NotificationComplainedReason, ""),
+		} {
+			t.Run(event, func(t *testing.T) {
+				// given
+				server, cl := newDeliveryEventsServer(t, notification.DeepCopy())
+				body := mailgunPayload(testSigningKey, time.Now(), event)
+
+				// when
+				resp := post(server, MailgunDeliveryEventsPath, body, http.Header{})
+
+				// then
+				assert.Equal(t, http.StatusOK, resp.Code)
+				ntest.AssertThatNotification(t, notification.Name, cl).
+					HasConditions(expected)
+			})
+		}
+
+		t.Run("ignored event", func(t *testing.T) {
+			// given
+			server, cl := newDeliveryEventsServer(t, notification.DeepCopy())
+			body := mailgunPayload(testSigningKey, time.Now(), `"event": "opened"`)
+
+			// when
+			resp := post(server, MailgunDeliveryEventsPath, body, http.Header{})
+
+			// then
+			assert.Equal(t, http.StatusOK, resp.Code)
+			ntest.AssertThatNotification(t, notification.Name, cl).
+				HasConditions()
+		})
+
+		t.Run("invalid signature", func(t *testing.T) {
+			// given
+			server, cl := newDeliveryEventsServer(t, notification.DeepCopy())
+			body := mailgunPayload("other", time.Now(), `"event": "complained"`)
+
+			// when
+			resp := post(server, MailgunDeliveryEventsPath, body, http.Header{})
+
+			// then
+			assert.Equal(t, http.StatusForbidden, resp.Code)
+			assertNotSuppressed(t, cl, "jane@acme.com")
+		})
+	})
+
+	t.Run("failures", func(t *testing.T) {
+		t.Run("no signing key", func(t *testing.T) {
+			// given
+			toolchainConfig := commonconfig.NewToolchainConfigObjWithReset(t)
+			cl := test.NewFakeClient(t, toolchainConfig)
+			server := &DeliveryEventsServer{Client: cl, Namespace: test.HostOperatorNs}
+			body := []byte(`{"messageID": "abc@mg.foo.com", "event": "bounced"}`)
+
+			// when
+			resp := post(server, DeliveryEventsPath, body, signedHeaders("", time.Now(), body))
+
+			// then
+			assert.Equal(t, http.StatusForbidden, resp.Code)
+		})
+
+		t.Run("invalid method", func(t *testing.T) {
+			// given
+			server, _ := newDeliveryEventsServer(t)
+			req := httptest.NewRequest(http.MethodGet, DeliveryEventsPath, nil)
+			resp := httptest.NewRecorder()
+
+			// when
+			server.Handler().ServeHTTP(resp, req)
+
+			// then
+			assert.Equal(t, http.StatusMethodNotAllowed, resp.Code)
+		})
+
+		t.Run("event not processed", func(t *testing.T) {
+			// given
+			server, cl := newDeliveryEventsServer(t, notification.DeepCopy())
+			cl.MockList = func(ctx context.Context, list runtimeclient.ObjectList, opts ...runtimeclient.ListOption) error {
+				if _, ok := list.(*toolchainv1alpha1.NotificationList); ok {
+					return assert.AnError
+				}
+				return cl.Client.List(ctx, list, opts...)
+			}
+			body := mailgunPayload(testSigningKey, time.Now(), `"event": "delivered"`)
+
+			// when
+			resp := post(server, MailgunDeliveryEventsPath, body, http.Header{})
+
+			// then
+			assert.Equal(t, http.StatusInternalServerError, resp.Code)
+		})
+	})
+}
+
+func newDeliveryEventsServer(t *testing.T, initObjs ...runtimeclient.Object) (*DeliveryEventsServer, *test.FakeClient) {
+	toolchainConfig := commonconfig.NewToolchainConfigObjWithReset(t,
+		testconfig.Notifications().Secret().Ref("host-operator-secret"))
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "host-operator-secret",
+			Namespace: test.HostOperatorNs,
+		},
+		Data: map[string][]byte{
+			"deliveryEventsSigningKey": []byte(testSigningKey),
+		},
+	}
+	cl := test.NewFakeClient(t, append(initObjs, toolchainConfig, secret)...)
+	_, err := toolchainconfig.GetToolchainConfig(cl)
+	require.NoError(t, err)
+	return &DeliveryEventsServer{
+		Client:    cl,
+		Namespace: test.HostOperatorNs,
+	}, cl
+}
+
+func post(server *DeliveryEventsServer, path string, body []byte, headers http.Header) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, path, bytes.NewReader(body))
+	req.Header = headers
+	resp := httptest.NewRecorder()
+	server.Handler().ServeHTTP(resp, req)
+	return resp
+}
+
+func signedHeaders(key string, at time.Time, body []byte) http.Header {
+	timestamp := strconv.FormatInt(at.Unix(), 10)
+	return http.Header{
+		WebhookTimestampHeader: {timestamp},
+		WebhookSignatureHeader: {"sha256=" + SignWebhookPayload(key, timestamp, body)},
+	}
+}
+
+// mailgunPayload returns the payload of a Mailgun webhook, with the given fields of the event data
+func mailgunPayload(key string, at time.Time, eventData string) []byte {
+	timestamp := strconv.FormatInt(at.Unix(), 10)
+	token := "a8ce0edb2dd8301dee6c2405235584e45aa91d1e9f979f3de0"
+	mac := hmac.New(sha256.New, []byte(key))
+	mac.Write([]byte(timestamp + token))
+	return []byte(fmt.Sprintf(`{
+		"signature": {"timestamp": "%s", "token": "%s", "signature": "%s"},
+		"event-data": {%s, "recipient": "jane@acme.com", "message": {"headers": {"message-id": "abc@mg.foo.com"}}}
+	}`, timestamp, token, hex.EncodeToString(mac.Sum(nil)), eventData))
+}
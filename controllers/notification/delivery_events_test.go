@@ -0,0 +1,236 @@
+package notification
+
+import (
+	"context"
+	"testing"
+
+	toolchainv1alpha1 "github.com/codeready-toolchain/api/api/v1alpha1"
+	"github.com/codeready-toolchain/host-operator/pkg/metrics"
+	ntest "github.com/codeready-toolchain/host-operator/test/notification"
+	"github.com/codeready-toolchain/toolchain-common/pkg/test"
+	metricstest "github.com/codeready-toolchain/toolchain-common/pkg/test/metrics"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	runtimeclient "sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func TestSetMessageID(t *testing.T) {
+	t.Run("id is recorded without angle brackets", func(t *testing.T) {
+		// given
+		notification := &toolchainv1alpha1.Notification{}
+
+		// when
+		setMessageID(notification, "<20240101.abc@mg.foo.com>")
+
+		// then
+		assert.Equal(t, "20240101.abc@mg.foo.com", notification.Annotations[MessageIDAnnotationKey])
+		assert.NotEmpty(t, notification.Labels[MessageIDHashLabelKey])
+	})
+
+	t.Run("empty id is ignored", func(t *testing.T) {
+		// given
+		notification := &toolchainv1alpha1.Notification{}
+
+		// when
+		setMessageID(notification, "")
+
+		// then
+		assert.Empty(t, notification.Annotations)
+		assert.Empty(t, notification.Labels)
+	})
+}
+
+func TestDeliveryEventProcessor(t *testing.T) {
+	newSentNotification := func(name, messageID string) *toolchainv1alpha1.Notification {
+		notification := &toolchainv1alpha1.Notification{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      name,
+				Namespace: test.HostOperatorNs,
+			},
+			Spec: toolchainv1alpha1.NotificationSpec{
+				Recipient: "jane@acme.com",
+			},
+			Status: toolchainv1alpha1.NotificationStatus{
+				Conditions: []toolchainv1alpha1.Condition{sentCond()},
+			},
+		}
+		setMessageID(notification, messageID)
+		return notification
+	}
+
+	t.Run("delivered", func(t *testing.T) {
+		// given
+		metrics.Reset()
+		notification := newSentNotification("jane-provisioned", "<abc@mg.foo.com>")
+		processor, cl := newDeliveryEventProcessor(t, notification)
+
+		// when
+		err := processor.Process(context.TODO(), DeliveryEvent{MessageID: "abc@mg.foo.com", Type: DeliveryEventDelivered})
+
+		// then
+		require.NoError(t, err)
+		ntest.AssertThatNotification(t, notification.Name, cl).
+			HasConditions(sentCond(), deliveryCond(corev1.ConditionTrue, NotificationDeliveredReason, ""))
+		metricstest.AssertMetricsCounterEquals(t, 1, metrics.NotificationDeliveryEventsTotal.WithLabelValues("delivered"))
+		assertNotSuppressed(t, cl, "jane@acme.com")
+
+		t.Run("late deferred event is ignored", func(t *testing.T) {
+			// when
+			err := processor.Process(context.TODO(), DeliveryEvent{MessageID: "abc@mg.foo.com", Type: DeliveryEventDeferred, Reason: "mailbox full"})
+
+			// then
+			require.NoError(t, err)
+			ntest.AssertThatNotification(t, notification.Name, cl).
+				HasConditions(sentCond(), deliveryCond(corev1.ConditionTrue, NotificationDeliveredReason, ""))
+		})
+	})
+
+	t.Run("deferred and then delivered", func(t *testing.T) {
+		// given
+		notification := newSentNotification("jane-provisioned", "abc@mg.foo.com")
+		processor, cl := newDeliveryEventProcessor(t, notification)
+
+		// when
+		err := processor.Process(context.TODO(), DeliveryEvent{MessageID: "<abc@mg.foo.com>", Type: DeliveryEventDeferred, Reason: "mailbox full"})
+
+		// then
+		require.NoError(t, err)
+		ntest.AssertThatNotification(t, notification.Name, cl).
+			HasConditions(sentCond(), deliveryCond(corev1.ConditionFalse, NotificationDeliveryDeferredReason, "mailbox full"))
+
+		t.Run("delivered", func(t *testing.T) {
+			// when
+			err := processor.Process(context.TODO(), DeliveryEvent{MessageID: "abc@mg.foo.com", Type: DeliveryEventDelivered})
+
+			// then
+			require.NoError(t, err)
+			ntest.AssertThatNotification(t, notification.Name, cl).
+				HasConditions(sentCond(), deliveryCond(corev1.ConditionTrue, NotificationDeliveredReason, ""))
+		})
+	})
+
+	t.Run("bounced", func(t *testing.T) {
+		// given
+		metrics.Reset()
+		notification := newSentNotification("jane-provisioned", "abc@mg.foo.com")
+		other := newSentNotification("john-provisioned", "def@mg.foo.com")
+		processor, cl := newDeliveryEventProcessor(t, notification, other)
+
+		// when
+		err := processor.Process(context.TODO(), DeliveryEvent{MessageID: "abc@mg.foo.com", Type: DeliveryEventBounced, Reason: "no such user"})
+
+		// then
+		require.NoError(t, err)
+		ntest.AssertThatNotification(t, notification.Name, cl).
+			HasConditions(sentCond(), deliveryCond(corev1.ConditionFalse, NotificationBouncedReason, "no such user"))
+		ntest.AssertThatNotification(t, other.Name, cl).
+			HasConditions(sentCond())
+		metricstest.AssertMetricsCounterEquals(t, 1, metrics.NotificationDeliveryEventsTotal.WithLabelValues("bounced"))
+		// the recipient is taken from the notification
+		assertSuppressed(t, cl, "jane@acme.com", "bounced")
+	})
+
+	t.Run("complained", func(t *testing.T) {
+		// given
+		notification := newSentNotification("jane-provisioned", "abc@mg.foo.com")
+		processor, cl := newDeliveryEventProcessor(t, notification)
+
+		// when
+		err := processor.Process(context.TODO(), DeliveryEvent{MessageID: "abc@mg.foo.com", Recipient: "Jane@acme.com", Type: DeliveryEventComplained})
+
+		// then
+		require.NoError(t, err)
+		ntest.AssertThatNotification(t, notification.Name, cl).
+			HasConditions(sentCond(), deliveryCond(corev1.ConditionFalse, NotificationComplainedReason, ""))
+		assertSuppressed(t, cl, "jane@acme.com", "complained")
+	})
+
+	t.Run("bounced notification already deleted", func(t *testing.T) {
+		// given
+		processor, cl := newDeliveryEventProcessor(t)
+
+		// when
+		err := processor.Process(context.TODO(), DeliveryEvent{MessageID: "abc@mg.foo.com", Recipient: "jane@acme.com", Type: DeliveryEventBounced})
+
+		// then
+		require.NoError(t, err)
+		assertSuppressed(t, cl, "jane@acme.com", "bounced")
+	})
+
+	t.Run("failures", func(t *testing.T) {
+		t.Run("unknown event", func(t *testing.T) {
+			// given
+			processor, _ := newDeliveryEventProcessor(t)
+
+			// when
+			err := processor.Process(context.TODO(), DeliveryEvent{MessageID: "abc@mg.foo.com", Type: "opened"})
+
+			// then
+			require.EqualError(t, err, "unknown delivery event 'opened'")
+		})
+
+		t.Run("unable to list the notifications", func(t *testing.T) {
+			// given
+			processor, cl := newDeliveryEventProcessor(t)
+			cl.MockList = func(ctx context.Context, list runtimeclient.ObjectList, opts ...runtimeclient.ListOption) error {
+				return assert.AnError
+			}
+
+			// when
+			err := processor.Process(context.TODO(), DeliveryEvent{MessageID: "abc@mg.foo.com", Type: DeliveryEventDelivered})
+
+			// then
+			require.EqualError(t, err, "unable to list the notifications: "+assert.AnError.Error())
+		})
+
+		t.Run("unable to update the status", func(t *testing.T) {
+			// given
+			notification := newSentNotification("jane-provisioned", "abc@mg.foo.com")
+			processor, cl := newDeliveryEventProcessor(t, notification)
+			cl.MockStatusUpdate = func(ctx context.Context, obj runtimeclient.Object, opts ...runtimeclient.SubResourceUpdateOption) error {
+				return assert.AnError
+			}
+
+			// when
+			err := processor.Process(context.TODO(), DeliveryEvent{MessageID: "abc@mg.foo.com", Type: DeliveryEventBounced})
+
+			// then
+			require.Equal(t, assert.AnError, err)
+			// the recipient is not suppressed yet, the event is expected to be sent again
+			assertNotSuppressed(t, cl, "jane@acme.com")
+		})
+	})
+}
+
+func newDeliveryEventProcessor(t *testing.T, initObjs ...runtimeclient.Object) (*DeliveryEventProcessor, *test.FakeClient) {
+	cl := test.NewFakeClient(t, initObjs...)
+	return &DeliveryEventProcessor{
+		Client:    cl,
+		Namespace: test.HostOperatorNs,
+	}, cl
+}
+
+func deliveryCond(status corev1.ConditionStatus, reason, msg string) toolchainv1alpha1.Condition {
+	return toolchainv1alpha1.Condition{
+		Type:    NotificationDelivered,
+		Status:  status,
+		Reason:  reason,
+		Message: msg,
+	}
+}
+
+func assertSuppressed(t *testing.T, cl runtimeclient.Client, email, reason string) {
+	suppressed, err := GetSuppressedRecipient(context.TODO(), cl, test.HostOperatorNs, email)
+	require.NoError(t, err)
+	require.NotNil(t, suppressed)
+	assert.Equal(t, reason, suppressed.Reason)
+}
+
+func assertNotSuppressed(t *testing.T, cl runtimeclient.Client, email string) {
+	suppressed, err := GetSuppressedRecipient(context.TODO(), cl, test.HostOperatorNs, email)
+	require.NoError(t, err)
+	assert.Nil(t, suppressed)
+}
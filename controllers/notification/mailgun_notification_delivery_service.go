@@ -18,12 +18,18 @@ type MailgunDeliveryError struct {
 	id           string
 	response     string
 	errorMessage string
+	permanent    bool
 }
 
 func (e MailgunDeliveryError) Error() string {
 	return fmt.Sprintf("error while delivering notification (ID: %s, Response: %s) - %s", e.id, e.response, e.errorMessage)
 }
 
+// Permanent returns true if Mailgun rejected the message (4xx status), in which case retrying will not help
+func (e MailgunDeliveryError) Permanent() bool {
+	return e.permanent
+}
+
 func NewMailgunDeliveryError(id, response, errorMessage string) error {
 	return MailgunDeliveryError{
 		id:           id,
@@ -71,50 +77,24 @@ func NewMailgunNotificationDeliveryService(config DeliveryServiceFactoryConfig,
 
 func (s *MailgunNotificationDeliveryService) Send(notification *toolchainv1alpha1.Notification, templateSetName string) error {
 
-	var subject, body string
-
-	if notification.Spec.Template != "" {
-		template, err := s.base.TemplateLoader.GetNotificationTemplate(notification.Spec.Template, templateSetName)
-		if err != nil {
-			return err
-		}
-
-		// Copy the context to a local variable, we will add some more values to it here
-		context := notification.Spec.Context
-
-		if s.ReplyToEmail != "" {
-			context[ContextReplyTo] = s.ReplyToEmail
-		} else {
-			context[ContextReplyTo] = s.SenderEmail
-		}
-
-		subject, err = s.base.GenerateContent(context, template.Subject)
-		if err != nil {
-			return err
-		}
-
-		body, err = s.base.GenerateContent(context, template.Content)
-		if err != nil {
-			return err
-		}
-	} else {
-		// If there is no template specified then simply use the subject and content provided by the notification
-		subject = notification.Spec.Subject
-		body = notification.Spec.Content
+	replyTo := s.ReplyToEmail
+	if replyTo == "" {
+		replyTo = s.SenderEmail
 	}
-
-	if subject == "" || body == "" {
-		return fmt.Errorf("no subject or body specified for notification")
+	msg, err := s.base.generateMessage(notification, templateSetName, replyTo)
+	if err != nil {
+		return err
 	}
 
-	// The message object allows you to add attachments and Bcc recipients
-	message := s.Mailgun.NewMessage(s.SenderEmail, subject, "", notification.Spec.Recipient)
+	// The message object allows you to add attachments and Bcc recipients.
+	// Mailgun sends a multipart/alternative message with the text and the HTML bodies.
+	message := s.Mailgun.NewMessage(s.SenderEmail, msg.subject, msg.text, notification.Spec.Recipient)
 
 	if s.ReplyToEmail != "" {
 		message.SetReplyTo(s.ReplyToEmail)
 	}
 
-	message.SetHtml(body)
+	message.SetHtml(msg.html)
 
 	ctx, cancel := context.WithTimeout(context.Background(), time.Second*10)
 	defer cancel()
@@ -122,8 +102,14 @@ func (s *MailgunNotificationDeliveryService) Send(notification *toolchainv1alpha
 	// Send the message with a 10 second timeout
 	response, id, err := s.Mailgun.Send(ctx, message)
 	if err != nil {
-		return NewMailgunDeliveryError(id, response, err.Error())
+		return MailgunDeliveryError{
+			id:           id,
+			response:     response,
+			errorMessage: err.Error(),
+			permanent:    isPermanentHTTPStatus(mailgun.GetStatusFromErr(err)),
+		}
 	}
+	setMessageID(notification, id)
 
 	return nil
 }
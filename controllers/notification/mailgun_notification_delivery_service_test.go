@@ -10,8 +10,10 @@ import (
 	toolchainv1alpha1 "github.com/codeready-toolchain/api/api/v1alpha1"
 
 	"github.com/codeready-toolchain/host-operator/pkg/templates/notificationtemplates"
+	"github.com/codeready-toolchain/toolchain-common/pkg/hash"
 
 	"github.com/mailgun/mailgun-go/v4"
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
 
@@ -77,17 +79,22 @@ func TestMailgunNotificationDeliveryService(t *testing.T) {
 		t.Run("test mailgun notification delivery service send", func(t *testing.T) {
 			// when
 			mgds := NewMailgunNotificationDeliveryService(config, templateLoader, mockServerOption)
-			err := mgds.Send(&toolchainv1alpha1.Notification{
+			notification := &toolchainv1alpha1.Notification{
 				Spec: toolchainv1alpha1.NotificationSpec{
 					Recipient: "foo@bar.com",
 					Subject:   "test",
 					Content:   "abc",
 					Context:   notCtx,
 				},
-			}, setName)
+			}
+			err := mgds.Send(notification, setName)
 
 			// then
 			require.NoError(t, err)
+			// the ID of the message is recorded without the angle brackets
+			require.NotEmpty(t, notification.Annotations[MessageIDAnnotationKey])
+			assert.NotContains(t, notification.Annotations[MessageIDAnnotationKey], "<")
+			assert.Equal(t, hash.EncodeString(notification.Annotations[MessageIDAnnotationKey]), notification.Labels[MessageIDHashLabelKey])
 		})
 
 		t.Run("test mailgun notification delivery service send fails", func(t *testing.T) {
@@ -203,6 +210,7 @@ func TestMailgunNotificationDeliveryService(t *testing.T) {
 			// then
 			require.NoError(t, err)
 			require.Equal(t, "a message sent to info@foo.com", formValues.Get("html"))
+			require.Equal(t, "a message sent to info@foo.com", formValues.Get("text"))
 
 		})
 
@@ -2,16 +2,21 @@ package notification
 
 import (
 	"context"
+	"encoding/json"
+	"fmt"
+	"io"
 	"time"
 
 	toolchainv1alpha1 "github.com/codeready-toolchain/api/api/v1alpha1"
 	"github.com/codeready-toolchain/host-operator/controllers/toolchainconfig"
+	"github.com/codeready-toolchain/host-operator/pkg/metrics"
 	"github.com/codeready-toolchain/toolchain-common/pkg/condition"
 
 	errs "github.com/pkg/errors"
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/builder"
 	runtimeclient "sigs.k8s.io/controller-runtime/pkg/client"
@@ -23,12 +28,16 @@ import (
 
 // SetupWithManager sets up the controller with the Manager.
 func (r *Reconciler) SetupWithManager(mgr manager.Manager, config toolchainconfig.ToolchainConfig) error {
-	factory := NewNotificationDeliveryServiceFactory(mgr.GetClient(), toolchainconfig.DeliveryServiceFactoryConfig{ToolchainConfig: config})
-	svc, err := factory.CreateNotificationDeliveryService()
+	r.newDeliveryService = func(config toolchainconfig.ToolchainConfig) (DeliveryService, error) {
+		factory := NewNotificationDeliveryServiceFactory(mgr.GetClient(), toolchainconfig.DeliveryServiceFactoryConfig{ToolchainConfig: config})
+		return factory.CreateNotificationDeliveryService()
+	}
+	svc, err := r.newDeliveryService(config)
 	if err != nil {
 		return err
 	}
 	r.deliveryService = svc
+	r.deliveryServiceSecretHash = config.Notifications().SecretHash()
 
 	return ctrl.NewControllerManagedBy(mgr).
 		For(&toolchainv1alpha1.Notification{}, builder.WithPredicates(predicate.GenerationChangedPredicate{})).
@@ -40,6 +49,10 @@ type Reconciler struct {
 	Client          runtimeclient.Client
 	Scheme          *runtime.Scheme
 	deliveryService DeliveryService
+	// deliveryServiceSecretHash is the hash of the notification secret the delivery service was created with
+	deliveryServiceSecretHash string
+	// newDeliveryService creates the delivery service again when the notification secret is rotated
+	newDeliveryService func(config toolchainconfig.ToolchainConfig) (DeliveryService, error)
 }
 
 //+kubebuilder:rbac:groups=toolchain.dev.openshift.com,resources=notifications,verbs=get;list;watch;create;update;patch;delete
@@ -85,21 +98,108 @@ func (r *Reconciler) Reconcile(ctx context.Context, request ctrl.Request) (ctrl.
 		}, nil
 	}
 
+	// if the notification was dead-lettered or suppressed, then keep it around (for inspection) as long as a sent notification, and then delete it
+	for _, condType := range []toolchainv1alpha1.ConditionType{NotificationDeadLettered, NotificationSuppressed} {
+		cond, found := condition.FindConditionByType(notification.Status.Conditions, condType)
+		if found && cond.Status == corev1.ConditionTrue {
+			deleted, requeueAfter, err := r.checkTransitionTimeAndDelete(ctx, config.Notifications().DurationBeforeNotificationDeletion(), notification, cond)
+			if deleted || err != nil {
+				return reconcile.Result{}, err
+			}
+			return reconcile.Result{
+				Requeue:      true,
+				RequeueAfter: requeueAfter,
+			}, nil
+		}
+	}
+
+	// if the previous attempt failed, then wait for the end of the backoff period before trying again
+	if attempts := getDeliveryAttempts(notification); len(attempts) > 0 {
+		backoff := minDeliveryBackoff(len(attempts), config.Notifications().DeliveryInitialBackoff(), config.Notifications().DeliveryMaxBackoff())
+		if remaining := backoff - time.Since(attempts[len(attempts)-1].Time); remaining > 0 {
+			reqLogger.Info("waiting before the next delivery attempt", "attempts", len(attempts), "requeue-after", remaining)
+			return reconcile.Result{
+				Requeue:      true,
+				RequeueAfter: remaining,
+			}, nil
+		}
+	}
+
+	// do not send anything to a recipient whose previous notifications bounced or were reported as spam
+	suppressed, err := GetSuppressedRecipient(ctx, r.Client, notification.Namespace, notification.Spec.Recipient)
+	if err != nil {
+		return reconcile.Result{}, err
+	}
+	if suppressed != nil {
+		reqLogger.Info("the recipient is in the suppression list, the notification will not be sent", "suppression-reason", suppressed.Reason)
+		metrics.NotificationRecipientSuppressedTotal.Inc()
+		return reconcile.Result{
+			Requeue:      true,
+			RequeueAfter: config.Notifications().DurationBeforeNotificationDeletion(),
+		}, r.updateStatusConditions(ctx, notification,
+			toolchainv1alpha1.Condition{
+				Type:    NotificationDeadLettered,
+				Status:  corev1.ConditionTrue,
+				Reason:  NotificationRecipientSuppressedReason,
+				Message: fmt.Sprintf("the recipient is in the suppression list (%s)", suppressed.Reason),
+			})
+	}
+
 	// if the environment is set to e2e do not attempt sending via mailgun
 	if config.Environment() != "e2e-tests" {
+		// do not send the same notification twice in a row, nor too many notifications to the same recipient
+		throttled, err := r.checkThrottling(ctx, config.Notifications(), notification)
+		if err != nil {
+			return reconcile.Result{}, err
+		}
+		if throttled.duplicate != nil {
+			reqLogger.Info("the notification is suppressed: the same notification was recently sent to the same recipient", "duplicate", throttled.duplicate.Name)
+			metrics.NotificationThrottledTotal.WithLabelValues("duplicate").Inc()
+			return reconcile.Result{
+				Requeue:      true,
+				RequeueAfter: config.Notifications().DurationBeforeNotificationDeletion(),
+			}, r.updateStatusConditions(ctx, notification,
+				toolchainv1alpha1.Condition{
+					Type:    NotificationSuppressed,
+					Status:  corev1.ConditionTrue,
+					Reason:  NotificationDuplicateReason,
+					Message: duplicateMessage(throttled.duplicate),
+				})
+		}
+		if throttled.retryAfter > 0 {
+			reqLogger.Info("the notification is delayed: too many notifications were recently sent to the same recipient", "requeue-after", throttled.retryAfter)
+			metrics.NotificationThrottledTotal.WithLabelValues("rate-limited").Inc()
+			return reconcile.Result{
+				Requeue:      true,
+				RequeueAfter: throttled.retryAfter,
+			}, r.updateStatusConditions(ctx, notification,
+				toolchainv1alpha1.Condition{
+					Type:    toolchainv1alpha1.NotificationSent,
+					Status:  corev1.ConditionFalse,
+					Reason:  NotificationRateLimitedReason,
+					Message: "too many notifications were recently sent to the same recipient",
+				})
+		}
+
+		if err := r.reloadDeliveryServiceOnRotation(ctx, config); err != nil {
+			return reconcile.Result{}, err
+		}
+
 		// get the notification environment
 		templateSetName := config.Notifications().TemplateSetName()
 		// Send the notification via the configured delivery service
+		metrics.NotificationDeliveryAttemptsTotal.Inc()
 		err = r.deliveryService.Send(notification, templateSetName)
 		if err != nil {
 			reqLogger.Error(err, "delivery service failed to send notification",
 				"notification spec", notification.Spec,
 			)
-
-			return reconcile.Result{}, r.wrapErrorWithStatusUpdate(ctx, notification,
-				r.setStatusNotificationDeliveryError, err, "failed to send notification")
+			return r.handleDeliveryFailure(ctx, config, notification, err)
 		}
 		reqLogger.Info("Notification has been sent")
+		if err := r.recordDelivery(ctx, notification); err != nil {
+			return reconcile.Result{}, err
+		}
 	} else {
 		reqLogger.Info("Notification has been skipped")
 	}
@@ -110,6 +210,91 @@ func (r *Reconciler) Reconcile(ctx context.Context, request ctrl.Request) (ctrl.
 	}, r.updateStatus(ctx, notification, r.setStatusNotificationSent)
 }
 
+// recordDelivery keeps the labels used for the deduplication and the rate limit, as well as the ID of the message (if any), so that the
+// delivery events reported by the email provider can be matched with the notification. Only the labels and the annotations are patched.
+// A failure doesn't prevent the notification from being marked as sent, which would cause it to be sent again: the notification is
+// reloaded instead, so that its status can be updated.
+func (r *Reconciler) recordDelivery(ctx context.Context, notification *toolchainv1alpha1.Notification) error {
+	setThrottlingLabels(notification)
+	patch, err := json.Marshal(map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"labels":      notification.Labels,
+			"annotations": notification.Annotations,
+		},
+	})
+	if err == nil {
+		err = r.Client.Patch(ctx, notification, runtimeclient.RawPatch(types.MergePatchType, patch))
+	}
+	if err == nil {
+		return nil
+	}
+	log.FromContext(ctx).Error(err, "unable to record the delivery labels and annotations of the notification")
+	if err := r.Client.Get(ctx, runtimeclient.ObjectKeyFromObject(notification), notification); err != nil {
+		return errs.Wrap(err, "unable to reload the notification after its delivery")
+	}
+	return nil
+}
+
+// handleDeliveryFailure records the failed delivery attempt, and either schedules the next attempt or, if the error is permanent
+// or if it was the last attempt, dead-letters the notification
+func (r *Reconciler) handleDeliveryFailure(ctx context.Context, config toolchainconfig.ToolchainConfig, notification *toolchainv1alpha1.Notification,
+	deliveryErr error) (reconcile.Result, error) {
+	logger := log.FromContext(ctx)
+
+	permanent := IsPermanentDeliveryError(deliveryErr)
+	if permanent {
+		metrics.NotificationDeliveryFailuresTotal.WithLabelValues("permanent").Inc()
+	} else {
+		metrics.NotificationDeliveryFailuresTotal.WithLabelValues("transient").Inc()
+	}
+
+	attempts, err := addDeliveryAttempt(notification, DeliveryAttempt{
+		Time:      time.Now(),
+		Error:     deliveryErr.Error(),
+		Permanent: permanent,
+	})
+	if err == nil {
+		err = r.Client.Update(ctx, notification)
+	}
+	if err != nil {
+		return reconcile.Result{}, r.wrapErrorWithStatusUpdate(ctx, notification,
+			r.setStatusNotificationDeliveryError, err, "unable to record the failed delivery attempt")
+	}
+
+	maxAttempts := config.Notifications().DeliveryMaxAttempts()
+	if permanent || len(attempts) >= maxAttempts {
+		reason := NotificationMaxAttemptsReachedReason
+		if permanent {
+			reason = NotificationPermanentErrorReason
+		}
+		logger.Info("giving up on the delivery of the notification", "reason", reason, "attempts", len(attempts))
+		metrics.NotificationDeadLetteredTotal.Inc()
+		return reconcile.Result{
+			Requeue:      true,
+			RequeueAfter: config.Notifications().DurationBeforeNotificationDeletion(),
+		}, r.updateStatusConditions(ctx, notification,
+			toolchainv1alpha1.Condition{
+				Type:    toolchainv1alpha1.NotificationSent,
+				Status:  corev1.ConditionFalse,
+				Reason:  toolchainv1alpha1.NotificationDeliveryErrorReason,
+				Message: deliveryErr.Error(),
+			},
+			toolchainv1alpha1.Condition{
+				Type:    NotificationDeadLettered,
+				Status:  corev1.ConditionTrue,
+				Reason:  reason,
+				Message: fmt.Sprintf("delivery failed after %d attempt(s): %s", len(attempts), deliveryErr.Error()),
+			})
+	}
+
+	backoff := deliveryBackoff(len(attempts), config.Notifications().DeliveryInitialBackoff(), config.Notifications().DeliveryMaxBackoff())
+	logger.Info("the delivery of the notification will be retried", "attempts", len(attempts), "max-attempts", maxAttempts, "requeue-after", backoff)
+	return reconcile.Result{
+		Requeue:      true,
+		RequeueAfter: backoff,
+	}, r.setStatusNotificationDeliveryError(ctx, notification, deliveryErr.Error())
+}
+
 // checkTransitionTimeAndDelete checks if the last transition time has surpassed
 // the duration before the notification should be deleted. If so, the notification is deleted.
 // Returns bool indicating if the notification was deleted, the time before the notification
@@ -135,6 +320,34 @@ func (r *Reconciler) checkTransitionTimeAndDelete(ctx context.Context, durationB
 	return false, diff, nil
 }
 
+// reloadDeliveryServiceOnRotation creates the delivery service again if the notification secret was rotated since it was created,
+// so that the notifications are sent with the new credentials (eg. the Mailgun API key)
+func (r *Reconciler) reloadDeliveryServiceOnRotation(ctx context.Context, config toolchainconfig.ToolchainConfig) error {
+	if r.newDeliveryService == nil {
+		return nil
+	}
+	hash := config.Notifications().SecretHash()
+	if hash == r.deliveryServiceSecretHash {
+		return nil
+	}
+	svc, err := r.newDeliveryService(config)
+	if err != nil {
+		return errs.Wrap(err, "unable to reload the notification delivery service")
+	}
+	logger := log.FromContext(ctx)
+	logger.Info("the notification secret was rotated, the delivery service is reloaded")
+	previous := r.deliveryService
+	r.deliveryService = svc
+	r.deliveryServiceSecretHash = hash
+	// release the resources of the previous delivery service, such as the idle connections to the SMTP server
+	if closer, ok := previous.(io.Closer); ok {
+		if err := closer.Close(); err != nil {
+			logger.Error(err, "unable to close the previous notification delivery service")
+		}
+	}
+	return nil
+}
+
 type statusUpdater func(ctx context.Context, notification *toolchainv1alpha1.Notification, message string) error
 
 func (r *Reconciler) wrapErrorWithStatusUpdate(ctx context.Context, notification *toolchainv1alpha1.Notification,
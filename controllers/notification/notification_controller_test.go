@@ -4,17 +4,22 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"strings"
 	"testing"
 	"time"
 
 	toolchainv1alpha1 "github.com/codeready-toolchain/api/api/v1alpha1"
+	"github.com/codeready-toolchain/host-operator/controllers/toolchainconfig"
 	"github.com/codeready-toolchain/host-operator/pkg/apis"
+	"github.com/codeready-toolchain/host-operator/pkg/metrics"
 	"github.com/codeready-toolchain/host-operator/pkg/templates/notificationtemplates"
+	hostconfig "github.com/codeready-toolchain/host-operator/test/config"
 	ntest "github.com/codeready-toolchain/host-operator/test/notification"
 	commonconfig "github.com/codeready-toolchain/toolchain-common/pkg/configuration"
 	notify "github.com/codeready-toolchain/toolchain-common/pkg/notification"
 	"github.com/codeready-toolchain/toolchain-common/pkg/test"
 	testconfig "github.com/codeready-toolchain/toolchain-common/pkg/test/config"
+	metricstest "github.com/codeready-toolchain/toolchain-common/pkg/test/metrics"
 	"github.com/codeready-toolchain/toolchain-common/pkg/test/usersignup"
 
 	"github.com/mailgun/mailgun-go/v4"
@@ -86,6 +91,76 @@ func TestNotificationSuccess(t *testing.T) {
 	})
 }
 
+func TestNotificationDeliveryRecorded(t *testing.T) {
+	toolchainConfig := commonconfig.NewToolchainConfigObjWithReset(t, testconfig.Notifications().DurationBeforeNotificationDeletion("10s"))
+	newNotification := func(t *testing.T, cl runtimeclient.Client) *toolchainv1alpha1.Notification {
+		notification, err := notify.NewNotificationBuilder(cl, test.HostOperatorNs).
+			WithSubjectAndContent("test", "test content").
+			Create(context.TODO(), "jane@acme.com")
+		require.NoError(t, err)
+		return notification
+	}
+
+	t.Run("labels patched", func(t *testing.T) {
+		// given
+		ds, _ := mockDeliveryService(defaultTemplateLoader())
+		controller, cl := newController(t, ds, toolchainConfig)
+		notification := newNotification(t, cl)
+
+		// when
+		_, err := reconcileNotification(controller, notification)
+
+		// then
+		require.NoError(t, err)
+		actual := &toolchainv1alpha1.Notification{}
+		require.NoError(t, cl.Get(context.TODO(), runtimeclient.ObjectKeyFromObject(notification), actual))
+		assert.NotEmpty(t, actual.Labels[DeduplicationKeyLabelKey])
+		assert.NotEmpty(t, actual.Labels[RecipientHashLabelKey])
+		assert.Empty(t, actual.Spec.Context)
+		assert.Equal(t, notification.Spec.Content, actual.Spec.Content)
+		ntest.AssertThatNotification(t, notification.Name, cl).HasConditions(sentCond())
+	})
+
+	t.Run("marked as sent when the labels can't be patched", func(t *testing.T) {
+		// given
+		ds, _ := mockDeliveryService(defaultTemplateLoader())
+		controller, cl := newController(t, ds, toolchainConfig)
+		notification := newNotification(t, cl)
+		cl.MockPatch = func(ctx context.Context, obj runtimeclient.Object, patch runtimeclient.Patch, opts ...runtimeclient.PatchOption) error {
+			return fmt.Errorf("mock error")
+		}
+
+		// when
+		_, err := reconcileNotification(controller, notification)
+
+		// then
+		require.NoError(t, err)
+		ntest.AssertThatNotification(t, notification.Name, cl).HasConditions(sentCond())
+	})
+
+	t.Run("unable to reload the notification", func(t *testing.T) {
+		// given
+		ds, _ := mockDeliveryService(defaultTemplateLoader())
+		controller, cl := newController(t, ds, toolchainConfig)
+		notification := newNotification(t, cl)
+		cl.MockPatch = func(ctx context.Context, obj runtimeclient.Object, patch runtimeclient.Patch, opts ...runtimeclient.PatchOption) error {
+			cl.MockGet = func(ctx context.Context, key runtimeclient.ObjectKey, obj runtimeclient.Object, opts ...runtimeclient.GetOption) error {
+				if _, ok := obj.(*toolchainv1alpha1.Notification); ok {
+					return fmt.Errorf("mock error")
+				}
+				return cl.Client.Get(ctx, key, obj, opts...)
+			}
+			return fmt.Errorf("mock error")
+		}
+
+		// when
+		_, err := reconcileNotification(controller, notification)
+
+		// then
+		require.EqualError(t, err, "unable to reload the notification after its delivery: mock error")
+	})
+}
+
 func TestNotificationSentFailure(t *testing.T) {
 	toolchainConfig := commonconfig.NewToolchainConfigObjWithReset(t, testconfig.Notifications().DurationBeforeNotificationDeletion("10s"))
 
@@ -181,6 +256,9 @@ func TestNotificationDelivery(t *testing.T) {
 		e := events[0]
 		require.IsType(t, &events2.Accepted{}, e)
 		accepted := e.(*events2.Accepted)
+		// the ID of the message is recorded on the notification
+		assert.Equal(t, accepted.Message.Headers.MessageID, instance.Annotations[MessageIDAnnotationKey])
+		assert.NotEmpty(t, instance.Labels[MessageIDHashLabelKey])
 		require.Equal(t, "foo@redhat.com", accepted.Recipient)
 		require.Equal(t, "redhat.com", accepted.RecipientDomain)
 		require.Equal(t, "foo", accepted.Message.Headers.Subject)
@@ -302,9 +380,10 @@ func TestNotificationDelivery(t *testing.T) {
 		result, err := reconcileNotification(controller, notification)
 
 		// then
-		require.Error(t, err)
-		require.False(t, result.Requeue)
-		require.Equal(t, "failed to send notification: delivery error", err.Error())
+		require.NoError(t, err)
+		require.True(t, result.Requeue)
+		assert.GreaterOrEqual(t, result.RequeueAfter, 30*time.Second)
+		assert.Less(t, result.RequeueAfter, 36*time.Second)
 
 		// Load the reconciled notification
 		key := types.NamespacedName{
@@ -317,6 +396,331 @@ func TestNotificationDelivery(t *testing.T) {
 
 		ntest.AssertThatNotification(t, instance.Name, cl).
 			HasConditions(deliveryErrorCond("delivery error"))
+		attempts := getDeliveryAttempts(instance)
+		require.Len(t, attempts, 1)
+		assert.Equal(t, "delivery error", attempts[0].Error)
+		assert.False(t, attempts[0].Permanent)
+	})
+}
+
+func TestNotificationDeliveryServiceReload(t *testing.T) {
+	// given
+	toolchainConfig := commonconfig.NewToolchainConfigObjWithReset(t,
+		testconfig.Notifications().Secret().Ref("notification-secret").MailgunAPIKey("mailgunAPIKey"))
+	secret := test.CreateSecret("notification-secret", test.HostOperatorNs, map[string][]byte{
+		"mailgunAPIKey": []byte("abc123"),
+	})
+	controller, cl := newController(t, &MockDeliveryService{}, toolchainConfig, secret)
+	var apiKeys []string
+	var services []*closableDeliveryService
+	controller.newDeliveryService = func(config toolchainconfig.ToolchainConfig) (DeliveryService, error) {
+		apiKeys = append(apiKeys, config.Notifications().MailgunAPIKey())
+		ds, _ := mockDeliveryService(defaultTemplateLoader())
+		services = append(services, &closableDeliveryService{DeliveryService: ds})
+		return services[len(services)-1], nil
+	}
+	sendNotification := func(t *testing.T, recipient string) {
+		notification, err := notify.NewNotificationBuilder(cl, test.HostOperatorNs).
+			WithSubjectAndContent("foo", "test content").
+			Create(context.TODO(), recipient)
+		require.NoError(t, err)
+
+		_, err = reconcileNotification(controller, notification)
+
+		require.NoError(t, err)
+		ntest.AssertThatNotification(t, notification.Name, cl).HasConditions(sentCond())
+	}
+
+	t.Run("reloaded when created with another secret", func(t *testing.T) {
+		// when
+		sendNotification(t, "foo@redhat.com")
+
+		// then
+		assert.Equal(t, []string{"abc123"}, apiKeys)
+	})
+
+	t.Run("not reloaded when the secret didn't change", func(t *testing.T) {
+		// when
+		sendNotification(t, "bar@redhat.com")
+
+		// then
+		assert.Equal(t, []string{"abc123"}, apiKeys)
+	})
+
+	t.Run("reloaded when the secret was rotated", func(t *testing.T) {
+		// given
+		secret.Data["mailgunAPIKey"] = []byte("def456")
+		require.NoError(t, cl.Update(context.TODO(), secret))
+		_, err := toolchainconfig.ForceLoadToolchainConfig(cl)
+		require.NoError(t, err)
+
+		// when
+		sendNotification(t, "baz@redhat.com")
+
+		// then
+		assert.Equal(t, []string{"abc123", "def456"}, apiKeys)
+		require.Len(t, services, 2)
+		assert.True(t, services[0].closed, "the previous delivery service should be closed")
+		assert.False(t, services[1].closed)
+	})
+
+	t.Run("reload failure", func(t *testing.T) {
+		// given
+		secret.Data["mailgunAPIKey"] = []byte("ghi789")
+		require.NoError(t, cl.Update(context.TODO(), secret))
+		_, err := toolchainconfig.ForceLoadToolchainConfig(cl)
+		require.NoError(t, err)
+		controller.newDeliveryService = func(_ toolchainconfig.ToolchainConfig) (DeliveryService, error) {
+			return nil, fmt.Errorf("invalid configuration")
+		}
+		notification, err := notify.NewNotificationBuilder(cl, test.HostOperatorNs).
+			WithSubjectAndContent("foo", "test content").
+			Create(context.TODO(), "qux@redhat.com")
+		require.NoError(t, err)
+
+		// when
+		_, err = reconcileNotification(controller, notification)
+
+		// then
+		require.EqualError(t, err, "unable to reload the notification delivery service: invalid configuration")
+	})
+}
+
+// closableDeliveryService records if the delivery service was closed
+type closableDeliveryService struct {
+	DeliveryService
+	closed bool
+}
+
+func (s *closableDeliveryService) Close() error {
+	s.closed = true
+	return nil
+}
+
+func TestNotificationSuppressedRecipient(t *testing.T) {
+	// given
+	metrics.Reset()
+	toolchainConfig := commonconfig.NewToolchainConfigObjWithReset(t, testconfig.Notifications().DurationBeforeNotificationDeletion("10s"))
+	ds := &failingDeliveryService{err: errors.New("should not be called")}
+	controller, cl := newController(t, ds, toolchainConfig)
+	require.NoError(t, SuppressRecipient(context.TODO(), cl, test.HostOperatorNs, "jane@acme.com", "bounced"))
+
+	notification, err := notify.NewNotificationBuilder(cl, test.HostOperatorNs).
+		WithSubjectAndContent("test", "test content").
+		Create(context.TODO(), "jane@acme.com")
+	require.NoError(t, err)
+
+	// when
+	result, err := reconcileNotification(controller, notification)
+
+	// then
+	require.NoError(t, err)
+	assert.Equal(t, 10*time.Second, result.RequeueAfter)
+	assert.Equal(t, 0, ds.calls)
+	ntest.AssertThatNotification(t, notification.Name, cl).
+		HasConditions(deadLetteredCond(NotificationRecipientSuppressedReason, "the recipient is in the suppression list (bounced)"))
+	metricstest.AssertMetricsCounterEquals(t, 1, metrics.NotificationRecipientSuppressedTotal)
+	metricstest.AssertMetricsCounterEquals(t, 0, metrics.NotificationDeliveryAttemptsTotal)
+
+	t.Run("other recipients still receive their notifications", func(t *testing.T) {
+		// given
+		ds.err = nil
+		notification, err := notify.NewNotificationBuilder(cl, test.HostOperatorNs).
+			WithSubjectAndContent("test", "test content").
+			Create(context.TODO(), "john@acme.com")
+		require.NoError(t, err)
+
+		// when
+		_, err = reconcileNotification(controller, notification)
+
+		// then
+		require.NoError(t, err)
+		assert.Equal(t, 1, ds.calls)
+		ntest.AssertThatNotification(t, notification.Name, cl).
+			HasConditions(sentCond())
+	})
+
+	t.Run("unable to get the suppression list", func(t *testing.T) {
+		// given
+		cl.MockGet = func(ctx context.Context, key runtimeclient.ObjectKey, obj runtimeclient.Object, opts ...runtimeclient.GetOption) error {
+			if strings.HasPrefix(key.Name, SuppressionListConfigMapNamePrefix) {
+				return assert.AnError
+			}
+			return cl.Client.Get(ctx, key, obj, opts...)
+		}
+		notification, err := notify.NewNotificationBuilder(cl, test.HostOperatorNs).
+			WithSubjectAndContent("test", "test content").
+			Create(context.TODO(), "joe@acme.com")
+		require.NoError(t, err)
+
+		// when
+		_, err = reconcileNotification(controller, notification)
+
+		// then
+		require.EqualError(t, err, "unable to get the notification suppression list: "+assert.AnError.Error())
+	})
+}
+
+type failingDeliveryService struct {
+	err   error
+	calls int
+}
+
+func (s *failingDeliveryService) Send(_ *toolchainv1alpha1.Notification, _ string) error {
+	s.calls++
+	return s.err
+}
+
+func TestNotificationDeliveryRetries(t *testing.T) {
+	toolchainConfig := commonconfig.NewToolchainConfigObjWithReset(t,
+		testconfig.Notifications().DurationBeforeNotificationDeletion("10s"),
+		hostconfig.Annotation(toolchainconfig.NotificationDeliveryMaxAttemptsAnnotationKey, "3"),
+		hostconfig.Annotation(toolchainconfig.NotificationDeliveryInitialBackoffAnnotationKey, "1m"),
+		hostconfig.Annotation(toolchainconfig.NotificationDeliveryMaxBackoffAnnotationKey, "3m"))
+
+	newNotificationWithAttempts := func(t *testing.T, cl *test.FakeClient, attempts ...DeliveryAttempt) *toolchainv1alpha1.Notification {
+		notification, err := notify.NewNotificationBuilder(cl, test.HostOperatorNs).
+			WithSubjectAndContent("test", "test content").
+			Create(context.TODO(), "foo@redhat.com")
+		require.NoError(t, err)
+		for _, attempt := range attempts {
+			_, err := addDeliveryAttempt(notification, attempt)
+			require.NoError(t, err)
+		}
+		require.NoError(t, cl.Update(context.TODO(), notification))
+		return notification
+	}
+
+	t.Run("transient failure is retried with backoff", func(t *testing.T) {
+		// given
+		metrics.Reset()
+		ds := &failingDeliveryService{err: errors.New("service unavailable")}
+		controller, cl := newController(t, ds, toolchainConfig)
+		notification := newNotificationWithAttempts(t, cl, DeliveryAttempt{Time: time.Now().Add(-2 * time.Minute), Error: "service unavailable"})
+
+		// when
+		result, err := reconcileNotification(controller, notification)
+
+		// then
+		require.NoError(t, err)
+		assert.Equal(t, 1, ds.calls)
+		// second failed attempt, so the backoff is doubled
+		assert.GreaterOrEqual(t, result.RequeueAfter, 2*time.Minute)
+		assert.Less(t, result.RequeueAfter, 2*time.Minute+24*time.Second)
+		ntest.AssertThatNotification(t, notification.Name, cl).
+			HasConditions(deliveryErrorCond("service unavailable"))
+		metricstest.AssertMetricsCounterEquals(t, 1, metrics.NotificationDeliveryAttemptsTotal)
+		metricstest.AssertMetricsCounterEquals(t, 1, metrics.NotificationDeliveryFailuresTotal.WithLabelValues("transient"))
+		metricstest.AssertMetricsCounterEquals(t, 0, metrics.NotificationDeliveryFailuresTotal.WithLabelValues("permanent"))
+		metricstest.AssertMetricsCounterEquals(t, 0, metrics.NotificationDeadLetteredTotal)
+	})
+
+	t.Run("not sent again during the backoff period", func(t *testing.T) {
+		// given
+		metrics.Reset()
+		ds := &failingDeliveryService{err: errors.New("service unavailable")}
+		controller, cl := newController(t, ds, toolchainConfig)
+		notification := newNotificationWithAttempts(t, cl, DeliveryAttempt{Time: time.Now().Add(-10 * time.Second), Error: "service unavailable"})
+
+		// when
+		result, err := reconcileNotification(controller, notification)
+
+		// then
+		require.NoError(t, err)
+		assert.Equal(t, 0, ds.calls)
+		assert.True(t, result.Requeue)
+		assert.LessOrEqual(t, result.RequeueAfter, 50*time.Second)
+		assert.Greater(t, result.RequeueAfter, 40*time.Second)
+		metricstest.AssertMetricsCounterEquals(t, 0, metrics.NotificationDeliveryAttemptsTotal)
+	})
+
+	t.Run("dead-lettered after the last attempt", func(t *testing.T) {
+		// given
+		metrics.Reset()
+		ds := &failingDeliveryService{err: errors.New("service unavailable")}
+		controller, cl := newController(t, ds, toolchainConfig)
+		notification := newNotificationWithAttempts(t, cl,
+			DeliveryAttempt{Time: time.Now().Add(-10 * time.Minute), Error: "service unavailable"},
+			DeliveryAttempt{Time: time.Now().Add(-5 * time.Minute), Error: "service unavailable"})
+
+		// when
+		result, err := reconcileNotification(controller, notification)
+
+		// then
+		require.NoError(t, err)
+		assert.Equal(t, 1, ds.calls)
+		assert.Equal(t, 10*time.Second, result.RequeueAfter)
+		ntest.AssertThatNotification(t, notification.Name, cl).
+			HasConditions(deliveryErrorCond("service unavailable"),
+				deadLetteredCond(NotificationMaxAttemptsReachedReason, "delivery failed after 3 attempt(s): service unavailable"))
+		metricstest.AssertMetricsCounterEquals(t, 1, metrics.NotificationDeliveryFailuresTotal.WithLabelValues("transient"))
+		metricstest.AssertMetricsCounterEquals(t, 1, metrics.NotificationDeadLetteredTotal)
+	})
+
+	t.Run("dead-lettered after a permanent failure", func(t *testing.T) {
+		// given
+		metrics.Reset()
+		ds := &failingDeliveryService{err: NewPermanentDeliveryError(errors.New("invalid recipient"))}
+		controller, cl := newController(t, ds, toolchainConfig)
+		notification := newNotificationWithAttempts(t, cl)
+
+		// when
+		result, err := reconcileNotification(controller, notification)
+
+		// then
+		require.NoError(t, err)
+		assert.Equal(t, 1, ds.calls)
+		assert.Equal(t, 10*time.Second, result.RequeueAfter)
+		instance := &toolchainv1alpha1.Notification{}
+		require.NoError(t, cl.Get(context.TODO(), test.NamespacedName(test.HostOperatorNs, notification.Name), instance))
+		attempts := getDeliveryAttempts(instance)
+		require.Len(t, attempts, 1)
+		assert.True(t, attempts[0].Permanent)
+		ntest.AssertThatNotification(t, notification.Name, cl).
+			HasConditions(deliveryErrorCond("invalid recipient"),
+				deadLetteredCond(NotificationPermanentErrorReason, "delivery failed after 1 attempt(s): invalid recipient"))
+		metricstest.AssertMetricsCounterEquals(t, 1, metrics.NotificationDeliveryFailuresTotal.WithLabelValues("permanent"))
+		metricstest.AssertMetricsCounterEquals(t, 1, metrics.NotificationDeadLetteredTotal)
+	})
+
+	t.Run("dead-lettered notification", func(t *testing.T) {
+
+		t.Run("is not sent again", func(t *testing.T) {
+			// given
+			ds := &failingDeliveryService{}
+			controller, cl := newController(t, ds, toolchainConfig)
+			notification := newNotificationWithAttempts(t, cl, DeliveryAttempt{Time: time.Now().Add(-10 * time.Minute), Error: "invalid", Permanent: true})
+			notification.Status.Conditions = []toolchainv1alpha1.Condition{deadLetteredCond(NotificationPermanentErrorReason, "invalid")}
+			require.NoError(t, cl.Status().Update(context.TODO(), notification))
+
+			// when
+			result, err := reconcileNotification(controller, notification)
+
+			// then
+			require.NoError(t, err)
+			assert.Equal(t, 0, ds.calls)
+			assert.True(t, result.Requeue)
+			assert.LessOrEqual(t, result.RequeueAfter, 10*time.Second)
+		})
+
+		t.Run("is deleted after the deletion timeout", func(t *testing.T) {
+			// given
+			ds := &failingDeliveryService{}
+			controller, cl := newController(t, ds, toolchainConfig)
+			notification := newNotificationWithAttempts(t, cl, DeliveryAttempt{Time: time.Now().Add(-10 * time.Minute), Error: "invalid", Permanent: true})
+			cond := deadLetteredCond(NotificationPermanentErrorReason, "invalid")
+			cond.LastTransitionTime = metav1.Time{Time: time.Now().Add(-10 * time.Second)}
+			notification.Status.Conditions = []toolchainv1alpha1.Condition{cond}
+			require.NoError(t, cl.Status().Update(context.TODO(), notification))
+
+			// when
+			_, err := reconcileNotification(controller, notification)
+
+			// then
+			require.NoError(t, err)
+			assert.Equal(t, 0, ds.calls)
+			AssertThatNotificationIsDeleted(t, cl, notification.Name)
+		})
 	})
 }
 
@@ -366,6 +770,16 @@ func deliveryErrorCond(msg string) toolchainv1alpha1.Condition {
 	}
 }
 
+func deadLetteredCond(reason, msg string) toolchainv1alpha1.Condition {
+	return toolchainv1alpha1.Condition{
+		Type:               NotificationDeadLettered,
+		Status:             corev1.ConditionTrue,
+		Reason:             reason,
+		Message:            msg,
+		LastTransitionTime: metav1.Time{Time: time.Now()},
+	}
+}
+
 func deletionCond(msg string) toolchainv1alpha1.Condition {
 	return toolchainv1alpha1.Condition{
 		Type:               toolchainv1alpha1.NotificationDeletionError,
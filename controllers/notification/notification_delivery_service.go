@@ -3,6 +3,8 @@ package notification
 import (
 	"bytes"
 	"errors"
+	"fmt"
+	"net/http"
 	"text/template"
 
 	toolchainv1alpha1 "github.com/codeready-toolchain/api/api/v1alpha1"
@@ -17,13 +19,13 @@ type notificationDeliveryServiceConfig interface {
 }
 
 type TemplateLoader interface {
-	GetNotificationTemplate(name string, templateSetName string) (*notificationtemplates.NotificationTemplate, error)
+	GetNotificationTemplate(name, templateSetName, locale string) (*notificationtemplates.NotificationTemplate, error)
 }
 
 type DefaultTemplateLoader struct{}
 
-func (l *DefaultTemplateLoader) GetNotificationTemplate(name string, templateSetName string) (*notificationtemplates.NotificationTemplate, error) {
-	return notificationtemplates.GetNotificationTemplate(name, templateSetName)
+func (l *DefaultTemplateLoader) GetNotificationTemplate(name, templateSetName, locale string) (*notificationtemplates.NotificationTemplate, error) {
+	return notificationtemplates.GetLocalizedNotificationTemplate(name, templateSetName, locale)
 }
 
 type DeliveryService interface {
@@ -38,6 +40,8 @@ type DeliveryServiceFactory struct {
 type DeliveryServiceFactoryConfig interface {
 	notificationDeliveryServiceConfig
 	MailgunConfig
+	SMTPConfig
+	WebhookConfig
 }
 
 func NewNotificationDeliveryServiceFactory(client runtimeclient.Client, config DeliveryServiceFactoryConfig) *DeliveryServiceFactory {
@@ -48,21 +52,95 @@ func NewNotificationDeliveryServiceFactory(client runtimeclient.Client, config D
 }
 
 func (f *DeliveryServiceFactory) CreateNotificationDeliveryService() (DeliveryService, error) {
+	email, err := f.createEmailDeliveryService()
+	if err != nil {
+		return nil, err
+	}
+	routes, err := f.Config.GetNotificationRoutes()
+	if err != nil {
+		return nil, err
+	}
+	if len(routes) == 0 {
+		return email, nil
+	}
+	return NewRoutingNotificationDeliveryService(email, f.Config, &DefaultTemplateLoader{})
+}
+
+func (f *DeliveryServiceFactory) createEmailDeliveryService() (DeliveryService, error) {
 	switch f.Config.GetNotificationDeliveryService() {
 	case toolchainconfig.NotificationDeliveryServiceMailgun:
 		return NewMailgunNotificationDeliveryService(f.Config, &DefaultTemplateLoader{}), nil
+	case toolchainconfig.NotificationDeliveryServiceSMTP:
+		return NewSMTPNotificationDeliveryService(f.Config, &DefaultTemplateLoader{}), nil
 	}
 	return nil, errors.New("invalid notification delivery service configuration")
 }
 
+// PermanentDeliveryError is a delivery failure which will not be solved by retrying, for example an invalid template
+type PermanentDeliveryError struct {
+	err error
+}
+
+func NewPermanentDeliveryError(err error) error {
+	return PermanentDeliveryError{err: err}
+}
+
+func (e PermanentDeliveryError) Error() string {
+	return e.err.Error()
+}
+
+func (e PermanentDeliveryError) Unwrap() error {
+	return e.err
+}
+
+func (e PermanentDeliveryError) Permanent() bool {
+	return true
+}
+
+// IsPermanentDeliveryError returns true if retrying the delivery will not help. The delivery errors are considered as transient
+// unless they are known to be permanent. When the notification was delivered to several channels, the error is permanent only
+// if all the failures are permanent.
+func IsPermanentDeliveryError(err error) bool {
+	if joined, ok := err.(interface{ Unwrap() []error }); ok {
+		for _, e := range joined.Unwrap() {
+			if !IsPermanentDeliveryError(e) {
+				return false
+			}
+		}
+		return len(joined.Unwrap()) > 0
+	}
+	var p interface{ Permanent() bool }
+	return errors.As(err, &p) && p.Permanent()
+}
+
+// isPermanentHTTPStatus returns true if the given HTTP status code of a failed request means that retrying the request will not help
+func isPermanentHTTPStatus(statusCode int) bool {
+	return statusCode >= 400 && statusCode < 500 && statusCode != http.StatusTooManyRequests && statusCode != http.StatusRequestTimeout
+}
+
 type BaseNotificationDeliveryService struct {
 	TemplateLoader TemplateLoader
+	// StrictContext makes the generation of the content fail when a template uses a key which is missing from the notification context,
+	// instead of rendering `<no value>`
+	StrictContext bool
 }
 
 func (s *BaseNotificationDeliveryService) GenerateContent(context map[string]string,
 	templateDefinition string) (string, error) {
+	return s.generateContent(context, "", templateDefinition)
+}
 
-	tmpl, err := template.New("template").Parse(templateDefinition)
+// generateContent executes the given template definition, which may use the named templates defined in the given partials
+func (s *BaseNotificationDeliveryService) generateContent(context map[string]string, partials, templateDefinition string) (string, error) {
+	tmpl := template.New("partials")
+	if s.StrictContext {
+		tmpl = tmpl.Option("missingkey=error")
+	}
+	tmpl, err := tmpl.Parse(partials)
+	if err != nil {
+		return "", err
+	}
+	tmpl, err = tmpl.New("template").Parse(templateDefinition)
 	if err != nil {
 		return "", err
 	}
@@ -76,3 +154,68 @@ func (s *BaseNotificationDeliveryService) GenerateContent(context map[string]str
 
 	return buf.String(), nil
 }
+
+// RenderNotification returns the subject, and the HTML and plain text bodies of the given notification, as they would be delivered
+func (s *BaseNotificationDeliveryService) RenderNotification(notification *toolchainv1alpha1.Notification, templateSetName, replyTo string) (string, string, string, error) {
+	msg, err := s.generateMessage(notification, templateSetName, replyTo)
+	return msg.subject, msg.html, msg.text, err
+}
+
+// message contains the subject and the HTML and plain text alternatives of the body of a notification
+type message struct {
+	subject string
+	html    string
+	text    string
+}
+
+// generateMessage returns the subject and the body of the given notification, either generated from its template
+// or as specified in the notification itself. When the template has no plain text content (or when there is no template),
+// then the plain text body is derived from the HTML body.
+func (s *BaseNotificationDeliveryService) generateMessage(notification *toolchainv1alpha1.Notification, templateSetName, replyTo string) (message, error) {
+	var msg message
+
+	if notification.Spec.Template != "" {
+		locale := notification.Spec.Context[toolchainconfig.NotificationContextLocaleKey]
+		template, err := s.TemplateLoader.GetNotificationTemplate(notification.Spec.Template, templateSetName, locale)
+		if err != nil {
+			return message{}, NewPermanentDeliveryError(err)
+		}
+
+		// Copy the context to a local variable, we will add some more values to it here, which must not end up in the spec
+		// of the notification when it's updated later on (eg. to record the delivery attempts)
+		context := make(map[string]string, len(notification.Spec.Context)+1)
+		for k, v := range notification.Spec.Context {
+			context[k] = v
+		}
+		context[ContextReplyTo] = replyTo
+
+		msg.subject, err = s.generateContent(context, template.Partials, template.Subject)
+		if err != nil {
+			return message{}, NewPermanentDeliveryError(err)
+		}
+
+		msg.html, err = s.generateContent(context, template.Partials, template.Content)
+		if err != nil {
+			return message{}, NewPermanentDeliveryError(err)
+		}
+
+		if template.TextContent != "" {
+			msg.text, err = s.generateContent(context, template.Partials, template.TextContent)
+			if err != nil {
+				return message{}, NewPermanentDeliveryError(err)
+			}
+		}
+	} else {
+		// If there is no template specified then simply use the subject and content provided by the notification
+		msg.subject = notification.Spec.Subject
+		msg.html = notification.Spec.Content
+	}
+
+	if msg.subject == "" || msg.html == "" {
+		return message{}, NewPermanentDeliveryError(fmt.Errorf("no subject or body specified for notification"))
+	}
+	if msg.text == "" {
+		msg.text = htmlToText(msg.html)
+	}
+	return msg, nil
+}
@@ -2,16 +2,33 @@ package notification
 
 import (
 	"errors"
+	"fmt"
 	"testing"
 
+	toolchainv1alpha1 "github.com/codeready-toolchain/api/api/v1alpha1"
+	"github.com/codeready-toolchain/host-operator/controllers/toolchainconfig"
 	"github.com/codeready-toolchain/host-operator/pkg/templates/notificationtemplates"
 	"github.com/codeready-toolchain/toolchain-common/pkg/test"
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
 
 type MockNotificationDeliveryServiceFactoryConfig struct {
-	Mailgun MockMailgunConfiguration
-	Service MockNotificationDeliveryServiceConfig
+	Mailgun  MockMailgunConfiguration
+	SMTP     MockSMTPConfiguration
+	Webhooks []toolchainconfig.NotificationWebhook
+	Routes   []toolchainconfig.NotificationRoute
+	// WebhooksErr is returned with the webhooks and the routes, as if their configuration was invalid
+	WebhooksErr error
+	Service     MockNotificationDeliveryServiceConfig
+}
+
+func (c *MockNotificationDeliveryServiceFactoryConfig) GetNotificationWebhooks() ([]toolchainconfig.NotificationWebhook, error) {
+	return c.Webhooks, c.WebhooksErr
+}
+
+func (c *MockNotificationDeliveryServiceFactoryConfig) GetNotificationRoutes() ([]toolchainconfig.NotificationRoute, error) {
+	return c.Routes, c.WebhooksErr
 }
 
 type MockNotificationDeliveryServiceConfig struct {
@@ -45,6 +62,49 @@ func (c *MockNotificationDeliveryServiceFactoryConfig) GetMailgunReplyToEmail()
 	return c.Mailgun.ReplyToEmail
 }
 
+type MockSMTPConfiguration struct {
+	Host         string
+	Port         int
+	TLSMode      string
+	PoolSize     int
+	Username     string
+	Password     string
+	SenderEmail  string
+	ReplyToEmail string
+}
+
+func (c *MockNotificationDeliveryServiceFactoryConfig) GetSMTPHost() string {
+	return c.SMTP.Host
+}
+
+func (c *MockNotificationDeliveryServiceFactoryConfig) GetSMTPPort() int {
+	return c.SMTP.Port
+}
+
+func (c *MockNotificationDeliveryServiceFactoryConfig) GetSMTPTLSMode() string {
+	return c.SMTP.TLSMode
+}
+
+func (c *MockNotificationDeliveryServiceFactoryConfig) GetSMTPPoolSize() int {
+	return c.SMTP.PoolSize
+}
+
+func (c *MockNotificationDeliveryServiceFactoryConfig) GetSMTPUsername() string {
+	return c.SMTP.Username
+}
+
+func (c *MockNotificationDeliveryServiceFactoryConfig) GetSMTPPassword() string {
+	return c.SMTP.Password
+}
+
+func (c *MockNotificationDeliveryServiceFactoryConfig) GetSMTPSenderEmail() string {
+	return c.SMTP.SenderEmail
+}
+
+func (c *MockNotificationDeliveryServiceFactoryConfig) GetSMTPReplyToEmail() string {
+	return c.SMTP.ReplyToEmail
+}
+
 func NewNotificationDeliveryServiceFactoryConfig(domain, apiKey, senderEmail, replyToEmail, service string) DeliveryServiceFactoryConfig {
 	return &MockNotificationDeliveryServiceFactoryConfig{
 		Mailgun: MockMailgunConfiguration{
@@ -62,8 +122,11 @@ type MockTemplateLoader struct {
 	templates map[string]*notificationtemplates.NotificationTemplate
 }
 
-func (l *MockTemplateLoader) GetNotificationTemplate(name string, _ string) (*notificationtemplates.NotificationTemplate, error) {
-	template := l.templates[name]
+func (l *MockTemplateLoader) GetNotificationTemplate(name, _, locale string) (*notificationtemplates.NotificationTemplate, error) {
+	template := l.templates[notificationtemplates.LocalizedTemplateName(name, locale)]
+	if template == nil {
+		template = l.templates[name]
+	}
 	if template != nil {
 		return template, nil
 	}
@@ -74,9 +137,11 @@ func NewMockTemplateLoader(templates ...*notificationtemplates.NotificationTempl
 	tmpl := make(map[string]*notificationtemplates.NotificationTemplate)
 	for _, template := range templates {
 		tmpl[template.Name] = &notificationtemplates.NotificationTemplate{
-			Subject: template.Subject,
-			Content: template.Content,
-			Name:    template.Name,
+			Subject:     template.Subject,
+			Content:     template.Content,
+			TextContent: template.TextContent,
+			Partials:    template.Partials,
+			Name:        template.Name,
 		}
 	}
 	return &MockTemplateLoader{tmpl}
@@ -97,6 +162,56 @@ func TestNotificationDeliveryServiceFactory(t *testing.T) {
 		require.IsType(t, &MailgunNotificationDeliveryService{}, svc)
 	})
 
+	t.Run("factory configured with smtp delivery service", func(t *testing.T) {
+		// when
+		factory := NewNotificationDeliveryServiceFactory(client, NewNotificationDeliveryServiceFactoryConfig(
+			"", "", "noreply@foo.com", "", "smtp"))
+		svc, err := factory.CreateNotificationDeliveryService()
+
+		// then
+		require.NoError(t, err)
+		require.IsType(t, &SMTPNotificationDeliveryService{}, svc)
+	})
+
+	t.Run("factory configured with notification routes", func(t *testing.T) {
+		// given
+		config := NewNotificationDeliveryServiceFactoryConfig("mg.foo.com", "abcd12345", "noreply@foo.com", "", "mailgun")
+		config.(*MockNotificationDeliveryServiceFactoryConfig).Webhooks = []toolchainconfig.NotificationWebhook{{Name: "ops", URL: "https://ops.foo.com"}}
+		config.(*MockNotificationDeliveryServiceFactoryConfig).Routes = []toolchainconfig.NotificationRoute{{NotificationTypes: []string{"toolchainstatus-unready"}, Webhooks: []string{"ops"}}}
+
+		t.Run("valid", func(t *testing.T) {
+			// when
+			svc, err := NewNotificationDeliveryServiceFactory(client, config).CreateNotificationDeliveryService()
+
+			// then
+			require.NoError(t, err)
+			require.IsType(t, &RoutingNotificationDeliveryService{}, svc)
+			require.IsType(t, &MailgunNotificationDeliveryService{}, svc.(*RoutingNotificationDeliveryService).Email)
+		})
+
+		t.Run("unknown webhook", func(t *testing.T) {
+			// given
+			config.(*MockNotificationDeliveryServiceFactoryConfig).Routes = []toolchainconfig.NotificationRoute{{NotificationTypes: []string{"toolchainstatus-unready"}, Webhooks: []string{"unknown"}}}
+
+			// when
+			_, err := NewNotificationDeliveryServiceFactory(client, config).CreateNotificationDeliveryService()
+
+			// then
+			require.EqualError(t, err, "invalid notification routing configuration: unknown webhook 'unknown'")
+		})
+
+		t.Run("invalid configuration", func(t *testing.T) {
+			// given
+			config.(*MockNotificationDeliveryServiceFactoryConfig).WebhooksErr = fmt.Errorf("invalid notification webhooks configuration")
+
+			// when
+			_, err := NewNotificationDeliveryServiceFactory(client, config).CreateNotificationDeliveryService()
+
+			// then
+			require.EqualError(t, err, "invalid notification webhooks configuration")
+		})
+	})
+
 	t.Run("factory configured with invalid delivery service", func(t *testing.T) {
 
 		// when
@@ -111,6 +226,22 @@ func TestNotificationDeliveryServiceFactory(t *testing.T) {
 	})
 }
 
+func TestIsPermanentDeliveryError(t *testing.T) {
+	permanent := NewPermanentDeliveryError(errors.New("invalid template"))
+	transient := errors.New("connection refused")
+
+	assert.True(t, IsPermanentDeliveryError(permanent))
+	assert.True(t, IsPermanentDeliveryError(fmt.Errorf("wrapped: %w", permanent)))
+	assert.True(t, IsPermanentDeliveryError(WebhookDeliveryError{statusCode: 400, permanent: true}))
+	assert.True(t, IsPermanentDeliveryError(SMTPDeliveryError{permanent: true}))
+	assert.True(t, IsPermanentDeliveryError(MailgunDeliveryError{permanent: true}))
+	assert.True(t, IsPermanentDeliveryError(errors.Join(permanent, WebhookDeliveryError{permanent: true})))
+	assert.False(t, IsPermanentDeliveryError(transient))
+	assert.False(t, IsPermanentDeliveryError(WebhookDeliveryError{statusCode: 503}))
+	assert.False(t, IsPermanentDeliveryError(MailgunDeliveryError{}))
+	assert.False(t, IsPermanentDeliveryError(errors.Join(permanent, transient)))
+}
+
 func TestBaseNotificationDeliveryServiceGenerateContent(t *testing.T) {
 	// given
 	baseService := &BaseNotificationDeliveryService{}
@@ -162,4 +293,141 @@ func TestBaseNotificationDeliveryServiceGenerateContent(t *testing.T) {
 		require.NoError(t, err)
 		require.Equal(t, "Increase developer productivity at Red Hat today!", content)
 	})
+
+	t.Run("missing key", func(t *testing.T) {
+		def := "hello, {{.UserName}}"
+
+		t.Run("rendered as no value by default", func(t *testing.T) {
+			// when
+			content, err := baseService.GenerateContent(nCtx, def)
+
+			// then
+			require.NoError(t, err)
+			require.Equal(t, "hello, <no value>", content)
+		})
+
+		t.Run("fails with the strict context", func(t *testing.T) {
+			// given
+			strictService := &BaseNotificationDeliveryService{StrictContext: true}
+
+			// when
+			_, err := strictService.GenerateContent(nCtx, def)
+
+			// then
+			require.ErrorContains(t, err, `map has no entry for key "UserName"`)
+		})
+	})
+}
+
+func TestBaseNotificationDeliveryServiceRenderNotification(t *testing.T) {
+	// given
+	baseService := &BaseNotificationDeliveryService{
+		TemplateLoader: NewMockTemplateLoader(
+			&notificationtemplates.NotificationTemplate{
+				Name:    "welcome",
+				Subject: "Welcome {{.FirstName}}",
+				Content: "<p>Hello {{.FirstName}}</p>",
+			}),
+		StrictContext: true,
+	}
+	notification := &toolchainv1alpha1.Notification{
+		Spec: toolchainv1alpha1.NotificationSpec{
+			Template: "welcome",
+			Context: map[string]string{
+				"FirstName": "John",
+			},
+		},
+	}
+
+	t.Run("success", func(t *testing.T) {
+		// when
+		subject, html, text, err := baseService.RenderNotification(notification, notificationtemplates.SandboxTemplateSetName, "")
+
+		// then
+		require.NoError(t, err)
+		assert.Equal(t, "Welcome John", subject)
+		assert.Equal(t, "<p>Hello John</p>", html)
+		assert.Contains(t, text, "Hello John")
+	})
+
+	t.Run("missing key in the context", func(t *testing.T) {
+		// given
+		notification := notification.DeepCopy()
+		notification.Spec.Context = map[string]string{}
+
+		// when
+		_, _, _, err := baseService.RenderNotification(notification, notificationtemplates.SandboxTemplateSetName, "")
+
+		// then
+		require.ErrorContains(t, err, `map has no entry for key "FirstName"`)
+	})
+
+	t.Run("unknown template", func(t *testing.T) {
+		// given
+		notification := notification.DeepCopy()
+		notification.Spec.Template = "unknown"
+
+		// when
+		_, _, _, err := baseService.RenderNotification(notification, notificationtemplates.SandboxTemplateSetName, "")
+
+		// then
+		require.Error(t, err)
+	})
+}
+
+func TestBaseNotificationDeliveryServiceGenerateMessage(t *testing.T) {
+	// given
+	baseService := &BaseNotificationDeliveryService{
+		TemplateLoader: NewMockTemplateLoader(
+			&notificationtemplates.NotificationTemplate{
+				Name:    "welcome",
+				Subject: "Welcome {{.FirstName}}",
+				Content: "<p>Hello {{.FirstName}}</p>",
+			},
+			&notificationtemplates.NotificationTemplate{
+				Name:    notificationtemplates.LocalizedTemplateName("welcome", "fr"),
+				Subject: "Bienvenue {{.FirstName}}",
+				Content: "<p>Bonjour {{.FirstName}}</p>",
+			}),
+	}
+
+	for locale, expected := range map[string][]string{
+		"":   {"Welcome John", "<p>Hello John</p>"},
+		"fr": {"Bienvenue John", "<p>Bonjour John</p>"},
+		"de": {"Welcome John", "<p>Hello John</p>"},
+	} {
+		t.Run("locale "+locale, func(t *testing.T) {
+			// when
+			msg, err := baseService.generateMessage(&toolchainv1alpha1.Notification{
+				Spec: toolchainv1alpha1.NotificationSpec{
+					Template: "welcome",
+					Context: map[string]string{
+						"FirstName": "John",
+						toolchainconfig.NotificationContextLocaleKey: locale,
+					},
+				},
+			}, notificationtemplates.SandboxTemplateSetName, "")
+
+			// then
+			require.NoError(t, err)
+			assert.Equal(t, expected, []string{msg.subject, msg.html})
+		})
+	}
+
+	t.Run("context of the notification is unchanged", func(t *testing.T) {
+		// given
+		notification := &toolchainv1alpha1.Notification{
+			Spec: toolchainv1alpha1.NotificationSpec{
+				Template: "welcome",
+				Context:  map[string]string{"FirstName": "John"},
+			},
+		}
+
+		// when
+		_, err := baseService.generateMessage(notification, notificationtemplates.SandboxTemplateSetName, "support@foo.com")
+
+		// then
+		require.NoError(t, err)
+		assert.Equal(t, map[string]string{"FirstName": "John"}, notification.Spec.Context)
+	})
 }
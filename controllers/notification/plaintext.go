@@ -0,0 +1,48 @@
+package notification
+
+import (
+	"html"
+	"regexp"
+	"strings"
+)
+
+var (
+	// invisibleElements matches the elements whose content is not displayed
+	invisibleElements = regexp.MustCompile(`(?is)<(head|style|script|title)\b.*?</(head|style|script|title)\s*>`)
+	// lineBreaks matches the tags which end a line of text, including the end of line which follows a <br> in the source
+	lineBreaks = regexp.MustCompile(`(?i)<br\s*/?>[ \t]*\r?\n?|</(p|div|h[1-6]|li|tr|table|ul|ol)\s*>`)
+	// links matches the links, to keep their target in the text
+	links = regexp.MustCompile(`(?is)<a\s[^>]*href="([^"]*)"[^>]*>(.*?)</a\s*>`)
+	// tags matches any remaining tag or comment
+	tags = regexp.MustCompile(`(?s)<!--.*?-->|<[^>]*>`)
+	// spaces matches the consecutive horizontal whitespaces
+	spaces = regexp.MustCompile(`[ \t\r\f\v]+`)
+	// blankLines matches more than one consecutive blank line
+	blankLines = regexp.MustCompile(`\n{3,}`)
+)
+
+// htmlToText returns a plain text version of the given HTML content, to be used as the text alternative of an email when
+// the template doesn't provide one. This is not a full HTML renderer: it only keeps the text, the line breaks and the links.
+func htmlToText(content string) string {
+	text := invisibleElements.ReplaceAllString(content, "")
+	text = links.ReplaceAllStringFunc(text, func(link string) string {
+		m := links.FindStringSubmatch(link)
+		label := strings.TrimSpace(tags.ReplaceAllString(m[2], ""))
+		if label == "" || label == m[1] {
+			return m[1]
+		}
+		return label + " (" + m[1] + ")"
+	})
+	text = lineBreaks.ReplaceAllString(text, "\n")
+	text = tags.ReplaceAllString(text, "")
+	text = html.UnescapeString(text)
+
+	lines := strings.Split(text, "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimSpace(spaces.ReplaceAllString(line, " "))
+	}
+	text = strings.Join(lines, "\n")
+	// paragraphs are separated by a single blank line
+	text = blankLines.ReplaceAllString(text, "\n\n")
+	return strings.TrimSpace(text)
+}
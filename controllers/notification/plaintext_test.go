@@ -0,0 +1,63 @@
+package notification
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHTMLToText(t *testing.T) {
+	for name, tc := range map[string]struct {
+		html     string
+		expected string
+	}{
+		"plain text": {
+			html:     "no markup here",
+			expected: "no markup here",
+		},
+		"document": {
+			html: `<!DOCTYPE html>
+<html lang="en">
+<head>
+    <title>Notice</title>
+    <style>
+        p { margin: 30px 0; }
+    </style>
+</head>
+<body>
+<div>
+    <p>
+        You are receiving this email because the account associated with john@redhat.com
+        is now ready to use.
+    </p>
+
+    <!-- a comment -->
+    <p>
+        Check out our <a href="https://developers.redhat.com/activities">activities</a> or https://redhat.com!
+    </p>
+
+    <p>
+        Thanks,<br />
+        The team &amp; friends
+    </p>
+</div>
+</body>
+</html>`,
+			expected: "You are receiving this email because the account associated with john@redhat.com\n" +
+				"is now ready to use.\n" +
+				"\n" +
+				"Check out our activities (https://developers.redhat.com/activities) or https://redhat.com!\n" +
+				"\n" +
+				"Thanks,\n" +
+				"The team & friends",
+		},
+		"link without label": {
+			html:     `<a href="https://redhat.com">https://redhat.com</a>`,
+			expected: "https://redhat.com",
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, htmlToText(tc.html))
+		})
+	}
+}
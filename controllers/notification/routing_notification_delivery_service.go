@@ -0,0 +1,156 @@
+package notification
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	toolchainv1alpha1 "github.com/codeready-toolchain/api/api/v1alpha1"
+	"github.com/codeready-toolchain/host-operator/controllers/toolchainconfig"
+)
+
+const (
+	// DeliveredChannelsAnnotationKey is set on a Notification when its delivery failed on some of the delivery channels selected by its route.
+	// The value is the comma-separated list of the channels (`email` or `webhook:<name>`) the notification was already delivered to,
+	// which are skipped when the delivery is attempted again.
+	DeliveredChannelsAnnotationKey = toolchainv1alpha1.LabelKeyPrefix + "delivered-channels"
+
+	emailChannel         = "email"
+	webhookChannelPrefix = "webhook:"
+)
+
+// RoutingNotificationDeliveryService sends the notifications to the delivery channels selected by the routes matching their type.
+// The notifications which don't match any route are sent by email.
+type RoutingNotificationDeliveryService struct {
+	Email    DeliveryService
+	Webhooks map[string]DeliveryService
+	Routes   []toolchainconfig.NotificationRoute
+}
+
+// NewRoutingNotificationDeliveryService creates a delivery service which routes the notifications between the given email delivery service
+// and the configured webhooks
+func NewRoutingNotificationDeliveryService(email DeliveryService, config WebhookConfig, templateLoader TemplateLoader,
+	opts ...WebhookOption) (DeliveryService, error) {
+
+	webhooksConfig, err := config.GetNotificationWebhooks()
+	if err != nil {
+		return nil, err
+	}
+	webhooks := map[string]DeliveryService{}
+	for _, webhook := range webhooksConfig {
+		svc, err := NewWebhookNotificationDeliveryService(webhook, templateLoader, opts...)
+		if err != nil {
+			return nil, err
+		}
+		webhooks[webhook.Name] = svc
+	}
+	routes, err := config.GetNotificationRoutes()
+	if err != nil {
+		return nil, err
+	}
+	for _, route := range routes {
+		for _, name := range route.Webhooks {
+			if _, found := webhooks[name]; !found {
+				return nil, fmt.Errorf("invalid notification routing configuration: unknown webhook '%s'", name)
+			}
+		}
+	}
+
+	return &RoutingNotificationDeliveryService{
+		Email:    email,
+		Webhooks: webhooks,
+		Routes:   routes,
+	}, nil
+}
+
+// Close closes the delivery services of the channels which hold resources, such as the SMTP delivery service
+func (s *RoutingNotificationDeliveryService) Close() error {
+	services := []DeliveryService{s.Email}
+	for _, svc := range s.Webhooks {
+		services = append(services, svc)
+	}
+	var errs []error
+	for _, svc := range services {
+		if closer, ok := svc.(io.Closer); ok {
+			errs = append(errs, closer.Close())
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Send sends the notification to all the channels of its route, except the channels it was already delivered to in a previous attempt.
+// When some of the channels fail, the channels the notification was delivered to are recorded in the DeliveredChannelsAnnotationKey
+// annotation of the notification, which is persisted with the failed delivery attempt.
+func (s *RoutingNotificationDeliveryService) Send(notification *toolchainv1alpha1.Notification, templateSetName string) error {
+	route, found := s.findRoute(notification)
+	if !found {
+		return s.Email.Send(notification, templateSetName)
+	}
+
+	delivered := getDeliveredChannels(notification)
+	var errs []error
+	send := func(channel string, svc DeliveryService) {
+		if delivered[channel] {
+			return
+		}
+		if err := svc.Send(notification, templateSetName); err != nil {
+			errs = append(errs, err)
+			return
+		}
+		delivered[channel] = true
+	}
+	for _, name := range route.Webhooks {
+		send(webhookChannelPrefix+name, s.Webhooks[name])
+	}
+	if route.Email {
+		send(emailChannel, s.Email)
+	}
+	if len(errs) > 0 {
+		setDeliveredChannels(notification, delivered)
+	}
+	return errors.Join(errs...)
+}
+
+// getDeliveredChannels returns the channels the notification was already delivered to
+func getDeliveredChannels(notification *toolchainv1alpha1.Notification) map[string]bool {
+	delivered := map[string]bool{}
+	for _, channel := range strings.Split(notification.Annotations[DeliveredChannelsAnnotationKey], ",") {
+		if channel != "" {
+			delivered[channel] = true
+		}
+	}
+	return delivered
+}
+
+func setDeliveredChannels(notification *toolchainv1alpha1.Notification, delivered map[string]bool) {
+	if len(delivered) == 0 {
+		return
+	}
+	channels := make([]string, 0, len(delivered))
+	for channel := range delivered {
+		channels = append(channels, channel)
+	}
+	sort.Strings(channels)
+	if notification.Annotations == nil {
+		notification.Annotations = map[string]string{}
+	}
+	notification.Annotations[DeliveredChannelsAnnotationKey] = strings.Join(channels, ",")
+}
+
+// findRoute returns the first route matching the type of the given notification
+func (s *RoutingNotificationDeliveryService) findRoute(notification *toolchainv1alpha1.Notification) (toolchainconfig.NotificationRoute, bool) {
+	notificationType, found := notification.Labels[toolchainv1alpha1.NotificationTypeLabelKey]
+	if !found {
+		return toolchainconfig.NotificationRoute{}, false
+	}
+	for _, route := range s.Routes {
+		for _, t := range route.NotificationTypes {
+			if t == notificationType {
+				return route, true
+			}
+		}
+	}
+	return toolchainconfig.NotificationRoute{}, false
+}
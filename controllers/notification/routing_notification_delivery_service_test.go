@@ -0,0 +1,173 @@
+package notification
+
+import (
+	"errors"
+	"testing"
+
+	toolchainv1alpha1 "github.com/codeready-toolchain/api/api/v1alpha1"
+	"github.com/codeready-toolchain/host-operator/controllers/toolchainconfig"
+	"github.com/codeready-toolchain/host-operator/pkg/templates/notificationtemplates"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+type recordingDeliveryService struct {
+	sent []*toolchainv1alpha1.Notification
+	err  error
+}
+
+func (s *recordingDeliveryService) Send(notification *toolchainv1alpha1.Notification, _ string) error {
+	s.sent = append(s.sent, notification)
+	return s.err
+}
+
+func TestRoutingNotificationDeliveryService(t *testing.T) {
+	newNotification := func(notificationType string) *toolchainv1alpha1.Notification {
+		n := &toolchainv1alpha1.Notification{
+			ObjectMeta: metav1.ObjectMeta{Name: "notification"},
+		}
+		if notificationType != "" {
+			n.Labels = map[string]string{toolchainv1alpha1.NotificationTypeLabelKey: notificationType}
+		}
+		return n
+	}
+	newService := func() (*RoutingNotificationDeliveryService, *recordingDeliveryService, *recordingDeliveryService, *recordingDeliveryService) {
+		email, ops, chat := &recordingDeliveryService{}, &recordingDeliveryService{}, &recordingDeliveryService{}
+		return &RoutingNotificationDeliveryService{
+			Email:    email,
+			Webhooks: map[string]DeliveryService{"ops": ops, "chat": chat},
+			Routes: []toolchainconfig.NotificationRoute{
+				{NotificationTypes: []string{"toolchainstatus-unready"}, Webhooks: []string{"ops", "chat"}},
+				{NotificationTypes: []string{"toolchainstatus-restored"}, Webhooks: []string{"chat"}, Email: true},
+			},
+		}, email, ops, chat
+	}
+
+	t.Run("notification without type is sent by email", func(t *testing.T) {
+		// given
+		svc, email, ops, chat := newService()
+
+		// when
+		err := svc.Send(newNotification(""), notificationtemplates.SandboxTemplateSetName)
+
+		// then
+		require.NoError(t, err)
+		assert.Len(t, email.sent, 1)
+		assert.Empty(t, ops.sent)
+		assert.Empty(t, chat.sent)
+	})
+
+	t.Run("notification not matching any route is sent by email", func(t *testing.T) {
+		// given
+		svc, email, ops, chat := newService()
+
+		// when
+		err := svc.Send(newNotification(toolchainv1alpha1.NotificationTypeDeactivated), notificationtemplates.SandboxTemplateSetName)
+
+		// then
+		require.NoError(t, err)
+		assert.Len(t, email.sent, 1)
+		assert.Empty(t, ops.sent)
+		assert.Empty(t, chat.sent)
+	})
+
+	t.Run("notification is sent to webhooks only", func(t *testing.T) {
+		// given
+		svc, email, ops, chat := newService()
+
+		// when
+		err := svc.Send(newNotification("toolchainstatus-unready"), notificationtemplates.SandboxTemplateSetName)
+
+		// then
+		require.NoError(t, err)
+		assert.Empty(t, email.sent)
+		assert.Len(t, ops.sent, 1)
+		assert.Len(t, chat.sent, 1)
+	})
+
+	t.Run("notification is sent to webhook and by email", func(t *testing.T) {
+		// given
+		svc, email, ops, chat := newService()
+
+		// when
+		err := svc.Send(newNotification("toolchainstatus-restored"), notificationtemplates.SandboxTemplateSetName)
+
+		// then
+		require.NoError(t, err)
+		assert.Len(t, email.sent, 1)
+		assert.Empty(t, ops.sent)
+		assert.Len(t, chat.sent, 1)
+	})
+
+	t.Run("failure of one channel does not prevent the delivery to the others", func(t *testing.T) {
+		// given
+		svc, email, ops, chat := newService()
+		ops.err = errors.New("ops is down")
+
+		// when
+		err := svc.Send(newNotification("toolchainstatus-unready"), notificationtemplates.SandboxTemplateSetName)
+
+		// then
+		require.EqualError(t, err, "ops is down")
+		assert.Empty(t, email.sent)
+		assert.Len(t, ops.sent, 1)
+		assert.Len(t, chat.sent, 1)
+	})
+
+	t.Run("retry is only sent to the channels which failed", func(t *testing.T) {
+		// given
+		svc, email, ops, chat := newService()
+		svc.Routes[0].Email = true
+		ops.err = errors.New("ops is down")
+		notification := newNotification("toolchainstatus-unready")
+
+		// when
+		err := svc.Send(notification, notificationtemplates.SandboxTemplateSetName)
+
+		// then
+		require.EqualError(t, err, "ops is down")
+		assert.Equal(t, "email,webhook:chat", notification.Annotations[DeliveredChannelsAnnotationKey])
+
+		t.Run("retry fails again", func(t *testing.T) {
+			// when
+			err := svc.Send(notification, notificationtemplates.SandboxTemplateSetName)
+
+			// then
+			require.EqualError(t, err, "ops is down")
+			assert.Len(t, email.sent, 1)
+			assert.Len(t, ops.sent, 2)
+			assert.Len(t, chat.sent, 1)
+			assert.Equal(t, "email,webhook:chat", notification.Annotations[DeliveredChannelsAnnotationKey])
+		})
+
+		t.Run("retry succeeds", func(t *testing.T) {
+			// given
+			ops.err = nil
+
+			// when
+			err := svc.Send(notification, notificationtemplates.SandboxTemplateSetName)
+
+			// then
+			require.NoError(t, err)
+			assert.Len(t, email.sent, 1)
+			assert.Len(t, ops.sent, 3)
+			assert.Len(t, chat.sent, 1)
+		})
+	})
+
+	t.Run("closes the email delivery service", func(t *testing.T) {
+		// given
+		svc, _, _, _ := newService()
+		email := &closableDeliveryService{DeliveryService: &recordingDeliveryService{}}
+		svc.Email = email
+
+		// when
+		err := svc.Close()
+
+		// then
+		require.NoError(t, err)
+		assert.True(t, email.closed)
+	})
+}
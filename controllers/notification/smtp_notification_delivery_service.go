@@ -0,0 +1,355 @@
+package notification
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/tls"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"time"
+
+	toolchainv1alpha1 "github.com/codeready-toolchain/api/api/v1alpha1"
+	"github.com/codeready-toolchain/host-operator/controllers/toolchainconfig"
+)
+
+const (
+	smtpDialTimeout = 10 * time.Second
+	// smtpTimeout is the deadline of each exchange with the SMTP server (eg. the greeting, STARTTLS and the authentication, or
+	// the delivery of a message), so that a server which stops responding doesn't block the reconcile forever
+	smtpTimeout = 30 * time.Second
+)
+
+type SMTPDeliveryError struct {
+	recipient    string
+	errorMessage string
+	permanent    bool
+}
+
+func (e SMTPDeliveryError) Error() string {
+	return fmt.Sprintf("error while delivering notification via SMTP (Recipient: %s) - %s", e.recipient, e.errorMessage)
+}
+
+// Permanent returns true if the message was rejected (invalid address or 5xx reply), in which case retrying will not help
+func (e SMTPDeliveryError) Permanent() bool {
+	return e.permanent
+}
+
+func NewSMTPDeliveryError(recipient, errorMessage string) error {
+	return SMTPDeliveryError{
+		recipient:    recipient,
+		errorMessage: errorMessage,
+	}
+}
+
+type SMTPConfig interface {
+	GetSMTPHost() string
+	GetSMTPPort() int
+	GetSMTPTLSMode() string
+	GetSMTPPoolSize() int
+	GetSMTPUsername() string
+	GetSMTPPassword() string
+	GetSMTPSenderEmail() string
+	GetSMTPReplyToEmail() string
+}
+
+type SMTPOption interface {
+	// ApplyToSMTP applies this configuration to the given SMTP delivery service.
+	ApplyToSMTP(*SMTPNotificationDeliveryService)
+}
+
+// SMTPNotificationDeliveryService delivers the notifications via a plain SMTP server.
+// The connections to the server are kept open (up to the configured pool size) and reused between the deliveries.
+type SMTPNotificationDeliveryService struct {
+	base         BaseNotificationDeliveryService
+	Host         string
+	Port         int
+	TLSMode      string
+	TLSConfig    *tls.Config
+	Username     string
+	Password     string
+	SenderEmail  string
+	ReplyToEmail string
+	pool         chan *smtpConn
+	timeout      time.Duration
+}
+
+// smtpConn is a connection to the SMTP server, whose underlying network connection is kept to set the deadlines of the exchanges
+type smtpConn struct {
+	*smtp.Client
+	conn net.Conn
+}
+
+// setDeadline sets the deadline of the next exchange with the SMTP server
+func (c *smtpConn) setDeadline(timeout time.Duration) error {
+	return c.conn.SetDeadline(time.Now().Add(timeout))
+}
+
+// NewSMTPNotificationDeliveryService creates a delivery service that uses an SMTP server to deliver email notifications
+func NewSMTPNotificationDeliveryService(config DeliveryServiceFactoryConfig, templateLoader TemplateLoader,
+	opts ...SMTPOption) DeliveryService {
+
+	s := &SMTPNotificationDeliveryService{
+		base:         BaseNotificationDeliveryService{TemplateLoader: templateLoader},
+		Host:         config.GetSMTPHost(),
+		Port:         config.GetSMTPPort(),
+		TLSMode:      config.GetSMTPTLSMode(),
+		TLSConfig:    &tls.Config{ServerName: config.GetSMTPHost(), MinVersion: tls.VersionTLS12},
+		Username:     config.GetSMTPUsername(),
+		Password:     config.GetSMTPPassword(),
+		SenderEmail:  config.GetSMTPSenderEmail(),
+		ReplyToEmail: config.GetSMTPReplyToEmail(),
+		pool:         make(chan *smtpConn, config.GetSMTPPoolSize()),
+		timeout:      smtpTimeout,
+	}
+
+	for _, opt := range opts {
+		opt.ApplyToSMTP(s)
+	}
+
+	return s
+}
+
+func (s *SMTPNotificationDeliveryService) Send(notification *toolchainv1alpha1.Notification, templateSetName string) error {
+	replyTo := s.ReplyToEmail
+	if replyTo == "" {
+		replyTo = s.SenderEmail
+	}
+	msg, err := s.base.generateMessage(notification, templateSetName, replyTo)
+	if err != nil {
+		return err
+	}
+
+	messageID, message, err := s.newMessage(notification.Spec.Recipient, msg)
+	if err != nil {
+		return SMTPDeliveryError{
+			recipient:    notification.Spec.Recipient,
+			errorMessage: err.Error(),
+			permanent:    true,
+		}
+	}
+
+	client, err := s.getClient()
+	if err != nil {
+		return NewSMTPDeliveryError(notification.Spec.Recipient, err.Error())
+	}
+	if err := s.send(client, notification.Spec.Recipient, message); err != nil {
+		// the state of the connection is unknown, so don't return it to the pool
+		_ = client.Close()
+		var smtpErr *textproto.Error
+		return SMTPDeliveryError{
+			recipient:    notification.Spec.Recipient,
+			errorMessage: err.Error(),
+			permanent:    errors.As(err, &smtpErr) && smtpErr.Code >= 500,
+		}
+	}
+	s.releaseClient(client)
+	setMessageID(notification, messageID)
+	return nil
+}
+
+func (s *SMTPNotificationDeliveryService) send(client *smtpConn, recipient string, message []byte) error {
+	if err := client.setDeadline(s.timeout); err != nil {
+		return err
+	}
+	if err := client.Mail(s.SenderEmail); err != nil {
+		return err
+	}
+	if err := client.Rcpt(recipient); err != nil {
+		return err
+	}
+	w, err := client.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(message); err != nil {
+		return err
+	}
+	return w.Close()
+}
+
+// newMessage returns the ID and the content of the email to send to the given recipient: the headers and a multipart/alternative body
+// with the plain text and the HTML versions of the message
+func (s *SMTPNotificationDeliveryService) newMessage(recipient string, msg message) (string, []byte, error) {
+	from, err := mail.ParseAddress(s.SenderEmail)
+	if err != nil {
+		return "", nil, fmt.Errorf("invalid sender email: %w", err)
+	}
+	to, err := mail.ParseAddress(recipient)
+	if err != nil {
+		return "", nil, fmt.Errorf("invalid recipient email: %w", err)
+	}
+	var replyTo *mail.Address
+	if s.ReplyToEmail != "" {
+		if replyTo, err = mail.ParseAddress(s.ReplyToEmail); err != nil {
+			return "", nil, fmt.Errorf("invalid reply-to email: %w", err)
+		}
+	}
+	messageID, err := newMessageID(from.Address)
+	if err != nil {
+		return "", nil, err
+	}
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "From: %s\r\n", from.String())
+	fmt.Fprintf(&buf, "To: %s\r\n", to.String())
+	if replyTo != nil {
+		fmt.Fprintf(&buf, "Reply-To: %s\r\n", replyTo.String())
+	}
+	fmt.Fprintf(&buf, "Subject: %s\r\n", mime.QEncoding.Encode("UTF-8", msg.subject))
+	fmt.Fprintf(&buf, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	fmt.Fprintf(&buf, "Message-ID: %s\r\n", messageID)
+	buf.WriteString("MIME-Version: 1.0\r\n")
+
+	parts := multipart.NewWriter(&buf)
+	fmt.Fprintf(&buf, "Content-Type: multipart/alternative; boundary=%s\r\n", parts.Boundary())
+	buf.WriteString("\r\n")
+	// the preferred alternative comes last
+	if err := writePart(parts, "text/plain; charset=UTF-8", msg.text); err != nil {
+		return "", nil, err
+	}
+	if err := writePart(parts, "text/html; charset=UTF-8", msg.html); err != nil {
+		return "", nil, err
+	}
+	if err := parts.Close(); err != nil {
+		return "", nil, err
+	}
+	return messageID, buf.Bytes(), nil
+}
+
+// newMessageID returns a unique message ID in the domain of the given sender address (eg. `<2f1d...@example.com>`),
+// so that the delivery events reported by the SMTP relay can be matched with the notification
+func newMessageID(sender string) (string, error) {
+	domain := sender[strings.LastIndex(sender, "@")+1:]
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("<%s@%s>", hex.EncodeToString(b), domain), nil
+}
+
+// writePart writes a quoted-printable encoded part with the given content type and content
+func writePart(parts *multipart.Writer, contentType, content string) error {
+	part, err := parts.CreatePart(textproto.MIMEHeader{
+		"Content-Type":              {contentType},
+		"Content-Transfer-Encoding": {"quoted-printable"},
+	})
+	if err != nil {
+		return err
+	}
+	w := quotedprintable.NewWriter(part)
+	if _, err := w.Write([]byte(content)); err != nil {
+		return err
+	}
+	return w.Close()
+}
+
+// getClient returns an idle connection from the pool if there is one which is still usable, or opens a new one otherwise
+func (s *SMTPNotificationDeliveryService) getClient() (*smtpConn, error) {
+	for {
+		select {
+		case client := <-s.pool:
+			if err := client.setDeadline(s.timeout); err != nil {
+				_ = client.Close()
+				continue
+			}
+			if err := client.Noop(); err != nil {
+				// the server closed the connection in the meantime
+				_ = client.Close()
+				continue
+			}
+			return client, nil
+		default:
+			return s.dial()
+		}
+	}
+}
+
+// Close closes the idle connections of the pool. The delivery service must not be used anymore once closed.
+func (s *SMTPNotificationDeliveryService) Close() error {
+	for {
+		select {
+		case client := <-s.pool:
+			if err := client.setDeadline(s.timeout); err != nil {
+				_ = client.Close()
+				continue
+			}
+			_ = client.Quit()
+		default:
+			return nil
+		}
+	}
+}
+
+// releaseClient returns the connection to the pool, or closes it if the pool is already full
+func (s *SMTPNotificationDeliveryService) releaseClient(client *smtpConn) {
+	if err := client.setDeadline(s.timeout); err != nil {
+		_ = client.Close()
+		return
+	}
+	if err := client.Reset(); err != nil {
+		_ = client.Close()
+		return
+	}
+	select {
+	case s.pool <- client:
+	default:
+		_ = client.Quit()
+	}
+}
+
+// dial opens a new connection to the SMTP server, with the TLS and the authentication configured. The greeting of the server, STARTTLS
+// and the authentication must complete before the deadline.
+func (s *SMTPNotificationDeliveryService) dial() (*smtpConn, error) {
+	addr := net.JoinHostPort(s.Host, strconv.Itoa(s.Port))
+	dialer := &net.Dialer{Timeout: smtpDialTimeout}
+
+	var conn net.Conn
+	var err error
+	if s.TLSMode == toolchainconfig.SMTPTLSModeTLS {
+		conn, err = tls.DialWithDialer(dialer, "tcp", addr, s.TLSConfig)
+	} else {
+		conn, err = dialer.Dial("tcp", addr)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := conn.SetDeadline(time.Now().Add(s.timeout)); err != nil {
+		_ = conn.Close()
+		return nil, err
+	}
+	client, err := smtp.NewClient(conn, s.Host)
+	if err != nil {
+		_ = conn.Close()
+		return nil, err
+	}
+
+	if s.TLSMode == toolchainconfig.SMTPTLSModeStartTLS {
+		if ok, _ := client.Extension("STARTTLS"); !ok {
+			_ = client.Close()
+			return nil, fmt.Errorf("SMTP server %s does not support STARTTLS", addr)
+		}
+		if err := client.StartTLS(s.TLSConfig); err != nil {
+			_ = client.Close()
+			return nil, err
+		}
+	}
+
+	if s.Username != "" {
+		if err := client.Auth(smtp.PlainAuth("", s.Username, s.Password, s.Host)); err != nil {
+			_ = client.Close()
+			return nil, err
+		}
+	}
+	return &smtpConn{Client: client, conn: conn}, nil
+}
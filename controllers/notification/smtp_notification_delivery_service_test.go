@@ -0,0 +1,601 @@
+package notification
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"io"
+	"math/big"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net"
+	"net/mail"
+	"net/textproto"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	toolchainv1alpha1 "github.com/codeready-toolchain/api/api/v1alpha1"
+	"github.com/codeready-toolchain/host-operator/controllers/toolchainconfig"
+	"github.com/codeready-toolchain/host-operator/pkg/templates/notificationtemplates"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type SMTPTLSConfigOption struct {
+	tlsConfig *tls.Config
+}
+
+func (o *SMTPTLSConfigOption) ApplyToSMTP(s *SMTPNotificationDeliveryService) {
+	s.TLSConfig = o.tlsConfig
+}
+
+func TestSMTPNotificationDeliveryService(t *testing.T) {
+	// given
+	templateLoader := NewMockTemplateLoader(
+		&notificationtemplates.NotificationTemplate{
+			Subject: "Welcome {{.FirstName}}",
+			Content: "<p>Hi {{.FirstName}}, reply to {{.ReplyTo}}</p>",
+			Name:    "welcome",
+		})
+	notification := func() *toolchainv1alpha1.Notification {
+		return &toolchainv1alpha1.Notification{
+			Spec: toolchainv1alpha1.NotificationSpec{
+				Recipient: "jsmith@redhat.com",
+				Template:  "welcome",
+				Context: map[string]string{
+					"FirstName": "John",
+				},
+			},
+		}
+	}
+
+	t.Run("starttls with authentication", func(t *testing.T) {
+		// given
+		server := newTestSMTPServer(t, toolchainconfig.SMTPTLSModeStartTLS, "smtp-user", "smtp-pass")
+		svc := NewSMTPNotificationDeliveryService(server.config("smtp-user", "smtp-pass", 1), templateLoader, server.tlsOption())
+
+		n := notification()
+
+		// when
+		err := svc.Send(n, notificationtemplates.SandboxTemplateSetName)
+
+		// then
+		require.NoError(t, err)
+		messages := server.receivedMessages()
+		require.Len(t, messages, 1)
+		assert.Equal(t, "noreply@foo.com", messages[0].from)
+		assert.Equal(t, []string{"jsmith@redhat.com"}, messages[0].to)
+		assert.True(t, messages[0].tls)
+		msg := messages[0].parse(t)
+		assert.Equal(t, "Welcome John", msg.subject)
+		assert.Equal(t, "<noreply@foo.com>", msg.header.Get("From"))
+		assert.Equal(t, "<jsmith@redhat.com>", msg.header.Get("To"))
+		assert.Equal(t, "<support@foo.com>", msg.header.Get("Reply-To"))
+		assert.Equal(t, "<p>Hi John, reply to support@foo.com</p>", msg.body)
+		assert.Equal(t, "Hi John, reply to support@foo.com", msg.text)
+		assert.Regexp(t, "^<[0-9a-f]{32}@foo.com>$", msg.header.Get("Message-ID"))
+		assert.Equal(t, strings.Trim(msg.header.Get("Message-ID"), "<>"), n.Annotations[MessageIDAnnotationKey])
+	})
+
+	t.Run("implicit tls", func(t *testing.T) {
+		// given
+		server := newTestSMTPServer(t, toolchainconfig.SMTPTLSModeTLS, "smtp-user", "smtp-pass")
+		svc := NewSMTPNotificationDeliveryService(server.config("smtp-user", "smtp-pass", 1), templateLoader, server.tlsOption())
+
+		// when
+		err := svc.Send(&toolchainv1alpha1.Notification{
+			Spec: toolchainv1alpha1.NotificationSpec{
+				Recipient: "jsmith@redhat.com",
+				Subject:   "Hello",
+				Content:   "no template here",
+			},
+		}, notificationtemplates.SandboxTemplateSetName)
+
+		// then
+		require.NoError(t, err)
+		messages := server.receivedMessages()
+		require.Len(t, messages, 1)
+		assert.True(t, messages[0].tls)
+		msg := messages[0].parse(t)
+		assert.Equal(t, "Hello", msg.subject)
+		assert.Equal(t, "no template here", msg.body)
+		assert.Equal(t, "no template here", msg.text)
+	})
+
+	t.Run("text content and partials", func(t *testing.T) {
+		// given
+		server := newTestSMTPServer(t, toolchainconfig.SMTPTLSModeNone, "", "")
+		svc := NewSMTPNotificationDeliveryService(server.config("", "", 1), NewMockTemplateLoader(
+			&notificationtemplates.NotificationTemplate{
+				Name:        "welcome",
+				Subject:     "Welcome {{.FirstName}}",
+				Content:     `{{template "header"}}<p>Hi {{.FirstName}}</p>{{template "footer"}}`,
+				TextContent: "Hi {{.FirstName}}\n-- {{template \"signature\"}}",
+				Partials:    `{{define "header"}}<html><body>{{end}}{{define "footer"}}</body></html>{{end}}{{define "signature"}}The team{{end}}`,
+			}))
+
+		// when
+		err := svc.Send(notification(), notificationtemplates.SandboxTemplateSetName)
+
+		// then
+		require.NoError(t, err)
+		messages := server.receivedMessages()
+		require.Len(t, messages, 1)
+		msg := messages[0].parse(t)
+		assert.Equal(t, "<html><body><p>Hi John</p></body></html>", msg.body)
+		assert.Equal(t, "Hi John\r\n-- The team", msg.text)
+	})
+
+	t.Run("without tls nor authentication", func(t *testing.T) {
+		// given
+		server := newTestSMTPServer(t, toolchainconfig.SMTPTLSModeNone, "", "")
+		svc := NewSMTPNotificationDeliveryService(server.config("", "", 1), templateLoader)
+
+		// when
+		err := svc.Send(notification(), notificationtemplates.SandboxTemplateSetName)
+
+		// then
+		require.NoError(t, err)
+		messages := server.receivedMessages()
+		require.Len(t, messages, 1)
+		assert.False(t, messages[0].tls)
+	})
+
+	t.Run("connections are reused", func(t *testing.T) {
+		// given
+		server := newTestSMTPServer(t, toolchainconfig.SMTPTLSModeStartTLS, "smtp-user", "smtp-pass")
+		svc := NewSMTPNotificationDeliveryService(server.config("smtp-user", "smtp-pass", 1), templateLoader, server.tlsOption())
+
+		// when
+		for i := 0; i < 3; i++ {
+			require.NoError(t, svc.Send(notification(), notificationtemplates.SandboxTemplateSetName))
+		}
+
+		// then
+		assert.Len(t, server.receivedMessages(), 3)
+		assert.Equal(t, 1, server.connectionCount())
+	})
+
+	t.Run("connections are not reused when pool is disabled", func(t *testing.T) {
+		// given
+		server := newTestSMTPServer(t, toolchainconfig.SMTPTLSModeStartTLS, "smtp-user", "smtp-pass")
+		svc := NewSMTPNotificationDeliveryService(server.config("smtp-user", "smtp-pass", 0), templateLoader, server.tlsOption())
+
+		// when
+		for i := 0; i < 3; i++ {
+			require.NoError(t, svc.Send(notification(), notificationtemplates.SandboxTemplateSetName))
+		}
+
+		// then
+		assert.Len(t, server.receivedMessages(), 3)
+		assert.Equal(t, 3, server.connectionCount())
+	})
+
+	t.Run("idle connections are closed", func(t *testing.T) {
+		// given
+		server := newTestSMTPServer(t, toolchainconfig.SMTPTLSModeStartTLS, "smtp-user", "smtp-pass")
+		svc := NewSMTPNotificationDeliveryService(server.config("smtp-user", "smtp-pass", 1), templateLoader, server.tlsOption())
+		require.NoError(t, svc.Send(notification(), notificationtemplates.SandboxTemplateSetName))
+		require.Len(t, svc.(*SMTPNotificationDeliveryService).pool, 1)
+
+		// when
+		err := svc.(io.Closer).Close()
+
+		// then
+		require.NoError(t, err)
+		assert.Empty(t, svc.(*SMTPNotificationDeliveryService).pool)
+	})
+
+	t.Run("connection closed by the server is replaced", func(t *testing.T) {
+		// given
+		server := newTestSMTPServer(t, toolchainconfig.SMTPTLSModeStartTLS, "smtp-user", "smtp-pass")
+		svc := NewSMTPNotificationDeliveryService(server.config("smtp-user", "smtp-pass", 1), templateLoader, server.tlsOption())
+		require.NoError(t, svc.Send(notification(), notificationtemplates.SandboxTemplateSetName))
+		server.closeConnections()
+
+		// when
+		err := svc.Send(notification(), notificationtemplates.SandboxTemplateSetName)
+
+		// then
+		require.NoError(t, err)
+		assert.Len(t, server.receivedMessages(), 2)
+		assert.Equal(t, 2, server.connectionCount())
+	})
+
+	t.Run("failures", func(t *testing.T) {
+
+		t.Run("invalid credentials", func(t *testing.T) {
+			// given
+			server := newTestSMTPServer(t, toolchainconfig.SMTPTLSModeStartTLS, "smtp-user", "smtp-pass")
+			svc := NewSMTPNotificationDeliveryService(server.config("smtp-user", "wrong", 1), templateLoader, server.tlsOption())
+
+			// when
+			err := svc.Send(notification(), notificationtemplates.SandboxTemplateSetName)
+
+			// then
+			require.Error(t, err)
+			require.IsType(t, SMTPDeliveryError{}, err)
+			assert.Contains(t, err.Error(), "error while delivering notification via SMTP (Recipient: jsmith@redhat.com)")
+			assert.False(t, IsPermanentDeliveryError(err))
+			assert.Empty(t, server.receivedMessages())
+		})
+
+		t.Run("starttls not supported by the server", func(t *testing.T) {
+			// given
+			server := newTestSMTPServer(t, toolchainconfig.SMTPTLSModeNone, "", "")
+			config := server.config("", "", 1)
+			config.(*MockNotificationDeliveryServiceFactoryConfig).SMTP.TLSMode = toolchainconfig.SMTPTLSModeStartTLS
+			svc := NewSMTPNotificationDeliveryService(config, templateLoader)
+
+			// when
+			err := svc.Send(notification(), notificationtemplates.SandboxTemplateSetName)
+
+			// then
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), "does not support STARTTLS")
+		})
+
+		t.Run("untrusted certificate", func(t *testing.T) {
+			// given
+			server := newTestSMTPServer(t, toolchainconfig.SMTPTLSModeStartTLS, "smtp-user", "smtp-pass")
+			svc := NewSMTPNotificationDeliveryService(server.config("smtp-user", "smtp-pass", 1), templateLoader)
+
+			// when
+			err := svc.Send(notification(), notificationtemplates.SandboxTemplateSetName)
+
+			// then
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), "certificate")
+		})
+
+		t.Run("invalid recipient", func(t *testing.T) {
+			// given
+			server := newTestSMTPServer(t, toolchainconfig.SMTPTLSModeNone, "", "")
+			svc := NewSMTPNotificationDeliveryService(server.config("", "", 1), templateLoader)
+			n := notification()
+			n.Spec.Recipient = "not an email"
+
+			// when
+			err := svc.Send(n, notificationtemplates.SandboxTemplateSetName)
+
+			// then
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), "invalid recipient email")
+			assert.True(t, IsPermanentDeliveryError(err))
+			assert.Equal(t, 0, server.connectionCount())
+		})
+
+		t.Run("invalid reply-to", func(t *testing.T) {
+			// given
+			server := newTestSMTPServer(t, toolchainconfig.SMTPTLSModeNone, "", "")
+			svc := NewSMTPNotificationDeliveryService(server.config("", "", 1), templateLoader)
+			svc.(*SMTPNotificationDeliveryService).ReplyToEmail = "support@foo.com\r\nBcc: someone@foo.com"
+
+			// when
+			err := svc.Send(notification(), notificationtemplates.SandboxTemplateSetName)
+
+			// then
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), "invalid reply-to email")
+			assert.True(t, IsPermanentDeliveryError(err))
+			assert.Equal(t, 0, server.connectionCount())
+		})
+
+		t.Run("server not responding", func(t *testing.T) {
+			// given
+			listener, err := net.Listen("tcp", "127.0.0.1:0")
+			require.NoError(t, err)
+			t.Cleanup(func() { _ = listener.Close() })
+			go func() {
+				// accept the connections but never send the greeting
+				for {
+					conn, err := listener.Accept()
+					if err != nil {
+						return
+					}
+					t.Cleanup(func() { _ = conn.Close() })
+				}
+			}()
+			config := &MockNotificationDeliveryServiceFactoryConfig{
+				SMTP: MockSMTPConfiguration{
+					Host:        "127.0.0.1",
+					Port:        listener.Addr().(*net.TCPAddr).Port,
+					TLSMode:     toolchainconfig.SMTPTLSModeNone,
+					PoolSize:    1,
+					SenderEmail: "noreply@foo.com",
+				},
+				Service: MockNotificationDeliveryServiceConfig{service: toolchainconfig.NotificationDeliveryServiceSMTP},
+			}
+			svc := NewSMTPNotificationDeliveryService(config, templateLoader)
+			svc.(*SMTPNotificationDeliveryService).timeout = 100 * time.Millisecond
+
+			// when
+			err = svc.Send(notification(), notificationtemplates.SandboxTemplateSetName)
+
+			// then
+			require.Error(t, err)
+			require.IsType(t, SMTPDeliveryError{}, err)
+			assert.False(t, IsPermanentDeliveryError(err))
+			assert.Contains(t, err.Error(), "i/o timeout")
+		})
+
+		t.Run("server unreachable", func(t *testing.T) {
+			// given
+			server := newTestSMTPServer(t, toolchainconfig.SMTPTLSModeNone, "", "")
+			config := server.config("", "", 1)
+			server.stop()
+			svc := NewSMTPNotificationDeliveryService(config, templateLoader)
+
+			// when
+			err := svc.Send(notification(), notificationtemplates.SandboxTemplateSetName)
+
+			// then
+			require.Error(t, err)
+			require.IsType(t, SMTPDeliveryError{}, err)
+		})
+	})
+}
+
+type receivedMessage struct {
+	from string
+	to   []string
+	data string
+	tls  bool
+}
+
+type parsedMessage struct {
+	header  mail.Header
+	subject string
+	// body is the HTML part of the message
+	body string
+	text string
+}
+
+func (m receivedMessage) parse(t *testing.T) parsedMessage {
+	msg, err := mail.ReadMessage(strings.NewReader(m.data))
+	require.NoError(t, err)
+	subject, err := new(mime.WordDecoder).DecodeHeader(msg.Header.Get("Subject"))
+	require.NoError(t, err)
+	mediaType, params, err := mime.ParseMediaType(msg.Header.Get("Content-Type"))
+	require.NoError(t, err)
+	require.Equal(t, "multipart/alternative", mediaType)
+	parsed := parsedMessage{
+		header:  msg.Header,
+		subject: subject,
+	}
+	parts := multipart.NewReader(msg.Body, params["boundary"])
+	for {
+		part, err := parts.NextRawPart()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+		require.Equal(t, "quoted-printable", part.Header.Get("Content-Transfer-Encoding"))
+		content, err := io.ReadAll(quotedprintable.NewReader(part))
+		require.NoError(t, err)
+		switch part.Header.Get("Content-Type") {
+		case "text/plain; charset=UTF-8":
+			parsed.text = strings.TrimRight(string(content), "\r\n")
+		case "text/html; charset=UTF-8":
+			parsed.body = strings.TrimRight(string(content), "\r\n")
+		default:
+			require.Fail(t, "unexpected part", part.Header.Get("Content-Type"))
+		}
+	}
+	return parsed
+}
+
+// testSMTPServer is a minimal in-process SMTP server which supports STARTTLS, implicit TLS and the PLAIN authentication
+type testSMTPServer struct {
+	listener  net.Listener
+	tlsMode   string
+	tlsConfig *tls.Config
+	rootCAs   *x509.CertPool
+	username  string
+	password  string
+
+	mu          sync.Mutex
+	connections []net.Conn
+	messages    []receivedMessage
+}
+
+func newTestSMTPServer(t *testing.T, tlsMode, username, password string) *testSMTPServer {
+	cert, rootCAs := newTestCertificate(t)
+	server := &testSMTPServer{
+		tlsMode:   tlsMode,
+		tlsConfig: &tls.Config{Certificates: []tls.Certificate{cert}, MinVersion: tls.VersionTLS12},
+		rootCAs:   rootCAs,
+		username:  username,
+		password:  password,
+	}
+	var err error
+	if tlsMode == toolchainconfig.SMTPTLSModeTLS {
+		server.listener, err = tls.Listen("tcp", "127.0.0.1:0", server.tlsConfig)
+	} else {
+		server.listener, err = net.Listen("tcp", "127.0.0.1:0")
+	}
+	require.NoError(t, err)
+	t.Cleanup(server.stop)
+
+	go func() {
+		for {
+			conn, err := server.listener.Accept()
+			if err != nil {
+				return
+			}
+			server.mu.Lock()
+			server.connections = append(server.connections, conn)
+			server.mu.Unlock()
+			go server.handle(conn)
+		}
+	}()
+	return server
+}
+
+func (s *testSMTPServer) config(username, password string, poolSize int) DeliveryServiceFactoryConfig {
+	return &MockNotificationDeliveryServiceFactoryConfig{
+		SMTP: MockSMTPConfiguration{
+			Host:         "127.0.0.1",
+			Port:         s.listener.Addr().(*net.TCPAddr).Port,
+			TLSMode:      s.tlsMode,
+			PoolSize:     poolSize,
+			Username:     username,
+			Password:     password,
+			SenderEmail:  "noreply@foo.com",
+			ReplyToEmail: "support@foo.com",
+		},
+		Service: MockNotificationDeliveryServiceConfig{service: toolchainconfig.NotificationDeliveryServiceSMTP},
+	}
+}
+
+func (s *testSMTPServer) tlsOption() SMTPOption {
+	return &SMTPTLSConfigOption{tlsConfig: &tls.Config{ServerName: "127.0.0.1", RootCAs: s.rootCAs, MinVersion: tls.VersionTLS12}}
+}
+
+func (s *testSMTPServer) stop() {
+	_ = s.listener.Close()
+	s.closeConnections()
+}
+
+func (s *testSMTPServer) closeConnections() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, conn := range s.connections {
+		_ = conn.Close()
+	}
+}
+
+func (s *testSMTPServer) connectionCount() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.connections)
+}
+
+func (s *testSMTPServer) receivedMessages() []receivedMessage {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]receivedMessage{}, s.messages...)
+}
+
+func (s *testSMTPServer) handle(conn net.Conn) {
+	defer conn.Close()
+	tp := textproto.NewConn(conn)
+	tlsActive := s.tlsMode == toolchainconfig.SMTPTLSModeTLS
+	authenticated := s.username == ""
+	var current receivedMessage
+
+	_ = tp.PrintfLine("220 localhost ESMTP test server")
+	for {
+		line, err := tp.ReadLine()
+		if err != nil {
+			return
+		}
+		cmd, arg, _ := strings.Cut(line, " ")
+		switch strings.ToUpper(cmd) {
+		case "EHLO", "HELO":
+			extensions := []string{"localhost"}
+			if s.tlsMode == toolchainconfig.SMTPTLSModeStartTLS && !tlsActive {
+				extensions = append(extensions, "STARTTLS")
+			}
+			if s.username != "" {
+				extensions = append(extensions, "AUTH PLAIN")
+			}
+			extensions = append(extensions, "8BITMIME")
+			for i, ext := range extensions {
+				if i == len(extensions)-1 {
+					_ = tp.PrintfLine("250 %s", ext)
+				} else {
+					_ = tp.PrintfLine("250-%s", ext)
+				}
+			}
+		case "STARTTLS":
+			_ = tp.PrintfLine("220 ready to start TLS")
+			tlsConn := tls.Server(conn, s.tlsConfig)
+			if err := tlsConn.Handshake(); err != nil {
+				return
+			}
+			conn = tlsConn
+			tp = textproto.NewConn(tlsConn)
+			tlsActive = true
+		case "AUTH":
+			mechanism, initialResponse, _ := strings.Cut(arg, " ")
+			decoded, err := base64.StdEncoding.DecodeString(initialResponse)
+			if strings.ToUpper(mechanism) != "PLAIN" || err != nil || string(decoded) != "\x00"+s.username+"\x00"+s.password {
+				_ = tp.PrintfLine("535 authentication failed")
+				continue
+			}
+			authenticated = true
+			_ = tp.PrintfLine("235 authentication succeeded")
+		case "MAIL":
+			if !authenticated {
+				_ = tp.PrintfLine("530 authentication required")
+				continue
+			}
+			current = receivedMessage{from: trimAddress(arg), tls: tlsActive}
+			_ = tp.PrintfLine("250 OK")
+		case "RCPT":
+			current.to = append(current.to, trimAddress(arg))
+			_ = tp.PrintfLine("250 OK")
+		case "DATA":
+			_ = tp.PrintfLine("354 end data with <CR><LF>.<CR><LF>")
+			lines, err := tp.ReadDotLines()
+			if err != nil {
+				return
+			}
+			current.data = strings.Join(lines, "\r\n")
+			s.mu.Lock()
+			s.messages = append(s.messages, current)
+			s.mu.Unlock()
+			current = receivedMessage{}
+			_ = tp.PrintfLine("250 OK")
+		case "RSET":
+			current = receivedMessage{}
+			_ = tp.PrintfLine("250 OK")
+		case "NOOP":
+			_ = tp.PrintfLine("250 OK")
+		case "QUIT":
+			_ = tp.PrintfLine("221 bye")
+			return
+		default:
+			_ = tp.PrintfLine("502 command not implemented")
+		}
+	}
+}
+
+// trimAddress returns the address of a `FROM:<address>` or `TO:<address>` argument
+func trimAddress(arg string) string {
+	_, addr, _ := strings.Cut(arg, ":")
+	addr, _, _ = strings.Cut(addr, " ")
+	return strings.Trim(addr, "<>")
+}
+
+func newTestCertificate(t *testing.T) (tls.Certificate, *x509.CertPool) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "127.0.0.1"},
+		IPAddresses:           []net.IP{net.ParseIP("127.0.0.1")},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+	pool := x509.NewCertPool()
+	pool.AddCert(cert)
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}, pool
+}
@@ -0,0 +1,144 @@
+package notification
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/mail"
+	"strings"
+	"time"
+
+	toolchainv1alpha1 "github.com/codeready-toolchain/api/api/v1alpha1"
+
+	errs "github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/util/retry"
+	runtimeclient "sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// SuppressionListConfigMapNamePrefix is the prefix of the names of the ConfigMaps which contain the recipients to which no notification
+	// is sent anymore, because a previous notification bounced or was reported as spam. The list is sharded in up to 256 ConfigMaps
+	// (suffixed with the first 2 characters of the entry keys) so that it does not hit the size limit of a single ConfigMap.
+	// Each entry is keyed by the SHA-256 hash of the lower-cased email address (so that no address is retained in plain text),
+	// and contains a SuppressedRecipient. A recipient is removed from the list by deleting its entry.
+	SuppressionListConfigMapNamePrefix = "notification-suppression-list-"
+
+	// SuppressionListLabelKey is used to label the ConfigMaps of the suppression list
+	SuppressionListLabelKey = toolchainv1alpha1.LabelKeyPrefix + "notification-suppression-list"
+)
+
+// SuppressedRecipient is an entry of the suppression list
+type SuppressedRecipient struct {
+	Reason string    `json:"reason"`
+	Time   time.Time `json:"time"`
+}
+
+// suppressionListKey returns the key of the given email address in the suppression list
+func suppressionListKey(email string) string {
+	if addr, err := mail.ParseAddress(email); err == nil {
+		email = addr.Address
+	}
+	sum := sha256.Sum256([]byte(strings.ToLower(strings.TrimSpace(email))))
+	return hex.EncodeToString(sum[:])
+}
+
+// suppressionListName returns the name of the ConfigMap (ie, the shard of the suppression list) which contains the given key
+func suppressionListName(key string) string {
+	return SuppressionListConfigMapNamePrefix + key[:2]
+}
+
+// SuppressRecipient adds the given email address to the suppression list, creating the list if it does not exist yet
+func SuppressRecipient(ctx context.Context, cl runtimeclient.Client, namespace, email, reason string) error {
+	entry, err := json.Marshal(SuppressedRecipient{
+		Reason: reason,
+		Time:   time.Now().UTC().Truncate(time.Second),
+	})
+	if err != nil {
+		return err
+	}
+	key := suppressionListKey(email)
+	name := suppressionListName(key)
+	err = retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		cm := &corev1.ConfigMap{}
+		if err := cl.Get(ctx, runtimeclient.ObjectKey{Namespace: namespace, Name: name}, cm); err != nil {
+			if !errors.IsNotFound(err) {
+				return err
+			}
+			cm = &corev1.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{
+					Namespace: namespace,
+					Name:      name,
+					Labels: map[string]string{
+						SuppressionListLabelKey: "true",
+					},
+				},
+				Data: map[string]string{key: string(entry)},
+			}
+			if err := cl.Create(ctx, cm); err != nil {
+				if errors.IsAlreadyExists(err) {
+					// created in the meantime: retry with an update
+					return errors.NewConflict(corev1.Resource("configmaps"), name, err)
+				}
+				return err
+			}
+			return nil
+		}
+		if cm.Data == nil {
+			cm.Data = map[string]string{}
+		}
+		cm.Data[key] = string(entry)
+		return cl.Update(ctx, cm)
+	})
+	return errs.Wrapf(err, "unable to add '%s' to the notification suppression list", email)
+}
+
+// UnsuppressRecipient removes the given email address from the suppression list. Returns true if the address was in the list.
+func UnsuppressRecipient(ctx context.Context, cl runtimeclient.Client, namespace, email string) (bool, error) {
+	key := suppressionListKey(email)
+	removed := false
+	err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		cm := &corev1.ConfigMap{}
+		if err := cl.Get(ctx, runtimeclient.ObjectKey{Namespace: namespace, Name: suppressionListName(key)}, cm); err != nil {
+			if errors.IsNotFound(err) {
+				return nil
+			}
+			return err
+		}
+		if _, found := cm.Data[key]; !found {
+			return nil
+		}
+		delete(cm.Data, key)
+		if err := cl.Update(ctx, cm); err != nil {
+			return err
+		}
+		removed = true
+		return nil
+	})
+	return removed, errs.Wrap(err, "unable to remove the recipient from the notification suppression list")
+}
+
+// GetSuppressedRecipient returns the entry of the given email address in the suppression list, or nil if the address is not suppressed
+func GetSuppressedRecipient(ctx context.Context, cl runtimeclient.Client, namespace, email string) (*SuppressedRecipient, error) {
+	key := suppressionListKey(email)
+	cm := &corev1.ConfigMap{}
+	if err := cl.Get(ctx, runtimeclient.ObjectKey{Namespace: namespace, Name: suppressionListName(key)}, cm); err != nil {
+		if errors.IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, errs.Wrap(err, "unable to get the notification suppression list")
+	}
+	v, found := cm.Data[key]
+	if !found {
+		return nil, nil
+	}
+	entry := &SuppressedRecipient{}
+	if err := json.Unmarshal([]byte(v), entry); err != nil {
+		// keep suppressing the address rather than sending to an address which bounced
+		return &SuppressedRecipient{}, nil
+	}
+	return entry, nil
+}
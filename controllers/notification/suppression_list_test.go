@@ -0,0 +1,157 @@
+package notification
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/codeready-toolchain/toolchain-common/pkg/test"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	runtimeclient "sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func TestSuppressionList(t *testing.T) {
+	t.Run("no suppression list", func(t *testing.T) {
+		// given
+		cl := test.NewFakeClient(t)
+
+		// when
+		suppressed, err := GetSuppressedRecipient(context.TODO(), cl, test.HostOperatorNs, "jane@acme.com")
+
+		// then
+		require.NoError(t, err)
+		assert.Nil(t, suppressed)
+	})
+
+	t.Run("recipients are added to the list", func(t *testing.T) {
+		// given
+		cl := test.NewFakeClient(t)
+
+		// when
+		err := SuppressRecipient(context.TODO(), cl, test.HostOperatorNs, "jane@acme.com", "bounced")
+		require.NoError(t, err)
+		err = SuppressRecipient(context.TODO(), cl, test.HostOperatorNs, "john@acme.com", "complained")
+		require.NoError(t, err)
+
+		// then
+		lists := &corev1.ConfigMapList{}
+		require.NoError(t, cl.List(context.TODO(), lists, runtimeclient.MatchingLabels{SuppressionListLabelKey: "true"}))
+		entries := 0
+		for _, cm := range lists.Items {
+			assert.True(t, strings.HasPrefix(cm.Name, SuppressionListConfigMapNamePrefix))
+			for k, v := range cm.Data {
+				assert.Equal(t, SuppressionListConfigMapNamePrefix+k[:2], cm.Name)
+				// the addresses are not retained in plain text
+				assert.NotContains(t, k+v, "acme.com")
+				entries++
+			}
+		}
+		assert.Equal(t, 2, entries)
+
+		suppressed, err := GetSuppressedRecipient(context.TODO(), cl, test.HostOperatorNs, "Jane@ACME.com")
+		require.NoError(t, err)
+		require.NotNil(t, suppressed)
+		assert.Equal(t, "bounced", suppressed.Reason)
+		assert.False(t, suppressed.Time.IsZero())
+
+		suppressed, err = GetSuppressedRecipient(context.TODO(), cl, test.HostOperatorNs, "John <john@acme.com>")
+		require.NoError(t, err)
+		require.NotNil(t, suppressed)
+		assert.Equal(t, "complained", suppressed.Reason)
+
+		suppressed, err = GetSuppressedRecipient(context.TODO(), cl, test.HostOperatorNs, "joe@acme.com")
+		require.NoError(t, err)
+		assert.Nil(t, suppressed)
+	})
+
+	t.Run("invalid entry is still suppressed", func(t *testing.T) {
+		// given
+		cl := test.NewFakeClient(t)
+		require.NoError(t, SuppressRecipient(context.TODO(), cl, test.HostOperatorNs, "jane@acme.com", "bounced"))
+		key := suppressionListKey("jane@acme.com")
+		cm := &corev1.ConfigMap{}
+		require.NoError(t, cl.Get(context.TODO(), test.NamespacedName(test.HostOperatorNs, suppressionListName(key)), cm))
+		cm.Data[key] = "banana"
+		require.NoError(t, cl.Update(context.TODO(), cm))
+
+		// when
+		suppressed, err := GetSuppressedRecipient(context.TODO(), cl, test.HostOperatorNs, "jane@acme.com")
+
+		// then
+		require.NoError(t, err)
+		assert.NotNil(t, suppressed)
+	})
+
+	t.Run("recipients are removed from the list", func(t *testing.T) {
+		// given
+		cl := test.NewFakeClient(t)
+		require.NoError(t, SuppressRecipient(context.TODO(), cl, test.HostOperatorNs, "jane@acme.com", "bounced"))
+		require.NoError(t, SuppressRecipient(context.TODO(), cl, test.HostOperatorNs, "john@acme.com", "bounced"))
+
+		// when
+		removed, err := UnsuppressRecipient(context.TODO(), cl, test.HostOperatorNs, "Jane <JANE@acme.com>")
+
+		// then
+		require.NoError(t, err)
+		assert.True(t, removed)
+		suppressed, err := GetSuppressedRecipient(context.TODO(), cl, test.HostOperatorNs, "jane@acme.com")
+		require.NoError(t, err)
+		assert.Nil(t, suppressed)
+		suppressed, err = GetSuppressedRecipient(context.TODO(), cl, test.HostOperatorNs, "john@acme.com")
+		require.NoError(t, err)
+		assert.NotNil(t, suppressed)
+
+		t.Run("not in the list", func(t *testing.T) {
+			// when
+			removed, err := UnsuppressRecipient(context.TODO(), cl, test.HostOperatorNs, "jane@acme.com")
+
+			// then
+			require.NoError(t, err)
+			assert.False(t, removed)
+		})
+	})
+
+	t.Run("list created concurrently", func(t *testing.T) {
+		// given
+		cl := test.NewFakeClient(t)
+		cl.MockCreate = func(ctx context.Context, obj runtimeclient.Object, opts ...runtimeclient.CreateOption) error {
+			// the list is created by someone else in the meantime
+			require.NoError(t, cl.Client.Create(ctx, obj.DeepCopyObject().(runtimeclient.Object), opts...))
+			return apierrors.NewAlreadyExists(schema.GroupResource{Resource: "configmaps"}, obj.GetName())
+		}
+
+		// when
+		err := SuppressRecipient(context.TODO(), cl, test.HostOperatorNs, "jane@acme.com", "bounced")
+
+		// then
+		require.NoError(t, err)
+		suppressed, err := GetSuppressedRecipient(context.TODO(), cl, test.HostOperatorNs, "jane@acme.com")
+		require.NoError(t, err)
+		assert.NotNil(t, suppressed)
+	})
+
+	t.Run("failures", func(t *testing.T) {
+		t.Run("unable to get the list", func(t *testing.T) {
+			// given
+			cl := test.NewFakeClient(t)
+			cl.MockGet = func(ctx context.Context, key runtimeclient.ObjectKey, obj runtimeclient.Object, opts ...runtimeclient.GetOption) error {
+				return assert.AnError
+			}
+
+			// when
+			_, getErr := GetSuppressedRecipient(context.TODO(), cl, test.HostOperatorNs, "jane@acme.com")
+			suppressErr := SuppressRecipient(context.TODO(), cl, test.HostOperatorNs, "jane@acme.com", "bounced")
+			_, unsuppressErr := UnsuppressRecipient(context.TODO(), cl, test.HostOperatorNs, "jane@acme.com")
+
+			// then
+			require.EqualError(t, getErr, "unable to get the notification suppression list: "+assert.AnError.Error())
+			require.EqualError(t, suppressErr, "unable to add 'jane@acme.com' to the notification suppression list: "+assert.AnError.Error())
+			require.EqualError(t, unsuppressErr, "unable to remove the recipient from the notification suppression list: "+assert.AnError.Error())
+		})
+	})
+}
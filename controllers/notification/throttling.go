@@ -0,0 +1,137 @@
+package notification
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	toolchainv1alpha1 "github.com/codeready-toolchain/api/api/v1alpha1"
+	"github.com/codeready-toolchain/host-operator/controllers/toolchainconfig"
+	"github.com/codeready-toolchain/toolchain-common/pkg/condition"
+	"github.com/codeready-toolchain/toolchain-common/pkg/hash"
+
+	errs "github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	runtimeclient "sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// DeduplicationKeyLabelKey is set on the sent Notifications, with the hash of their template (or type, or subject) and recipient,
+	// so that the duplicates sent within the deduplication window can be found
+	DeduplicationKeyLabelKey = toolchainv1alpha1.LabelKeyPrefix + "notification-deduplication-key"
+	// RecipientHashLabelKey is set on the sent Notifications, with the hash of their recipient, so that the rate limit can be enforced
+	RecipientHashLabelKey = toolchainv1alpha1.LabelKeyPrefix + "notification-recipient-hash"
+
+	// NotificationSuppressed is the condition type set on a Notification which was not sent because the same notification
+	// was recently sent to the same recipient
+	NotificationSuppressed toolchainv1alpha1.ConditionType = "Suppressed"
+	// NotificationDuplicateReason is the reason of the NotificationSuppressed condition
+	NotificationDuplicateReason = "Duplicate"
+	// NotificationRateLimitedReason is the reason of the NotificationSent condition when the notification is delayed because
+	// too many notifications were recently sent to the same recipient
+	NotificationRateLimitedReason = "RateLimited"
+)
+
+// deduplicationName returns the template of the given notification, or its type or its subject if it has no template
+func deduplicationName(notification *toolchainv1alpha1.Notification) string {
+	if notification.Spec.Template != "" {
+		return notification.Spec.Template
+	}
+	if notificationType, found := notification.Labels[toolchainv1alpha1.NotificationTypeLabelKey]; found {
+		return notificationType
+	}
+	return notification.Spec.Subject
+}
+
+// setThrottlingLabels sets the labels used to find the notifications recently sent to the same recipient
+func setThrottlingLabels(notification *toolchainv1alpha1.Notification) {
+	if notification.Labels == nil {
+		notification.Labels = map[string]string{}
+	}
+	key := deduplicationName(notification)
+	if notification.Spec.Template == "" {
+		// the notifications of the same type may have different subjects, eg. the admin alerts about different clusters
+		key += "\n" + notification.Spec.Subject
+	}
+	notification.Labels[DeduplicationKeyLabelKey] = hash.EncodeString(key + "\n" + notification.Spec.Recipient)
+	notification.Labels[RecipientHashLabelKey] = hash.EncodeString(notification.Spec.Recipient)
+}
+
+// throttling is the outcome of checkThrottling
+type throttling struct {
+	// duplicate is the notification which was recently sent to the same recipient, if any
+	duplicate *toolchainv1alpha1.Notification
+	// retryAfter is the delay before the notification can be sent without exceeding the rate limit, if any
+	retryAfter time.Duration
+}
+
+// checkThrottling checks whether the given notification is a duplicate of a notification recently sent to the same recipient,
+// or whether it would exceed the rate limit of the recipient
+func (r *Reconciler) checkThrottling(ctx context.Context, config toolchainconfig.NotificationsConfig, notification *toolchainv1alpha1.Notification) (throttling, error) {
+	result := throttling{}
+	candidate := notification.DeepCopy()
+	setThrottlingLabels(candidate)
+
+	if window := config.DeduplicationWindow(deduplicationName(notification)); window > 0 {
+		sent, err := r.listSentNotifications(ctx, notification, DeduplicationKeyLabelKey, candidate.Labels[DeduplicationKeyLabelKey], window)
+		if err != nil {
+			return result, err
+		}
+		if len(sent) > 0 {
+			result.duplicate = &sent[0].notification
+			return result, nil
+		}
+	}
+
+	if limit := config.RateLimit(); limit > 0 {
+		period := config.RateLimitPeriod()
+		sent, err := r.listSentNotifications(ctx, notification, RecipientHashLabelKey, candidate.Labels[RecipientHashLabelKey], period)
+		if err != nil {
+			return result, err
+		}
+		if len(sent) >= limit {
+			// wait until the limit-th most recent notification is out of the period
+			result.retryAfter = time.Until(sent[limit-1].sentAt.Add(period))
+			if result.retryAfter <= 0 {
+				result.retryAfter = time.Second
+			}
+		}
+	}
+	return result, nil
+}
+
+type sentNotification struct {
+	notification toolchainv1alpha1.Notification
+	sentAt       time.Time
+}
+
+// listSentNotifications returns the notifications with the given label which were sent within the given duration, the most recent first
+func (r *Reconciler) listSentNotifications(ctx context.Context, notification *toolchainv1alpha1.Notification, labelKey, labelValue string,
+	within time.Duration) ([]sentNotification, error) {
+	notifications := &toolchainv1alpha1.NotificationList{}
+	if err := r.Client.List(ctx, notifications, runtimeclient.InNamespace(notification.Namespace),
+		runtimeclient.MatchingLabels{labelKey: labelValue}); err != nil {
+		return nil, errs.Wrap(err, "unable to list the notifications sent to the same recipient")
+	}
+	var sent []sentNotification
+	for _, n := range notifications.Items {
+		if n.Name == notification.Name {
+			continue
+		}
+		cond, found := condition.FindConditionByType(n.Status.Conditions, toolchainv1alpha1.NotificationSent)
+		if !found || cond.Status != corev1.ConditionTrue || time.Since(cond.LastTransitionTime.Time) >= within {
+			continue
+		}
+		sent = append(sent, sentNotification{notification: n, sentAt: cond.LastTransitionTime.Time})
+	}
+	sort.Slice(sent, func(i, j int) bool {
+		return sent[i].sentAt.After(sent[j].sentAt)
+	})
+	return sent, nil
+}
+
+// duplicateMessage returns the message of the NotificationSuppressed condition
+func duplicateMessage(duplicate *toolchainv1alpha1.Notification) string {
+	return fmt.Sprintf("the same notification was recently sent to the same recipient: '%s'", duplicate.Name)
+}
@@ -0,0 +1,304 @@
+package notification
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	toolchainv1alpha1 "github.com/codeready-toolchain/api/api/v1alpha1"
+	"github.com/codeready-toolchain/host-operator/controllers/toolchainconfig"
+	"github.com/codeready-toolchain/host-operator/pkg/metrics"
+	hostconfig "github.com/codeready-toolchain/host-operator/test/config"
+	ntest "github.com/codeready-toolchain/host-operator/test/notification"
+	commonconfig "github.com/codeready-toolchain/toolchain-common/pkg/configuration"
+	"github.com/codeready-toolchain/toolchain-common/pkg/test"
+	testconfig "github.com/codeready-toolchain/toolchain-common/pkg/test/config"
+	metricstest "github.com/codeready-toolchain/toolchain-common/pkg/test/metrics"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	runtimeclient "sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func TestNotificationDeduplication(t *testing.T) {
+	t.Run("duplicate is suppressed", func(t *testing.T) {
+		// given
+		metrics.Reset()
+		toolchainConfig := commonconfig.NewToolchainConfigObjWithReset(t, testconfig.Notifications().DurationBeforeNotificationDeletion("10s"),
+			hostconfig.Annotation(toolchainconfig.NotificationDeduplicationWindowAnnotationKey, "10m"))
+		previous := newSentNotification("jane-deactivating-1", "userdeactivating", "jane@acme.com", 5*time.Minute)
+		notification := newTemplateNotification("jane-deactivating-2", "userdeactivating", "jane@acme.com")
+		ds := &failingDeliveryService{}
+		controller, cl := newController(t, ds, toolchainConfig, previous, notification)
+
+		// when
+		result, err := reconcileNotification(controller, notification)
+
+		// then
+		require.NoError(t, err)
+		assert.Equal(t, 10*time.Second, result.RequeueAfter)
+		assert.Equal(t, 0, ds.calls)
+		ntest.AssertThatNotification(t, notification.Name, cl).
+			HasConditions(suppressedCond("the same notification was recently sent to the same recipient: 'jane-deactivating-1'"))
+		metricstest.AssertMetricsCounterEquals(t, 1, metrics.NotificationThrottledTotal.WithLabelValues("duplicate"))
+
+		t.Run("suppressed notification is deleted after the deletion timeout", func(t *testing.T) {
+			// given
+			require.NoError(t, cl.Get(context.TODO(), runtimeclient.ObjectKeyFromObject(notification), notification))
+			notification.Status.Conditions[0].LastTransitionTime = metav1.NewTime(time.Now().Add(-10 * time.Second))
+			require.NoError(t, cl.Status().Update(context.TODO(), notification))
+
+			// when
+			_, err := reconcileNotification(controller, notification)
+
+			// then
+			require.NoError(t, err)
+			AssertThatNotificationIsDeleted(t, cl, notification.Name)
+		})
+	})
+
+	t.Run("not a duplicate", func(t *testing.T) {
+		for name, previous := range map[string]*toolchainv1alpha1.Notification{
+			"other template":        newSentNotification("jane-provisioned", "userprovisioned", "jane@acme.com", 5*time.Minute),
+			"other recipient":       newSentNotification("john-deactivating", "userdeactivating", "john@acme.com", 5*time.Minute),
+			"outside of the window": newSentNotification("jane-deactivating-1", "userdeactivating", "jane@acme.com", 11*time.Minute),
+		} {
+			t.Run(name, func(t *testing.T) {
+				// given
+				toolchainConfig := commonconfig.NewToolchainConfigObjWithReset(t,
+					hostconfig.Annotation(toolchainconfig.NotificationDeduplicationWindowAnnotationKey, "10m"))
+				notification := newTemplateNotification("jane-deactivating-2", "userdeactivating", "jane@acme.com")
+				ds := &failingDeliveryService{}
+				controller, cl := newController(t, ds, toolchainConfig, previous, notification)
+
+				// when
+				_, err := reconcileNotification(controller, notification)
+
+				// then
+				require.NoError(t, err)
+				assert.Equal(t, 1, ds.calls)
+				ntest.AssertThatNotification(t, notification.Name, cl).
+					HasConditions(sentCond())
+				// the labels used to find the duplicates are set
+				sent := &toolchainv1alpha1.Notification{}
+				require.NoError(t, cl.Get(context.TODO(), runtimeclient.ObjectKeyFromObject(notification), sent))
+				assert.Equal(t, newSentNotification("", "userdeactivating", "jane@acme.com", 0).Labels[DeduplicationKeyLabelKey], sent.Labels[DeduplicationKeyLabelKey])
+				assert.NotEmpty(t, sent.Labels[RecipientHashLabelKey])
+			})
+		}
+	})
+
+	t.Run("deduplication disabled by default", func(t *testing.T) {
+		// given
+		toolchainConfig := commonconfig.NewToolchainConfigObjWithReset(t)
+		previous := newSentNotification("jane-deactivating-1", "userdeactivating", "jane@acme.com", time.Minute)
+		notification := newTemplateNotification("jane-deactivating-2", "userdeactivating", "jane@acme.com")
+		ds := &failingDeliveryService{}
+		controller, cl := newController(t, ds, toolchainConfig, previous, notification)
+
+		// when
+		_, err := reconcileNotification(controller, notification)
+
+		// then
+		require.NoError(t, err)
+		assert.Equal(t, 1, ds.calls)
+		ntest.AssertThatNotification(t, notification.Name, cl).
+			HasConditions(sentCond())
+	})
+
+	t.Run("notifications of the same type with different subjects are not duplicates", func(t *testing.T) {
+		// given
+		toolchainConfig := commonconfig.NewToolchainConfigObjWithReset(t,
+			hostconfig.Annotation(toolchainconfig.NotificationDeduplicationWindowAnnotationKey, "10m"))
+		previous := newSentNotification("alert-1", "", "admin@acme.com", time.Minute)
+		previous.Labels[toolchainv1alpha1.NotificationTypeLabelKey] = "capacity-alert"
+		previous.Spec.Subject = "member-1 is full"
+		setThrottlingLabels(previous)
+		notification := newTemplateNotification("alert-2", "", "admin@acme.com")
+		notification.Labels = map[string]string{toolchainv1alpha1.NotificationTypeLabelKey: "capacity-alert"}
+		notification.Spec.Subject = "member-2 is full"
+		ds := &failingDeliveryService{}
+		controller, cl := newController(t, ds, toolchainConfig, previous, notification)
+
+		// when
+		_, err := reconcileNotification(controller, notification)
+
+		// then
+		require.NoError(t, err)
+		assert.Equal(t, 1, ds.calls)
+		ntest.AssertThatNotification(t, notification.Name, cl).
+			HasConditions(sentCond())
+	})
+
+	t.Run("deduplication window of the template", func(t *testing.T) {
+		// given
+		toolchainConfig := commonconfig.NewToolchainConfigObjWithReset(t,
+			hostconfig.Annotation(toolchainconfig.NotificationDeduplicationWindowAnnotationKey, "0s"),
+			hostconfig.Annotation(toolchainconfig.NotificationDeduplicationTemplateWindowsAnnotationKey, `{"userdeactivating": "24h"}`))
+		previousDeactivating := newSentNotification("jane-deactivating-1", "userdeactivating", "jane@acme.com", 12*time.Hour)
+		previousProvisioned := newSentNotification("jane-provisioned-1", "userprovisioned", "jane@acme.com", time.Minute)
+		deactivating := newTemplateNotification("jane-deactivating-2", "userdeactivating", "jane@acme.com")
+		provisioned := newTemplateNotification("jane-provisioned-2", "userprovisioned", "jane@acme.com")
+		ds := &failingDeliveryService{}
+		controller, cl := newController(t, ds, toolchainConfig, previousDeactivating, previousProvisioned, deactivating, provisioned)
+
+		// when
+		_, err := reconcileNotification(controller, deactivating)
+		require.NoError(t, err)
+		_, err = reconcileNotification(controller, provisioned)
+		require.NoError(t, err)
+
+		// then
+		assert.Equal(t, 1, ds.calls)
+		ntest.AssertThatNotification(t, deactivating.Name, cl).
+			HasConditions(suppressedCond("the same notification was recently sent to the same recipient: 'jane-deactivating-1'"))
+		// the deduplication is disabled for the other templates
+		ntest.AssertThatNotification(t, provisioned.Name, cl).
+			HasConditions(sentCond())
+	})
+}
+
+func TestNotificationRateLimit(t *testing.T) {
+	t.Run("notification is delayed when the limit is reached", func(t *testing.T) {
+		// given
+		metrics.Reset()
+		toolchainConfig := commonconfig.NewToolchainConfigObjWithReset(t,
+			hostconfig.Annotation(toolchainconfig.NotificationRateLimitAnnotationKey, "3"),
+			hostconfig.Annotation(toolchainconfig.NotificationRateLimitPeriodAnnotationKey, "1h"))
+		initObjs := []runtimeclient.Object{toolchainConfig}
+		for i, sentAgo := range []time.Duration{50 * time.Minute, 40 * time.Minute, 10 * time.Minute, 2 * time.Hour} {
+			initObjs = append(initObjs, newSentNotification(fmt.Sprintf("alert-%d", i), fmt.Sprintf("alert-%d", i), "admin@acme.com", sentAgo))
+		}
+		notification := newTemplateNotification("alert", "alert", "admin@acme.com")
+		ds := &failingDeliveryService{}
+		controller, cl := newController(t, ds, append(initObjs, notification)...)
+
+		// when
+		result, err := reconcileNotification(controller, notification)
+
+		// then
+		require.NoError(t, err)
+		assert.Equal(t, 0, ds.calls)
+		// the notification sent 50 minutes ago must be out of the period
+		assert.Greater(t, result.RequeueAfter, 9*time.Minute)
+		assert.LessOrEqual(t, result.RequeueAfter, 10*time.Minute)
+		ntest.AssertThatNotification(t, notification.Name, cl).
+			HasConditions(toolchainv1alpha1.Condition{
+				Type:    toolchainv1alpha1.NotificationSent,
+				Status:  corev1.ConditionFalse,
+				Reason:  NotificationRateLimitedReason,
+				Message: "too many notifications were recently sent to the same recipient",
+			})
+		metricstest.AssertMetricsCounterEquals(t, 1, metrics.NotificationThrottledTotal.WithLabelValues("rate-limited"))
+
+		t.Run("notification is sent once below the limit", func(t *testing.T) {
+			// given
+			sent := &toolchainv1alpha1.Notification{}
+			require.NoError(t, cl.Get(context.TODO(), test.NamespacedName(test.HostOperatorNs, "alert-0"), sent))
+			sent.Status.Conditions[0].LastTransitionTime = metav1.NewTime(time.Now().Add(-61 * time.Minute))
+			require.NoError(t, cl.Status().Update(context.TODO(), sent))
+
+			// when
+			_, err := reconcileNotification(controller, notification)
+
+			// then
+			require.NoError(t, err)
+			assert.Equal(t, 1, ds.calls)
+			ntest.AssertThatNotification(t, notification.Name, cl).
+				HasConditions(sentCond())
+		})
+	})
+
+	t.Run("rate limit disabled", func(t *testing.T) {
+		// given
+		toolchainConfig := commonconfig.NewToolchainConfigObjWithReset(t,
+			hostconfig.Annotation(toolchainconfig.NotificationRateLimitAnnotationKey, "0"))
+		previous := newSentNotification("alert-1", "alert-1", "admin@acme.com", time.Minute)
+		notification := newTemplateNotification("alert", "alert", "admin@acme.com")
+		ds := &failingDeliveryService{}
+		controller, _ := newController(t, ds, toolchainConfig, previous, notification)
+
+		// when
+		_, err := reconcileNotification(controller, notification)
+
+		// then
+		require.NoError(t, err)
+		assert.Equal(t, 1, ds.calls)
+	})
+
+	t.Run("rate limit disabled by default", func(t *testing.T) {
+		// given
+		toolchainConfig := commonconfig.NewToolchainConfigObjWithReset(t)
+		initObjs := []runtimeclient.Object{toolchainConfig}
+		for i := 0; i < 20; i++ {
+			initObjs = append(initObjs, newSentNotification(fmt.Sprintf("alert-%d", i), fmt.Sprintf("alert-%d", i), "admin@acme.com", time.Minute))
+		}
+		notification := newTemplateNotification("alert", "alert", "admin@acme.com")
+		ds := &failingDeliveryService{}
+		controller, _ := newController(t, ds, append(initObjs, notification)...)
+
+		// when
+		_, err := reconcileNotification(controller, notification)
+
+		// then
+		require.NoError(t, err)
+		assert.Equal(t, 1, ds.calls)
+	})
+
+	t.Run("unable to list the sent notifications", func(t *testing.T) {
+		// given
+		toolchainConfig := commonconfig.NewToolchainConfigObjWithReset(t,
+			hostconfig.Annotation(toolchainconfig.NotificationRateLimitAnnotationKey, "3"))
+		notification := newTemplateNotification("alert", "alert", "admin@acme.com")
+		ds := &failingDeliveryService{}
+		controller, cl := newController(t, ds, toolchainConfig, notification)
+		cl.MockList = func(ctx context.Context, list runtimeclient.ObjectList, opts ...runtimeclient.ListOption) error {
+			if _, ok := list.(*toolchainv1alpha1.NotificationList); ok {
+				return assert.AnError
+			}
+			return cl.Client.List(ctx, list, opts...)
+		}
+
+		// when
+		_, err := reconcileNotification(controller, notification)
+
+		// then
+		require.EqualError(t, err, "unable to list the notifications sent to the same recipient: "+assert.AnError.Error())
+		assert.Equal(t, 0, ds.calls)
+	})
+}
+
+func newTemplateNotification(name, template, recipient string) *toolchainv1alpha1.Notification {
+	return &toolchainv1alpha1.Notification{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: test.HostOperatorNs,
+		},
+		Spec: toolchainv1alpha1.NotificationSpec{
+			Template:  template,
+			Recipient: recipient,
+		},
+	}
+}
+
+// newSentNotification returns a notification which was sent the given duration ago
+func newSentNotification(name, template, recipient string, sentAgo time.Duration) *toolchainv1alpha1.Notification {
+	notification := newTemplateNotification(name, template, recipient)
+	setThrottlingLabels(notification)
+	cond := sentCond()
+	cond.LastTransitionTime = metav1.NewTime(time.Now().Add(-sentAgo))
+	notification.Status.Conditions = []toolchainv1alpha1.Condition{cond}
+	return notification
+}
+
+func suppressedCond(msg string) toolchainv1alpha1.Condition {
+	return toolchainv1alpha1.Condition{
+		Type:    NotificationSuppressed,
+		Status:  corev1.ConditionTrue,
+		Reason:  NotificationDuplicateReason,
+		Message: msg,
+	}
+}
@@ -0,0 +1,208 @@
+package notification
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"text/template"
+	"time"
+
+	toolchainv1alpha1 "github.com/codeready-toolchain/api/api/v1alpha1"
+	"github.com/codeready-toolchain/host-operator/controllers/toolchainconfig"
+)
+
+const (
+	// WebhookSignatureHeader contains the HMAC-SHA256 signature (`sha256=<hex>`) of `<timestamp>.<payload>`,
+	// computed with the signing secret of the webhook
+	WebhookSignatureHeader = "X-Toolchain-Signature"
+	// WebhookTimestampHeader contains the unix time at which the payload was signed
+	WebhookTimestampHeader = "X-Toolchain-Timestamp"
+
+	webhookRequestTimeout         = 10 * time.Second
+	defaultWebhookPayloadTemplate = `{"name": {{json .Name}}, "type": {{json .Type}}, "recipient": {{json .Recipient}}, "subject": {{json .Subject}}, "content": {{json .Content}}}`
+)
+
+type WebhookDeliveryError struct {
+	webhook      string
+	statusCode   int
+	errorMessage string
+	permanent    bool
+}
+
+func (e WebhookDeliveryError) Error() string {
+	return fmt.Sprintf("error while delivering notification to webhook (Name: %s, Status: %d) - %s", e.webhook, e.statusCode, e.errorMessage)
+}
+
+// Permanent returns true if the webhook rejected the payload (4xx status) or if the payload could not be generated,
+// in which case retrying will not help
+func (e WebhookDeliveryError) Permanent() bool {
+	return e.permanent
+}
+
+func NewWebhookDeliveryError(webhook string, statusCode int, errorMessage string) error {
+	return WebhookDeliveryError{
+		webhook:      webhook,
+		statusCode:   statusCode,
+		errorMessage: errorMessage,
+	}
+}
+
+type WebhookConfig interface {
+	GetNotificationWebhooks() ([]toolchainconfig.NotificationWebhook, error)
+	GetNotificationRoutes() ([]toolchainconfig.NotificationRoute, error)
+}
+
+type WebhookOption interface {
+	// ApplyToWebhook applies this configuration to the given webhook delivery service.
+	ApplyToWebhook(*WebhookNotificationDeliveryService)
+}
+
+// webhookPayloadContext is the data the payload template is executed with. Text is the plain text alternative of the (HTML) Content.
+type webhookPayloadContext struct {
+	Name      string
+	Type      string
+	Recipient string
+	Subject   string
+	Content   string
+	Text      string
+	Context   map[string]string
+}
+
+// WebhookNotificationDeliveryService delivers the notifications as JSON payloads POSTed to an HTTP endpoint.
+// A failed delivery is not retried by the service: the notification controller retries it, after a backoff.
+type WebhookNotificationDeliveryService struct {
+	base            BaseNotificationDeliveryService
+	Name            string
+	URL             string
+	SigningSecret   string
+	HTTPClient      *http.Client
+	payloadTemplate *template.Template
+}
+
+// NewWebhookNotificationDeliveryService creates a delivery service that sends the notifications to the given webhook
+func NewWebhookNotificationDeliveryService(webhook toolchainconfig.NotificationWebhook, templateLoader TemplateLoader,
+	opts ...WebhookOption) (*WebhookNotificationDeliveryService, error) {
+
+	payloadTemplate := webhook.PayloadTemplate
+	if payloadTemplate == "" {
+		payloadTemplate = defaultWebhookPayloadTemplate
+	}
+	tmpl, err := template.New(webhook.Name).Funcs(template.FuncMap{"json": jsonValue}).Parse(payloadTemplate)
+	if err != nil {
+		return nil, fmt.Errorf("invalid payload template of webhook '%s': %w", webhook.Name, err)
+	}
+
+	s := &WebhookNotificationDeliveryService{
+		base:            BaseNotificationDeliveryService{TemplateLoader: templateLoader},
+		Name:            webhook.Name,
+		URL:             webhook.URL,
+		SigningSecret:   webhook.SigningSecret,
+		HTTPClient:      &http.Client{Timeout: webhookRequestTimeout},
+		payloadTemplate: tmpl,
+	}
+
+	for _, opt := range opts {
+		opt.ApplyToWebhook(s)
+	}
+
+	return s, nil
+}
+
+func (s *WebhookNotificationDeliveryService) Send(notification *toolchainv1alpha1.Notification, templateSetName string) error {
+	msg, err := s.base.generateMessage(notification, templateSetName, "")
+	if err != nil {
+		return err
+	}
+
+	payload, err := s.newPayload(notification, msg)
+	if err != nil {
+		return WebhookDeliveryError{
+			webhook:      s.Name,
+			errorMessage: err.Error(),
+			permanent:    true,
+		}
+	}
+
+	statusCode, retryable, err := s.post(payload)
+	if err != nil {
+		return WebhookDeliveryError{
+			webhook:      s.Name,
+			statusCode:   statusCode,
+			errorMessage: err.Error(),
+			permanent:    !retryable,
+		}
+	}
+	return nil
+}
+
+// newPayload executes the payload template and verifies that the result is valid JSON
+func (s *WebhookNotificationDeliveryService) newPayload(notification *toolchainv1alpha1.Notification, msg message) ([]byte, error) {
+	var buf bytes.Buffer
+	err := s.payloadTemplate.Execute(&buf, webhookPayloadContext{
+		Name:      notification.Name,
+		Type:      notification.Labels[toolchainv1alpha1.NotificationTypeLabelKey],
+		Recipient: notification.Spec.Recipient,
+		Subject:   msg.subject,
+		Content:   msg.html,
+		Text:      msg.text,
+		Context:   notification.Spec.Context,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if !json.Valid(buf.Bytes()) {
+		return nil, fmt.Errorf("the payload template did not produce valid JSON")
+	}
+	return buf.Bytes(), nil
+}
+
+// post sends the payload to the webhook. Returns the status code of the response (if any), and if the request can be retried in case of error
+func (s *WebhookNotificationDeliveryService) post(payload []byte) (int, bool, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), webhookRequestTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.URL, bytes.NewReader(payload))
+	if err != nil {
+		return 0, false, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if s.SigningSecret != "" {
+		timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+		req.Header.Set(WebhookTimestampHeader, timestamp)
+		req.Header.Set(WebhookSignatureHeader, "sha256="+SignWebhookPayload(s.SigningSecret, timestamp, payload))
+	}
+
+	resp, err := s.HTTPClient.Do(req)
+	if err != nil {
+		// network errors are transient
+		return 0, true, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return resp.StatusCode, false, nil
+	}
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+	return resp.StatusCode, !isPermanentHTTPStatus(resp.StatusCode), fmt.Errorf("unexpected response: %s", string(body))
+}
+
+// SignWebhookPayload returns the hex-encoded HMAC-SHA256 of `<timestamp>.<payload>`
+func SignWebhookPayload(secret, timestamp string, payload []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(payload)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// jsonValue returns the given value as a JSON literal, to be used in the payload templates
+func jsonValue(v interface{}) (string, error) {
+	b, err := json.Marshal(v)
+	return string(b), err
+}
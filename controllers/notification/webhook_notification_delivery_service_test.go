@@ -0,0 +1,240 @@
+package notification
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	toolchainv1alpha1 "github.com/codeready-toolchain/api/api/v1alpha1"
+	"github.com/codeready-toolchain/host-operator/controllers/toolchainconfig"
+	"github.com/codeready-toolchain/host-operator/pkg/templates/notificationtemplates"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestWebhookNotificationDeliveryService(t *testing.T) {
+	// given
+	templateLoader := NewMockTemplateLoader(
+		&notificationtemplates.NotificationTemplate{
+			Subject: "Hi {{.FirstName}}",
+			Content: "<p>Welcome {{.FirstName}}</p>",
+			Name:    "welcome",
+		})
+	notification := &toolchainv1alpha1.Notification{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:   "toolchainstatus-unready-20240101",
+			Labels: map[string]string{toolchainv1alpha1.NotificationTypeLabelKey: "toolchainstatus-unready"},
+		},
+		Spec: toolchainv1alpha1.NotificationSpec{
+			Recipient: "admin@redhat.com",
+			Subject:   "ToolchainStatus is \"unready\"",
+			Content:   "<div>details</div>",
+		},
+	}
+
+	t.Run("default payload", func(t *testing.T) {
+		// given
+		server := newTestWebhookServer(t)
+		svc, err := NewWebhookNotificationDeliveryService(toolchainconfig.NotificationWebhook{Name: "ops", URL: server.URL()}, templateLoader)
+		require.NoError(t, err)
+
+		// when
+		err = svc.Send(notification, notificationtemplates.SandboxTemplateSetName)
+
+		// then
+		require.NoError(t, err)
+		requests := server.receivedRequests()
+		require.Len(t, requests, 1)
+		assert.Equal(t, "application/json", requests[0].header.Get("Content-Type"))
+		assert.Empty(t, requests[0].header.Get(WebhookSignatureHeader))
+		payload := map[string]string{}
+		require.NoError(t, json.Unmarshal(requests[0].body, &payload))
+		assert.Equal(t, map[string]string{
+			"name":      "toolchainstatus-unready-20240101",
+			"type":      "toolchainstatus-unready",
+			"recipient": "admin@redhat.com",
+			"subject":   "ToolchainStatus is \"unready\"",
+			"content":   "<div>details</div>",
+		}, payload)
+	})
+
+	t.Run("custom payload template with notification template", func(t *testing.T) {
+		// given
+		server := newTestWebhookServer(t)
+		svc, err := NewWebhookNotificationDeliveryService(toolchainconfig.NotificationWebhook{
+			Name:            "chat",
+			URL:             server.URL(),
+			PayloadTemplate: `{"text": {{json .Subject}}, "user": {{json (index .Context "FirstName")}}}`,
+		}, templateLoader)
+		require.NoError(t, err)
+
+		// when
+		err = svc.Send(&toolchainv1alpha1.Notification{
+			Spec: toolchainv1alpha1.NotificationSpec{
+				Recipient: "jsmith@redhat.com",
+				Template:  "welcome",
+				Context:   map[string]string{"FirstName": "John"},
+			},
+		}, notificationtemplates.SandboxTemplateSetName)
+
+		// then
+		require.NoError(t, err)
+		requests := server.receivedRequests()
+		require.Len(t, requests, 1)
+		assert.JSONEq(t, `{"text": "Hi John", "user": "John"}`, string(requests[0].body))
+	})
+
+	t.Run("signed payload", func(t *testing.T) {
+		// given
+		server := newTestWebhookServer(t)
+		svc, err := NewWebhookNotificationDeliveryService(toolchainconfig.NotificationWebhook{
+			Name:          "ops",
+			URL:           server.URL(),
+			SigningSecret: "s3cr3t",
+		}, templateLoader)
+		require.NoError(t, err)
+
+		// when
+		err = svc.Send(notification, notificationtemplates.SandboxTemplateSetName)
+
+		// then
+		require.NoError(t, err)
+		requests := server.receivedRequests()
+		require.Len(t, requests, 1)
+		timestamp := requests[0].header.Get(WebhookTimestampHeader)
+		require.NotEmpty(t, timestamp)
+		assert.Equal(t, "sha256="+SignWebhookPayload("s3cr3t", timestamp, requests[0].body), requests[0].header.Get(WebhookSignatureHeader))
+		assert.NotEqual(t, "sha256="+SignWebhookPayload("other", timestamp, requests[0].body), requests[0].header.Get(WebhookSignatureHeader))
+	})
+
+	t.Run("delivery errors", func(t *testing.T) {
+
+		t.Run("server errors are transient and not retried by the service", func(t *testing.T) {
+			// given
+			server := newTestWebhookServer(t, http.StatusBadGateway)
+			svc, err := NewWebhookNotificationDeliveryService(toolchainconfig.NotificationWebhook{Name: "ops", URL: server.URL()}, templateLoader)
+			require.NoError(t, err)
+
+			// when
+			err = svc.Send(notification, notificationtemplates.SandboxTemplateSetName)
+
+			// then
+			require.Error(t, err)
+			require.IsType(t, WebhookDeliveryError{}, err)
+			assert.Contains(t, err.Error(), "error while delivering notification to webhook (Name: ops, Status: 502)")
+			assert.False(t, IsPermanentDeliveryError(err))
+			// the delivery is retried by the controller, after a backoff
+			assert.Len(t, server.receivedRequests(), 1)
+		})
+
+		t.Run("rate limited requests are transient", func(t *testing.T) {
+			// given
+			server := newTestWebhookServer(t, http.StatusTooManyRequests)
+			svc, err := NewWebhookNotificationDeliveryService(toolchainconfig.NotificationWebhook{Name: "ops", URL: server.URL()}, templateLoader)
+			require.NoError(t, err)
+
+			// when
+			err = svc.Send(notification, notificationtemplates.SandboxTemplateSetName)
+
+			// then
+			require.Error(t, err)
+			assert.False(t, IsPermanentDeliveryError(err))
+			assert.Len(t, server.receivedRequests(), 1)
+		})
+
+		t.Run("client errors are permanent", func(t *testing.T) {
+			// given
+			server := newTestWebhookServer(t, http.StatusBadRequest)
+			svc, err := NewWebhookNotificationDeliveryService(toolchainconfig.NotificationWebhook{Name: "ops", URL: server.URL()}, templateLoader)
+			require.NoError(t, err)
+
+			// when
+			err = svc.Send(notification, notificationtemplates.SandboxTemplateSetName)
+
+			// then
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), "Status: 400")
+			assert.True(t, IsPermanentDeliveryError(err))
+			assert.Len(t, server.receivedRequests(), 1)
+		})
+	})
+
+	t.Run("failures", func(t *testing.T) {
+
+		t.Run("invalid payload template", func(t *testing.T) {
+			// when
+			_, err := NewWebhookNotificationDeliveryService(toolchainconfig.NotificationWebhook{Name: "ops", PayloadTemplate: "{{invalid"}, templateLoader)
+
+			// then
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), "invalid payload template of webhook 'ops'")
+		})
+
+		t.Run("payload is not json", func(t *testing.T) {
+			// given
+			server := newTestWebhookServer(t)
+			svc, err := NewWebhookNotificationDeliveryService(toolchainconfig.NotificationWebhook{
+				Name:            "ops",
+				URL:             server.URL(),
+				PayloadTemplate: `{"text": {{.Subject}}}`,
+			}, templateLoader)
+			require.NoError(t, err)
+
+			// when
+			err = svc.Send(notification, notificationtemplates.SandboxTemplateSetName)
+
+			// then
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), "the payload template did not produce valid JSON")
+			assert.Empty(t, server.receivedRequests())
+		})
+	})
+}
+
+type receivedRequest struct {
+	header http.Header
+	body   []byte
+}
+
+// testWebhookServer records the requests it receives, and replies with the given status codes (in order) before replying with 200
+type testWebhookServer struct {
+	server   *httptest.Server
+	mu       sync.Mutex
+	requests []receivedRequest
+	statuses []int
+}
+
+func newTestWebhookServer(t *testing.T, statuses ...int) *testWebhookServer {
+	s := &testWebhookServer{statuses: statuses}
+	s.server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		require.NoError(t, err)
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		s.requests = append(s.requests, receivedRequest{header: r.Header.Clone(), body: body})
+		if len(s.statuses) > 0 {
+			status := s.statuses[0]
+			s.statuses = s.statuses[1:]
+			w.WriteHeader(status)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	t.Cleanup(s.server.Close)
+	return s
+}
+
+func (s *testWebhookServer) URL() string {
+	return s.server.URL
+}
+
+func (s *testWebhookServer) receivedRequests() []receivedRequest {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]receivedRequest{}, s.requests...)
+}
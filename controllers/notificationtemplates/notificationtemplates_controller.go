@@ -0,0 +1,162 @@
+package notificationtemplates
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	toolchainv1alpha1 "github.com/codeready-toolchain/api/api/v1alpha1"
+	templates "github.com/codeready-toolchain/host-operator/pkg/templates/notificationtemplates"
+
+	errs "github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	runtimeclient "sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+const (
+	// TemplateSetLabelKey is the label of the ConfigMaps which contain notification templates. The value is the name of the template set
+	// (eg. `sandbox`) whose templates are overridden by the ones in the ConfigMap.
+	TemplateSetLabelKey = toolchainv1alpha1.LabelKeyPrefix + "notification-template-set"
+
+	// TemplateErrorAnnotationKey is set on the ConfigMaps of a template set which could not be activated, with the validation error as value
+	TemplateErrorAnnotationKey = toolchainv1alpha1.LabelKeyPrefix + "notification-template-error"
+
+	// SubjectKeySuffix is the suffix of the ConfigMap keys which contain the subject of a template (eg. `userprovisioned.subject.txt`).
+	// The keys of the localized templates start with their localized name instead (eg. `userprovisioned.fr.subject.txt`).
+	SubjectKeySuffix = ".subject.txt"
+	// ContentKeySuffix is the suffix of the ConfigMap keys which contain the content of a template (eg. `userprovisioned.notification.html`)
+	ContentKeySuffix = ".notification.html"
+	// TextContentKeySuffix is the suffix of the ConfigMap keys which contain the plain text content of a template (eg. `userprovisioned.notification.txt`)
+	TextContentKeySuffix = ".notification.txt"
+)
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *Reconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		Named("notificationtemplates").
+		For(&corev1.ConfigMap{}, builder.WithPredicates(templateSetPredicate())).
+		Complete(r)
+}
+
+// templateSetPredicate accepts the events of the ConfigMaps which have (or had) the TemplateSetLabelKey label
+func templateSetPredicate() predicate.Funcs {
+	hasLabel := func(obj runtimeclient.Object) bool {
+		_, found := obj.GetLabels()[TemplateSetLabelKey]
+		return found
+	}
+	return predicate.Funcs{
+		CreateFunc:  func(e event.CreateEvent) bool { return hasLabel(e.Object) },
+		UpdateFunc:  func(e event.UpdateEvent) bool { return hasLabel(e.ObjectOld) || hasLabel(e.ObjectNew) },
+		DeleteFunc:  func(e event.DeleteEvent) bool { return hasLabel(e.Object) },
+		GenericFunc: func(e event.GenericEvent) bool { return hasLabel(e.Object) },
+	}
+}
+
+// Reconciler loads the notification templates from the ConfigMaps, and activates them in place of the embedded templates
+type Reconciler struct {
+	Client    runtimeclient.Client
+	Namespace string
+}
+
+//+kubebuilder:rbac:groups="",resources=configmaps,verbs=get;list;watch;update;patch
+
+// Reconcile reloads all the template sets, regardless of which ConfigMap changed, so that deleted ConfigMaps (whose template set is unknown)
+// are also taken into account. The ConfigMaps of a same template set are applied in the order of their names.
+func (r *Reconciler) Reconcile(ctx context.Context, _ ctrl.Request) (ctrl.Result, error) {
+	logger := log.FromContext(ctx)
+
+	configMaps := &corev1.ConfigMapList{}
+	if err := r.Client.List(ctx, configMaps, runtimeclient.InNamespace(r.Namespace), runtimeclient.HasLabels{TemplateSetLabelKey}); err != nil {
+		return reconcile.Result{}, errs.Wrap(err, "unable to list the notification template ConfigMaps")
+	}
+	sets := map[string][]corev1.ConfigMap{}
+	for _, cm := range configMaps.Items {
+		setName := cm.Labels[TemplateSetLabelKey]
+		sets[setName] = append(sets[setName], cm)
+	}
+
+	for setName, cms := range sets {
+		sort.Slice(cms, func(i, j int) bool {
+			return cms[i].Name < cms[j].Name
+		})
+		err := activate(setName, cms)
+		if err != nil {
+			logger.Error(err, "unable to activate the notification templates", "template-set", setName)
+		} else {
+			logger.Info("notification templates activated", "template-set", setName)
+		}
+		if err := r.setTemplateError(ctx, cms, err); err != nil {
+			return reconcile.Result{}, err
+		}
+	}
+
+	// the template sets which don't have any ConfigMap anymore go back to the embedded templates
+	for _, setName := range templates.TemplateOverrideSetNames() {
+		if _, found := sets[setName]; !found {
+			templates.ClearTemplateOverrides(setName)
+			logger.Info("notification template overrides removed", "template-set", setName)
+		}
+	}
+	return reconcile.Result{}, nil
+}
+
+// activate merges the templates of the given ConfigMaps, and activates them if they are all valid
+func activate(setName string, configMaps []corev1.ConfigMap) error {
+	overrides := map[string]templates.NotificationTemplate{}
+	for _, cm := range configMaps {
+		for key, value := range cm.Data {
+			var name string
+			template := templates.NotificationTemplate{}
+			switch {
+			case strings.HasSuffix(key, SubjectKeySuffix):
+				name = strings.TrimSuffix(key, SubjectKeySuffix)
+				template = overrides[name]
+				template.Subject = value
+			case strings.HasSuffix(key, ContentKeySuffix):
+				name = strings.TrimSuffix(key, ContentKeySuffix)
+				template = overrides[name]
+				template.Content = value
+			case strings.HasSuffix(key, TextContentKeySuffix):
+				name = strings.TrimSuffix(key, TextContentKeySuffix)
+				template = overrides[name]
+				template.TextContent = value
+			default:
+				return fmt.Errorf("invalid key '%s' in ConfigMap '%s': must end with '%s', '%s' or '%s'", key, cm.Name, SubjectKeySuffix, ContentKeySuffix, TextContentKeySuffix)
+			}
+			overrides[name] = template
+		}
+	}
+	return templates.SetTemplateOverrides(setName, overrides)
+}
+
+// setTemplateError sets (or removes, if the given error is nil) the error annotation on the given ConfigMaps
+func (r *Reconciler) setTemplateError(ctx context.Context, configMaps []corev1.ConfigMap, templateErr error) error {
+	for i := range configMaps {
+		cm := &configMaps[i]
+		current, found := cm.Annotations[TemplateErrorAnnotationKey]
+		switch {
+		case templateErr == nil && !found:
+			continue
+		case templateErr == nil:
+			delete(cm.Annotations, TemplateErrorAnnotationKey)
+		case current == templateErr.Error():
+			continue
+		default:
+			if cm.Annotations == nil {
+				cm.Annotations = map[string]string{}
+			}
+			cm.Annotations[TemplateErrorAnnotationKey] = templateErr.Error()
+		}
+		if err := r.Client.Update(ctx, cm); err != nil {
+			return errs.Wrapf(err, "unable to update the ConfigMap '%s'", cm.Name)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,220 @@
+package notificationtemplates
+
+import (
+	"context"
+	"testing"
+
+	templates "github.com/codeready-toolchain/host-operator/pkg/templates/notificationtemplates"
+	"github.com/codeready-toolchain/toolchain-common/pkg/test"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	runtimeclient "sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+func TestReconcile(t *testing.T) {
+	// given
+	t.Cleanup(func() {
+		templates.ClearTemplateOverrides(templates.SandboxTemplateSetName)
+	})
+
+	t.Run("valid templates are activated", func(t *testing.T) {
+		// given
+		templates.ClearTemplateOverrides(templates.SandboxTemplateSetName)
+		cm := newTemplateConfigMap("custom-templates", templates.SandboxTemplateSetName, map[string]string{
+			"userprovisioned" + SubjectKeySuffix:  "Welcome aboard!",
+			"custom" + SubjectKeySuffix:           "Hi {{.FirstName}}",
+			"custom" + ContentKeySuffix:           "<p>Hello {{.FirstName}}</p>",
+			"custom" + TextContentKeySuffix:       "Hello {{.FirstName}}",
+			"userdeactivated" + ContentKeySuffix:  "<p>Bye</p>",
+			"userdeactivating" + ContentKeySuffix: "<p>Soon</p>",
+			"userdeactivating" + SubjectKeySuffix: "Leaving soon",
+		})
+		r, cl := prepareReconcile(t, cm)
+
+		// when
+		_, err := r.Reconcile(context.TODO(), reconcile.Request{})
+
+		// then
+		require.NoError(t, err)
+		provisioned, err := templates.GetNotificationTemplate(templates.UserProvisionedTemplateName, templates.SandboxTemplateSetName)
+		require.NoError(t, err)
+		assert.Equal(t, "Welcome aboard!", provisioned.Subject)
+		assert.NotEmpty(t, provisioned.Content) // taken from the embedded template
+		custom, err := templates.GetNotificationTemplate("custom", templates.SandboxTemplateSetName)
+		require.NoError(t, err)
+		assert.Equal(t, "Hi {{.FirstName}}", custom.Subject)
+		assert.Equal(t, "<p>Hello {{.FirstName}}</p>", custom.Content)
+		assert.Equal(t, "Hello {{.FirstName}}", custom.TextContent)
+		assertTemplateError(t, cl, cm.Name, "")
+
+		t.Run("invalid update is rejected and previous templates remain active", func(t *testing.T) {
+			// given
+			cm.Data["userprovisioned"+SubjectKeySuffix] = "Welcome {{.FirstName"
+			require.NoError(t, cl.Update(context.TODO(), cm))
+
+			// when
+			_, err := r.Reconcile(context.TODO(), reconcile.Request{})
+
+			// then
+			require.NoError(t, err)
+			provisioned, err := templates.GetNotificationTemplate(templates.UserProvisionedTemplateName, templates.SandboxTemplateSetName)
+			require.NoError(t, err)
+			assert.Equal(t, "Welcome aboard!", provisioned.Subject)
+			assertTemplateError(t, cl, cm.Name, "unclosed action")
+
+			t.Run("fixed update is activated and error is removed", func(t *testing.T) {
+				// given
+				require.NoError(t, cl.Get(context.TODO(), runtimeclient.ObjectKeyFromObject(cm), cm))
+				cm.Data["userprovisioned"+SubjectKeySuffix] = "Welcome {{.FirstName}}"
+				require.NoError(t, cl.Update(context.TODO(), cm))
+
+				// when
+				_, err := r.Reconcile(context.TODO(), reconcile.Request{})
+
+				// then
+				require.NoError(t, err)
+				provisioned, err := templates.GetNotificationTemplate(templates.UserProvisionedTemplateName, templates.SandboxTemplateSetName)
+				require.NoError(t, err)
+				assert.Equal(t, "Welcome {{.FirstName}}", provisioned.Subject)
+				assertTemplateError(t, cl, cm.Name, "")
+
+				t.Run("deleted configmap restores the embedded templates", func(t *testing.T) {
+					// given
+					require.NoError(t, cl.Delete(context.TODO(), cm))
+
+					// when
+					_, err := r.Reconcile(context.TODO(), reconcile.Request{})
+
+					// then
+					require.NoError(t, err)
+					assert.Empty(t, templates.TemplateOverrideSetNames())
+					provisioned, err := templates.GetNotificationTemplate(templates.UserProvisionedTemplateName, templates.SandboxTemplateSetName)
+					require.NoError(t, err)
+					assert.NotEqual(t, "Welcome {{.FirstName}}", provisioned.Subject)
+					_, err = templates.GetNotificationTemplate("custom", templates.SandboxTemplateSetName)
+					require.Error(t, err)
+				})
+			})
+		})
+	})
+
+	t.Run("configmaps of a same set are applied in the order of their names", func(t *testing.T) {
+		// given
+		templates.ClearTemplateOverrides(templates.SandboxTemplateSetName)
+		first := newTemplateConfigMap("a-templates", templates.SandboxTemplateSetName, map[string]string{
+			"userprovisioned" + SubjectKeySuffix: "first",
+		})
+		second := newTemplateConfigMap("b-templates", templates.SandboxTemplateSetName, map[string]string{
+			"userprovisioned" + SubjectKeySuffix: "second",
+		})
+		r, _ := prepareReconcile(t, second, first)
+
+		// when
+		_, err := r.Reconcile(context.TODO(), reconcile.Request{})
+
+		// then
+		require.NoError(t, err)
+		provisioned, err := templates.GetNotificationTemplate(templates.UserProvisionedTemplateName, templates.SandboxTemplateSetName)
+		require.NoError(t, err)
+		assert.Equal(t, "second", provisioned.Subject)
+	})
+
+	t.Run("failures", func(t *testing.T) {
+
+		t.Run("unknown key", func(t *testing.T) {
+			// given
+			templates.ClearTemplateOverrides(templates.SandboxTemplateSetName)
+			cm := newTemplateConfigMap("custom-templates", templates.SandboxTemplateSetName, map[string]string{
+				"userprovisioned.html": "<p>Hi</p>",
+			})
+			r, cl := prepareReconcile(t, cm)
+
+			// when
+			_, err := r.Reconcile(context.TODO(), reconcile.Request{})
+
+			// then
+			require.NoError(t, err)
+			assert.Empty(t, templates.TemplateOverrideSetNames())
+			assertTemplateError(t, cl, cm.Name, "invalid key 'userprovisioned.html' in ConfigMap 'custom-templates'")
+		})
+
+		t.Run("new template without content", func(t *testing.T) {
+			// given
+			templates.ClearTemplateOverrides(templates.SandboxTemplateSetName)
+			cm := newTemplateConfigMap("custom-templates", templates.SandboxTemplateSetName, map[string]string{
+				"custom" + SubjectKeySuffix: "Hi",
+			})
+			r, cl := prepareReconcile(t, cm)
+
+			// when
+			_, err := r.Reconcile(context.TODO(), reconcile.Request{})
+
+			// then
+			require.NoError(t, err)
+			assert.Empty(t, templates.TemplateOverrideSetNames())
+			assertTemplateError(t, cl, cm.Name, "must have a subject and a content")
+		})
+
+		t.Run("unable to list configmaps", func(t *testing.T) {
+			// given
+			r, cl := prepareReconcile(t)
+			cl.MockList = func(ctx context.Context, list runtimeclient.ObjectList, opts ...runtimeclient.ListOption) error {
+				return assert.AnError
+			}
+
+			// when
+			_, err := r.Reconcile(context.TODO(), reconcile.Request{})
+
+			// then
+			require.EqualError(t, err, "unable to list the notification template ConfigMaps: "+assert.AnError.Error())
+		})
+	})
+}
+
+func TestTemplateSetPredicate(t *testing.T) {
+	labeled := newTemplateConfigMap("custom-templates", templates.SandboxTemplateSetName, nil)
+	other := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "other", Namespace: test.HostOperatorNs}}
+	p := templateSetPredicate()
+
+	assert.True(t, p.Create(event.CreateEvent{Object: labeled}))
+	assert.False(t, p.Create(event.CreateEvent{Object: other}))
+	assert.True(t, p.Update(event.UpdateEvent{ObjectOld: labeled, ObjectNew: other}))
+	assert.True(t, p.Update(event.UpdateEvent{ObjectOld: other, ObjectNew: labeled}))
+	assert.False(t, p.Update(event.UpdateEvent{ObjectOld: other, ObjectNew: other}))
+	assert.True(t, p.Delete(event.DeleteEvent{Object: labeled}))
+	assert.False(t, p.Delete(event.DeleteEvent{Object: other}))
+}
+
+func prepareReconcile(t *testing.T, initObjs ...runtimeclient.Object) (*Reconciler, *test.FakeClient) {
+	cl := test.NewFakeClient(t, initObjs...)
+	return &Reconciler{
+		Client:    cl,
+		Namespace: test.HostOperatorNs,
+	}, cl
+}
+
+func newTemplateConfigMap(name, setName string, data map[string]string) *corev1.ConfigMap {
+	return &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: test.HostOperatorNs,
+			Labels:    map[string]string{TemplateSetLabelKey: setName},
+		},
+		Data: data,
+	}
+}
+
+func assertTemplateError(t *testing.T, cl runtimeclient.Client, name, expected string) {
+	cm := &corev1.ConfigMap{}
+	require.NoError(t, cl.Get(context.TODO(), test.NamespacedName(test.HostOperatorNs, name), cm))
+	if expected == "" {
+		assert.NotContains(t, cm.Annotations, TemplateErrorAnnotationKey)
+		return
+	}
+	assert.Contains(t, cm.Annotations[TemplateErrorAnnotationKey], expected)
+}
@@ -12,6 +12,7 @@ import (
 	"github.com/codeready-toolchain/host-operator/pkg/cluster"
 	"github.com/codeready-toolchain/host-operator/pkg/counter"
 	"github.com/codeready-toolchain/host-operator/pkg/mapper"
+	spaceutil "github.com/codeready-toolchain/host-operator/pkg/space"
 	"github.com/codeready-toolchain/host-operator/pkg/templates/nstemplatetiers"
 	"github.com/codeready-toolchain/toolchain-common/pkg/condition"
 	"github.com/codeready-toolchain/toolchain-common/pkg/hash"
@@ -36,7 +37,7 @@ import (
 type Reconciler struct {
 	Client              runtimeclient.Client
 	Namespace           string
-	MemberClusters      map[string]cluster.Cluster
+	MemberClusters      *cluster.MemberClusters
 	NextScheduledUpdate time.Time
 	LastExecutedUpdate  time.Time
 }
@@ -44,21 +45,25 @@ type Reconciler struct {
 // SetupWithManager sets up the controller reconciler with the Manager and the given member clusters.
 // Watches the Space resources in the current (host) cluster as its primary resources.
 // Watches NSTemplateSets on the member clusters as its secondary resources.
-func (r *Reconciler) SetupWithManager(mgr ctrl.Manager, memberClusters map[string]cluster.Cluster) error {
-	b := ctrl.NewControllerManagedBy(mgr).
+func (r *Reconciler) SetupWithManager(mgr ctrl.Manager, memberClusters *cluster.MemberClusters) error {
+	c, err := ctrl.NewControllerManagedBy(mgr).
 		// watch Spaces in the host cluster
 		For(&toolchainv1alpha1.Space{}, builder.WithPredicates(predicate.Or[runtimeclient.Object](predicate.GenerationChangedPredicate{}, predicate.AnnotationChangedPredicate{}))).
 		Watches(&toolchainv1alpha1.NSTemplateTier{},
 			handler.EnqueueRequestsFromMapFunc(MapNSTemplateTierToSpaces(r.Namespace, r.Client))).
 		Watches(&toolchainv1alpha1.SpaceBinding{},
-			handler.EnqueueRequestsFromMapFunc(MapSpaceBindingToParentAndSubSpaces(r.Client)))
-	// watch NSTemplateSets in all the member clusters
-	for _, memberCluster := range memberClusters {
-		b = b.WatchesRawSource(source.Kind[runtimeclient.Object](memberCluster.Cache, &toolchainv1alpha1.NSTemplateSet{},
+			handler.EnqueueRequestsFromMapFunc(MapSpaceBindingToParentAndSubSpaces(r.Client))).
+		// the requests rejected by the circuit breaker of a member cluster are retried once it lets the requests through again
+		Build(cluster.RequeueOnOpenCircuit(r))
+	if err != nil {
+		return err
+	}
+	// watch NSTemplateSets in all the member clusters, including the ones added later
+	return memberClusters.AddHandler(func(memberCluster cluster.Cluster) error {
+		return c.Watch(source.Kind[runtimeclient.Object](memberCluster.Cache, &toolchainv1alpha1.NSTemplateSet{},
 			handler.EnqueueRequestsFromMapFunc(mapper.MapByResourceName(r.Namespace)),
 		))
-	}
-	return b.Complete(r)
+	})
 }
 
 //+kubebuilder:rbac:groups=toolchain.dev.openshift.com,resources=spaces,verbs=get;list;watch;create;update;patch;delete
@@ -181,7 +186,7 @@ func (r *Reconciler) ensureNSTemplateSet(ctx context.Context, space *toolchainv1
 		return norequeue, err
 	}
 
-	memberCluster, found := r.MemberClusters[space.Spec.TargetCluster]
+	memberCluster, found := r.MemberClusters.Get(space.Spec.TargetCluster)
 	if !found {
 		return norequeue, r.setStatusProvisioningFailed(ctx, space, fmt.Errorf("unknown target member cluster '%s'", space.Spec.TargetCluster))
 	}
@@ -336,11 +341,11 @@ func (r *Reconciler) manageNSTemplateSet(ctx context.Context, space *toolchainv1
 
 	// update the NSTemplateSet if needed (including in case of missing space roles)
 	nsTmplSetSpec := NewNSTemplateSetSpec(space, spaceBindings, tmplTier)
-	featureToggleAnnotationUpdated := ensureFeatureToggleAnnotation(space, nsTmplSet) // also check if the feature annotation was updated
-	if !reflect.DeepEqual(nsTmplSet.Spec, nsTmplSetSpec) || featureToggleAnnotationUpdated {
+	annotationsUpdated := ensurePropagatedAnnotations(space, nsTmplSet) // also check if the feature, quarantine or hibernation annotations were updated
+	if !reflect.DeepEqual(nsTmplSet.Spec, nsTmplSetSpec) || annotationsUpdated {
 		logger.Info("NSTemplateSet is not up-to-date")
 		// postpone NSTemplateSet updates if needed
-		// but only for NSTemplateTier updates, not tier promotions or changes in spacebindings or feature toggle annotation
+		// but only for NSTemplateTier updates, not tier promotions or changes in spacebindings or propagated annotations
 		if space.Labels[hash.TemplateTierHashLabelKey(space.Spec.TierName)] != "" &&
 			condition.IsTrue(space.Status.Conditions, toolchainv1alpha1.ConditionReady) {
 			// postpone if needed, so we don't overflow the cluster with too many concurrent updates
@@ -392,28 +397,51 @@ func NewNSTemplateSet(namespace string, space *toolchainv1alpha1.Space, bindings
 	}
 	nsTmplSet.Spec = NewNSTemplateSetSpec(space, bindings, tmplTier)
 
-	// propagate the feature annotation from the space
-	ensureFeatureToggleAnnotation(space, nsTmplSet)
+	// propagate the feature, quarantine and hibernation annotations from the space
+	ensurePropagatedAnnotations(space, nsTmplSet)
 	return nsTmplSet
 }
 
-// ensureFeatureToggleAnnotation propagates the feature toggle annotation from the parent Space to the child NSTemplateSet
+// propagatedAnnotationKeys are the keys of the annotations which are propagated from the Space to the NSTemplateSet:
+// - the feature toggle annotation, so that the features which "won" are enabled in the namespaces
+// - the pending-deletion annotation, so that the namespaces of a Space in quarantine are locked
+// - the hibernated annotation, so that the NSTemplateSet of a hibernated Space is switched to the hibernated mode
+var propagatedAnnotationKeys = []string{
+	toolchainv1alpha1.FeatureToggleNameAnnotationKey,
+	spaceutil.PendingDeletionAnnotationKey,
+	spaceutil.HibernatedAnnotationKey,
+}
+
+// ensurePropagatedAnnotations propagates the annotations with the propagatedAnnotationKeys from the parent Space to the child NSTemplateSet.
+// If there is no such annotation in the Space then the annotation is deleted from the NSTemplateSet too.
+// Returns true if there is any changes in the NSTemplateSet's annotations.
+func ensurePropagatedAnnotations(space *toolchainv1alpha1.Space, nsTemplateSet *toolchainv1alpha1.NSTemplateSet) bool {
+	updated := false
+	for _, key := range propagatedAnnotationKeys {
+		if ensureAnnotation(space, nsTemplateSet, key) {
+			updated = true
+		}
+	}
+	return updated
+}
+
+// ensureAnnotation propagates the annotation with the given key from the parent Space to the child NSTemplateSet
 // if it's present in the space. If there is no annotation in the Space then the annotation is deleted from the NSTemplateSet too.
 // Returns true if there is any changes in the NSTemplateSet's annotation.
-func ensureFeatureToggleAnnotation(space *toolchainv1alpha1.Space, nsTemplateSet *toolchainv1alpha1.NSTemplateSet) bool {
-	winners, found := space.Annotations[toolchainv1alpha1.FeatureToggleNameAnnotationKey]
-	oldNSTemplateAnnotation, oldNSTemplateAnnotationFound := nsTemplateSet.Annotations[toolchainv1alpha1.FeatureToggleNameAnnotationKey]
+func ensureAnnotation(space *toolchainv1alpha1.Space, nsTemplateSet *toolchainv1alpha1.NSTemplateSet, key string) bool {
+	value, found := space.Annotations[key]
+	oldNSTemplateAnnotation, oldNSTemplateAnnotationFound := nsTemplateSet.Annotations[key]
 	if found {
 		// Propagate from Space to NSTemplateSet
 		if nsTemplateSet.Annotations == nil {
 			nsTemplateSet.Annotations = make(map[string]string)
 		}
-		nsTemplateSet.Annotations[toolchainv1alpha1.FeatureToggleNameAnnotationKey] = winners
-		return oldNSTemplateAnnotation != winners
+		nsTemplateSet.Annotations[key] = value
+		return !oldNSTemplateAnnotationFound || oldNSTemplateAnnotation != value
 	}
 	if oldNSTemplateAnnotationFound {
 		// Delete the annotation from NSTemplateSet because it doesn't exist in the Space anymore
-		delete(nsTemplateSet.Annotations, toolchainv1alpha1.FeatureToggleNameAnnotationKey)
+		delete(nsTemplateSet.Annotations, key)
 		return true
 	}
 	return false
@@ -551,7 +579,7 @@ func (r *Reconciler) deleteNSTemplateSet(ctx context.Context, space *toolchainv1
 func (r *Reconciler) deleteNSTemplateSetFromCluster(ctx context.Context, space *toolchainv1alpha1.Space, targetCluster string) (bool, error) {
 	logger := log.FromContext(ctx)
 
-	memberCluster, found := r.MemberClusters[targetCluster]
+	memberCluster, found := r.MemberClusters.Get(targetCluster)
 	if !found {
 		return false, fmt.Errorf("cannot delete NSTemplateSet: unknown target member cluster: '%s'", targetCluster)
 	}
@@ -12,6 +12,7 @@ import (
 	"github.com/codeready-toolchain/host-operator/pkg/apis"
 	"github.com/codeready-toolchain/host-operator/pkg/cluster"
 	"github.com/codeready-toolchain/host-operator/pkg/metrics"
+	spaceutil "github.com/codeready-toolchain/host-operator/pkg/space"
 	. "github.com/codeready-toolchain/host-operator/test"
 	tiertest "github.com/codeready-toolchain/host-operator/test/nstemplatetier"
 	spacebindingtest "github.com/codeready-toolchain/host-operator/test/spacebinding"
@@ -1293,6 +1294,48 @@ func TestUpdateSpaceTier(t *testing.T) {
 		}
 	})
 
+	t.Run("update needed due to pending-deletion annotation change in space", func(t *testing.T) {
+
+		t.Run("space in quarantine", func(t *testing.T) {
+			// given
+			s := spacetest.NewSpace(test.HostOperatorNs, "oddity",
+				spacetest.WithSpecTargetCluster("member-1"),
+				spacetest.WithAnnotation(spaceutil.PendingDeletionAnnotationKey, "2024-01-01T00:00:00Z"))
+			nsTemplateSet := nstemplatetsettest.NewNSTemplateSet("oddity", nstemplatetsettest.WithReadyCondition(), nstemplatetsettest.WithReferencesFor(base1nsTier))
+			hostClient := test.NewFakeClient(t, s, base1nsTier)
+			member1 := NewMemberClusterWithClient(test.NewFakeClient(t, nsTemplateSet), "member-1", corev1.ConditionTrue)
+			ctrl := newReconciler(hostClient, member1)
+
+			// when
+			_, err = ctrl.Reconcile(context.TODO(), requestFor(s))
+
+			// then
+			require.NoError(t, err)
+			nstemplatetsettest.AssertThatNSTemplateSet(t, test.MemberOperatorNs, s.Name, member1.Client).
+				Exists().
+				HasAnnotationWithValue(spaceutil.PendingDeletionAnnotationKey, "2024-01-01T00:00:00Z")
+		})
+
+		t.Run("space restored from quarantine", func(t *testing.T) {
+			// given
+			s := spacetest.NewSpace(test.HostOperatorNs, "oddity", spacetest.WithSpecTargetCluster("member-1"))
+			nsTemplateSet := nstemplatetsettest.NewNSTemplateSet("oddity", nstemplatetsettest.WithReadyCondition(), nstemplatetsettest.WithReferencesFor(base1nsTier),
+				nstemplatetsettest.WithAnnotation(spaceutil.PendingDeletionAnnotationKey, "2024-01-01T00:00:00Z"))
+			hostClient := test.NewFakeClient(t, s, base1nsTier)
+			member1 := NewMemberClusterWithClient(test.NewFakeClient(t, nsTemplateSet), "member-1", corev1.ConditionTrue)
+			ctrl := newReconciler(hostClient, member1)
+
+			// when
+			_, err = ctrl.Reconcile(context.TODO(), requestFor(s))
+
+			// then
+			require.NoError(t, err)
+			nstemplatetsettest.AssertThatNSTemplateSet(t, test.MemberOperatorNs, s.Name, member1.Client).
+				Exists().
+				DoesNotHaveAnnotation(spaceutil.PendingDeletionAnnotationKey)
+		})
+	})
+
 	t.Run("update not needed when already up-to-date", func(t *testing.T) {
 		// given that Space is promoted to `base1ns` tier and corresponding NSTemplateSet is already up-to-date and ready
 		s := spacetest.NewSpace(test.HostOperatorNs, "oddity",
@@ -2229,7 +2272,7 @@ func newReconciler(hostCl runtimeclient.Client, memberClusters ...*commoncluster
 	return &space.Reconciler{
 		Client:         hostCl,
 		Namespace:      test.HostOperatorNs,
-		MemberClusters: clusters,
+		MemberClusters: cluster.NewStaticMemberClusters(clusters),
 	}
 }
 
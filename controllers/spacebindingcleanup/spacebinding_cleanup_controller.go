@@ -44,7 +44,7 @@ type Reconciler struct {
 	runtimeclient.Client
 	Scheme         *runtime.Scheme
 	Namespace      string
-	MemberClusters map[string]cluster.Cluster
+	MemberClusters *cluster.MemberClusters
 }
 
 //+kubebuilder:rbac:groups=toolchain.dev.openshift.com,resources=spacebindings,verbs=get;list;watch;create;update;patch;delete
@@ -166,7 +166,7 @@ func (r *Reconciler) deleteSpaceBindingRequest(ctx context.Context, sbrAssociate
 	logger := log.FromContext(ctx)
 
 	spaceBindingRequest := &toolchainv1alpha1.SpaceBindingRequest{}
-	memberClusterWithSpaceBindingRequest, found, err := cluster.LookupMember(ctx, r.MemberClusters, types.NamespacedName{
+	memberClusterWithSpaceBindingRequest, found, err := cluster.LookupMember(ctx, r.MemberClusters.All(), types.NamespacedName{
 		Namespace: sbrAssociated.namespace,
 		Name:      sbrAssociated.name,
 	}, spaceBindingRequest)
@@ -358,7 +358,7 @@ func prepareReconciler(t *testing.T, hostCl runtimeclient.Client, memberClusters
 		Namespace:      test.HostOperatorNs,
 		Scheme:         s,
 		Client:         hostCl,
-		MemberClusters: clusters,
+		MemberClusters: cluster.NewStaticMemberClusters(clusters),
 	}
 	return reconciler
 }
@@ -29,30 +29,33 @@ type Reconciler struct {
 	Client         runtimeclient.Client
 	Scheme         *runtime.Scheme
 	Namespace      string
-	MemberClusters map[string]cluster.Cluster
+	MemberClusters *cluster.MemberClusters
 }
 
 // SetupWithManager sets up the controller reconciler with the Manager and the given member clusters.
 // Watches SpaceBindingRequests on the member clusters as its primary resources.
-func (r *Reconciler) SetupWithManager(mgr ctrl.Manager, memberClusters map[string]cluster.Cluster) error {
+func (r *Reconciler) SetupWithManager(mgr ctrl.Manager, memberClusters *cluster.MemberClusters) error {
 	// since it's mandatory to add a primary resource when creating a new controller,
 	// we add the SpaceBindingRequest CR even if there should be no reconciles triggered from the host cluster,
 	// only from member clusters (see watches below)
 	// SpaceBindingRequest owns spacebindings so events will be triggered for those from the host cluster.
-	b := ctrl.NewControllerManagedBy(mgr).
+	c, err := ctrl.NewControllerManagedBy(mgr).
 		For(&toolchainv1alpha1.SpaceBindingRequest{}).
 		Watches(&toolchainv1alpha1.SpaceBinding{},
 			handler.EnqueueRequestsFromMapFunc(MapSpaceBindingToSpaceBindingRequest()),
-		)
+		).
+		Build(r)
+	if err != nil {
+		return err
+	}
 
-	// Watch SpaceBindingRequests in all member clusters and all namespaces.
-	for _, memberCluster := range memberClusters {
-		b = b.WatchesRawSource(
+	// Watch SpaceBindingRequests in all member clusters and all namespaces, including the member clusters added later.
+	return memberClusters.AddHandler(func(memberCluster cluster.Cluster) error {
+		return c.Watch(
 			source.Kind[runtimeclient.Object](memberCluster.Cache, &toolchainv1alpha1.SpaceBindingRequest{},
 				&handler.EnqueueRequestForObject{},
 				predicate.GenerationChangedPredicate{}))
-	}
-	return b.Complete(r)
+	})
 }
 
 //+kubebuilder:rbac:groups=toolchain.dev.openshift.com,resources=spacebindingrequests,verbs=get;list;watch;create;update;patch;delete
@@ -67,7 +70,7 @@ func (r *Reconciler) Reconcile(ctx context.Context, request ctrl.Request) (ctrl.
 	// Fetch the SpaceBindingRequest
 	// search on all member clusters
 	spaceBindingRequest := &toolchainv1alpha1.SpaceBindingRequest{}
-	memberClusterWithSpaceBindingRequest, found, err := cluster.LookupMember(ctx, r.MemberClusters, types.NamespacedName{
+	memberClusterWithSpaceBindingRequest, found, err := cluster.LookupMember(ctx, r.MemberClusters.All(), types.NamespacedName{
 		Namespace: request.Namespace,
 		Name:      request.Name,
 	}, spaceBindingRequest)
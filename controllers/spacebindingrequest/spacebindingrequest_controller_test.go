@@ -743,7 +743,7 @@ func newReconciler(t *testing.T, hostCl runtimeclient.Client, memberClusters ...
 		Client:         hostCl,
 		Scheme:         s,
 		Namespace:      test.HostOperatorNs,
-		MemberClusters: clusters,
+		MemberClusters: cluster.NewStaticMemberClusters(clusters),
 	}
 }
 
@@ -5,6 +5,9 @@ import (
 	"time"
 
 	toolchainv1alpha1 "github.com/codeready-toolchain/api/api/v1alpha1"
+	"github.com/codeready-toolchain/host-operator/controllers/toolchainconfig"
+	"github.com/codeready-toolchain/host-operator/pkg/metrics"
+	spaceutil "github.com/codeready-toolchain/host-operator/pkg/space"
 	commoncontrollers "github.com/codeready-toolchain/toolchain-common/controllers"
 
 	errs "github.com/pkg/errors"
@@ -12,9 +15,12 @@ import (
 	"k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/types"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
 	runtimeclient "sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
 	"sigs.k8s.io/controller-runtime/pkg/handler"
 	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 )
 
@@ -25,7 +31,7 @@ type Reconciler struct {
 }
 
 // SetupWithManager sets up the controller reconciler with the Manager
-// Watches the Space and SpaceBinding resources
+// Watches the Space, SpaceBinding and UserSignup resources
 func (r *Reconciler) SetupWithManager(mgr ctrl.Manager) error {
 	return ctrl.NewControllerManagedBy(mgr).
 		Named("spacecleanup").
@@ -33,15 +39,42 @@ func (r *Reconciler) SetupWithManager(mgr ctrl.Manager) error {
 		Watches(
 			&toolchainv1alpha1.SpaceBinding{},
 			handler.EnqueueRequestsFromMapFunc(commoncontrollers.MapToOwnerByLabel(r.Namespace, toolchainv1alpha1.SpaceBindingSpaceLabelKey))).
+		Watches(
+			&toolchainv1alpha1.UserSignup{},
+			handler.EnqueueRequestsFromMapFunc(r.mapUserSignupToSpaces),
+			builder.WithPredicates(predicate.Funcs{
+				// the hibernated Spaces are deleted once their creator is deleted
+				CreateFunc: func(event.CreateEvent) bool { return false },
+				UpdateFunc: func(event.UpdateEvent) bool { return false },
+			})).
 		Complete(r)
 }
 
+// mapUserSignupToSpaces maps the UserSignup to the Spaces it created
+func (r *Reconciler) mapUserSignupToSpaces(ctx context.Context, obj runtimeclient.Object) []reconcile.Request {
+	spaces := &toolchainv1alpha1.SpaceList{}
+	if err := r.Client.List(ctx, spaces, runtimeclient.InNamespace(r.Namespace),
+		runtimeclient.MatchingLabels{toolchainv1alpha1.SpaceCreatorLabelKey: obj.GetName()}); err != nil {
+		log.FromContext(ctx).Error(err, "unable to list the Spaces created by the UserSignup", "usersignup", obj.GetName())
+		return nil
+	}
+	requests := make([]reconcile.Request, 0, len(spaces.Items))
+	for _, space := range spaces.Items {
+		requests = append(requests, reconcile.Request{NamespacedName: types.NamespacedName{Namespace: space.Namespace, Name: space.Name}})
+	}
+	return requests
+}
+
 const deletionTimeThreshold = 30 * time.Second
 
-//+kubebuilder:rbac:groups=toolchain.dev.openshift.com,resources=spaces,verbs=get;list;watch;delete
+//+kubebuilder:rbac:groups=toolchain.dev.openshift.com,resources=spaces,verbs=get;list;watch;update;patch;delete
 //+kubebuilder:rbac:groups=toolchain.dev.openshift.com,resources=spacebindings,verbs=get;list;watch
+//+kubebuilder:rbac:groups=toolchain.dev.openshift.com,resources=usersignups,verbs=get;list;watch
+//+kubebuilder:rbac:groups=toolchain.dev.openshift.com,resources=toolchainconfigs,verbs=get;list;watch
 
-// Reconcile ensures that Space which doesn't have any SpaceBinding is deleted
+// Reconcile ensures that Space which doesn't have any SpaceBinding is put in quarantine, and deleted once the quarantine period expired.
+// A Space in quarantine is restored when it gets a SpaceBinding again, or when an admin asks to retain it.
+// A hibernated Space is kept until its creator (UserSignup) is deleted, or until the max hibernation period expired.
 func (r *Reconciler) Reconcile(ctx context.Context, request ctrl.Request) (ctrl.Result, error) {
 	logger := log.FromContext(ctx)
 	logger.Info("reconciling Space")
@@ -88,7 +121,7 @@ func (r *Reconciler) ensureDeletionIfNeeded(ctx context.Context, space *toolchai
 
 	if len(bindings.Items) > 0 {
 		logger.Info("Space has SpaceBindings - skipping...", "number-of-spacebindings", len(bindings.Items))
-		return false, 0, nil
+		return false, 0, r.restoreIfNeeded(ctx, space, "binding")
 	}
 
 	// check if space has a parentSpace
@@ -98,20 +131,117 @@ func (r *Reconciler) ensureDeletionIfNeeded(ctx context.Context, space *toolchai
 		return false, 0, nil
 	}
 
+	// an admin asked to keep this space
+	if spaceutil.IsRetained(space) {
+		logger.Info("Space is retained - skipping...")
+		return false, 0, r.restoreIfNeeded(ctx, space, "admin")
+	}
+
+	config, err := toolchainconfig.GetToolchainConfig(r.Client)
+	if err != nil {
+		return false, 0, errs.Wrap(err, "unable to get ToolchainConfig")
+	}
+
+	// the space of a deactivated user was hibernated, it will be woken up when the user comes back
+	if spaceutil.IsHibernated(space) {
+		keep, requeueAfter, err := r.keepHibernated(ctx, space, config.SpaceConfig().MaxHibernationPeriod())
+		if err != nil || keep {
+			return requeueAfter > 0, requeueAfter, err
+		}
+	}
+
 	timeSinceCreation := time.Since(space.GetCreationTimestamp().Time)
-	if timeSinceCreation > deletionTimeThreshold {
+	if timeSinceCreation <= deletionTimeThreshold {
+		requeueAfter := deletionTimeThreshold - timeSinceCreation
+		logger.Info("Space is not ready for deletion yet", "requeue-after", requeueAfter, "created", space.CreationTimestamp)
+		return true, requeueAfter, nil
+	}
+
+	quarantinePeriod := config.SpaceConfig().QuarantinePeriod()
+	if quarantinePeriod == 0 {
+		// quarantine is disabled
 		if err := r.Client.Delete(ctx, space); err != nil {
 			return false, 0, errs.Wrap(err, "unable to delete Space")
 		}
-
 		logger.Info("Space has been deleted")
 		return false, 0, nil
 	}
 
-	requeueAfter := deletionTimeThreshold - timeSinceCreation
-	logger.Info("Space is not ready for deletion yet", "requeue-after", requeueAfter, "created", space.CreationTimestamp)
+	since, inQuarantine := spaceutil.PendingDeletionSince(space)
+	if !inQuarantine {
+		if space.Annotations == nil {
+			space.Annotations = map[string]string{}
+		}
+		space.Annotations[spaceutil.PendingDeletionAnnotationKey] = time.Now().Format(time.RFC3339)
+		if err := r.Client.Update(ctx, space); err != nil {
+			return false, 0, errs.Wrap(err, "unable to mark Space as pending deletion")
+		}
+		logger.Info("Space has been put in quarantine", "quarantine-period", quarantinePeriod)
+		return true, quarantinePeriod, nil
+	}
+
+	if timeInQuarantine := time.Since(since); timeInQuarantine < quarantinePeriod {
+		requeueAfter := quarantinePeriod - timeInQuarantine
+		logger.Info("Space is in quarantine", "requeue-after", requeueAfter, "pending-deletion-since", since)
+		return true, requeueAfter, nil
+	}
+
+	if err := r.Client.Delete(ctx, space); err != nil {
+		return false, 0, errs.Wrap(err, "unable to delete Space")
+	}
+	metrics.SpaceQuarantineDeletedTotal.Inc()
+	logger.Info("Space has been deleted after its quarantine period expired")
+	return false, 0, nil
+}
+
+// keepHibernated returns true if the hibernated Space should be kept, ie, if its creator still exists and the max hibernation period
+// (if any) didn't expire yet. In that case, it also returns when the max hibernation period expires.
+func (r *Reconciler) keepHibernated(ctx context.Context, space *toolchainv1alpha1.Space, maxHibernationPeriod time.Duration) (bool, time.Duration, error) {
+	logger := log.FromContext(ctx)
+	if creator := space.Labels[toolchainv1alpha1.SpaceCreatorLabelKey]; creator != "" {
+		userSignup := &toolchainv1alpha1.UserSignup{}
+		if err := r.Client.Get(ctx, types.NamespacedName{Namespace: space.Namespace, Name: creator}, userSignup); err != nil {
+			if !errors.IsNotFound(err) {
+				return false, 0, errs.Wrap(err, "unable to get the creator of the hibernated Space")
+			}
+			logger.Info("Space is hibernated but its creator was deleted", "creator", creator)
+			return false, 0, nil
+		}
+	}
+	if maxHibernationPeriod == 0 {
+		logger.Info("Space is hibernated - skipping...")
+		return true, 0, nil
+	}
+	since, err := time.Parse(time.RFC3339, space.Annotations[spaceutil.HibernatedAnnotationKey])
+	if err != nil {
+		// the hibernation time is unknown, the max hibernation period starts now
+		since = time.Now()
+		space.Annotations[spaceutil.HibernatedAnnotationKey] = since.Format(time.RFC3339)
+		if err := r.Client.Update(ctx, space); err != nil {
+			return false, 0, errs.Wrap(err, "unable to set the hibernation time of the Space")
+		}
+	}
+	if timeInHibernation := time.Since(since); timeInHibernation < maxHibernationPeriod {
+		requeueAfter := maxHibernationPeriod - timeInHibernation
+		logger.Info("Space is hibernated - skipping...", "requeue-after", requeueAfter, "hibernated-since", since)
+		return true, requeueAfter, nil
+	}
+	logger.Info("Space is hibernated since longer than the max hibernation period", "hibernated-since", since, "max-hibernation-period", maxHibernationPeriod)
+	return false, 0, nil
+}
 
-	return true, requeueAfter, nil
+// restoreIfNeeded takes the Space out of the quarantine, if it was in quarantine
+func (r *Reconciler) restoreIfNeeded(ctx context.Context, space *toolchainv1alpha1.Space, reason string) error {
+	if !spaceutil.IsPendingDeletion(space) {
+		return nil
+	}
+	delete(space.Annotations, spaceutil.PendingDeletionAnnotationKey)
+	if err := r.Client.Update(ctx, space); err != nil {
+		return errs.Wrap(err, "unable to restore Space from quarantine")
+	}
+	metrics.SpaceQuarantineRestoredTotal.WithLabelValues(reason).Inc()
+	log.FromContext(ctx).Info("Space has been restored from quarantine", "reason", reason)
+	return nil
 }
 
 // hasParentSpaceSpec verifies if there .spec.ParentSpace field is set in the current Space.
@@ -9,10 +9,17 @@ import (
 
 	toolchainv1alpha1 "github.com/codeready-toolchain/api/api/v1alpha1"
 	"github.com/codeready-toolchain/host-operator/controllers/spacecleanup"
+	"github.com/codeready-toolchain/host-operator/controllers/toolchainconfig"
 	"github.com/codeready-toolchain/host-operator/pkg/apis"
+	"github.com/codeready-toolchain/host-operator/pkg/metrics"
+	spaceutil "github.com/codeready-toolchain/host-operator/pkg/space"
+	hostconfig "github.com/codeready-toolchain/host-operator/test/config"
 	"github.com/codeready-toolchain/host-operator/test/spacebinding"
+	commonconfig "github.com/codeready-toolchain/toolchain-common/pkg/configuration"
 	"github.com/codeready-toolchain/toolchain-common/pkg/test"
+	metricstest "github.com/codeready-toolchain/toolchain-common/pkg/test/metrics"
 	spacetest "github.com/codeready-toolchain/toolchain-common/pkg/test/space"
+	commonsignup "github.com/codeready-toolchain/toolchain-common/pkg/test/usersignup"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -39,6 +46,240 @@ func TestCleanupSpace(t *testing.T) {
 			DoesNotExist()
 	})
 
+	t.Run("without any SpaceBinding and quarantine enabled - Space should be put in quarantine", func(t *testing.T) {
+		// given
+		space := spacetest.NewSpace(test.HostOperatorNs, "without-spacebinding", spacetest.WithCreationTimestamp(time.Now().Add(-31*time.Second)))
+		r, req, cl := prepareReconcile(t, space, commonconfig.NewToolchainConfigObjWithReset(t, hostconfig.Annotation(toolchainconfig.SpaceQuarantinePeriodAnnotationKey, "24h")))
+
+		// when
+		res, err := r.Reconcile(context.TODO(), req)
+
+		// then
+		require.NoError(t, err)
+		assert.True(t, res.Requeue)
+		assert.Equal(t, 24*time.Hour, res.RequeueAfter)
+		quarantined := spacetest.AssertThatSpace(t, test.HostOperatorNs, space.Name, cl).
+			Exists().
+			Get()
+		assert.True(t, spaceutil.IsPendingDeletion(quarantined))
+		metricstest.AssertMetricsCounterEquals(t, 0, metrics.SpaceQuarantineDeletedTotal)
+	})
+
+	t.Run("in quarantine", func(t *testing.T) {
+
+		t.Run("quarantine period not expired yet - Space shouldn't be deleted, just requeued", func(t *testing.T) {
+			// given
+			since := time.Now().Add(-time.Hour).Format(time.RFC3339)
+			space := spacetest.NewSpace(test.HostOperatorNs, "in-quarantine",
+				spacetest.WithCreationTimestamp(time.Now().Add(-time.Hour)),
+				spacetest.WithAnnotation(spaceutil.PendingDeletionAnnotationKey, since))
+			r, req, cl := prepareReconcile(t, space, commonconfig.NewToolchainConfigObjWithReset(t, hostconfig.Annotation(toolchainconfig.SpaceQuarantinePeriodAnnotationKey, "2h")))
+
+			// when
+			res, err := r.Reconcile(context.TODO(), req)
+
+			// then
+			require.NoError(t, err)
+			assert.True(t, res.Requeue)
+			assert.LessOrEqual(t, res.RequeueAfter, time.Hour)
+			assert.Greater(t, res.RequeueAfter, 59*time.Minute)
+			spacetest.AssertThatSpace(t, test.HostOperatorNs, space.Name, cl).
+				Exists().
+				HasAnnotationWithValue(spaceutil.PendingDeletionAnnotationKey, since)
+		})
+
+		t.Run("quarantine period expired - Space should be deleted", func(t *testing.T) {
+			// given
+			space := spacetest.NewSpace(test.HostOperatorNs, "in-quarantine",
+				spacetest.WithCreationTimestamp(time.Now().Add(-3*time.Hour)),
+				spacetest.WithAnnotation(spaceutil.PendingDeletionAnnotationKey, time.Now().Add(-3*time.Hour).Format(time.RFC3339)))
+			r, req, cl := prepareReconcile(t, space, commonconfig.NewToolchainConfigObjWithReset(t, hostconfig.Annotation(toolchainconfig.SpaceQuarantinePeriodAnnotationKey, "2h")))
+
+			// when
+			res, err := r.Reconcile(context.TODO(), req)
+
+			// then
+			require.NoError(t, err)
+			assert.False(t, res.Requeue)
+			spacetest.AssertThatSpace(t, test.HostOperatorNs, space.Name, cl).
+				DoesNotExist()
+			metricstest.AssertMetricsCounterEquals(t, 1, metrics.SpaceQuarantineDeletedTotal)
+		})
+
+		t.Run("with returning SpaceBinding - Space should be restored", func(t *testing.T) {
+			// given
+			space := spacetest.NewSpace(test.HostOperatorNs, "in-quarantine",
+				spacetest.WithCreationTimestamp(time.Now().Add(-3*time.Hour)),
+				spacetest.WithAnnotation(spaceutil.PendingDeletionAnnotationKey, time.Now().Add(-3*time.Hour).Format(time.RFC3339)))
+			spaceBinding := spacebinding.NewSpaceBinding("johny", space.Name, "admin", "a-creator")
+			r, req, cl := prepareReconcile(t, space, spaceBinding)
+
+			// when
+			res, err := r.Reconcile(context.TODO(), req)
+
+			// then
+			require.NoError(t, err)
+			assert.False(t, res.Requeue)
+			spacetest.AssertThatSpace(t, test.HostOperatorNs, space.Name, cl).
+				Exists().
+				DoesNotHaveAnnotation(spaceutil.PendingDeletionAnnotationKey)
+			metricstest.AssertMetricsCounterEquals(t, 1, metrics.SpaceQuarantineRestoredTotal.WithLabelValues("binding"))
+			metricstest.AssertMetricsCounterEquals(t, 0, metrics.SpaceQuarantineRestoredTotal.WithLabelValues("admin"))
+		})
+
+		t.Run("retained by an admin - Space should be restored", func(t *testing.T) {
+			// given
+			space := spacetest.NewSpace(test.HostOperatorNs, "in-quarantine",
+				spacetest.WithCreationTimestamp(time.Now().Add(-3*time.Hour)),
+				spacetest.WithAnnotation(spaceutil.PendingDeletionAnnotationKey, time.Now().Add(-3*time.Hour).Format(time.RFC3339)),
+				spacetest.WithAnnotation(spaceutil.RetainAnnotationKey, "true"))
+			r, req, cl := prepareReconcile(t, space)
+
+			// when
+			res, err := r.Reconcile(context.TODO(), req)
+
+			// then
+			require.NoError(t, err)
+			assert.False(t, res.Requeue)
+			spacetest.AssertThatSpace(t, test.HostOperatorNs, space.Name, cl).
+				Exists().
+				DoesNotHaveAnnotation(spaceutil.PendingDeletionAnnotationKey)
+			metricstest.AssertMetricsCounterEquals(t, 0, metrics.SpaceQuarantineRestoredTotal.WithLabelValues("binding"))
+			metricstest.AssertMetricsCounterEquals(t, 1, metrics.SpaceQuarantineRestoredTotal.WithLabelValues("admin"))
+		})
+	})
+
+	t.Run("hibernated", func(t *testing.T) {
+		newHibernatedSpace := func(hibernatedSince time.Time) *toolchainv1alpha1.Space {
+			return spacetest.NewSpace(test.HostOperatorNs, "hibernated",
+				spacetest.WithCreatorLabel("johny"),
+				spacetest.WithCreationTimestamp(time.Now().Add(-48*time.Hour)),
+				spacetest.WithAnnotation(spaceutil.HibernatedAnnotationKey, hibernatedSince.Format(time.RFC3339)))
+		}
+		userSignup := commonsignup.NewUserSignup(commonsignup.WithName("johny"), commonsignup.Deactivated())
+
+		t.Run("with existing creator - Space shouldn't be put in quarantine", func(t *testing.T) {
+			// given
+			space := newHibernatedSpace(time.Now().Add(-25 * time.Hour))
+			r, req, cl := prepareReconcile(t, space, userSignup, commonconfig.NewToolchainConfigObjWithReset(t, hostconfig.Annotation(toolchainconfig.SpaceQuarantinePeriodAnnotationKey, "24h")))
+
+			// when
+			res, err := r.Reconcile(context.TODO(), req)
+
+			// then
+			require.NoError(t, err)
+			assert.False(t, res.Requeue)
+			spacetest.AssertThatSpace(t, test.HostOperatorNs, space.Name, cl).
+				Exists().
+				DoesNotHaveAnnotation(spaceutil.PendingDeletionAnnotationKey)
+		})
+
+		t.Run("with deleted creator - Space should be deleted", func(t *testing.T) {
+			// given
+			space := newHibernatedSpace(time.Now().Add(-25 * time.Hour))
+			r, req, cl := prepareReconcile(t, space)
+
+			// when
+			res, err := r.Reconcile(context.TODO(), req)
+
+			// then
+			require.NoError(t, err)
+			assert.False(t, res.Requeue)
+			spacetest.AssertThatSpace(t, test.HostOperatorNs, space.Name, cl).
+				DoesNotExist()
+		})
+
+		t.Run("max hibernation period not expired yet - Space shouldn't be deleted, just requeued", func(t *testing.T) {
+			// given
+			space := newHibernatedSpace(time.Now().Add(-25 * time.Hour))
+			r, req, cl := prepareReconcile(t, space, userSignup, commonconfig.NewToolchainConfigObjWithReset(t, hostconfig.Annotation(toolchainconfig.SpaceMaxHibernationPeriodAnnotationKey, "26h")))
+
+			// when
+			res, err := r.Reconcile(context.TODO(), req)
+
+			// then
+			require.NoError(t, err)
+			assert.True(t, res.Requeue)
+			assert.LessOrEqual(t, res.RequeueAfter, time.Hour)
+			assert.Greater(t, res.RequeueAfter, 59*time.Minute)
+			spacetest.AssertThatSpace(t, test.HostOperatorNs, space.Name, cl).
+				Exists()
+		})
+
+		t.Run("max hibernation period expired - Space should be deleted", func(t *testing.T) {
+			// given
+			space := newHibernatedSpace(time.Now().Add(-25 * time.Hour))
+			r, req, cl := prepareReconcile(t, space, userSignup, commonconfig.NewToolchainConfigObjWithReset(t, hostconfig.Annotation(toolchainconfig.SpaceMaxHibernationPeriodAnnotationKey, "24h")))
+
+			// when
+			res, err := r.Reconcile(context.TODO(), req)
+
+			// then
+			require.NoError(t, err)
+			assert.False(t, res.Requeue)
+			spacetest.AssertThatSpace(t, test.HostOperatorNs, space.Name, cl).
+				DoesNotExist()
+		})
+
+		t.Run("unknown hibernation time - max hibernation period starts now", func(t *testing.T) {
+			// given
+			space := newHibernatedSpace(time.Now())
+			space.Annotations[spaceutil.HibernatedAnnotationKey] = "a while ago"
+			r, req, cl := prepareReconcile(t, space, userSignup, commonconfig.NewToolchainConfigObjWithReset(t, hostconfig.Annotation(toolchainconfig.SpaceMaxHibernationPeriodAnnotationKey, "24h")))
+
+			// when
+			res, err := r.Reconcile(context.TODO(), req)
+
+			// then
+			require.NoError(t, err)
+			assert.True(t, res.Requeue)
+			assert.Greater(t, res.RequeueAfter, 23*time.Hour)
+			hibernated := spacetest.AssertThatSpace(t, test.HostOperatorNs, space.Name, cl).
+				Exists().
+				Get()
+			_, err = time.Parse(time.RFC3339, hibernated.Annotations[spaceutil.HibernatedAnnotationKey])
+			require.NoError(t, err)
+		})
+
+		t.Run("unable to get the creator", func(t *testing.T) {
+			// given
+			space := newHibernatedSpace(time.Now().Add(-25 * time.Hour))
+			r, req, cl := prepareReconcile(t, space, userSignup)
+			cl.MockGet = func(ctx context.Context, key runtimeclient.ObjectKey, obj runtimeclient.Object, opts ...runtimeclient.GetOption) error {
+				if _, ok := obj.(*toolchainv1alpha1.UserSignup); ok {
+					return fmt.Errorf("some error")
+				}
+				return cl.Client.Get(ctx, key, obj, opts...)
+			}
+
+			// when
+			_, err := r.Reconcile(context.TODO(), req)
+
+			// then
+			require.EqualError(t, err, "unable to get the creator of the hibernated Space: some error")
+			spacetest.AssertThatSpace(t, test.HostOperatorNs, space.Name, cl).
+				Exists()
+		})
+	})
+
+	t.Run("retained by an admin - Space shouldn't be put in quarantine", func(t *testing.T) {
+		// given
+		space := spacetest.NewSpace(test.HostOperatorNs, "retained",
+			spacetest.WithCreationTimestamp(time.Now().Add(-time.Minute)),
+			spacetest.WithAnnotation(spaceutil.RetainAnnotationKey, "true"))
+		r, req, cl := prepareReconcile(t, space, commonconfig.NewToolchainConfigObjWithReset(t, hostconfig.Annotation(toolchainconfig.SpaceQuarantinePeriodAnnotationKey, "24h")))
+
+		// when
+		res, err := r.Reconcile(context.TODO(), req)
+
+		// then
+		require.NoError(t, err)
+		assert.False(t, res.Requeue)
+		spacetest.AssertThatSpace(t, test.HostOperatorNs, space.Name, cl).
+			Exists().
+			DoesNotHaveAnnotation(spaceutil.PendingDeletionAnnotationKey)
+	})
+
 	t.Run("without any SpaceBinding and created less than 30 seconds ago- Space shouldn't be deleted, just requeued", func(t *testing.T) {
 		// given
 		space := spacetest.NewSpace(test.HostOperatorNs, "without-spacebinding", spacetest.WithCreationTimestamp(time.Now().Add(-29*time.Second)))
@@ -207,6 +448,44 @@ func TestCleanupSpace(t *testing.T) {
 				Exists()
 		})
 
+		t.Run("when marking Space as pending deletion fails", func(t *testing.T) {
+			// given
+			space := spacetest.NewSpace(test.HostOperatorNs, "update-fails", spacetest.WithCreationTimestamp(time.Now().Add(-time.Minute)))
+			r, req, cl := prepareReconcile(t, space, commonconfig.NewToolchainConfigObjWithReset(t, hostconfig.Annotation(toolchainconfig.SpaceQuarantinePeriodAnnotationKey, "24h")))
+			cl.MockUpdate = func(ctx context.Context, obj runtimeclient.Object, opts ...runtimeclient.UpdateOption) error {
+				return fmt.Errorf("some error")
+			}
+
+			// when
+			res, err := r.Reconcile(context.TODO(), req)
+
+			// then
+			require.EqualError(t, err, "unable to mark Space as pending deletion: some error")
+			assert.False(t, res.Requeue)
+			spacetest.AssertThatSpace(t, test.HostOperatorNs, space.Name, cl).
+				Exists().
+				DoesNotHaveAnnotation(spaceutil.PendingDeletionAnnotationKey)
+		})
+
+		t.Run("when restoring Space fails", func(t *testing.T) {
+			// given
+			space := spacetest.NewSpace(test.HostOperatorNs, "restore-fails",
+				spacetest.WithCreationTimestamp(time.Now().Add(-time.Minute)),
+				spacetest.WithAnnotation(spaceutil.PendingDeletionAnnotationKey, time.Now().Format(time.RFC3339)))
+			spaceBinding := spacebinding.NewSpaceBinding("johny", space.Name, "admin", "a-creator")
+			r, req, cl := prepareReconcile(t, space, spaceBinding)
+			cl.MockUpdate = func(ctx context.Context, obj runtimeclient.Object, opts ...runtimeclient.UpdateOption) error {
+				return fmt.Errorf("some error")
+			}
+
+			// when
+			_, err := r.Reconcile(context.TODO(), req)
+
+			// then
+			require.EqualError(t, err, "unable to restore Space from quarantine: some error")
+			metricstest.AssertMetricsCounterEquals(t, 0, metrics.SpaceQuarantineRestoredTotal.WithLabelValues("binding"))
+		})
+
 		t.Run("when delete Space fails", func(t *testing.T) {
 			// given
 			space := spacetest.NewSpace(test.HostOperatorNs, "delete-fails", spacetest.WithCreationTimestamp(time.Now().Add(-time.Minute)))
@@ -229,6 +508,7 @@ func TestCleanupSpace(t *testing.T) {
 
 func prepareReconcile(t *testing.T, space *toolchainv1alpha1.Space, initObjs ...runtimeclient.Object) (*spacecleanup.Reconciler, reconcile.Request, *test.FakeClient) {
 	require.NoError(t, os.Setenv("WATCH_NAMESPACE", test.HostOperatorNs))
+	metrics.Reset()
 	s := scheme.Scheme
 	err := apis.AddToScheme(s)
 	require.NoError(t, err)
@@ -40,30 +40,34 @@ type Reconciler struct {
 	Client         runtimeclient.Client
 	Scheme         *runtime.Scheme
 	Namespace      string
-	MemberClusters map[string]cluster.Cluster
+	MemberClusters *cluster.MemberClusters
 }
 
 // SetupWithManager sets up the controller reconciler with the Manager and the given member clusters.
 // Watches SpaceRequests on the member clusters as its primary resources.
-func (r *Reconciler) SetupWithManager(mgr ctrl.Manager, memberClusters map[string]cluster.Cluster) error {
+func (r *Reconciler) SetupWithManager(mgr ctrl.Manager, memberClusters *cluster.MemberClusters) error {
 	// since it's mandatory to add a primary resource when creating a new controller,
 	// we add the SpaceRequest CR even if there should be no reconciles triggered from the host cluster,
 	// only from member clusters (see watches below)
 	// SpaceRequest owns subSpaces so events will be triggered for those from the host cluster.
-	b := ctrl.NewControllerManagedBy(mgr).
+	c, err := ctrl.NewControllerManagedBy(mgr).
 		For(&toolchainv1alpha1.SpaceRequest{}).
 		Watches(&toolchainv1alpha1.Space{},
 			handler.EnqueueRequestsFromMapFunc(MapSubSpaceToSpaceRequest()),
-		)
+		).
+		// the requests rejected by the circuit breaker of a member cluster are retried once it lets the requests through again
+		Build(cluster.RequeueOnOpenCircuit(r))
+	if err != nil {
+		return err
+	}
 
-	// Watch SpaceRequests in all member clusters and all namespaces.
-	for _, memberCluster := range memberClusters {
-		b = b.WatchesRawSource(
+	// Watch SpaceRequests in all member clusters and all namespaces, including the member clusters added later.
+	return memberClusters.AddHandler(func(memberCluster cluster.Cluster) error {
+		return c.Watch(
 			source.Kind[runtimeclient.Object](memberCluster.Cache, &toolchainv1alpha1.SpaceRequest{},
 				&handler.EnqueueRequestForObject{},
 				predicate.GenerationChangedPredicate{}))
-	}
-	return b.Complete(r)
+	})
 }
 
 //+kubebuilder:rbac:groups=toolchain.dev.openshift.com,resources=spacerequests,verbs=get;list;watch;create;update;patch;delete
@@ -78,7 +82,7 @@ func (r *Reconciler) Reconcile(ctx context.Context, request ctrl.Request) (ctrl.
 	// Fetch the SpaceRequest
 	// search on all member clusters
 	spaceRequest := &toolchainv1alpha1.SpaceRequest{}
-	memberClusterWithSpaceRequest, found, err := cluster.LookupMember(ctx, r.MemberClusters, types.NamespacedName{
+	memberClusterWithSpaceRequest, found, err := cluster.LookupMember(ctx, r.MemberClusters.All(), types.NamespacedName{
 		Namespace: request.Namespace,
 		Name:      request.Name,
 	}, spaceRequest)
@@ -559,10 +563,15 @@ func (r *Reconciler) searchExistingSecretRef(
 
 func (r *Reconciler) generateKubeConfig(ctx context.Context, subSpaceTargetCluster cluster.Cluster, namespace, serviceAccountName string) (*api.Config, error) {
 	// create a token request for the admin service account
-	token, err := restclient.CreateTokenRequest(ctx, subSpaceTargetCluster.RESTClient, types.NamespacedName{
-		Namespace: namespace,
-		Name:      serviceAccountName,
-	}, TokenRequestExpirationSeconds)
+	var token string
+	err := subSpaceTargetCluster.CircuitBreaker.Do(func() error {
+		var err error
+		token, err = restclient.CreateTokenRequest(ctx, subSpaceTargetCluster.RESTClient, types.NamespacedName{
+			Namespace: namespace,
+			Name:      serviceAccountName,
+		}, TokenRequestExpirationSeconds)
+		return err
+	})
 	if err != nil {
 		return nil, err
 	}
@@ -669,7 +678,7 @@ func (r *Reconciler) updateStatus(ctx context.Context, spaceRequest *toolchainv1
 
 // getTargetCluster checks if the targetClusterName from the space exists and returns its client.
 func (r *Reconciler) getTargetCluster(targetClusterName string) (cluster.Cluster, error) {
-	targetCluster, found := r.MemberClusters[targetClusterName]
+	targetCluster, found := r.MemberClusters.Get(targetClusterName)
 	if !found {
 		return cluster.Cluster{}, fmt.Errorf("unable to find target cluster with name: %s", targetClusterName)
 	}
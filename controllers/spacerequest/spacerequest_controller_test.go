@@ -1513,7 +1513,7 @@ func newReconciler(t *testing.T, hostCl runtimeclient.Client, memberClusters ...
 		Client:         hostCl,
 		Scheme:         s,
 		Namespace:      commontest.HostOperatorNs,
-		MemberClusters: clusters,
+		MemberClusters: cluster.NewStaticMemberClusters(clusters),
 	}
 }
 
@@ -0,0 +1,60 @@
+package toolchainconfig
+
+import (
+	"strconv"
+	"sync"
+	"time"
+)
+
+// reportedFallbacks are the invalid annotation values which were already logged, so that each of them is logged only once
+var reportedFallbacks sync.Map
+
+// fallback logs that the value of the given annotation is invalid and returns the default value, which is used instead.
+// The invalid values are also reported in the ConfigValid condition of the ToolchainConfig (see ToolchainConfig.Validate).
+func fallback[T any](key, value string, defaultValue T) T {
+	if _, reported := reportedFallbacks.LoadOrStore(key+"="+value, true); !reported {
+		logger.Info("invalid value of the ToolchainConfig annotation, the default value is used instead", "annotation", key, "value", value, "default", defaultValue)
+	}
+	return defaultValue
+}
+
+// durationAnnotationValue returns the duration of the given annotation, or the default value if the annotation is missing or invalid.
+// The duration can be 0 only if zeroAllowed is true.
+func durationAnnotationValue(annotations map[string]string, key string, defaultValue time.Duration, zeroAllowed bool) time.Duration {
+	v, found := annotations[key]
+	if !found {
+		return defaultValue
+	}
+	duration, err := time.ParseDuration(v)
+	if err != nil || duration < 0 || (duration == 0 && !zeroAllowed) {
+		return fallback(key, v, defaultValue)
+	}
+	return duration
+}
+
+// intAnnotationValue returns the integer of the given annotation, or the default value if the annotation is missing or invalid.
+// The integer must be between lower and upper, or at least lower if upper is 0.
+func intAnnotationValue(annotations map[string]string, key string, defaultValue, lower, upper int) int {
+	v, found := annotations[key]
+	if !found {
+		return defaultValue
+	}
+	i, err := strconv.Atoi(v)
+	if err != nil || i < lower || (upper > 0 && i > upper) {
+		return fallback(key, v, defaultValue)
+	}
+	return i
+}
+
+// boolAnnotationValue returns the boolean of the given annotation, or the default value if the annotation is missing or invalid
+func boolAnnotationValue(annotations map[string]string, key string, defaultValue bool) bool {
+	v, found := annotations[key]
+	if !found {
+		return defaultValue
+	}
+	b, err := strconv.ParseBool(v)
+	if err != nil {
+		return fallback(key, v, defaultValue)
+	}
+	return b
+}
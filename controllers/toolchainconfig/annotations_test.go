@@ -0,0 +1,67 @@
+package toolchainconfig
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAnnotationValues(t *testing.T) {
+	const key = "toolchain.dev.openshift.com/test"
+
+	t.Run("duration", func(t *testing.T) {
+		for value, expected := range map[string]time.Duration{
+			"1h":      time.Hour,
+			"0s":      0,
+			"-1h":     time.Minute,
+			"forever": time.Minute,
+		} {
+			t.Run(value, func(t *testing.T) {
+				assert.Equal(t, expected, durationAnnotationValue(map[string]string{key: value}, key, time.Minute, true))
+			})
+		}
+		t.Run("zero not allowed", func(t *testing.T) {
+			assert.Equal(t, time.Minute, durationAnnotationValue(map[string]string{key: "0s"}, key, time.Minute, false))
+		})
+		t.Run("missing", func(t *testing.T) {
+			assert.Equal(t, time.Minute, durationAnnotationValue(map[string]string{}, key, time.Minute, true))
+		})
+	})
+
+	t.Run("int", func(t *testing.T) {
+		for value, expected := range map[string]int{
+			"1":    1,
+			"100":  100,
+			"0":    5,
+			"101":  5,
+			"many": 5,
+		} {
+			t.Run(value, func(t *testing.T) {
+				assert.Equal(t, expected, intAnnotationValue(map[string]string{key: value}, key, 5, 1, 100))
+			})
+		}
+		t.Run("no upper limit", func(t *testing.T) {
+			assert.Equal(t, 1000, intAnnotationValue(map[string]string{key: "1000"}, key, 5, 1, 0))
+		})
+		t.Run("missing", func(t *testing.T) {
+			assert.Equal(t, 5, intAnnotationValue(map[string]string{}, key, 5, 1, 0))
+		})
+	})
+
+	t.Run("bool", func(t *testing.T) {
+		for value, expected := range map[string]bool{
+			"true":  true,
+			"True":  true,
+			"false": false,
+			"yes":   true,
+		} {
+			t.Run(value, func(t *testing.T) {
+				assert.Equal(t, expected, boolAnnotationValue(map[string]string{key: value}, key, true))
+			})
+		}
+		t.Run("missing", func(t *testing.T) {
+			assert.False(t, boolAnnotationValue(map[string]string{}, key, false))
+		})
+	})
+}
@@ -1,6 +1,7 @@
 package toolchainconfig
 
 import (
+	"encoding/json"
 	"fmt"
 	"strings"
 	"time"
@@ -19,7 +20,203 @@ const (
 	// NotificationDeliveryServiceMailgun is the notification delivery service to use during production
 	NotificationDeliveryServiceMailgun = "mailgun"
 
+	// NotificationDeliveryServiceSMTP is the notification delivery service which sends the emails via a plain SMTP server
+	NotificationDeliveryServiceSMTP = "smtp"
+
 	NotificationContextRegistrationURLKey = "RegistrationURL"
+	// NotificationContextLocaleKey is the key of the notification context which contains the locale of the user (eg. `fr`),
+	// used to select the localized variant of the notification template
+	NotificationContextLocaleKey = "Locale"
+
+	// UserSignupLocaleAnnotationKey is the UserSignup annotation which contains the preferred locale of the user (eg. `fr` or `pt-BR`).
+	// It is propagated into the context of the notifications sent to the user.
+	UserSignupLocaleAnnotationKey = toolchainv1alpha1.LabelKeyPrefix + "locale"
+
+	// SpaceQuarantinePeriodAnnotationKey is the ToolchainConfig annotation which configures for how long a Space without any SpaceBinding
+	// is kept (in a quarantine) before it's deleted. The value is a duration (eg: `72h`). The quarantine is disabled by default (`0s`).
+	// +validation=durationAnnotation(true)
+	SpaceQuarantinePeriodAnnotationKey = toolchainv1alpha1.LabelKeyPrefix + "space-quarantine-period"
+
+	// SpaceHibernationEnabledAnnotationKey is the ToolchainConfig annotation which enables (with the value "true") the hibernation
+	// of the home Space of the deactivated users, instead of its deletion
+	// +validation=boolAnnotation
+	SpaceHibernationEnabledAnnotationKey = toolchainv1alpha1.LabelKeyPrefix + "space-hibernation-enabled"
+
+	// SpaceMaxHibernationPeriodAnnotationKey is the ToolchainConfig annotation which configures for how long a Space can stay hibernated
+	// before it's deleted. The value is a duration (eg: `4320h`). The hibernated Spaces are kept as long as their creator exists by default (`0s`).
+	// +validation=durationAnnotation(true)
+	SpaceMaxHibernationPeriodAnnotationKey = toolchainv1alpha1.LabelKeyPrefix + "space-max-hibernation-period"
+
+	// NotificationSMTPHostAnnotationKey is the ToolchainConfig annotation which configures the host of the SMTP server
+	// +validation=anyAnnotation
+	NotificationSMTPHostAnnotationKey = toolchainv1alpha1.LabelKeyPrefix + "notification-smtp-host"
+	// NotificationSMTPPortAnnotationKey is the ToolchainConfig annotation which configures the port of the SMTP server (default: 587)
+	// +validation=intAnnotation(1, 65535)
+	NotificationSMTPPortAnnotationKey = toolchainv1alpha1.LabelKeyPrefix + "notification-smtp-port"
+	// NotificationSMTPTLSModeAnnotationKey is the ToolchainConfig annotation which configures how the connection to the SMTP server
+	// is secured: `starttls` (default), `tls` (implicit TLS) or `none`
+	// +validation=smtpTLSModeAnnotation
+	NotificationSMTPTLSModeAnnotationKey = toolchainv1alpha1.LabelKeyPrefix + "notification-smtp-tls-mode"
+	// NotificationSMTPPoolSizeAnnotationKey is the ToolchainConfig annotation which configures how many idle connections to the
+	// SMTP server are kept open (default: 2)
+	// +validation=intAnnotation(0, 0)
+	NotificationSMTPPoolSizeAnnotationKey = toolchainv1alpha1.LabelKeyPrefix + "notification-smtp-pool-size"
+	// NotificationSMTPUsernameKeyAnnotationKey is the ToolchainConfig annotation which configures the key of the SMTP username
+	// in the notification secret (default: `smtpUsername`)
+	// +validation=notificationSecretKeyAnnotation
+	NotificationSMTPUsernameKeyAnnotationKey = toolchainv1alpha1.LabelKeyPrefix + "notification-smtp-username-key"
+	// NotificationSMTPPasswordKeyAnnotationKey is the ToolchainConfig annotation which configures the key of the SMTP password
+	// in the notification secret (default: `smtpPassword`)
+	// +validation=notificationSecretKeyAnnotation
+	NotificationSMTPPasswordKeyAnnotationKey = toolchainv1alpha1.LabelKeyPrefix + "notification-smtp-password-key"
+
+	// NotificationWebhooksAnnotationKey is the ToolchainConfig annotation which configures the webhooks notifications can be delivered to,
+	// as well as the routing rules which decide which notifications go to which webhooks. The value is a JSON document, see NotificationWebhooksConfig.
+	// +validation=notificationWebhooksAnnotation
+	NotificationWebhooksAnnotationKey = toolchainv1alpha1.LabelKeyPrefix + "notification-webhooks"
+
+	// NotificationDeliveryMaxAttemptsAnnotationKey is the ToolchainConfig annotation which configures how many times the delivery
+	// of a notification is attempted before it's dead-lettered (default: 5)
+	// +validation=intAnnotation(1, 0)
+	NotificationDeliveryMaxAttemptsAnnotationKey = toolchainv1alpha1.LabelKeyPrefix + "notification-delivery-max-attempts"
+	// NotificationDeliveryInitialBackoffAnnotationKey is the ToolchainConfig annotation which configures the delay before the first retry
+	// of a failed notification delivery (default: `30s`). The delay doubles after each failed attempt.
+	// +validation=durationAnnotation(false)
+	NotificationDeliveryInitialBackoffAnnotationKey = toolchainv1alpha1.LabelKeyPrefix + "notification-delivery-initial-backoff"
+	// NotificationDeliveryMaxBackoffAnnotationKey is the ToolchainConfig annotation which configures the maximum delay between two attempts
+	// to deliver a notification (default: `30m`)
+	// +validation=durationAnnotation(false)
+	NotificationDeliveryMaxBackoffAnnotationKey = toolchainv1alpha1.LabelKeyPrefix + "notification-delivery-max-backoff"
+
+	// NotificationDeliveryEventsSigningKeyKeyAnnotationKey is the ToolchainConfig annotation which configures the key of the secret
+	// used to verify the signature of the delivery events (delivered, bounced, etc.) reported by the email provider, in the notification
+	// secret (default: `deliveryEventsSigningKey`). For Mailgun, this is the webhook signing key of the account.
+	// +validation=notificationSecretKeyAnnotation
+	NotificationDeliveryEventsSigningKeyKeyAnnotationKey = toolchainv1alpha1.LabelKeyPrefix + "notification-delivery-events-signing-key-key"
+
+	// NotificationDeduplicationWindowAnnotationKey is the ToolchainConfig annotation which configures for how long a notification is not sent again
+	// to the same recipient, with the same template (or type, or subject if it has no template). The value is a duration (default: `0s`,
+	// ie, the deduplication is disabled, so that the admin and system alerts are never throttled unless opted in).
+	// +validation=durationAnnotation(true)
+	NotificationDeduplicationWindowAnnotationKey = toolchainv1alpha1.LabelKeyPrefix + "notification-deduplication-window"
+	// NotificationDeduplicationTemplateWindowsAnnotationKey is the ToolchainConfig annotation which overrides the deduplication window
+	// of some templates (or types). The value is a JSON object with the template names as keys and the durations as values
+	// (eg. `{"userdeactivating": "24h"}`).
+	// +validation=deduplicationTemplateWindowsAnnotation
+	NotificationDeduplicationTemplateWindowsAnnotationKey = toolchainv1alpha1.LabelKeyPrefix + "notification-deduplication-template-windows"
+	// NotificationRateLimitAnnotationKey is the ToolchainConfig annotation which configures how many notifications can be sent to a same
+	// recipient during the rate limit period (default: 0, ie, the rate limit is disabled). The other notifications are delayed.
+	// +validation=intAnnotation(0, 0)
+	NotificationRateLimitAnnotationKey = toolchainv1alpha1.LabelKeyPrefix + "notification-rate-limit"
+	// NotificationRateLimitPeriodAnnotationKey is the ToolchainConfig annotation which configures the period of the rate limit (default: `1h`)
+	// +validation=durationAnnotation(false)
+	NotificationRateLimitPeriodAnnotationKey = toolchainv1alpha1.LabelKeyPrefix + "notification-rate-limit-period"
+
+	// CapacityAlertSpaceThresholdAnnotationKey is the ToolchainConfig annotation which configures the percentage of the maximum number
+	// of Spaces of a member cluster (as set in its SpaceProvisionerConfig) above which an alert is sent to the admins (default: 90).
+	// `0` disables the alert.
+	// +validation=intAnnotation(0, 100)
+	CapacityAlertSpaceThresholdAnnotationKey = toolchainv1alpha1.LabelKeyPrefix + "capacity-alert-space-threshold"
+	// CapacityAlertPendingUserSignupsThresholdAnnotationKey is the ToolchainConfig annotation which configures the number of UserSignups
+	// pending approval above which an alert is sent to the admins. `0` (default) disables the alert.
+	// +validation=intAnnotation(0, 0)
+	CapacityAlertPendingUserSignupsThresholdAnnotationKey = toolchainv1alpha1.LabelKeyPrefix + "capacity-alert-pending-usersignups-threshold"
+	// CapacityAlertHysteresisAnnotationKey is the ToolchainConfig annotation which configures how far below its threshold (in percents)
+	// the monitored value must go before a capacity alert is resolved, so that the alerts don't flap around the threshold (default: 5)
+	// +validation=intAnnotation(0, 100)
+	CapacityAlertHysteresisAnnotationKey = toolchainv1alpha1.LabelKeyPrefix + "capacity-alert-hysteresis"
+
+	// ToolchainStatusUnreadyAlertDelayAnnotationKey is the ToolchainConfig annotation which configures for how long the ToolchainStatus
+	// must be unready before the admins are notified (default: `10m`)
+	// +validation=durationAnnotation(true)
+	ToolchainStatusUnreadyAlertDelayAnnotationKey = toolchainv1alpha1.LabelKeyPrefix + "toolchainstatus-unready-alert-delay"
+	// ToolchainStatusUnreadyAlertRepeatIntervalAnnotationKey is the ToolchainConfig annotation which configures how often the unready
+	// notification is repeated while the ToolchainStatus is still unready. The value is a duration, `0s` (default) disables the repeats.
+	// +validation=durationAnnotation(true)
+	ToolchainStatusUnreadyAlertRepeatIntervalAnnotationKey = toolchainv1alpha1.LabelKeyPrefix + "toolchainstatus-unready-alert-repeat-interval"
+	// ToolchainStatusUnreadyAlertEscalationDelayAnnotationKey is the ToolchainConfig annotation which configures after how long since the
+	// first unready notification the alert is escalated to the escalation recipients. The value is a duration, `0s` (default) disables
+	// the escalation.
+	// +validation=durationAnnotation(true)
+	ToolchainStatusUnreadyAlertEscalationDelayAnnotationKey = toolchainv1alpha1.LabelKeyPrefix + "toolchainstatus-unready-alert-escalation-delay"
+	// ToolchainStatusUnreadyAlertEscalationEmailAnnotationKey is the ToolchainConfig annotation which configures the recipients (a comma
+	// separated list of email addresses) the unready alert is escalated to. Once escalated, they also receive the repeated and restored
+	// notifications.
+	// +validation=emailsAnnotation
+	ToolchainStatusUnreadyAlertEscalationEmailAnnotationKey = toolchainv1alpha1.LabelKeyPrefix + "toolchainstatus-unready-alert-escalation-email"
+	// ToolchainStatusUnreadyAlertFlapWindowAnnotationKey is the ToolchainConfig annotation which configures for how long the ToolchainStatus
+	// must be ready again before the restored notification is sent. If it becomes unready again in the meantime, this is considered as
+	// the same outage and no new unready notification is sent. The value is a duration, `0s` (default) disables the flap suppression.
+	// +validation=durationAnnotation(true)
+	ToolchainStatusUnreadyAlertFlapWindowAnnotationKey = toolchainv1alpha1.LabelKeyPrefix + "toolchainstatus-unready-alert-flap-window"
+
+	// ToolchainStatusHealthChecksAnnotationKey is the ToolchainConfig annotation which configures additional health checks of the components
+	// the installation depends on (HTTP endpoints, Kubernetes resources, TLS certificates). The value is a JSON document, see HealthChecksConfig.
+	// +validation=healthChecksAnnotation
+	ToolchainStatusHealthChecksAnnotationKey = toolchainv1alpha1.LabelKeyPrefix + "toolchainstatus-health-checks"
+	// ToolchainStatusHistoryMaxTransitionsAnnotationKey is the ToolchainConfig annotation which configures how many readiness transitions
+	// are kept per component in the ToolchainStatus history, from which the availability of the components is computed (default: 100).
+	// The period covered by the history is exported in the `sandbox_toolchainstatus_history_covered_seconds` metric.
+	// +validation=intAnnotation(1, 0)
+	ToolchainStatusHistoryMaxTransitionsAnnotationKey = toolchainv1alpha1.LabelKeyPrefix + "toolchainstatus-history-max-transitions"
+	// ToolchainStatusRevisionCheckAnnotationKey is the ToolchainConfig annotation which configures the source of the latest commits
+	// against which the deployed revisions are checked in production. The value is a JSON document, see RevisionCheckConfig.
+	// +validation=revisionCheckAnnotation
+	ToolchainStatusRevisionCheckAnnotationKey = toolchainv1alpha1.LabelKeyPrefix + "toolchainstatus-revision-check"
+
+	// ToolchainConfigHistoryLimitAnnotationKey is the ToolchainConfig annotation which configures how many revisions of the spec
+	// (and of the annotations with the `toolchain.dev.openshift.com/` prefix) are kept in the history (default: 20), see ConfigRevision
+	// +validation=intAnnotation(1, 0)
+	ToolchainConfigHistoryLimitAnnotationKey = toolchainv1alpha1.LabelKeyPrefix + "toolchainconfig-history-limit"
+	// ToolchainConfigRollbackAnnotationKey is the ToolchainConfig annotation set by the admins to roll the spec and the annotations back
+	// to the revision of the history with the given number. It's removed by the controller once the rollback succeeded or failed
+	// (see the Rollback condition).
+	// +validation=intAnnotation(1, 0)
+	ToolchainConfigRollbackAnnotationKey = toolchainv1alpha1.LabelKeyPrefix + "toolchainconfig-rollback-to"
+
+	// MemberConfigSyncCanariesAnnotationKey is the ToolchainConfig annotation which contains the comma-separated names of the member
+	// clusters to which a new MemberOperatorConfig spec is synced first. The other member clusters receive it once all the canaries
+	// are ready in the ToolchainStatus. Without canaries (default), the spec is synced to all the member clusters at once. The names
+	// which don't match any member cluster are reported as sync errors.
+	// +validation=anyAnnotation
+	MemberConfigSyncCanariesAnnotationKey = toolchainv1alpha1.LabelKeyPrefix + "member-config-sync-canaries"
+	// MemberConfigSyncCanaryDelayAnnotationKey is the ToolchainConfig annotation which configures for how long the canaries must have been
+	// running with the new MemberOperatorConfig spec before it's synced to the other member clusters. The value is a duration (default: `10m`).
+	// +validation=durationAnnotation(true)
+	MemberConfigSyncCanaryDelayAnnotationKey = toolchainv1alpha1.LabelKeyPrefix + "member-config-sync-canary-delay"
+
+	// MemberClientCircuitBreakerFailureThresholdAnnotationKey is the ToolchainConfig annotation which configures after how many consecutive
+	// failed requests to the API server of a member cluster its circuit breaker opens, so that the next requests fail fast instead of
+	// waiting for the client timeout. `0` (default) disables the circuit breakers.
+	// +validation=intAnnotation(0, 0)
+	MemberClientCircuitBreakerFailureThresholdAnnotationKey = toolchainv1alpha1.LabelKeyPrefix + "member-client-circuit-breaker-failure-threshold"
+	// MemberClientCircuitBreakerOpenDurationAnnotationKey is the ToolchainConfig annotation which configures for how long a circuit breaker
+	// stays open before a request is sent again to probe the member cluster. The duration is doubled each time the probe fails (default: `10s`).
+	// +validation=durationAnnotation(false)
+	MemberClientCircuitBreakerOpenDurationAnnotationKey = toolchainv1alpha1.LabelKeyPrefix + "member-client-circuit-breaker-open-duration"
+	// MemberClientCircuitBreakerMaxOpenDurationAnnotationKey is the ToolchainConfig annotation which configures the maximum duration
+	// a circuit breaker stays open between two probes (default: `5m`)
+	// +validation=durationAnnotation(false)
+	MemberClientCircuitBreakerMaxOpenDurationAnnotationKey = toolchainv1alpha1.LabelKeyPrefix + "member-client-circuit-breaker-max-open-duration"
+
+	// FeatureToggleTargetingAnnotationKey is the ToolchainConfig annotation which restricts the feature toggles to some Spaces, depending on
+	// the email domain of their creator, on their tier or on their cluster. The value is a JSON object with the names of the feature toggles
+	// as keys, see FeatureToggleTargeting. The weight of a targeted feature toggle only applies to the Spaces which match its targeting.
+	// +validation=featureToggleTargetingAnnotation
+	FeatureToggleTargetingAnnotationKey = toolchainv1alpha1.LabelKeyPrefix + "feature-toggle-targeting"
+	// FeatureToggleReevaluationEnabledAnnotationKey is the ToolchainConfig annotation which enables (with the value "true") the re-evaluation
+	// of the feature toggles of the existing Spaces when the feature toggles change, instead of only evaluating them when the Spaces are created
+	// +validation=boolAnnotation
+	FeatureToggleReevaluationEnabledAnnotationKey = toolchainv1alpha1.LabelKeyPrefix + "feature-toggle-reevaluation-enabled"
+
+	// SecretSourcesAnnotationKey is the ToolchainConfig annotation which configures where the secrets referenced in the spec are read from,
+	// besides the Secrets of the operator namespace (eg. mounted files or environment variables). The value is a JSON document, see SecretSourcesConfig.
+	// +validation=secretSourcesAnnotation
+	SecretSourcesAnnotationKey = toolchainv1alpha1.LabelKeyPrefix + "secret-sources"
+
+	SMTPTLSModeStartTLS = "starttls"
+	SMTPTLSModeTLS      = "tls"
+	SMTPTLSModeNone     = "none"
 )
 
 // captcha specific configuration for annotating assessments
@@ -31,8 +228,9 @@ const (
 var logger = logf.Log.WithName("toolchainconfig")
 
 type ToolchainConfig struct {
-	cfg     *toolchainv1alpha1.ToolchainConfigSpec
-	secrets map[string]map[string]string
+	cfg         *toolchainv1alpha1.ToolchainConfigSpec
+	annotations map[string]string
+	secrets     map[string]map[string]string
 }
 
 // GetToolchainConfig returns a ToolchainConfig using the cache, or if the cache was not initialized
@@ -76,13 +274,26 @@ func newToolchainConfig(config runtime.Object, secrets map[string]map[string]str
 		logger.Error(fmt.Errorf("cache does not contain toolchainconfig resource type"), "failed to get ToolchainConfig from resource, using default configuration")
 		return ToolchainConfig{cfg: &toolchainv1alpha1.ToolchainConfigSpec{}}
 	}
-	return ToolchainConfig{cfg: &toolchaincfg.Spec, secrets: secrets}
+	return ToolchainConfig{cfg: &toolchaincfg.Spec, annotations: toolchaincfg.Annotations, secrets: resolveSecrets(toolchaincfg.Annotations, secrets)}
 }
 
 func (c *ToolchainConfig) Print() {
 	logger.Info("Toolchain configuration", "config", c.cfg)
 }
 
+// HistoryLimit returns how many revisions of the ToolchainConfig spec are kept in the history
+func (c *ToolchainConfig) HistoryLimit() int {
+	return intAnnotationValue(c.annotations, ToolchainConfigHistoryLimitAnnotationKey, 20, 1, 0)
+}
+
+func (c *ToolchainConfig) MemberConfigSync() MemberConfigSyncConfig {
+	return MemberConfigSyncConfig{annotations: c.annotations}
+}
+
+func (c *ToolchainConfig) MemberClients() MemberClientsConfig {
+	return MemberClientsConfig{annotations: c.annotations}
+}
+
 func (c *ToolchainConfig) Environment() string {
 	return commonconfig.GetString(c.cfg.Host.Environment, "prod")
 }
@@ -99,7 +310,10 @@ func (c *ToolchainConfig) AutomaticApproval() AutoApprovalConfig {
 }
 
 func (c *ToolchainConfig) SpaceConfig() SpaceConfig {
-	return SpaceConfig{c.cfg.Host.SpaceConfig}
+	return SpaceConfig{
+		spaceConfig: c.cfg.Host.SpaceConfig,
+		annotations: c.annotations,
+	}
 }
 
 func (c *ToolchainConfig) Deactivation() DeactivationConfig {
@@ -112,8 +326,9 @@ func (c *ToolchainConfig) Metrics() MetricsConfig {
 
 func (c *ToolchainConfig) Notifications() NotificationsConfig {
 	return NotificationsConfig{
-		c:       c.cfg.Host.Notifications,
-		secrets: c.secrets,
+		c:           c.cfg.Host.Notifications,
+		annotations: c.annotations,
+		secrets:     c.secrets,
 	}
 }
 
@@ -125,11 +340,17 @@ func (c *ToolchainConfig) RegistrationService() RegistrationServiceConfig {
 }
 
 func (c *ToolchainConfig) Tiers() TiersConfig {
-	return TiersConfig{c.cfg.Host.Tiers}
+	return TiersConfig{
+		tiers:       c.cfg.Host.Tiers,
+		annotations: c.annotations,
+	}
 }
 
 func (c *ToolchainConfig) ToolchainStatus() ToolchainStatusConfig {
-	return ToolchainStatusConfig{c.cfg.Host.ToolchainStatus}
+	return ToolchainStatusConfig{
+		t:           c.cfg.Host.ToolchainStatus,
+		annotations: c.annotations,
+	}
 }
 
 func (c *ToolchainConfig) Users() UsersConfig {
@@ -160,6 +381,7 @@ func (a AutoApprovalConfig) Domains() []string {
 
 type SpaceConfig struct {
 	spaceConfig toolchainv1alpha1.SpaceConfig
+	annotations map[string]string
 }
 
 func (s SpaceConfig) SpaceRequestIsEnabled() bool {
@@ -170,6 +392,22 @@ func (s SpaceConfig) SpaceBindingRequestIsEnabled() bool {
 	return commonconfig.GetBool(s.spaceConfig.SpaceBindingRequestEnabled, false)
 }
 
+// QuarantinePeriod returns for how long a Space without any SpaceBinding is kept before it's deleted.
+// The quarantine is disabled by default, ie, such a Space is deleted right away.
+func (s SpaceConfig) QuarantinePeriod() time.Duration {
+	return durationAnnotationValue(s.annotations, SpaceQuarantinePeriodAnnotationKey, 0, true)
+}
+
+// HibernationIsEnabled returns true if the home Space of a deactivated user should be hibernated instead of deleted
+func (s SpaceConfig) HibernationIsEnabled() bool {
+	return boolAnnotationValue(s.annotations, SpaceHibernationEnabledAnnotationKey, false)
+}
+
+// MaxHibernationPeriod returns for how long a Space can stay hibernated before it's deleted, or 0 if there is no limit
+func (s SpaceConfig) MaxHibernationPeriod() time.Duration {
+	return durationAnnotationValue(s.annotations, SpaceMaxHibernationPeriodAnnotationKey, 0, true)
+}
+
 type DeactivationConfig struct {
 	dctv toolchainv1alpha1.DeactivationConfig
 }
@@ -218,8 +456,9 @@ func (gh GitHubSecret) AccessTokenKey() string {
 }
 
 type NotificationsConfig struct {
-	c       toolchainv1alpha1.NotificationsConfig
-	secrets map[string]map[string]string
+	c           toolchainv1alpha1.NotificationsConfig
+	annotations map[string]string
+	secrets     map[string]map[string]string
 }
 
 func (n NotificationsConfig) notificationSecret(secretKey string) string {
@@ -227,6 +466,11 @@ func (n NotificationsConfig) notificationSecret(secretKey string) string {
 	return n.secrets[secret][secretKey]
 }
 
+// SecretHash returns a hash of the content of the notification secret, which changes when the secret is rotated
+func (n NotificationsConfig) SecretHash() string {
+	return secretHash(n.secrets[commonconfig.GetString(n.c.Secret.Ref, "")])
+}
+
 func (n NotificationsConfig) NotificationDeliveryService() string {
 	return commonconfig.GetString(n.c.NotificationDeliveryService, "mailgun")
 }
@@ -268,6 +512,160 @@ func (n NotificationsConfig) MailgunReplyToEmail() string {
 	return n.notificationSecret(key)
 }
 
+// DeliveryMaxAttempts returns how many times the delivery of a notification is attempted before it's dead-lettered
+func (n NotificationsConfig) DeliveryMaxAttempts() int {
+	return intAnnotationValue(n.annotations, NotificationDeliveryMaxAttemptsAnnotationKey, 5, 1, 0)
+}
+
+// DeliveryInitialBackoff returns the delay before the first retry of a failed notification delivery
+func (n NotificationsConfig) DeliveryInitialBackoff() time.Duration {
+	return n.durationAnnotation(NotificationDeliveryInitialBackoffAnnotationKey, 30*time.Second)
+}
+
+// DeliveryMaxBackoff returns the maximum delay between two attempts to deliver a notification
+func (n NotificationsConfig) DeliveryMaxBackoff() time.Duration {
+	return n.durationAnnotation(NotificationDeliveryMaxBackoffAnnotationKey, 30*time.Minute)
+}
+
+// DeliveryEventsSigningKey returns the secret used to verify the signature of the delivery events reported by the email provider.
+// The delivery events are all rejected if it's empty.
+func (n NotificationsConfig) DeliveryEventsSigningKey() string {
+	key, found := n.annotations[NotificationDeliveryEventsSigningKeyKeyAnnotationKey]
+	if !found {
+		key = "deliveryEventsSigningKey"
+	}
+	return n.notificationSecret(key)
+}
+
+// DeduplicationWindow returns for how long the notifications with the given template (or type, or subject) are not sent again to the same recipient
+func (n NotificationsConfig) DeduplicationWindow(template string) time.Duration {
+	// unlike the other durations, `0s` is a valid value here
+	defaultWindow := durationAnnotationValue(n.annotations, NotificationDeduplicationWindowAnnotationKey, 0, true)
+	v, found := n.annotations[NotificationDeduplicationTemplateWindowsAnnotationKey]
+	if !found {
+		return defaultWindow
+	}
+	windows := map[string]string{}
+	if err := json.Unmarshal([]byte(v), &windows); err != nil {
+		return fallback(NotificationDeduplicationTemplateWindowsAnnotationKey, v, defaultWindow)
+	}
+	w, found := windows[template]
+	if !found {
+		return defaultWindow
+	}
+	window, err := time.ParseDuration(w)
+	if err != nil || window < 0 {
+		return fallback(NotificationDeduplicationTemplateWindowsAnnotationKey+"["+template+"]", w, defaultWindow)
+	}
+	return window
+}
+
+// RateLimit returns how many notifications can be sent to a same recipient during the RateLimitPeriod, 0 meaning no limit
+func (n NotificationsConfig) RateLimit() int {
+	return intAnnotationValue(n.annotations, NotificationRateLimitAnnotationKey, 0, 0, 0)
+}
+
+func (n NotificationsConfig) RateLimitPeriod() time.Duration {
+	return n.durationAnnotation(NotificationRateLimitPeriodAnnotationKey, time.Hour)
+}
+
+func (n NotificationsConfig) durationAnnotation(key string, defaultValue time.Duration) time.Duration {
+	return durationAnnotationValue(n.annotations, key, defaultValue, false)
+}
+
+// Webhooks returns the webhooks notifications can be delivered to. The signing secrets are resolved from the notification secret.
+// It returns an error if the configuration of the webhooks is invalid.
+func (n NotificationsConfig) Webhooks() ([]NotificationWebhook, error) {
+	cfg, err := n.webhooksConfig()
+	if err != nil {
+		return nil, err
+	}
+	webhooks := cfg.Webhooks
+	for i := range webhooks {
+		if webhooks[i].SigningSecretKey != "" {
+			webhooks[i].SigningSecret = n.notificationSecret(webhooks[i].SigningSecretKey)
+		}
+	}
+	return webhooks, nil
+}
+
+// Routes returns the rules which decide to which delivery channels the notifications are sent.
+// The notifications which don't match any route are delivered by email. It returns an error if the configuration of the webhooks is invalid.
+func (n NotificationsConfig) Routes() ([]NotificationRoute, error) {
+	cfg, err := n.webhooksConfig()
+	return cfg.Routes, err
+}
+
+func (n NotificationsConfig) webhooksConfig() (NotificationWebhooksConfig, error) {
+	cfg := NotificationWebhooksConfig{}
+	v, found := n.annotations[NotificationWebhooksAnnotationKey]
+	if !found {
+		return cfg, nil
+	}
+	if err := json.Unmarshal([]byte(v), &cfg); err != nil {
+		return NotificationWebhooksConfig{}, fmt.Errorf("invalid notification webhooks configuration in the '%s' annotation: %w", NotificationWebhooksAnnotationKey, err)
+	}
+	return cfg, nil
+}
+
+func (n NotificationsConfig) SMTP() SMTPConfig {
+	return SMTPConfig{n: n}
+}
+
+// SMTPConfig contains the settings of the SMTP notification delivery service.
+// The sender and reply-to emails are shared with the Mailgun delivery service: they are read from the same keys of the notification secret.
+type SMTPConfig struct {
+	n NotificationsConfig
+}
+
+func (s SMTPConfig) Host() string {
+	return s.n.annotations[NotificationSMTPHostAnnotationKey]
+}
+
+func (s SMTPConfig) Port() int {
+	return intAnnotationValue(s.n.annotations, NotificationSMTPPortAnnotationKey, 587, 1, 65535)
+}
+
+func (s SMTPConfig) TLSMode() string {
+	mode, found := s.n.annotations[NotificationSMTPTLSModeAnnotationKey]
+	switch {
+	case !found:
+		return SMTPTLSModeStartTLS
+	case mode == SMTPTLSModeStartTLS, mode == SMTPTLSModeTLS, mode == SMTPTLSModeNone:
+		return mode
+	default:
+		return fallback(NotificationSMTPTLSModeAnnotationKey, mode, SMTPTLSModeStartTLS)
+	}
+}
+
+func (s SMTPConfig) PoolSize() int {
+	return intAnnotationValue(s.n.annotations, NotificationSMTPPoolSizeAnnotationKey, 2, 0, 0)
+}
+
+func (s SMTPConfig) Username() string {
+	key, found := s.n.annotations[NotificationSMTPUsernameKeyAnnotationKey]
+	if !found {
+		key = "smtpUsername"
+	}
+	return s.n.notificationSecret(key)
+}
+
+func (s SMTPConfig) Password() string {
+	key, found := s.n.annotations[NotificationSMTPPasswordKeyAnnotationKey]
+	if !found {
+		key = "smtpPassword"
+	}
+	return s.n.notificationSecret(key)
+}
+
+func (s SMTPConfig) SenderEmail() string {
+	return s.n.MailgunSenderEmail()
+}
+
+func (s SMTPConfig) ReplyToEmail() string {
+	return s.n.MailgunReplyToEmail()
+}
+
 type RegistrationServiceConfig struct {
 	c       toolchainv1alpha1.RegistrationServiceConfig
 	secrets map[string]map[string]string
@@ -301,8 +699,46 @@ func (r RegistrationServiceConfig) RegistrationServiceURL() string {
 	return commonconfig.GetString(r.c.RegistrationServiceURL, "https://registration.crt-placeholder.com")
 }
 
+type MemberConfigSyncConfig struct {
+	annotations map[string]string
+}
+
+// Canaries returns the names of the member clusters to which a new MemberOperatorConfig spec is synced first
+func (m MemberConfigSyncConfig) Canaries() []string {
+	return strings.FieldsFunc(m.annotations[MemberConfigSyncCanariesAnnotationKey], func(c rune) bool {
+		return c == ',' || c == ' '
+	})
+}
+
+// CanaryDelay returns for how long the canaries must have been running with a new MemberOperatorConfig spec before it's synced
+// to the other member clusters
+func (m MemberConfigSyncConfig) CanaryDelay() time.Duration {
+	return durationAnnotationValue(m.annotations, MemberConfigSyncCanaryDelayAnnotationKey, 10*time.Minute, true)
+}
+
+type MemberClientsConfig struct {
+	annotations map[string]string
+}
+
+// CircuitBreakerFailureThreshold returns after how many consecutive failed requests to a member cluster its circuit breaker opens,
+// or 0 if the circuit breakers are disabled (default)
+func (m MemberClientsConfig) CircuitBreakerFailureThreshold() int {
+	return intAnnotationValue(m.annotations, MemberClientCircuitBreakerFailureThresholdAnnotationKey, 0, 0, 0)
+}
+
+// CircuitBreakerOpenDuration returns for how long a circuit breaker stays open before the member cluster is probed for the first time
+func (m MemberClientsConfig) CircuitBreakerOpenDuration() time.Duration {
+	return durationAnnotationValue(m.annotations, MemberClientCircuitBreakerOpenDurationAnnotationKey, 10*time.Second, false)
+}
+
+// CircuitBreakerMaxOpenDuration returns the maximum duration a circuit breaker stays open between two probes of the member cluster
+func (m MemberClientsConfig) CircuitBreakerMaxOpenDuration() time.Duration {
+	return durationAnnotationValue(m.annotations, MemberClientCircuitBreakerMaxOpenDurationAnnotationKey, 5*time.Minute, false)
+}
+
 type TiersConfig struct {
-	tiers toolchainv1alpha1.TiersConfig
+	tiers       toolchainv1alpha1.TiersConfig
+	annotations map[string]string
 }
 
 func (d TiersConfig) DefaultUserTier() string {
@@ -323,15 +759,38 @@ func (d TiersConfig) DurationBeforeChangeTierRequestDeletion() time.Duration {
 }
 
 func (d TiersConfig) FeatureToggles() []FeatureToggle {
+	targeting := map[string]FeatureToggleTargeting{}
+	if v, found := d.annotations[FeatureToggleTargetingAnnotationKey]; found {
+		if err := json.Unmarshal([]byte(v), &targeting); err != nil {
+			logger.Error(err, "invalid feature toggle targeting", "annotation", FeatureToggleTargetingAnnotationKey)
+			targeting = map[string]FeatureToggleTargeting{}
+		}
+	}
 	toggles := make([]FeatureToggle, 0, len(d.tiers.FeatureToggles))
 	for _, t := range d.tiers.FeatureToggles {
-		toggles = append(toggles, FeatureToggle{t})
+		toggles = append(toggles, FeatureToggle{toggle: t, targeting: targeting[t.Name]})
 	}
 	return toggles
 }
 
+// FeatureToggleReevaluationEnabled returns true if the feature toggles of the existing Spaces are re-evaluated when the feature toggles change
+func (d TiersConfig) FeatureToggleReevaluationEnabled() bool {
+	return boolAnnotationValue(d.annotations, FeatureToggleReevaluationEnabledAnnotationKey, false)
+}
+
+// FeatureToggleTargeting restricts a feature toggle to the Spaces which match all its non-empty lists
+type FeatureToggleTargeting struct {
+	// EmailDomains are the domains of the email address of the creator of the Space (eg. `acme.com`)
+	EmailDomains []string `json:"emailDomains,omitempty"`
+	// Tiers are the names of the NSTemplateTiers of the Space
+	Tiers []string `json:"tiers,omitempty"`
+	// Clusters are the names of the member clusters the Space is provisioned to
+	Clusters []string `json:"clusters,omitempty"`
+}
+
 type FeatureToggle struct {
-	toggle toolchainv1alpha1.FeatureToggle
+	toggle    toolchainv1alpha1.FeatureToggle
+	targeting FeatureToggleTargeting
 }
 
 func NewFeatureToggle(t toolchainv1alpha1.FeatureToggle) FeatureToggle {
@@ -340,6 +799,14 @@ func NewFeatureToggle(t toolchainv1alpha1.FeatureToggle) FeatureToggle {
 	}
 }
 
+// NewTargetedFeatureToggle returns a feature toggle which is restricted to the Spaces matching the given targeting
+func NewTargetedFeatureToggle(t toolchainv1alpha1.FeatureToggle, targeting FeatureToggleTargeting) FeatureToggle {
+	return FeatureToggle{
+		toggle:    t,
+		targeting: targeting,
+	}
+}
+
 func (t FeatureToggle) Name() string {
 	return t.toggle.Name
 }
@@ -348,8 +815,13 @@ func (t FeatureToggle) Weight() uint {
 	return commonconfig.GetUint(t.toggle.Weight, 100)
 }
 
+func (t FeatureToggle) Targeting() FeatureToggleTargeting {
+	return t.targeting
+}
+
 type ToolchainStatusConfig struct {
-	t toolchainv1alpha1.ToolchainStatusConfig
+	t           toolchainv1alpha1.ToolchainStatusConfig
+	annotations map[string]string
 }
 
 func (d ToolchainStatusConfig) ToolchainStatusRefreshTime() time.Duration {
@@ -361,6 +833,92 @@ func (d ToolchainStatusConfig) ToolchainStatusRefreshTime() time.Duration {
 	return duration
 }
 
+// CapacityAlertSpaceThreshold returns the percentage of the maximum number of Spaces of a member cluster above which an alert is sent
+func (d ToolchainStatusConfig) CapacityAlertSpaceThreshold() int {
+	return d.percentAnnotation(CapacityAlertSpaceThresholdAnnotationKey, 90)
+}
+
+// CapacityAlertPendingUserSignupsThreshold returns the number of UserSignups pending approval above which an alert is sent
+func (d ToolchainStatusConfig) CapacityAlertPendingUserSignupsThreshold() int {
+	return intAnnotationValue(d.annotations, CapacityAlertPendingUserSignupsThresholdAnnotationKey, 0, 0, 0)
+}
+
+// CapacityAlertHysteresis returns how far below its threshold (in percents) the monitored value must go before a capacity alert is resolved
+func (d ToolchainStatusConfig) CapacityAlertHysteresis() int {
+	return d.percentAnnotation(CapacityAlertHysteresisAnnotationKey, 5)
+}
+
+// UnreadyAlertDelay returns for how long the ToolchainStatus must be unready before the admins are notified
+func (d ToolchainStatusConfig) UnreadyAlertDelay() time.Duration {
+	return d.durationAnnotation(ToolchainStatusUnreadyAlertDelayAnnotationKey, 10*time.Minute)
+}
+
+// UnreadyAlertRepeatInterval returns how often the unready notification is repeated while the ToolchainStatus is still unready,
+// or 0 if it's not repeated
+func (d ToolchainStatusConfig) UnreadyAlertRepeatInterval() time.Duration {
+	return d.durationAnnotation(ToolchainStatusUnreadyAlertRepeatIntervalAnnotationKey, 0)
+}
+
+// UnreadyAlertEscalationDelay returns after how long since the first unready notification the alert is escalated, or 0 if it's not escalated
+func (d ToolchainStatusConfig) UnreadyAlertEscalationDelay() time.Duration {
+	return d.durationAnnotation(ToolchainStatusUnreadyAlertEscalationDelayAnnotationKey, 0)
+}
+
+// UnreadyAlertEscalationEmail returns the recipients the unready alert is escalated to
+func (d ToolchainStatusConfig) UnreadyAlertEscalationEmail() string {
+	return d.annotations[ToolchainStatusUnreadyAlertEscalationEmailAnnotationKey]
+}
+
+// UnreadyAlertFlapWindow returns for how long the ToolchainStatus must be ready again before the restored notification is sent,
+// or 0 if it's sent immediately
+func (d ToolchainStatusConfig) UnreadyAlertFlapWindow() time.Duration {
+	return d.durationAnnotation(ToolchainStatusUnreadyAlertFlapWindowAnnotationKey, 0)
+}
+
+// HistoryMaxTransitions returns how many readiness transitions are kept per component in the ToolchainStatus history
+func (d ToolchainStatusConfig) HistoryMaxTransitions() int {
+	return intAnnotationValue(d.annotations, ToolchainStatusHistoryMaxTransitionsAnnotationKey, 100, 1, 0)
+}
+
+// HealthChecks returns the additional health checks whose results are reported in the ToolchainStatus
+func (d ToolchainStatusConfig) HealthChecks() []HealthCheck {
+	v, found := d.annotations[ToolchainStatusHealthChecksAnnotationKey]
+	if !found {
+		return nil
+	}
+	cfg := HealthChecksConfig{}
+	if err := json.Unmarshal([]byte(v), &cfg); err != nil {
+		logger.Error(err, "invalid health checks configuration", "annotation", ToolchainStatusHealthChecksAnnotationKey)
+		return nil
+	}
+	return cfg.Checks
+}
+
+// RevisionCheck returns the configuration of the revision check, which defaults to the GitHub source.
+// Returns an error if the annotation is not a valid JSON document, rather than falling back to the GitHub source.
+func (d ToolchainStatusConfig) RevisionCheck() (RevisionCheckConfig, error) {
+	cfg := RevisionCheckConfig{}
+	if v, found := d.annotations[ToolchainStatusRevisionCheckAnnotationKey]; found {
+		if err := json.Unmarshal([]byte(v), &cfg); err != nil {
+			return RevisionCheckConfig{}, fmt.Errorf("invalid revision check configuration in the '%s' annotation: %w", ToolchainStatusRevisionCheckAnnotationKey, err)
+		}
+	}
+	if cfg.Source == "" {
+		cfg.Source = RevisionCheckSourceGitHub
+	}
+	return cfg, nil
+}
+
+// durationAnnotation returns the duration of the given annotation, or the default value if it's missing or invalid.
+// Contrary to the notification settings, `0s` is a valid value here, which disables the corresponding feature.
+func (d ToolchainStatusConfig) durationAnnotation(key string, defaultValue time.Duration) time.Duration {
+	return durationAnnotationValue(d.annotations, key, defaultValue, true)
+}
+
+func (d ToolchainStatusConfig) percentAnnotation(key string, defaultValue int) int {
+	return intAnnotationValue(d.annotations, key, defaultValue, 0, 100)
+}
+
 type UsersConfig struct {
 	c toolchainv1alpha1.UsersConfig
 }
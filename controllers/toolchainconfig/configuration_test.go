@@ -8,6 +8,7 @@ import (
 	"time"
 
 	toolchainv1alpha1 "github.com/codeready-toolchain/api/api/v1alpha1"
+	hostconfig "github.com/codeready-toolchain/host-operator/test/config"
 	commonconfig "github.com/codeready-toolchain/toolchain-common/pkg/configuration"
 	"github.com/codeready-toolchain/toolchain-common/pkg/test"
 	testconfig "github.com/codeready-toolchain/toolchain-common/pkg/test/config"
@@ -326,6 +327,85 @@ func TestEnvironment(t *testing.T) {
 	})
 }
 
+func TestHistoryLimit(t *testing.T) {
+	t.Run("default", func(t *testing.T) {
+		cfg := commonconfig.NewToolchainConfigObjWithReset(t)
+		toolchainCfg := newToolchainConfig(cfg, map[string]map[string]string{})
+
+		assert.Equal(t, 20, toolchainCfg.HistoryLimit())
+	})
+	t.Run("non-default", func(t *testing.T) {
+		cfg := commonconfig.NewToolchainConfigObjWithReset(t, hostconfig.Annotation(ToolchainConfigHistoryLimitAnnotationKey, "5"))
+		toolchainCfg := newToolchainConfig(cfg, map[string]map[string]string{})
+
+		assert.Equal(t, 5, toolchainCfg.HistoryLimit())
+	})
+	t.Run("invalid", func(t *testing.T) {
+		cfg := commonconfig.NewToolchainConfigObjWithReset(t, hostconfig.Annotation(ToolchainConfigHistoryLimitAnnotationKey, "-1"))
+		toolchainCfg := newToolchainConfig(cfg, map[string]map[string]string{})
+
+		assert.Equal(t, 20, toolchainCfg.HistoryLimit())
+	})
+}
+
+func TestMemberClients(t *testing.T) {
+	t.Run("default", func(t *testing.T) {
+		cfg := commonconfig.NewToolchainConfigObjWithReset(t)
+		toolchainCfg := newToolchainConfig(cfg, map[string]map[string]string{})
+
+		assert.Zero(t, toolchainCfg.MemberClients().CircuitBreakerFailureThreshold())
+		assert.Equal(t, 10*time.Second, toolchainCfg.MemberClients().CircuitBreakerOpenDuration())
+		assert.Equal(t, 5*time.Minute, toolchainCfg.MemberClients().CircuitBreakerMaxOpenDuration())
+	})
+	t.Run("non-default", func(t *testing.T) {
+		cfg := commonconfig.NewToolchainConfigObjWithReset(t,
+			hostconfig.Annotation(MemberClientCircuitBreakerFailureThresholdAnnotationKey, "5"),
+			hostconfig.Annotation(MemberClientCircuitBreakerOpenDurationAnnotationKey, "1s"),
+			hostconfig.Annotation(MemberClientCircuitBreakerMaxOpenDurationAnnotationKey, "1m"))
+		toolchainCfg := newToolchainConfig(cfg, map[string]map[string]string{})
+
+		assert.Equal(t, 5, toolchainCfg.MemberClients().CircuitBreakerFailureThreshold())
+		assert.Equal(t, time.Second, toolchainCfg.MemberClients().CircuitBreakerOpenDuration())
+		assert.Equal(t, time.Minute, toolchainCfg.MemberClients().CircuitBreakerMaxOpenDuration())
+	})
+	t.Run("invalid", func(t *testing.T) {
+		cfg := commonconfig.NewToolchainConfigObjWithReset(t,
+			hostconfig.Annotation(MemberClientCircuitBreakerFailureThresholdAnnotationKey, "-1"),
+			hostconfig.Annotation(MemberClientCircuitBreakerOpenDurationAnnotationKey, "0s"),
+			hostconfig.Annotation(MemberClientCircuitBreakerMaxOpenDurationAnnotationKey, "later"))
+		toolchainCfg := newToolchainConfig(cfg, map[string]map[string]string{})
+
+		assert.Zero(t, toolchainCfg.MemberClients().CircuitBreakerFailureThreshold())
+		assert.Equal(t, 10*time.Second, toolchainCfg.MemberClients().CircuitBreakerOpenDuration())
+		assert.Equal(t, 5*time.Minute, toolchainCfg.MemberClients().CircuitBreakerMaxOpenDuration())
+	})
+}
+
+func TestMemberConfigSync(t *testing.T) {
+	t.Run("default", func(t *testing.T) {
+		cfg := commonconfig.NewToolchainConfigObjWithReset(t)
+		toolchainCfg := newToolchainConfig(cfg, map[string]map[string]string{})
+
+		assert.Empty(t, toolchainCfg.MemberConfigSync().Canaries())
+		assert.Equal(t, 10*time.Minute, toolchainCfg.MemberConfigSync().CanaryDelay())
+	})
+	t.Run("non-default", func(t *testing.T) {
+		cfg := commonconfig.NewToolchainConfigObjWithReset(t,
+			hostconfig.Annotation(MemberConfigSyncCanariesAnnotationKey, "member1, member2"),
+			hostconfig.Annotation(MemberConfigSyncCanaryDelayAnnotationKey, "0s"))
+		toolchainCfg := newToolchainConfig(cfg, map[string]map[string]string{})
+
+		assert.Equal(t, []string{"member1", "member2"}, toolchainCfg.MemberConfigSync().Canaries())
+		assert.Zero(t, toolchainCfg.MemberConfigSync().CanaryDelay())
+	})
+	t.Run("invalid", func(t *testing.T) {
+		cfg := commonconfig.NewToolchainConfigObjWithReset(t, hostconfig.Annotation(MemberConfigSyncCanaryDelayAnnotationKey, "soon"))
+		toolchainCfg := newToolchainConfig(cfg, map[string]map[string]string{})
+
+		assert.Equal(t, 10*time.Minute, toolchainCfg.MemberConfigSync().CanaryDelay())
+	})
+}
+
 func TestMetrics(t *testing.T) {
 	t.Run("default", func(t *testing.T) {
 		cfg := commonconfig.NewToolchainConfigObjWithReset(t)
@@ -397,6 +477,231 @@ func TestNotifications(t *testing.T) {
 
 		assert.Equal(t, 24*time.Hour, toolchainCfg.Notifications().DurationBeforeNotificationDeletion())
 	})
+
+	t.Run("smtp", func(t *testing.T) {
+		t.Run("default", func(t *testing.T) {
+			cfg := commonconfig.NewToolchainConfigObjWithReset(t)
+			toolchainCfg := newToolchainConfig(cfg, map[string]map[string]string{})
+
+			assert.Empty(t, toolchainCfg.Notifications().SMTP().Host())
+			assert.Equal(t, 587, toolchainCfg.Notifications().SMTP().Port())
+			assert.Equal(t, SMTPTLSModeStartTLS, toolchainCfg.Notifications().SMTP().TLSMode())
+			assert.Equal(t, 2, toolchainCfg.Notifications().SMTP().PoolSize())
+			assert.Empty(t, toolchainCfg.Notifications().SMTP().Username())
+			assert.Empty(t, toolchainCfg.Notifications().SMTP().Password())
+		})
+
+		t.Run("non-default", func(t *testing.T) {
+			cfg := commonconfig.NewToolchainConfigObjWithReset(t,
+				testconfig.Notifications().
+					NotificationDeliveryService("smtp").
+					Secret().
+					Ref("notifications").
+					MailgunReplyToEmail("replyTo").
+					MailgunSenderEmail("sender"),
+				hostconfig.Annotation(NotificationSMTPHostAnnotationKey, "smtp.example.com"),
+				hostconfig.Annotation(NotificationSMTPPortAnnotationKey, "465"),
+				hostconfig.Annotation(NotificationSMTPTLSModeAnnotationKey, "tls"),
+				hostconfig.Annotation(NotificationSMTPPoolSizeAnnotationKey, "5"),
+				hostconfig.Annotation(NotificationSMTPUsernameKeyAnnotationKey, "user"),
+				hostconfig.Annotation(NotificationSMTPPasswordKeyAnnotationKey, "pass"))
+			secrets := map[string]map[string]string{
+				"notifications": {
+					"user":    "smtp-user",
+					"pass":    "smtp-pass",
+					"replyTo": "devsandbox_rulez@redhat.com",
+					"sender":  "devsandbox@redhat.com",
+				},
+			}
+
+			toolchainCfg := newToolchainConfig(cfg, secrets)
+
+			assert.Equal(t, "smtp", toolchainCfg.Notifications().NotificationDeliveryService())
+			assert.Equal(t, "smtp.example.com", toolchainCfg.Notifications().SMTP().Host())
+			assert.Equal(t, 465, toolchainCfg.Notifications().SMTP().Port())
+			assert.Equal(t, SMTPTLSModeTLS, toolchainCfg.Notifications().SMTP().TLSMode())
+			assert.Equal(t, 5, toolchainCfg.Notifications().SMTP().PoolSize())
+			assert.Equal(t, "smtp-user", toolchainCfg.Notifications().SMTP().Username())
+			assert.Equal(t, "smtp-pass", toolchainCfg.Notifications().SMTP().Password())
+			assert.Equal(t, "devsandbox@redhat.com", toolchainCfg.Notifications().SMTP().SenderEmail())
+			assert.Equal(t, "devsandbox_rulez@redhat.com", toolchainCfg.Notifications().SMTP().ReplyToEmail())
+		})
+
+		t.Run("invalid", func(t *testing.T) {
+			cfg := commonconfig.NewToolchainConfigObjWithReset(t,
+				hostconfig.Annotation(NotificationSMTPPortAnnotationKey, "banana"),
+				hostconfig.Annotation(NotificationSMTPTLSModeAnnotationKey, "ssl"),
+				hostconfig.Annotation(NotificationSMTPPoolSizeAnnotationKey, "-1"))
+
+			toolchainCfg := newToolchainConfig(cfg, nil)
+
+			assert.Equal(t, 587, toolchainCfg.Notifications().SMTP().Port())
+			assert.Equal(t, SMTPTLSModeStartTLS, toolchainCfg.Notifications().SMTP().TLSMode())
+			assert.Equal(t, 2, toolchainCfg.Notifications().SMTP().PoolSize())
+		})
+	})
+
+	t.Run("delivery retries", func(t *testing.T) {
+		t.Run("default", func(t *testing.T) {
+			cfg := commonconfig.NewToolchainConfigObjWithReset(t)
+			toolchainCfg := newToolchainConfig(cfg, map[string]map[string]string{})
+
+			assert.Equal(t, 5, toolchainCfg.Notifications().DeliveryMaxAttempts())
+			assert.Equal(t, 30*time.Second, toolchainCfg.Notifications().DeliveryInitialBackoff())
+			assert.Equal(t, 30*time.Minute, toolchainCfg.Notifications().DeliveryMaxBackoff())
+		})
+
+		t.Run("non-default", func(t *testing.T) {
+			cfg := commonconfig.NewToolchainConfigObjWithReset(t,
+				hostconfig.Annotation(NotificationDeliveryMaxAttemptsAnnotationKey, "10"),
+				hostconfig.Annotation(NotificationDeliveryInitialBackoffAnnotationKey, "1m"),
+				hostconfig.Annotation(NotificationDeliveryMaxBackoffAnnotationKey, "2h"))
+			toolchainCfg := newToolchainConfig(cfg, map[string]map[string]string{})
+
+			assert.Equal(t, 10, toolchainCfg.Notifications().DeliveryMaxAttempts())
+			assert.Equal(t, time.Minute, toolchainCfg.Notifications().DeliveryInitialBackoff())
+			assert.Equal(t, 2*time.Hour, toolchainCfg.Notifications().DeliveryMaxBackoff())
+		})
+
+		t.Run("invalid", func(t *testing.T) {
+			cfg := commonconfig.NewToolchainConfigObjWithReset(t,
+				hostconfig.Annotation(NotificationDeliveryMaxAttemptsAnnotationKey, "0"),
+				hostconfig.Annotation(NotificationDeliveryInitialBackoffAnnotationKey, "banana"),
+				hostconfig.Annotation(NotificationDeliveryMaxBackoffAnnotationKey, "-1m"))
+			toolchainCfg := newToolchainConfig(cfg, map[string]map[string]string{})
+
+			assert.Equal(t, 5, toolchainCfg.Notifications().DeliveryMaxAttempts())
+			assert.Equal(t, 30*time.Second, toolchainCfg.Notifications().DeliveryInitialBackoff())
+			assert.Equal(t, 30*time.Minute, toolchainCfg.Notifications().DeliveryMaxBackoff())
+		})
+	})
+
+	t.Run("delivery events signing key", func(t *testing.T) {
+		t.Run("default", func(t *testing.T) {
+			cfg := commonconfig.NewToolchainConfigObjWithReset(t, testconfig.Notifications().Secret().Ref("notifications"))
+			toolchainCfg := newToolchainConfig(cfg, map[string]map[string]string{
+				"notifications": {"deliveryEventsSigningKey": "abc123"},
+			})
+
+			assert.Equal(t, "abc123", toolchainCfg.Notifications().DeliveryEventsSigningKey())
+		})
+
+		t.Run("non-default", func(t *testing.T) {
+			cfg := commonconfig.NewToolchainConfigObjWithReset(t,
+				testconfig.Notifications().Secret().Ref("notifications"),
+				hostconfig.Annotation(NotificationDeliveryEventsSigningKeyKeyAnnotationKey, "mailgunSigningKey"))
+			toolchainCfg := newToolchainConfig(cfg, map[string]map[string]string{
+				"notifications": {"mailgunSigningKey": "def456"},
+			})
+
+			assert.Equal(t, "def456", toolchainCfg.Notifications().DeliveryEventsSigningKey())
+		})
+	})
+
+	t.Run("deduplication and rate limit", func(t *testing.T) {
+		t.Run("default", func(t *testing.T) {
+			cfg := commonconfig.NewToolchainConfigObjWithReset(t)
+			toolchainCfg := newToolchainConfig(cfg, map[string]map[string]string{})
+
+			assert.Equal(t, time.Duration(0), toolchainCfg.Notifications().DeduplicationWindow("userdeactivating"))
+			assert.Equal(t, 0, toolchainCfg.Notifications().RateLimit())
+			assert.Equal(t, time.Hour, toolchainCfg.Notifications().RateLimitPeriod())
+		})
+
+		t.Run("non-default", func(t *testing.T) {
+			cfg := commonconfig.NewToolchainConfigObjWithReset(t,
+				hostconfig.Annotation(NotificationDeduplicationWindowAnnotationKey, "0s"),
+				hostconfig.Annotation(NotificationDeduplicationTemplateWindowsAnnotationKey, `{"userdeactivating": "24h", "userprovisioned": "banana"}`),
+				hostconfig.Annotation(NotificationRateLimitAnnotationKey, "3"),
+				hostconfig.Annotation(NotificationRateLimitPeriodAnnotationKey, "10m"))
+			toolchainCfg := newToolchainConfig(cfg, map[string]map[string]string{})
+
+			assert.Equal(t, 24*time.Hour, toolchainCfg.Notifications().DeduplicationWindow("userdeactivating"))
+			assert.Equal(t, time.Duration(0), toolchainCfg.Notifications().DeduplicationWindow("userprovisioned"))
+			assert.Equal(t, time.Duration(0), toolchainCfg.Notifications().DeduplicationWindow("userdeactivated"))
+			assert.Equal(t, 3, toolchainCfg.Notifications().RateLimit())
+			assert.Equal(t, 10*time.Minute, toolchainCfg.Notifications().RateLimitPeriod())
+		})
+
+		t.Run("invalid", func(t *testing.T) {
+			cfg := commonconfig.NewToolchainConfigObjWithReset(t,
+				hostconfig.Annotation(NotificationDeduplicationWindowAnnotationKey, "-1m"),
+				hostconfig.Annotation(NotificationDeduplicationTemplateWindowsAnnotationKey, `banana`),
+				hostconfig.Annotation(NotificationRateLimitAnnotationKey, "-1"),
+				hostconfig.Annotation(NotificationRateLimitPeriodAnnotationKey, "0s"))
+			toolchainCfg := newToolchainConfig(cfg, map[string]map[string]string{})
+
+			assert.Equal(t, time.Duration(0), toolchainCfg.Notifications().DeduplicationWindow("userdeactivating"))
+			assert.Equal(t, 0, toolchainCfg.Notifications().RateLimit())
+			assert.Equal(t, time.Hour, toolchainCfg.Notifications().RateLimitPeriod())
+		})
+	})
+
+	t.Run("webhooks", func(t *testing.T) {
+		t.Run("default", func(t *testing.T) {
+			cfg := commonconfig.NewToolchainConfigObjWithReset(t)
+			toolchainCfg := newToolchainConfig(cfg, map[string]map[string]string{})
+
+			webhooks, err := toolchainCfg.Notifications().Webhooks()
+			require.NoError(t, err)
+			assert.Empty(t, webhooks)
+			routes, err := toolchainCfg.Notifications().Routes()
+			require.NoError(t, err)
+			assert.Empty(t, routes)
+		})
+
+		t.Run("non-default", func(t *testing.T) {
+			cfg := commonconfig.NewToolchainConfigObjWithReset(t,
+				testconfig.Notifications().Secret().Ref("notifications"),
+				hostconfig.Annotation(NotificationWebhooksAnnotationKey, `{
+					"webhooks": [
+						{"name": "ops", "url": "https://ops.example.com", "signingSecretKey": "opsKey"},
+						{"name": "chat", "url": "https://chat.example.com", "payloadTemplate": "{\"text\": {{json .Subject}}}"}
+					],
+					"routes": [
+						{"notificationTypes": ["toolchainstatus-unready", "toolchainstatus-restored"], "webhooks": ["ops", "chat"], "email": true}
+					]
+				}`))
+			secrets := map[string]map[string]string{
+				"notifications": {
+					"opsKey": "s3cr3t",
+				},
+			}
+
+			toolchainCfg := newToolchainConfig(cfg, secrets)
+
+			webhooks, err := toolchainCfg.Notifications().Webhooks()
+			require.NoError(t, err)
+			require.Len(t, webhooks, 2)
+			assert.Equal(t, "ops", webhooks[0].Name)
+			assert.Equal(t, "https://ops.example.com", webhooks[0].URL)
+			assert.Equal(t, "s3cr3t", webhooks[0].SigningSecret)
+			assert.Equal(t, "chat", webhooks[1].Name)
+			assert.Equal(t, `{"text": {{json .Subject}}}`, webhooks[1].PayloadTemplate)
+			assert.Empty(t, webhooks[1].SigningSecret)
+			routes, err := toolchainCfg.Notifications().Routes()
+			require.NoError(t, err)
+			assert.Equal(t, []NotificationRoute{
+				{
+					NotificationTypes: []string{"toolchainstatus-unready", "toolchainstatus-restored"},
+					Webhooks:          []string{"ops", "chat"},
+					Email:             true,
+				},
+			}, routes)
+		})
+
+		t.Run("invalid", func(t *testing.T) {
+			cfg := commonconfig.NewToolchainConfigObjWithReset(t,
+				hostconfig.Annotation(NotificationWebhooksAnnotationKey, "{not json"))
+
+			toolchainCfg := newToolchainConfig(cfg, nil)
+
+			_, err := toolchainCfg.Notifications().Webhooks()
+			require.ErrorContains(t, err, "invalid notification webhooks configuration in the 'toolchain.dev.openshift.com/notification-webhooks' annotation")
+			_, err = toolchainCfg.Notifications().Routes()
+			require.ErrorContains(t, err, "invalid notification webhooks configuration")
+		})
+	})
 }
 
 func TestRegistrationService(t *testing.T) {
@@ -444,12 +749,18 @@ func TestTiers(t *testing.T) {
 		assert.Equal(t, "base", toolchainCfg.Tiers().DefaultSpaceTier())
 		assert.Equal(t, 24*time.Hour, toolchainCfg.Tiers().DurationBeforeChangeTierRequestDeletion())
 		assert.Empty(t, toolchainCfg.Tiers().FeatureToggles())
+		assert.False(t, toolchainCfg.Tiers().FeatureToggleReevaluationEnabled())
 	})
 	t.Run("invalid", func(t *testing.T) {
-		cfg := commonconfig.NewToolchainConfigObjWithReset(t, testconfig.Tiers().DurationBeforeChangeTierRequestDeletion("rapid"))
+		cfg := commonconfig.NewToolchainConfigObjWithReset(t,
+			testconfig.Tiers().DurationBeforeChangeTierRequestDeletion("rapid").FeatureToggle("feature-1", nil),
+			hostconfig.Annotation(FeatureToggleTargetingAnnotationKey, `{"feature-1": ["acme.com"]}`),
+			hostconfig.Annotation(FeatureToggleReevaluationEnabledAnnotationKey, "sure"))
 		toolchainCfg := newToolchainConfig(cfg, map[string]map[string]string{})
 
 		assert.Equal(t, 24*time.Hour, toolchainCfg.Tiers().DurationBeforeChangeTierRequestDeletion())
+		assert.Empty(t, toolchainCfg.Tiers().FeatureToggles()[0].Targeting())
+		assert.False(t, toolchainCfg.Tiers().FeatureToggleReevaluationEnabled())
 	})
 	t.Run("non-default", func(t *testing.T) {
 		weight10 := uint(10)
@@ -458,7 +769,9 @@ func TestTiers(t *testing.T) {
 			DefaultSpaceTier("advanced").
 			DurationBeforeChangeTierRequestDeletion("48h").
 			FeatureToggle("feature-1", nil). // With default weight
-			FeatureToggle("feature-2", &weight10))
+			FeatureToggle("feature-2", &weight10),
+			hostconfig.Annotation(FeatureToggleTargetingAnnotationKey, `{"feature-2": {"emailDomains": ["acme.com"], "tiers": ["advanced"]}}`),
+			hostconfig.Annotation(FeatureToggleReevaluationEnabledAnnotationKey, "true"))
 		toolchainCfg := newToolchainConfig(cfg, map[string]map[string]string{})
 
 		assert.Equal(t, "deactivate90", toolchainCfg.Tiers().DefaultUserTier())
@@ -469,6 +782,48 @@ func TestTiers(t *testing.T) {
 		assert.Equal(t, uint(100), toolchainCfg.Tiers().FeatureToggles()[0].Weight()) // default weight
 		assert.Equal(t, "feature-2", toolchainCfg.Tiers().FeatureToggles()[1].Name())
 		assert.Equal(t, weight10, toolchainCfg.Tiers().FeatureToggles()[1].Weight())
+		assert.Empty(t, toolchainCfg.Tiers().FeatureToggles()[0].Targeting())
+		assert.Equal(t, FeatureToggleTargeting{EmailDomains: []string{"acme.com"}, Tiers: []string{"advanced"}}, toolchainCfg.Tiers().FeatureToggles()[1].Targeting())
+		assert.True(t, toolchainCfg.Tiers().FeatureToggleReevaluationEnabled())
+	})
+}
+
+func TestSpaceConfig(t *testing.T) {
+	t.Run("default", func(t *testing.T) {
+		cfg := commonconfig.NewToolchainConfigObjWithReset(t)
+		toolchainCfg := newToolchainConfig(cfg, map[string]map[string]string{})
+
+		assert.False(t, toolchainCfg.SpaceConfig().SpaceRequestIsEnabled())
+		assert.False(t, toolchainCfg.SpaceConfig().SpaceBindingRequestIsEnabled())
+		assert.Equal(t, time.Duration(0), toolchainCfg.SpaceConfig().QuarantinePeriod())
+		assert.False(t, toolchainCfg.SpaceConfig().HibernationIsEnabled())
+		assert.Equal(t, time.Duration(0), toolchainCfg.SpaceConfig().MaxHibernationPeriod())
+	})
+	t.Run("invalid", func(t *testing.T) {
+		cfg := commonconfig.NewToolchainConfigObjWithReset(t, hostconfig.Annotation(SpaceQuarantinePeriodAnnotationKey, "forever"))
+		toolchainCfg := newToolchainConfig(cfg, map[string]map[string]string{})
+
+		assert.Equal(t, time.Duration(0), toolchainCfg.SpaceConfig().QuarantinePeriod())
+	})
+	t.Run("non-default", func(t *testing.T) {
+		cfg := commonconfig.NewToolchainConfigObjWithReset(t,
+			testconfig.SpaceConfig().SpaceRequestEnabled(true).SpaceBindingRequestEnabled(true),
+			hostconfig.Annotation(SpaceQuarantinePeriodAnnotationKey, "72h"),
+			hostconfig.Annotation(SpaceHibernationEnabledAnnotationKey, "true"),
+			hostconfig.Annotation(SpaceMaxHibernationPeriodAnnotationKey, "4320h"))
+		toolchainCfg := newToolchainConfig(cfg, map[string]map[string]string{})
+
+		assert.True(t, toolchainCfg.SpaceConfig().SpaceRequestIsEnabled())
+		assert.True(t, toolchainCfg.SpaceConfig().SpaceBindingRequestIsEnabled())
+		assert.Equal(t, 72*time.Hour, toolchainCfg.SpaceConfig().QuarantinePeriod())
+		assert.True(t, toolchainCfg.SpaceConfig().HibernationIsEnabled())
+		assert.Equal(t, 4320*time.Hour, toolchainCfg.SpaceConfig().MaxHibernationPeriod())
+	})
+	t.Run("disabled", func(t *testing.T) {
+		cfg := commonconfig.NewToolchainConfigObjWithReset(t, hostconfig.Annotation(SpaceQuarantinePeriodAnnotationKey, "0s"))
+		toolchainCfg := newToolchainConfig(cfg, map[string]map[string]string{})
+
+		assert.Equal(t, time.Duration(0), toolchainCfg.SpaceConfig().QuarantinePeriod())
 	})
 }
 
@@ -478,19 +833,85 @@ func TestToolchainStatus(t *testing.T) {
 		toolchainCfg := newToolchainConfig(cfg, map[string]map[string]string{})
 
 		assert.Equal(t, 5*time.Second, toolchainCfg.ToolchainStatus().ToolchainStatusRefreshTime())
+		assert.Equal(t, 90, toolchainCfg.ToolchainStatus().CapacityAlertSpaceThreshold())
+		assert.Equal(t, 0, toolchainCfg.ToolchainStatus().CapacityAlertPendingUserSignupsThreshold())
+		assert.Equal(t, 5, toolchainCfg.ToolchainStatus().CapacityAlertHysteresis())
+		assert.Equal(t, 10*time.Minute, toolchainCfg.ToolchainStatus().UnreadyAlertDelay())
+		assert.Zero(t, toolchainCfg.ToolchainStatus().UnreadyAlertRepeatInterval())
+		assert.Zero(t, toolchainCfg.ToolchainStatus().UnreadyAlertEscalationDelay())
+		assert.Empty(t, toolchainCfg.ToolchainStatus().UnreadyAlertEscalationEmail())
+		assert.Zero(t, toolchainCfg.ToolchainStatus().UnreadyAlertFlapWindow())
+		assert.Empty(t, toolchainCfg.ToolchainStatus().HealthChecks())
+		assert.Equal(t, 100, toolchainCfg.ToolchainStatus().HistoryMaxTransitions())
+		revisionCheck, err := toolchainCfg.ToolchainStatus().RevisionCheck()
+		require.NoError(t, err)
+		assert.Equal(t, RevisionCheckConfig{Source: RevisionCheckSourceGitHub}, revisionCheck)
 	})
 	t.Run("non-default", func(t *testing.T) {
-		cfg := commonconfig.NewToolchainConfigObjWithReset(t, testconfig.ToolchainStatus().ToolchainStatusRefreshTime("10s"))
+		cfg := commonconfig.NewToolchainConfigObjWithReset(t, testconfig.ToolchainStatus().ToolchainStatusRefreshTime("10s"),
+			hostconfig.Annotation(CapacityAlertSpaceThresholdAnnotationKey, "80"),
+			hostconfig.Annotation(CapacityAlertPendingUserSignupsThresholdAnnotationKey, "100"),
+			hostconfig.Annotation(CapacityAlertHysteresisAnnotationKey, "10"),
+			hostconfig.Annotation(ToolchainStatusUnreadyAlertDelayAnnotationKey, "0s"),
+			hostconfig.Annotation(ToolchainStatusUnreadyAlertRepeatIntervalAnnotationKey, "4h"),
+			hostconfig.Annotation(ToolchainStatusUnreadyAlertEscalationDelayAnnotationKey, "12h"),
+			hostconfig.Annotation(ToolchainStatusUnreadyAlertEscalationEmailAnnotationKey, "oncall@acme.com, lead@acme.com"),
+			hostconfig.Annotation(ToolchainStatusUnreadyAlertFlapWindowAnnotationKey, "15m"),
+			hostconfig.Annotation(ToolchainStatusHistoryMaxTransitionsAnnotationKey, "500"),
+			hostconfig.Annotation(ToolchainStatusRevisionCheckAnnotationKey, `{"source":"pinned","expectedCommits":{"host-operator":"abc123"}}`),
+			hostconfig.Annotation(ToolchainStatusHealthChecksAnnotationKey, `{"checks":[
+				{"name":"sso","type":"http","url":"https://sso.acme.com/health","expectedStatusCode":204},
+				{"name":"banner","type":"resource","apiVersion":"v1","kind":"ConfigMap","resourceName":"banner"},
+				{"name":"api-cert","type":"tls-expiry","address":"api.acme.com:443","minValidity":"720h","timeout":"3s"}]}`))
 		toolchainCfg := newToolchainConfig(cfg, map[string]map[string]string{})
 
 		assert.Equal(t, 10*time.Second, toolchainCfg.ToolchainStatus().ToolchainStatusRefreshTime())
+		assert.Equal(t, 80, toolchainCfg.ToolchainStatus().CapacityAlertSpaceThreshold())
+		assert.Equal(t, 100, toolchainCfg.ToolchainStatus().CapacityAlertPendingUserSignupsThreshold())
+		assert.Equal(t, 10, toolchainCfg.ToolchainStatus().CapacityAlertHysteresis())
+		assert.Zero(t, toolchainCfg.ToolchainStatus().UnreadyAlertDelay())
+		assert.Equal(t, 4*time.Hour, toolchainCfg.ToolchainStatus().UnreadyAlertRepeatInterval())
+		assert.Equal(t, 12*time.Hour, toolchainCfg.ToolchainStatus().UnreadyAlertEscalationDelay())
+		assert.Equal(t, "oncall@acme.com, lead@acme.com", toolchainCfg.ToolchainStatus().UnreadyAlertEscalationEmail())
+		assert.Equal(t, 15*time.Minute, toolchainCfg.ToolchainStatus().UnreadyAlertFlapWindow())
+		assert.Equal(t, 500, toolchainCfg.ToolchainStatus().HistoryMaxTransitions())
+		checks := toolchainCfg.ToolchainStatus().HealthChecks()
+		require.Len(t, checks, 3)
+		assert.Equal(t, HealthCheck{Name: "sso", Type: HealthCheckTypeHTTP, URL: "https://sso.acme.com/health", ExpectedStatusCode: 204}, checks[0])
+		assert.Equal(t, HealthCheck{Name: "banner", Type: HealthCheckTypeResource, APIVersion: "v1", Kind: "ConfigMap", ResourceName: "banner"}, checks[1])
+		assert.Equal(t, "api.acme.com:443", checks[2].Address)
+		assert.Equal(t, 720*time.Hour, checks[2].MinValidityDuration())
+		assert.Equal(t, 3*time.Second, checks[2].TimeoutDuration())
+		assert.Equal(t, 10*time.Second, checks[0].TimeoutDuration())
+		revisionCheck, err := toolchainCfg.ToolchainStatus().RevisionCheck()
+		require.NoError(t, err)
+		assert.Equal(t, RevisionCheckConfig{Source: RevisionCheckSourcePinned, ExpectedCommits: map[string]string{"host-operator": "abc123"}}, revisionCheck)
 	})
 	t.Run("edge case", func(t *testing.T) {
-		cfg := commonconfig.NewToolchainConfigObjWithReset(t, testconfig.ToolchainStatus().ToolchainStatusRefreshTime("banana"))
+		cfg := commonconfig.NewToolchainConfigObjWithReset(t, testconfig.ToolchainStatus().ToolchainStatusRefreshTime("banana"),
+			hostconfig.Annotation(CapacityAlertSpaceThresholdAnnotationKey, "120"),
+			hostconfig.Annotation(CapacityAlertPendingUserSignupsThresholdAnnotationKey, "-1"),
+			hostconfig.Annotation(CapacityAlertHysteresisAnnotationKey, "banana"),
+			hostconfig.Annotation(ToolchainStatusUnreadyAlertDelayAnnotationKey, "-1m"),
+			hostconfig.Annotation(ToolchainStatusUnreadyAlertRepeatIntervalAnnotationKey, "banana"),
+			hostconfig.Annotation(ToolchainStatusHealthChecksAnnotationKey, "banana"),
+			hostconfig.Annotation(ToolchainStatusHistoryMaxTransitionsAnnotationKey, "0"),
+			hostconfig.Annotation(ToolchainStatusRevisionCheckAnnotationKey, "banana"))
 
 		toolchainCfg := newToolchainConfig(cfg, nil)
 
 		assert.Equal(t, 5*time.Second, toolchainCfg.ToolchainStatus().ToolchainStatusRefreshTime())
+		assert.Equal(t, 90, toolchainCfg.ToolchainStatus().CapacityAlertSpaceThreshold())
+		assert.Equal(t, 0, toolchainCfg.ToolchainStatus().CapacityAlertPendingUserSignupsThreshold())
+		assert.Equal(t, 5, toolchainCfg.ToolchainStatus().CapacityAlertHysteresis())
+		assert.Equal(t, 10*time.Minute, toolchainCfg.ToolchainStatus().UnreadyAlertDelay())
+		assert.Zero(t, toolchainCfg.ToolchainStatus().UnreadyAlertRepeatInterval())
+		assert.Empty(t, toolchainCfg.ToolchainStatus().HealthChecks())
+		assert.Equal(t, 100, toolchainCfg.ToolchainStatus().HistoryMaxTransitions())
+		assert.Equal(t, 7*24*time.Hour, HealthCheck{MinValidity: "banana"}.MinValidityDuration())
+		assert.Equal(t, 10*time.Second, HealthCheck{Timeout: "banana"}.TimeoutDuration())
+		_, err := toolchainCfg.ToolchainStatus().RevisionCheck()
+		require.ErrorContains(t, err, "invalid revision check configuration in the 'toolchain.dev.openshift.com/toolchainstatus-revision-check' annotation")
 	})
 }
 
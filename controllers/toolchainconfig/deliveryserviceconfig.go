@@ -23,3 +23,43 @@ func (d DeliveryServiceFactoryConfig) GetMailgunSenderEmail() string {
 func (d DeliveryServiceFactoryConfig) GetMailgunReplyToEmail() string {
 	return d.Notifications().MailgunReplyToEmail()
 }
+
+func (d DeliveryServiceFactoryConfig) GetSMTPHost() string {
+	return d.Notifications().SMTP().Host()
+}
+
+func (d DeliveryServiceFactoryConfig) GetSMTPPort() int {
+	return d.Notifications().SMTP().Port()
+}
+
+func (d DeliveryServiceFactoryConfig) GetSMTPTLSMode() string {
+	return d.Notifications().SMTP().TLSMode()
+}
+
+func (d DeliveryServiceFactoryConfig) GetSMTPPoolSize() int {
+	return d.Notifications().SMTP().PoolSize()
+}
+
+func (d DeliveryServiceFactoryConfig) GetSMTPUsername() string {
+	return d.Notifications().SMTP().Username()
+}
+
+func (d DeliveryServiceFactoryConfig) GetSMTPPassword() string {
+	return d.Notifications().SMTP().Password()
+}
+
+func (d DeliveryServiceFactoryConfig) GetSMTPSenderEmail() string {
+	return d.Notifications().SMTP().SenderEmail()
+}
+
+func (d DeliveryServiceFactoryConfig) GetSMTPReplyToEmail() string {
+	return d.Notifications().SMTP().ReplyToEmail()
+}
+
+func (d DeliveryServiceFactoryConfig) GetNotificationWebhooks() ([]NotificationWebhook, error) {
+	return d.Notifications().Webhooks()
+}
+
+func (d DeliveryServiceFactoryConfig) GetNotificationRoutes() ([]NotificationRoute, error) {
+	return d.Notifications().Routes()
+}
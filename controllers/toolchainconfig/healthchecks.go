@@ -0,0 +1,65 @@
+package toolchainconfig
+
+import (
+	"time"
+)
+
+const (
+	// HealthCheckTypeHTTP is the type of the health checks which send a GET request to an HTTP endpoint
+	HealthCheckTypeHTTP = "http"
+	// HealthCheckTypeResource is the type of the health checks which verify that a Kubernetes resource exists
+	HealthCheckTypeResource = "resource"
+	// HealthCheckTypeTLSExpiry is the type of the health checks which verify that the certificate served by a TLS endpoint doesn't expire soon
+	HealthCheckTypeTLSExpiry = "tls-expiry"
+)
+
+// HealthChecksConfig is the content of the ToolchainStatusHealthChecksAnnotationKey annotation
+type HealthChecksConfig struct {
+	Checks []HealthCheck `json:"checks,omitempty"`
+}
+
+// HealthCheck is an additional check of a component the installation depends on, whose result is reported in the ToolchainStatus
+type HealthCheck struct {
+	// Name identifies the check in the ToolchainStatus conditions and in the unready notifications
+	Name string `json:"name"`
+	// Type is one of `http`, `resource` or `tls-expiry`
+	Type string `json:"type"`
+
+	// URL is the endpoint of the `http` checks
+	URL string `json:"url,omitempty"`
+	// ExpectedStatusCode is the status code returned by a healthy endpoint in the `http` checks. Any 2xx status code is accepted if not set.
+	ExpectedStatusCode int `json:"expectedStatusCode,omitempty"`
+
+	// APIVersion, Kind, Namespace and ResourceName identify the resource which must exist in the `resource` checks.
+	// The namespace defaults to the namespace of the host operator, and the host operator must be allowed to get the resource.
+	APIVersion   string `json:"apiVersion,omitempty"`
+	Kind         string `json:"kind,omitempty"`
+	Namespace    string `json:"namespace,omitempty"`
+	ResourceName string `json:"resourceName,omitempty"`
+
+	// Address is the `host:port` of the TLS endpoint in the `tls-expiry` checks
+	Address string `json:"address,omitempty"`
+	// MinValidity is the duration during which the certificate must still be valid in the `tls-expiry` checks (default: `168h`)
+	MinValidity string `json:"minValidity,omitempty"`
+	// InsecureSkipVerify disables the verification of the certificate chain in the `tls-expiry` checks, eg. for self-signed certificates
+	InsecureSkipVerify bool `json:"insecureSkipVerify,omitempty"`
+
+	// Timeout is the maximum duration of the check, after which the check fails (default: `10s`)
+	Timeout string `json:"timeout,omitempty"`
+}
+
+// TimeoutDuration returns the Timeout of the check, or its default value if not set or invalid
+func (c HealthCheck) TimeoutDuration() time.Duration {
+	if d, err := time.ParseDuration(c.Timeout); err == nil && d > 0 {
+		return d
+	}
+	return 10 * time.Second
+}
+
+// MinValidityDuration returns the MinValidity of a `tls-expiry` check, or its default value if not set or invalid
+func (c HealthCheck) MinValidityDuration() time.Duration {
+	if d, err := time.ParseDuration(c.MinValidity); err == nil && d > 0 {
+		return d
+	}
+	return 7 * 24 * time.Hour
+}
@@ -0,0 +1,33 @@
+package toolchainconfig
+
+// NotificationWebhooksConfig is the content of the NotificationWebhooksAnnotationKey annotation
+type NotificationWebhooksConfig struct {
+	Webhooks []NotificationWebhook `json:"webhooks,omitempty"`
+	Routes   []NotificationRoute   `json:"routes,omitempty"`
+}
+
+// NotificationWebhook is an HTTP endpoint which receives notifications as JSON payloads
+type NotificationWebhook struct {
+	// Name is the name used to refer to the webhook in the routes
+	Name string `json:"name"`
+	// URL is the endpoint the payloads are POSTed to
+	URL string `json:"url"`
+	// PayloadTemplate is the Go template of the JSON payload. The template receives the Subject, Content, Recipient, Type and Name
+	// of the notification as well as its Context, and can use the `json` function to quote values.
+	// When empty, a payload with all these fields is sent.
+	PayloadTemplate string `json:"payloadTemplate,omitempty"`
+	// SigningSecretKey is the key of the HMAC signing secret in the notification secret. The payloads are not signed if empty.
+	SigningSecretKey string `json:"signingSecretKey,omitempty"`
+	// SigningSecret is the HMAC signing secret, resolved from the notification secret
+	SigningSecret string `json:"-"`
+}
+
+// NotificationRoute sends the notifications of the given types to the given webhooks and, optionally, by email
+type NotificationRoute struct {
+	// NotificationTypes are the values of the notification type label matched by this route
+	NotificationTypes []string `json:"notificationTypes"`
+	// Webhooks are the names of the webhooks the notifications are sent to
+	Webhooks []string `json:"webhooks,omitempty"`
+	// Email is true if the notifications should also be sent by email
+	Email bool `json:"email,omitempty"`
+}
@@ -0,0 +1,27 @@
+package toolchainconfig
+
+const (
+	// RevisionCheckSourceGitHub is the source of the revision check which compares the deployed commits with the latest commits
+	// of the repositories in GitHub. This is the default source.
+	RevisionCheckSourceGitHub = "github"
+	// RevisionCheckSourcePinned is the source of the revision check which compares the deployed commits with the commits pinned in
+	// the ToolchainConfig, eg. for the disconnected installations or the installations built from forks
+	RevisionCheckSourcePinned = "pinned"
+	// RevisionCheckSourceHTTP is the source of the revision check which compares the deployed commits with the commits returned by
+	// an HTTP endpoint
+	RevisionCheckSourceHTTP = "http"
+	// RevisionCheckSourceDisabled disables the revision check
+	RevisionCheckSourceDisabled = "disabled"
+)
+
+// RevisionCheckConfig is the content of the ToolchainStatusRevisionCheckAnnotationKey annotation
+type RevisionCheckConfig struct {
+	// Source is one of `github` (default), `pinned`, `http` or `disabled`
+	Source string `json:"source,omitempty"`
+	// ExpectedCommits contains the expected commit SHA of the components in the `pinned` source, keyed by their repository name
+	// (`host-operator` or `registration-service`). The components with no expected commit are not checked.
+	ExpectedCommits map[string]string `json:"expectedCommits,omitempty"`
+	// URL is the endpoint of the `http` source. A GET request is sent with the `repository` and `branch` query parameters,
+	// and the endpoint must return the latest commit SHA of the repository as plain text.
+	URL string `json:"url,omitempty"`
+}
@@ -0,0 +1,361 @@
+package toolchainconfig
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	toolchainv1alpha1 "github.com/codeready-toolchain/api/api/v1alpha1"
+
+	errs "github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	runtimeclient "sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+const (
+	// ConfigRevisionLabelKey is the label of the ConfigMaps which contain the history of the ToolchainConfig spec, one ConfigMap per revision.
+	// The value is the number of the revision, which is incremented each time the spec changes.
+	ConfigRevisionLabelKey = toolchainv1alpha1.LabelKeyPrefix + "toolchainconfig-revision"
+	// ConfigRevisionHashAnnotationKey is the annotation of the revision ConfigMaps which contains the SHA-256 hash of the spec
+	// (and of the settings annotations, if any)
+	ConfigRevisionHashAnnotationKey = toolchainv1alpha1.LabelKeyPrefix + "toolchainconfig-revision-hash"
+	// ConfigRevisionTimestampAnnotationKey is the annotation of the revision ConfigMaps which contains when the revision was recorded (RFC 3339)
+	ConfigRevisionTimestampAnnotationKey = toolchainv1alpha1.LabelKeyPrefix + "toolchainconfig-revision-timestamp"
+	// ConfigRevisionRollbackOfAnnotationKey is the annotation of the revision ConfigMaps which contains the number of the revision
+	// restored by a rollback, if the revision is the result of a rollback
+	ConfigRevisionRollbackOfAnnotationKey = toolchainv1alpha1.LabelKeyPrefix + "toolchainconfig-revision-rollback-of"
+
+	// ConfigRevisionSpecKey is the key of the revision ConfigMaps which contains the spec, as a JSON document
+	ConfigRevisionSpecKey = "spec.json"
+	// ConfigRevisionAnnotationsKey is the key of the revision ConfigMaps which contains the annotations of the ToolchainConfig
+	// which configure the host operator (ie. with the `toolchain.dev.openshift.com/` prefix), as a JSON document
+	ConfigRevisionAnnotationsKey = "annotations.json"
+	// ConfigRevisionChangedPathsKey is the key of the revision ConfigMaps which contains the paths of the spec changed since the
+	// previous revision, one per line
+	ConfigRevisionChangedPathsKey = "changedPaths"
+
+	configRevisionNamePrefix = "toolchainconfig-revision-"
+)
+
+// ConfigRevision is a revision of the ToolchainConfig spec recorded in the history, along with the annotations which configure the
+// host operator (see settingsAnnotations)
+type ConfigRevision struct {
+	Number       int
+	Hash         string
+	Timestamp    time.Time
+	ChangedPaths []string
+	// RollbackOf is the number of the revision restored by a rollback, or 0 if the revision is not the result of a rollback
+	RollbackOf int
+	Spec       toolchainv1alpha1.ToolchainConfigSpec
+	// Annotations are the settings annotations of the revision, or nil if the revision was recorded before the annotations
+	// were part of the history (in which case the annotations are left unchanged by a rollback to this revision)
+	Annotations map[string]string
+}
+
+// ListConfigRevisions returns the revisions of the ToolchainConfig spec recorded in the given namespace, from the oldest to the latest
+func ListConfigRevisions(ctx context.Context, cl runtimeclient.Client, namespace string) ([]ConfigRevision, error) {
+	cms := &corev1.ConfigMapList{}
+	if err := cl.List(ctx, cms, runtimeclient.InNamespace(namespace), runtimeclient.HasLabels{ConfigRevisionLabelKey}); err != nil {
+		return nil, errs.Wrap(err, "unable to list the ToolchainConfig revisions")
+	}
+	revisions := make([]ConfigRevision, 0, len(cms.Items))
+	for _, cm := range cms.Items {
+		revision, err := toConfigRevision(cm)
+		if err != nil {
+			return nil, errs.Wrapf(err, "invalid ToolchainConfig revision '%s'", cm.Name)
+		}
+		revisions = append(revisions, revision)
+	}
+	sort.Slice(revisions, func(i, j int) bool {
+		return revisions[i].Number < revisions[j].Number
+	})
+	return revisions, nil
+}
+
+// GetActiveConfigRevision returns the latest revision of the ToolchainConfig spec recorded in the given namespace, ie. the revision
+// of the current spec, or nil if no revision was recorded yet
+func GetActiveConfigRevision(ctx context.Context, cl runtimeclient.Client, namespace string) (*ConfigRevision, error) {
+	revisions, err := ListConfigRevisions(ctx, cl, namespace)
+	if err != nil || len(revisions) == 0 {
+		return nil, err
+	}
+	return &revisions[len(revisions)-1], nil
+}
+
+func configRevisionName(number int) string {
+	return fmt.Sprintf("%s%d", configRevisionNamePrefix, number)
+}
+
+func toConfigRevision(cm corev1.ConfigMap) (ConfigRevision, error) {
+	number, err := strconv.Atoi(cm.Labels[ConfigRevisionLabelKey])
+	if err != nil {
+		return ConfigRevision{}, errs.Wrap(err, "invalid revision number")
+	}
+	revision := ConfigRevision{
+		Number: number,
+		Hash:   cm.Annotations[ConfigRevisionHashAnnotationKey],
+	}
+	if revision.Timestamp, err = time.Parse(time.RFC3339, cm.Annotations[ConfigRevisionTimestampAnnotationKey]); err != nil {
+		return ConfigRevision{}, errs.Wrap(err, "invalid timestamp")
+	}
+	if v, found := cm.Annotations[ConfigRevisionRollbackOfAnnotationKey]; found {
+		if revision.RollbackOf, err = strconv.Atoi(v); err != nil {
+			return ConfigRevision{}, errs.Wrap(err, "invalid rolled back revision")
+		}
+	}
+	if paths := cm.Data[ConfigRevisionChangedPathsKey]; paths != "" {
+		revision.ChangedPaths = strings.Split(paths, "\n")
+	}
+	if err := json.Unmarshal([]byte(cm.Data[ConfigRevisionSpecKey]), &revision.Spec); err != nil {
+		return ConfigRevision{}, errs.Wrap(err, "invalid spec")
+	}
+	if v, found := cm.Data[ConfigRevisionAnnotationsKey]; found {
+		revision.Annotations = map[string]string{}
+		if err := json.Unmarshal([]byte(v), &revision.Annotations); err != nil {
+			return ConfigRevision{}, errs.Wrap(err, "invalid annotations")
+		}
+	}
+	return revision, nil
+}
+
+// settingsAnnotations returns the annotations of the ToolchainConfig which configure the host operator, ie. the annotations with the
+// `toolchain.dev.openshift.com/` prefix, except the rollback request
+func settingsAnnotations(toolchainConfig *toolchainv1alpha1.ToolchainConfig) map[string]string {
+	annotations := map[string]string{}
+	for key, value := range toolchainConfig.Annotations {
+		if strings.HasPrefix(key, toolchainv1alpha1.LabelKeyPrefix) && key != ToolchainConfigRollbackAnnotationKey {
+			annotations[key] = value
+		}
+	}
+	return annotations
+}
+
+// revisionHash returns the SHA-256 hash of the given JSON documents of the spec and of the settings annotations. Without annotations,
+// it's the hash of the spec only, as for the revisions recorded before the annotations were part of the history.
+func revisionHash(spec, annotations []byte) string {
+	if string(annotations) == "{}" {
+		return hashOf(spec)
+	}
+	return hashOf(append(append(append([]byte{}, spec...), '\n'), annotations...))
+}
+
+// recordRevision adds the spec of the given ToolchainConfig to the history if it differs from the latest revision, and deletes
+// the oldest revisions beyond the given limit. The revision ConfigMaps are owned by the ToolchainConfig.
+func (r *Reconciler) recordRevision(ctx context.Context, toolchainConfig *toolchainv1alpha1.ToolchainConfig, limit, rollbackOf int) error {
+	revisions, err := ListConfigRevisions(ctx, r.Client, toolchainConfig.Namespace)
+	if err != nil {
+		return err
+	}
+	spec, err := json.Marshal(toolchainConfig.Spec)
+	if err != nil {
+		return errs.Wrap(err, "unable to marshal the ToolchainConfig spec")
+	}
+	annotations, err := json.Marshal(settingsAnnotations(toolchainConfig))
+	if err != nil {
+		return errs.Wrap(err, "unable to marshal the ToolchainConfig annotations")
+	}
+	revision := ConfigRevision{
+		Number:      1,
+		Hash:        revisionHash(spec, annotations),
+		Timestamp:   time.Now(),
+		RollbackOf:  rollbackOf,
+		Annotations: settingsAnnotations(toolchainConfig),
+	}
+	previous := []byte("{}")
+	var previousAnnotations map[string]string
+	if len(revisions) > 0 {
+		latest := revisions[len(revisions)-1]
+		if latest.Hash == revision.Hash {
+			return r.pruneRevisions(ctx, toolchainConfig.Namespace, revisions, limit)
+		}
+		revision.Number = latest.Number + 1
+		if previous, err = json.Marshal(latest.Spec); err != nil {
+			return errs.Wrap(err, "unable to marshal the previous ToolchainConfig spec")
+		}
+		previousAnnotations = latest.Annotations
+	}
+	if revision.ChangedPaths, err = changedPaths(previous, spec); err != nil {
+		return err
+	}
+	revision.ChangedPaths = append(revision.ChangedPaths, changedAnnotations(previousAnnotations, revision.Annotations)...)
+
+	cm := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: toolchainConfig.Namespace,
+			Name:      configRevisionName(revision.Number),
+			Labels: map[string]string{
+				ConfigRevisionLabelKey: strconv.Itoa(revision.Number),
+			},
+			Annotations: map[string]string{
+				ConfigRevisionHashAnnotationKey:      revision.Hash,
+				ConfigRevisionTimestampAnnotationKey: revision.Timestamp.UTC().Format(time.RFC3339),
+			},
+		},
+		Data: map[string]string{
+			ConfigRevisionSpecKey:         string(spec),
+			ConfigRevisionAnnotationsKey:  string(annotations),
+			ConfigRevisionChangedPathsKey: strings.Join(revision.ChangedPaths, "\n"),
+		},
+	}
+	if rollbackOf > 0 {
+		cm.Annotations[ConfigRevisionRollbackOfAnnotationKey] = strconv.Itoa(rollbackOf)
+	}
+	if err := controllerutil.SetControllerReference(toolchainConfig, cm, r.Scheme); err != nil {
+		return errs.Wrap(err, "unable to set the owner of the ToolchainConfig revision")
+	}
+	if err := r.Client.Create(ctx, cm); err != nil {
+		return errs.Wrapf(err, "unable to create the ToolchainConfig revision %d", revision.Number)
+	}
+	log.FromContext(ctx).Info("Recorded the ToolchainConfig revision", "revision", revision.Number, "hash", revision.Hash,
+		"changedPaths", revision.ChangedPaths)
+	return r.pruneRevisions(ctx, toolchainConfig.Namespace, append(revisions, revision), limit)
+}
+
+// pruneRevisions deletes the oldest of the given revisions, so that at most the given number of revisions are kept
+func (r *Reconciler) pruneRevisions(ctx context.Context, namespace string, revisions []ConfigRevision, limit int) error {
+	for i := 0; i < len(revisions)-limit; i++ {
+		cm := &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: namespace,
+				Name:      configRevisionName(revisions[i].Number),
+			},
+		}
+		if err := r.Client.Delete(ctx, cm); err != nil && !errors.IsNotFound(err) {
+			return errs.Wrapf(err, "unable to delete the ToolchainConfig revision %d", revisions[i].Number)
+		}
+	}
+	return nil
+}
+
+// rollback restores the spec and the settings annotations of the ToolchainConfig from the revision of the history with the given number,
+// and removes the rollback annotation. It returns the number of the restored revision. The given ToolchainConfig is left unchanged if the
+// rollback fails.
+func (r *Reconciler) rollback(ctx context.Context, toolchainConfig *toolchainv1alpha1.ToolchainConfig, value string) (int, error) {
+	number, err := strconv.Atoi(value)
+	if err != nil || number <= 0 {
+		return 0, fmt.Errorf("invalid revision '%s'", value)
+	}
+	cm := &corev1.ConfigMap{}
+	if err := r.Client.Get(ctx, types.NamespacedName{Namespace: toolchainConfig.Namespace, Name: configRevisionName(number)}, cm); err != nil {
+		if errors.IsNotFound(err) {
+			return 0, fmt.Errorf("revision %d not found in the history", number)
+		}
+		return 0, errs.Wrapf(err, "unable to get the revision %d", number)
+	}
+	revision, err := toConfigRevision(*cm)
+	if err != nil {
+		return 0, errs.Wrapf(err, "invalid revision %d", number)
+	}
+
+	rolledBack := toolchainConfig.DeepCopy()
+	rolledBack.Spec = revision.Spec
+	if revision.Annotations != nil {
+		for key := range settingsAnnotations(rolledBack) {
+			delete(rolledBack.Annotations, key)
+		}
+		for key, value := range revision.Annotations {
+			if rolledBack.Annotations == nil {
+				rolledBack.Annotations = map[string]string{}
+			}
+			rolledBack.Annotations[key] = value
+		}
+	}
+	delete(rolledBack.Annotations, ToolchainConfigRollbackAnnotationKey)
+	if err := r.Client.Update(ctx, rolledBack); err != nil {
+		return 0, errs.Wrap(err, "unable to update the ToolchainConfig")
+	}
+	*toolchainConfig = *rolledBack
+	log.FromContext(ctx).Info("Rolled back the ToolchainConfig", "revision", number)
+	return number, nil
+}
+
+// dropRollbackRequest removes the rollback annotation from the ToolchainConfig, once the rollback failed
+func (r *Reconciler) dropRollbackRequest(ctx context.Context, toolchainConfig *toolchainv1alpha1.ToolchainConfig) error {
+	delete(toolchainConfig.Annotations, ToolchainConfigRollbackAnnotationKey)
+	return errs.Wrap(r.Client.Update(ctx, toolchainConfig), "unable to remove the rollback request")
+}
+
+// specHash returns the SHA-256 hash of the JSON document of the given spec
+func specHash(spec interface{}) (string, error) {
+	data, err := json.Marshal(spec)
+	if err != nil {
+		return "", errs.Wrap(err, "unable to marshal the spec")
+	}
+	return hashOf(data), nil
+}
+
+func hashOf(data []byte) string {
+	hash := sha256.Sum256(data)
+	return hex.EncodeToString(hash[:])
+}
+
+// changedPaths returns the paths (eg. `spec.host.automaticApproval.enabled`) of the values which differ between the given JSON
+// documents of the spec, in the alphabetical order. The lists are compared as a whole.
+func changedPaths(previous, current []byte) ([]string, error) {
+	var previousValue, currentValue interface{}
+	if err := json.Unmarshal(previous, &previousValue); err != nil {
+		return nil, errs.Wrap(err, "unable to unmarshal the previous spec")
+	}
+	if err := json.Unmarshal(current, &currentValue); err != nil {
+		return nil, errs.Wrap(err, "unable to unmarshal the current spec")
+	}
+	var paths []string
+	diffPaths("spec", previousValue, currentValue, &paths)
+	sort.Strings(paths)
+	return paths, nil
+}
+
+// changedAnnotations returns the paths (eg. `metadata.annotations[toolchain.dev.openshift.com/notification-rate-limit]`) of the annotations
+// which differ between the given settings annotations, in the alphabetical order
+func changedAnnotations(previous, current map[string]string) []string {
+	var paths []string
+	for key, value := range current {
+		if previousValue, found := previous[key]; !found || previousValue != value {
+			paths = append(paths, annotationField(key))
+		}
+	}
+	for key := range previous {
+		if _, found := current[key]; !found {
+			paths = append(paths, annotationField(key))
+		}
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+func diffPaths(path string, previous, current interface{}, paths *[]string) {
+	previousMap, previousIsMap := previous.(map[string]interface{})
+	currentMap, currentIsMap := current.(map[string]interface{})
+	// a missing object is compared as an empty one, so that only the paths of its values are reported
+	if previous == nil && currentIsMap {
+		previousMap, previousIsMap = map[string]interface{}{}, true
+	} else if current == nil && previousIsMap {
+		currentMap, currentIsMap = map[string]interface{}{}, true
+	}
+	if !previousIsMap || !currentIsMap {
+		if !reflect.DeepEqual(previous, current) {
+			*paths = append(*paths, path)
+		}
+		return
+	}
+	for key, value := range currentMap {
+		diffPaths(path+"."+key, previousMap[key], value, paths)
+	}
+	for key, value := range previousMap {
+		if _, found := currentMap[key]; !found {
+			diffPaths(path+"."+key, value, nil, paths)
+		}
+	}
+}
@@ -0,0 +1,277 @@
+package toolchainconfig_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	toolchainv1alpha1 "github.com/codeready-toolchain/api/api/v1alpha1"
+	"github.com/codeready-toolchain/host-operator/controllers/toolchainconfig"
+	. "github.com/codeready-toolchain/host-operator/test"
+	hostconfig "github.com/codeready-toolchain/host-operator/test/config"
+	commonconfig "github.com/codeready-toolchain/toolchain-common/pkg/configuration"
+	"github.com/codeready-toolchain/toolchain-common/pkg/test"
+	testconfig "github.com/codeready-toolchain/toolchain-common/pkg/test/config"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	runtimeclient "sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func TestConfigRevisions(t *testing.T) {
+	t.Run("record the revisions", func(t *testing.T) {
+		// given
+		config := commonconfig.NewToolchainConfigObjWithReset(t, testconfig.AutomaticApproval().Enabled(true))
+		hostCl := test.NewFakeClient(t, config)
+		controller := newController(t, hostCl, NewGetMemberClusters())
+
+		// when
+		_, err := controller.Reconcile(context.TODO(), newRequest())
+
+		// then
+		require.NoError(t, err)
+		revisions := listRevisions(t, hostCl)
+		require.Len(t, revisions, 1)
+		assert.Equal(t, 1, revisions[0].Number)
+		assert.NotEmpty(t, revisions[0].Hash)
+		assert.False(t, revisions[0].Timestamp.IsZero())
+		assert.Equal(t, []string{"spec.host.automaticApproval.enabled"}, revisions[0].ChangedPaths)
+		assert.Zero(t, revisions[0].RollbackOf)
+		assert.True(t, *revisions[0].Spec.Host.AutomaticApproval.Enabled)
+
+		t.Run("same spec and annotations are not recorded again", func(t *testing.T) {
+			// given
+			updateConfig(t, hostCl, func(config *toolchainv1alpha1.ToolchainConfig) {
+				config.Annotations = map[string]string{"description": "sandbox"}
+			})
+
+			// when
+			_, err := controller.Reconcile(context.TODO(), newRequest())
+
+			// then
+			require.NoError(t, err)
+			assert.Len(t, listRevisions(t, hostCl), 1)
+		})
+
+		t.Run("new annotations are recorded with the changed paths", func(t *testing.T) {
+			// given
+			updateConfig(t, hostCl, func(config *toolchainv1alpha1.ToolchainConfig) {
+				config.Annotations[toolchainconfig.SpaceHibernationEnabledAnnotationKey] = "true"
+			})
+
+			// when
+			_, err := controller.Reconcile(context.TODO(), newRequest())
+
+			// then
+			require.NoError(t, err)
+			revisions := listRevisions(t, hostCl)
+			require.Len(t, revisions, 2)
+			assert.Equal(t, 2, revisions[1].Number)
+			assert.NotEqual(t, revisions[0].Hash, revisions[1].Hash)
+			assert.Equal(t, []string{"metadata.annotations[toolchain.dev.openshift.com/space-hibernation-enabled]"}, revisions[1].ChangedPaths)
+			assert.Equal(t, map[string]string{toolchainconfig.SpaceHibernationEnabledAnnotationKey: "true"}, revisions[1].Annotations)
+			assert.Empty(t, revisions[0].Annotations)
+		})
+
+		t.Run("new spec is recorded with the changed paths", func(t *testing.T) {
+			// given
+			updateConfig(t, hostCl, func(config *toolchainv1alpha1.ToolchainConfig) {
+				testconfig.AutomaticApproval().Enabled(false).Domains("acme.com").Apply(config)
+				testconfig.Deactivation().DeactivatingNotificationDays(5).Apply(config)
+			})
+
+			// when
+			_, err := controller.Reconcile(context.TODO(), newRequest())
+
+			// then
+			require.NoError(t, err)
+			revisions := listRevisions(t, hostCl)
+			require.Len(t, revisions, 3)
+			assert.Equal(t, 3, revisions[2].Number)
+			assert.NotEqual(t, revisions[1].Hash, revisions[2].Hash)
+			assert.Equal(t, []string{
+				"spec.host.automaticApproval.domains",
+				"spec.host.automaticApproval.enabled",
+				"spec.host.deactivation.deactivatingNotificationDays",
+			}, revisions[2].ChangedPaths)
+		})
+
+		t.Run("roll back to a revision", func(t *testing.T) {
+			// given
+			updateConfig(t, hostCl, func(config *toolchainv1alpha1.ToolchainConfig) {
+				config.Annotations[toolchainconfig.ToolchainConfigRollbackAnnotationKey] = "1"
+			})
+
+			// when
+			_, err := controller.Reconcile(context.TODO(), newRequest())
+
+			// then
+			require.NoError(t, err)
+			actual, err := toolchainconfig.GetToolchainConfig(hostCl)
+			require.NoError(t, err)
+			assert.True(t, actual.AutomaticApproval().IsEnabled())
+			assert.Empty(t, actual.AutomaticApproval().Domains())
+			assert.Equal(t, 3, actual.Deactivation().DeactivatingNotificationDays())
+			testconfig.AssertThatToolchainConfig(t, test.HostOperatorNs, hostCl).
+				HasConditions(
+					toolchainconfig.ToRollbackComplete(1),
+					toolchainconfig.ToConfigValidity(nil),
+					toolchainconfig.ToSyncComplete(),
+					toolchainconfig.ToRegServiceDeployComplete())
+			config := &toolchainv1alpha1.ToolchainConfig{}
+			require.NoError(t, hostCl.Get(context.TODO(), test.NamespacedName(test.HostOperatorNs, "config"), config))
+			// the settings annotations are rolled back too, the other annotations are kept
+			assert.Equal(t, map[string]string{"description": "sandbox"}, config.Annotations)
+
+			revisions := listRevisions(t, hostCl)
+			require.Len(t, revisions, 4)
+			assert.Equal(t, 4, revisions[3].Number)
+			assert.Equal(t, 1, revisions[3].RollbackOf)
+			assert.Equal(t, revisions[0].Hash, revisions[3].Hash)
+			assert.Equal(t, append(revisions[2].ChangedPaths, revisions[1].ChangedPaths...), revisions[3].ChangedPaths)
+
+			active, err := toolchainconfig.GetActiveConfigRevision(context.TODO(), hostCl, test.HostOperatorNs)
+			require.NoError(t, err)
+			assert.Equal(t, revisions[3], *active)
+		})
+
+		t.Run("oldest revisions are deleted beyond the limit", func(t *testing.T) {
+			// given
+			updateConfig(t, hostCl, func(config *toolchainv1alpha1.ToolchainConfig) {
+				config.Annotations[toolchainconfig.ToolchainConfigHistoryLimitAnnotationKey] = "2"
+				testconfig.Deactivation().DeactivatingNotificationDays(7).Apply(config)
+			})
+
+			// when
+			_, err := controller.Reconcile(context.TODO(), newRequest())
+
+			// then
+			require.NoError(t, err)
+			revisions := listRevisions(t, hostCl)
+			require.Len(t, revisions, 2)
+			assert.Equal(t, 4, revisions[0].Number)
+			assert.Equal(t, 5, revisions[1].Number)
+			assert.Equal(t, []string{
+				"spec.host.deactivation.deactivatingNotificationDays",
+				"metadata.annotations[toolchain.dev.openshift.com/toolchainconfig-history-limit]",
+			}, revisions[1].ChangedPaths)
+		})
+	})
+
+	t.Run("roll back to a revision recorded without the annotations", func(t *testing.T) {
+		// given
+		config := commonconfig.NewToolchainConfigObjWithReset(t, testconfig.AutomaticApproval().Enabled(true))
+		hostCl := test.NewFakeClient(t, config)
+		controller := newController(t, hostCl, NewGetMemberClusters())
+		_, err := controller.Reconcile(context.TODO(), newRequest())
+		require.NoError(t, err)
+		cm := &corev1.ConfigMap{}
+		require.NoError(t, hostCl.Get(context.TODO(), test.NamespacedName(test.HostOperatorNs, "toolchainconfig-revision-1"), cm))
+		delete(cm.Data, toolchainconfig.ConfigRevisionAnnotationsKey)
+		require.NoError(t, hostCl.Update(context.TODO(), cm))
+		updateConfig(t, hostCl, func(config *toolchainv1alpha1.ToolchainConfig) {
+			testconfig.AutomaticApproval().Enabled(false).Apply(config)
+			config.Annotations = map[string]string{
+				toolchainconfig.SpaceHibernationEnabledAnnotationKey: "true",
+				toolchainconfig.ToolchainConfigRollbackAnnotationKey: "1",
+			}
+		})
+
+		// when
+		_, err = controller.Reconcile(context.TODO(), newRequest())
+
+		// then
+		require.NoError(t, err)
+		actual := &toolchainv1alpha1.ToolchainConfig{}
+		require.NoError(t, hostCl.Get(context.TODO(), test.NamespacedName(test.HostOperatorNs, "config"), actual))
+		assert.True(t, *actual.Spec.Host.AutomaticApproval.Enabled)
+		// the annotations are left unchanged
+		assert.Equal(t, map[string]string{toolchainconfig.SpaceHibernationEnabledAnnotationKey: "true"}, actual.Annotations)
+	})
+
+	t.Run("rollback failures", func(t *testing.T) {
+		for name, tc := range map[string]struct {
+			revision    string
+			expectedErr string
+		}{
+			"unknown revision": {
+				revision:    "5",
+				expectedErr: "failed to roll back to revision 5: revision 5 not found in the history",
+			},
+			"invalid revision": {
+				revision:    "latest",
+				expectedErr: "failed to roll back to revision latest: invalid revision 'latest'",
+			},
+		} {
+			t.Run(name, func(t *testing.T) {
+				// given
+				config := commonconfig.NewToolchainConfigObjWithReset(t, testconfig.AutomaticApproval().Enabled(true),
+					hostconfig.Annotation(toolchainconfig.ToolchainConfigRollbackAnnotationKey, tc.revision))
+				hostCl := test.NewFakeClient(t, config)
+				controller := newController(t, hostCl, NewGetMemberClusters())
+
+				// when
+				_, err := controller.Reconcile(context.TODO(), newRequest())
+
+				// then
+				// the rollback request is dropped, and the rest of the reconcile is not blocked
+				require.NoError(t, err)
+				testconfig.AssertThatToolchainConfig(t, test.HostOperatorNs, hostCl).
+					HasConditions(
+						toolchainconfig.ToRollbackFailure(tc.expectedErr),
+						toolchainconfig.ToConfigValidity(nil),
+						toolchainconfig.ToSyncComplete(),
+						toolchainconfig.ToRegServiceDeploying("updated resources: [ServiceAccount: registration-service Role: registration-service "+
+							"RoleBinding: registration-service Deployment: registration-service Route: registration-service Service: registration-service "+
+							"Service: registration-service-metrics Route: api Service: api Service: proxy-metrics-service]"))
+				actual := &toolchainv1alpha1.ToolchainConfig{}
+				require.NoError(t, hostCl.Get(context.TODO(), test.NamespacedName(test.HostOperatorNs, "config"), actual))
+				assert.NotContains(t, actual.Annotations, toolchainconfig.ToolchainConfigRollbackAnnotationKey)
+				assert.True(t, *actual.Spec.Host.AutomaticApproval.Enabled)
+				assert.Len(t, listRevisions(t, hostCl), 1)
+			})
+		}
+
+		t.Run("rollback request cannot be removed", func(t *testing.T) {
+			// given
+			config := commonconfig.NewToolchainConfigObjWithReset(t, testconfig.AutomaticApproval().Enabled(true),
+				hostconfig.Annotation(toolchainconfig.ToolchainConfigRollbackAnnotationKey, "5"))
+			hostCl := test.NewFakeClient(t, config)
+			hostCl.MockUpdate = func(ctx context.Context, obj runtimeclient.Object, opts ...runtimeclient.UpdateOption) error {
+				if _, ok := obj.(*toolchainv1alpha1.ToolchainConfig); ok {
+					return fmt.Errorf("mock error")
+				}
+				return hostCl.Client.Update(ctx, obj, opts...)
+			}
+			controller := newController(t, hostCl, NewGetMemberClusters())
+
+			// when
+			_, err := controller.Reconcile(context.TODO(), newRequest())
+
+			// then
+			require.EqualError(t, err, "unable to remove the rollback request: mock error")
+			assert.Empty(t, listRevisions(t, hostCl))
+		})
+	})
+}
+
+func updateConfig(t *testing.T, hostCl runtimeclient.Client, modify func(config *toolchainv1alpha1.ToolchainConfig)) {
+	config := &toolchainv1alpha1.ToolchainConfig{}
+	require.NoError(t, hostCl.Get(context.TODO(), test.NamespacedName(test.HostOperatorNs, "config"), config))
+	modify(config)
+	require.NoError(t, hostCl.Update(context.TODO(), config))
+}
+
+func listRevisions(t *testing.T, hostCl runtimeclient.Client) []toolchainconfig.ConfigRevision {
+	revisions, err := toolchainconfig.ListConfigRevisions(context.TODO(), hostCl, test.HostOperatorNs)
+	require.NoError(t, err)
+	// the revisions are owned by the ToolchainConfig
+	cms := &corev1.ConfigMapList{}
+	require.NoError(t, hostCl.List(context.TODO(), cms, runtimeclient.HasLabels{toolchainconfig.ConfigRevisionLabelKey}))
+	for _, cm := range cms.Items {
+		require.Len(t, cm.OwnerReferences, 1)
+		assert.Equal(t, "ToolchainConfig", cm.OwnerReferences[0].Kind)
+	}
+	return revisions
+}
@@ -0,0 +1,239 @@
+package toolchainconfig
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	errs "github.com/pkg/errors"
+)
+
+const (
+	// SecretSourceSecret is the source of the secrets which are read from the Secrets of the operator namespace. This is the default source.
+	// The values are cached with the ToolchainConfig and reloaded when the Secret changes.
+	SecretSourceSecret = "secret"
+	// SecretSourceFile is the source of the secrets which are read from the files of a directory, eg. a CSI or projected volume.
+	// Each file contains the value of the key with the same name. The files are read again once the refresh interval elapsed,
+	// so that the values rotated in the volume are picked up.
+	SecretSourceFile = "file"
+	// SecretSourceEnv is the source of the secrets which are read from the environment variables of the operator. The values
+	// can't change while the operator is running, so they are resolved only once.
+	SecretSourceEnv = "env"
+
+	defaultSecretFileRefreshInterval = time.Minute
+)
+
+// SecretSourcesConfig is the content of the SecretSourcesAnnotationKey annotation: the sources of the secrets referenced in the
+// ToolchainConfig spec, keyed by the name of the secret (as in the `ref` fields). The secrets with no source are read from the Secrets.
+type SecretSourcesConfig map[string]SecretSource
+
+// SecretSource is the source of the keys and values of a secret referenced in the ToolchainConfig spec
+type SecretSource struct {
+	// Type is one of `secret` (default), `file` or `env`
+	Type string `json:"type,omitempty"`
+	// Path is the directory of the `file` source
+	Path string `json:"path,omitempty"`
+	// RefreshInterval is how often the files of the `file` source are read again (default: `1m`). `0s` reads them every time the
+	// ToolchainConfig is loaded.
+	RefreshInterval string `json:"refreshInterval,omitempty"`
+	// Keys maps the keys of the secret to the names of the environment variables containing their values, in the `env` source
+	Keys map[string]string `json:"keys,omitempty"`
+}
+
+// SecretProvider provides the keys and values of a secret referenced in the ToolchainConfig spec
+type SecretProvider interface {
+	// Values returns the keys and values of the secret
+	Values() (map[string]string, error)
+}
+
+// NewSecretProvider returns the provider of the given source, for the secrets which are not read from the Secrets
+func NewSecretProvider(source SecretSource) (SecretProvider, error) {
+	switch source.Type {
+	case SecretSourceFile:
+		if source.Path == "" {
+			return nil, errs.New("the path of the file secret source is missing")
+		}
+		refreshInterval := defaultSecretFileRefreshInterval
+		if source.RefreshInterval != "" {
+			d, err := time.ParseDuration(source.RefreshInterval)
+			if err != nil || d < 0 {
+				return nil, errs.Errorf("invalid refresh interval '%s'", source.RefreshInterval)
+			}
+			refreshInterval = d
+		}
+		return &fileSecretProvider{path: source.Path, refreshInterval: refreshInterval}, nil
+	case SecretSourceEnv:
+		return &envSecretProvider{keys: source.Keys}, nil
+	}
+	return nil, errs.Errorf("unsupported secret source '%s'", source.Type)
+}
+
+// fileSecretProvider reads the secret from the files of a directory, and caches the values until the refresh interval elapsed.
+// The kubelet and the CSI drivers swap the content of the mounted volumes atomically, so the files read together are consistent.
+type fileSecretProvider struct {
+	path            string
+	refreshInterval time.Duration
+
+	mu     sync.Mutex
+	readAt time.Time
+	values map[string]string
+	err    error
+}
+
+func (p *fileSecretProvider) Values() (map[string]string, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.readAt.IsZero() || time.Since(p.readAt) >= p.refreshInterval {
+		p.values, p.err = p.read()
+		p.readAt = time.Now()
+		if p.err != nil {
+			logger.Error(p.err, "unable to read the secret files", "path", p.path)
+		}
+	}
+	return p.values, p.err
+}
+
+func (p *fileSecretProvider) read() (map[string]string, error) {
+	entries, err := os.ReadDir(p.path)
+	if err != nil {
+		return nil, errs.Wrapf(err, "unable to list the secret files in '%s'", p.path)
+	}
+	values := map[string]string{}
+	for _, entry := range entries {
+		// skip the internal entries of the projected volumes (`..data` and the timestamped directories)
+		if strings.HasPrefix(entry.Name(), "..") {
+			continue
+		}
+		path := filepath.Join(p.path, entry.Name())
+		// the files of the projected volumes are symlinks, hence the stat instead of the type of the entry
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, errs.Wrapf(err, "unable to read the secret file '%s'", path)
+		}
+		if !info.Mode().IsRegular() {
+			continue
+		}
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return nil, errs.Wrapf(err, "unable to read the secret file '%s'", path)
+		}
+		values[entry.Name()] = string(content)
+	}
+	return values, nil
+}
+
+// envSecretProvider reads the secret from the environment variables once, since they can't change while the operator is running
+type envSecretProvider struct {
+	keys map[string]string
+
+	once   sync.Once
+	values map[string]string
+}
+
+func (p *envSecretProvider) Values() (map[string]string, error) {
+	p.once.Do(func() {
+		p.values = map[string]string{}
+		for key, envVar := range p.keys {
+			if value, found := os.LookupEnv(envVar); found {
+				p.values[key] = value
+			}
+		}
+	})
+	return p.values, nil
+}
+
+type cachedSecretProvider struct {
+	source   SecretSource
+	provider SecretProvider
+}
+
+var (
+	secretProvidersMu sync.Mutex
+	// secretProviders are the providers of the secrets, keyed by the name of the secret. They are kept between the loads of the
+	// ToolchainConfig so that the resolved values are cached, and replaced when the source of the secret changes.
+	secretProviders = map[string]cachedSecretProvider{}
+)
+
+func getSecretProvider(name string, source SecretSource) (SecretProvider, error) {
+	secretProvidersMu.Lock()
+	defer secretProvidersMu.Unlock()
+	if cached, found := secretProviders[name]; found && reflect.DeepEqual(cached.source, source) {
+		return cached.provider, nil
+	}
+	provider, err := NewSecretProvider(source)
+	if err != nil {
+		return nil, err
+	}
+	secretProviders[name] = cachedSecretProvider{source: source, provider: provider}
+	return provider, nil
+}
+
+// resetSecretProviders drops the cached providers
+func resetSecretProviders() {
+	secretProvidersMu.Lock()
+	defer secretProvidersMu.Unlock()
+	secretProviders = map[string]cachedSecretProvider{}
+}
+
+// resolveSecrets returns the given secrets (read from the Secrets) completed with the secrets read from the other sources
+// configured in the annotations. The secrets which can't be resolved are missing, which is reported by the validation of the config.
+func resolveSecrets(annotations map[string]string, secrets map[string]map[string]string) map[string]map[string]string {
+	v, found := annotations[SecretSourcesAnnotationKey]
+	if !found || secrets == nil {
+		// the config was loaded without its secrets
+		return secrets
+	}
+	sources := SecretSourcesConfig{}
+	if err := json.Unmarshal([]byte(v), &sources); err != nil {
+		logger.Error(err, "invalid secret sources configuration", "annotation", SecretSourcesAnnotationKey)
+		return secrets
+	}
+	resolved := make(map[string]map[string]string, len(secrets)+len(sources))
+	for name, values := range secrets {
+		resolved[name] = values
+	}
+	for name, source := range sources {
+		if source.Type == "" || source.Type == SecretSourceSecret {
+			continue
+		}
+		delete(resolved, name)
+		provider, err := getSecretProvider(name, source)
+		if err != nil {
+			logger.Error(err, "invalid secret source", "secret", name)
+			continue
+		}
+		values, err := provider.Values()
+		if err != nil {
+			// already logged by the provider when reading the source
+			continue
+		}
+		resolved[name] = values
+	}
+	return resolved
+}
+
+// secretHash returns a hash of the keys and values of the given secret, or an empty string if the secret is empty.
+// The components holding clients created with the values of a secret compare it to detect the rotation of the secret.
+func secretHash(values map[string]string) string {
+	if len(values) == 0 {
+		return ""
+	}
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	var content strings.Builder
+	for _, key := range keys {
+		content.WriteString(key)
+		content.WriteByte(0)
+		content.WriteString(values[key])
+		content.WriteByte(0)
+	}
+	return hashOf([]byte(content.String()))
+}
@@ -0,0 +1,157 @@
+package toolchainconfig
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	toolchainv1alpha1 "github.com/codeready-toolchain/api/api/v1alpha1"
+	hostconfig "github.com/codeready-toolchain/host-operator/test/config"
+	commonconfig "github.com/codeready-toolchain/toolchain-common/pkg/configuration"
+	testconfig "github.com/codeready-toolchain/toolchain-common/pkg/test/config"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSecretSources(t *testing.T) {
+	secrets := func() map[string]map[string]string {
+		return map[string]map[string]string{
+			"notification-secret": {"mailgunAPIKey": "from-secret"},
+			"github":              {"accessToken": "gh-token"},
+		}
+	}
+	configWithSources := func(t *testing.T, sources SecretSourcesConfig) *toolchainv1alpha1.ToolchainConfig {
+		t.Cleanup(resetSecretProviders)
+		value, err := json.Marshal(sources)
+		require.NoError(t, err)
+		return commonconfig.NewToolchainConfigObjWithReset(t,
+			testconfig.Notifications().Secret().Ref("notification-secret").MailgunAPIKey("mailgunAPIKey").MailgunDomain("mailgunDomain"),
+			testconfig.ToolchainStatus().GitHubSecretRef("github").GitHubSecretAccessTokenKey("accessToken"),
+			hostconfig.Annotation(SecretSourcesAnnotationKey, string(value)))
+	}
+
+	t.Run("file source", func(t *testing.T) {
+		// given
+		dir := t.TempDir()
+		writeSecretFile(t, dir, "mailgunAPIKey", "abc123")
+		// internal entry of the projected volumes, which is ignored
+		require.NoError(t, os.Mkdir(filepath.Join(dir, "..data"), 0o755))
+		writeSecretFile(t, filepath.Join(dir, "..data"), "mailgunDomain", "acme.com")
+		cfg := configWithSources(t, SecretSourcesConfig{"notification-secret": {Type: SecretSourceFile, Path: dir}})
+
+		// when
+		toolchainCfg := newToolchainConfig(cfg, secrets())
+
+		// then
+		assert.Equal(t, "abc123", toolchainCfg.Notifications().MailgunAPIKey())
+		assert.Empty(t, toolchainCfg.Notifications().MailgunDomain())
+		assert.Equal(t, "gh-token", toolchainCfg.GitHubSecret().AccessTokenKey())
+		hash := toolchainCfg.Notifications().SecretHash()
+		assert.NotEmpty(t, hash)
+
+		t.Run("cached until the refresh interval elapsed", func(t *testing.T) {
+			// given
+			writeSecretFile(t, dir, "mailgunAPIKey", "def456")
+
+			// when
+			toolchainCfg := newToolchainConfig(cfg, secrets())
+
+			// then
+			assert.Equal(t, "abc123", toolchainCfg.Notifications().MailgunAPIKey())
+			assert.Equal(t, hash, toolchainCfg.Notifications().SecretHash())
+		})
+	})
+
+	t.Run("file source rotated", func(t *testing.T) {
+		// given
+		dir := t.TempDir()
+		writeSecretFile(t, dir, "mailgunAPIKey", "abc123")
+		cfg := configWithSources(t, SecretSourcesConfig{"notification-secret": {Type: SecretSourceFile, Path: dir, RefreshInterval: "0s"}})
+		toolchainCfg := newToolchainConfig(cfg, secrets())
+		require.Equal(t, "abc123", toolchainCfg.Notifications().MailgunAPIKey())
+		hash := toolchainCfg.Notifications().SecretHash()
+		writeSecretFile(t, dir, "mailgunAPIKey", "def456")
+
+		// when
+		toolchainCfg = newToolchainConfig(cfg, secrets())
+
+		// then
+		assert.Equal(t, "def456", toolchainCfg.Notifications().MailgunAPIKey())
+		assert.NotEqual(t, hash, toolchainCfg.Notifications().SecretHash())
+	})
+
+	t.Run("missing directory", func(t *testing.T) {
+		// given
+		cfg := configWithSources(t, SecretSourcesConfig{"notification-secret": {Type: SecretSourceFile, Path: filepath.Join(t.TempDir(), "missing")}})
+
+		// when
+		toolchainCfg := newToolchainConfig(cfg, secrets())
+
+		// then
+		assert.Empty(t, toolchainCfg.Notifications().MailgunAPIKey())
+		assert.Equal(t, []ValidationError{
+			{Field: "spec.host.notifications.secret.ref", Message: "secret 'notification-secret' not found"},
+		}, toolchainCfg.Validate())
+	})
+
+	t.Run("env source", func(t *testing.T) {
+		// given
+		t.Setenv("MAILGUN_API_KEY", "abc123")
+		cfg := configWithSources(t, SecretSourcesConfig{"notification-secret": {Type: SecretSourceEnv, Keys: map[string]string{
+			"mailgunAPIKey": "MAILGUN_API_KEY",
+			"mailgunDomain": "MAILGUN_DOMAIN",
+		}}})
+
+		// when
+		toolchainCfg := newToolchainConfig(cfg, secrets())
+
+		// then
+		assert.Equal(t, "abc123", toolchainCfg.Notifications().MailgunAPIKey())
+		assert.Empty(t, toolchainCfg.Notifications().MailgunDomain())
+
+		t.Run("resolved only once", func(t *testing.T) {
+			// given
+			t.Setenv("MAILGUN_API_KEY", "def456")
+
+			// when
+			toolchainCfg := newToolchainConfig(cfg, secrets())
+
+			// then
+			assert.Equal(t, "abc123", toolchainCfg.Notifications().MailgunAPIKey())
+		})
+	})
+
+	t.Run("secret source", func(t *testing.T) {
+		// given
+		cfg := configWithSources(t, SecretSourcesConfig{"notification-secret": {Type: SecretSourceSecret}})
+
+		// when
+		toolchainCfg := newToolchainConfig(cfg, secrets())
+
+		// then
+		assert.Equal(t, "from-secret", toolchainCfg.Notifications().MailgunAPIKey())
+	})
+
+	t.Run("loaded without the secrets", func(t *testing.T) {
+		// given
+		cfg := configWithSources(t, SecretSourcesConfig{"notification-secret": {Type: SecretSourceEnv, Keys: map[string]string{"mailgunAPIKey": "MAILGUN_API_KEY"}}})
+
+		// when
+		toolchainCfg := newToolchainConfig(cfg, nil)
+
+		// then
+		assert.Nil(t, toolchainCfg.secrets)
+	})
+}
+
+func TestSecretHash(t *testing.T) {
+	assert.Empty(t, secretHash(nil))
+	assert.Equal(t, secretHash(map[string]string{"a": "1", "b": "2"}), secretHash(map[string]string{"b": "2", "a": "1"}))
+	assert.NotEqual(t, secretHash(map[string]string{"a": "1", "b": "2"}), secretHash(map[string]string{"a": "12"}))
+}
+
+func writeSecretFile(t *testing.T, dir, key, value string) {
+	require.NoError(t, os.WriteFile(filepath.Join(dir, key), []byte(value), 0o600))
+}
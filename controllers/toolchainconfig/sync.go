@@ -2,45 +2,89 @@ package toolchainconfig
 
 import (
 	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
 
 	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
+	runtimeclient "sigs.k8s.io/controller-runtime/pkg/client"
 
 	toolchainv1alpha1 "github.com/codeready-toolchain/api/api/v1alpha1"
 	"github.com/codeready-toolchain/toolchain-common/pkg/cluster"
+	"github.com/codeready-toolchain/toolchain-common/pkg/condition"
 
 	"github.com/go-logr/logr"
+	errs "github.com/pkg/errors"
+)
+
+const (
+	// MemberConfigSpecHashAnnotationKey is the annotation of the MemberOperatorConfigs which contains the hash of the spec synced
+	// by the host operator, to detect the changes made directly on the member clusters (drifts)
+	MemberConfigSpecHashAnnotationKey = toolchainv1alpha1.LabelKeyPrefix + "member-config-spec-hash"
+	// MemberConfigSyncedAtAnnotationKey is the annotation of the MemberOperatorConfigs which contains when their current spec was
+	// synced by the host operator (RFC 3339)
+	MemberConfigSyncedAtAnnotationKey = toolchainv1alpha1.LabelKeyPrefix + "member-config-synced-at"
 )
 
 type Synchronizer struct {
+	hostClient     runtimeclient.Client
 	getMembersFunc cluster.GetMemberClustersFunc
 	logger         logr.Logger
 }
 
-func NewSynchronizer(logger logr.Logger, getMembersFunc cluster.GetMemberClustersFunc) Synchronizer {
+func NewSynchronizer(logger logr.Logger, hostClient runtimeclient.Client, getMembersFunc cluster.GetMemberClustersFunc) Synchronizer {
 	return Synchronizer{
+		hostClient:     hostClient,
 		getMembersFunc: getMembersFunc,
 		logger:         logger,
 	}
 }
 
-// SyncMemberConfigs retrieves member operator configurations and syncs the appropriate configuration to each member cluster
-// returns a map of any errors encountered indexed by cluster name
-func (s *Synchronizer) SyncMemberConfigs(ctx context.Context, sourceConfig *toolchainv1alpha1.ToolchainConfig) map[string]string {
+// SyncResult is the result of the synchronization of the MemberOperatorConfigs
+type SyncResult struct {
+	// Errors contains the sync errors, indexed by the name of the member cluster
+	Errors map[string]string
+	// Pending contains the names of the member clusters which wait for the canaries to be healthy before receiving their new spec
+	Pending []string
+	// PendingReason explains why the canaries are not considered as healthy
+	PendingReason string
+	// Drifts contains the paths of the spec which were modified directly on the member clusters, and which have been reverted,
+	// indexed by the name of the member cluster
+	Drifts map[string][]string
+	// LatestSync is when a new spec was synced to a member cluster for the last time
+	LatestSync time.Time
+}
+
+// memberConfigState is the state of a MemberOperatorConfig after its synchronization
+type memberConfigState struct {
+	syncedAt time.Time
+	drift    []string
+}
+
+// SyncMemberConfigs retrieves member operator configurations and syncs the appropriate configuration to each member cluster.
+// When canaries are configured (see MemberConfigSyncConfig), a new spec is synced to the canaries first, and to the other member
+// clusters once the canaries have been ready for long enough. The changes made directly on the member clusters are reverted.
+func (s *Synchronizer) SyncMemberConfigs(ctx context.Context, sourceConfig *toolchainv1alpha1.ToolchainConfig) SyncResult {
 	toolchainConfig := sourceConfig.DeepCopy()
 
 	// get configs for member toolchainclusters
 	memberToolchainClusters := s.getMembersFunc()
-	syncErrors := make(map[string]string, len(memberToolchainClusters))
+	result := SyncResult{
+		Errors: make(map[string]string, len(memberToolchainClusters)),
+		Drifts: map[string][]string{},
+	}
 
 	if len(memberToolchainClusters) == 0 {
 		s.logger.Info("No toolchainclusters were found, skipping MemberOperatorConfig syncing")
-		return syncErrors
+		return result
 	}
 
 	membersWithSpecificConfig := toolchainConfig.Spec.Members.SpecificPerMemberCluster
-
+	memberConfigSpecs := make(map[string]toolchainv1alpha1.MemberOperatorConfigSpec, len(memberToolchainClusters))
 	for _, toolchainCluster := range memberToolchainClusters {
 		memberConfigSpec := toolchainConfig.Spec.Members.Default
 
@@ -49,23 +93,130 @@ func (s *Synchronizer) SyncMemberConfigs(ctx context.Context, sourceConfig *tool
 			memberConfigSpec = c
 			delete(membersWithSpecificConfig, toolchainCluster.Name)
 		}
+		memberConfigSpecs[toolchainCluster.Name] = memberConfigSpec
+	}
+
+	cfg := newToolchainConfig(sourceConfig, nil)
+	syncConfig := cfg.MemberConfigSync()
+	canaries := map[string]bool{}
+	for _, name := range syncConfig.Canaries() {
+		if _, found := memberConfigSpecs[name]; !found {
+			result.Errors[name] = "canary configured but no matching toolchaincluster was found"
+			continue
+		}
+		canaries[name] = true
+	}
 
-		if err := SyncMemberConfig(ctx, memberConfigSpec, toolchainCluster); err != nil {
+	sync := func(toolchainCluster *cluster.CachedToolchainCluster) (memberConfigState, bool) {
+		state, err := syncMemberConfig(ctx, memberConfigSpecs[toolchainCluster.Name], toolchainCluster)
+		if err != nil {
 			s.logger.Error(err, "failed to sync MemberOperatorConfig", "cluster_name", toolchainCluster.Name)
-			syncErrors[toolchainCluster.Name] = err.Error()
+			result.Errors[toolchainCluster.Name] = err.Error()
+			return state, false
 		}
+		if len(state.drift) > 0 {
+			s.logger.Info("Reverted the changes made on the MemberOperatorConfig", "cluster_name", toolchainCluster.Name, "paths", state.drift)
+			result.Drifts[toolchainCluster.Name] = state.drift
+		}
+		if state.syncedAt.After(result.LatestSync) {
+			result.LatestSync = state.syncedAt
+		}
+		return state, true
+	}
+
+	// sync the canaries first
+	syncedAt := map[string]time.Time{}
+	for _, toolchainCluster := range memberToolchainClusters {
+		if !canaries[toolchainCluster.Name] {
+			continue
+		}
+		state, ok := sync(toolchainCluster)
+		if !ok {
+			result.PendingReason = fmt.Sprintf("the MemberOperatorConfig could not be synced to the canary '%s'", toolchainCluster.Name)
+			continue
+		}
+		syncedAt[toolchainCluster.Name] = state.syncedAt
+	}
+	if len(canaries) > 0 && result.PendingReason == "" {
+		result.PendingReason = s.checkCanaries(ctx, sourceConfig.Namespace, syncedAt, syncConfig.CanaryDelay())
+	}
+
+	// then the other members, unless they need a new spec while the canaries are not healthy
+	for _, toolchainCluster := range memberToolchainClusters {
+		if canaries[toolchainCluster.Name] {
+			continue
+		}
+		if result.PendingReason != "" {
+			upToDate, err := isMemberConfigUpToDate(ctx, memberConfigSpecs[toolchainCluster.Name], toolchainCluster)
+			if err != nil {
+				s.logger.Error(err, "failed to get MemberOperatorConfig", "cluster_name", toolchainCluster.Name)
+				result.Errors[toolchainCluster.Name] = err.Error()
+				continue
+			}
+			if !upToDate {
+				result.Pending = append(result.Pending, toolchainCluster.Name)
+				continue
+			}
+		}
+		sync(toolchainCluster)
+	}
+	if len(result.Pending) == 0 {
+		result.PendingReason = ""
 	}
 
 	// add errors for any MemberOperatorConfigs that haven't been synced because there is no matching toolchaincluster
 	if len(membersWithSpecificConfig) > 0 {
 		for k := range membersWithSpecificConfig {
-			syncErrors[k] = "specific member configuration exists but no matching toolchaincluster was found"
+			result.Errors[k] = "specific member configuration exists but no matching toolchaincluster was found"
+		}
+	}
+	return result
+}
+
+// checkCanaries returns why the canaries are not healthy yet, or an empty string if they are: they must have been running with
+// their current spec for at least the given delay, and be ready in the ToolchainStatus
+func (s *Synchronizer) checkCanaries(ctx context.Context, namespace string, syncedAt map[string]time.Time, delay time.Duration) string {
+	toolchainStatus := &toolchainv1alpha1.ToolchainStatus{}
+	if err := s.hostClient.Get(ctx, types.NamespacedName{Namespace: namespace, Name: ToolchainStatusName}, toolchainStatus); err != nil {
+		return fmt.Sprintf("unable to get the ToolchainStatus: %s", err.Error())
+	}
+	ready := map[string]bool{}
+	for _, member := range toolchainStatus.Status.Members {
+		ready[member.ClusterName] = condition.IsTrue(member.MemberStatus.Conditions, toolchainv1alpha1.ConditionReady)
+	}
+	var notReady, tooRecent []string
+	for name, t := range syncedAt {
+		if !ready[name] {
+			notReady = append(notReady, name)
+		}
+		if time.Since(t) < delay {
+			tooRecent = append(tooRecent, name)
 		}
 	}
-	return syncErrors
+	sort.Strings(notReady)
+	sort.Strings(tooRecent)
+	switch {
+	case len(notReady) > 0:
+		return fmt.Sprintf("the canaries are not ready: %s", strings.Join(notReady, ", "))
+	case len(tooRecent) > 0:
+		return fmt.Sprintf("the canaries have been running with the new spec for less than %s: %s", delay, strings.Join(tooRecent, ", "))
+	}
+	return ""
 }
 
 func SyncMemberConfig(ctx context.Context, memberConfigSpec toolchainv1alpha1.MemberOperatorConfigSpec, memberCluster *cluster.CachedToolchainCluster) error {
+	_, err := syncMemberConfig(ctx, memberConfigSpec, memberCluster)
+	return err
+}
+
+// syncMemberConfig creates or updates the MemberOperatorConfig of the given member cluster with the given spec. It returns the
+// paths of the spec which were modified directly on the member cluster since the previous sync, if any.
+func syncMemberConfig(ctx context.Context, memberConfigSpec toolchainv1alpha1.MemberOperatorConfigSpec, memberCluster *cluster.CachedToolchainCluster) (memberConfigState, error) {
+	hash, err := specHash(memberConfigSpec)
+	if err != nil {
+		return memberConfigState{}, err
+	}
+	now := time.Now()
 	memberConfig := &toolchainv1alpha1.MemberOperatorConfig{}
 	if err := memberCluster.Client.Get(ctx, types.NamespacedName{Namespace: memberCluster.OperatorNamespace, Name: configResourceName}, memberConfig); err != nil {
 		if errors.IsNotFound(err) {
@@ -74,17 +225,75 @@ func SyncMemberConfig(ctx context.Context, memberConfigSpec toolchainv1alpha1.Me
 				ObjectMeta: metav1.ObjectMeta{
 					Name:      configResourceName,
 					Namespace: memberCluster.OperatorNamespace,
+					Annotations: map[string]string{
+						MemberConfigSpecHashAnnotationKey: hash,
+						MemberConfigSyncedAtAnnotationKey: now.UTC().Format(time.RFC3339),
+					},
 				},
 				Spec: memberConfigSpec,
 			}
-			return memberCluster.Client.Create(ctx, memberConfig)
+			return memberConfigState{syncedAt: now}, memberCluster.Client.Create(ctx, memberConfig)
 		}
 		// Error reading the object - try again on the next reconcile
-		return err
+		return memberConfigState{}, err
+	}
+
+	actualHash, err := specHash(memberConfig.Spec)
+	if err != nil {
+		return memberConfigState{}, err
+	}
+	state := memberConfigState{}
+	state.syncedAt, _ = time.Parse(time.RFC3339, memberConfig.Annotations[MemberConfigSyncedAtAnnotationKey])
+	syncedHash, synced := memberConfig.Annotations[MemberConfigSpecHashAnnotationKey]
+	if synced && syncedHash != actualHash {
+		// the spec was modified on the member cluster since it was synced
+		if state.drift, err = diffMemberConfigSpecs(memberConfigSpec, memberConfig.Spec); err != nil {
+			return memberConfigState{}, err
+		}
+	}
+	if actualHash == hash && syncedHash == hash {
+		return state, nil
+	}
+	if syncedHash != hash {
+		state.syncedAt = now
 	}
 
 	// MemberOperatorConfig exists - update spec
 	memberConfig.Spec = memberConfigSpec
+	if memberConfig.Annotations == nil {
+		memberConfig.Annotations = map[string]string{}
+	}
+	memberConfig.Annotations[MemberConfigSpecHashAnnotationKey] = hash
+	memberConfig.Annotations[MemberConfigSyncedAtAnnotationKey] = state.syncedAt.UTC().Format(time.RFC3339)
+
+	return state, memberCluster.Client.Update(ctx, memberConfig)
+}
+
+// isMemberConfigUpToDate returns true if the given spec was already synced to the member cluster
+func isMemberConfigUpToDate(ctx context.Context, memberConfigSpec toolchainv1alpha1.MemberOperatorConfigSpec, memberCluster *cluster.CachedToolchainCluster) (bool, error) {
+	hash, err := specHash(memberConfigSpec)
+	if err != nil {
+		return false, err
+	}
+	memberConfig := &toolchainv1alpha1.MemberOperatorConfig{}
+	if err := memberCluster.Client.Get(ctx, types.NamespacedName{Namespace: memberCluster.OperatorNamespace, Name: configResourceName}, memberConfig); err != nil {
+		if errors.IsNotFound(err) {
+			return false, nil
+		}
+		return false, err
+	}
+	return memberConfig.Annotations[MemberConfigSpecHashAnnotationKey] == hash, nil
+}
 
-	return memberCluster.Client.Update(ctx, memberConfig)
+// diffMemberConfigSpecs returns the paths of the values of the actual spec which differ from the expected one
+func diffMemberConfigSpecs(expected, actual toolchainv1alpha1.MemberOperatorConfigSpec) ([]string, error) {
+	expectedJSON, err := json.Marshal(expected)
+	if err != nil {
+		return nil, errs.Wrap(err, "unable to marshal the expected MemberOperatorConfig spec")
+	}
+	actualJSON, err := json.Marshal(actual)
+	if err != nil {
+		return nil, errs.Wrap(err, "unable to marshal the actual MemberOperatorConfig spec")
+	}
+	return changedPaths(expectedJSON, actualJSON)
 }
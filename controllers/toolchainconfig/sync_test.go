@@ -8,6 +8,8 @@ import (
 	toolchainv1alpha1 "github.com/codeready-toolchain/api/api/v1alpha1"
 	"github.com/codeready-toolchain/host-operator/controllers/toolchainconfig"
 	. "github.com/codeready-toolchain/host-operator/test"
+	hostconfig "github.com/codeready-toolchain/host-operator/test/config"
+	"github.com/codeready-toolchain/toolchain-common/pkg/cluster"
 	commonconfig "github.com/codeready-toolchain/toolchain-common/pkg/configuration"
 	"github.com/codeready-toolchain/toolchain-common/pkg/test"
 	testconfig "github.com/codeready-toolchain/toolchain-common/pkg/test/config"
@@ -16,6 +18,7 @@ import (
 	"github.com/stretchr/testify/require"
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/utils/ptr"
 	ctrl "sigs.k8s.io/controller-runtime"
 	runtimeclient "sigs.k8s.io/controller-runtime/pkg/client"
 )
@@ -33,11 +36,12 @@ func TestSyncMemberConfigs(t *testing.T) {
 				testconfig.Members().SpecificPerMemberCluster("member2", specificMemberConfig.Spec))
 			s := toolchainconfig.NewSynchronizer(
 				ctrl.Log.WithName("controllers").WithName("ToolchainConfig"),
+				test.NewFakeClient(t),
 				NewGetMemberClusters(),
 			)
 
 			// when
-			syncErrors := s.SyncMemberConfigs(context.TODO(), toolchainConfig)
+			syncErrors := s.SyncMemberConfigs(context.TODO(), toolchainConfig).Errors
 
 			// then
 			require.Empty(t, syncErrors)
@@ -50,11 +54,12 @@ func TestSyncMemberConfigs(t *testing.T) {
 				testconfig.Members().SpecificPerMemberCluster("member2", specificMemberConfig.Spec))
 			s := toolchainconfig.NewSynchronizer(
 				ctrl.Log.WithName("controllers").WithName("ToolchainConfig"),
+				test.NewFakeClient(t),
 				NewGetMemberClusters(NewMemberClusterWithTenantRole(t, "member1", corev1.ConditionTrue), NewMemberClusterWithTenantRole(t, "member2", corev1.ConditionTrue)),
 			)
 
 			// when
-			syncErrors := s.SyncMemberConfigs(context.TODO(), toolchainConfig)
+			syncErrors := s.SyncMemberConfigs(context.TODO(), toolchainConfig).Errors
 
 			// then
 			require.Empty(t, syncErrors)
@@ -74,11 +79,12 @@ func TestSyncMemberConfigs(t *testing.T) {
 				testconfig.Members().SpecificPerMemberCluster("member2", specificMemberConfig.Spec))
 			s := toolchainconfig.NewSynchronizer(
 				ctrl.Log.WithName("controllers").WithName("ToolchainConfig"),
+				test.NewFakeClient(t),
 				NewGetMemberClusters(NewMemberClusterWithTenantRole(t, "member1", corev1.ConditionTrue), NewMemberClusterWithClient(memberCl, "member2", corev1.ConditionTrue)),
 			)
 
 			// when
-			syncErrors := s.SyncMemberConfigs(context.TODO(), toolchainConfig)
+			syncErrors := s.SyncMemberConfigs(context.TODO(), toolchainConfig).Errors
 
 			// then
 			require.Len(t, syncErrors, 1)
@@ -100,11 +106,12 @@ func TestSyncMemberConfigs(t *testing.T) {
 				testconfig.Members().SpecificPerMemberCluster("member2", specificMemberConfig.Spec))
 			s := toolchainconfig.NewSynchronizer(
 				ctrl.Log.WithName("controllers").WithName("ToolchainConfig"),
+				test.NewFakeClient(t),
 				NewGetMemberClusters(NewMemberClusterWithClient(memberCl, "member1", corev1.ConditionTrue), NewMemberClusterWithClient(memberCl2, "member2", corev1.ConditionTrue)),
 			)
 
 			// when
-			syncErrors := s.SyncMemberConfigs(context.TODO(), toolchainConfig)
+			syncErrors := s.SyncMemberConfigs(context.TODO(), toolchainConfig).Errors
 
 			// then
 			require.Len(t, syncErrors, 2)
@@ -120,11 +127,12 @@ func TestSyncMemberConfigs(t *testing.T) {
 				testconfig.Members().SpecificPerMemberCluster("member2", specificMemberConfig.Spec))
 			s := toolchainconfig.NewSynchronizer(
 				ctrl.Log.WithName("controllers").WithName("ToolchainConfig"),
+				test.NewFakeClient(t),
 				NewGetMemberClusters(NewMemberClusterWithClient(memberCl, "member1", corev1.ConditionTrue)),
 			)
 
 			// when
-			syncErrors := s.SyncMemberConfigs(context.TODO(), toolchainConfig)
+			syncErrors := s.SyncMemberConfigs(context.TODO(), toolchainConfig).Errors
 
 			// then
 			require.Len(t, syncErrors, 1)
@@ -214,3 +222,219 @@ func TestSyncMemberConfig(t *testing.T) {
 		})
 	})
 }
+
+func TestStagedSyncMemberConfigs(t *testing.T) {
+	// given
+	oldMemberConfig := testconfig.NewMemberOperatorConfigObj(testconfig.MemberStatus().RefreshPeriod("5s"))
+	newMemberConfig := testconfig.NewMemberOperatorConfigObj(testconfig.MemberStatus().RefreshPeriod("15s"))
+	newToolchainConfig := func(t *testing.T, canaryDelay string) *toolchainv1alpha1.ToolchainConfig {
+		return commonconfig.NewToolchainConfigObjWithReset(t,
+			testconfig.Members().Default(newMemberConfig.Spec),
+			hostconfig.Annotation(toolchainconfig.MemberConfigSyncCanariesAnnotationKey, "member1"),
+			hostconfig.Annotation(toolchainconfig.MemberConfigSyncCanaryDelayAnnotationKey, canaryDelay))
+	}
+	// newMembers returns the member clusters, all of them having the old spec
+	newMembers := func(t *testing.T) (*cluster.CachedToolchainCluster, *cluster.CachedToolchainCluster) {
+		oldConfig := commonconfig.NewToolchainConfigObjWithReset(t, testconfig.Members().Default(oldMemberConfig.Spec))
+		member1 := NewMemberClusterWithTenantRole(t, "member1", corev1.ConditionTrue)
+		member2 := NewMemberClusterWithTenantRole(t, "member2", corev1.ConditionTrue)
+		s := toolchainconfig.NewSynchronizer(ctrl.Log.WithName("test"), test.NewFakeClient(t), NewGetMemberClusters(member1, member2))
+		require.Empty(t, s.SyncMemberConfigs(context.TODO(), oldConfig).Errors)
+		return member1, member2
+	}
+
+	t.Run("synced to all members when the canaries are healthy", func(t *testing.T) {
+		// given
+		member1, member2 := newMembers(t)
+		hostCl := test.NewFakeClient(t, NewToolchainStatus(WithMember("member1", WithMemberStatusCondition(ToBeReady()))))
+		s := toolchainconfig.NewSynchronizer(ctrl.Log.WithName("test"), hostCl, NewGetMemberClusters(member1, member2))
+
+		// when
+		result := s.SyncMemberConfigs(context.TODO(), newToolchainConfig(t, "0s"))
+
+		// then
+		assert.Empty(t, result.Errors)
+		assert.Empty(t, result.Pending)
+		assert.Empty(t, result.PendingReason)
+		assertMemberConfigSpec(t, member1, newMemberConfig.Spec)
+		assertMemberConfigSpec(t, member2, newMemberConfig.Spec)
+	})
+
+	t.Run("synced to the canaries only", func(t *testing.T) {
+		for name, tc := range map[string]struct {
+			toolchainStatus *toolchainv1alpha1.ToolchainStatus
+			canaryDelay     string
+			expectedReason  string
+		}{
+			"canary not ready": {
+				toolchainStatus: NewToolchainStatus(WithMember("member1", WithMemberStatusCondition(ToBeNotReady()))),
+				canaryDelay:     "0s",
+				expectedReason:  "the canaries are not ready: member1",
+			},
+			"canary missing in the ToolchainStatus": {
+				toolchainStatus: NewToolchainStatus(WithMember("member2", WithMemberStatusCondition(ToBeReady()))),
+				canaryDelay:     "0s",
+				expectedReason:  "the canaries are not ready: member1",
+			},
+			"canary updated too recently": {
+				toolchainStatus: NewToolchainStatus(WithMember("member1", WithMemberStatusCondition(ToBeReady()))),
+				canaryDelay:     "1h",
+				expectedReason:  "the canaries have been running with the new spec for less than 1h0m0s: member1",
+			},
+		} {
+			t.Run(name, func(t *testing.T) {
+				// given
+				member1, member2 := newMembers(t)
+				hostCl := test.NewFakeClient(t, tc.toolchainStatus)
+				s := toolchainconfig.NewSynchronizer(ctrl.Log.WithName("test"), hostCl, NewGetMemberClusters(member1, member2))
+
+				// when
+				result := s.SyncMemberConfigs(context.TODO(), newToolchainConfig(t, tc.canaryDelay))
+
+				// then
+				assert.Empty(t, result.Errors)
+				assert.Equal(t, []string{"member2"}, result.Pending)
+				assert.Equal(t, tc.expectedReason, result.PendingReason)
+				assertMemberConfigSpec(t, member1, newMemberConfig.Spec)
+				assertMemberConfigSpec(t, member2, oldMemberConfig.Spec)
+			})
+		}
+
+		t.Run("ToolchainStatus not found", func(t *testing.T) {
+			// given
+			member1, member2 := newMembers(t)
+			s := toolchainconfig.NewSynchronizer(ctrl.Log.WithName("test"), test.NewFakeClient(t), NewGetMemberClusters(member1, member2))
+
+			// when
+			result := s.SyncMemberConfigs(context.TODO(), newToolchainConfig(t, "0s"))
+
+			// then
+			assert.Empty(t, result.Errors)
+			assert.Equal(t, []string{"member2"}, result.Pending)
+			assert.Contains(t, result.PendingReason, "unable to get the ToolchainStatus: ")
+			assertMemberConfigSpec(t, member2, oldMemberConfig.Spec)
+		})
+
+		t.Run("failed to sync to the canary", func(t *testing.T) {
+			// given
+			member1Cl := test.NewFakeClient(t)
+			member1Cl.MockGet = func(_ context.Context, _ types.NamespacedName, _ runtimeclient.Object, _ ...runtimeclient.GetOption) error {
+				return fmt.Errorf("client error")
+			}
+			_, member2 := newMembers(t)
+			hostCl := test.NewFakeClient(t, NewToolchainStatus(WithMember("member1", WithMemberStatusCondition(ToBeReady()))))
+			s := toolchainconfig.NewSynchronizer(ctrl.Log.WithName("test"), hostCl,
+				NewGetMemberClusters(NewMemberClusterWithClient(member1Cl, "member1", corev1.ConditionTrue), member2))
+
+			// when
+			result := s.SyncMemberConfigs(context.TODO(), newToolchainConfig(t, "0s"))
+
+			// then
+			assert.Equal(t, map[string]string{"member1": "client error"}, result.Errors)
+			assert.Equal(t, []string{"member2"}, result.Pending)
+			assert.Equal(t, "the MemberOperatorConfig could not be synced to the canary 'member1'", result.PendingReason)
+			assertMemberConfigSpec(t, member2, oldMemberConfig.Spec)
+		})
+	})
+
+	t.Run("up-to-date members are not pending", func(t *testing.T) {
+		// given
+		member1, member2 := newMembers(t)
+		hostCl := test.NewFakeClient(t, NewToolchainStatus(WithMember("member1", WithMemberStatusCondition(ToBeNotReady()))))
+		s := toolchainconfig.NewSynchronizer(ctrl.Log.WithName("test"), hostCl, NewGetMemberClusters(member1, member2))
+		// the new spec is already synced to member2
+		require.NoError(t, toolchainconfig.SyncMemberConfig(context.TODO(), newMemberConfig.Spec, member2))
+
+		// when
+		result := s.SyncMemberConfigs(context.TODO(), newToolchainConfig(t, "0s"))
+
+		// then
+		assert.Empty(t, result.Errors)
+		assert.Empty(t, result.Pending)
+		assert.Empty(t, result.PendingReason)
+	})
+
+	t.Run("unknown canaries are reported", func(t *testing.T) {
+		// given
+		member1, member2 := newMembers(t)
+		toolchainConfig := commonconfig.NewToolchainConfigObjWithReset(t,
+			testconfig.Members().Default(newMemberConfig.Spec),
+			hostconfig.Annotation(toolchainconfig.MemberConfigSyncCanariesAnnotationKey, "member3"))
+		s := toolchainconfig.NewSynchronizer(ctrl.Log.WithName("test"), test.NewFakeClient(t), NewGetMemberClusters(member1, member2))
+
+		// when
+		result := s.SyncMemberConfigs(context.TODO(), toolchainConfig)
+
+		// then
+		assert.Equal(t, map[string]string{"member3": "canary configured but no matching toolchaincluster was found"}, result.Errors)
+		assert.Empty(t, result.Pending)
+		assertMemberConfigSpec(t, member1, newMemberConfig.Spec)
+		assertMemberConfigSpec(t, member2, newMemberConfig.Spec)
+	})
+}
+
+func TestMemberConfigDrift(t *testing.T) {
+	// given
+	memberConfig := testconfig.NewMemberOperatorConfigObj(testconfig.MemberStatus().RefreshPeriod("5s"))
+	toolchainConfig := commonconfig.NewToolchainConfigObjWithReset(t, testconfig.Members().Default(memberConfig.Spec))
+	member1 := NewMemberClusterWithTenantRole(t, "member1", corev1.ConditionTrue)
+	member2 := NewMemberClusterWithTenantRole(t, "member2", corev1.ConditionTrue)
+	s := toolchainconfig.NewSynchronizer(ctrl.Log.WithName("test"), test.NewFakeClient(t), NewGetMemberClusters(member1, member2))
+	result := s.SyncMemberConfigs(context.TODO(), toolchainConfig)
+	require.Empty(t, result.Errors)
+	require.Empty(t, result.Drifts)
+	latestSync := result.LatestSync
+	require.False(t, latestSync.IsZero())
+
+	t.Run("no drift", func(t *testing.T) {
+		// when
+		result := s.SyncMemberConfigs(context.TODO(), toolchainConfig)
+
+		// then
+		assert.Empty(t, result.Errors)
+		assert.Empty(t, result.Drifts)
+	})
+
+	t.Run("drift detected and reverted", func(t *testing.T) {
+		// given
+		actual := getMemberOperatorConfig(t, member1)
+		actual.Spec.MemberStatus.RefreshPeriod = ptr.To("1s")
+		actual.Spec.Webhook.Deploy = ptr.To(false)
+		require.NoError(t, member1.Client.Update(context.TODO(), actual))
+
+		// when
+		result := s.SyncMemberConfigs(context.TODO(), toolchainConfig)
+
+		// then
+		assert.Empty(t, result.Errors)
+		assert.Equal(t, map[string][]string{
+			"member1": {"spec.memberStatus.refreshPeriod", "spec.webhook.deploy"},
+		}, result.Drifts)
+		// reverting the drift is not a new sync
+		assert.False(t, result.LatestSync.After(latestSync))
+		assertMemberConfigSpec(t, member1, memberConfig.Spec)
+		assertMemberConfigSpec(t, member2, memberConfig.Spec)
+
+		t.Run("no drift after it was reverted", func(t *testing.T) {
+			// when
+			result := s.SyncMemberConfigs(context.TODO(), toolchainConfig)
+
+			// then
+			assert.Empty(t, result.Errors)
+			assert.Empty(t, result.Drifts)
+		})
+	})
+}
+
+func getMemberOperatorConfig(t *testing.T, memberCluster *cluster.CachedToolchainCluster) *toolchainv1alpha1.MemberOperatorConfig {
+	actual := &toolchainv1alpha1.MemberOperatorConfig{}
+	require.NoError(t, memberCluster.Client.Get(context.TODO(), types.NamespacedName{Name: "config", Namespace: memberCluster.OperatorNamespace}, actual))
+	return actual
+}
+
+func assertMemberConfigSpec(t *testing.T, memberCluster *cluster.CachedToolchainCluster, expected toolchainv1alpha1.MemberOperatorConfigSpec) {
+	actual := getMemberOperatorConfig(t, memberCluster)
+	assert.Equal(t, expected, actual.Spec)
+	assert.NotEmpty(t, actual.Annotations[toolchainconfig.MemberConfigSpecHashAnnotationKey])
+	assert.NotEmpty(t, actual.Annotations[toolchainconfig.MemberConfigSyncedAtAnnotationKey])
+}
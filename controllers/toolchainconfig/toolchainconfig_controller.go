@@ -4,6 +4,8 @@ import (
 	"context"
 	"fmt"
 	"os"
+	"sort"
+	"strings"
 	"time"
 
 	toolchainv1alpha1 "github.com/codeready-toolchain/api/api/v1alpha1"
@@ -31,6 +33,31 @@ import (
 
 const configResourceName = "config"
 
+const (
+	// ToolchainConfigValid is the type of the condition which reports whether the ToolchainConfig is valid, see ToolchainConfig.Validate
+	ToolchainConfigValid toolchainv1alpha1.ConditionType = "ConfigValid"
+
+	ToolchainConfigValidReason   = "Valid"
+	ToolchainConfigInvalidReason = "Invalid"
+
+	// ToolchainConfigRollback is the type of the condition which reports the result of the latest rollback of the spec to a revision
+	// of the history, see ToolchainConfigRollbackAnnotationKey
+	ToolchainConfigRollback toolchainv1alpha1.ConditionType = "Rollback"
+
+	ToolchainConfigRolledBackReason     = "RolledBack"
+	ToolchainConfigRollbackFailedReason = "RollbackFailed"
+
+	// ToolchainConfigMemberConfigDrift is the type of the condition which reports the changes made directly on the MemberOperatorConfigs
+	// of the member clusters, detected (and reverted) during the latest sync
+	ToolchainConfigMemberConfigDrift toolchainv1alpha1.ConditionType = "MemberConfigDrift"
+
+	ToolchainConfigDriftRevertedReason = "DriftReverted"
+	ToolchainConfigNoDriftReason       = "NoDrift"
+
+	// ToolchainConfigSyncPendingReason is the reason of the SyncComplete condition when some member clusters wait for the canaries
+	ToolchainConfigSyncPendingReason = "SyncPending"
+)
+
 // DefaultReconcile requeue every 10 seconds by default to ensure the MemberOperatorConfig on each member remains synchronized with the ToolchainConfig
 var DefaultReconcile = reconcile.Result{RequeueAfter: 10 * time.Second}
 
@@ -46,7 +73,8 @@ func (r *Reconciler) SetupWithManager(mgr manager.Manager) error {
 	log.Log.Info("Setup ToolchainConfig")
 
 	return ctrl.NewControllerManagedBy(mgr).
-		For(&toolchainv1alpha1.ToolchainConfig{}, builder.WithPredicates(predicate.GenerationChangedPredicate{})).
+		// the annotations are watched too, since they contain some settings and the rollback requests
+		For(&toolchainv1alpha1.ToolchainConfig{}, builder.WithPredicates(predicate.Or(predicate.GenerationChangedPredicate{}, predicate.AnnotationChangedPredicate{}))).
 		Watches(&corev1.Secret{},
 			handler.EnqueueRequestsFromMapFunc(MapSecretToToolchainConfig())).
 		Complete(r)
@@ -98,12 +126,43 @@ func (r *Reconciler) Reconcile(ctx context.Context, request ctrl.Request) (ctrl.
 		return DefaultReconcile, err
 	}
 
+	// Roll the spec back to a revision of the history, if requested. A failed rollback request is dropped and reported in the
+	// Rollback condition, so that it doesn't block the rest of the reconcile: the rollback can be requested again once fixed.
+	rollbackOf := 0
+	if revision, found := toolchainConfig.Annotations[ToolchainConfigRollbackAnnotationKey]; found {
+		if rollbackOf, err = r.rollback(ctx, toolchainConfig, revision); err != nil {
+			err = errs.Wrapf(err, "failed to roll back to revision %s", revision)
+			reqLogger.Error(err, "unable to roll back the ToolchainConfig")
+			if err := r.dropRollbackRequest(ctx, toolchainConfig); err != nil {
+				return reconcile.Result{}, err
+			}
+			if err := r.setStatusRollbackFailed(ctx, toolchainConfig, err.Error()); err != nil {
+				return reconcile.Result{}, err
+			}
+		}
+	}
+
 	// Load the latest config and secrets into the cache
 	cfg, err := ForceLoadToolchainConfig(r.Client)
 	if err != nil {
 		return reconcile.Result{}, r.WrapErrorWithStatusUpdate(ctx, toolchainConfig, r.setStatusDeployRegistrationServiceFailed, err, "failed to load the latest configuration")
 	}
 
+	// Record the spec in the history. A failure doesn't fail the reconcile, since the spec is recorded at the next reconcile.
+	if err := r.recordRevision(ctx, toolchainConfig, cfg.HistoryLimit(), rollbackOf); err != nil {
+		reqLogger.Error(err, "unable to record the ToolchainConfig revision")
+	}
+	if rollbackOf > 0 {
+		if err := r.updateStatusCondition(ctx, toolchainConfig, ToRollbackComplete(rollbackOf), false); err != nil {
+			return reconcile.Result{}, err
+		}
+	}
+
+	// Report the invalid values, which are otherwise silently replaced with their defaults
+	if err := r.updateStatusCondition(ctx, toolchainConfig, ToConfigValidity(cfg.Validate()), false); err != nil {
+		return reconcile.Result{}, err
+	}
+
 	// Deploy registration service
 	if err := r.ensureRegistrationService(ctx, toolchainConfig, getVars(request.Namespace, cfg)); err != nil {
 		// immediately reconcile again if there was an error
@@ -112,20 +171,43 @@ func (r *Reconciler) Reconcile(ctx context.Context, request ctrl.Request) (ctrl.
 
 	// Sync member configs to member clusters
 	sync := Synchronizer{
+		hostClient:     r.Client,
 		logger:         reqLogger,
 		getMembersFunc: r.GetMembersFunc,
 	}
 
-	if syncErrs := sync.SyncMemberConfigs(ctx, toolchainConfig); len(syncErrs) > 0 {
+	result := sync.SyncMemberConfigs(ctx, toolchainConfig)
+	if err := r.updateDriftStatus(ctx, toolchainConfig, result.Drifts, result.LatestSync); err != nil {
+		return DefaultReconcile, err
+	}
+	if syncErrs := result.Errors; len(syncErrs) > 0 {
 		for cluster, errMsg := range syncErrs {
 			err := fmt.Errorf("%s", errMsg)
 			reqLogger.Error(err, "error syncing configuration to member cluster", "cluster", cluster)
 		}
 		return DefaultReconcile, r.updateSyncStatus(ctx, toolchainConfig, syncErrs, ToSyncFailure())
 	}
+	if len(result.Pending) > 0 {
+		return DefaultReconcile, r.updateSyncStatus(ctx, toolchainConfig, map[string]string{}, ToSyncPending(result.Pending, result.PendingReason))
+	}
 	return DefaultReconcile, r.updateSyncStatus(ctx, toolchainConfig, map[string]string{}, ToSyncComplete())
 }
 
+// updateDriftStatus reports the changes made directly on the MemberOperatorConfigs of the member clusters, which have been reverted.
+// The condition is only added once a drift was detected, and its LastUpdatedTime is when the latest drift was reverted. The drift
+// is kept in the condition until a newer spec is synced to the member clusters, so that it isn't lost at the next reconcile.
+func (r *Reconciler) updateDriftStatus(ctx context.Context, toolchainConfig *toolchainv1alpha1.ToolchainConfig, drifts map[string][]string, latestSync time.Time) error {
+	if len(drifts) > 0 {
+		toolchainConfig.Status.Conditions = condition.AddOrUpdateStatusConditionsWithLastUpdatedTimestamp(toolchainConfig.Status.Conditions, ToMemberConfigDrift(drifts))
+		return r.Client.Status().Update(ctx, toolchainConfig)
+	}
+	drift, found := condition.FindConditionByType(toolchainConfig.Status.Conditions, ToolchainConfigMemberConfigDrift)
+	if !found || (drift.Status == corev1.ConditionTrue && drift.LastUpdatedTime != nil && !latestSync.After(drift.LastUpdatedTime.Time)) {
+		return nil
+	}
+	return r.updateStatusCondition(ctx, toolchainConfig, ToNoMemberConfigDrift(), false)
+}
+
 func (r *Reconciler) ensureRegistrationService(ctx context.Context, toolchainConfig *toolchainv1alpha1.ToolchainConfig, vars templateVars) error {
 	// process template with variables taken from the RegistrationService CRD
 	cl := applycl.NewApplyClient(r.Client)
@@ -181,6 +263,10 @@ func (r *Reconciler) setStatusDeployRegistrationServiceFailed(ctx context.Contex
 	return r.updateStatusCondition(ctx, toolchainConfig, ToRegServiceDeployFailure(message), false)
 }
 
+func (r *Reconciler) setStatusRollbackFailed(ctx context.Context, toolchainConfig *toolchainv1alpha1.ToolchainConfig, message string) error {
+	return r.updateStatusCondition(ctx, toolchainConfig, ToRollbackFailure(message), false)
+}
+
 func (r *Reconciler) updateSyncStatus(ctx context.Context, toolchainConfig *toolchainv1alpha1.ToolchainConfig, syncErrs map[string]string, newCondition toolchainv1alpha1.Condition) error {
 	toolchainConfig.Status.SyncErrors = syncErrs
 	return r.updateStatusCondition(ctx, toolchainConfig, newCondition, true)
@@ -234,6 +320,86 @@ func ToSyncFailure() toolchainv1alpha1.Condition {
 	}
 }
 
+// ToSyncPending condition when the new MemberOperatorConfigs are not synced to the given member clusters until the canaries are healthy
+func ToSyncPending(members []string, reason string) toolchainv1alpha1.Condition {
+	return toolchainv1alpha1.Condition{
+		Type:    toolchainv1alpha1.ToolchainConfigSyncComplete,
+		Status:  corev1.ConditionFalse,
+		Reason:  ToolchainConfigSyncPendingReason,
+		Message: fmt.Sprintf("waiting for the canaries before syncing the MemberOperatorConfigs to %s: %s", strings.Join(members, ", "), reason),
+	}
+}
+
+// ToMemberConfigDrift condition when the MemberOperatorConfigs were modified on the member clusters. The message contains the modified
+// paths of the spec per member cluster.
+func ToMemberConfigDrift(drifts map[string][]string) toolchainv1alpha1.Condition {
+	members := make([]string, 0, len(drifts))
+	for member := range drifts {
+		members = append(members, member)
+	}
+	sort.Strings(members)
+	msgs := make([]string, 0, len(members))
+	for _, member := range members {
+		msgs = append(msgs, fmt.Sprintf("%s: %s", member, strings.Join(drifts[member], ", ")))
+	}
+	return toolchainv1alpha1.Condition{
+		Type:    ToolchainConfigMemberConfigDrift,
+		Status:  corev1.ConditionTrue,
+		Reason:  ToolchainConfigDriftRevertedReason,
+		Message: "reverted the changes made on the member clusters: " + strings.Join(msgs, "; "),
+	}
+}
+
+// ToNoMemberConfigDrift condition when no MemberOperatorConfig was modified on the member clusters since the latest sync of a new spec
+func ToNoMemberConfigDrift() toolchainv1alpha1.Condition {
+	return toolchainv1alpha1.Condition{
+		Type:   ToolchainConfigMemberConfigDrift,
+		Status: corev1.ConditionFalse,
+		Reason: ToolchainConfigNoDriftReason,
+	}
+}
+
+// ToConfigValidity returns the ConfigValid condition for the given validation errors
+func ToConfigValidity(validationErrs []ValidationError) toolchainv1alpha1.Condition {
+	if len(validationErrs) == 0 {
+		return toolchainv1alpha1.Condition{
+			Type:   ToolchainConfigValid,
+			Status: corev1.ConditionTrue,
+			Reason: ToolchainConfigValidReason,
+		}
+	}
+	msgs := make([]string, 0, len(validationErrs))
+	for _, e := range validationErrs {
+		msgs = append(msgs, e.Error())
+	}
+	return toolchainv1alpha1.Condition{
+		Type:    ToolchainConfigValid,
+		Status:  corev1.ConditionFalse,
+		Reason:  ToolchainConfigInvalidReason,
+		Message: strings.Join(msgs, "; "),
+	}
+}
+
+// ToRollbackComplete condition when the spec was rolled back to the given revision
+func ToRollbackComplete(revision int) toolchainv1alpha1.Condition {
+	return toolchainv1alpha1.Condition{
+		Type:    ToolchainConfigRollback,
+		Status:  corev1.ConditionTrue,
+		Reason:  ToolchainConfigRolledBackReason,
+		Message: fmt.Sprintf("rolled back to revision %d", revision),
+	}
+}
+
+// ToRollbackFailure condition when the spec could not be rolled back
+func ToRollbackFailure(msg string) toolchainv1alpha1.Condition {
+	return toolchainv1alpha1.Condition{
+		Type:    ToolchainConfigRollback,
+		Status:  corev1.ConditionFalse,
+		Reason:  ToolchainConfigRollbackFailedReason,
+		Message: msg,
+	}
+}
+
 // ToRegServiceDeployComplete condition when deploying is completed
 func ToRegServiceDeployComplete() toolchainv1alpha1.Condition {
 	return toolchainv1alpha1.Condition{
@@ -11,7 +11,9 @@ import (
 	"github.com/codeready-toolchain/host-operator/pkg/apis"
 	"github.com/codeready-toolchain/host-operator/pkg/templates/registrationservice"
 	. "github.com/codeready-toolchain/host-operator/test"
+	hostconfig "github.com/codeready-toolchain/host-operator/test/config"
 	"github.com/codeready-toolchain/toolchain-common/pkg/cluster"
+	"github.com/codeready-toolchain/toolchain-common/pkg/condition"
 	commonconfig "github.com/codeready-toolchain/toolchain-common/pkg/configuration"
 	"github.com/codeready-toolchain/toolchain-common/pkg/test"
 	testconfig "github.com/codeready-toolchain/toolchain-common/pkg/test/config"
@@ -22,6 +24,7 @@ import (
 	"k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/types"
 	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/utils/ptr"
 	runtimeclient "sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/log"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
@@ -85,6 +88,7 @@ func TestReconcile(t *testing.T) {
 			testconfig.AssertThatToolchainConfig(t, test.HostOperatorNs, hostCl).
 				Exists().
 				HasConditions(
+					toolchainconfig.ToConfigValidity(nil),
 					toolchainconfig.ToSyncComplete(),
 					toolchainconfig.ToRegServiceDeploying("updated resources: [ServiceAccount: registration-service Role: registration-service RoleBinding: registration-service Deployment: registration-service Route: registration-service Service: registration-service Service: registration-service-metrics Route: api Service: api Service: proxy-metrics-service]")).
 				HasNoSyncErrors()
@@ -120,6 +124,7 @@ func TestReconcile(t *testing.T) {
 				testconfig.AssertThatToolchainConfig(t, test.HostOperatorNs, hostCl).
 					Exists().
 					HasConditions(
+						toolchainconfig.ToConfigValidity(nil),
 						toolchainconfig.ToSyncComplete(),
 						toolchainconfig.ToRegServiceDeployComplete()).
 					HasNoSyncErrors()
@@ -166,6 +171,143 @@ func TestReconcile(t *testing.T) {
 				assert.Equal(t, "20s", *member2Cfg.Spec.MemberStatus.RefreshPeriod)
 			})
 		})
+
+		t.Run("invalid config", func(t *testing.T) {
+			// given
+			config := commonconfig.NewToolchainConfigObjWithReset(t,
+				testconfig.Notifications().DurationBeforeNotificationDeletion("banana"),
+				testconfig.Notifications().Secret().Ref("notification-secret").MailgunAPIKey("mailgunAPIKey"),
+				testconfig.ToolchainStatus().GitHubSecretRef("github").GitHubSecretAccessTokenKey("accessToken"))
+			notificationSecret := test.CreateSecret("notification-secret", test.HostOperatorNs, map[string][]byte{"mailgunDomain": []byte("acme.com")})
+			hostCl := test.NewFakeClient(t, config, notificationSecret)
+			controller := newController(t, hostCl, NewGetMemberClusters())
+
+			// when
+			_, err := controller.Reconcile(context.TODO(), newRequest())
+
+			// then
+			require.NoError(t, err)
+			testconfig.AssertThatToolchainConfig(t, test.HostOperatorNs, hostCl).
+				Exists().
+				HasConditions(
+					toolchainconfig.ToConfigValidity([]toolchainconfig.ValidationError{
+						{Field: "spec.host.notifications.durationBeforeNotificationDeletion", Message: "invalid duration 'banana'"},
+						{Field: "spec.host.notifications.secret.mailgunAPIKey", Message: "key 'mailgunAPIKey' not found in secret 'notification-secret'"},
+						{Field: "spec.host.toolchainStatus.gitHubSecret.ref", Message: "secret 'github' not found"},
+					}),
+					toolchainconfig.ToSyncComplete(),
+					toolchainconfig.ToRegServiceDeploying("updated resources: [ServiceAccount: registration-service Role: registration-service RoleBinding: registration-service Deployment: registration-service Route: registration-service Service: registration-service Service: registration-service-metrics Route: api Service: api Service: proxy-metrics-service]"))
+		})
+
+		t.Run("staged sync and drift", func(t *testing.T) {
+			// given
+			config := commonconfig.NewToolchainConfigObjWithReset(t,
+				testconfig.Members().Default(defaultMemberConfig.Spec),
+				hostconfig.Annotation(toolchainconfig.MemberConfigSyncCanariesAnnotationKey, "member1"),
+				hostconfig.Annotation(toolchainconfig.MemberConfigSyncCanaryDelayAnnotationKey, "0s"))
+			toolchainStatus := NewToolchainStatus(WithMember("member1", WithMemberStatusCondition(ToBeNotReady())))
+			hostCl := test.NewFakeClient(t, config, toolchainStatus)
+			member1 := NewMemberClusterWithTenantRole(t, "member1", corev1.ConditionTrue)
+			member2 := NewMemberClusterWithTenantRole(t, "member2", corev1.ConditionTrue)
+			controller := newController(t, hostCl, NewGetMemberClusters(member1, member2))
+
+			// when
+			_, err := controller.Reconcile(context.TODO(), newRequest())
+
+			// then
+			require.NoError(t, err)
+			testconfig.AssertThatToolchainConfig(t, test.HostOperatorNs, hostCl).
+				HasConditions(
+					toolchainconfig.ToConfigValidity(nil),
+					toolchainconfig.ToSyncPending([]string{"member2"}, "the canaries are not ready: member1"),
+					toolchainconfig.ToRegServiceDeploying("updated resources: [ServiceAccount: registration-service Role: registration-service RoleBinding: registration-service Deployment: registration-service Route: registration-service Service: registration-service Service: registration-service-metrics Route: api Service: api Service: proxy-metrics-service]")).
+				HasNoSyncErrors()
+			_, err = getMemberConfig(member2)
+			require.True(t, errors.IsNotFound(err))
+
+			t.Run("synced to the other members once the canaries are ready", func(t *testing.T) {
+				// given
+				toolchainStatus.Status.Members[0].MemberStatus.Conditions = []toolchainv1alpha1.Condition{ToBeReady()}
+				require.NoError(t, hostCl.Status().Update(context.TODO(), toolchainStatus))
+
+				// when
+				_, err := controller.Reconcile(context.TODO(), newRequest())
+
+				// then
+				require.NoError(t, err)
+				testconfig.AssertThatToolchainConfig(t, test.HostOperatorNs, hostCl).
+					HasConditions(
+						toolchainconfig.ToConfigValidity(nil),
+						toolchainconfig.ToSyncComplete(),
+						toolchainconfig.ToRegServiceDeployComplete())
+				member2Cfg, err := getMemberConfig(member2)
+				require.NoError(t, err)
+				assert.Equal(t, "5s", *member2Cfg.Spec.MemberStatus.RefreshPeriod)
+			})
+
+			t.Run("drift reverted", func(t *testing.T) {
+				// given
+				member2Cfg, err := getMemberConfig(member2)
+				require.NoError(t, err)
+				member2Cfg.Spec.MemberStatus.RefreshPeriod = ptr.To("1s")
+				require.NoError(t, member2.Client.Update(context.TODO(), member2Cfg))
+
+				// when
+				_, err = controller.Reconcile(context.TODO(), newRequest())
+
+				// then
+				require.NoError(t, err)
+				testconfig.AssertThatToolchainConfig(t, test.HostOperatorNs, hostCl).
+					HasConditions(
+						toolchainconfig.ToConfigValidity(nil),
+						toolchainconfig.ToMemberConfigDrift(map[string][]string{"member2": {"spec.memberStatus.refreshPeriod"}}),
+						toolchainconfig.ToSyncComplete(),
+						toolchainconfig.ToRegServiceDeployComplete())
+				member2Cfg, err = getMemberConfig(member2)
+				require.NoError(t, err)
+				assert.Equal(t, "5s", *member2Cfg.Spec.MemberStatus.RefreshPeriod)
+
+				t.Run("drift kept until a newer sync", func(t *testing.T) {
+					// when
+					_, err = controller.Reconcile(context.TODO(), newRequest())
+
+					// then
+					require.NoError(t, err)
+					testconfig.AssertThatToolchainConfig(t, test.HostOperatorNs, hostCl).
+						HasConditions(
+							toolchainconfig.ToConfigValidity(nil),
+							toolchainconfig.ToMemberConfigDrift(map[string][]string{"member2": {"spec.memberStatus.refreshPeriod"}}),
+							toolchainconfig.ToSyncComplete(),
+							toolchainconfig.ToRegServiceDeployComplete())
+					actual := &toolchainv1alpha1.ToolchainConfig{}
+					require.NoError(t, hostCl.Get(context.TODO(), types.NamespacedName{Namespace: test.HostOperatorNs, Name: "config"}, actual))
+					drift, found := condition.FindConditionByType(actual.Status.Conditions, toolchainconfig.ToolchainConfigMemberConfigDrift)
+					require.True(t, found)
+					assert.NotNil(t, drift.LastUpdatedTime)
+
+					t.Run("no more drift after a newer sync", func(t *testing.T) {
+						// given
+						actual.Spec.Members.Default.MemberStatus.RefreshPeriod = ptr.To("7s")
+						require.NoError(t, hostCl.Update(context.TODO(), actual))
+
+						// when
+						_, err = controller.Reconcile(context.TODO(), newRequest())
+
+						// then
+						require.NoError(t, err)
+						testconfig.AssertThatToolchainConfig(t, test.HostOperatorNs, hostCl).
+							HasConditions(
+								toolchainconfig.ToConfigValidity(nil),
+								toolchainconfig.ToNoMemberConfigDrift(),
+								toolchainconfig.ToSyncComplete(),
+								toolchainconfig.ToRegServiceDeployComplete())
+						member2Cfg, err := getMemberConfig(member2)
+						require.NoError(t, err)
+						assert.Equal(t, "7s", *member2Cfg.Spec.MemberStatus.RefreshPeriod)
+					})
+				})
+			})
+		})
 	})
 
 	t.Run("failures", func(t *testing.T) {
@@ -250,6 +392,7 @@ func TestReconcile(t *testing.T) {
 			testconfig.AssertThatToolchainConfig(t, test.HostOperatorNs, hostCl).
 				Exists().
 				HasConditions(
+					toolchainconfig.ToConfigValidity(nil),
 					toolchainconfig.ToRegServiceDeployFailure("failed to apply registration service object registration-service: unable to create resource of kind: ServiceAccount, version: v1: create error")).
 				HasNoSyncErrors()
 		})
@@ -273,6 +416,7 @@ func TestReconcile(t *testing.T) {
 			testconfig.AssertThatToolchainConfig(t, test.HostOperatorNs, hostCl).
 				Exists().
 				HasConditions(
+					toolchainconfig.ToConfigValidity(nil),
 					toolchainconfig.ToSyncFailure(),
 					toolchainconfig.ToRegServiceDeploying("updated resources: [ServiceAccount: registration-service Role: registration-service RoleBinding: registration-service Deployment: registration-service Route: registration-service Service: registration-service Service: registration-service-metrics Route: api Service: api Service: proxy-metrics-service]")).
 				HasSyncErrors(map[string]string{"missing-member": "specific member configuration exists but no matching toolchaincluster was found"})
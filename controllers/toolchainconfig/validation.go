@@ -0,0 +1,404 @@
+package toolchainconfig
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/mail"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	toolchainv1alpha1 "github.com/codeready-toolchain/api/api/v1alpha1"
+	commonconfig "github.com/codeready-toolchain/toolchain-common/pkg/configuration"
+)
+
+// ValidationError is an invalid value of the ToolchainConfig
+type ValidationError struct {
+	// Field is the path of the invalid field (eg. `spec.host.notifications.durationBeforeNotificationDeletion`) or annotation
+	// (eg. `metadata.annotations[toolchain.dev.openshift.com/notification-rate-limit]`)
+	Field string
+	// Message explains why the value is invalid
+	Message string
+	// Fatal is true when the value can't be used at all (eg. a malformed duration or an unknown delivery service), as opposed to the
+	// values which may become valid later, such as a reference to a secret which doesn't exist yet. The ToolchainConfigs with fatal
+	// errors are rejected by the admission webhook.
+	Fatal bool
+}
+
+func (e ValidationError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Field, e.Message)
+}
+
+// Validate returns the invalid values of the configuration, sorted by field. The references to the secrets are only validated
+// when the configuration was loaded with its secrets.
+func (c ToolchainConfig) Validate() []ValidationError {
+	v := &validator{secrets: c.secrets}
+	v.validateSpec(c.cfg)
+	v.validateAnnotations(c)
+	sort.SliceStable(v.errors, func(i, j int) bool {
+		return v.errors[i].Field < v.errors[j].Field
+	})
+	return v.errors
+}
+
+type validator struct {
+	secrets map[string]map[string]string
+	errors  []ValidationError
+}
+
+func (v *validator) fatalf(field, format string, args ...interface{}) {
+	v.errors = append(v.errors, ValidationError{Field: field, Message: fmt.Sprintf(format, args...), Fatal: true})
+}
+
+func (v *validator) errorf(field, format string, args ...interface{}) {
+	v.errors = append(v.errors, ValidationError{Field: field, Message: fmt.Sprintf(format, args...)})
+}
+
+func (v *validator) validateSpec(cfg *toolchainv1alpha1.ToolchainConfigSpec) {
+	if cfg == nil {
+		return
+	}
+	host := cfg.Host
+
+	notifications := host.Notifications
+	deliveryService := commonconfig.GetString(notifications.NotificationDeliveryService, NotificationDeliveryServiceMailgun)
+	if deliveryService != NotificationDeliveryServiceMailgun && deliveryService != NotificationDeliveryServiceSMTP {
+		v.fatalf("spec.host.notifications.notificationDeliveryService", "unknown notification delivery service '%s' (expected '%s' or '%s')",
+			deliveryService, NotificationDeliveryServiceMailgun, NotificationDeliveryServiceSMTP)
+	}
+	v.duration("spec.host.notifications.durationBeforeNotificationDeletion", notifications.DurationBeforeNotificationDeletion, false)
+	if notifications.AdminEmail != nil {
+		v.emails("spec.host.notifications.adminEmail", *notifications.AdminEmail)
+	}
+	v.secretKeys("spec.host.notifications.secret", notifications.Secret.Ref, map[string]*string{
+		"mailgunDomain":       notifications.Secret.MailgunDomain,
+		"mailgunAPIKey":       notifications.Secret.MailgunAPIKey,
+		"mailgunSenderEmail":  notifications.Secret.MailgunSenderEmail,
+		"mailgunReplyToEmail": notifications.Secret.MailgunReplyToEmail,
+	})
+
+	v.duration("spec.host.tiers.durationBeforeChangeTierRequestDeletion", host.Tiers.DurationBeforeChangeTierRequestDeletion, false)
+	toggles := map[string]bool{}
+	for i, toggle := range host.Tiers.FeatureToggles {
+		field := fmt.Sprintf("spec.host.tiers.featureToggles[%d]", i)
+		switch {
+		case toggle.Name == "":
+			v.fatalf(field+".name", "the name is missing")
+		case toggles[toggle.Name]:
+			v.fatalf(field+".name", "duplicate feature toggle '%s'", toggle.Name)
+		}
+		toggles[toggle.Name] = true
+		if toggle.Weight != nil && *toggle.Weight > 100 {
+			v.fatalf(field+".weight", "the weight must be between 0 and 100, got %d", *toggle.Weight)
+		}
+	}
+
+	v.duration("spec.host.toolchainStatus.toolchainStatusRefreshTime", host.ToolchainStatus.ToolchainStatusRefreshTime, false)
+	v.secretKeys("spec.host.toolchainStatus.gitHubSecret", host.ToolchainStatus.GitHubSecret.Ref, map[string]*string{
+		"accessTokenKey": host.ToolchainStatus.GitHubSecret.AccessTokenKey,
+	})
+
+	if replicas := host.RegistrationService.Replicas; replicas != nil && *replicas < 0 {
+		v.fatalf("spec.host.registrationService.replicas", "the number of replicas can't be negative, got %d", *replicas)
+	}
+	verification := host.RegistrationService.Verification.Secret
+	v.secretKeys("spec.host.registrationService.verification.secret", verification.Ref, map[string]*string{
+		"twilioAccountSID":            verification.TwilioAccountSID,
+		"twilioAuthToken":             verification.TwilioAuthToken,
+		"twilioFromNumber":            verification.TwilioFromNumber,
+		"awsAccessKeyID":              verification.AWSAccessKeyID,
+		"awsSecretAccessKey":          verification.AWSSecretAccessKey,
+		"recaptchaServiceAccountFile": verification.RecaptchaServiceAccountFile,
+	})
+
+	for field, days := range map[string]*int{
+		"spec.host.deactivation.deactivatingNotificationDays":       host.Deactivation.DeactivatingNotificationDays,
+		"spec.host.deactivation.userSignupDeactivatedRetentionDays": host.Deactivation.UserSignupDeactivatedRetentionDays,
+		"spec.host.deactivation.userSignupUnverifiedRetentionDays":  host.Deactivation.UserSignupUnverifiedRetentionDays,
+	} {
+		if days != nil && *days < 0 {
+			v.fatalf(field, "the number of days can't be negative, got %d", *days)
+		}
+	}
+}
+
+// duration validates an optional duration of the spec
+func (v *validator) duration(field string, value *string, zeroAllowed bool) {
+	if value == nil {
+		return
+	}
+	v.parseDuration(field, *value, zeroAllowed)
+}
+
+func (v *validator) parseDuration(field, value string, zeroAllowed bool) {
+	d, err := time.ParseDuration(value)
+	switch {
+	case err != nil:
+		v.fatalf(field, "invalid duration '%s'", value)
+	case d < 0 || (d == 0 && !zeroAllowed):
+		v.fatalf(field, "the duration must be positive, got '%s'", value)
+	}
+}
+
+// integer validates an integer between lower and upper, or with no upper limit if upper is 0
+func (v *validator) integer(field, value string, lower, upper int) {
+	i, err := strconv.Atoi(value)
+	switch {
+	case err != nil:
+		v.fatalf(field, "invalid integer '%s'", value)
+	case upper > 0 && (i < lower || i > upper):
+		v.fatalf(field, "the value must be between %d and %d, got %d", lower, upper, i)
+	case i < lower:
+		v.fatalf(field, "the value must be at least %d, got %d", lower, i)
+	}
+}
+
+func (v *validator) emails(field, value string) {
+	if _, err := mail.ParseAddressList(value); err != nil {
+		v.fatalf(field, "invalid email address list '%s': %s", value, err.Error())
+	}
+}
+
+// strictJSON unmarshals the value into the given object, rejecting the unknown fields
+func (v *validator) strictJSON(field, value string, obj interface{}) bool {
+	decoder := json.NewDecoder(bytes.NewReader([]byte(value)))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(obj); err != nil {
+		v.fatalf(field, "invalid JSON document: %s", err.Error())
+		return false
+	}
+	return true
+}
+
+// secretKeys validates that the referenced secret exists and contains the given keys, if the secrets are known
+func (v *validator) secretKeys(field string, ref *string, keys map[string]*string) {
+	secretName := commonconfig.GetString(ref, "")
+	if v.secrets == nil {
+		return
+	}
+	if secretName == "" {
+		for name, key := range keys {
+			if key != nil && *key != "" {
+				v.errorf(field+"."+name, "key '%s' is set but no secret is referenced", *key)
+			}
+		}
+		return
+	}
+	secret, found := v.secrets[secretName]
+	if !found {
+		v.errorf(field+".ref", "secret '%s' not found", secretName)
+		return
+	}
+	for name, key := range keys {
+		if key == nil || *key == "" {
+			continue
+		}
+		if _, found := secret[*key]; !found {
+			v.errorf(field+"."+name, "key '%s' not found in secret '%s'", *key, secretName)
+		}
+	}
+}
+
+func annotationField(key string) string {
+	return fmt.Sprintf("metadata.annotations[%s]", key)
+}
+
+//go:generate go run ../../hack/gen-annotation-validators
+
+// annotationValidator validates the value of an annotation. The validators of the annotations are declared with a `+validation=`
+// marker on their keys, from which the annotationValidators registry is generated (see hack/gen-annotation-validators).
+type annotationValidator func(v *validator, c ToolchainConfig, field, value string)
+
+// validateAnnotations validates the annotations configuring the host operator, and reports the unknown ones (eg. with a typo in their key)
+func (v *validator) validateAnnotations(c ToolchainConfig) {
+	for key, value := range c.annotations {
+		if !strings.HasPrefix(key, toolchainv1alpha1.LabelKeyPrefix) {
+			continue
+		}
+		validate, found := annotationValidators[key]
+		if !found {
+			v.errorf(annotationField(key), "unknown annotation")
+			continue
+		}
+		validate(v, c, annotationField(key), value)
+	}
+}
+
+func anyAnnotation(_ *validator, _ ToolchainConfig, _, _ string) {}
+
+func boolAnnotation(v *validator, _ ToolchainConfig, field, value string) {
+	if _, err := strconv.ParseBool(value); err != nil {
+		v.fatalf(field, "invalid boolean '%s'", value)
+	}
+}
+
+func durationAnnotation(zeroAllowed bool) func(v *validator, c ToolchainConfig, field, value string) {
+	return func(v *validator, _ ToolchainConfig, field, value string) {
+		v.parseDuration(field, value, zeroAllowed)
+	}
+}
+
+func intAnnotation(lower, upper int) func(v *validator, c ToolchainConfig, field, value string) {
+	return func(v *validator, _ ToolchainConfig, field, value string) {
+		v.integer(field, value, lower, upper)
+	}
+}
+
+func smtpTLSModeAnnotation(v *validator, _ ToolchainConfig, field, value string) {
+	switch value {
+	case SMTPTLSModeStartTLS, SMTPTLSModeTLS, SMTPTLSModeNone:
+	default:
+		v.fatalf(field, "unknown TLS mode '%s' (expected '%s', '%s' or '%s')", value, SMTPTLSModeStartTLS, SMTPTLSModeTLS, SMTPTLSModeNone)
+	}
+}
+
+func notificationSecretKeyAnnotation(v *validator, c ToolchainConfig, field, value string) {
+	v.secretKeys(field, c.cfg.Host.Notifications.Secret.Ref, map[string]*string{"key": &value})
+}
+
+func notificationWebhooksAnnotation(v *validator, c ToolchainConfig, field, value string) {
+	cfg := NotificationWebhooksConfig{}
+	if !v.strictJSON(field, value, &cfg) {
+		return
+	}
+	names := map[string]bool{}
+	for i, webhook := range cfg.Webhooks {
+		switch {
+		case webhook.Name == "":
+			v.fatalf(fmt.Sprintf("%s.webhooks[%d].name", field, i), "the name is missing")
+		case names[webhook.Name]:
+			v.fatalf(fmt.Sprintf("%s.webhooks[%d].name", field, i), "duplicate webhook '%s'", webhook.Name)
+		}
+		names[webhook.Name] = true
+		if webhook.URL == "" {
+			v.fatalf(fmt.Sprintf("%s.webhooks[%d].url", field, i), "the URL is missing")
+		}
+		if webhook.SigningSecretKey != "" {
+			key := webhook.SigningSecretKey
+			v.secretKeys(fmt.Sprintf("%s.webhooks[%d]", field, i), c.cfg.Host.Notifications.Secret.Ref, map[string]*string{"signingSecretKey": &key})
+		}
+	}
+	for i, route := range cfg.Routes {
+		for _, name := range route.Webhooks {
+			if !names[name] {
+				v.fatalf(fmt.Sprintf("%s.routes[%d].webhooks", field, i), "unknown webhook '%s'", name)
+			}
+		}
+	}
+}
+
+func deduplicationTemplateWindowsAnnotation(v *validator, _ ToolchainConfig, field, value string) {
+	windows := map[string]string{}
+	if !v.strictJSON(field, value, &windows) {
+		return
+	}
+	for template, window := range windows {
+		v.parseDuration(fmt.Sprintf("%s[%s]", field, template), window, true)
+	}
+}
+
+func healthChecksAnnotation(v *validator, _ ToolchainConfig, field, value string) {
+	cfg := HealthChecksConfig{}
+	if !v.strictJSON(field, value, &cfg) {
+		return
+	}
+	names := map[string]bool{}
+	for i, check := range cfg.Checks {
+		checkField := fmt.Sprintf("%s.checks[%d]", field, i)
+		switch {
+		case check.Name == "":
+			v.fatalf(checkField+".name", "the name is missing")
+		case names[check.Name]:
+			v.fatalf(checkField+".name", "duplicate health check '%s'", check.Name)
+		}
+		names[check.Name] = true
+		switch check.Type {
+		case HealthCheckTypeHTTP:
+			if check.URL == "" {
+				v.fatalf(checkField+".url", "the URL is missing")
+			}
+		case HealthCheckTypeResource:
+			if check.APIVersion == "" || check.Kind == "" || check.ResourceName == "" {
+				v.fatalf(checkField, "the apiVersion, kind and resourceName are required")
+			}
+		case HealthCheckTypeTLSExpiry:
+			if check.Address == "" {
+				v.fatalf(checkField+".address", "the address is missing")
+			}
+			if check.MinValidity != "" {
+				v.parseDuration(checkField+".minValidity", check.MinValidity, false)
+			}
+		default:
+			v.fatalf(checkField+".type", "unknown health check type '%s'", check.Type)
+		}
+	}
+}
+
+func emailsAnnotation(v *validator, _ ToolchainConfig, field, value string) {
+	v.emails(field, value)
+}
+
+func revisionCheckAnnotation(v *validator, _ ToolchainConfig, field, value string) {
+	cfg := RevisionCheckConfig{}
+	if !v.strictJSON(field, value, &cfg) {
+		return
+	}
+	switch cfg.Source {
+	case "", RevisionCheckSourceGitHub, RevisionCheckSourceDisabled:
+	case RevisionCheckSourcePinned:
+		if len(cfg.ExpectedCommits) == 0 {
+			v.errorf(field+".expectedCommits", "no expected commit, the revision check is disabled")
+		}
+	case RevisionCheckSourceHTTP:
+		if cfg.URL == "" {
+			v.fatalf(field+".url", "the URL is missing")
+		}
+	default:
+		v.fatalf(field+".source", "unknown revision check source '%s'", cfg.Source)
+	}
+}
+
+func featureToggleTargetingAnnotation(v *validator, c ToolchainConfig, field, value string) {
+	targeting := map[string]FeatureToggleTargeting{}
+	if !v.strictJSON(field, value, &targeting) {
+		return
+	}
+	toggles := map[string]bool{}
+	for _, toggle := range c.cfg.Host.Tiers.FeatureToggles {
+		toggles[toggle.Name] = true
+	}
+	for name := range targeting {
+		if !toggles[name] {
+			v.errorf(fmt.Sprintf("%s[%s]", field, name), "unknown feature toggle '%s'", name)
+		}
+	}
+}
+
+func secretSourcesAnnotation(v *validator, _ ToolchainConfig, field, value string) {
+	sources := SecretSourcesConfig{}
+	if !v.strictJSON(field, value, &sources) {
+		return
+	}
+	for name, source := range sources {
+		sourceField := fmt.Sprintf("%s[%s]", field, name)
+		switch source.Type {
+		case "", SecretSourceSecret:
+		case SecretSourceFile:
+			if source.Path == "" {
+				v.fatalf(sourceField+".path", "the path is missing")
+			}
+			if source.RefreshInterval != "" {
+				v.parseDuration(sourceField+".refreshInterval", source.RefreshInterval, true)
+			}
+		case SecretSourceEnv:
+			if len(source.Keys) == 0 {
+				v.errorf(sourceField+".keys", "no key, the secret is empty")
+			}
+		default:
+			v.fatalf(sourceField+".type", "unknown secret source '%s' (expected '%s', '%s' or '%s')",
+				source.Type, SecretSourceSecret, SecretSourceFile, SecretSourceEnv)
+		}
+	}
+}
@@ -0,0 +1,174 @@
+package toolchainconfig
+
+import (
+	"testing"
+
+	hostconfig "github.com/codeready-toolchain/host-operator/test/config"
+	commonconfig "github.com/codeready-toolchain/toolchain-common/pkg/configuration"
+	testconfig "github.com/codeready-toolchain/toolchain-common/pkg/test/config"
+
+	"github.com/stretchr/testify/assert"
+	"k8s.io/utils/ptr"
+)
+
+func TestValidate(t *testing.T) {
+	annotation := func(key string) string {
+		return "metadata.annotations[" + key + "]"
+	}
+
+	t.Run("valid", func(t *testing.T) {
+		// given
+		cfg := commonconfig.NewToolchainConfigObjWithReset(t,
+			testconfig.Notifications().DurationBeforeNotificationDeletion("48h"),
+			testconfig.Notifications().NotificationDeliveryService(NotificationDeliveryServiceSMTP),
+			testconfig.Notifications().AdminEmail("admin@acme.com"),
+			testconfig.Notifications().Secret().Ref("notification-secret").MailgunSenderEmail("senderEmail"),
+			testconfig.ToolchainStatus().ToolchainStatusRefreshTime("10s"),
+			testconfig.Tiers().FeatureToggle("feature-1", nil).FeatureToggle("feature-2", ptr.To[uint](50)),
+			hostconfig.Annotation(NotificationSMTPPortAnnotationKey, "465"),
+			hostconfig.Annotation(NotificationSMTPTLSModeAnnotationKey, SMTPTLSModeTLS),
+			hostconfig.Annotation(NotificationSMTPPasswordKeyAnnotationKey, "password"),
+			hostconfig.Annotation(NotificationDeduplicationWindowAnnotationKey, "0s"),
+			hostconfig.Annotation(NotificationDeduplicationTemplateWindowsAnnotationKey, `{"userdeactivating": "24h"}`),
+			hostconfig.Annotation(ToolchainStatusUnreadyAlertEscalationEmailAnnotationKey, "oncall@acme.com, lead@acme.com"),
+			hostconfig.Annotation(NotificationWebhooksAnnotationKey, `{"webhooks":[{"name":"slack","url":"https://hooks.acme.com"}],
+				"routes":[{"notificationTypes":["capacity"],"webhooks":["slack"]}]}`),
+			hostconfig.Annotation(ToolchainStatusRevisionCheckAnnotationKey, `{"source":"disabled"}`),
+			hostconfig.Annotation(FeatureToggleTargetingAnnotationKey, `{"feature-2": {"emailDomains": ["acme.com"]}}`),
+			hostconfig.Annotation(FeatureToggleReevaluationEnabledAnnotationKey, "true"),
+			hostconfig.Annotation(MemberClientCircuitBreakerFailureThresholdAnnotationKey, "0"),
+			hostconfig.Annotation(MemberClientCircuitBreakerOpenDurationAnnotationKey, "30s"))
+		secrets := map[string]map[string]string{
+			"notification-secret": {"senderEmail": "noreply@acme.com", "password": "s3cr3t"},
+		}
+
+		// when
+		validationErrs := newToolchainConfig(cfg, secrets).Validate()
+
+		// then
+		assert.Empty(t, validationErrs)
+	})
+
+	t.Run("default", func(t *testing.T) {
+		// when
+		validationErrs := newToolchainConfig(nil, nil).Validate()
+
+		// then
+		assert.Empty(t, validationErrs)
+	})
+
+	t.Run("invalid spec", func(t *testing.T) {
+		// given
+		cfg := commonconfig.NewToolchainConfigObjWithReset(t,
+			testconfig.Notifications().DurationBeforeNotificationDeletion("banana"),
+			testconfig.Notifications().NotificationDeliveryService("pigeon"),
+			testconfig.Notifications().AdminEmail("admin"),
+			testconfig.ToolchainStatus().ToolchainStatusRefreshTime("-5s"),
+			testconfig.Tiers().DurationBeforeChangeTierRequestDeletion("1d").
+				FeatureToggle("feature-1", nil).FeatureToggle("feature-1", ptr.To[uint](150)),
+			testconfig.RegistrationService().Replicas(-1),
+			testconfig.Deactivation().DeactivatingNotificationDays(-3))
+
+		// when
+		validationErrs := newToolchainConfig(cfg, nil).Validate()
+
+		// then
+		assert.Equal(t, []ValidationError{
+			{Field: "spec.host.deactivation.deactivatingNotificationDays", Message: "the number of days can't be negative, got -3", Fatal: true},
+			{Field: "spec.host.notifications.adminEmail", Message: "invalid email address list 'admin': mail: missing '@' or angle-addr", Fatal: true},
+			{Field: "spec.host.notifications.durationBeforeNotificationDeletion", Message: "invalid duration 'banana'", Fatal: true},
+			{Field: "spec.host.notifications.notificationDeliveryService", Message: "unknown notification delivery service 'pigeon' (expected 'mailgun' or 'smtp')", Fatal: true},
+			{Field: "spec.host.registrationService.replicas", Message: "the number of replicas can't be negative, got -1", Fatal: true},
+			{Field: "spec.host.tiers.durationBeforeChangeTierRequestDeletion", Message: "invalid duration '1d'", Fatal: true},
+			{Field: "spec.host.tiers.featureToggles[1].name", Message: "duplicate feature toggle 'feature-1'", Fatal: true},
+			{Field: "spec.host.tiers.featureToggles[1].weight", Message: "the weight must be between 0 and 100, got 150", Fatal: true},
+			{Field: "spec.host.toolchainStatus.toolchainStatusRefreshTime", Message: "the duration must be positive, got '-5s'", Fatal: true},
+		}, validationErrs)
+	})
+
+	t.Run("invalid annotations", func(t *testing.T) {
+		// given
+		cfg := commonconfig.NewToolchainConfigObjWithReset(t,
+			hostconfig.Annotation(SpaceQuarantinePeriodAnnotationKey, "banana"),
+			hostconfig.Annotation(SpaceHibernationEnabledAnnotationKey, "yes please"),
+			hostconfig.Annotation(NotificationSMTPPortAnnotationKey, "100000"),
+			hostconfig.Annotation(NotificationSMTPTLSModeAnnotationKey, "ssl"),
+			hostconfig.Annotation(NotificationDeliveryMaxAttemptsAnnotationKey, "0"),
+			hostconfig.Annotation(NotificationDeliveryInitialBackoffAnnotationKey, "0s"),
+			hostconfig.Annotation(NotificationDeduplicationTemplateWindowsAnnotationKey, `{"userdeactivating": "1d"}`),
+			hostconfig.Annotation(NotificationRateLimitAnnotationKey, "ten"),
+			hostconfig.Annotation(CapacityAlertSpaceThresholdAnnotationKey, "120"),
+			hostconfig.Annotation(NotificationWebhooksAnnotationKey, `{"webhooks":[{"name":"slack"}],"routes":[{"webhooks":["teams"]}]}`),
+			hostconfig.Annotation(ToolchainStatusHealthChecksAnnotationKey, `{"checks":[{"name":"sso","type":"ping"},{"name":"sso","type":"http","url":"https://sso"}]}`),
+			hostconfig.Annotation(ToolchainStatusRevisionCheckAnnotationKey, `{"source":"http","uri":"https://revisions"}`),
+			hostconfig.Annotation(FeatureToggleTargetingAnnotationKey, `{"feature-3": {"tiers": ["base"]}}`),
+			hostconfig.Annotation(SecretSourcesAnnotationKey, `{"github":{"type":"vault"},"mailgun":{"type":"file","refreshInterval":"-1s"},"twilio":{"type":"env"}}`),
+			hostconfig.Annotation(toolchainPrefixed("capacity-alert-space-treshold"), "80"),
+			hostconfig.Annotation("example.com/unrelated", "foo"))
+
+		// when
+		validationErrs := newToolchainConfig(cfg, nil).Validate()
+
+		// then
+		assert.Equal(t, []ValidationError{
+			{Field: annotation(CapacityAlertSpaceThresholdAnnotationKey), Message: "the value must be between 0 and 100, got 120", Fatal: true},
+			{Field: annotation(toolchainPrefixed("capacity-alert-space-treshold")), Message: "unknown annotation"},
+			{Field: annotation(FeatureToggleTargetingAnnotationKey) + "[feature-3]", Message: "unknown feature toggle 'feature-3'"},
+			{Field: annotation(NotificationDeduplicationTemplateWindowsAnnotationKey) + "[userdeactivating]", Message: "invalid duration '1d'", Fatal: true},
+			{Field: annotation(NotificationDeliveryInitialBackoffAnnotationKey), Message: "the duration must be positive, got '0s'", Fatal: true},
+			{Field: annotation(NotificationDeliveryMaxAttemptsAnnotationKey), Message: "the value must be at least 1, got 0", Fatal: true},
+			{Field: annotation(NotificationRateLimitAnnotationKey), Message: "invalid integer 'ten'", Fatal: true},
+			{Field: annotation(NotificationSMTPPortAnnotationKey), Message: "the value must be between 1 and 65535, got 100000", Fatal: true},
+			{Field: annotation(NotificationSMTPTLSModeAnnotationKey), Message: "unknown TLS mode 'ssl' (expected 'starttls', 'tls' or 'none')", Fatal: true},
+			{Field: annotation(NotificationWebhooksAnnotationKey) + ".routes[0].webhooks", Message: "unknown webhook 'teams'", Fatal: true},
+			{Field: annotation(NotificationWebhooksAnnotationKey) + ".webhooks[0].url", Message: "the URL is missing", Fatal: true},
+			{Field: annotation(SecretSourcesAnnotationKey) + "[github].type", Message: "unknown secret source 'vault' (expected 'secret', 'file' or 'env')", Fatal: true},
+			{Field: annotation(SecretSourcesAnnotationKey) + "[mailgun].path", Message: "the path is missing", Fatal: true},
+			{Field: annotation(SecretSourcesAnnotationKey) + "[mailgun].refreshInterval", Message: "the duration must be positive, got '-1s'", Fatal: true},
+			{Field: annotation(SecretSourcesAnnotationKey) + "[twilio].keys", Message: "no key, the secret is empty"},
+			{Field: annotation(SpaceHibernationEnabledAnnotationKey), Message: "invalid boolean 'yes please'", Fatal: true},
+			{Field: annotation(SpaceQuarantinePeriodAnnotationKey), Message: "invalid duration 'banana'", Fatal: true},
+			{Field: annotation(ToolchainStatusHealthChecksAnnotationKey) + ".checks[0].type", Message: "unknown health check type 'ping'", Fatal: true},
+			{Field: annotation(ToolchainStatusHealthChecksAnnotationKey) + ".checks[1].name", Message: "duplicate health check 'sso'", Fatal: true},
+			{Field: annotation(ToolchainStatusRevisionCheckAnnotationKey), Message: `invalid JSON document: json: unknown field "uri"`, Fatal: true},
+		}, validationErrs)
+	})
+
+	t.Run("secrets", func(t *testing.T) {
+		// given
+		cfg := commonconfig.NewToolchainConfigObjWithReset(t,
+			testconfig.Notifications().Secret().Ref("notification-secret").MailgunDomain("domain").MailgunAPIKey("apiKey"),
+			testconfig.ToolchainStatus().GitHubSecretRef("github").GitHubSecretAccessTokenKey("accessToken"),
+			testconfig.RegistrationService().Verification().Secret().TwilioAuthToken("twilioAuthToken"),
+			hostconfig.Annotation(NotificationDeliveryEventsSigningKeyKeyAnnotationKey, "signingKey"),
+			hostconfig.Annotation(NotificationWebhooksAnnotationKey, `{"webhooks":[{"name":"slack","url":"https://hooks.acme.com","signingSecretKey":"slackKey"}]}`))
+		secrets := map[string]map[string]string{
+			"notification-secret": {"domain": "acme.com", "slackKey": "s3cr3t"},
+		}
+
+		t.Run("validated when known", func(t *testing.T) {
+			// when
+			validationErrs := newToolchainConfig(cfg, secrets).Validate()
+
+			// then
+			assert.Equal(t, []ValidationError{
+				{Field: annotation(NotificationDeliveryEventsSigningKeyKeyAnnotationKey) + ".key", Message: "key 'signingKey' not found in secret 'notification-secret'"},
+				{Field: "spec.host.notifications.secret.mailgunAPIKey", Message: "key 'apiKey' not found in secret 'notification-secret'"},
+				{Field: "spec.host.registrationService.verification.secret.twilioAuthToken", Message: "key 'twilioAuthToken' is set but no secret is referenced"},
+				{Field: "spec.host.toolchainStatus.gitHubSecret.ref", Message: "secret 'github' not found"},
+			}, validationErrs)
+		})
+
+		t.Run("ignored when unknown", func(t *testing.T) {
+			// when
+			validationErrs := newToolchainConfig(cfg, nil).Validate()
+
+			// then
+			assert.Empty(t, validationErrs)
+		})
+	})
+}
+
+func toolchainPrefixed(key string) string {
+	return "toolchain.dev.openshift.com/" + key
+}
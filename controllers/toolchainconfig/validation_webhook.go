@@ -0,0 +1,46 @@
+package toolchainconfig
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	toolchainv1alpha1 "github.com/codeready-toolchain/api/api/v1alpha1"
+
+	admissionv1 "k8s.io/api/admission/v1"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+// ValidationWebhookPath is the path of the admission webhook which validates the ToolchainConfigs
+const ValidationWebhookPath = "/validate-toolchainconfig"
+
+// ValidationWebhook is an admission handler which rejects the ToolchainConfigs with fatal validation errors (see ValidationError).
+// The other validation errors are returned as warnings: the secrets are not known here, and the errors are reported in the
+// ConfigValid condition by the controller anyway.
+type ValidationWebhook struct {
+	Decoder admission.Decoder
+}
+
+// Handle validates the ToolchainConfig of the admission request
+func (w *ValidationWebhook) Handle(_ context.Context, req admission.Request) admission.Response {
+	if req.Operation == admissionv1.Delete {
+		return admission.Allowed("")
+	}
+	config := &toolchainv1alpha1.ToolchainConfig{}
+	if err := w.Decoder.Decode(req, config); err != nil {
+		return admission.Errored(http.StatusBadRequest, err)
+	}
+
+	var fatal, warnings []string
+	for _, e := range newToolchainConfig(config, nil).Validate() {
+		if e.Fatal {
+			fatal = append(fatal, e.Error())
+		} else {
+			warnings = append(warnings, e.Error())
+		}
+	}
+	if len(fatal) > 0 {
+		return admission.Denied("invalid ToolchainConfig: " + strings.Join(fatal, "; ")).WithWarnings(warnings...)
+	}
+	return admission.Allowed("").WithWarnings(warnings...)
+}
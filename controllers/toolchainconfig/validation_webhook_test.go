@@ -0,0 +1,104 @@
+package toolchainconfig
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	toolchainv1alpha1 "github.com/codeready-toolchain/api/api/v1alpha1"
+	hostconfig "github.com/codeready-toolchain/host-operator/test/config"
+	commonconfig "github.com/codeready-toolchain/toolchain-common/pkg/configuration"
+	testconfig "github.com/codeready-toolchain/toolchain-common/pkg/test/config"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	admissionv1 "k8s.io/api/admission/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+func TestValidationWebhook(t *testing.T) {
+	// given
+	webhook := &ValidationWebhook{Decoder: admission.NewDecoder(scheme.Scheme)}
+
+	t.Run("valid config is allowed", func(t *testing.T) {
+		// given
+		cfg := commonconfig.NewToolchainConfigObjWithReset(t, testconfig.Notifications().DurationBeforeNotificationDeletion("48h"))
+
+		// when
+		resp := webhook.Handle(context.TODO(), admissionRequest(t, admissionv1.Create, cfg))
+
+		// then
+		assert.True(t, resp.Allowed)
+		assert.Empty(t, resp.Warnings)
+	})
+
+	t.Run("config with fatal errors is denied", func(t *testing.T) {
+		// given
+		cfg := commonconfig.NewToolchainConfigObjWithReset(t,
+			testconfig.Notifications().DurationBeforeNotificationDeletion("banana"),
+			testconfig.Notifications().NotificationDeliveryService("pigeon"),
+			hostconfig.Annotation(toolchainPrefixed("unknown"), "foo"))
+
+		// when
+		resp := webhook.Handle(context.TODO(), admissionRequest(t, admissionv1.Update, cfg))
+
+		// then
+		assert.False(t, resp.Allowed)
+		assert.Equal(t, int32(http.StatusForbidden), resp.Result.Code)
+		assert.Equal(t, "invalid ToolchainConfig: spec.host.notifications.durationBeforeNotificationDeletion: invalid duration 'banana'; "+
+			"spec.host.notifications.notificationDeliveryService: unknown notification delivery service 'pigeon' (expected 'mailgun' or 'smtp')",
+			resp.Result.Message)
+		assert.Equal(t, []string{"metadata.annotations[toolchain.dev.openshift.com/unknown]: unknown annotation"}, resp.Warnings)
+	})
+
+	t.Run("config with non-fatal errors is allowed with warnings", func(t *testing.T) {
+		// given
+		cfg := commonconfig.NewToolchainConfigObjWithReset(t,
+			hostconfig.Annotation(ToolchainStatusRevisionCheckAnnotationKey, `{"source":"pinned"}`))
+
+		// when
+		resp := webhook.Handle(context.TODO(), admissionRequest(t, admissionv1.Create, cfg))
+
+		// then
+		assert.True(t, resp.Allowed)
+		assert.Equal(t, []string{"metadata.annotations[toolchain.dev.openshift.com/toolchainstatus-revision-check].expectedCommits: " +
+			"no expected commit, the revision check is disabled"}, resp.Warnings)
+	})
+
+	t.Run("deletion is allowed", func(t *testing.T) {
+		// when
+		resp := webhook.Handle(context.TODO(), admission.Request{AdmissionRequest: admissionv1.AdmissionRequest{Operation: admissionv1.Delete}})
+
+		// then
+		assert.True(t, resp.Allowed)
+	})
+
+	t.Run("invalid object", func(t *testing.T) {
+		// given
+		req := admission.Request{AdmissionRequest: admissionv1.AdmissionRequest{
+			Operation: admissionv1.Create,
+			Object:    runtime.RawExtension{Raw: []byte("banana")},
+		}}
+
+		// when
+		resp := webhook.Handle(context.TODO(), req)
+
+		// then
+		assert.False(t, resp.Allowed)
+		assert.Equal(t, int32(http.StatusBadRequest), resp.Result.Code)
+	})
+}
+
+func admissionRequest(t *testing.T, operation admissionv1.Operation, cfg *toolchainv1alpha1.ToolchainConfig) admission.Request {
+	cfg.APIVersion = toolchainv1alpha1.GroupVersion.String()
+	cfg.Kind = "ToolchainConfig"
+	raw, err := json.Marshal(cfg)
+	require.NoError(t, err)
+	return admission.Request{AdmissionRequest: admissionv1.AdmissionRequest{
+		Operation: operation,
+		Object:    runtime.RawExtension{Raw: raw},
+	}}
+}
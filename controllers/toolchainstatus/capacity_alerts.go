@@ -0,0 +1,342 @@
+package toolchainstatus
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	toolchainv1alpha1 "github.com/codeready-toolchain/api/api/v1alpha1"
+	"github.com/codeready-toolchain/host-operator/controllers/toolchainconfig"
+	"github.com/codeready-toolchain/toolchain-common/pkg/cluster"
+	"github.com/codeready-toolchain/toolchain-common/pkg/condition"
+	notify "github.com/codeready-toolchain/toolchain-common/pkg/notification"
+
+	errs "github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	runtimeclient "sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+const (
+	// CapacityAlertsAnnotationKey is the ToolchainStatus annotation which contains the capacity alerts which are currently raised,
+	// so that the "resolved" notification can be sent when they are resolved
+	CapacityAlertsAnnotationKey = toolchainv1alpha1.LabelKeyPrefix + "capacity-alerts"
+
+	// NotificationTypeCapacityAlert is the type of the admin notification sent when a capacity alert is raised
+	NotificationTypeCapacityAlert = "capacity-alert"
+	// NotificationTypeCapacityAlertResolved is the type of the admin notification sent when a capacity alert is resolved
+	NotificationTypeCapacityAlertResolved = "capacity-alert-resolved"
+
+	adminCapacityAlertNotificationSubject         = "Capacity alert for %v: %s"
+	adminCapacityAlertResolvedNotificationSubject = "Capacity alert resolved for %v: %s"
+
+	// durationBeforeNoClustersAvailableResolved is how long at least one tenant cluster must be available again before the
+	// "no clusters available" alert is resolved
+	durationBeforeNoClustersAvailableResolved = 5 * time.Minute
+)
+
+// capacityAlert is the evaluation of a capacity alert
+type capacityAlert struct {
+	// name identifies the alert, eg. `spaces-member-1`
+	name string
+	// raised is true when the monitored value crossed the threshold
+	raised bool
+	// cleared is true when the monitored value went back below the threshold, minus the hysteresis
+	cleared bool
+	// resolveDelay is for how long the alert must be cleared before it's resolved
+	resolveDelay time.Duration
+	// message describes the alert
+	message string
+	// resolvedMessage describes the resolution of the alert
+	resolvedMessage string
+}
+
+// capacityAlertState is the state of a raised alert, stored in the CapacityAlertsAnnotationKey annotation of the ToolchainStatus
+type capacityAlertState struct {
+	// RaisedAt is when the alert was raised
+	RaisedAt metav1.Time `json:"raisedAt"`
+	// ClearedAt is when the alert was cleared, if it's waiting for its resolve delay
+	ClearedAt *metav1.Time `json:"clearedAt,omitempty"`
+	// ResolvedAt is when the alert was resolved, if the "resolved" notification was not created yet
+	ResolvedAt *metav1.Time `json:"resolvedAt,omitempty"`
+	// NotificationPending is true until the notification of the raised alert is created
+	NotificationPending bool `json:"notificationPending,omitempty"`
+}
+
+// capacityAlertsCheck evaluates the capacity alerts (member clusters running out of space or memory, no clusters available for the new
+// Spaces, too many UserSignups pending approval) and sends a notification to the admins when an alert is raised or resolved
+func (r *Reconciler) capacityAlertsCheck(ctx context.Context, toolchainStatus *toolchainv1alpha1.ToolchainStatus) error {
+	logger := log.FromContext(ctx)
+	config, err := toolchainconfig.GetToolchainConfig(r.Client)
+	if err != nil {
+		return errs.Wrapf(err, "unable to get ToolchainConfig")
+	}
+	if config.Notifications().AdminEmail() == "" {
+		// nobody to alert
+		return nil
+	}
+	alerts, err := r.evaluateCapacityAlerts(ctx, config.ToolchainStatus(), toolchainStatus)
+	if err != nil {
+		return err
+	}
+
+	states := map[string]capacityAlertState{}
+	if v, found := toolchainStatus.Annotations[CapacityAlertsAnnotationKey]; found {
+		if err := json.Unmarshal([]byte(v), &states); err != nil {
+			logger.Error(err, "ignoring the invalid state of the capacity alerts", "state", v)
+		}
+	}
+	previousStates := make(map[string]capacityAlertState, len(states))
+	for name, state := range states {
+		previousStates[name] = state
+	}
+
+	evaluated := map[string]bool{}
+	for _, alert := range alerts {
+		evaluated[alert.name] = true
+		state, active := states[alert.name]
+		switch {
+		case !active && alert.raised:
+			states[alert.name] = capacityAlertState{RaisedAt: metav1.Now(), NotificationPending: true}
+		case active && state.ResolvedAt != nil:
+			// waiting for the "resolved" notification to be created
+		case active && alert.cleared:
+			if state.ClearedAt == nil {
+				now := metav1.Now()
+				state.ClearedAt = &now
+			}
+			if time.Since(state.ClearedAt.Time) >= alert.resolveDelay {
+				now := metav1.Now()
+				state.ResolvedAt = &now
+			}
+			states[alert.name] = state
+		case active:
+			// still raised, or within the hysteresis
+			state.ClearedAt = nil
+			states[alert.name] = state
+		}
+	}
+	for name := range states {
+		if !evaluated[name] {
+			// eg. the cluster was removed, or the alert was disabled
+			logger.Info("dropping the capacity alert which is not monitored anymore", "alert", name)
+			delete(states, name)
+		}
+	}
+
+	// the state is stored before the notifications are created, so that a failed update of the ToolchainStatus does not
+	// lead to the same notifications being created again at the next check
+	if err := r.updateCapacityAlertsState(ctx, toolchainStatus, previousStates, states); err != nil {
+		return err
+	}
+	previousStates = make(map[string]capacityAlertState, len(states))
+	for name, state := range states {
+		previousStates[name] = state
+	}
+	for _, alert := range alerts {
+		state, active := states[alert.name]
+		switch {
+		case !active:
+			continue
+		case state.ResolvedAt != nil:
+			// no need to resolve an alert which was never sent
+			if !state.NotificationPending {
+				if err := r.sendCapacityAlertNotification(ctx, config, toolchainStatus, NotificationTypeCapacityAlertResolved, alert.name, state, alert.resolvedMessage); err != nil {
+					return err
+				}
+			}
+			delete(states, alert.name)
+		case state.NotificationPending:
+			if err := r.sendCapacityAlertNotification(ctx, config, toolchainStatus, NotificationTypeCapacityAlert, alert.name, state, alert.message); err != nil {
+				return err
+			}
+			state.NotificationPending = false
+			states[alert.name] = state
+		}
+	}
+	return r.updateCapacityAlertsState(ctx, toolchainStatus, previousStates, states)
+}
+
+// evaluateCapacityAlerts returns the capacity alerts, with their current state
+func (r *Reconciler) evaluateCapacityAlerts(ctx context.Context, config toolchainconfig.ToolchainStatusConfig,
+	toolchainStatus *toolchainv1alpha1.ToolchainStatus) ([]capacityAlert, error) {
+	hysteresis := config.CapacityAlertHysteresis()
+	var alerts []capacityAlert
+
+	spcs := &toolchainv1alpha1.SpaceProvisionerConfigList{}
+	if err := r.Client.List(ctx, spcs, runtimeclient.InNamespace(toolchainStatus.Namespace)); err != nil {
+		return nil, errs.Wrap(err, "unable to list the SpaceProvisionerConfigs")
+	}
+	tenantClusters := 0
+	availableTenantClusters := 0
+	for _, spc := range spcs.Items {
+		if hasTenantRole(spc) {
+			tenantClusters++
+			if condition.IsTrue(spc.Status.Conditions, toolchainv1alpha1.ConditionReady) {
+				availableTenantClusters++
+			}
+		}
+		member := findMember(toolchainStatus, spc.Spec.ToolchainCluster)
+		if member == nil {
+			continue
+		}
+		if threshold := config.CapacityAlertSpaceThreshold(); threshold > 0 && spc.Spec.CapacityThresholds.MaxNumberOfSpaces > 0 {
+			maxSpaces := int(spc.Spec.CapacityThresholds.MaxNumberOfSpaces) // nolint:gosec
+			usage := member.SpaceCount * 100 / maxSpaces
+			alerts = append(alerts, capacityAlert{
+				name:    "spaces-" + member.ClusterName,
+				raised:  usage >= threshold,
+				cleared: usage < threshold-hysteresis,
+				message: fmt.Sprintf("the member cluster '%s' reached %d%% of its maximum number of Spaces (%d/%d)",
+					member.ClusterName, usage, member.SpaceCount, maxSpaces),
+				resolvedMessage: fmt.Sprintf("the number of Spaces in the member cluster '%s' is back below %d%% of its maximum (%d/%d)",
+					member.ClusterName, threshold, member.SpaceCount, maxSpaces),
+			})
+		}
+		if threshold := int(spc.Spec.CapacityThresholds.MaxMemoryUtilizationPercent); threshold > 0 && len(member.MemberStatus.ResourceUsage.MemoryUsagePerNodeRole) > 0 { // nolint:gosec
+			usage, roles := maxMemoryUsage(member.MemberStatus.ResourceUsage.MemoryUsagePerNodeRole)
+			alerts = append(alerts, capacityAlert{
+				name:    "memory-" + member.ClusterName,
+				raised:  usage >= threshold,
+				cleared: usage < threshold-hysteresis,
+				message: fmt.Sprintf("the memory usage of the %s nodes of the member cluster '%s' reached its threshold of %d%% (%d%%)",
+					roles, member.ClusterName, threshold, usage),
+				resolvedMessage: fmt.Sprintf("the memory usage of the member cluster '%s' is back below its threshold of %d%% (%d%%)",
+					member.ClusterName, threshold, usage),
+			})
+		}
+	}
+
+	if tenantClusters > 0 {
+		alerts = append(alerts, capacityAlert{
+			name:            "no-clusters-available",
+			raised:          availableTenantClusters == 0,
+			cleared:         availableTenantClusters > 0,
+			resolveDelay:    durationBeforeNoClustersAvailableResolved,
+			message:         fmt.Sprintf("none of the %d tenant clusters is available for the new Spaces", tenantClusters),
+			resolvedMessage: fmt.Sprintf("%d of the %d tenant clusters are available again for the new Spaces", availableTenantClusters, tenantClusters),
+		})
+	}
+
+	if threshold := config.CapacityAlertPendingUserSignupsThreshold(); threshold > 0 {
+		pending := &toolchainv1alpha1.UserSignupList{}
+		if err := r.Client.List(ctx, pending, runtimeclient.InNamespace(toolchainStatus.Namespace),
+			runtimeclient.MatchingLabels{toolchainv1alpha1.UserSignupStateLabelKey: toolchainv1alpha1.UserSignupStateLabelValuePending}); err != nil {
+			return nil, errs.Wrap(err, "unable to list the UserSignups pending approval")
+		}
+		count := len(pending.Items)
+		alerts = append(alerts, capacityAlert{
+			name:    "pending-usersignups",
+			raised:  count >= threshold,
+			cleared: count*100 < threshold*(100-hysteresis),
+			message: fmt.Sprintf("%d UserSignups are pending approval (threshold: %d)", count, threshold),
+			resolvedMessage: fmt.Sprintf("the number of UserSignups pending approval is back below %d (%d)",
+				threshold, count),
+		})
+	}
+	return alerts, nil
+}
+
+func hasTenantRole(spc toolchainv1alpha1.SpaceProvisionerConfig) bool {
+	for _, role := range spc.Spec.PlacementRoles {
+		if role == cluster.RoleLabel(cluster.Tenant) {
+			return true
+		}
+	}
+	return false
+}
+
+func findMember(toolchainStatus *toolchainv1alpha1.ToolchainStatus, clusterName string) *toolchainv1alpha1.Member {
+	for i := range toolchainStatus.Status.Members {
+		if toolchainStatus.Status.Members[i].ClusterName == clusterName {
+			return &toolchainStatus.Status.Members[i]
+		}
+	}
+	return nil
+}
+
+// maxMemoryUsage returns the highest memory usage of the given node roles, and the roles which have this usage
+func maxMemoryUsage(usagePerNodeRole map[string]int) (int, string) {
+	usage := 0
+	var roles []string
+	for role, v := range usagePerNodeRole {
+		switch {
+		case v > usage:
+			usage = v
+			roles = []string{role}
+		case v == usage:
+			roles = append(roles, role)
+		}
+	}
+	sort.Strings(roles)
+	return usage, strings.Join(roles, "/")
+}
+
+// sendCapacityAlertNotification creates the notification of the given type for the given alert. The name of the notification is
+// derived from the time the alert was raised, so that the notification is created only once per occurrence of the alert.
+func (r *Reconciler) sendCapacityAlertNotification(ctx context.Context, config toolchainconfig.ToolchainConfig,
+	toolchainStatus *toolchainv1alpha1.ToolchainStatus, notificationType, alertName string, state capacityAlertState, message string) error {
+	logger := log.FromContext(ctx)
+	domain, err := removeSchemeFromURL(toolchainStatus.Status.HostRoutes.ProxyURL)
+	if err != nil {
+		logger.Error(err, fmt.Sprintf("Error while parsing proxyUrl %v", toolchainStatus.Status.HostRoutes.ProxyURL))
+	}
+	subject := fmt.Sprintf(adminCapacityAlertNotificationSubject, domain, message)
+	if notificationType == NotificationTypeCapacityAlertResolved {
+		subject = fmt.Sprintf(adminCapacityAlertResolvedNotificationSubject, domain, message)
+	}
+
+	notification, err := notify.NewNotificationBuilder(r.Client, toolchainStatus.Namespace).
+		WithName(fmt.Sprintf("%s-%s-%s", notificationType, alertName, state.RaisedAt.UTC().Format("20060102150405"))).
+		WithControllerReference(toolchainStatus, r.Scheme).
+		WithSubjectAndContent(subject, fmt.Sprintf("<div><pre>%s</pre></div>", message)).
+		WithNotificationType(notificationType).
+		Create(ctx, config.Notifications().AdminEmail())
+	if err != nil {
+		if errors.IsAlreadyExists(err) {
+			logger.Info("capacity alert notification already created", "notification", notification.Name)
+			return nil
+		}
+		return errs.Wrapf(err, "unable to create the %s notification", notificationType)
+	}
+	logger.Info("capacity alert notification created", "notification", notification.Name, "message", message)
+	return nil
+}
+
+// updateCapacityAlertsState stores the state of the capacity alerts in the annotation of the ToolchainStatus, if it changed.
+// Only the annotation is patched, so that the state can't conflict with the other changes of the ToolchainStatus.
+func (r *Reconciler) updateCapacityAlertsState(ctx context.Context, toolchainStatus *toolchainv1alpha1.ToolchainStatus,
+	previous, current map[string]capacityAlertState) error {
+	previousValue, err := json.Marshal(previous)
+	if err != nil {
+		return err
+	}
+	currentValue, err := json.Marshal(current)
+	if err != nil {
+		return err
+	}
+	if string(previousValue) == string(currentValue) {
+		return nil
+	}
+	var annotation interface{} // a null value removes the annotation
+	if len(current) > 0 {
+		annotation = string(currentValue)
+	}
+	patch, err := json.Marshal(map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"annotations": map[string]interface{}{
+				CapacityAlertsAnnotationKey: annotation,
+			},
+		},
+	})
+	if err != nil {
+		return err
+	}
+	return errs.Wrap(r.Client.Patch(ctx, toolchainStatus, runtimeclient.RawPatch(types.MergePatchType, patch)),
+		"unable to update the state of the capacity alerts")
+}
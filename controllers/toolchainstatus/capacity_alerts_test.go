@@ -0,0 +1,430 @@
+package toolchainstatus
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	toolchainv1alpha1 "github.com/codeready-toolchain/api/api/v1alpha1"
+	"github.com/codeready-toolchain/host-operator/controllers/toolchainconfig"
+	"github.com/codeready-toolchain/host-operator/pkg/apis"
+	. "github.com/codeready-toolchain/host-operator/test"
+	hostconfig "github.com/codeready-toolchain/host-operator/test/config"
+	tspc "github.com/codeready-toolchain/host-operator/test/spaceprovisionerconfig"
+	commonconfig "github.com/codeready-toolchain/toolchain-common/pkg/configuration"
+	"github.com/codeready-toolchain/toolchain-common/pkg/test"
+	testconfig "github.com/codeready-toolchain/toolchain-common/pkg/test/config"
+	. "github.com/codeready-toolchain/toolchain-common/pkg/test/spaceprovisionerconfig"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/scheme"
+	runtimeclient "sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func TestCapacityAlerts(t *testing.T) {
+	restore := test.SetEnvVarAndRestore(t, commonconfig.WatchNamespaceEnvVar, test.HostOperatorNs)
+	t.Cleanup(restore)
+
+	t.Run("space count alert", func(t *testing.T) {
+		// given
+		toolchainStatus := NewToolchainStatus(WithMember("member-1", WithSpaceCount(92)))
+		spc := tspc.NewEnabledValidTenantSPC("member-1", MaxNumberOfSpaces(100))
+		reconciler, cl := prepareCapacityAlerts(t, toolchainStatus, spc)
+
+		// when
+		err := checkCapacityAlerts(t, reconciler, cl)
+
+		// then
+		require.NoError(t, err)
+		notifications := capacityAlertNotifications(t, cl, NotificationTypeCapacityAlert)
+		require.Len(t, notifications, 1)
+		assert.Equal(t, "Capacity alert for host-cluster: the member cluster 'member-1' reached 92% of its maximum number of Spaces (92/100)", notifications[0].Spec.Subject)
+		assert.Equal(t, "admin@acme.com", notifications[0].Spec.Recipient)
+		assertCapacityAlerts(t, cl, "spaces-member-1")
+
+		t.Run("alert is not sent again", func(t *testing.T) {
+			// when
+			err := checkCapacityAlerts(t, reconciler, cl)
+
+			// then
+			require.NoError(t, err)
+			assert.Len(t, capacityAlertNotifications(t, cl, NotificationTypeCapacityAlert), 1)
+			assertCapacityAlerts(t, cl, "spaces-member-1")
+		})
+
+		t.Run("alert is not resolved within the hysteresis", func(t *testing.T) {
+			// given
+			setSpaceCount(t, cl, "member-1", 86)
+
+			// when
+			err := checkCapacityAlerts(t, reconciler, cl)
+
+			// then
+			require.NoError(t, err)
+			assert.Empty(t, capacityAlertNotifications(t, cl, NotificationTypeCapacityAlertResolved))
+			assertCapacityAlerts(t, cl, "spaces-member-1")
+
+			t.Run("alert is resolved below the hysteresis", func(t *testing.T) {
+				// given
+				setSpaceCount(t, cl, "member-1", 84)
+
+				// when
+				err := checkCapacityAlerts(t, reconciler, cl)
+
+				// then
+				require.NoError(t, err)
+				resolved := capacityAlertNotifications(t, cl, NotificationTypeCapacityAlertResolved)
+				require.Len(t, resolved, 1)
+				assert.Equal(t, "Capacity alert resolved for host-cluster: the number of Spaces in the member cluster 'member-1' is back below 90% of its maximum (84/100)",
+					resolved[0].Spec.Subject)
+				assertCapacityAlerts(t, cl)
+			})
+		})
+	})
+
+	t.Run("memory alert", func(t *testing.T) {
+		// given
+		toolchainStatus := NewToolchainStatus(WithMember("member-1", WithNodeRoleUsage("master", 40), WithNodeRoleUsage("worker", 85)))
+		spc := tspc.NewEnabledValidTenantSPC("member-1", MaxMemoryUtilizationPercent(80))
+		reconciler, cl := prepareCapacityAlerts(t, toolchainStatus, spc)
+
+		// when
+		err := checkCapacityAlerts(t, reconciler, cl)
+
+		// then
+		require.NoError(t, err)
+		notifications := capacityAlertNotifications(t, cl, NotificationTypeCapacityAlert)
+		require.Len(t, notifications, 1)
+		assert.Equal(t, "Capacity alert for host-cluster: the memory usage of the worker nodes of the member cluster 'member-1' reached its threshold of 80% (85%)",
+			notifications[0].Spec.Subject)
+		assertCapacityAlerts(t, cl, "memory-member-1")
+	})
+
+	t.Run("no clusters available", func(t *testing.T) {
+		// given
+		toolchainStatus := NewToolchainStatus(WithMember("member-1"), WithMember("member-2"))
+		spc1 := tspc.NewEnabledTenantSPC("member-1", WithReadyConditionInvalid("full"))
+		spc2 := tspc.NewEnabledTenantSPC("member-2", WithReadyConditionInvalid("full"))
+		reconciler, cl := prepareCapacityAlerts(t, toolchainStatus, spc1, spc2)
+
+		// when
+		err := checkCapacityAlerts(t, reconciler, cl)
+
+		// then
+		require.NoError(t, err)
+		notifications := capacityAlertNotifications(t, cl, NotificationTypeCapacityAlert)
+		require.Len(t, notifications, 1)
+		assert.Equal(t, "Capacity alert for host-cluster: none of the 2 tenant clusters is available for the new Spaces", notifications[0].Spec.Subject)
+		assertCapacityAlerts(t, cl, "no-clusters-available")
+
+		t.Run("alert is not resolved immediately", func(t *testing.T) {
+			// given
+			spc1 = ModifySpaceProvisionerConfig(spc1, WithReadyConditionValid())
+			require.NoError(t, cl.Status().Update(context.TODO(), spc1))
+
+			// when
+			err := checkCapacityAlerts(t, reconciler, cl)
+
+			// then
+			require.NoError(t, err)
+			assert.Empty(t, capacityAlertNotifications(t, cl, NotificationTypeCapacityAlertResolved))
+			assertCapacityAlerts(t, cl, "no-clusters-available")
+
+			t.Run("alert is resolved after the delay", func(t *testing.T) {
+				// given
+				clearedAt := time.Now().Add(-durationBeforeNoClustersAvailableResolved - time.Second).UTC().Format(time.RFC3339)
+				setCapacityAlertsAnnotation(t, cl, fmt.Sprintf(`{"no-clusters-available": {"raisedAt": "%s", "clearedAt": "%s"}}`, clearedAt, clearedAt))
+
+				// when
+				err := checkCapacityAlerts(t, reconciler, cl)
+
+				// then
+				require.NoError(t, err)
+				resolved := capacityAlertNotifications(t, cl, NotificationTypeCapacityAlertResolved)
+				require.Len(t, resolved, 1)
+				assert.Equal(t, "Capacity alert resolved for host-cluster: 1 of the 2 tenant clusters are available again for the new Spaces", resolved[0].Spec.Subject)
+				assertCapacityAlerts(t, cl)
+			})
+		})
+	})
+
+	t.Run("pending usersignups", func(t *testing.T) {
+		// given
+		toolchainStatus := NewToolchainStatus()
+		reconciler, cl := prepareCapacityAlerts(t, toolchainStatus,
+			pendingUserSignup("john"), pendingUserSignup("jane"),
+			hostconfig.Annotation(toolchainconfig.CapacityAlertPendingUserSignupsThresholdAnnotationKey, "2"))
+
+		// when
+		err := checkCapacityAlerts(t, reconciler, cl)
+
+		// then
+		require.NoError(t, err)
+		notifications := capacityAlertNotifications(t, cl, NotificationTypeCapacityAlert)
+		require.Len(t, notifications, 1)
+		assert.Equal(t, "Capacity alert for host-cluster: 2 UserSignups are pending approval (threshold: 2)", notifications[0].Spec.Subject)
+		assertCapacityAlerts(t, cl, "pending-usersignups")
+	})
+
+	t.Run("no alert", func(t *testing.T) {
+		for name, objsOrOptions := range map[string][]interface{}{
+			"below the thresholds": {
+				tspc.NewEnabledValidTenantSPC("member-1", MaxNumberOfSpaces(100), MaxMemoryUtilizationPercent(80)),
+				pendingUserSignup("john"),
+				hostconfig.Annotation(toolchainconfig.CapacityAlertPendingUserSignupsThresholdAnnotationKey, "2"),
+			},
+			"space count alert disabled": {
+				tspc.NewEnabledValidTenantSPC("member-1", MaxNumberOfSpaces(50)),
+				hostconfig.Annotation(toolchainconfig.CapacityAlertSpaceThresholdAnnotationKey, "0"),
+			},
+			"no thresholds": {
+				tspc.NewEnabledValidTenantSPC("member-1"),
+			},
+			"no SpaceProvisionerConfigs": {},
+		} {
+			t.Run(name, func(t *testing.T) {
+				// given
+				toolchainStatus := NewToolchainStatus(WithMember("member-1", WithSpaceCount(50), WithNodeRoleUsage("worker", 60)))
+				reconciler, cl := prepareCapacityAlerts(t, toolchainStatus, objsOrOptions...)
+
+				// when
+				err := checkCapacityAlerts(t, reconciler, cl)
+
+				// then
+				require.NoError(t, err)
+				assert.Empty(t, capacityAlertNotifications(t, cl, NotificationTypeCapacityAlert))
+				assertCapacityAlerts(t, cl)
+			})
+		}
+
+		t.Run("no admin email", func(t *testing.T) {
+			// given
+			toolchainStatus := NewToolchainStatus(WithMember("member-1", WithSpaceCount(100)))
+			spc := tspc.NewEnabledValidTenantSPC("member-1", MaxNumberOfSpaces(100))
+			reconciler, cl := prepareCapacityAlerts(t, toolchainStatus, spc, testconfig.Notifications().AdminEmail(""))
+
+			// when
+			err := checkCapacityAlerts(t, reconciler, cl)
+
+			// then
+			require.NoError(t, err)
+			assert.Empty(t, capacityAlertNotifications(t, cl, NotificationTypeCapacityAlert))
+		})
+	})
+
+	t.Run("alert which is not monitored anymore is dropped", func(t *testing.T) {
+		// given
+		toolchainStatus := NewToolchainStatus()
+		reconciler, cl := prepareCapacityAlerts(t, toolchainStatus)
+		setCapacityAlertsAnnotation(t, cl, `{"spaces-member-3": {"raisedAt": "2024-01-01T00:00:00Z"}}`)
+
+		// when
+		err := checkCapacityAlerts(t, reconciler, cl)
+
+		// then
+		require.NoError(t, err)
+		assert.Empty(t, capacityAlertNotifications(t, cl, NotificationTypeCapacityAlertResolved))
+		assertCapacityAlerts(t, cl)
+	})
+
+	t.Run("failures", func(t *testing.T) {
+		t.Run("unable to list the SpaceProvisionerConfigs", func(t *testing.T) {
+			// given
+			reconciler, cl := prepareCapacityAlerts(t, NewToolchainStatus())
+			cl.MockList = func(ctx context.Context, list runtimeclient.ObjectList, opts ...runtimeclient.ListOption) error {
+				if _, ok := list.(*toolchainv1alpha1.SpaceProvisionerConfigList); ok {
+					return fmt.Errorf("some error")
+				}
+				return cl.Client.List(ctx, list, opts...)
+			}
+
+			// when
+			err := checkCapacityAlerts(t, reconciler, cl)
+
+			// then
+			require.EqualError(t, err, "unable to list the SpaceProvisionerConfigs: some error")
+		})
+
+		t.Run("unable to create the notification", func(t *testing.T) {
+			// given
+			toolchainStatus := NewToolchainStatus(WithMember("member-1", WithSpaceCount(92)))
+			spc := tspc.NewEnabledValidTenantSPC("member-1", MaxNumberOfSpaces(100))
+			reconciler, cl := prepareCapacityAlerts(t, toolchainStatus, spc)
+			cl.MockCreate = func(ctx context.Context, obj runtimeclient.Object, opts ...runtimeclient.CreateOption) error {
+				return fmt.Errorf("some error")
+			}
+
+			// when
+			err := checkCapacityAlerts(t, reconciler, cl)
+
+			// then
+			require.EqualError(t, err, "unable to create the capacity-alert notification: some error")
+			// the alert is raised, but its notification is still pending
+			assertCapacityAlerts(t, cl, "spaces-member-1")
+			assert.Contains(t, capacityAlertsAnnotation(t, cl), `"notificationPending":true`)
+
+			t.Run("notification is created at the next check", func(t *testing.T) {
+				// given
+				cl.MockCreate = nil
+
+				// when
+				err := checkCapacityAlerts(t, reconciler, cl)
+
+				// then
+				require.NoError(t, err)
+				assert.Len(t, capacityAlertNotifications(t, cl, NotificationTypeCapacityAlert), 1)
+				assertCapacityAlerts(t, cl, "spaces-member-1")
+				assert.NotContains(t, capacityAlertsAnnotation(t, cl), "notificationPending")
+			})
+		})
+
+		t.Run("notification already created", func(t *testing.T) {
+			// given
+			toolchainStatus := NewToolchainStatus(WithMember("member-1", WithSpaceCount(92)))
+			spc := tspc.NewEnabledValidTenantSPC("member-1", MaxNumberOfSpaces(100))
+			reconciler, cl := prepareCapacityAlerts(t, toolchainStatus, spc)
+			cl.MockPatch = func(ctx context.Context, obj runtimeclient.Object, patch runtimeclient.Patch, opts ...runtimeclient.PatchOption) error {
+				data, err := patch.Data(obj)
+				require.NoError(t, err)
+				if _, ok := obj.(*toolchainv1alpha1.ToolchainStatus); ok && !strings.Contains(string(data), "notificationPending") {
+					// the notification was created, but the state could not be updated afterwards
+					return fmt.Errorf("some error")
+				}
+				return cl.Client.Patch(ctx, obj, patch, opts...)
+			}
+			err := checkCapacityAlerts(t, reconciler, cl)
+			require.EqualError(t, err, "unable to update the state of the capacity alerts: some error")
+			require.Len(t, capacityAlertNotifications(t, cl, NotificationTypeCapacityAlert), 1)
+			cl.MockPatch = nil
+
+			// when
+			err = checkCapacityAlerts(t, reconciler, cl)
+
+			// then
+			require.NoError(t, err)
+			// the notification of the same occurrence of the alert is not created again
+			assert.Len(t, capacityAlertNotifications(t, cl, NotificationTypeCapacityAlert), 1)
+			assertCapacityAlerts(t, cl, "spaces-member-1")
+			assert.NotContains(t, capacityAlertsAnnotation(t, cl), "notificationPending")
+		})
+
+		t.Run("unable to store the state of the alerts", func(t *testing.T) {
+			// given
+			toolchainStatus := NewToolchainStatus(WithMember("member-1", WithSpaceCount(92)))
+			spc := tspc.NewEnabledValidTenantSPC("member-1", MaxNumberOfSpaces(100))
+			reconciler, cl := prepareCapacityAlerts(t, toolchainStatus, spc)
+			cl.MockPatch = func(ctx context.Context, obj runtimeclient.Object, patch runtimeclient.Patch, opts ...runtimeclient.PatchOption) error {
+				return fmt.Errorf("some error")
+			}
+
+			// when
+			err := checkCapacityAlerts(t, reconciler, cl)
+
+			// then
+			require.EqualError(t, err, "unable to update the state of the capacity alerts: some error")
+			// the notification is not created until the alert is stored
+			assert.Empty(t, capacityAlertNotifications(t, cl, NotificationTypeCapacityAlert))
+			assertCapacityAlerts(t, cl)
+		})
+	})
+}
+
+// prepareCapacityAlerts returns a reconciler with the given ToolchainStatus and objects. The ToolchainConfig options are applied
+// on top of a config with an admin email.
+func prepareCapacityAlerts(t *testing.T, toolchainStatus *toolchainv1alpha1.ToolchainStatus, objsOrOptions ...interface{}) (*Reconciler, *test.FakeClient) {
+	toolchainStatus.Status.HostRoutes.ProxyURL = hostProxyURL
+	options := []testconfig.ToolchainConfigOption{testconfig.Notifications().AdminEmail("admin@acme.com")}
+	initObjs := []runtimeclient.Object{toolchainStatus}
+	for _, o := range objsOrOptions {
+		switch o := o.(type) {
+		case testconfig.ToolchainConfigOption:
+			options = append(options, o)
+		case runtimeclient.Object:
+			initObjs = append(initObjs, o)
+		}
+	}
+	initObjs = append(initObjs, commonconfig.NewToolchainConfigObjWithReset(t, options...))
+	s := scheme.Scheme
+	require.NoError(t, apis.AddToScheme(s))
+	cl := test.NewFakeClient(t, initObjs...)
+	return &Reconciler{Client: cl, Scheme: s, Namespace: test.HostOperatorNs}, cl
+}
+
+func checkCapacityAlerts(t *testing.T, reconciler *Reconciler, cl *test.FakeClient) error {
+	toolchainStatus := &toolchainv1alpha1.ToolchainStatus{}
+	require.NoError(t, cl.Get(context.TODO(), test.NamespacedName(test.HostOperatorNs, toolchainconfig.ToolchainStatusName), toolchainStatus))
+	return reconciler.capacityAlertsCheck(context.TODO(), toolchainStatus)
+}
+
+func capacityAlertNotifications(t *testing.T, cl *test.FakeClient, notificationType string) []toolchainv1alpha1.Notification {
+	notifications := &toolchainv1alpha1.NotificationList{}
+	require.NoError(t, cl.List(context.TODO(), notifications, runtimeclient.InNamespace(test.HostOperatorNs),
+		runtimeclient.MatchingLabels{toolchainv1alpha1.NotificationTypeLabelKey: notificationType}))
+	return notifications.Items
+}
+
+// assertCapacityAlerts verifies that the given alerts (and only them) are raised
+func assertCapacityAlerts(t *testing.T, cl *test.FakeClient, names ...string) {
+	toolchainStatus := &toolchainv1alpha1.ToolchainStatus{}
+	require.NoError(t, cl.Get(context.TODO(), test.NamespacedName(test.HostOperatorNs, toolchainconfig.ToolchainStatusName), toolchainStatus))
+	if len(names) == 0 {
+		assert.NotContains(t, toolchainStatus.Annotations, CapacityAlertsAnnotationKey)
+		return
+	}
+	require.Contains(t, toolchainStatus.Annotations, CapacityAlertsAnnotationKey)
+	for _, name := range names {
+		assert.Contains(t, toolchainStatus.Annotations[CapacityAlertsAnnotationKey], fmt.Sprintf(`"%s":`, name))
+	}
+}
+
+func capacityAlertsAnnotation(t *testing.T, cl *test.FakeClient) string {
+	toolchainStatus := &toolchainv1alpha1.ToolchainStatus{}
+	require.NoError(t, cl.Get(context.TODO(), test.NamespacedName(test.HostOperatorNs, toolchainconfig.ToolchainStatusName), toolchainStatus))
+	return toolchainStatus.Annotations[CapacityAlertsAnnotationKey]
+}
+
+func setSpaceCount(t *testing.T, cl *test.FakeClient, clusterName string, count int) {
+	toolchainStatus := &toolchainv1alpha1.ToolchainStatus{}
+	require.NoError(t, cl.Get(context.TODO(), test.NamespacedName(test.HostOperatorNs, toolchainconfig.ToolchainStatusName), toolchainStatus))
+	for i := range toolchainStatus.Status.Members {
+		if toolchainStatus.Status.Members[i].ClusterName == clusterName {
+			toolchainStatus.Status.Members[i].SpaceCount = count
+		}
+	}
+	require.NoError(t, cl.Status().Update(context.TODO(), toolchainStatus))
+}
+
+func setCapacityAlertsAnnotation(t *testing.T, cl *test.FakeClient, value string) {
+	toolchainStatus := &toolchainv1alpha1.ToolchainStatus{}
+	require.NoError(t, cl.Get(context.TODO(), test.NamespacedName(test.HostOperatorNs, toolchainconfig.ToolchainStatusName), toolchainStatus))
+	if toolchainStatus.Annotations == nil {
+		toolchainStatus.Annotations = map[string]string{}
+	}
+	toolchainStatus.Annotations[CapacityAlertsAnnotationKey] = value
+	require.NoError(t, cl.Update(context.TODO(), toolchainStatus))
+}
+
+func pendingUserSignup(name string) *toolchainv1alpha1.UserSignup {
+	return &toolchainv1alpha1.UserSignup{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: test.HostOperatorNs,
+			Labels: map[string]string{
+				toolchainv1alpha1.UserSignupStateLabelKey: toolchainv1alpha1.UserSignupStateLabelValuePending,
+			},
+		},
+		Spec: toolchainv1alpha1.UserSignupSpec{
+			IdentityClaims: toolchainv1alpha1.IdentityClaimsEmbedded{
+				PropagatedClaims: toolchainv1alpha1.PropagatedClaims{Email: name + "@acme.com"},
+			},
+		},
+		Status: toolchainv1alpha1.UserSignupStatus{
+			Conditions: []toolchainv1alpha1.Condition{{Type: toolchainv1alpha1.UserSignupApproved, Status: corev1.ConditionFalse}},
+		},
+	}
+}
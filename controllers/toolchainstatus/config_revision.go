@@ -0,0 +1,39 @@
+package toolchainstatus
+
+import (
+	"context"
+	"strconv"
+
+	toolchainv1alpha1 "github.com/codeready-toolchain/api/api/v1alpha1"
+	"github.com/codeready-toolchain/host-operator/controllers/toolchainconfig"
+
+	errs "github.com/pkg/errors"
+)
+
+// updateConfigRevision stores the number and hash of the active revision of the ToolchainConfig spec in the annotations of the
+// ToolchainStatus (with the same keys as the revision ConfigMaps), if they changed
+func (r *Reconciler) updateConfigRevision(ctx context.Context, toolchainStatus *toolchainv1alpha1.ToolchainStatus) error {
+	revision, err := toolchainconfig.GetActiveConfigRevision(ctx, r.Client, toolchainStatus.Namespace)
+	if err != nil {
+		return err
+	}
+	var number, hash string
+	if revision != nil {
+		number, hash = strconv.Itoa(revision.Number), revision.Hash
+	}
+	if toolchainStatus.Annotations[toolchainconfig.ConfigRevisionLabelKey] == number &&
+		toolchainStatus.Annotations[toolchainconfig.ConfigRevisionHashAnnotationKey] == hash {
+		return nil
+	}
+	if revision == nil {
+		delete(toolchainStatus.Annotations, toolchainconfig.ConfigRevisionLabelKey)
+		delete(toolchainStatus.Annotations, toolchainconfig.ConfigRevisionHashAnnotationKey)
+	} else {
+		if toolchainStatus.Annotations == nil {
+			toolchainStatus.Annotations = map[string]string{}
+		}
+		toolchainStatus.Annotations[toolchainconfig.ConfigRevisionLabelKey] = number
+		toolchainStatus.Annotations[toolchainconfig.ConfigRevisionHashAnnotationKey] = hash
+	}
+	return errs.Wrap(r.Client.Update(ctx, toolchainStatus), "unable to update the active ToolchainConfig revision")
+}
@@ -0,0 +1,102 @@
+package toolchainstatus
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/codeready-toolchain/host-operator/controllers/toolchainconfig"
+	. "github.com/codeready-toolchain/host-operator/test"
+	commonconfig "github.com/codeready-toolchain/toolchain-common/pkg/configuration"
+	"github.com/codeready-toolchain/toolchain-common/pkg/test"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestUpdateConfigRevision(t *testing.T) {
+	restore := test.SetEnvVarAndRestore(t, commonconfig.WatchNamespaceEnvVar, test.HostOperatorNs)
+	t.Cleanup(restore)
+
+	t.Run("no revision", func(t *testing.T) {
+		// given
+		reconciler, cl := prepareCapacityAlerts(t, NewToolchainStatus())
+
+		// when
+		err := updateConfigRevision(t, reconciler, cl)
+
+		// then
+		require.NoError(t, err)
+		assert.Empty(t, getToolchainStatus(t, cl).Annotations)
+	})
+
+	t.Run("active revision", func(t *testing.T) {
+		// given
+		reconciler, cl := prepareCapacityAlerts(t, NewToolchainStatus(), configRevision(1, "aaa"), configRevision(2, "bbb"))
+
+		// when
+		err := updateConfigRevision(t, reconciler, cl)
+
+		// then
+		require.NoError(t, err)
+		assert.Equal(t, map[string]string{
+			toolchainconfig.ConfigRevisionLabelKey:          "2",
+			toolchainconfig.ConfigRevisionHashAnnotationKey: "bbb",
+		}, getToolchainStatus(t, cl).Annotations)
+
+		t.Run("updated with the new revision", func(t *testing.T) {
+			// given
+			require.NoError(t, cl.Create(context.TODO(), configRevision(3, "ccc")))
+
+			// when
+			err := updateConfigRevision(t, reconciler, cl)
+
+			// then
+			require.NoError(t, err)
+			assert.Equal(t, map[string]string{
+				toolchainconfig.ConfigRevisionLabelKey:          "3",
+				toolchainconfig.ConfigRevisionHashAnnotationKey: "ccc",
+			}, getToolchainStatus(t, cl).Annotations)
+		})
+	})
+
+	t.Run("invalid revision", func(t *testing.T) {
+		// given
+		cm := configRevision(1, "aaa")
+		cm.Annotations[toolchainconfig.ConfigRevisionTimestampAnnotationKey] = "yesterday"
+		reconciler, cl := prepareCapacityAlerts(t, NewToolchainStatus(), cm)
+
+		// when
+		err := updateConfigRevision(t, reconciler, cl)
+
+		// then
+		require.ErrorContains(t, err, "invalid ToolchainConfig revision 'toolchainconfig-revision-1': invalid timestamp")
+		assert.Empty(t, getToolchainStatus(t, cl).Annotations)
+	})
+}
+
+func updateConfigRevision(t *testing.T, reconciler *Reconciler, cl *test.FakeClient) error {
+	return reconciler.updateConfigRevision(context.TODO(), getToolchainStatus(t, cl))
+}
+
+func configRevision(number int, hash string) *corev1.ConfigMap {
+	return &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: test.HostOperatorNs,
+			Name:      fmt.Sprintf("toolchainconfig-revision-%d", number),
+			Labels: map[string]string{
+				toolchainconfig.ConfigRevisionLabelKey: fmt.Sprint(number),
+			},
+			Annotations: map[string]string{
+				toolchainconfig.ConfigRevisionHashAnnotationKey:      hash,
+				toolchainconfig.ConfigRevisionTimestampAnnotationKey: time.Now().UTC().Format(time.RFC3339),
+			},
+		},
+		Data: map[string]string{
+			toolchainconfig.ConfigRevisionSpecKey: "{}",
+		},
+	}
+}
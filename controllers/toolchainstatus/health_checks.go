@@ -0,0 +1,184 @@
+package toolchainstatus
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	toolchainv1alpha1 "github.com/codeready-toolchain/api/api/v1alpha1"
+	"github.com/codeready-toolchain/host-operator/controllers/toolchainconfig"
+	"github.com/codeready-toolchain/toolchain-common/pkg/condition"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+const (
+	// HealthCheckConditionTypePrefix is the prefix of the type of the ToolchainStatus conditions which report the results
+	// of the health checks configured in the ToolchainConfig. It's followed by the name of the check.
+	HealthCheckConditionTypePrefix = "HealthCheck-"
+
+	// ToolchainStatusHealthCheckPassedReason is the reason of the health check conditions when the check passed
+	ToolchainStatusHealthCheckPassedReason = "HealthCheckPassed"
+	// ToolchainStatusHealthCheckFailedReason is the reason of the health check conditions when the check failed
+	ToolchainStatusHealthCheckFailedReason = "HealthCheckFailed"
+	// ToolchainStatusHealthCheckInvalidReason is the reason of the health check conditions when the check is not properly configured
+	ToolchainStatusHealthCheckInvalidReason = "HealthCheckInvalid"
+)
+
+// healthProbe checks a component the installation depends on. It returns an error describing why the component is not healthy.
+// The given context is canceled once the timeout of the check is reached.
+type healthProbe func(ctx context.Context, r *Reconciler, check toolchainconfig.HealthCheck) error
+
+// healthProbes is the registry of the probes of the health checks configured in the ToolchainConfig, by type of check
+var healthProbes = map[string]healthProbe{
+	toolchainconfig.HealthCheckTypeHTTP:      httpHealthProbe,
+	toolchainconfig.HealthCheckTypeResource:  resourceHealthProbe,
+	toolchainconfig.HealthCheckTypeTLSExpiry: tlsExpiryHealthProbe,
+}
+
+// healthCheckConditionType returns the type of the ToolchainStatus condition which reports the result of the health check with the given name
+func healthCheckConditionType(name string) toolchainv1alpha1.ConditionType {
+	return toolchainv1alpha1.ConditionType(HealthCheckConditionTypePrefix + name)
+}
+
+// healthChecksHandleStatus runs the health checks configured in the ToolchainConfig and reports their results in the ToolchainStatus conditions.
+// It returns false if any of the checks failed.
+func (r *Reconciler) healthChecksHandleStatus(ctx context.Context, toolchainStatus *toolchainv1alpha1.ToolchainStatus) bool {
+	logger := log.FromContext(ctx)
+	config, err := toolchainconfig.GetToolchainConfig(r.Client)
+	if err != nil {
+		logger.Error(err, "unable to get ToolchainConfig")
+		return false
+	}
+
+	ready := true
+	checks := config.ToolchainStatus().HealthChecks()
+	configured := map[toolchainv1alpha1.ConditionType]bool{}
+	var results []toolchainv1alpha1.Condition
+	for _, check := range checks {
+		conditionType := healthCheckConditionType(check.Name)
+		configured[conditionType] = true
+		result := toolchainv1alpha1.Condition{
+			Type:   conditionType,
+			Status: corev1.ConditionTrue,
+			Reason: ToolchainStatusHealthCheckPassedReason,
+		}
+		if probe, found := healthProbes[check.Type]; !found {
+			result.Status = corev1.ConditionFalse
+			result.Reason = ToolchainStatusHealthCheckInvalidReason
+			result.Message = fmt.Sprintf("unknown health check type '%s'", check.Type)
+		} else if err := runHealthProbe(ctx, r, probe, check); err != nil {
+			result.Status = corev1.ConditionFalse
+			result.Reason = ToolchainStatusHealthCheckFailedReason
+			result.Message = err.Error()
+		}
+		if result.Status != corev1.ConditionTrue {
+			logger.Info("health check failed", "name", check.Name, "type", check.Type, "reason", result.Reason, "message", result.Message)
+			ready = false
+		}
+		results = append(results, result)
+	}
+
+	// remove the results of the checks which are not configured anymore
+	conditions := make([]toolchainv1alpha1.Condition, 0, len(toolchainStatus.Status.Conditions))
+	for _, c := range toolchainStatus.Status.Conditions {
+		if strings.HasPrefix(string(c.Type), HealthCheckConditionTypePrefix) && !configured[c.Type] {
+			continue
+		}
+		conditions = append(conditions, c)
+	}
+	toolchainStatus.Status.Conditions = condition.AddOrUpdateStatusConditionsWithLastUpdatedTimestamp(conditions, results...)
+	return ready
+}
+
+// runHealthProbe runs the given probe, within the timeout of the check
+func runHealthProbe(ctx context.Context, r *Reconciler, probe healthProbe, check toolchainconfig.HealthCheck) error {
+	ctx, cancel := context.WithTimeout(ctx, check.TimeoutDuration())
+	defer cancel()
+	return probe(ctx, r, check)
+}
+
+// httpHealthProbe sends a GET request to the URL of the check and verifies the status code of the response
+func httpHealthProbe(ctx context.Context, r *Reconciler, check toolchainconfig.HealthCheck) error {
+	if check.URL == "" {
+		return fmt.Errorf("no URL to check")
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, check.URL, nil)
+	if err != nil {
+		return fmt.Errorf("invalid URL '%s': %w", check.URL, err)
+	}
+	resp, err := r.HTTPClientImpl.Do(req)
+	if err != nil {
+		return fmt.Errorf("unable to reach %s: %w", check.URL, err)
+	}
+	if resp.Body != nil {
+		defer resp.Body.Close()
+	}
+	if check.ExpectedStatusCode != 0 && resp.StatusCode != check.ExpectedStatusCode {
+		return fmt.Errorf("%s returned status code %d instead of %d", check.URL, resp.StatusCode, check.ExpectedStatusCode)
+	}
+	if check.ExpectedStatusCode == 0 && (resp.StatusCode < 200 || resp.StatusCode > 299) {
+		return fmt.Errorf("%s returned status code %d", check.URL, resp.StatusCode)
+	}
+	return nil
+}
+
+// resourceHealthProbe verifies that the Kubernetes resource of the check exists
+func resourceHealthProbe(ctx context.Context, r *Reconciler, check toolchainconfig.HealthCheck) error {
+	if check.Kind == "" || check.ResourceName == "" {
+		return fmt.Errorf("no kind or name of the resource to check")
+	}
+	gv, err := schema.ParseGroupVersion(check.APIVersion)
+	if err != nil {
+		return fmt.Errorf("invalid API version '%s': %w", check.APIVersion, err)
+	}
+	namespace := check.Namespace
+	if namespace == "" {
+		namespace = r.Namespace
+	}
+	obj := &unstructured.Unstructured{}
+	obj.SetGroupVersionKind(gv.WithKind(check.Kind))
+	if err := r.Client.Get(ctx, types.NamespacedName{Namespace: namespace, Name: check.ResourceName}, obj); err != nil {
+		return fmt.Errorf("unable to get %s %s/%s: %w", check.Kind, namespace, check.ResourceName, err)
+	}
+	return nil
+}
+
+// tlsExpiryHealthProbe verifies that the certificate served by the TLS endpoint of the check is still valid for at least its min validity
+func tlsExpiryHealthProbe(ctx context.Context, _ *Reconciler, check toolchainconfig.HealthCheck) error {
+	if check.Address == "" {
+		return fmt.Errorf("no address to check")
+	}
+	host, _, err := net.SplitHostPort(check.Address)
+	if err != nil {
+		return fmt.Errorf("invalid address '%s': %w", check.Address, err)
+	}
+	dialer := &tls.Dialer{
+		Config: &tls.Config{
+			ServerName:         host,
+			InsecureSkipVerify: check.InsecureSkipVerify, // nolint:gosec
+			MinVersion:         tls.VersionTLS12,
+		},
+	}
+	conn, err := dialer.DialContext(ctx, "tcp", check.Address)
+	if err != nil {
+		return fmt.Errorf("unable to connect to %s: %w", check.Address, err)
+	}
+	defer conn.Close()
+	certs := conn.(*tls.Conn).ConnectionState().PeerCertificates
+	if len(certs) == 0 {
+		return fmt.Errorf("no certificate served by %s", check.Address)
+	}
+	if expiry := certs[0].NotAfter; time.Until(expiry) < check.MinValidityDuration() {
+		return fmt.Errorf("the certificate served by %s expires on %s", check.Address, expiry.UTC().Format(time.RFC3339))
+	}
+	return nil
+}
@@ -0,0 +1,267 @@
+package toolchainstatus
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	toolchainv1alpha1 "github.com/codeready-toolchain/api/api/v1alpha1"
+	"github.com/codeready-toolchain/host-operator/controllers/toolchainconfig"
+	. "github.com/codeready-toolchain/host-operator/test"
+	hostconfig "github.com/codeready-toolchain/host-operator/test/config"
+	"github.com/codeready-toolchain/toolchain-common/pkg/condition"
+	commonconfig "github.com/codeready-toolchain/toolchain-common/pkg/configuration"
+	"github.com/codeready-toolchain/toolchain-common/pkg/test"
+	testconfig "github.com/codeready-toolchain/toolchain-common/pkg/test/config"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestHealthChecks(t *testing.T) {
+	restore := test.SetEnvVarAndRestore(t, commonconfig.WatchNamespaceEnvVar, test.HostOperatorNs)
+	t.Cleanup(restore)
+
+	t.Run("no health checks", func(t *testing.T) {
+		// given
+		reconciler, _ := prepareCapacityAlerts(t, NewToolchainStatus())
+		toolchainStatus := NewToolchainStatus()
+
+		// when
+		ready := reconciler.healthChecksHandleStatus(context.TODO(), toolchainStatus)
+
+		// then
+		assert.True(t, ready)
+		assert.Empty(t, toolchainStatus.Status.Conditions)
+	})
+
+	t.Run("http", func(t *testing.T) {
+		for name, tc := range map[string]struct {
+			check         string
+			client        *fakeHTTPClient
+			expectedReady bool
+			expectedMsg   string
+		}{
+			"healthy": {
+				check:         `{"name":"sso","type":"http","url":"https://sso.acme.com/health"}`,
+				client:        &fakeHTTPClient{response: http.Response{StatusCode: http.StatusNoContent}},
+				expectedReady: true,
+			},
+			"expected status code": {
+				check:         `{"name":"sso","type":"http","url":"https://sso.acme.com/health","expectedStatusCode":401}`,
+				client:        &fakeHTTPClient{response: http.Response{StatusCode: http.StatusUnauthorized}},
+				expectedReady: true,
+			},
+			"unexpected status code": {
+				check:       `{"name":"sso","type":"http","url":"https://sso.acme.com/health","expectedStatusCode":200}`,
+				client:      &fakeHTTPClient{response: http.Response{StatusCode: http.StatusNoContent}},
+				expectedMsg: "https://sso.acme.com/health returned status code 204 instead of 200",
+			},
+			"error status code": {
+				check:       `{"name":"sso","type":"http","url":"https://sso.acme.com/health"}`,
+				client:      &fakeHTTPClient{response: http.Response{StatusCode: http.StatusServiceUnavailable}},
+				expectedMsg: "https://sso.acme.com/health returned status code 503",
+			},
+			"unreachable": {
+				check:       `{"name":"sso","type":"http","url":"https://sso.acme.com/health"}`,
+				client:      &fakeHTTPClient{err: fmt.Errorf("connection refused")},
+				expectedMsg: "unable to reach https://sso.acme.com/health: connection refused",
+			},
+		} {
+			t.Run(name, func(t *testing.T) {
+				// given
+				reconciler, _ := prepareCapacityAlerts(t, NewToolchainStatus(), healthChecks(tc.check))
+				reconciler.HTTPClientImpl = tc.client
+				toolchainStatus := NewToolchainStatus()
+
+				// when
+				ready := reconciler.healthChecksHandleStatus(context.TODO(), toolchainStatus)
+
+				// then
+				assert.Equal(t, tc.expectedReady, ready)
+				assertHealthCheckCondition(t, toolchainStatus, "sso", tc.expectedReady, tc.expectedMsg)
+			})
+		}
+	})
+
+	t.Run("http timeout", func(t *testing.T) {
+		// given
+		reconciler, _ := prepareCapacityAlerts(t, NewToolchainStatus(),
+			healthChecks(`{"name":"sso","type":"http","url":"https://sso.acme.com/health","timeout":"10ms"}`))
+		reconciler.HTTPClientImpl = &hangingHTTPClient{}
+		toolchainStatus := NewToolchainStatus()
+
+		// when
+		ready := reconciler.healthChecksHandleStatus(context.TODO(), toolchainStatus)
+
+		// then
+		assert.False(t, ready)
+		assertHealthCheckCondition(t, toolchainStatus, "sso", false, "unable to reach https://sso.acme.com/health: "+context.DeadlineExceeded.Error())
+	})
+
+	t.Run("resource", func(t *testing.T) {
+		check := healthChecks(`{"name":"banner","type":"resource","apiVersion":"v1","kind":"ConfigMap","resourceName":"banner"}`)
+
+		t.Run("exists", func(t *testing.T) {
+			// given
+			configMap := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "banner", Namespace: test.HostOperatorNs}}
+			reconciler, _ := prepareCapacityAlerts(t, NewToolchainStatus(), check, configMap)
+			toolchainStatus := NewToolchainStatus()
+
+			// when
+			ready := reconciler.healthChecksHandleStatus(context.TODO(), toolchainStatus)
+
+			// then
+			assert.True(t, ready)
+			assertHealthCheckCondition(t, toolchainStatus, "banner", true, "")
+		})
+
+		t.Run("missing", func(t *testing.T) {
+			// given
+			configMap := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "banner", Namespace: "other"}}
+			reconciler, _ := prepareCapacityAlerts(t, NewToolchainStatus(), check, configMap)
+			toolchainStatus := NewToolchainStatus()
+
+			// when
+			ready := reconciler.healthChecksHandleStatus(context.TODO(), toolchainStatus)
+
+			// then
+			assert.False(t, ready)
+			assertHealthCheckCondition(t, toolchainStatus, "banner", false, `unable to get ConfigMap toolchain-host-operator/banner: configmaps "banner" not found`)
+		})
+	})
+
+	t.Run("tls expiry", func(t *testing.T) {
+		server := httptest.NewTLSServer(http.HandlerFunc(func(_ http.ResponseWriter, _ *http.Request) {}))
+		t.Cleanup(server.Close)
+		address := strings.TrimPrefix(server.URL, "https://")
+
+		t.Run("valid", func(t *testing.T) {
+			// given
+			reconciler, _ := prepareCapacityAlerts(t, NewToolchainStatus(),
+				healthChecks(fmt.Sprintf(`{"name":"cert","type":"tls-expiry","address":"%s","insecureSkipVerify":true}`, address)))
+			toolchainStatus := NewToolchainStatus()
+
+			// when
+			ready := reconciler.healthChecksHandleStatus(context.TODO(), toolchainStatus)
+
+			// then
+			assert.True(t, ready)
+			assertHealthCheckCondition(t, toolchainStatus, "cert", true, "")
+		})
+
+		t.Run("expires soon", func(t *testing.T) {
+			// given
+			reconciler, _ := prepareCapacityAlerts(t, NewToolchainStatus(),
+				healthChecks(fmt.Sprintf(`{"name":"cert","type":"tls-expiry","address":"%s","insecureSkipVerify":true,"minValidity":"1000000h"}`, address)))
+			toolchainStatus := NewToolchainStatus()
+
+			// when
+			ready := reconciler.healthChecksHandleStatus(context.TODO(), toolchainStatus)
+
+			// then
+			assert.False(t, ready)
+			assertHealthCheckCondition(t, toolchainStatus, "cert", false, fmt.Sprintf("the certificate served by %s expires on ", address))
+		})
+
+		t.Run("untrusted certificate", func(t *testing.T) {
+			// given
+			reconciler, _ := prepareCapacityAlerts(t, NewToolchainStatus(),
+				healthChecks(fmt.Sprintf(`{"name":"cert","type":"tls-expiry","address":"%s"}`, address)))
+			toolchainStatus := NewToolchainStatus()
+
+			// when
+			ready := reconciler.healthChecksHandleStatus(context.TODO(), toolchainStatus)
+
+			// then
+			assert.False(t, ready)
+			assertHealthCheckCondition(t, toolchainStatus, "cert", false, fmt.Sprintf("unable to connect to %s: ", address))
+		})
+	})
+
+	t.Run("unknown type", func(t *testing.T) {
+		// given
+		reconciler, _ := prepareCapacityAlerts(t, NewToolchainStatus(), healthChecks(`{"name":"dns","type":"dns"}`))
+		toolchainStatus := NewToolchainStatus()
+
+		// when
+		ready := reconciler.healthChecksHandleStatus(context.TODO(), toolchainStatus)
+
+		// then
+		assert.False(t, ready)
+		c, found := condition.FindConditionByType(toolchainStatus.Status.Conditions, healthCheckConditionType("dns"))
+		require.True(t, found)
+		assert.Equal(t, ToolchainStatusHealthCheckInvalidReason, c.Reason)
+		assert.Equal(t, "unknown health check type 'dns'", c.Message)
+	})
+
+	t.Run("results of the checks which are not configured anymore are removed", func(t *testing.T) {
+		// given
+		reconciler, _ := prepareCapacityAlerts(t, NewToolchainStatus(), healthChecks(`{"name":"sso","type":"http","url":"https://sso.acme.com/health"}`))
+		reconciler.HTTPClientImpl = &fakeHTTPClient{response: http.Response{StatusCode: http.StatusOK}}
+		toolchainStatus := NewToolchainStatus()
+		toolchainStatus.Status.Conditions = []toolchainv1alpha1.Condition{
+			{Type: toolchainv1alpha1.ConditionReady, Status: corev1.ConditionTrue},
+			{Type: healthCheckConditionType("old"), Status: corev1.ConditionFalse},
+		}
+
+		// when
+		ready := reconciler.healthChecksHandleStatus(context.TODO(), toolchainStatus)
+
+		// then
+		assert.True(t, ready)
+		require.Len(t, toolchainStatus.Status.Conditions, 2)
+		assert.Equal(t, toolchainv1alpha1.ConditionReady, toolchainStatus.Status.Conditions[0].Type)
+		assert.Equal(t, healthCheckConditionType("sso"), toolchainStatus.Status.Conditions[1].Type)
+	})
+
+	t.Run("failed checks are in the unready notification content", func(t *testing.T) {
+		// given
+		toolchainStatus := NewToolchainStatus()
+		toolchainStatus.Status.Conditions = []toolchainv1alpha1.Condition{
+			{Type: healthCheckConditionType("sso"), Status: corev1.ConditionFalse, Reason: ToolchainStatusHealthCheckFailedReason, Message: "connection refused"},
+			{Type: healthCheckConditionType("banner"), Status: corev1.ConditionTrue, Reason: ToolchainStatusHealthCheckPassedReason},
+		}
+
+		// when
+		content, err := GenerateUnreadyNotificationContent(ClusterURLs(logger, toolchainStatus), ExtractStatusMetadata(toolchainStatus))
+
+		// then
+		require.NoError(t, err)
+		assert.Contains(t, content, "<h4>sso Health Check not ready</h4>")
+		assert.Contains(t, content, "connection refused")
+		assert.NotContains(t, content, "banner")
+	})
+}
+
+func healthChecks(checks ...string) testconfig.ToolchainConfigOption {
+	return hostconfig.Annotation(toolchainconfig.ToolchainStatusHealthChecksAnnotationKey, fmt.Sprintf(`{"checks":[%s]}`, strings.Join(checks, ",")))
+}
+
+func assertHealthCheckCondition(t *testing.T, toolchainStatus *toolchainv1alpha1.ToolchainStatus, name string, passed bool, messagePrefix string) {
+	c, found := condition.FindConditionByType(toolchainStatus.Status.Conditions, healthCheckConditionType(name))
+	require.True(t, found)
+	if passed {
+		assert.Equal(t, corev1.ConditionTrue, c.Status)
+		assert.Equal(t, ToolchainStatusHealthCheckPassedReason, c.Reason)
+		return
+	}
+	assert.Equal(t, corev1.ConditionFalse, c.Status)
+	assert.Equal(t, ToolchainStatusHealthCheckFailedReason, c.Reason)
+	assert.True(t, strings.HasPrefix(c.Message, messagePrefix), "unexpected message: %s", c.Message)
+}
+
+// hangingHTTPClient is an HTTPClient whose requests never complete, until their context is done
+type hangingHTTPClient struct {
+	fakeHTTPClient
+}
+
+func (c *hangingHTTPClient) Do(req *http.Request) (*http.Response, error) {
+	<-req.Context().Done()
+	return nil, req.Context().Err()
+}
@@ -0,0 +1,165 @@
+package toolchainstatus
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	toolchainv1alpha1 "github.com/codeready-toolchain/api/api/v1alpha1"
+	"github.com/codeready-toolchain/host-operator/controllers/toolchainconfig"
+	"github.com/codeready-toolchain/host-operator/pkg/metrics"
+
+	errs "github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+const (
+	// HistoryConfigMapName is the name of the ConfigMap, in the host operator namespace, which contains the readiness transitions
+	// of the toolchain components. It's owned by the ToolchainStatus.
+	HistoryConfigMapName = "toolchainstatus-history"
+	// HistoryConfigMapKey is the key of the history in the HistoryConfigMapName ConfigMap. The value is a JSON object whose keys
+	// are the components and values their transitions, from the oldest to the latest.
+	HistoryConfigMapKey = "history.json"
+
+	// toolchainStatusComponent is the component of the history which tracks the overall readiness of the ToolchainStatus
+	toolchainStatusComponent = "toolchainStatus"
+)
+
+// availabilityWindow is a rolling window over which the availability of the components is exported
+type availabilityWindow struct {
+	label    string
+	duration time.Duration
+}
+
+var availabilityWindows = []availabilityWindow{
+	{label: "1h", duration: time.Hour},
+	{label: "24h", duration: 24 * time.Hour},
+	{label: "7d", duration: 7 * 24 * time.Hour},
+	{label: "30d", duration: 30 * 24 * time.Hour},
+}
+
+// componentTransition is a change of the readiness of a component
+type componentTransition struct {
+	Time  metav1.Time `json:"time"`
+	Ready bool        `json:"ready"`
+}
+
+// componentHistory contains the transitions of each component
+type componentHistory map[string][]componentTransition
+
+// recordHistory adds the readiness of the given components to their history when it changed, keeping at most the configured number
+// of transitions per component, and exports their availability over the rolling windows. Since the oldest transitions are dropped,
+// the period covered by the history is exported too, so that an availability computed over a shorter period than its window can be spotted.
+func (r *Reconciler) recordHistory(ctx context.Context, toolchainStatus *toolchainv1alpha1.ToolchainStatus, readiness map[string]bool) error {
+	config, err := toolchainconfig.GetToolchainConfig(r.Client)
+	if err != nil {
+		return errs.Wrapf(err, "unable to get ToolchainConfig")
+	}
+	maxTransitions := config.ToolchainStatus().HistoryMaxTransitions()
+
+	cm := &corev1.ConfigMap{}
+	exists := true
+	if err := r.Client.Get(ctx, types.NamespacedName{Namespace: toolchainStatus.Namespace, Name: HistoryConfigMapName}, cm); err != nil {
+		if !errors.IsNotFound(err) {
+			return errs.Wrapf(err, "unable to get the ToolchainStatus history")
+		}
+		exists = false
+		cm = &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: toolchainStatus.Namespace,
+				Name:      HistoryConfigMapName,
+			},
+		}
+	}
+	history := componentHistory{}
+	if v, found := cm.Data[HistoryConfigMapKey]; found {
+		if err := json.Unmarshal([]byte(v), &history); err != nil {
+			log.FromContext(ctx).Error(err, "resetting the invalid ToolchainStatus history")
+			history = componentHistory{}
+		}
+	}
+
+	now := metav1.Now()
+	changed := false
+	for component, ready := range readiness {
+		transitions := history[component]
+		if len(transitions) > 0 && transitions[len(transitions)-1].Ready == ready {
+			continue
+		}
+		transitions = append(transitions, componentTransition{Time: now, Ready: ready})
+		if len(transitions) > maxTransitions {
+			transitions = transitions[len(transitions)-maxTransitions:]
+			log.FromContext(ctx).Info("dropping the oldest transitions from the ToolchainStatus history", "component", component,
+				"max-transitions", maxTransitions, "covered-since", transitions[0].Time.UTC().Format(time.RFC3339))
+		}
+		history[component] = transitions
+		changed = true
+	}
+	exportAvailability(history, now.Time)
+	if !changed {
+		return nil
+	}
+
+	data, err := json.Marshal(history)
+	if err != nil {
+		return errs.Wrapf(err, "unable to marshal the ToolchainStatus history")
+	}
+	if cm.Data == nil {
+		cm.Data = map[string]string{}
+	}
+	cm.Data[HistoryConfigMapKey] = string(data)
+	if !exists {
+		if err := controllerutil.SetControllerReference(toolchainStatus, cm, r.Scheme); err != nil {
+			return errs.Wrapf(err, "unable to set the owner of the ToolchainStatus history")
+		}
+		return errs.Wrapf(r.Client.Create(ctx, cm), "unable to create the ToolchainStatus history")
+	}
+	return errs.Wrapf(r.Client.Update(ctx, cm), "unable to update the ToolchainStatus history")
+}
+
+// exportAvailability sets the availability gauges of the components over each rolling window, along with the period covered by their history
+func exportAvailability(history componentHistory, now time.Time) {
+	for component, transitions := range history {
+		if len(transitions) > 0 {
+			metrics.ToolchainStatusHistoryCoveredSecondsGaugeVec.WithLabelValues(component).Set(now.Sub(transitions[0].Time.Time).Seconds())
+		}
+		for _, window := range availabilityWindows {
+			if percent, known := availability(transitions, now, window.duration); known {
+				metrics.ToolchainStatusAvailabilityGaugeVec.WithLabelValues(component, window.label).Set(percent)
+			}
+		}
+	}
+}
+
+// availability returns the percentage of time the component was ready during the given window before now, according to its transitions.
+// The time before the first transition is unknown and is not taken into account. It returns false if the whole window is unknown.
+func availability(transitions []componentTransition, now time.Time, window time.Duration) (float64, bool) {
+	start := now.Add(-window)
+	var known, ready time.Duration
+	for i, t := range transitions {
+		from := t.Time.Time
+		if from.Before(start) {
+			from = start
+		}
+		to := now
+		if i+1 < len(transitions) {
+			to = transitions[i+1].Time.Time
+		}
+		if !to.After(from) {
+			continue
+		}
+		known += to.Sub(from)
+		if t.Ready {
+			ready += to.Sub(from)
+		}
+	}
+	if known == 0 {
+		return 0, false
+	}
+	return 100 * float64(ready) / float64(known), true
+}
@@ -0,0 +1,196 @@
+package toolchainstatus
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/codeready-toolchain/host-operator/controllers/toolchainconfig"
+	"github.com/codeready-toolchain/host-operator/pkg/metrics"
+	. "github.com/codeready-toolchain/host-operator/test"
+	hostconfig "github.com/codeready-toolchain/host-operator/test/config"
+	commonconfig "github.com/codeready-toolchain/toolchain-common/pkg/configuration"
+	"github.com/codeready-toolchain/toolchain-common/pkg/test"
+
+	promtestutil "github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestAvailability(t *testing.T) {
+	now := time.Now()
+	at := func(ago time.Duration, ready bool) componentTransition {
+		return componentTransition{Time: metav1.NewTime(now.Add(-ago)), Ready: ready}
+	}
+
+	for name, tc := range map[string]struct {
+		transitions     []componentTransition
+		window          time.Duration
+		expectedPercent float64
+		expectedKnown   bool
+	}{
+		"no transitions": {
+			window: time.Hour,
+		},
+		"ready during the whole window": {
+			transitions:     []componentTransition{at(2*time.Hour, true)},
+			window:          time.Hour,
+			expectedPercent: 100,
+			expectedKnown:   true,
+		},
+		"unready during the whole window": {
+			transitions:     []componentTransition{at(2*time.Hour, true), at(90*time.Minute, false)},
+			window:          time.Hour,
+			expectedPercent: 0,
+			expectedKnown:   true,
+		},
+		"unready for a quarter of the window": {
+			transitions:     []componentTransition{at(2*time.Hour, true), at(30*time.Minute, false), at(15*time.Minute, true)},
+			window:          time.Hour,
+			expectedPercent: 75,
+			expectedKnown:   true,
+		},
+		"time before the first transition is unknown": {
+			transitions:     []componentTransition{at(30*time.Minute, false), at(15*time.Minute, true)},
+			window:          24 * time.Hour,
+			expectedPercent: 50,
+			expectedKnown:   true,
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			// when
+			percent, known := availability(tc.transitions, now, tc.window)
+
+			// then
+			assert.Equal(t, tc.expectedKnown, known)
+			assert.InDelta(t, tc.expectedPercent, percent, 0.01)
+		})
+	}
+}
+
+func TestRecordHistory(t *testing.T) {
+	restore := test.SetEnvVarAndRestore(t, commonconfig.WatchNamespaceEnvVar, test.HostOperatorNs)
+	t.Cleanup(restore)
+
+	t.Run("history is created and the transitions are recorded", func(t *testing.T) {
+		// given
+		toolchainStatus := NewToolchainStatus()
+		reconciler, cl := prepareCapacityAlerts(t, toolchainStatus)
+
+		// when
+		err := reconciler.recordHistory(context.TODO(), toolchainStatus, map[string]bool{"hostOperator": true, "members": false})
+
+		// then
+		require.NoError(t, err)
+		history := getHistory(t, cl)
+		require.Len(t, history["hostOperator"], 1)
+		assert.True(t, history["hostOperator"][0].Ready)
+		require.Len(t, history["members"], 1)
+		assert.False(t, history["members"][0].Ready)
+
+		cm := &corev1.ConfigMap{}
+		require.NoError(t, cl.Get(context.TODO(), test.NamespacedName(test.HostOperatorNs, HistoryConfigMapName), cm))
+		require.Len(t, cm.OwnerReferences, 1)
+		assert.Equal(t, toolchainconfig.ToolchainStatusName, cm.OwnerReferences[0].Name)
+
+		t.Run("unchanged readiness is not recorded", func(t *testing.T) {
+			// when
+			err := reconciler.recordHistory(context.TODO(), toolchainStatus, map[string]bool{"hostOperator": true, "members": false})
+
+			// then
+			require.NoError(t, err)
+			history := getHistory(t, cl)
+			assert.Len(t, history["hostOperator"], 1)
+			assert.Len(t, history["members"], 1)
+		})
+
+		t.Run("changed readiness is recorded", func(t *testing.T) {
+			// when
+			err := reconciler.recordHistory(context.TODO(), toolchainStatus, map[string]bool{"hostOperator": true, "members": true})
+
+			// then
+			require.NoError(t, err)
+			history := getHistory(t, cl)
+			assert.Len(t, history["hostOperator"], 1)
+			require.Len(t, history["members"], 2)
+			assert.True(t, history["members"][1].Ready)
+		})
+	})
+
+	t.Run("availability is exported", func(t *testing.T) {
+		// given
+		metrics.Reset()
+		toolchainStatus := NewToolchainStatus()
+		history, err := json.Marshal(componentHistory{
+			"members": {
+				{Time: metav1.NewTime(time.Now().Add(-4 * time.Hour)), Ready: true},
+				{Time: metav1.NewTime(time.Now().Add(-2 * time.Hour)), Ready: false},
+			},
+		})
+		require.NoError(t, err)
+		cm := &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: HistoryConfigMapName, Namespace: test.HostOperatorNs},
+			Data:       map[string]string{HistoryConfigMapKey: string(history)},
+		}
+		reconciler, _ := prepareCapacityAlerts(t, toolchainStatus, cm)
+
+		// when
+		err = reconciler.recordHistory(context.TODO(), toolchainStatus, map[string]bool{"members": true})
+
+		// then
+		require.NoError(t, err)
+		assert.InDelta(t, 0.0, promtestutil.ToFloat64(metrics.ToolchainStatusAvailabilityGaugeVec.WithLabelValues("members", "1h")), 0.01)
+		assert.InDelta(t, 50.0, promtestutil.ToFloat64(metrics.ToolchainStatusAvailabilityGaugeVec.WithLabelValues("members", "24h")), 0.01)
+		// the history only covers the last 4 hours
+		assert.InDelta(t, (4 * time.Hour).Seconds(), promtestutil.ToFloat64(metrics.ToolchainStatusHistoryCoveredSecondsGaugeVec.WithLabelValues("members")), 1)
+	})
+
+	t.Run("oldest transitions are dropped", func(t *testing.T) {
+		// given
+		toolchainStatus := NewToolchainStatus()
+		reconciler, cl := prepareCapacityAlerts(t, toolchainStatus,
+			hostconfig.Annotation(toolchainconfig.ToolchainStatusHistoryMaxTransitionsAnnotationKey, "3"))
+
+		// when
+		for i := 0; i < 5; i++ {
+			require.NoError(t, reconciler.recordHistory(context.TODO(), toolchainStatus, map[string]bool{"registrationService": i%2 == 0}))
+		}
+
+		// then
+		history := getHistory(t, cl)
+		require.Len(t, history["registrationService"], 3)
+		assert.True(t, history["registrationService"][0].Ready)
+		assert.False(t, history["registrationService"][1].Ready)
+		assert.True(t, history["registrationService"][2].Ready)
+	})
+
+	t.Run("invalid history is reset", func(t *testing.T) {
+		// given
+		toolchainStatus := NewToolchainStatus()
+		cm := &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: HistoryConfigMapName, Namespace: test.HostOperatorNs},
+			Data:       map[string]string{HistoryConfigMapKey: "banana"},
+		}
+		reconciler, cl := prepareCapacityAlerts(t, toolchainStatus, cm)
+
+		// when
+		err := reconciler.recordHistory(context.TODO(), toolchainStatus, map[string]bool{"hostOperator": false})
+
+		// then
+		require.NoError(t, err)
+		history := getHistory(t, cl)
+		assert.Len(t, history, 1)
+		assert.Len(t, history["hostOperator"], 1)
+	})
+}
+
+func getHistory(t *testing.T, cl *test.FakeClient) componentHistory {
+	cm := &corev1.ConfigMap{}
+	require.NoError(t, cl.Get(context.TODO(), test.NamespacedName(test.HostOperatorNs, HistoryConfigMapName), cm))
+	history := componentHistory{}
+	require.NoError(t, json.Unmarshal([]byte(cm.Data[HistoryConfigMapKey]), &history))
+	return history
+}
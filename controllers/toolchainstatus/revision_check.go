@@ -0,0 +1,175 @@
+package toolchainstatus
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	toolchainv1alpha1 "github.com/codeready-toolchain/api/api/v1alpha1"
+	"github.com/codeready-toolchain/host-operator/controllers/toolchainconfig"
+	"github.com/codeready-toolchain/toolchain-common/pkg/client"
+	"github.com/codeready-toolchain/toolchain-common/pkg/status"
+
+	errs "github.com/pkg/errors"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+const (
+	// ToolchainStatusDeploymentRevisionCheckHTTPErrorReason is the reason of the RevisionCheck condition when the latest commit
+	// could not be retrieved from the endpoint of the `http` revision check source
+	ToolchainStatusDeploymentRevisionCheckHTTPErrorReason = "DeploymentRevisionCheckHTTPError"
+
+	// errMsgDeploymentIsNotExpected means that the deployed commit is not the one expected by the revision check source
+	errMsgDeploymentIsNotExpected = "deployment version is not up to date with the expected commit SHA"
+
+	// revisionCheckHTTPTimeout is the timeout of the requests to the endpoint of the `http` revision check source
+	revisionCheckHTTPTimeout = 10 * time.Second
+)
+
+// checkDeployedRevision returns the RevisionCheck condition of the commit deployed from the given repository, according to the revision
+// check source configured in the ToolchainConfig. The GitHub source is delegated to the VersionCheckManager, as before the other sources
+// were introduced. The conditions of all the sources mention the source in their message.
+func checkDeployedRevision(ctx context.Context, versionCheckManager *status.VersionCheckManager, httpRevisions *httpRevisionCache, httpClient HTTPClient,
+	toolchainConfig toolchainconfig.ToolchainConfig, existingConditions []toolchainv1alpha1.Condition, repo client.GitHubRepository) *toolchainv1alpha1.Condition {
+	cfg, err := toolchainConfig.ToolchainStatus().RevisionCheck()
+	if err != nil {
+		return status.NewComponentErrorCondition(toolchainv1alpha1.ToolchainStatusDeploymentRevisionCheckOperatorErrorReason, err.Error())
+	}
+	isProd := isProdEnvironment(toolchainConfig)
+
+	var cond *toolchainv1alpha1.Condition
+	switch {
+	case cfg.Source == toolchainconfig.RevisionCheckSourceGitHub:
+		cond = versionCheckManager.CheckDeployedVersionIsUpToDate(ctx, isProd, toolchainConfig.GitHubSecret().AccessTokenKey(), existingConditions, repo)
+	case !isProd:
+		cond = status.NewComponentReadyCondition(toolchainv1alpha1.ToolchainStatusDeploymentRevisionCheckDisabledReason)
+		cond.Message = "is not running in prod environment"
+	case cfg.Source == toolchainconfig.RevisionCheckSourceDisabled:
+		cond = status.NewComponentReadyCondition(toolchainv1alpha1.ToolchainStatusDeploymentRevisionCheckDisabledReason)
+		cond.Message = "revision check is disabled"
+	case cfg.Source == toolchainconfig.RevisionCheckSourcePinned:
+		expected, found := cfg.ExpectedCommits[repo.Name]
+		if !found || expected == "" {
+			cond = status.NewComponentReadyCondition(toolchainv1alpha1.ToolchainStatusDeploymentRevisionCheckDisabledReason)
+			cond.Message = fmt.Sprintf("no expected commit for the '%s' repository", repo.Name)
+			break
+		}
+		cond = compareDeployedRevision(repo, expected)
+	case cfg.Source == toolchainconfig.RevisionCheckSourceHTTP:
+		latest, err := httpRevisions.latestCommit(ctx, httpClient, cfg.URL, repo)
+		if err != nil {
+			cond = status.NewComponentErrorCondition(ToolchainStatusDeploymentRevisionCheckHTTPErrorReason, err.Error())
+			break
+		}
+		cond = compareDeployedRevision(repo, latest)
+	default:
+		cond = status.NewComponentErrorCondition(toolchainv1alpha1.ToolchainStatusDeploymentRevisionCheckOperatorErrorReason,
+			fmt.Sprintf("unknown revision check source '%s'", cfg.Source))
+	}
+	return withRevisionCheckSource(cond, cfg.Source)
+}
+
+// withRevisionCheckSource returns a copy of the condition, whose message mentions the given revision check source. The message is left
+// as-is if it already mentions the source, which happens when the VersionCheckManager returns the existing condition.
+func withRevisionCheckSource(cond *toolchainv1alpha1.Condition, source string) *toolchainv1alpha1.Condition {
+	result := cond.DeepCopy()
+	suffix := fmt.Sprintf("revision check source: %s", source)
+	switch {
+	case result.Message == "":
+		result.Message = suffix
+	case result.Message == suffix || strings.HasSuffix(result.Message, "("+suffix+")"):
+	default:
+		result.Message = fmt.Sprintf("%s (%s)", result.Message, suffix)
+	}
+	return result
+}
+
+// compareDeployedRevision returns a ready condition if the deployed commit of the repository is the expected one
+func compareDeployedRevision(repo client.GitHubRepository, expected string) *toolchainv1alpha1.Condition {
+	if repo.DeployedCommitSHA != expected {
+		return status.NewComponentErrorCondition(toolchainv1alpha1.ToolchainStatusDeploymentNotUpToDateReason,
+			fmt.Sprintf("%s: deployed commit SHA %s, expected commit SHA %s", errMsgDeploymentIsNotExpected, repo.DeployedCommitSHA, expected))
+	}
+	return status.NewComponentReadyCondition(toolchainv1alpha1.ToolchainStatusDeploymentUpToDateReason)
+}
+
+// httpRevisionCache caches the latest commits returned by the endpoint of the `http` revision check source, so that the endpoint is
+// called at most once per client.GitHubAPICallDelay and per repository, as the VersionCheckManager does with the GitHub API.
+// The errors are cached too, so that an unavailable endpoint is not called on every reconcile. The zero value is ready to use.
+type httpRevisionCache struct {
+	mu      sync.Mutex
+	entries map[string]httpRevision
+	now     func() time.Time
+}
+
+type httpRevision struct {
+	commit    string
+	err       error
+	fetchedAt time.Time
+}
+
+// latestCommit returns the latest commit of the repository, from the cache or from the endpoint at the given URL
+func (c *httpRevisionCache) latestCommit(ctx context.Context, httpClient HTTPClient, endpoint string, repo client.GitHubRepository) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	now := time.Now
+	if c.now != nil {
+		now = c.now
+	}
+	key := endpoint + "|" + repo.Name + "|" + repo.Branch
+	if entry, found := c.entries[key]; found && now().Before(entry.fetchedAt.Add(client.GitHubAPICallDelay)) {
+		return entry.commit, entry.err
+	}
+	commit, err := getLatestCommitFromHTTP(ctx, httpClient, endpoint, repo)
+	if c.entries == nil {
+		c.entries = map[string]httpRevision{}
+	}
+	c.entries[key] = httpRevision{commit: commit, err: err, fetchedAt: now()}
+	return commit, err
+}
+
+// getLatestCommitFromHTTP returns the latest commit SHA of the repository, as returned in plain text by the endpoint at the given URL.
+// The request is canceled after revisionCheckHTTPTimeout.
+func getLatestCommitFromHTTP(ctx context.Context, httpClient HTTPClient, endpoint string, repo client.GitHubRepository) (string, error) {
+	u, err := url.Parse(endpoint)
+	if err != nil || endpoint == "" {
+		return "", fmt.Errorf("invalid revision check URL '%s'", endpoint)
+	}
+	query := u.Query()
+	query.Set("repository", repo.Name)
+	query.Set("branch", repo.Branch)
+	u.RawQuery = query.Encode()
+
+	ctx, cancel := context.WithTimeout(ctx, revisionCheckHTTPTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return "", errs.Wrap(err, "unable to create the request of the latest commit")
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return "", errs.Wrap(err, "unable to get the latest commit")
+	}
+	defer func() {
+		if err := resp.Body.Close(); err != nil {
+			log.FromContext(ctx).Error(err, "unable to close the body")
+		}
+	}()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("bad response from the revision check endpoint: statusCode=%d", resp.StatusCode)
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1024))
+	if err != nil {
+		return "", errs.Wrap(err, "unable to read the latest commit")
+	}
+	latest := strings.TrimSpace(string(body))
+	if latest == "" {
+		return "", fmt.Errorf("no commit returned by the revision check endpoint for the '%s' repository", repo.Name)
+	}
+	return latest, nil
+}
@@ -0,0 +1,310 @@
+package toolchainstatus
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"testing"
+	"time"
+
+	toolchainv1alpha1 "github.com/codeready-toolchain/api/api/v1alpha1"
+	"github.com/codeready-toolchain/host-operator/controllers/toolchainconfig"
+	. "github.com/codeready-toolchain/host-operator/test"
+	hostconfig "github.com/codeready-toolchain/host-operator/test/config"
+	"github.com/codeready-toolchain/toolchain-common/pkg/client"
+	commonconfig "github.com/codeready-toolchain/toolchain-common/pkg/configuration"
+	"github.com/codeready-toolchain/toolchain-common/pkg/test"
+	testconfig "github.com/codeready-toolchain/toolchain-common/pkg/test/config"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+)
+
+func TestCheckDeployedRevision(t *testing.T) {
+	restore := test.SetEnvVarAndRestore(t, commonconfig.WatchNamespaceEnvVar, test.HostOperatorNs)
+	t.Cleanup(restore)
+	repo := client.GitHubRepository{
+		Org:               toolchainv1alpha1.ProviderLabelValue,
+		Name:              hostOperatorRepoName,
+		Branch:            hostOperatorRepoBranchName,
+		DeployedCommitSHA: buildCommitSHA,
+	}
+
+	for name, tc := range map[string]struct {
+		env            testconfig.EnvName
+		revisionCheck  string
+		httpClient     *recordingHTTPClient
+		expectedStatus corev1.ConditionStatus
+		expectedReason string
+		expectedMsg    string
+	}{
+		"github by default": {
+			env:            "prod",
+			expectedStatus: corev1.ConditionTrue,
+			expectedReason: toolchainv1alpha1.ToolchainStatusDeploymentRevisionCheckDisabledReason,
+			expectedMsg:    "access token key is not provided (revision check source: github)",
+		},
+		"invalid configuration": {
+			env:            "prod",
+			revisionCheck:  `{"source":`,
+			expectedStatus: corev1.ConditionFalse,
+			expectedReason: toolchainv1alpha1.ToolchainStatusDeploymentRevisionCheckOperatorErrorReason,
+			expectedMsg: "invalid revision check configuration in the 'toolchain.dev.openshift.com/toolchainstatus-revision-check' annotation: " +
+				"unexpected end of JSON input",
+		},
+		"disabled": {
+			env:            "prod",
+			revisionCheck:  `{"source":"disabled"}`,
+			expectedStatus: corev1.ConditionTrue,
+			expectedReason: toolchainv1alpha1.ToolchainStatusDeploymentRevisionCheckDisabledReason,
+			expectedMsg:    "revision check is disabled (revision check source: disabled)",
+		},
+		"not in prod": {
+			env:            "dev",
+			revisionCheck:  `{"source":"pinned","expectedCommits":{"host-operator":"abc123"}}`,
+			expectedStatus: corev1.ConditionTrue,
+			expectedReason: toolchainv1alpha1.ToolchainStatusDeploymentRevisionCheckDisabledReason,
+			expectedMsg:    "is not running in prod environment (revision check source: pinned)",
+		},
+		"pinned and up to date": {
+			env:            "prod",
+			revisionCheck:  fmt.Sprintf(`{"source":"pinned","expectedCommits":{"host-operator":"%s"}}`, buildCommitSHA),
+			expectedStatus: corev1.ConditionTrue,
+			expectedReason: toolchainv1alpha1.ToolchainStatusDeploymentUpToDateReason,
+			expectedMsg:    "revision check source: pinned",
+		},
+		"pinned and not up to date": {
+			env:            "prod",
+			revisionCheck:  `{"source":"pinned","expectedCommits":{"host-operator":"abc123"}}`,
+			expectedStatus: corev1.ConditionFalse,
+			expectedReason: toolchainv1alpha1.ToolchainStatusDeploymentNotUpToDateReason,
+			expectedMsg: fmt.Sprintf("deployment version is not up to date with the expected commit SHA: deployed commit SHA %s, expected commit SHA abc123 "+
+				"(revision check source: pinned)", buildCommitSHA),
+		},
+		"pinned for another repository": {
+			env:            "prod",
+			revisionCheck:  `{"source":"pinned","expectedCommits":{"registration-service":"abc123"}}`,
+			expectedStatus: corev1.ConditionTrue,
+			expectedReason: toolchainv1alpha1.ToolchainStatusDeploymentRevisionCheckDisabledReason,
+			expectedMsg:    "no expected commit for the 'host-operator' repository (revision check source: pinned)",
+		},
+		"http and up to date": {
+			env:            "prod",
+			revisionCheck:  `{"source":"http","url":"https://revisions.acme.com/latest"}`,
+			httpClient:     &recordingHTTPClient{statusCode: http.StatusOK, body: buildCommitSHA + "\n"},
+			expectedStatus: corev1.ConditionTrue,
+			expectedReason: toolchainv1alpha1.ToolchainStatusDeploymentUpToDateReason,
+			expectedMsg:    "revision check source: http",
+		},
+		"http and not up to date": {
+			env:            "prod",
+			revisionCheck:  `{"source":"http","url":"https://revisions.acme.com/latest"}`,
+			httpClient:     &recordingHTTPClient{statusCode: http.StatusOK, body: "abc123"},
+			expectedStatus: corev1.ConditionFalse,
+			expectedReason: toolchainv1alpha1.ToolchainStatusDeploymentNotUpToDateReason,
+			expectedMsg: fmt.Sprintf("deployment version is not up to date with the expected commit SHA: deployed commit SHA %s, expected commit SHA abc123 "+
+				"(revision check source: http)", buildCommitSHA),
+		},
+		"http with bad status code": {
+			env:            "prod",
+			revisionCheck:  `{"source":"http","url":"https://revisions.acme.com/latest"}`,
+			httpClient:     &recordingHTTPClient{statusCode: http.StatusNotFound},
+			expectedStatus: corev1.ConditionFalse,
+			expectedReason: ToolchainStatusDeploymentRevisionCheckHTTPErrorReason,
+			expectedMsg:    "bad response from the revision check endpoint: statusCode=404 (revision check source: http)",
+		},
+		"http with empty response": {
+			env:            "prod",
+			revisionCheck:  `{"source":"http","url":"https://revisions.acme.com/latest"}`,
+			httpClient:     &recordingHTTPClient{statusCode: http.StatusOK, body: " "},
+			expectedStatus: corev1.ConditionFalse,
+			expectedReason: ToolchainStatusDeploymentRevisionCheckHTTPErrorReason,
+			expectedMsg:    "no commit returned by the revision check endpoint for the 'host-operator' repository (revision check source: http)",
+		},
+		"http unreachable": {
+			env:            "prod",
+			revisionCheck:  `{"source":"http","url":"https://revisions.acme.com/latest"}`,
+			httpClient:     &recordingHTTPClient{err: fmt.Errorf("connection refused")},
+			expectedStatus: corev1.ConditionFalse,
+			expectedReason: ToolchainStatusDeploymentRevisionCheckHTTPErrorReason,
+			expectedMsg:    "unable to get the latest commit: connection refused (revision check source: http)",
+		},
+		"http without URL": {
+			env:            "prod",
+			revisionCheck:  `{"source":"http"}`,
+			httpClient:     &recordingHTTPClient{},
+			expectedStatus: corev1.ConditionFalse,
+			expectedReason: ToolchainStatusDeploymentRevisionCheckHTTPErrorReason,
+			expectedMsg:    "invalid revision check URL '' (revision check source: http)",
+		},
+		"unknown source": {
+			env:            "prod",
+			revisionCheck:  `{"source":"gitlab"}`,
+			expectedStatus: corev1.ConditionFalse,
+			expectedReason: toolchainv1alpha1.ToolchainStatusDeploymentRevisionCheckOperatorErrorReason,
+			expectedMsg:    "unknown revision check source 'gitlab' (revision check source: gitlab)",
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			// given
+			options := []interface{}{testconfig.Environment(tc.env)}
+			if tc.revisionCheck != "" {
+				options = append(options, hostconfig.Annotation(toolchainconfig.ToolchainStatusRevisionCheckAnnotationKey, tc.revisionCheck))
+			}
+			reconciler, cl := prepareCapacityAlerts(t, NewToolchainStatus(), options...)
+			if tc.httpClient != nil {
+				reconciler.HTTPClientImpl = tc.httpClient
+			}
+			toolchainConfig, err := toolchainconfig.GetToolchainConfig(cl)
+			require.NoError(t, err)
+
+			// when
+			cond := checkDeployedRevision(context.TODO(), &reconciler.VersionCheckManager, &httpRevisionCache{}, reconciler.HTTPClientImpl, toolchainConfig, nil, repo)
+
+			// then
+			require.NotNil(t, cond)
+			assert.Equal(t, toolchainv1alpha1.ConditionReady, cond.Type)
+			assert.Equal(t, tc.expectedStatus, cond.Status)
+			assert.Equal(t, tc.expectedReason, cond.Reason)
+			assert.Equal(t, tc.expectedMsg, cond.Message)
+			if tc.httpClient != nil && tc.httpClient.url != "" {
+				assert.Equal(t, "https://revisions.acme.com/latest?branch=master&repository=host-operator", tc.httpClient.url)
+			}
+		})
+	}
+}
+
+func TestWithRevisionCheckSource(t *testing.T) {
+	for message, expected := range map[string]string{
+		"":                              "revision check source: github",
+		"revision check source: github": "revision check source: github",
+		"no commit":                     "no commit (revision check source: github)",
+		"no commit (revision check source: github)": "no commit (revision check source: github)",
+	} {
+		t.Run(message, func(t *testing.T) {
+			// given
+			cond := &toolchainv1alpha1.Condition{Type: toolchainv1alpha1.ConditionReady, Message: message}
+
+			// when
+			result := withRevisionCheckSource(cond, toolchainconfig.RevisionCheckSourceGitHub)
+
+			// then
+			assert.Equal(t, expected, result.Message)
+			assert.Equal(t, message, cond.Message)
+		})
+	}
+}
+
+func TestHTTPRevisionCache(t *testing.T) {
+	repo := client.GitHubRepository{Name: hostOperatorRepoName, Branch: hostOperatorRepoBranchName}
+	const endpoint = "https://revisions.acme.com/latest"
+
+	t.Run("endpoint called once per delay", func(t *testing.T) {
+		// given
+		now := time.Now()
+		cache := &httpRevisionCache{now: func() time.Time { return now }}
+		httpClient := &recordingHTTPClient{statusCode: http.StatusOK, body: "abc123"}
+		_, err := cache.latestCommit(context.TODO(), httpClient, endpoint, repo)
+		require.NoError(t, err)
+		httpClient.body = "def456"
+
+		// when
+		latest, err := cache.latestCommit(context.TODO(), httpClient, endpoint, repo)
+
+		// then
+		require.NoError(t, err)
+		assert.Equal(t, "abc123", latest)
+		assert.Equal(t, 1, httpClient.calls)
+
+		t.Run("other repository", func(t *testing.T) {
+			// when
+			latest, err := cache.latestCommit(context.TODO(), httpClient, endpoint, client.GitHubRepository{Name: registrationServiceRepoName, Branch: registrationServiceRepoBranchName})
+
+			// then
+			require.NoError(t, err)
+			assert.Equal(t, "def456", latest)
+			assert.Equal(t, 2, httpClient.calls)
+		})
+
+		t.Run("called again after the delay", func(t *testing.T) {
+			// given
+			now = now.Add(client.GitHubAPICallDelay)
+
+			// when
+			latest, err := cache.latestCommit(context.TODO(), httpClient, endpoint, repo)
+
+			// then
+			require.NoError(t, err)
+			assert.Equal(t, "def456", latest)
+			assert.Equal(t, 3, httpClient.calls)
+		})
+	})
+
+	t.Run("errors are cached", func(t *testing.T) {
+		// given
+		cache := &httpRevisionCache{}
+		httpClient := &recordingHTTPClient{err: fmt.Errorf("connection refused")}
+		_, err := cache.latestCommit(context.TODO(), httpClient, endpoint, repo)
+		require.Error(t, err)
+
+		// when
+		_, err = cache.latestCommit(context.TODO(), httpClient, endpoint, repo)
+
+		// then
+		require.EqualError(t, err, "unable to get the latest commit: connection refused")
+		assert.Equal(t, 1, httpClient.calls)
+	})
+
+	t.Run("request has a deadline", func(t *testing.T) {
+		// given
+		cache := &httpRevisionCache{}
+		httpClient := &recordingHTTPClient{statusCode: http.StatusOK, body: "abc123"}
+
+		// when
+		_, err := cache.latestCommit(context.TODO(), httpClient, endpoint, repo)
+
+		// then
+		require.NoError(t, err)
+		deadline, found := httpClient.deadline()
+		require.True(t, found)
+		assert.WithinDuration(t, time.Now().Add(revisionCheckHTTPTimeout), deadline, time.Second)
+	})
+}
+
+// recordingHTTPClient is an HTTPClient which records the requested URL and returns a new response for each request
+type recordingHTTPClient struct {
+	statusCode int
+	body       string
+	err        error
+	url        string
+	calls      int
+	req        *http.Request
+}
+
+func (c *recordingHTTPClient) deadline() (time.Time, bool) {
+	return c.req.Context().Deadline()
+}
+
+func (c *recordingHTTPClient) Get(url string) (*http.Response, error) {
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	return c.Do(req)
+}
+
+func (c *recordingHTTPClient) Do(req *http.Request) (*http.Response, error) {
+	c.url = req.URL.String()
+	c.req = req
+	c.calls++
+	if c.err != nil {
+		return nil, c.err
+	}
+	return &http.Response{
+		StatusCode: c.statusCode,
+		Body:       io.NopCloser(bytes.NewReader([]byte(c.body))),
+	}, nil
+}
@@ -17,6 +17,7 @@ import (
 	routev1 "github.com/openshift/api/route/v1"
 
 	"github.com/codeready-toolchain/host-operator/controllers/toolchainconfig"
+	hostcluster "github.com/codeready-toolchain/host-operator/pkg/cluster"
 	notify "github.com/codeready-toolchain/toolchain-common/pkg/notification"
 
 	toolchainv1alpha1 "github.com/codeready-toolchain/api/api/v1alpha1"
@@ -43,6 +44,10 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 )
 
+// ConditionMemberClientCircuitBreakerClosed is the type of the condition of the member entries which is false while the circuit breaker
+// of the requests sent by the host operator to the member cluster is open (or half-open)
+const ConditionMemberClientCircuitBreakerClosed toolchainv1alpha1.ConditionType = "MemberClientCircuitBreakerClosed"
+
 // general toolchainstatus constants
 const (
 	memberStatusName = "toolchain-member-status"
@@ -69,20 +74,36 @@ const (
 	hostRoutesTag          statusComponentTag = "hostRoutes"
 	hostOperatorTag        statusComponentTag = "hostOperator"
 	memberConnectionsTag   statusComponentTag = "members"
+	healthChecksTag        statusComponentTag = "healthChecks"
 	counterTag             statusComponentTag = "MasterUserRecord and UserAccount counter"
-	durationAfterUnready   time.Duration      = 10 * time.Minute
 )
 
 const (
-	adminUnreadyNotificationSubject  = "ToolchainStatus has been in an unready status for an extended period for %v"
-	adminRestoredNotificationSubject = "ToolchainStatus has now been restored to ready status for %v"
+	adminUnreadyNotificationSubject      = "ToolchainStatus has been in an unready status for an extended period for %v"
+	adminStillUnreadyNotificationSubject = "ToolchainStatus is still in an unready status for %v"
+	adminEscalatedNotificationSubject    = "Escalation: ToolchainStatus has been in an unready status since %v for %v"
+	adminRestoredNotificationSubject     = "ToolchainStatus has now been restored to ready status for %v"
 )
 
 type toolchainStatusNotificationType string
 
 const (
-	unreadyStatus  toolchainStatusNotificationType = "unready"
-	restoredStatus toolchainStatusNotificationType = "restored"
+	unreadyStatus      toolchainStatusNotificationType = "unready"
+	stillUnreadyStatus toolchainStatusNotificationType = "still-unready"
+	escalatedStatus    toolchainStatusNotificationType = "escalated"
+	restoredStatus     toolchainStatusNotificationType = "restored"
+)
+
+const (
+	// NotificationTypeToolchainStatusUnready is the type of the admin notification sent when the ToolchainStatus has been unready for too long
+	NotificationTypeToolchainStatusUnready = "toolchainstatus-" + string(unreadyStatus)
+	// NotificationTypeToolchainStatusStillUnready is the type of the admin notification repeated while the ToolchainStatus is still unready
+	NotificationTypeToolchainStatusStillUnready = "toolchainstatus-" + string(stillUnreadyStatus)
+	// NotificationTypeToolchainStatusEscalated is the type of the notification sent to the escalation recipients when the ToolchainStatus
+	// has been unready for too long
+	NotificationTypeToolchainStatusEscalated = "toolchainstatus-" + string(escalatedStatus)
+	// NotificationTypeToolchainStatusRestored is the type of the admin notification sent when the ToolchainStatus is back to ready
+	NotificationTypeToolchainStatusRestored = "toolchainstatus-" + string(restoredStatus)
 )
 
 const (
@@ -113,6 +134,7 @@ func (r *Reconciler) SetupWithManager(mgr manager.Manager) error {
 
 type HTTPClient interface {
 	Get(url string) (*http.Response, error)
+	Do(req *http.Request) (*http.Response, error)
 }
 
 // Reconciler reconciles a ToolchainStatus object
@@ -123,6 +145,10 @@ type Reconciler struct {
 	HTTPClientImpl      HTTPClient
 	Namespace           string
 	VersionCheckManager status.VersionCheckManager
+	// CircuitBreakers are the circuit breakers of the requests sent to the member clusters, whose state is added to the member entries
+	CircuitBreakers *hostcluster.CircuitBreakers
+
+	httpRevisions httpRevisionCache
 }
 
 //+kubebuilder:rbac:groups=toolchain.dev.openshift.com,resources=toolchainstatuses,verbs=get;list;watch;create;update;patch;delete
@@ -178,6 +204,7 @@ func (r *Reconciler) aggregateAndUpdateStatus(ctx context.Context, toolchainStat
 	registrationServiceStatusHandlerFunc := statusHandler{name: registrationServiceTag, handleStatus: r.registrationServiceHandleStatus}
 	proxyURLHandlerFunc := statusHandler{name: hostRoutesTag, handleStatus: r.hostRoutesHandleStatus}
 	memberStatusHandlerFunc := statusHandler{name: memberConnectionsTag, handleStatus: r.membersHandleStatus}
+	healthChecksHandlerFunc := statusHandler{name: healthChecksTag, handleStatus: r.healthChecksHandleStatus}
 	// should be executed as the last one
 	counterHandlerFunc := statusHandler{name: counterTag, handleStatus: r.synchronizeWithCounter}
 
@@ -186,11 +213,13 @@ func (r *Reconciler) aggregateAndUpdateStatus(ctx context.Context, toolchainStat
 		memberStatusHandlerFunc,
 		registrationServiceStatusHandlerFunc,
 		proxyURLHandlerFunc,
+		healthChecksHandlerFunc,
 		counterHandlerFunc,
 	}
 
 	// track components that are not ready
 	var unreadyComponents []string
+	readiness := map[string]bool{}
 
 	// retrieve component statuses eg. ToolchainCluster, host deployment
 	for _, statusHandler := range statusHandlers {
@@ -198,7 +227,9 @@ func (r *Reconciler) aggregateAndUpdateStatus(ctx context.Context, toolchainStat
 		if !isReady {
 			unreadyComponents = append(unreadyComponents, string(statusHandler.name))
 		}
+		readiness[string(statusHandler.name)] = isReady
 	}
+	readiness[toolchainStatusComponent] = len(unreadyComponents) == 0
 
 	// if any components were not ready then set the overall status to not ready
 	if len(unreadyComponents) > 0 {
@@ -207,61 +238,82 @@ func (r *Reconciler) aggregateAndUpdateStatus(ctx context.Context, toolchainStat
 			return err
 		}
 
-		return r.setStatusNotReady(ctx, toolchainStatus, fmt.Sprintf("components not ready: %v", unreadyComponents))
+		if err := r.setStatusNotReady(ctx, toolchainStatus, fmt.Sprintf("components not ready: %v", unreadyComponents)); err != nil {
+			return err
+		}
+	} else if err := r.setStatusReady(ctx, toolchainStatus); err != nil {
+		return err
 	}
 
-	return r.setStatusReady(ctx, toolchainStatus)
+	// the capacity alerts are checked once the status is updated, since they patch the ToolchainStatus annotations.
+	// A failure fails the reconcile (once the history and the config revision are recorded), so that the alerts are checked
+	// again without waiting for the next refresh, eg. when a notification of a raised alert could not be created.
+	capacityAlertsErr := r.capacityAlertsCheck(ctx, toolchainStatus)
+	// same for the history: a failed update is recorded at the next refresh
+	if err := r.recordHistory(ctx, toolchainStatus, readiness); err != nil {
+		log.FromContext(ctx).Error(err, "unable to record the ToolchainStatus history")
+	}
+	if err := r.updateConfigRevision(ctx, toolchainStatus); err != nil {
+		log.FromContext(ctx).Error(err, "unable to update the active ToolchainConfig revision")
+	}
+	return errs.Wrap(capacityAlertsErr, "unable to check the capacity alerts")
 }
 
 func (r *Reconciler) notificationCheck(ctx context.Context, toolchainStatus *toolchainv1alpha1.ToolchainStatus) error {
 	// If the current ToolchainStatus:
 	// a) Is currently not ready or the deployments versions are not up-to-date,
-	// b) has not been ready for longer than the configured threshold, and
+	// b) has not been ready for longer than the configured delay, and
 	// c) no notification has been already sent, then
-	// send a notification to the admin mailing list
+	// send a notification to the admin mailing list.
+	// If a notification was already sent, then the alert policy decides whether it should be escalated or repeated.
 	c, found := condition.FindConditionByType(toolchainStatus.Status.Conditions, toolchainv1alpha1.ConditionReady)
-	if found && c.Status == corev1.ConditionFalse {
-		threshold := time.Now().Add(-durationAfterUnready)
-		if c.LastTransitionTime.Before(&metav1.Time{Time: threshold}) {
-			if !condition.IsTrue(toolchainStatus.Status.Conditions, toolchainv1alpha1.ToolchainStatusUnreadyNotificationCreated) {
-				logger := log.FromContext(ctx)
-
-				if err := r.sendToolchainStatusNotification(ctx, toolchainStatus, unreadyStatus); err != nil {
-					logger.Error(err, "Failed to create toolchain status unready notification")
-
-					// set the failed to create notification status condition
-					return r.wrapErrorWithStatusUpdate(ctx, toolchainStatus,
-						r.setStatusUnreadyNotificationCreationFailed, err,
-						"Failed to create toolchain status unready notification")
-				}
+	if !found || c.Status != corev1.ConditionFalse {
+		return nil
+	}
+	config, err := toolchainconfig.GetToolchainConfig(r.Client)
+	if err != nil {
+		return errs.Wrapf(err, "unable to get ToolchainConfig")
+	}
+	if condition.IsTrue(toolchainStatus.Status.Conditions, toolchainv1alpha1.ToolchainStatusUnreadyNotificationCreated) {
+		return r.unreadyAlertPolicyCheck(ctx, config, toolchainStatus)
+	}
 
-				if err := r.setStatusToolchainStatusUnreadyNotificationCreated(ctx, toolchainStatus); err != nil {
-					logger.Error(err, "Failed to update notification created status")
-					return err
-				}
-			}
+	threshold := time.Now().Add(-config.ToolchainStatus().UnreadyAlertDelay())
+	if c.LastTransitionTime.Before(&metav1.Time{Time: threshold}) {
+		logger := log.FromContext(ctx)
+
+		if err := r.sendToolchainStatusNotification(ctx, toolchainStatus, unreadyStatus, config.Notifications().AdminEmail()); err != nil {
+			logger.Error(err, "Failed to create toolchain status unready notification")
+
+			// set the failed to create notification status condition
+			return r.wrapErrorWithStatusUpdate(ctx, toolchainStatus,
+				r.setStatusUnreadyNotificationCreationFailed, err,
+				"Failed to create toolchain status unready notification")
+		}
+
+		if err := r.setStatusToolchainStatusUnreadyNotificationCreated(ctx, toolchainStatus); err != nil {
+			logger.Error(err, "Failed to update notification created status")
+			return err
 		}
 	}
 
 	return nil
 }
 
-func (r *Reconciler) restoredCheck(ctx context.Context, toolchainStatus *toolchainv1alpha1.ToolchainStatus) error {
+func (r *Reconciler) restoredCheck(ctx context.Context, toolchainStatus *toolchainv1alpha1.ToolchainStatus, recipients string) error {
 	// If the current ToolchainStatus:
-	// a) Was not ready before,
+	// a) Was not ready before (or is waiting for the end of the flap window),
 	// b) notification was sent out due prolonged not ready status, and
 	// c) status is now restored
 	// send a notification to the admin mailing list
-	if condition.IsFalse(toolchainStatus.Status.Conditions, toolchainv1alpha1.ConditionReady) {
-		if condition.IsTrue(toolchainStatus.Status.Conditions, toolchainv1alpha1.ToolchainStatusUnreadyNotificationCreated) {
-			if err := r.sendToolchainStatusNotification(ctx, toolchainStatus, restoredStatus); err != nil {
-				logger := log.FromContext(ctx)
-				logger.Error(err, "Failed to create toolchain status restored notification")
-				// set the failed to create notification status condition
-				return r.wrapErrorWithStatusUpdate(ctx, toolchainStatus,
-					r.setStatusReadyNotificationCreationFailed, err,
-					"Failed to create toolchain restored notification")
-			}
+	if condition.IsTrue(toolchainStatus.Status.Conditions, toolchainv1alpha1.ToolchainStatusUnreadyNotificationCreated) {
+		if err := r.sendToolchainStatusNotification(ctx, toolchainStatus, restoredStatus, recipients); err != nil {
+			logger := log.FromContext(ctx)
+			logger.Error(err, "Failed to create toolchain status restored notification")
+			// set the failed to create notification status condition
+			return r.wrapErrorWithStatusUpdate(ctx, toolchainStatus,
+				r.setStatusReadyNotificationCreationFailed, err,
+				"Failed to create toolchain restored notification")
 		}
 	}
 	return nil
@@ -328,7 +380,6 @@ func (r *Reconciler) hostOperatorHandleStatus(ctx context.Context, toolchainStat
 		toolchainStatus.Status.HostOperator = operatorStatus
 		return false
 	}
-	isProd := isProdEnvironment(toolchainConfig)
 	githubRepo := client.GitHubRepository{
 		Org:               toolchainv1alpha1.ProviderLabelValue,
 		Name:              hostOperatorRepoName,
@@ -337,7 +388,7 @@ func (r *Reconciler) hostOperatorHandleStatus(ctx context.Context, toolchainStat
 	}
 
 	// verify deployment version
-	versionCondition := r.VersionCheckManager.CheckDeployedVersionIsUpToDate(ctx, isProd, toolchainConfig.GitHubSecret().AccessTokenKey(), toolchainStatus.Status.HostOperator.RevisionCheck.Conditions, githubRepo)
+	versionCondition := checkDeployedRevision(ctx, &r.VersionCheckManager, &r.httpRevisions, r.HTTPClientImpl, toolchainConfig, toolchainStatus.Status.HostOperator.RevisionCheck.Conditions, githubRepo)
 	errVersionCheck := status.ValidateComponentConditionReady(*versionCondition)
 	if errVersionCheck != nil {
 		// let's set deployment is not up-to-date reason
@@ -362,6 +413,7 @@ func (r *Reconciler) registrationServiceHandleStatus(ctx context.Context, toolch
 		controllerClient:    r.Client,
 		httpClientImpl:      r.HTTPClientImpl,
 		versionCheckManager: r.VersionCheckManager,
+		httpRevisions:       &r.httpRevisions,
 	}
 
 	// gather the functions for handling registration service status eg. deployment, health endpoint
@@ -445,6 +497,7 @@ func (r *Reconciler) membersHandleStatus(ctx context.Context, toolchainStatus *t
 			logger.Error(err, fmt.Sprintf("cannot find memberstatus resource in namespace %s in cluster %s", memberCluster.OperatorNamespace, memberCluster.Name))
 			memberStatusNotFoundCondition := status.NewComponentErrorCondition(toolchainv1alpha1.ToolchainStatusMemberStatusNotFoundReason, err.Error())
 			memberStatus := customMemberStatus(*memberStatusNotFoundCondition)
+			r.addCircuitBreakerCondition(ctx, memberCluster.Name, &memberStatus)
 			members[memberCluster.Name] = memberStatus
 			ready = false
 			continue
@@ -470,6 +523,7 @@ func (r *Reconciler) membersHandleStatus(ctx context.Context, toolchainStatus *t
 		} else {
 			logger.Info("adding member status", "member_name", memberCluster.Name, string(toolchainv1alpha1.ConditionReady), "unknown")
 		}
+		r.addCircuitBreakerCondition(ctx, memberCluster.Name, &memberStatus)
 		members[memberCluster.Name] = memberStatus
 	}
 
@@ -478,6 +532,34 @@ func (r *Reconciler) membersHandleStatus(ctx context.Context, toolchainStatus *t
 	return ready
 }
 
+// addCircuitBreakerCondition adds the state of the circuit breaker of the member cluster to its status. The condition is informational:
+// it doesn't make the member cluster not ready, since the circuit breaker only opens for a while (and is opt-in), and the readiness of
+// the member cluster is reported by its MemberStatus. The condition is listed with the unready components when the toolchain isn't ready.
+func (r *Reconciler) addCircuitBreakerCondition(ctx context.Context, clusterName string, memberStatus *toolchainv1alpha1.MemberStatusStatus) {
+	if r.CircuitBreakers == nil {
+		return
+	}
+	breakerStatus := r.CircuitBreakers.Get(clusterName).Status()
+	cond := toolchainv1alpha1.Condition{
+		Type:               ConditionMemberClientCircuitBreakerClosed,
+		Status:             corev1.ConditionTrue,
+		Reason:             circuitBreakerReason(breakerStatus.State),
+		LastTransitionTime: metav1.NewTime(breakerStatus.LastTransitionTime),
+	}
+	if breakerStatus.State != hostcluster.CircuitClosed {
+		cond.Status = corev1.ConditionFalse
+		cond.Message = fmt.Sprintf("%d consecutive failed requests, next attempt at %s: %s",
+			breakerStatus.Failures, breakerStatus.RetryAt.UTC().Format(time.RFC3339), breakerStatus.LastError)
+		log.FromContext(ctx).Info("the circuit breaker of the member cluster is not closed", "cluster", clusterName, "state", breakerStatus.State, "message", cond.Message)
+	}
+	// copy the conditions, which may be shared with the MemberStatus
+	memberStatus.Conditions = append(append([]toolchainv1alpha1.Condition{}, memberStatus.Conditions...), cond)
+}
+
+func circuitBreakerReason(state hostcluster.CircuitState) string {
+	return "CircuitBreaker" + string(state)
+}
+
 func getAPIEndpoint(clusterName string, memberClusters []*cluster.CachedToolchainCluster) string {
 	for _, memberCluster := range memberClusters {
 		if memberCluster.Name == clusterName {
@@ -498,22 +580,20 @@ func removeSchemeFromURL(proxyURL string) (string, error) {
 }
 
 func (r *Reconciler) sendToolchainStatusNotification(ctx context.Context,
-	toolchainStatus *toolchainv1alpha1.ToolchainStatus, status toolchainStatusNotificationType) error {
+	toolchainStatus *toolchainv1alpha1.ToolchainStatus, status toolchainStatusNotificationType, recipients string) error {
 	logger := log.FromContext(ctx)
-	config, err := toolchainconfig.GetToolchainConfig(r.Client)
-	if err != nil {
-		return errs.Wrapf(err, "unable to get ToolchainConfig")
-	}
 
 	tsValue := time.Now().Format("20060102150405")
 	contentString := ""
 	subjectString := ""
 	domain := ""
+	var err error
 	if domain, err = removeSchemeFromURL(toolchainStatus.Status.HostRoutes.ProxyURL); err != nil {
 		logger.Error(err, fmt.Sprintf("Error while parsing proxyUrl %v", toolchainStatus.Status.HostRoutes.ProxyURL))
 	}
 	switch status {
-	case unreadyStatus:
+	case unreadyStatus, stillUnreadyStatus, escalatedStatus:
+		unreadySince := unreadyAlertSince(toolchainStatus)
 		toolchainStatus = toolchainStatus.DeepCopy()
 		toolchainStatus.ManagedFields = nil // we don't need these managed fields in the notification
 		clusterURLs := ClusterURLs(logger, toolchainStatus)
@@ -521,7 +601,14 @@ func (r *Reconciler) sendToolchainStatusNotification(ctx context.Context,
 		if err != nil {
 			return err
 		}
-		subjectString = fmt.Sprintf(adminUnreadyNotificationSubject, domain)
+		switch status {
+		case stillUnreadyStatus:
+			subjectString = fmt.Sprintf(adminStillUnreadyNotificationSubject, domain)
+		case escalatedStatus:
+			subjectString = fmt.Sprintf(adminEscalatedNotificationSubject, unreadySince.UTC().Format(time.RFC3339), domain)
+		default:
+			subjectString = fmt.Sprintf(adminUnreadyNotificationSubject, domain)
+		}
 	case restoredStatus:
 		contentString = "<div><pre>ToolchainStatus is back to ready status.</pre></div>"
 		subjectString = fmt.Sprintf(adminRestoredNotificationSubject, domain)
@@ -533,7 +620,8 @@ func (r *Reconciler) sendToolchainStatusNotification(ctx context.Context,
 		WithName(fmt.Sprintf("toolchainstatus-%s-%s", string(status), tsValue)).
 		WithControllerReference(toolchainStatus, r.Scheme).
 		WithSubjectAndContent(subjectString, contentString).
-		Create(ctx, config.Notifications().AdminEmail())
+		WithNotificationType("toolchainstatus-"+string(status)).
+		Create(ctx, recipients)
 
 	if err != nil {
 		logger.Error(err, fmt.Sprintf("Failed to create toolchain status %s notification resource", status))
@@ -643,6 +731,16 @@ func ExtractStatusMetadata(instance *toolchainv1alpha1.ToolchainStatus) []*Compo
 				})
 			}
 
+			cond, found = condition.FindConditionByType(member.MemberStatus.Conditions, ConditionMemberClientCircuitBreakerClosed)
+			if found && cond.Status != corev1.ConditionTrue {
+				result = append(result, &ComponentNotReadyStatus{
+					ComponentType: "Member Client",
+					ComponentName: member.ClusterName,
+					Reason:        cond.Reason,
+					Message:       cond.Message,
+				})
+			}
+
 			if member.MemberStatus.Routes != nil {
 				cond, found = condition.FindConditionByType(member.MemberStatus.Routes.Conditions, toolchainv1alpha1.ConditionReady)
 				if found && cond.Status != corev1.ConditionTrue {
@@ -705,6 +803,17 @@ func ExtractStatusMetadata(instance *toolchainv1alpha1.ToolchainStatus) []*Compo
 		}
 	}
 
+	for _, cond := range instance.Status.Conditions {
+		if strings.HasPrefix(string(cond.Type), HealthCheckConditionTypePrefix) && cond.Status != corev1.ConditionTrue {
+			result = append(result, &ComponentNotReadyStatus{
+				ComponentName: strings.TrimPrefix(string(cond.Type), HealthCheckConditionTypePrefix),
+				ComponentType: "Health Check",
+				Reason:        cond.Reason,
+				Message:       cond.Message,
+			})
+		}
+	}
+
 	// Safety check - confirm the status metadata has all nil details values initialized to an empty map
 	for _, meta := range result {
 		if meta.Details == nil {
@@ -786,23 +895,37 @@ func (r *Reconciler) wrapErrorWithStatusUpdate(ctx context.Context, toolchainSta
 }
 
 func (r *Reconciler) setStatusReady(ctx context.Context, toolchainStatus *toolchainv1alpha1.ToolchainStatus) error {
-	err := r.restoredCheck(ctx, toolchainStatus)
+	config, err := toolchainconfig.GetToolchainConfig(r.Client)
 	if err != nil {
+		return errs.Wrapf(err, "unable to get ToolchainConfig")
+	}
+	ready := toolchainv1alpha1.Condition{
+		Type:   toolchainv1alpha1.ConditionReady,
+		Status: corev1.ConditionTrue,
+		Reason: toolchainv1alpha1.ToolchainStatusAllComponentsReadyReason,
+	}
+	if restoredNotificationPending(config.ToolchainStatus(), toolchainStatus) {
+		// wait until the end of the flap window before sending the restored notification
+		return r.updateStatusConditions(ctx, toolchainStatus, ready, toolchainv1alpha1.Condition{
+			Type:   toolchainv1alpha1.ToolchainStatusUnreadyNotificationCreated,
+			Status: corev1.ConditionTrue,
+			Reason: ToolchainStatusRestoredNotificationPendingReason,
+		})
+	}
+	if err := r.restoredCheck(ctx, toolchainStatus, unreadyAlertRecipients(config, toolchainStatus)); err != nil {
 		return err
 	}
 	return r.updateStatusConditions(
 		ctx,
 		toolchainStatus,
-		toolchainv1alpha1.Condition{
-			Type:   toolchainv1alpha1.ConditionReady,
-			Status: corev1.ConditionTrue,
-			Reason: toolchainv1alpha1.ToolchainStatusAllComponentsReadyReason,
-		},
-		toolchainv1alpha1.Condition{
-			Type:   toolchainv1alpha1.ToolchainStatusUnreadyNotificationCreated,
-			Status: corev1.ConditionFalse,
-			Reason: toolchainv1alpha1.ToolchainStatusAllComponentsReadyReason,
-		})
+		append([]toolchainv1alpha1.Condition{
+			ready,
+			{
+				Type:   toolchainv1alpha1.ToolchainStatusUnreadyNotificationCreated,
+				Status: corev1.ConditionFalse,
+				Reason: toolchainv1alpha1.ToolchainStatusAllComponentsReadyReason,
+			},
+		}, resetUnreadyAlertPolicyConditions(toolchainStatus)...)...)
 }
 
 func (r *Reconciler) setStatusNotReady(ctx context.Context, toolchainStatus *toolchainv1alpha1.ToolchainStatus, message string) error {
@@ -868,6 +991,7 @@ type regServiceSubstatusHandler struct {
 	httpClientImpl      HTTPClient
 	controllerClient    runtimeclient.Client
 	versionCheckManager status.VersionCheckManager
+	httpRevisions       *httpRevisionCache
 }
 
 // addRegistrationServiceDeploymentStatus handles the RegistrationService.Deployment part of the toolchainstatus
@@ -960,14 +1084,13 @@ func (s *regServiceSubstatusHandler) addRegistrationServiceHealthAndRevisionChec
 	}
 
 	// validate deployed version
-	isProd := isProdEnvironment(toolchainConfig)
 	githubRepo := client.GitHubRepository{
 		Org:               toolchainv1alpha1.ProviderLabelValue,
 		Name:              registrationServiceRepoName,
 		Branch:            registrationServiceRepoBranchName,
 		DeployedCommitSHA: healthValues.Revision,
 	}
-	versionCondition := s.versionCheckManager.CheckDeployedVersionIsUpToDate(ctx, isProd, toolchainConfig.GitHubSecret().AccessTokenKey(), toolchainStatus.Status.RegistrationService.RevisionCheck.Conditions, githubRepo)
+	versionCondition := checkDeployedRevision(ctx, &s.versionCheckManager, s.httpRevisions, s.httpClientImpl, toolchainConfig, toolchainStatus.Status.RegistrationService.RevisionCheck.Conditions, githubRepo)
 	err = status.ValidateComponentConditionReady(*versionCondition)
 	if err != nil {
 		// add version is not up-to-date condition
@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"net/url"
 	"os"
 	"reflect"
 	"strings"
@@ -21,6 +22,7 @@ import (
 
 	toolchainv1alpha1 "github.com/codeready-toolchain/api/api/v1alpha1"
 	"github.com/codeready-toolchain/host-operator/controllers/toolchainconfig"
+	hostcluster "github.com/codeready-toolchain/host-operator/pkg/cluster"
 	"github.com/codeready-toolchain/host-operator/pkg/counter"
 	"github.com/codeready-toolchain/host-operator/pkg/metrics"
 	"github.com/codeready-toolchain/host-operator/pkg/templates/registrationservice"
@@ -64,6 +66,10 @@ func (f *fakeHTTPClient) Get(_ string) (*http.Response, error) {
 	return &f.response, f.err
 }
 
+func (f *fakeHTTPClient) Do(_ *http.Request) (*http.Response, error) {
+	return &f.response, f.err
+}
+
 const buildCommitSHA = "64af1be5c6011fae5497a7c35e2a986d633b3421"
 const respBodyGood = `{"alive":true,"environment":"prod","revision":"` + buildCommitSHA + `","buildTime":"0","startTime":"2020-07-06T13:18:30Z"}`
 const respBodyInvalid = `{"not found"}`
@@ -196,16 +202,43 @@ func TestToolchainStatusConditions(t *testing.T) {
 			HasConditions(componentsReady(), unreadyNotificationNotCreated()).
 			HasHostOperatorStatus(hostOperatorStatusWithConditions(defaultHostOperatorDeploymentName,
 				conditionReady(toolchainv1alpha1.ToolchainStatusDeploymentReadyReason),
-				conditionReady(toolchainv1alpha1.ToolchainStatusDeploymentUpToDateReason),
+				conditionReadyWithMessage(toolchainv1alpha1.ToolchainStatusDeploymentUpToDateReason, "revision check source: github"),
 			)).
 			HasMemberClusterStatus(memberCluster("member-1", ready()), memberCluster("member-2", ready())).
 			HasRegistrationServiceStatus(registrationServiceReady(
 				conditionReady(toolchainv1alpha1.ToolchainStatusRegServiceReadyReason),
-				conditionReady(toolchainv1alpha1.ToolchainStatusDeploymentUpToDateReason),
+				conditionReadyWithMessage(toolchainv1alpha1.ToolchainStatusDeploymentUpToDateReason, "revision check source: github"),
 			)). // also regservice is not up-to-date since we return the same mocked github commit
 			HasHostRoutesStatus(hostProxyURL, hostRoutesAvailable())
 	})
 
+	t.Run("capacity alerts can't be checked", func(t *testing.T) {
+		// given
+		toolchainConfig := commonconfig.NewToolchainConfigObjWithReset(t, testconfig.Notifications().AdminEmail("admin@acme.com"))
+		hostOperatorDeployment := newDeploymentWithConditions(defaultHostOperatorDeploymentName, status.DeploymentAvailableCondition(), status.DeploymentProgressingCondition())
+		registrationServiceDeployment := newDeploymentWithConditions(registrationservice.ResourceName, status.DeploymentAvailableCondition(), status.DeploymentProgressingCondition())
+		memberStatus := newMemberStatus(ready())
+		toolchainStatus := NewToolchainStatus()
+		reconciler, req, fakeClient := prepareReconcile(t, requestName, newResponseGood(), mockLastGitHubAPICall, defaultGitHubClient, []string{"member-1", "member-2"},
+			hostOperatorDeployment, memberStatus, registrationServiceDeployment, toolchainStatus, proxyRoute(), toolchainConfig)
+		fakeClient.MockList = func(ctx context.Context, list runtimeclient.ObjectList, opts ...runtimeclient.ListOption) error {
+			if _, ok := list.(*toolchainv1alpha1.SpaceProvisionerConfigList); ok {
+				return fmt.Errorf("some error")
+			}
+			return fakeClient.Client.List(ctx, list, opts...)
+		}
+
+		// when
+		res, err := reconciler.Reconcile(context.TODO(), req)
+
+		// then
+		// the reconcile fails so that the alerts are checked again, but the status is updated anyway
+		require.EqualError(t, err, "unable to check the capacity alerts: unable to list the SpaceProvisionerConfigs: some error")
+		assert.Equal(t, requeueResult, res)
+		AssertThatToolchainStatus(t, req.Namespace, requestName, fakeClient).
+			HasConditions(componentsReady(), unreadyNotificationNotCreated())
+	})
+
 	t.Run("HostOperator tests", func(t *testing.T) {
 		toolchainStatus := NewToolchainStatus()
 		registrationServiceDeployment := newDeploymentWithConditions(registrationservice.ResourceName, status.DeploymentAvailableCondition(), status.DeploymentProgressingCondition())
@@ -231,7 +264,7 @@ func TestToolchainStatusConditions(t *testing.T) {
 					conditionReady(toolchainv1alpha1.ToolchainStatusDeploymentUpToDateReason),
 				)).
 				HasMemberClusterStatus(memberCluster("member-1", ready()), memberCluster("member-2", ready())).
-				HasRegistrationServiceStatus(registrationServiceReady(conditionReady(toolchainv1alpha1.ToolchainStatusRegServiceReadyReason), conditionReadyWithMessage(toolchainv1alpha1.ToolchainStatusDeploymentRevisionCheckDisabledReason, "access token key is not provided"))).
+				HasRegistrationServiceStatus(registrationServiceReady(conditionReady(toolchainv1alpha1.ToolchainStatusRegServiceReadyReason), conditionReadyWithMessage(toolchainv1alpha1.ToolchainStatusDeploymentRevisionCheckDisabledReason, "access token key is not provided (revision check source: github)"))).
 				HasHostRoutesStatus(hostProxyURL, hostRoutesAvailable())
 		})
 
@@ -253,7 +286,7 @@ func TestToolchainStatusConditions(t *testing.T) {
 					conditionReady(toolchainv1alpha1.ToolchainStatusDeploymentUpToDateReason),
 				)).
 				HasMemberClusterStatus(memberCluster("member-1", ready()), memberCluster("member-2", ready())).
-				HasRegistrationServiceStatus(registrationServiceReady(conditionReady(toolchainv1alpha1.ToolchainStatusRegServiceReadyReason), conditionReadyWithMessage(toolchainv1alpha1.ToolchainStatusDeploymentRevisionCheckDisabledReason, "access token key is not provided"))).
+				HasRegistrationServiceStatus(registrationServiceReady(conditionReady(toolchainv1alpha1.ToolchainStatusRegServiceReadyReason), conditionReadyWithMessage(toolchainv1alpha1.ToolchainStatusDeploymentRevisionCheckDisabledReason, "access token key is not provided (revision check source: github)"))).
 				HasHostRoutesStatus(hostProxyURL, hostRoutesAvailable())
 		})
 
@@ -276,7 +309,7 @@ func TestToolchainStatusConditions(t *testing.T) {
 					conditionReady(toolchainv1alpha1.ToolchainStatusDeploymentUpToDateReason),
 				)).
 				HasMemberClusterStatus(memberCluster("member-1", ready()), memberCluster("member-2", ready())).
-				HasRegistrationServiceStatus(registrationServiceReady(conditionReady(toolchainv1alpha1.ToolchainStatusRegServiceReadyReason), conditionReadyWithMessage(toolchainv1alpha1.ToolchainStatusDeploymentRevisionCheckDisabledReason, "access token key is not provided"))).
+				HasRegistrationServiceStatus(registrationServiceReady(conditionReady(toolchainv1alpha1.ToolchainStatusRegServiceReadyReason), conditionReadyWithMessage(toolchainv1alpha1.ToolchainStatusDeploymentRevisionCheckDisabledReason, "access token key is not provided (revision check source: github)"))).
 				HasHostRoutesStatus(hostProxyURL, hostRoutesAvailable())
 		})
 
@@ -299,7 +332,7 @@ func TestToolchainStatusConditions(t *testing.T) {
 					conditionReady(toolchainv1alpha1.ToolchainStatusDeploymentUpToDateReason),
 				)).
 				HasMemberClusterStatus(memberCluster("member-1", ready()), memberCluster("member-2", ready())).
-				HasRegistrationServiceStatus(registrationServiceReady(conditionReady(toolchainv1alpha1.ToolchainStatusRegServiceReadyReason), conditionReadyWithMessage(toolchainv1alpha1.ToolchainStatusDeploymentRevisionCheckDisabledReason, "access token key is not provided"))).
+				HasRegistrationServiceStatus(registrationServiceReady(conditionReady(toolchainv1alpha1.ToolchainStatusRegServiceReadyReason), conditionReadyWithMessage(toolchainv1alpha1.ToolchainStatusDeploymentRevisionCheckDisabledReason, "access token key is not provided (revision check source: github)"))).
 				HasHostRoutesStatus(hostProxyURL, hostRoutesAvailable())
 		})
 
@@ -326,12 +359,12 @@ func TestToolchainStatusConditions(t *testing.T) {
 			AssertThatToolchainStatus(t, req.Namespace, requestName, fakeClient).
 				HasHostOperatorStatus(hostOperatorStatusWithConditions(defaultHostOperatorDeploymentName,
 					conditionReady(toolchainv1alpha1.ToolchainStatusDeploymentReadyReason),
-					conditionNotReady(toolchainv1alpha1.ToolchainStatusDeploymentNotUpToDateReason, "deployment version is not up to date with latest github commit SHA. deployed commit SHA "+version.Commit+" ,github latest SHA "+latestCommitSHA+", expected deployment timestamp: "+commitTimeStamp.Add(status.DeploymentThreshold).Format(time.RFC3339)),
+					conditionNotReady(toolchainv1alpha1.ToolchainStatusDeploymentNotUpToDateReason, "deployment version is not up to date with latest github commit SHA. deployed commit SHA "+version.Commit+" ,github latest SHA "+latestCommitSHA+", expected deployment timestamp: "+commitTimeStamp.Add(status.DeploymentThreshold).Format(time.RFC3339)+" (revision check source: github)"),
 				)).
 				HasMemberClusterStatus(memberCluster("member-1", ready()), memberCluster("member-2", ready())).
 				HasRegistrationServiceStatus(registrationServiceReady(
 					conditionReady(toolchainv1alpha1.ToolchainStatusRegServiceReadyReason),
-					conditionNotReady(toolchainv1alpha1.ToolchainStatusDeploymentNotUpToDateReason, "deployment version is not up to date with latest github commit SHA. deployed commit SHA "+version.Commit+" ,github latest SHA "+latestCommitSHA+", expected deployment timestamp: "+commitTimeStamp.Add(status.DeploymentThreshold).Format(time.RFC3339)),
+					conditionNotReady(toolchainv1alpha1.ToolchainStatusDeploymentNotUpToDateReason, "deployment version is not up to date with latest github commit SHA. deployed commit SHA "+version.Commit+" ,github latest SHA "+latestCommitSHA+", expected deployment timestamp: "+commitTimeStamp.Add(status.DeploymentThreshold).Format(time.RFC3339)+" (revision check source: github)"),
 				)). // also regservice is not up-to-date since we return the same mocked github commit
 				HasHostRoutesStatus(hostProxyURL, hostRoutesAvailable())
 		})
@@ -359,12 +392,12 @@ func TestToolchainStatusConditions(t *testing.T) {
 				AssertThatToolchainStatus(t, req.Namespace, requestName, fakeClient).
 					HasHostOperatorStatus(hostOperatorStatusWithConditions(defaultHostOperatorDeploymentName,
 						conditionReady(toolchainv1alpha1.ToolchainStatusDeploymentReadyReason),
-						conditionReadyWithMessage(toolchainv1alpha1.ToolchainStatusDeploymentRevisionCheckDisabledReason, "is not running in prod environment"),
+						conditionReadyWithMessage(toolchainv1alpha1.ToolchainStatusDeploymentRevisionCheckDisabledReason, "is not running in prod environment (revision check source: github)"),
 					)).
 					HasMemberClusterStatus(memberCluster("member-1", ready()), memberCluster("member-2", ready())).
 					HasRegistrationServiceStatus(registrationServiceReady(
 						conditionReady(toolchainv1alpha1.ToolchainStatusRegServiceReadyReason),
-						conditionReadyWithMessage(toolchainv1alpha1.ToolchainStatusDeploymentRevisionCheckDisabledReason, "is not running in prod environment"),
+						conditionReadyWithMessage(toolchainv1alpha1.ToolchainStatusDeploymentRevisionCheckDisabledReason, "is not running in prod environment (revision check source: github)"),
 					)).
 					HasHostRoutesStatus(hostProxyURL, hostRoutesAvailable())
 			})
@@ -391,12 +424,12 @@ func TestToolchainStatusConditions(t *testing.T) {
 				AssertThatToolchainStatus(t, req.Namespace, requestName, fakeClient).
 					HasHostOperatorStatus(hostOperatorStatusWithConditions(defaultHostOperatorDeploymentName,
 						conditionReady(toolchainv1alpha1.ToolchainStatusDeploymentReadyReason),
-						conditionReadyWithMessage(toolchainv1alpha1.ToolchainStatusDeploymentRevisionCheckDisabledReason, "access token key is not provided"),
+						conditionReadyWithMessage(toolchainv1alpha1.ToolchainStatusDeploymentRevisionCheckDisabledReason, "access token key is not provided (revision check source: github)"),
 					)).
 					HasMemberClusterStatus(memberCluster("member-1", ready()), memberCluster("member-2", ready())).
 					HasRegistrationServiceStatus(registrationServiceReady(
 						conditionReady(toolchainv1alpha1.ToolchainStatusRegServiceReadyReason),
-						conditionReadyWithMessage(toolchainv1alpha1.ToolchainStatusDeploymentRevisionCheckDisabledReason, "access token key is not provided"),
+						conditionReadyWithMessage(toolchainv1alpha1.ToolchainStatusDeploymentRevisionCheckDisabledReason, "access token key is not provided (revision check source: github)"),
 					)).
 					HasHostRoutesStatus(hostProxyURL, hostRoutesAvailable())
 			})
@@ -449,12 +482,12 @@ func TestToolchainStatusConditions(t *testing.T) {
 				AssertThatToolchainStatus(t, req.Namespace, requestName, fakeClient).
 					HasHostOperatorStatus(hostOperatorStatusWithConditions(defaultHostOperatorDeploymentName,
 						conditionReady(toolchainv1alpha1.ToolchainStatusDeploymentReadyReason),
-						conditionReady(toolchainv1alpha1.ToolchainStatusDeploymentUpToDateReason),
+						conditionReadyWithMessage(toolchainv1alpha1.ToolchainStatusDeploymentUpToDateReason, "revision check source: github"),
 					)).
 					HasMemberClusterStatus(memberCluster("member-1", ready()), memberCluster("member-2", ready())).
 					HasRegistrationServiceStatus(registrationServiceReady(
 						conditionReady(toolchainv1alpha1.ToolchainStatusRegServiceReadyReason),
-						conditionReady(toolchainv1alpha1.ToolchainStatusDeploymentUpToDateReason),
+						conditionReadyWithMessage(toolchainv1alpha1.ToolchainStatusDeploymentUpToDateReason, "revision check source: github"),
 					)).
 					HasHostRoutesStatus(hostProxyURL, hostRoutesAvailable())
 			})
@@ -633,7 +666,7 @@ func TestToolchainStatusConditions(t *testing.T) {
 				HasConditions(componentsNotReady(string(hostRoutesTag))).
 				HasHostOperatorStatus(hostOperatorStatusReady()).
 				HasMemberClusterStatus(memberCluster("member-1", ready()), memberCluster("member-2", ready())).
-				HasRegistrationServiceStatus(registrationServiceReady(conditionReady(toolchainv1alpha1.ToolchainStatusRegServiceReadyReason), conditionReadyWithMessage(toolchainv1alpha1.ToolchainStatusDeploymentRevisionCheckDisabledReason, "access token key is not provided"))).
+				HasRegistrationServiceStatus(registrationServiceReady(conditionReady(toolchainv1alpha1.ToolchainStatusRegServiceReadyReason), conditionReadyWithMessage(toolchainv1alpha1.ToolchainStatusDeploymentRevisionCheckDisabledReason, "access token key is not provided (revision check source: github)"))).
 				HasHostRoutesStatus("", proxyRouteUnavailable("routes.route.openshift.io \"api\" not found"))
 		})
 
@@ -655,7 +688,7 @@ func TestToolchainStatusConditions(t *testing.T) {
 				HasConditions(componentsReady(), unreadyNotificationNotCreated()).
 				HasHostOperatorStatus(hostOperatorStatusReady()).
 				HasMemberClusterStatus(memberCluster("member-1", ready()), memberCluster("member-2", ready())).
-				HasRegistrationServiceStatus(registrationServiceReady(conditionReady(toolchainv1alpha1.ToolchainStatusRegServiceReadyReason), conditionReadyWithMessage(toolchainv1alpha1.ToolchainStatusDeploymentRevisionCheckDisabledReason, "access token key is not provided"))).
+				HasRegistrationServiceStatus(registrationServiceReady(conditionReady(toolchainv1alpha1.ToolchainStatusRegServiceReadyReason), conditionReadyWithMessage(toolchainv1alpha1.ToolchainStatusDeploymentRevisionCheckDisabledReason, "access token key is not provided (revision check source: github)"))).
 				HasHostRoutesStatus("http://api-toolchain-host-operator.apps.host-cluster/api", hostRoutesAvailable())
 		})
 	})
@@ -683,7 +716,7 @@ func TestToolchainStatusConditions(t *testing.T) {
 				HasRegistrationServiceStatus(
 					registrationServiceReady(
 						conditionReady(toolchainv1alpha1.ToolchainStatusRegServiceReadyReason),
-						conditionReadyWithMessage(toolchainv1alpha1.ToolchainStatusDeploymentRevisionCheckDisabledReason, "access token key is not provided"))).
+						conditionReadyWithMessage(toolchainv1alpha1.ToolchainStatusDeploymentRevisionCheckDisabledReason, "access token key is not provided (revision check source: github)"))).
 				HasHostRoutesStatus(hostProxyURL, hostRoutesAvailable())
 		})
 
@@ -717,7 +750,7 @@ func TestToolchainStatusConditions(t *testing.T) {
 					memberCluster("member-1", spaceCount(10), noResourceUsage(), notReady("MemberToolchainClusterMissing", "ToolchainCluster CR wasn't found for member cluster `member-1` that was previously registered in the host")),
 					memberCluster("member-2", spaceCount(10), noResourceUsage(), notReady("MemberToolchainClusterMissing", "ToolchainCluster CR wasn't found for member cluster `member-2` that was previously registered in the host")),
 				).
-				HasRegistrationServiceStatus(registrationServiceReady(conditionReady(toolchainv1alpha1.ToolchainStatusRegServiceReadyReason), conditionReadyWithMessage(toolchainv1alpha1.ToolchainStatusDeploymentRevisionCheckDisabledReason, "access token key is not provided"))).
+				HasRegistrationServiceStatus(registrationServiceReady(conditionReady(toolchainv1alpha1.ToolchainStatusRegServiceReadyReason), conditionReadyWithMessage(toolchainv1alpha1.ToolchainStatusDeploymentRevisionCheckDisabledReason, "access token key is not provided (revision check source: github)"))).
 				HasHostRoutesStatus(hostProxyURL, hostRoutesAvailable())
 		})
 
@@ -754,7 +787,7 @@ func TestToolchainStatusConditions(t *testing.T) {
 					memberCluster("member-1", spaceCount(10), noResourceUsage(), notReady("MemberToolchainClusterMissing", "ToolchainCluster CR wasn't found for member cluster `member-1` that was previously registered in the host")),
 					memberCluster("member-2", spaceCount(10), ready()),
 				).
-				HasRegistrationServiceStatus(registrationServiceReady(conditionReady(toolchainv1alpha1.ToolchainStatusRegServiceReadyReason), conditionReadyWithMessage(toolchainv1alpha1.ToolchainStatusDeploymentRevisionCheckDisabledReason, "access token key is not provided"))).
+				HasRegistrationServiceStatus(registrationServiceReady(conditionReady(toolchainv1alpha1.ToolchainStatusRegServiceReadyReason), conditionReadyWithMessage(toolchainv1alpha1.ToolchainStatusDeploymentRevisionCheckDisabledReason, "access token key is not provided (revision check source: github)"))).
 				HasHostRoutesStatus(hostProxyURL, hostRoutesAvailable())
 		})
 
@@ -790,7 +823,7 @@ func TestToolchainStatusConditions(t *testing.T) {
 					memberCluster("member-1", spaceCount(10), ready()),
 					memberCluster("member-2", spaceCount(10), noResourceUsage(), notReady("MemberToolchainClusterMissing", "ToolchainCluster CR wasn't found for member cluster `member-2` that was previously registered in the host")),
 				).
-				HasRegistrationServiceStatus(registrationServiceReady(conditionReady(toolchainv1alpha1.ToolchainStatusRegServiceReadyReason), conditionReadyWithMessage(toolchainv1alpha1.ToolchainStatusDeploymentRevisionCheckDisabledReason, "access token key is not provided"))).
+				HasRegistrationServiceStatus(registrationServiceReady(conditionReady(toolchainv1alpha1.ToolchainStatusRegServiceReadyReason), conditionReadyWithMessage(toolchainv1alpha1.ToolchainStatusDeploymentRevisionCheckDisabledReason, "access token key is not provided (revision check source: github)"))).
 				HasHostRoutesStatus(hostProxyURL, hostRoutesAvailable())
 		})
 
@@ -815,7 +848,7 @@ func TestToolchainStatusConditions(t *testing.T) {
 				HasConditions(componentsReady(), unreadyNotificationNotCreated()).
 				HasHostOperatorStatus(hostOperatorStatusReady()).
 				HasMemberClusterStatus(memberCluster("member-1", ready()), memberCluster("member-2", ready())).
-				HasRegistrationServiceStatus(registrationServiceReady(conditionReady(toolchainv1alpha1.ToolchainStatusRegServiceReadyReason), conditionReadyWithMessage(toolchainv1alpha1.ToolchainStatusDeploymentRevisionCheckDisabledReason, "access token key is not provided"))).
+				HasRegistrationServiceStatus(registrationServiceReady(conditionReady(toolchainv1alpha1.ToolchainStatusRegServiceReadyReason), conditionReadyWithMessage(toolchainv1alpha1.ToolchainStatusDeploymentRevisionCheckDisabledReason, "access token key is not provided (revision check source: github)"))).
 				HasHostRoutesStatus(hostProxyURL, hostRoutesAvailable())
 		})
 
@@ -839,7 +872,7 @@ func TestToolchainStatusConditions(t *testing.T) {
 					memberCluster("member-1", noResourceUsage(), spaceCount(0), notReady("MemberStatusNotFound", "memberstatuses.toolchain.dev.openshift.com \"toolchain-member-status\" not found")),
 					memberCluster("member-2", noResourceUsage(), spaceCount(0), notReady("MemberStatusNotFound", "memberstatuses.toolchain.dev.openshift.com \"toolchain-member-status\" not found")),
 				).
-				HasRegistrationServiceStatus(registrationServiceReady(conditionReady(toolchainv1alpha1.ToolchainStatusRegServiceReadyReason), conditionReadyWithMessage(toolchainv1alpha1.ToolchainStatusDeploymentRevisionCheckDisabledReason, "access token key is not provided"))).
+				HasRegistrationServiceStatus(registrationServiceReady(conditionReady(toolchainv1alpha1.ToolchainStatusRegServiceReadyReason), conditionReadyWithMessage(toolchainv1alpha1.ToolchainStatusDeploymentRevisionCheckDisabledReason, "access token key is not provided (revision check source: github)"))).
 				HasHostRoutesStatus(hostProxyURL, hostRoutesAvailable())
 		})
 
@@ -864,10 +897,55 @@ func TestToolchainStatusConditions(t *testing.T) {
 					memberCluster("member-1", notReady("ComponentsNotReady", "components not ready: [memberOperator]")),
 					memberCluster("member-2", notReady("ComponentsNotReady", "components not ready: [memberOperator]")),
 				).
-				HasRegistrationServiceStatus(registrationServiceReady(conditionReady(toolchainv1alpha1.ToolchainStatusRegServiceReadyReason), conditionReadyWithMessage(toolchainv1alpha1.ToolchainStatusDeploymentRevisionCheckDisabledReason, "access token key is not provided"))).
+				HasRegistrationServiceStatus(registrationServiceReady(conditionReady(toolchainv1alpha1.ToolchainStatusRegServiceReadyReason), conditionReadyWithMessage(toolchainv1alpha1.ToolchainStatusDeploymentRevisionCheckDisabledReason, "access token key is not provided (revision check source: github)"))).
 				HasHostRoutesStatus(hostProxyURL, hostRoutesAvailable())
 		})
 
+		t.Run("circuit breaker of a member cluster open", func(t *testing.T) {
+			// given
+			emptyToolchainStatus := NewToolchainStatus()
+			memberStatus := newMemberStatus(ready())
+			reconciler, req, fakeClient := prepareReconcile(t, requestName, newResponseGood(), mockLastGitHubAPICall, defaultGitHubClient, []string{"member-1", "member-2"},
+				hostOperatorDeployment, memberStatus, registrationServiceDeployment, emptyToolchainStatus, proxyRoute())
+			InitializeCounters(t, emptyToolchainStatus)
+			reconciler.CircuitBreakers = hostcluster.NewCircuitBreakers(func() hostcluster.CircuitBreakerSettings {
+				return hostcluster.CircuitBreakerSettings{FailureThreshold: 1, OpenDuration: time.Minute, MaxOpenDuration: time.Minute}
+			})
+			_ = reconciler.CircuitBreakers.Get("member-1").Do(func() error {
+				return &url.Error{Op: "Put", URL: "https://member-1", Err: fmt.Errorf("connection refused")}
+			})
+
+			// when
+			res, err := reconciler.Reconcile(context.TODO(), req)
+
+			// then
+			require.NoError(t, err)
+			assert.Equal(t, requeueResult, res)
+			// the state of the circuit breaker is reported, but doesn't make the member cluster not ready
+			AssertThatToolchainStatus(t, req.Namespace, requestName, fakeClient).
+				HasConditions(componentsReady(), unreadyNotificationNotCreated())
+			toolchainStatus := &toolchainv1alpha1.ToolchainStatus{}
+			require.NoError(t, fakeClient.Get(context.TODO(), test.NamespacedName(req.Namespace, requestName), toolchainStatus))
+			require.Len(t, toolchainStatus.Status.Members, 2)
+			for _, member := range toolchainStatus.Status.Members {
+				breakerCond, found := condition.FindConditionByType(member.MemberStatus.Conditions, ConditionMemberClientCircuitBreakerClosed)
+				require.True(t, found, "circuit breaker condition of %s", member.ClusterName)
+				readyCond, found := condition.FindConditionByType(member.MemberStatus.Conditions, toolchainv1alpha1.ConditionReady)
+				require.True(t, found)
+				assert.Equal(t, corev1.ConditionTrue, readyCond.Status)
+				if member.ClusterName == "member-1" {
+					assert.Equal(t, corev1.ConditionFalse, breakerCond.Status)
+					assert.Equal(t, "CircuitBreakerOpen", breakerCond.Reason)
+					assert.Contains(t, breakerCond.Message, "1 consecutive failed requests, next attempt at ")
+					assert.Contains(t, breakerCond.Message, `Put "https://member-1": connection refused`)
+				} else {
+					assert.Equal(t, corev1.ConditionTrue, breakerCond.Status)
+					assert.Equal(t, "CircuitBreakerClosed", breakerCond.Reason)
+					assert.Empty(t, breakerCond.Message)
+				}
+			}
+		})
+
 		t.Run("synchronization with the counter fails", func(t *testing.T) {
 			// given
 			emptyToolchainStatus := NewToolchainStatus()
@@ -888,7 +966,7 @@ func TestToolchainStatusConditions(t *testing.T) {
 				HasConditions(componentsNotReady(string(counterTag))).
 				HasHostOperatorStatus(hostOperatorStatusReady()).
 				HasMemberClusterStatus(memberCluster("member-1", ready()), memberCluster("member-2", ready())).
-				HasRegistrationServiceStatus(registrationServiceReady(conditionReady(toolchainv1alpha1.ToolchainStatusRegServiceReadyReason), conditionReadyWithMessage(toolchainv1alpha1.ToolchainStatusDeploymentRevisionCheckDisabledReason, "access token key is not provided"))).
+				HasRegistrationServiceStatus(registrationServiceReady(conditionReady(toolchainv1alpha1.ToolchainStatusRegServiceReadyReason), conditionReadyWithMessage(toolchainv1alpha1.ToolchainStatusDeploymentRevisionCheckDisabledReason, "access token key is not provided (revision check source: github)"))).
 				HasHostRoutesStatus(hostProxyURL, hostRoutesAvailable())
 		})
 
@@ -914,7 +992,7 @@ func TestToolchainStatusConditions(t *testing.T) {
 				HasConditions(componentsReady(), unreadyNotificationNotCreated()).
 				HasHostOperatorStatus(hostOperatorStatusReady()).
 				HasMemberClusterStatus(memberCluster("member-1", ready()), memberCluster("member-2", ready())).
-				HasRegistrationServiceStatus(registrationServiceReady(conditionReady(toolchainv1alpha1.ToolchainStatusRegServiceReadyReason), conditionReadyWithMessage(toolchainv1alpha1.ToolchainStatusDeploymentRevisionCheckDisabledReason, "access token key is not provided"))).
+				HasRegistrationServiceStatus(registrationServiceReady(conditionReady(toolchainv1alpha1.ToolchainStatusRegServiceReadyReason), conditionReadyWithMessage(toolchainv1alpha1.ToolchainStatusDeploymentRevisionCheckDisabledReason, "access token key is not provided (revision check source: github)"))).
 				HasHostRoutesStatus(hostProxyURL, hostRoutesAvailable())
 		})
 
@@ -936,7 +1014,7 @@ func TestToolchainStatusConditions(t *testing.T) {
 				HasConditions(componentsNotReady(string(memberConnectionsTag))).
 				HasHostOperatorStatus(hostOperatorStatusReady()).
 				HasMemberClusterStatus(memberCluster("member-1"), memberCluster("member-2")).
-				HasRegistrationServiceStatus(registrationServiceReady(conditionReady(toolchainv1alpha1.ToolchainStatusRegServiceReadyReason), conditionReadyWithMessage(toolchainv1alpha1.ToolchainStatusDeploymentRevisionCheckDisabledReason, "access token key is not provided"))).
+				HasRegistrationServiceStatus(registrationServiceReady(conditionReady(toolchainv1alpha1.ToolchainStatusRegServiceReadyReason), conditionReadyWithMessage(toolchainv1alpha1.ToolchainStatusDeploymentRevisionCheckDisabledReason, "access token key is not provided (revision check source: github)"))).
 				HasHostRoutesStatus(hostProxyURL, hostRoutesAvailable())
 		})
 
@@ -971,7 +1049,7 @@ func TestToolchainStatusConditions(t *testing.T) {
 						memberCluster("member-2", ready()),
 						memberCluster("member-3", noResourceUsage(), spaceCount(0), notReady("MemberToolchainClusterMissing", "ToolchainCluster CR wasn't found for member cluster `member-3` that was previously registered in the host")),
 					).
-					HasRegistrationServiceStatus(registrationServiceReady(conditionReady(toolchainv1alpha1.ToolchainStatusRegServiceReadyReason), conditionReadyWithMessage(toolchainv1alpha1.ToolchainStatusDeploymentRevisionCheckDisabledReason, "access token key is not provided"))).
+					HasRegistrationServiceStatus(registrationServiceReady(conditionReady(toolchainv1alpha1.ToolchainStatusRegServiceReadyReason), conditionReadyWithMessage(toolchainv1alpha1.ToolchainStatusDeploymentRevisionCheckDisabledReason, "access token key is not provided (revision check source: github)"))).
 					HasHostRoutesStatus(hostProxyURL, hostRoutesAvailable())
 			})
 
@@ -994,7 +1072,7 @@ func TestToolchainStatusConditions(t *testing.T) {
 					HasConditions(componentsReady(), unreadyNotificationNotCreated()).
 					HasHostOperatorStatus(hostOperatorStatusReady()).
 					HasMemberClusterStatus(memberCluster("member-1", ready()), memberCluster("member-2", ready())).
-					HasRegistrationServiceStatus(registrationServiceReady(conditionReady(toolchainv1alpha1.ToolchainStatusRegServiceReadyReason), conditionReadyWithMessage(toolchainv1alpha1.ToolchainStatusDeploymentRevisionCheckDisabledReason, "access token key is not provided"))).
+					HasRegistrationServiceStatus(registrationServiceReady(conditionReady(toolchainv1alpha1.ToolchainStatusRegServiceReadyReason), conditionReadyWithMessage(toolchainv1alpha1.ToolchainStatusDeploymentRevisionCheckDisabledReason, "access token key is not provided (revision check source: github)"))).
 					HasHostRoutesStatus(hostProxyURL, hostRoutesAvailable())
 			})
 		})
@@ -1028,7 +1106,7 @@ func TestToolchainStatusConditions(t *testing.T) {
 				HasMemberClusterStatus(
 					memberCluster("member-2", spaceCount(10), ready()), // member-1 status should be removed, only member-2 should remain
 				).
-				HasRegistrationServiceStatus(registrationServiceReady(conditionReady(toolchainv1alpha1.ToolchainStatusRegServiceReadyReason), conditionReadyWithMessage(toolchainv1alpha1.ToolchainStatusDeploymentRevisionCheckDisabledReason, "access token key is not provided"))).
+				HasRegistrationServiceStatus(registrationServiceReady(conditionReady(toolchainv1alpha1.ToolchainStatusRegServiceReadyReason), conditionReadyWithMessage(toolchainv1alpha1.ToolchainStatusDeploymentRevisionCheckDisabledReason, "access token key is not provided (revision check source: github)"))).
 				HasHostRoutesStatus(hostProxyURL, hostRoutesAvailable())
 		})
 
@@ -1157,7 +1235,7 @@ func TestToolchainStatusNotifications(t *testing.T) {
 				HasConditions(componentsReady(), unreadyNotificationNotCreated()).
 				HasHostOperatorStatus(hostOperatorStatusReady()).
 				HasMemberClusterStatus(memberCluster("member-1", ready()), memberCluster("member-2", ready())).
-				HasRegistrationServiceStatus(registrationServiceReady(conditionReady(toolchainv1alpha1.ToolchainStatusRegServiceReadyReason), conditionReadyWithMessage(toolchainv1alpha1.ToolchainStatusDeploymentRevisionCheckDisabledReason, "access token key is not provided"))).
+				HasRegistrationServiceStatus(registrationServiceReady(conditionReady(toolchainv1alpha1.ToolchainStatusRegServiceReadyReason), conditionReadyWithMessage(toolchainv1alpha1.ToolchainStatusDeploymentRevisionCheckDisabledReason, "access token key is not provided (revision check source: github)"))).
 				HasHostRoutesStatus(hostProxyURL, hostRoutesAvailable())
 
 			// Confirm there is no notification
@@ -1251,6 +1329,7 @@ func TestToolchainStatusNotifications(t *testing.T) {
 						// Confirm the unready notification has been created
 						notification := assertToolchainStatusNotificationCreated(t, fakeClient)
 						require.True(t, strings.HasPrefix(notification.Name, "toolchainstatus-unready-"))
+						require.Equal(t, NotificationTypeToolchainStatusUnready, notification.Labels[toolchainv1alpha1.NotificationTypeLabelKey])
 
 						require.NotNil(t, notification)
 						require.Equal(t, "ToolchainStatus has been in an unready status for an extended period for host-cluster", notification.Spec.Subject)
@@ -1280,6 +1359,7 @@ func TestToolchainStatusNotifications(t *testing.T) {
 							// Confirm restored notification has been created
 							notification := assertToolchainStatusNotificationCreated(t, fakeClient)
 							require.True(t, strings.HasPrefix(notification.Name, "toolchainstatus-restored-"))
+							require.Equal(t, NotificationTypeToolchainStatusRestored, notification.Labels[toolchainv1alpha1.NotificationTypeLabelKey])
 
 							fmt.Println(notification)
 							require.NotNil(t, notification)
@@ -1470,7 +1550,7 @@ func TestSynchronizationWithCounter(t *testing.T) {
 			HasMemberClusterStatus(
 				memberCluster("member-1", ready(), spaceCount(8)),
 				memberCluster("member-2", ready(), spaceCount(2))).
-			HasRegistrationServiceStatus(registrationServiceReady(conditionReady(toolchainv1alpha1.ToolchainStatusRegServiceReadyReason), conditionReadyWithMessage(toolchainv1alpha1.ToolchainStatusDeploymentRevisionCheckDisabledReason, "access token key is not provided"))).
+			HasRegistrationServiceStatus(registrationServiceReady(conditionReady(toolchainv1alpha1.ToolchainStatusRegServiceReadyReason), conditionReadyWithMessage(toolchainv1alpha1.ToolchainStatusDeploymentRevisionCheckDisabledReason, "access token key is not provided (revision check source: github)"))).
 			Exists().HasUsersPerActivationsAndDomain(toolchainv1alpha1.Metric{
 			"1,internal": 2, // users "cookie-00" and "pasta-00"
 			"2,internal": 2, // users "cookie-01" and "pasta-01"
@@ -1503,7 +1583,7 @@ func TestSynchronizationWithCounter(t *testing.T) {
 				HasMemberClusterStatus(
 					memberCluster("member-1", ready(), spaceCount(9)),
 					memberCluster("member-2", ready(), spaceCount(2))).
-				HasRegistrationServiceStatus(registrationServiceReady(conditionReady(toolchainv1alpha1.ToolchainStatusRegServiceReadyReason), conditionReadyWithMessage(toolchainv1alpha1.ToolchainStatusDeploymentRevisionCheckDisabledReason, "access token key is not provided")))
+				HasRegistrationServiceStatus(registrationServiceReady(conditionReady(toolchainv1alpha1.ToolchainStatusRegServiceReadyReason), conditionReadyWithMessage(toolchainv1alpha1.ToolchainStatusDeploymentRevisionCheckDisabledReason, "access token key is not provided (revision check source: github)")))
 		})
 
 	})
@@ -1544,7 +1624,7 @@ func TestSynchronizationWithCounter(t *testing.T) {
 			HasMemberClusterStatus(
 				memberCluster("member-1", ready(), spaceCount(7)), // was incremented
 				memberCluster("member-2", ready(), spaceCount(2))).
-			HasRegistrationServiceStatus(registrationServiceReady(conditionReady(toolchainv1alpha1.ToolchainStatusRegServiceReadyReason), conditionReadyWithMessage(toolchainv1alpha1.ToolchainStatusDeploymentRevisionCheckDisabledReason, "access token key is not provided"))).
+			HasRegistrationServiceStatus(registrationServiceReady(conditionReady(toolchainv1alpha1.ToolchainStatusRegServiceReadyReason), conditionReadyWithMessage(toolchainv1alpha1.ToolchainStatusDeploymentRevisionCheckDisabledReason, "access token key is not provided (revision check source: github)"))).
 			HasUsersPerActivationsAndDomain(toolchainv1alpha1.Metric{
 				"1,internal": 4, // was incremented by `counter.UpdateUsersPerActivationCounters(1)` but decremented `counter.UpdateUsersPerActivationCounters(2)`
 				"1,external": 1, // unchanged
@@ -1705,6 +1785,28 @@ func TestExtractStatusMetadata(t *testing.T) {
 		require.Equal(t, "member-sandbox.ccc.openshiftapps.com", meta[0].ComponentName)
 	})
 
+	t.Run("test status metadata for member client circuit breaker open", func(t *testing.T) {
+		// given
+		toolchainStatus := NewToolchainStatus(WithMember("member-sandbox.ccc.openshiftapps.com"))
+		toolchainStatus.Status.Members[0].MemberStatus.Conditions, _ = condition.AddOrUpdateStatusConditions(
+			toolchainStatus.Status.Members[0].MemberStatus.Conditions, toolchainv1alpha1.Condition{
+				Type:    ConditionMemberClientCircuitBreakerClosed,
+				Status:  corev1.ConditionFalse,
+				Reason:  "CircuitBreakerOpen",
+				Message: "5 consecutive failed requests",
+			})
+
+		// when
+		meta := ExtractStatusMetadata(toolchainStatus)
+
+		// then
+		require.Len(t, meta, 1)
+		require.Equal(t, "Member Client", meta[0].ComponentType)
+		require.Equal(t, "CircuitBreakerOpen", meta[0].Reason)
+		require.Equal(t, "5 consecutive failed requests", meta[0].Message)
+		require.Equal(t, "member-sandbox.ccc.openshiftapps.com", meta[0].ComponentName)
+	})
+
 	t.Run("test status metadata for registration service deployment not ready", func(t *testing.T) {
 		// given
 		toolchainStatus := NewToolchainStatus(WithRegistrationService(WithDeploymentCondition(
@@ -2030,7 +2132,7 @@ func hostOperatorStatusReady() toolchainv1alpha1.HostOperatorStatus {
 		DeploymentName: defaultHostOperatorDeploymentName,
 		Revision:       version.Commit,
 		Version:        version.Version,
-		RevisionCheck:  toolchainv1alpha1.RevisionCheck{Conditions: []toolchainv1alpha1.Condition{conditionReadyWithMessage(toolchainv1alpha1.ToolchainStatusDeploymentRevisionCheckDisabledReason, "access token key is not provided")}},
+		RevisionCheck:  toolchainv1alpha1.RevisionCheck{Conditions: []toolchainv1alpha1.Condition{conditionReadyWithMessage(toolchainv1alpha1.ToolchainStatusDeploymentRevisionCheckDisabledReason, "access token key is not provided (revision check source: github)")}},
 	}
 }
 
@@ -2158,7 +2260,7 @@ func registrationServiceDeploymentNotReady(reason, msg string) toolchainv1alpha1
 		Type:    toolchainv1alpha1.ConditionReady,
 		Status:  corev1.ConditionTrue,
 		Reason:  toolchainv1alpha1.ToolchainStatusDeploymentRevisionCheckDisabledReason,
-		Message: "access token key is not provided",
+		Message: "access token key is not provided (revision check source: github)",
 	}
 	return registrationServiceStatus(deploy, healtCondition, revisionCheckCondition)
 }
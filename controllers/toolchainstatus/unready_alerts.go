@@ -0,0 +1,146 @@
+package toolchainstatus
+
+import (
+	"context"
+	"time"
+
+	toolchainv1alpha1 "github.com/codeready-toolchain/api/api/v1alpha1"
+	"github.com/codeready-toolchain/host-operator/controllers/toolchainconfig"
+	"github.com/codeready-toolchain/toolchain-common/pkg/condition"
+
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+const (
+	// ToolchainStatusUnreadyNotificationRepeated is the condition type which is true once the unready notification was repeated because
+	// the ToolchainStatus is still unready. Its last updated time is the time of the last repeat.
+	ToolchainStatusUnreadyNotificationRepeated toolchainv1alpha1.ConditionType = "UnreadyNotificationRepeated"
+	// ToolchainStatusUnreadyNotificationEscalated is the condition type which is true once the unready alert was escalated
+	// to the escalation recipients
+	ToolchainStatusUnreadyNotificationEscalated toolchainv1alpha1.ConditionType = "UnreadyNotificationEscalated"
+
+	// ToolchainStatusUnreadyNotificationRepeatedReason is the reason of the ToolchainStatusUnreadyNotificationRepeated condition
+	ToolchainStatusUnreadyNotificationRepeatedReason = "UnreadyNotificationRepeated"
+	// ToolchainStatusUnreadyNotificationEscalatedReason is the reason of the ToolchainStatusUnreadyNotificationEscalated condition
+	ToolchainStatusUnreadyNotificationEscalatedReason = "UnreadyNotificationEscalated"
+	// ToolchainStatusRestoredNotificationPendingReason is the reason of the UnreadyNotificationCreated condition when the ToolchainStatus
+	// is ready again, but the restored notification is not sent until the end of the flap window
+	ToolchainStatusRestoredNotificationPendingReason = "RestoredNotificationPending"
+)
+
+// unreadyAlertPolicyCheck escalates or repeats the unready alert when the ToolchainStatus is still unready after the first notification
+func (r *Reconciler) unreadyAlertPolicyCheck(ctx context.Context, config toolchainconfig.ToolchainConfig, toolchainStatus *toolchainv1alpha1.ToolchainStatus) error {
+	logger := log.FromContext(ctx)
+	policy := config.ToolchainStatus()
+	created, _ := condition.FindConditionByType(toolchainStatus.Status.Conditions, toolchainv1alpha1.ToolchainStatusUnreadyNotificationCreated)
+	var newConditions []toolchainv1alpha1.Condition
+	if created.Reason == ToolchainStatusRestoredNotificationPendingReason {
+		// unready again within the flap window: this is still the same outage, so no new unready notification is sent
+		logger.Info("ToolchainStatus is unready again within the flap window")
+		newConditions = append(newConditions, toolchainv1alpha1.Condition{
+			Type:   toolchainv1alpha1.ToolchainStatusUnreadyNotificationCreated,
+			Status: corev1.ConditionTrue,
+			Reason: toolchainv1alpha1.ToolchainStatusUnreadyNotificationCRCreatedReason,
+		})
+	}
+
+	escalationDelay := policy.UnreadyAlertEscalationDelay()
+	escalationEmail := policy.UnreadyAlertEscalationEmail()
+	repeatInterval := policy.UnreadyAlertRepeatInterval()
+	switch {
+	case escalationDelay > 0 && escalationEmail != "" &&
+		!condition.IsTrue(toolchainStatus.Status.Conditions, ToolchainStatusUnreadyNotificationEscalated) &&
+		time.Since(created.LastTransitionTime.Time) >= escalationDelay:
+		if err := r.sendToolchainStatusNotification(ctx, toolchainStatus, escalatedStatus, escalationEmail); err != nil {
+			return err
+		}
+		newConditions = append(newConditions, toolchainv1alpha1.Condition{
+			Type:   ToolchainStatusUnreadyNotificationEscalated,
+			Status: corev1.ConditionTrue,
+			Reason: ToolchainStatusUnreadyNotificationEscalatedReason,
+		})
+	case repeatInterval > 0 && time.Since(lastUnreadyAlertTime(toolchainStatus)) >= repeatInterval:
+		if err := r.sendToolchainStatusNotification(ctx, toolchainStatus, stillUnreadyStatus, unreadyAlertRecipients(config, toolchainStatus)); err != nil {
+			return err
+		}
+		newConditions = append(newConditions, toolchainv1alpha1.Condition{
+			Type:   ToolchainStatusUnreadyNotificationRepeated,
+			Status: corev1.ConditionTrue,
+			Reason: ToolchainStatusUnreadyNotificationRepeatedReason,
+		})
+	}
+
+	if len(newConditions) == 0 {
+		return nil
+	}
+	return r.updateStatusConditions(ctx, toolchainStatus, newConditions...)
+}
+
+// lastUnreadyAlertTime returns when the last unready (or repeated, or escalated) notification was sent
+func lastUnreadyAlertTime(toolchainStatus *toolchainv1alpha1.ToolchainStatus) time.Time {
+	created, _ := condition.FindConditionByType(toolchainStatus.Status.Conditions, toolchainv1alpha1.ToolchainStatusUnreadyNotificationCreated)
+	last := created.LastTransitionTime.Time
+	for _, conditionType := range []toolchainv1alpha1.ConditionType{ToolchainStatusUnreadyNotificationRepeated, ToolchainStatusUnreadyNotificationEscalated} {
+		if c, found := condition.FindConditionByType(toolchainStatus.Status.Conditions, conditionType); found &&
+			c.Status == corev1.ConditionTrue && c.LastUpdatedTime != nil && c.LastUpdatedTime.After(last) {
+			last = c.LastUpdatedTime.Time
+		}
+	}
+	return last
+}
+
+// unreadyAlertSince returns since when the ToolchainStatus is unready, ie, when it became unready,
+// or when the first unready notification was sent if the status flapped since then
+func unreadyAlertSince(toolchainStatus *toolchainv1alpha1.ToolchainStatus) time.Time {
+	since := time.Now()
+	if ready, found := condition.FindConditionByType(toolchainStatus.Status.Conditions, toolchainv1alpha1.ConditionReady); found &&
+		ready.Status == corev1.ConditionFalse {
+		since = ready.LastTransitionTime.Time
+	}
+	if created, found := condition.FindConditionByType(toolchainStatus.Status.Conditions, toolchainv1alpha1.ToolchainStatusUnreadyNotificationCreated); found &&
+		created.Status == corev1.ConditionTrue && created.LastTransitionTime.Time.Before(since) {
+		since = created.LastTransitionTime.Time
+	}
+	return since
+}
+
+// unreadyAlertRecipients returns the recipients of the unready alert, including the escalation recipients once the alert was escalated
+func unreadyAlertRecipients(config toolchainconfig.ToolchainConfig, toolchainStatus *toolchainv1alpha1.ToolchainStatus) string {
+	recipients := config.Notifications().AdminEmail()
+	if escalationEmail := config.ToolchainStatus().UnreadyAlertEscalationEmail(); escalationEmail != "" &&
+		condition.IsTrue(toolchainStatus.Status.Conditions, ToolchainStatusUnreadyNotificationEscalated) {
+		recipients += ", " + escalationEmail
+	}
+	return recipients
+}
+
+// restoredNotificationPending returns true if the ToolchainStatus is ready again after an unready notification was sent,
+// but for less than the flap window
+func restoredNotificationPending(config toolchainconfig.ToolchainStatusConfig, toolchainStatus *toolchainv1alpha1.ToolchainStatus) bool {
+	flapWindow := config.UnreadyAlertFlapWindow()
+	if flapWindow == 0 || !condition.IsTrue(toolchainStatus.Status.Conditions, toolchainv1alpha1.ToolchainStatusUnreadyNotificationCreated) {
+		return false
+	}
+	ready, found := condition.FindConditionByType(toolchainStatus.Status.Conditions, toolchainv1alpha1.ConditionReady)
+	if !found || ready.Status != corev1.ConditionTrue {
+		// becoming ready right now
+		return true
+	}
+	return time.Since(ready.LastTransitionTime.Time) < flapWindow
+}
+
+// resetUnreadyAlertPolicyConditions returns the conditions which reset the state of the alert policy, once the restored notification was sent
+func resetUnreadyAlertPolicyConditions(toolchainStatus *toolchainv1alpha1.ToolchainStatus) []toolchainv1alpha1.Condition {
+	var conditions []toolchainv1alpha1.Condition
+	for _, conditionType := range []toolchainv1alpha1.ConditionType{ToolchainStatusUnreadyNotificationRepeated, ToolchainStatusUnreadyNotificationEscalated} {
+		if _, found := condition.FindConditionByType(toolchainStatus.Status.Conditions, conditionType); found {
+			conditions = append(conditions, toolchainv1alpha1.Condition{
+				Type:   conditionType,
+				Status: corev1.ConditionFalse,
+				Reason: toolchainv1alpha1.ToolchainStatusAllComponentsReadyReason,
+			})
+		}
+	}
+	return conditions
+}
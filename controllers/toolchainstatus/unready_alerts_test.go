@@ -0,0 +1,252 @@
+package toolchainstatus
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	toolchainv1alpha1 "github.com/codeready-toolchain/api/api/v1alpha1"
+	"github.com/codeready-toolchain/host-operator/controllers/toolchainconfig"
+	. "github.com/codeready-toolchain/host-operator/test"
+	hostconfig "github.com/codeready-toolchain/host-operator/test/config"
+	"github.com/codeready-toolchain/toolchain-common/pkg/condition"
+	commonconfig "github.com/codeready-toolchain/toolchain-common/pkg/configuration"
+	"github.com/codeready-toolchain/toolchain-common/pkg/test"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestUnreadyAlertPolicy(t *testing.T) {
+	restore := test.SetEnvVarAndRestore(t, commonconfig.WatchNamespaceEnvVar, test.HostOperatorNs)
+	t.Cleanup(restore)
+
+	t.Run("repeat", func(t *testing.T) {
+		// given
+		reconciler, cl := prepareCapacityAlerts(t, NewToolchainStatus(),
+			hostconfig.Annotation(toolchainconfig.ToolchainStatusUnreadyAlertRepeatIntervalAnnotationKey, "1h"))
+		setConditions(t, cl, unreadySince(3*time.Hour), unreadyNotificationCreated(2*time.Hour))
+
+		// when
+		err := reconciler.notificationCheck(context.TODO(), getToolchainStatus(t, cl))
+
+		// then
+		require.NoError(t, err)
+		notifications := capacityAlertNotifications(t, cl, NotificationTypeToolchainStatusStillUnready)
+		require.Len(t, notifications, 1)
+		assert.Equal(t, "ToolchainStatus is still in an unready status for host-cluster", notifications[0].Spec.Subject)
+		assert.Equal(t, "admin@acme.com", notifications[0].Spec.Recipient)
+		assert.True(t, condition.IsTrue(getToolchainStatus(t, cl).Status.Conditions, ToolchainStatusUnreadyNotificationRepeated))
+
+		t.Run("not repeated before the interval", func(t *testing.T) {
+			// when
+			err := reconciler.notificationCheck(context.TODO(), getToolchainStatus(t, cl))
+
+			// then
+			require.NoError(t, err)
+			assert.Len(t, capacityAlertNotifications(t, cl, NotificationTypeToolchainStatusStillUnready), 1)
+		})
+	})
+
+	t.Run("escalation", func(t *testing.T) {
+		// given
+		reconciler, cl := prepareCapacityAlerts(t, NewToolchainStatus(),
+			hostconfig.Annotation(toolchainconfig.ToolchainStatusUnreadyAlertRepeatIntervalAnnotationKey, "1h"),
+			hostconfig.Annotation(toolchainconfig.ToolchainStatusUnreadyAlertEscalationDelayAnnotationKey, "4h"),
+			hostconfig.Annotation(toolchainconfig.ToolchainStatusUnreadyAlertEscalationEmailAnnotationKey, "oncall@acme.com"))
+		setConditions(t, cl, unreadySince(5*time.Hour), unreadyNotificationCreated(5*time.Hour),
+			toolchainv1alpha1.Condition{
+				Type:            ToolchainStatusUnreadyNotificationRepeated,
+				Status:          corev1.ConditionTrue,
+				Reason:          ToolchainStatusUnreadyNotificationRepeatedReason,
+				LastUpdatedTime: &metav1.Time{Time: time.Now().Add(-30 * time.Minute)},
+			})
+
+		// when
+		err := reconciler.notificationCheck(context.TODO(), getToolchainStatus(t, cl))
+
+		// then
+		require.NoError(t, err)
+		notifications := capacityAlertNotifications(t, cl, NotificationTypeToolchainStatusEscalated)
+		require.Len(t, notifications, 1)
+		assert.Contains(t, notifications[0].Spec.Subject, "Escalation: ToolchainStatus has been in an unready status since ")
+		assert.Equal(t, "oncall@acme.com", notifications[0].Spec.Recipient)
+		assert.True(t, condition.IsTrue(getToolchainStatus(t, cl).Status.Conditions, ToolchainStatusUnreadyNotificationEscalated))
+
+		t.Run("repeated notification is sent to the escalation recipients too", func(t *testing.T) {
+			// given
+			toolchainStatus := getToolchainStatus(t, cl)
+			for i, c := range toolchainStatus.Status.Conditions {
+				if c.Type == ToolchainStatusUnreadyNotificationRepeated || c.Type == ToolchainStatusUnreadyNotificationEscalated {
+					toolchainStatus.Status.Conditions[i].LastUpdatedTime = &metav1.Time{Time: time.Now().Add(-2 * time.Hour)}
+				}
+			}
+			require.NoError(t, cl.Status().Update(context.TODO(), toolchainStatus))
+
+			// when
+			err := reconciler.notificationCheck(context.TODO(), getToolchainStatus(t, cl))
+
+			// then
+			require.NoError(t, err)
+			notifications := capacityAlertNotifications(t, cl, NotificationTypeToolchainStatusStillUnready)
+			require.Len(t, notifications, 1)
+			assert.Equal(t, "admin@acme.com, oncall@acme.com", notifications[0].Spec.Recipient)
+			// not escalated again
+			assert.Len(t, capacityAlertNotifications(t, cl, NotificationTypeToolchainStatusEscalated), 1)
+
+			t.Run("restored notification is sent to the escalation recipients and the policy is reset", func(t *testing.T) {
+				// when
+				err := reconciler.setStatusReady(context.TODO(), getToolchainStatus(t, cl))
+
+				// then
+				require.NoError(t, err)
+				notifications := capacityAlertNotifications(t, cl, NotificationTypeToolchainStatusRestored)
+				require.Len(t, notifications, 1)
+				assert.Equal(t, "admin@acme.com, oncall@acme.com", notifications[0].Spec.Recipient)
+				conditions := getToolchainStatus(t, cl).Status.Conditions
+				assert.True(t, condition.IsFalse(conditions, toolchainv1alpha1.ToolchainStatusUnreadyNotificationCreated))
+				assert.True(t, condition.IsFalse(conditions, ToolchainStatusUnreadyNotificationRepeated))
+				assert.True(t, condition.IsFalse(conditions, ToolchainStatusUnreadyNotificationEscalated))
+			})
+		})
+	})
+
+	t.Run("no escalation before the delay", func(t *testing.T) {
+		// given
+		reconciler, cl := prepareCapacityAlerts(t, NewToolchainStatus(),
+			hostconfig.Annotation(toolchainconfig.ToolchainStatusUnreadyAlertEscalationDelayAnnotationKey, "4h"),
+			hostconfig.Annotation(toolchainconfig.ToolchainStatusUnreadyAlertEscalationEmailAnnotationKey, "oncall@acme.com"))
+		setConditions(t, cl, unreadySince(3*time.Hour), unreadyNotificationCreated(3*time.Hour))
+
+		// when
+		err := reconciler.notificationCheck(context.TODO(), getToolchainStatus(t, cl))
+
+		// then
+		require.NoError(t, err)
+		assert.Empty(t, capacityAlertNotifications(t, cl, NotificationTypeToolchainStatusEscalated))
+		assert.Empty(t, capacityAlertNotifications(t, cl, NotificationTypeToolchainStatusStillUnready))
+	})
+
+	t.Run("initial delay", func(t *testing.T) {
+		// given
+		reconciler, cl := prepareCapacityAlerts(t, NewToolchainStatus(),
+			hostconfig.Annotation(toolchainconfig.ToolchainStatusUnreadyAlertDelayAnnotationKey, "1h"))
+		setConditions(t, cl, unreadySince(30*time.Minute))
+
+		// when
+		err := reconciler.notificationCheck(context.TODO(), getToolchainStatus(t, cl))
+
+		// then
+		require.NoError(t, err)
+		assert.Empty(t, capacityAlertNotifications(t, cl, NotificationTypeToolchainStatusUnready))
+
+		t.Run("sent after the delay", func(t *testing.T) {
+			// given
+			setConditions(t, cl, unreadySince(61*time.Minute))
+
+			// when
+			err := reconciler.notificationCheck(context.TODO(), getToolchainStatus(t, cl))
+
+			// then
+			require.NoError(t, err)
+			assert.Len(t, capacityAlertNotifications(t, cl, NotificationTypeToolchainStatusUnready), 1)
+		})
+	})
+
+	t.Run("flap suppression", func(t *testing.T) {
+		// given
+		reconciler, cl := prepareCapacityAlerts(t, NewToolchainStatus(),
+			hostconfig.Annotation(toolchainconfig.ToolchainStatusUnreadyAlertFlapWindowAnnotationKey, "15m"))
+		setConditions(t, cl, unreadySince(time.Hour), unreadyNotificationCreated(30*time.Minute))
+
+		// when
+		err := reconciler.setStatusReady(context.TODO(), getToolchainStatus(t, cl))
+
+		// then
+		require.NoError(t, err)
+		assert.Empty(t, capacityAlertNotifications(t, cl, NotificationTypeToolchainStatusRestored))
+		created, _ := condition.FindConditionByType(getToolchainStatus(t, cl).Status.Conditions, toolchainv1alpha1.ToolchainStatusUnreadyNotificationCreated)
+		assert.Equal(t, corev1.ConditionTrue, created.Status)
+		assert.Equal(t, ToolchainStatusRestoredNotificationPendingReason, created.Reason)
+
+		t.Run("unready again within the flap window", func(t *testing.T) {
+			// given
+			require.NoError(t, reconciler.setStatusNotReady(context.TODO(), getToolchainStatus(t, cl), "components not ready: [members]"))
+			setConditions(t, cl, unreadySince(time.Hour))
+
+			// when
+			err := reconciler.notificationCheck(context.TODO(), getToolchainStatus(t, cl))
+
+			// then
+			require.NoError(t, err)
+			// same outage: no new unready notification
+			assert.Empty(t, capacityAlertNotifications(t, cl, NotificationTypeToolchainStatusUnready))
+			created, _ := condition.FindConditionByType(getToolchainStatus(t, cl).Status.Conditions, toolchainv1alpha1.ToolchainStatusUnreadyNotificationCreated)
+			assert.Equal(t, corev1.ConditionTrue, created.Status)
+			assert.Equal(t, toolchainv1alpha1.ToolchainStatusUnreadyNotificationCRCreatedReason, created.Reason)
+
+			t.Run("restored notification is sent at the end of the flap window", func(t *testing.T) {
+				// given
+				setConditions(t, cl, toolchainv1alpha1.Condition{
+					Type:               toolchainv1alpha1.ConditionReady,
+					Status:             corev1.ConditionTrue,
+					Reason:             toolchainv1alpha1.ToolchainStatusAllComponentsReadyReason,
+					LastTransitionTime: metav1.NewTime(time.Now().Add(-16 * time.Minute)),
+				})
+
+				// when
+				err := reconciler.setStatusReady(context.TODO(), getToolchainStatus(t, cl))
+
+				// then
+				require.NoError(t, err)
+				notifications := capacityAlertNotifications(t, cl, NotificationTypeToolchainStatusRestored)
+				require.Len(t, notifications, 1)
+				assert.True(t, condition.IsFalse(getToolchainStatus(t, cl).Status.Conditions, toolchainv1alpha1.ToolchainStatusUnreadyNotificationCreated))
+			})
+		})
+	})
+}
+
+func getToolchainStatus(t *testing.T, cl *test.FakeClient) *toolchainv1alpha1.ToolchainStatus {
+	toolchainStatus := &toolchainv1alpha1.ToolchainStatus{}
+	require.NoError(t, cl.Get(context.TODO(), test.NamespacedName(test.HostOperatorNs, toolchainconfig.ToolchainStatusName), toolchainStatus))
+	return toolchainStatus
+}
+
+// setConditions sets the given conditions (with their timestamps) on the ToolchainStatus
+func setConditions(t *testing.T, cl *test.FakeClient, conditions ...toolchainv1alpha1.Condition) {
+	toolchainStatus := getToolchainStatus(t, cl)
+	for _, c := range conditions {
+		replaced := false
+		for i, existing := range toolchainStatus.Status.Conditions {
+			if existing.Type == c.Type {
+				toolchainStatus.Status.Conditions[i] = c
+				replaced = true
+			}
+		}
+		if !replaced {
+			toolchainStatus.Status.Conditions = append(toolchainStatus.Status.Conditions, c)
+		}
+	}
+	require.NoError(t, cl.Status().Update(context.TODO(), toolchainStatus))
+}
+
+func unreadySince(d time.Duration) toolchainv1alpha1.Condition {
+	return toolchainv1alpha1.Condition{
+		Type:               toolchainv1alpha1.ConditionReady,
+		Status:             corev1.ConditionFalse,
+		Reason:             toolchainv1alpha1.ToolchainStatusComponentsNotReadyReason,
+		LastTransitionTime: metav1.NewTime(time.Now().Add(-d)),
+	}
+}
+
+func unreadyNotificationCreated(d time.Duration) toolchainv1alpha1.Condition {
+	return toolchainv1alpha1.Condition{
+		Type:               toolchainv1alpha1.ToolchainStatusUnreadyNotificationCreated,
+		Status:             corev1.ConditionTrue,
+		Reason:             toolchainv1alpha1.ToolchainStatusUnreadyNotificationCRCreatedReason,
+		LastTransitionTime: metav1.NewTime(time.Now().Add(-d)),
+	}
+}
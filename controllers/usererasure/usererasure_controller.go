@@ -0,0 +1,375 @@
+package usererasure
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	toolchainv1alpha1 "github.com/codeready-toolchain/api/api/v1alpha1"
+	"github.com/codeready-toolchain/host-operator/controllers/notification"
+	"github.com/codeready-toolchain/toolchain-common/pkg/cluster"
+	"github.com/codeready-toolchain/toolchain-common/pkg/hash"
+
+	errs "github.com/pkg/errors"
+	"github.com/redhat-cop/operator-utils/pkg/util"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	runtimeclient "sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+const (
+	// ErasureRequestedAnnotationKey is set by an admin on a UserSignup (with the value "true") to request
+	// the erasure of all the resources and personal data associated with the user
+	ErasureRequestedAnnotationKey = toolchainv1alpha1.LabelKeyPrefix + "erasure-requested"
+
+	// ErasureFinalizerName is the finalizer which retains the UserSignup until the erasure is complete
+	ErasureFinalizerName = toolchainv1alpha1.LabelKeyPrefix + "erasure"
+
+	// ErasureReceiptLabelKey is used to label the ConfigMaps which hold the erasure completion receipts
+	ErasureReceiptLabelKey = toolchainv1alpha1.LabelKeyPrefix + "erasure-receipt"
+
+	// keys of the erasure receipt data
+	ReceiptUserSignupHashKey           = "userSignupHash"
+	ReceiptRequestedAtKey              = "requestedAt"
+	ReceiptCompletedAtKey              = "completedAt"
+	ReceiptMemberClustersVerifiedKey   = "memberClustersVerified"
+	ReceiptBannedUsersScrubbedKey      = "bannedUsersScrubbed"
+	ReceiptNotificationsDeletedKey     = "notificationsDeleted"
+	ReceiptUnsuppressedKey             = "removedFromSuppressionList"
+	receiptNamePrefix                  = "erasure-receipt-"
+	requeueDelayWhileResourcesRemain   = 5 * time.Second
+	erasureRequestedAnnotationValueYes = "true"
+)
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *Reconciler) SetupWithManager(mgr manager.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		Named("usererasure").
+		For(&toolchainv1alpha1.UserSignup{}).
+		Complete(r)
+}
+
+// Reconciler drives the erasure of all the host and member resources associated with a UserSignup
+// which was annotated with the `toolchain.dev.openshift.com/erasure-requested` annotation
+type Reconciler struct {
+	Client         runtimeclient.Client
+	Scheme         *runtime.Scheme
+	Namespace      string
+	GetMembersFunc cluster.GetMemberClustersFunc
+}
+
+//+kubebuilder:rbac:groups=toolchain.dev.openshift.com,resources=usersignups,verbs=get;list;watch;update;patch;delete
+//+kubebuilder:rbac:groups=toolchain.dev.openshift.com,resources=usersignups/finalizers,verbs=update
+//+kubebuilder:rbac:groups=toolchain.dev.openshift.com,resources=masteruserrecords;spaces;spacebindings;notifications,verbs=get;list;watch;delete
+//+kubebuilder:rbac:groups=toolchain.dev.openshift.com,resources=bannedusers,verbs=get;list;watch;update;patch
+//+kubebuilder:rbac:groups="",resources=configmaps,verbs=get;list;watch;create;update
+
+// Reconcile reads the state of the cluster for a UserSignup object and, if the erasure was requested, deletes the UserSignup
+// and all its associated resources, verifies that nothing was left on the member clusters, scrubs the personal data from the
+// remaining resources (including the notification suppression list) and records a receipt that does not contain any personal data.
+// Note:
+// The Controller will requeue the Request to be processed again if the returned error is non-nil or
+// Result.Requeue is true, otherwise upon completion it will remove the work from the queue.
+func (r *Reconciler) Reconcile(ctx context.Context, request ctrl.Request) (ctrl.Result, error) {
+	logger := log.FromContext(ctx)
+
+	userSignup := &toolchainv1alpha1.UserSignup{}
+	if err := r.Client.Get(ctx, request.NamespacedName, userSignup); err != nil {
+		if errors.IsNotFound(err) {
+			// Request object not found, could have been deleted after reconcile request.
+			// Return and don't requeue
+			return reconcile.Result{}, nil
+		}
+		// Error reading the object - requeue the request.
+		return reconcile.Result{}, err
+	}
+
+	if !IsErasureRequested(userSignup) {
+		return reconcile.Result{}, nil
+	}
+	logger.Info("Reconciling erasure of UserSignup")
+
+	if !util.IsBeingDeleted(userSignup) {
+		return reconcile.Result{}, r.startErasure(ctx, userSignup)
+	}
+	if !util.HasFinalizer(userSignup, ErasureFinalizerName) {
+		logger.Info("erasure of the UserSignup was already completed")
+		return reconcile.Result{}, nil
+	}
+
+	remaining, err := r.deleteHostResources(ctx, userSignup)
+	if err != nil {
+		return reconcile.Result{}, err
+	}
+	if remaining > 0 {
+		logger.Info("waiting for the host resources to be deleted", "remaining", remaining)
+		return reconcile.Result{Requeue: true, RequeueAfter: requeueDelayWhileResourcesRemain}, nil
+	}
+
+	verifiedClusters, leftovers, err := r.verifyMemberResources(ctx, userSignup)
+	if err != nil {
+		return reconcile.Result{}, err
+	}
+	if len(leftovers) > 0 {
+		logger.Info("waiting for the member resources to be deleted", "leftovers", leftovers)
+		return reconcile.Result{Requeue: true, RequeueAfter: requeueDelayWhileResourcesRemain}, nil
+	}
+
+	scrubbed, err := r.scrubBannedUsers(ctx, userSignup)
+	if err != nil {
+		return reconcile.Result{}, err
+	}
+	deletedNotifications, err := r.deleteNotifications(ctx, userSignup)
+	if err != nil {
+		return reconcile.Result{}, err
+	}
+
+	unsuppressed, err := r.unsuppressRecipient(ctx, userSignup)
+	if err != nil {
+		return reconcile.Result{}, err
+	}
+
+	if err := r.createReceipt(ctx, userSignup, verifiedClusters, scrubbed, deletedNotifications, unsuppressed); err != nil {
+		return reconcile.Result{}, err
+	}
+
+	// finally, let the UserSignup go
+	util.RemoveFinalizer(userSignup, ErasureFinalizerName)
+	if err := r.Client.Update(ctx, userSignup); err != nil {
+		return reconcile.Result{}, errs.Wrap(err, "unable to remove the erasure finalizer from the UserSignup")
+	}
+	logger.Info("erasure of the UserSignup completed")
+	return reconcile.Result{}, nil
+}
+
+// IsErasureRequested returns true if the given UserSignup was annotated to request its erasure
+func IsErasureRequested(userSignup *toolchainv1alpha1.UserSignup) bool {
+	return userSignup.Annotations[ErasureRequestedAnnotationKey] == erasureRequestedAnnotationValueYes
+}
+
+// startErasure sets the erasure finalizer on the UserSignup and triggers its deletion, so that all dependent
+// resources are garbage collected before the UserSignup itself
+func (r *Reconciler) startErasure(ctx context.Context, userSignup *toolchainv1alpha1.UserSignup) error {
+	logger := log.FromContext(ctx)
+	if !util.HasFinalizer(userSignup, ErasureFinalizerName) {
+		util.AddFinalizer(userSignup, ErasureFinalizerName)
+		if err := r.Client.Update(ctx, userSignup); err != nil {
+			return errs.Wrap(err, "unable to add the erasure finalizer on the UserSignup")
+		}
+	}
+	propagationPolicy := metav1.DeletePropagationForeground
+	if err := r.Client.Delete(ctx, userSignup, &runtimeclient.DeleteOptions{
+		PropagationPolicy: &propagationPolicy,
+	}); err != nil && !errors.IsNotFound(err) {
+		return errs.Wrap(err, "unable to delete the UserSignup")
+	}
+	logger.Info("erasure of the UserSignup started")
+	return nil
+}
+
+// deleteHostResources triggers the deletion of the MasterUserRecords, Spaces and SpaceBindings associated with the UserSignup
+// (in case the garbage collector did not take care of them yet) and returns the number of resources that still exist.
+func (r *Reconciler) deleteHostResources(ctx context.Context, userSignup *toolchainv1alpha1.UserSignup) (int, error) {
+	var objs []runtimeclient.Object
+
+	murs := &toolchainv1alpha1.MasterUserRecordList{}
+	if err := r.Client.List(ctx, murs, runtimeclient.InNamespace(userSignup.Namespace),
+		runtimeclient.MatchingLabels{toolchainv1alpha1.MasterUserRecordOwnerLabelKey: userSignup.Name}); err != nil {
+		return 0, errs.Wrap(err, "unable to list MasterUserRecords")
+	}
+	for i := range murs.Items {
+		objs = append(objs, &murs.Items[i])
+	}
+
+	spaces := &toolchainv1alpha1.SpaceList{}
+	if err := r.Client.List(ctx, spaces, runtimeclient.InNamespace(userSignup.Namespace),
+		runtimeclient.MatchingLabels{toolchainv1alpha1.SpaceCreatorLabelKey: userSignup.Name}); err != nil {
+		return 0, errs.Wrap(err, "unable to list Spaces")
+	}
+	for i := range spaces.Items {
+		objs = append(objs, &spaces.Items[i])
+	}
+
+	bindings := map[string]*toolchainv1alpha1.SpaceBinding{}
+	selectors := []runtimeclient.MatchingLabels{{toolchainv1alpha1.SpaceCreatorLabelKey: userSignup.Name}}
+	if userSignup.Status.CompliantUsername != "" {
+		// also include the bindings which grant the user access to the Spaces of other users
+		selectors = append(selectors, runtimeclient.MatchingLabels{toolchainv1alpha1.SpaceBindingMasterUserRecordLabelKey: userSignup.Status.CompliantUsername})
+	}
+	for _, selector := range selectors {
+		spaceBindings := &toolchainv1alpha1.SpaceBindingList{}
+		if err := r.Client.List(ctx, spaceBindings, runtimeclient.InNamespace(userSignup.Namespace), selector); err != nil {
+			return 0, errs.Wrap(err, "unable to list SpaceBindings")
+		}
+		for i := range spaceBindings.Items {
+			bindings[spaceBindings.Items[i].Name] = &spaceBindings.Items[i]
+		}
+	}
+	for _, binding := range bindings {
+		objs = append(objs, binding)
+	}
+
+	logger := log.FromContext(ctx)
+	for _, obj := range objs {
+		if util.IsBeingDeleted(obj) {
+			continue
+		}
+		logger.Info("deleting resource associated with the UserSignup", "kind", fmt.Sprintf("%T", obj), "name", obj.GetName())
+		if err := r.Client.Delete(ctx, obj); err != nil && !errors.IsNotFound(err) {
+			return 0, errs.Wrapf(err, "unable to delete %T '%s'", obj, obj.GetName())
+		}
+	}
+	return len(objs), nil
+}
+
+// verifyMemberResources checks that neither the UserAccount nor the NSTemplateSet of the user's home Space remain on any member cluster.
+// Returns the names of the verified member clusters, and the leftover resources (if any)
+func (r *Reconciler) verifyMemberResources(ctx context.Context, userSignup *toolchainv1alpha1.UserSignup) ([]string, []string, error) {
+	var verified, leftovers []string
+	for _, memberCluster := range r.GetMembersFunc() {
+		var candidates []runtimeclient.Object
+		if userSignup.Status.CompliantUsername != "" {
+			candidates = append(candidates, &toolchainv1alpha1.UserAccount{ObjectMeta: metav1.ObjectMeta{Name: userSignup.Status.CompliantUsername}})
+		}
+		if userSignup.Status.HomeSpace != "" {
+			candidates = append(candidates, &toolchainv1alpha1.NSTemplateSet{ObjectMeta: metav1.ObjectMeta{Name: userSignup.Status.HomeSpace}})
+		}
+		for _, obj := range candidates {
+			name := obj.GetName()
+			err := memberCluster.Client.Get(ctx, types.NamespacedName{Namespace: memberCluster.OperatorNamespace, Name: name}, obj)
+			if err == nil {
+				leftovers = append(leftovers, fmt.Sprintf("%s/%T/%s", memberCluster.Name, obj, name))
+				continue
+			}
+			if !errors.IsNotFound(err) {
+				return nil, nil, errs.Wrapf(err, "unable to verify the %T '%s' on member cluster '%s'", obj, name, memberCluster.Name)
+			}
+		}
+		verified = append(verified, memberCluster.Name)
+	}
+	return verified, leftovers, nil
+}
+
+// scrubBannedUsers replaces the email address in the BannedUsers which match the user's email with the hash of the address,
+// so that the ban remains effective without retaining the address itself. Returns the number of scrubbed BannedUsers.
+func (r *Reconciler) scrubBannedUsers(ctx context.Context, userSignup *toolchainv1alpha1.UserSignup) (int, error) {
+	emailHash, found := userSignup.Labels[toolchainv1alpha1.UserSignupUserEmailHashLabelKey]
+	if !found || emailHash == "" {
+		return 0, nil
+	}
+	bannedUsers := &toolchainv1alpha1.BannedUserList{}
+	if err := r.Client.List(ctx, bannedUsers, runtimeclient.InNamespace(userSignup.Namespace),
+		runtimeclient.MatchingLabels{toolchainv1alpha1.BannedUserEmailHashLabelKey: emailHash}); err != nil {
+		return 0, errs.Wrap(err, "unable to list BannedUsers")
+	}
+	scrubbed := 0
+	for i := range bannedUsers.Items {
+		bannedUser := &bannedUsers.Items[i]
+		if bannedUser.Spec.Email == emailHash {
+			// already scrubbed
+			continue
+		}
+		bannedUser.Spec.Email = emailHash
+		if err := r.Client.Update(ctx, bannedUser); err != nil {
+			return 0, errs.Wrapf(err, "unable to scrub BannedUser '%s'", bannedUser.Name)
+		}
+		scrubbed++
+	}
+	return scrubbed, nil
+}
+
+// deleteNotifications deletes all the Notifications which were addressed to the user or which refer to the user.
+// Returns the number of deleted Notifications.
+func (r *Reconciler) deleteNotifications(ctx context.Context, userSignup *toolchainv1alpha1.UserSignup) (int, error) {
+	notifications := &toolchainv1alpha1.NotificationList{}
+	if err := r.Client.List(ctx, notifications, runtimeclient.InNamespace(userSignup.Namespace)); err != nil {
+		return 0, errs.Wrap(err, "unable to list Notifications")
+	}
+	deleted := 0
+	for i := range notifications.Items {
+		notification := &notifications.Items[i]
+		if !refersToUser(notification, userSignup) {
+			continue
+		}
+		if err := r.Client.Delete(ctx, notification); err != nil && !errors.IsNotFound(err) {
+			return 0, errs.Wrapf(err, "unable to delete Notification '%s'", notification.Name)
+		}
+		deleted++
+	}
+	return deleted, nil
+}
+
+// unsuppressRecipient removes the user's email address from the notification suppression list.
+// Returns true if the address was in the list.
+func (r *Reconciler) unsuppressRecipient(ctx context.Context, userSignup *toolchainv1alpha1.UserSignup) (bool, error) {
+	email := userSignup.Spec.IdentityClaims.Email
+	if email == "" {
+		return false, nil
+	}
+	return notification.UnsuppressRecipient(ctx, r.Client, userSignup.Namespace, email)
+}
+
+func refersToUser(notification *toolchainv1alpha1.Notification, userSignup *toolchainv1alpha1.UserSignup) bool {
+	if userSignup.Status.CompliantUsername != "" &&
+		notification.Labels[toolchainv1alpha1.NotificationUserNameLabelKey] == userSignup.Status.CompliantUsername {
+		return true
+	}
+	email := userSignup.Spec.IdentityClaims.Email
+	if email == "" {
+		return false
+	}
+	if strings.EqualFold(notification.Spec.Recipient, email) {
+		return true
+	}
+	for _, v := range notification.Spec.Context {
+		if strings.EqualFold(v, email) {
+			return true
+		}
+	}
+	return false
+}
+
+// createReceipt records the completion of the erasure in a ConfigMap which only contains hashed identifiers
+func (r *Reconciler) createReceipt(ctx context.Context, userSignup *toolchainv1alpha1.UserSignup, verifiedClusters []string, scrubbed, deletedNotifications int, unsuppressed bool) error {
+	userSignupHash := hash.EncodeString(userSignup.Name)
+	requestedAt := ""
+	if userSignup.DeletionTimestamp != nil {
+		requestedAt = userSignup.DeletionTimestamp.UTC().Format(time.RFC3339)
+	}
+	receipt := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: userSignup.Namespace,
+			Name:      receiptNamePrefix + userSignupHash,
+			Labels: map[string]string{
+				ErasureReceiptLabelKey: "true",
+			},
+		},
+		Data: map[string]string{
+			ReceiptUserSignupHashKey:         userSignupHash,
+			ReceiptRequestedAtKey:            requestedAt,
+			ReceiptCompletedAtKey:            time.Now().UTC().Format(time.RFC3339),
+			ReceiptMemberClustersVerifiedKey: strings.Join(verifiedClusters, ","),
+			ReceiptBannedUsersScrubbedKey:    strconv.Itoa(scrubbed),
+			ReceiptNotificationsDeletedKey:   strconv.Itoa(deletedNotifications),
+			ReceiptUnsuppressedKey:           strconv.FormatBool(unsuppressed),
+		},
+	}
+	if err := r.Client.Create(ctx, receipt); err != nil {
+		if errors.IsAlreadyExists(err) {
+			// the receipt was created during a previous reconcile, but the finalizer could not be removed
+			return nil
+		}
+		return errs.Wrap(err, "unable to create the erasure receipt")
+	}
+	log.FromContext(ctx).Info("erasure receipt created", "name", receipt.Name)
+	return nil
+}
@@ -0,0 +1,263 @@
+package usererasure
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	toolchainv1alpha1 "github.com/codeready-toolchain/api/api/v1alpha1"
+	"github.com/codeready-toolchain/host-operator/controllers/notification"
+	"github.com/codeready-toolchain/host-operator/pkg/apis"
+	. "github.com/codeready-toolchain/host-operator/test"
+	"github.com/codeready-toolchain/toolchain-common/pkg/cluster"
+	"github.com/codeready-toolchain/toolchain-common/pkg/hash"
+	"github.com/codeready-toolchain/toolchain-common/pkg/test"
+	commonsignup "github.com/codeready-toolchain/toolchain-common/pkg/test/usersignup"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/scheme"
+	runtimeclient "sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+func TestUserErasure(t *testing.T) {
+
+	t.Run("not requested", func(t *testing.T) {
+		// given
+		userSignup := commonsignup.NewUserSignup(commonsignup.WithName("johny"))
+		r, req, cl := prepareReconcile(t, userSignup.Name, nil, userSignup)
+
+		// when
+		res, err := r.Reconcile(context.TODO(), req)
+
+		// then
+		require.NoError(t, err)
+		assert.Equal(t, reconcile.Result{}, res)
+		actual := &toolchainv1alpha1.UserSignup{}
+		require.NoError(t, cl.Get(context.TODO(), types.NamespacedName{Namespace: test.HostOperatorNs, Name: "johny"}, actual))
+		assert.Nil(t, actual.DeletionTimestamp)
+		assert.Empty(t, actual.Finalizers)
+	})
+
+	t.Run("requested", func(t *testing.T) {
+		// given
+		userSignup := newUserSignupToErase()
+		r, req, cl := prepareReconcile(t, userSignup.Name, nil, userSignup)
+
+		// when
+		res, err := r.Reconcile(context.TODO(), req)
+
+		// then
+		require.NoError(t, err)
+		assert.Equal(t, reconcile.Result{}, res)
+		actual := &toolchainv1alpha1.UserSignup{}
+		require.NoError(t, cl.Get(context.TODO(), types.NamespacedName{Namespace: test.HostOperatorNs, Name: "johny"}, actual))
+		assert.NotNil(t, actual.DeletionTimestamp)
+		assert.Contains(t, actual.Finalizers, ErasureFinalizerName)
+	})
+
+	t.Run("being deleted", func(t *testing.T) {
+
+		t.Run("host resources remain", func(t *testing.T) {
+			// given
+			userSignup := newUserSignupToErase(beingDeleted)
+			mur := &toolchainv1alpha1.MasterUserRecord{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "johny",
+					Namespace: test.HostOperatorNs,
+					Labels:    map[string]string{toolchainv1alpha1.MasterUserRecordOwnerLabelKey: "johny"},
+				},
+			}
+			space := &toolchainv1alpha1.Space{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "johny",
+					Namespace: test.HostOperatorNs,
+					Labels:    map[string]string{toolchainv1alpha1.SpaceCreatorLabelKey: "johny"},
+				},
+			}
+			binding := &toolchainv1alpha1.SpaceBinding{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "other-johny",
+					Namespace: test.HostOperatorNs,
+					Labels:    map[string]string{toolchainv1alpha1.SpaceBindingMasterUserRecordLabelKey: "johny"},
+				},
+			}
+			r, req, cl := prepareReconcile(t, userSignup.Name, nil, userSignup, mur, space, binding)
+
+			// when
+			res, err := r.Reconcile(context.TODO(), req)
+
+			// then
+			require.NoError(t, err)
+			assert.Equal(t, reconcile.Result{Requeue: true, RequeueAfter: requeueDelayWhileResourcesRemain}, res)
+			assertNotFound(t, cl, mur.Name, &toolchainv1alpha1.MasterUserRecord{})
+			assertNotFound(t, cl, space.Name, &toolchainv1alpha1.Space{})
+			assertNotFound(t, cl, binding.Name, &toolchainv1alpha1.SpaceBinding{})
+			assertFinalizerPresent(t, cl)
+			assertNoReceipt(t, cl)
+		})
+
+		t.Run("member resources remain", func(t *testing.T) {
+			// given
+			userSignup := newUserSignupToErase(beingDeleted)
+			userAccount := &toolchainv1alpha1.UserAccount{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "johny",
+					Namespace: test.MemberOperatorNs,
+				},
+			}
+			member1 := NewMemberClusterWithClient(test.NewFakeClient(t), "member-1", corev1.ConditionTrue)
+			member2 := NewMemberClusterWithClient(test.NewFakeClient(t, userAccount), "member-2", corev1.ConditionTrue)
+			r, req, cl := prepareReconcile(t, userSignup.Name, NewGetMemberClusters(member1, member2), userSignup)
+
+			// when
+			res, err := r.Reconcile(context.TODO(), req)
+
+			// then
+			require.NoError(t, err)
+			assert.Equal(t, reconcile.Result{Requeue: true, RequeueAfter: requeueDelayWhileResourcesRemain}, res)
+			assertFinalizerPresent(t, cl)
+			assertNoReceipt(t, cl)
+		})
+
+		t.Run("complete", func(t *testing.T) {
+			// given
+			userSignup := newUserSignupToErase(beingDeleted)
+			emailHash := hash.EncodeString("johny@redhat.com")
+			bannedUser := &toolchainv1alpha1.BannedUser{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "banned-johny",
+					Namespace: test.HostOperatorNs,
+					Labels:    map[string]string{toolchainv1alpha1.BannedUserEmailHashLabelKey: emailHash},
+				},
+				Spec: toolchainv1alpha1.BannedUserSpec{
+					Email: "johny@redhat.com",
+				},
+			}
+			byUsername := newNotification("johny-deactivated", map[string]string{toolchainv1alpha1.NotificationUserNameLabelKey: "johny"}, "")
+			byRecipient := newNotification("johny-other", nil, "johny@redhat.com")
+			unrelated := newNotification("someone-else", map[string]string{toolchainv1alpha1.NotificationUserNameLabelKey: "someone"}, "someone@redhat.com")
+			member1 := NewMemberClusterWithClient(test.NewFakeClient(t), "member-1", corev1.ConditionTrue)
+			r, req, cl := prepareReconcile(t, userSignup.Name, NewGetMemberClusters(member1), userSignup, bannedUser, byUsername, byRecipient, unrelated)
+			require.NoError(t, notification.SuppressRecipient(context.TODO(), cl, test.HostOperatorNs, "johny@redhat.com", "bounced"))
+			require.NoError(t, notification.SuppressRecipient(context.TODO(), cl, test.HostOperatorNs, "someone@redhat.com", "bounced"))
+
+			// when
+			res, err := r.Reconcile(context.TODO(), req)
+
+			// then
+			require.NoError(t, err)
+			assert.Equal(t, reconcile.Result{}, res)
+			// the UserSignup is gone since its last finalizer was removed
+			assertNotFound(t, cl, "johny", &toolchainv1alpha1.UserSignup{})
+			// the banned user was scrubbed
+			actualBannedUser := &toolchainv1alpha1.BannedUser{}
+			require.NoError(t, cl.Get(context.TODO(), types.NamespacedName{Namespace: test.HostOperatorNs, Name: bannedUser.Name}, actualBannedUser))
+			assert.Equal(t, emailHash, actualBannedUser.Spec.Email)
+			// the notifications were deleted
+			assertNotFound(t, cl, byUsername.Name, &toolchainv1alpha1.Notification{})
+			assertNotFound(t, cl, byRecipient.Name, &toolchainv1alpha1.Notification{})
+			require.NoError(t, cl.Get(context.TODO(), types.NamespacedName{Namespace: test.HostOperatorNs, Name: unrelated.Name}, &toolchainv1alpha1.Notification{}))
+			// the user was removed from the suppression list
+			suppressed, err := notification.GetSuppressedRecipient(context.TODO(), cl, test.HostOperatorNs, "johny@redhat.com")
+			require.NoError(t, err)
+			assert.Nil(t, suppressed)
+			suppressed, err = notification.GetSuppressedRecipient(context.TODO(), cl, test.HostOperatorNs, "someone@redhat.com")
+			require.NoError(t, err)
+			assert.NotNil(t, suppressed)
+			// the receipt was created
+			receipt := &corev1.ConfigMap{}
+			require.NoError(t, cl.Get(context.TODO(), types.NamespacedName{Namespace: test.HostOperatorNs, Name: receiptNamePrefix + hash.EncodeString("johny")}, receipt))
+			assert.Equal(t, "true", receipt.Labels[ErasureReceiptLabelKey])
+			assert.Equal(t, hash.EncodeString("johny"), receipt.Data[ReceiptUserSignupHashKey])
+			assert.Equal(t, "member-1", receipt.Data[ReceiptMemberClustersVerifiedKey])
+			assert.Equal(t, "1", receipt.Data[ReceiptBannedUsersScrubbedKey])
+			assert.Equal(t, "2", receipt.Data[ReceiptNotificationsDeletedKey])
+			assert.Equal(t, "true", receipt.Data[ReceiptUnsuppressedKey])
+			assert.NotEmpty(t, receipt.Data[ReceiptRequestedAtKey])
+			assert.NotEmpty(t, receipt.Data[ReceiptCompletedAtKey])
+			for _, v := range receipt.Data {
+				assert.NotContains(t, v, "johny@redhat.com")
+			}
+		})
+	})
+}
+
+func newUserSignupToErase(modifiers ...commonsignup.Modifier) *toolchainv1alpha1.UserSignup {
+	modifiers = append([]commonsignup.Modifier{
+		commonsignup.WithName("johny"),
+		commonsignup.WithEmail("johny@redhat.com"),
+		commonsignup.WithCompliantUsername("johny"),
+		commonsignup.WithHomeSpace("johny"),
+		commonsignup.WithAnnotation(ErasureRequestedAnnotationKey, "true"),
+	}, modifiers...)
+	return commonsignup.NewUserSignup(modifiers...)
+}
+
+func beingDeleted(userSignup *toolchainv1alpha1.UserSignup) {
+	now := metav1.Now()
+	userSignup.DeletionTimestamp = &now
+	userSignup.Finalizers = []string{ErasureFinalizerName}
+}
+
+func newNotification(name string, labels map[string]string, recipient string) *toolchainv1alpha1.Notification {
+	return &toolchainv1alpha1.Notification{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: test.HostOperatorNs,
+			Labels:    labels,
+		},
+		Spec: toolchainv1alpha1.NotificationSpec{
+			Recipient: recipient,
+		},
+	}
+}
+
+func assertNotFound(t *testing.T, cl runtimeclient.Client, name string, obj runtimeclient.Object) {
+	err := cl.Get(context.TODO(), types.NamespacedName{Namespace: test.HostOperatorNs, Name: name}, obj)
+	require.Error(t, err)
+	assert.True(t, apierrors.IsNotFound(err))
+}
+
+func assertFinalizerPresent(t *testing.T, cl runtimeclient.Client) {
+	userSignup := &toolchainv1alpha1.UserSignup{}
+	require.NoError(t, cl.Get(context.TODO(), types.NamespacedName{Namespace: test.HostOperatorNs, Name: "johny"}, userSignup))
+	assert.Contains(t, userSignup.Finalizers, ErasureFinalizerName)
+}
+
+func assertNoReceipt(t *testing.T, cl runtimeclient.Client) {
+	receipts := &corev1.ConfigMapList{}
+	require.NoError(t, cl.List(context.TODO(), receipts, runtimeclient.MatchingLabels{ErasureReceiptLabelKey: "true"}))
+	assert.Empty(t, receipts.Items)
+}
+
+func prepareReconcile(t *testing.T, name string, getMembersFunc cluster.GetMemberClustersFunc, initObjs ...runtimeclient.Object) (*Reconciler, reconcile.Request, *test.FakeClient) {
+	os.Setenv("WATCH_NAMESPACE", test.HostOperatorNs)
+
+	s := scheme.Scheme
+	err := apis.AddToScheme(s)
+	require.NoError(t, err)
+
+	fakeClient := test.NewFakeClient(t, initObjs...)
+	if getMembersFunc == nil {
+		getMembersFunc = NewGetMemberClusters()
+	}
+
+	r := &Reconciler{
+		Client:         fakeClient,
+		Scheme:         s,
+		Namespace:      test.HostOperatorNs,
+		GetMembersFunc: getMembersFunc,
+	}
+	return r, reconcile.Request{
+		NamespacedName: types.NamespacedName{
+			Name:      name,
+			Namespace: test.HostOperatorNs,
+		},
+	}, fakeClient
+}
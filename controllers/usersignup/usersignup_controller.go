@@ -255,9 +255,10 @@ func (r *Reconciler) isUserBanned(
 				return false, r.wrapErrorWithStatusUpdate(ctx, userSignup, r.setStatusFailedToReadBannedUsers, err, "Failed to query BannedUsers")
 			}
 
-			// One last check to confirm that the e-mail addresses match also (in case of the infinitesimal chance of a hash collision)
+			// One last check to confirm that the e-mail addresses match also (in case of the infinitesimal chance of a hash collision).
+			// BannedUsers which were scrubbed during a user erasure only retain the hash of the e-mail address.
 			for _, bannedUser := range bannedUserList.Items {
-				if bannedUser.Spec.Email == userSignup.Spec.IdentityClaims.Email {
+				if bannedUser.Spec.Email == userSignup.Spec.IdentityClaims.Email || bannedUser.Spec.Email == emailHashLbl {
 					banned = true
 					break
 				}
@@ -323,6 +324,10 @@ func (r *Reconciler) checkIfMurAlreadyExists(
 
 		// If the user has been deactivated, then we need to delete the MUR
 		if states.Deactivated(userSignup) {
+			// keep the home Space of the user (if the hibernation is enabled), so that it can be woken up when the user comes back
+			if err := r.hibernateSpaceIfEnabled(ctx, config, userSignup, mur); err != nil {
+				return true, r.wrapErrorWithStatusUpdate(ctx, userSignup, r.setStatusDeactivationInProgress, err, "unable to hibernate the Space")
+			}
 			// We set the inProgressStatusUpdater parameter here to setStatusDeactivationInProgress, as a temporary status before
 			// the main reconcile function completes the deactivation process
 			logger.Info("Deleting MasterUserRecord since user has been deactivated")
@@ -644,6 +649,10 @@ func (r *Reconciler) generateCompliantUsername(
 					}
 					return "", err
 				}
+				if space.Labels[toolchainv1alpha1.SpaceCreatorLabelKey] == instance.Name && spaceutil.IsHibernated(space) {
+					// the hibernated Space of the returning user, which will be woken up
+					return newUsername, nil
+				}
 			} else {
 				// If there was a NotFound error looking up the mur and the creation of the default space should be skipped, then it means we found an available name
 				return newUsername, nil
@@ -741,6 +750,9 @@ func (r *Reconciler) ensureSpace(
 		if util.IsBeingDeleted(space) {
 			return nil, false, fmt.Errorf("cannot create space because it is currently being deleted")
 		}
+		if spaceutil.IsHibernated(space) {
+			return space, false, r.wakeUpSpace(ctx, userSignup, space)
+		}
 		logger.Info("Space exists")
 		return space, false, nil
 	}
@@ -765,6 +777,58 @@ func (r *Reconciler) ensureSpace(
 	return space, true, nil
 }
 
+// hibernateSpaceIfEnabled hibernates the home Space of the deactivated user instead of letting it be deleted along with the MUR
+// and its SpaceBinding, if the hibernation is enabled in the ToolchainConfig
+func (r *Reconciler) hibernateSpaceIfEnabled(
+	ctx context.Context,
+	config toolchainconfig.ToolchainConfig,
+	userSignup *toolchainv1alpha1.UserSignup,
+	mur *toolchainv1alpha1.MasterUserRecord,
+) error {
+	if !config.SpaceConfig().HibernationIsEnabled() || !shouldManageSpace(userSignup) {
+		return nil
+	}
+	logger := log.FromContext(ctx)
+
+	space := &toolchainv1alpha1.Space{}
+	if err := r.Client.Get(ctx, types.NamespacedName{
+		Namespace: userSignup.Namespace,
+		Name:      mur.Name,
+	}, space); err != nil {
+		if errors.IsNotFound(err) {
+			logger.Info("no Space to hibernate", "name", mur.Name)
+			return nil
+		}
+		return errs.Wrapf(err, `failed to get Space associated with mur "%s"`, mur.Name)
+	}
+	// only hibernate the Space which was created for this user
+	if space.Labels[toolchainv1alpha1.SpaceCreatorLabelKey] != userSignup.Name || util.IsBeingDeleted(space) || spaceutil.IsHibernated(space) {
+		return nil
+	}
+	if space.Annotations == nil {
+		space.Annotations = map[string]string{}
+	}
+	space.Annotations[spaceutil.HibernatedAnnotationKey] = time.Now().Format(time.RFC3339)
+	if err := r.Client.Update(ctx, space); err != nil {
+		return errs.Wrapf(err, `failed to hibernate Space "%s"`, space.Name)
+	}
+	logger.Info("Hibernated Space", "name", space.Name, "target_cluster", space.Spec.TargetCluster)
+	return nil
+}
+
+// wakeUpSpace wakes up the hibernated Space of a returning user, on the cluster the user was last provisioned to
+func (r *Reconciler) wakeUpSpace(ctx context.Context, userSignup *toolchainv1alpha1.UserSignup, space *toolchainv1alpha1.Space) error {
+	delete(space.Annotations, spaceutil.HibernatedAnnotationKey)
+	if space.Spec.TargetCluster == "" {
+		space.Spec.TargetCluster = userSignup.Annotations[toolchainv1alpha1.UserSignupLastTargetClusterAnnotationKey]
+	}
+	if err := r.Client.Update(ctx, space); err != nil {
+		return errs.Wrapf(err, `failed to wake up Space "%s"`, space.Name)
+	}
+	log.FromContext(ctx).Info("Woke up hibernated Space", "name", space.Name, "target_cluster", space.Spec.TargetCluster)
+	return nil
+}
+
 // ensureSpaceBinding creates a SpaceBinding for the provided MUR and Space if one does not exist
 func (r *Reconciler) ensureSpaceBinding(
 	ctx context.Context,
@@ -945,6 +1009,9 @@ func (r *Reconciler) sendDeactivatingNotification(ctx context.Context, config to
 		keysAndVals := map[string]string{
 			toolchainconfig.NotificationContextRegistrationURLKey: config.RegistrationService().RegistrationServiceURL(),
 		}
+		if locale := userSignup.Annotations[toolchainconfig.UserSignupLocaleAnnotationKey]; locale != "" {
+			keysAndVals[toolchainconfig.NotificationContextLocaleKey] = locale
+		}
 
 		notification, err := notify.NewNotificationBuilder(r.Client, userSignup.Namespace).
 			WithTemplate(notificationtemplates.UserDeactivatingTemplateName).
@@ -981,6 +1048,9 @@ func (r *Reconciler) sendDeactivatedNotification(ctx context.Context, config too
 		keysAndVals := map[string]string{
 			toolchainconfig.NotificationContextRegistrationURLKey: config.RegistrationService().RegistrationServiceURL(),
 		}
+		if locale := userSignup.Annotations[toolchainconfig.UserSignupLocaleAnnotationKey]; locale != "" {
+			keysAndVals[toolchainconfig.NotificationContextLocaleKey] = locale
+		}
 
 		notification, err := notify.NewNotificationBuilder(r.Client, userSignup.Namespace).
 			WithTemplate(notificationtemplates.UserDeactivatedTemplateName).
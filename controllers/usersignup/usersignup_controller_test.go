@@ -20,6 +20,7 @@ import (
 	"github.com/codeready-toolchain/host-operator/pkg/metrics"
 	"github.com/codeready-toolchain/host-operator/pkg/segment"
 	. "github.com/codeready-toolchain/host-operator/test"
+	hostconfig "github.com/codeready-toolchain/host-operator/test/config"
 	ntest "github.com/codeready-toolchain/host-operator/test/notification"
 	tiertest "github.com/codeready-toolchain/host-operator/test/nstemplatetier"
 	segmenttest "github.com/codeready-toolchain/host-operator/test/segment"
@@ -3080,6 +3081,37 @@ func TestUserSignupDeactivatingNotificationCreated(t *testing.T) {
 	)
 }
 
+func TestUserSignupDeactivatingNotificationWithLocale(t *testing.T) {
+	// given
+	userSignup := commonsignup.NewUserSignup(
+		commonsignup.WithName("edward-jones"),
+		commonsignup.ApprovedManually(),
+		commonsignup.WithStateLabel(toolchainv1alpha1.UserSignupStateLabelValueApproved),
+		commonsignup.WithAnnotation(toolchainconfig.UserSignupLocaleAnnotationKey, "fr"),
+	)
+	states.SetDeactivating(userSignup, true)
+	userSignup.Status.CompliantUsername = "edward-jones"
+	mur := murtest.NewMasterUserRecord(t, "edward-jones", murtest.MetaNamespace(test.HostOperatorNs))
+	mur.Labels = map[string]string{
+		toolchainv1alpha1.MasterUserRecordOwnerLabelKey: userSignup.Name,
+		toolchainv1alpha1.UserSignupStateLabelKey:       "approved",
+	}
+	r, req, _ := prepareReconcile(t, userSignup.Name, userSignup, mur,
+		commonconfig.NewToolchainConfigObjWithReset(t, testconfig.AutomaticApproval().Enabled(true)), baseNSTemplateTier, deactivate30Tier)
+
+	// when
+	_, err := r.Reconcile(context.TODO(), req)
+
+	// then
+	require.NoError(t, err)
+	notifications := &toolchainv1alpha1.NotificationList{}
+	err = r.Client.List(context.TODO(), notifications)
+	require.NoError(t, err)
+	require.Len(t, notifications.Items, 1)
+	assert.Equal(t, "userdeactivating", notifications.Items[0].Spec.Template)
+	assert.Equal(t, "fr", notifications.Items[0].Spec.Context[toolchainconfig.NotificationContextLocaleKey])
+}
+
 func TestUserSignupBannedWithoutMURAndSpace(t *testing.T) {
 	// given
 	userSignup := commonsignup.NewUserSignup()
@@ -3144,6 +3176,198 @@ func TestUserSignupBannedWithoutMURAndSpace(t *testing.T) {
 		})
 }
 
+func TestUserSignupBannedWithScrubbedEmail(t *testing.T) {
+	// given
+	userSignup := commonsignup.NewUserSignup()
+	userSignup.Labels[toolchainv1alpha1.UserSignupStateLabelKey] = "approved"
+
+	// the email of the BannedUser was replaced with its hash during the erasure of a previous UserSignup
+	bannedUser := &toolchainv1alpha1.BannedUser{
+		ObjectMeta: metav1.ObjectMeta{
+			Labels: map[string]string{
+				toolchainv1alpha1.BannedUserEmailHashLabelKey: "fd2addbd8d82f0d2dc088fa122377eaa",
+			},
+		},
+		Spec: toolchainv1alpha1.BannedUserSpec{
+			Email: "fd2addbd8d82f0d2dc088fa122377eaa",
+		},
+	}
+
+	r, req, _ := prepareReconcile(t, userSignup.Name, userSignup, bannedUser, commonconfig.NewToolchainConfigObjWithReset(t, testconfig.AutomaticApproval().Enabled(true)), baseNSTemplateTier)
+	InitializeCounters(t, NewToolchainStatus())
+
+	// when
+	_, err := r.Reconcile(context.TODO(), req)
+
+	// then
+	require.NoError(t, err)
+	err = r.Client.Get(context.TODO(), test.NamespacedName(test.HostOperatorNs, userSignup.Name), userSignup)
+	require.NoError(t, err)
+	assert.Equal(t, "banned", userSignup.Labels[toolchainv1alpha1.UserSignupStateLabelKey])
+	metricstest.AssertMetricsCounterEquals(t, 1, metrics.UserSignupBannedTotal)
+	test.AssertConditionsMatch(t, userSignup.Status.Conditions,
+		toolchainv1alpha1.Condition{
+			Type:   toolchainv1alpha1.UserSignupComplete,
+			Status: corev1.ConditionTrue,
+			Reason: "Banned",
+		})
+}
+
+func TestUserSignupSpaceHibernation(t *testing.T) {
+	newDeactivatedUserSignup := func() *toolchainv1alpha1.UserSignup {
+		return commonsignup.NewUserSignup(
+			commonsignup.WithName("edward-jones"),
+			commonsignup.WithCompliantUsername("edward-jones"),
+			commonsignup.WithHomeSpace("edward-jones"),
+			commonsignup.Deactivated(),
+			commonsignup.WithLabel(toolchainv1alpha1.UserSignupStateLabelKey, "approved"),
+			commonsignup.WithAnnotation(toolchainv1alpha1.UserSignupLastTargetClusterAnnotationKey, "member-1"))
+	}
+	newMUR := func(userSignup *toolchainv1alpha1.UserSignup) *toolchainv1alpha1.MasterUserRecord {
+		mur := murtest.NewMasterUserRecord(t, "edward-jones", murtest.MetaNamespace(test.HostOperatorNs))
+		mur.Labels = map[string]string{
+			toolchainv1alpha1.MasterUserRecordOwnerLabelKey: userSignup.Name,
+		}
+		return mur
+	}
+
+	t.Run("deactivation", func(t *testing.T) {
+
+		t.Run("space is hibernated when enabled", func(t *testing.T) {
+			// given
+			userSignup := newDeactivatedUserSignup()
+			space := spacetest.NewSpace(test.HostOperatorNs, "edward-jones",
+				spacetest.WithCreatorLabel(userSignup.Name),
+				spacetest.WithSpecTargetCluster("member-1"))
+			r, req, _ := prepareReconcile(t, userSignup.Name, userSignup, newMUR(userSignup), space,
+				commonconfig.NewToolchainConfigObjWithReset(t, hostconfig.Annotation(toolchainconfig.SpaceHibernationEnabledAnnotationKey, "true")),
+				baseNSTemplateTier, deactivate30Tier)
+			InitializeCounters(t, NewToolchainStatus())
+
+			// when
+			_, err := r.Reconcile(context.TODO(), req)
+
+			// then
+			require.NoError(t, err)
+			murtest.AssertThatMasterUserRecords(t, r.Client).HaveCount(0)
+			hibernated := spacetest.AssertThatSpace(t, test.HostOperatorNs, "edward-jones", r.Client).
+				Exists().
+				Get()
+			assert.True(t, IsHibernated(hibernated))
+		})
+
+		t.Run("space is not hibernated when disabled", func(t *testing.T) {
+			// given
+			userSignup := newDeactivatedUserSignup()
+			space := spacetest.NewSpace(test.HostOperatorNs, "edward-jones",
+				spacetest.WithCreatorLabel(userSignup.Name),
+				spacetest.WithSpecTargetCluster("member-1"))
+			r, req, _ := prepareReconcile(t, userSignup.Name, userSignup, newMUR(userSignup), space,
+				commonconfig.NewToolchainConfigObjWithReset(t), baseNSTemplateTier, deactivate30Tier)
+			InitializeCounters(t, NewToolchainStatus())
+
+			// when
+			_, err := r.Reconcile(context.TODO(), req)
+
+			// then
+			require.NoError(t, err)
+			murtest.AssertThatMasterUserRecords(t, r.Client).HaveCount(0)
+			spacetest.AssertThatSpace(t, test.HostOperatorNs, "edward-jones", r.Client).
+				Exists().
+				DoesNotHaveAnnotation(HibernatedAnnotationKey)
+		})
+
+		t.Run("space of another user is not hibernated", func(t *testing.T) {
+			// given
+			userSignup := newDeactivatedUserSignup()
+			space := spacetest.NewSpace(test.HostOperatorNs, "edward-jones",
+				spacetest.WithCreatorLabel("someone-else"),
+				spacetest.WithSpecTargetCluster("member-1"))
+			r, req, _ := prepareReconcile(t, userSignup.Name, userSignup, newMUR(userSignup), space,
+				commonconfig.NewToolchainConfigObjWithReset(t, hostconfig.Annotation(toolchainconfig.SpaceHibernationEnabledAnnotationKey, "true")),
+				baseNSTemplateTier, deactivate30Tier)
+			InitializeCounters(t, NewToolchainStatus())
+
+			// when
+			_, err := r.Reconcile(context.TODO(), req)
+
+			// then
+			require.NoError(t, err)
+			spacetest.AssertThatSpace(t, test.HostOperatorNs, "edward-jones", r.Client).
+				Exists().
+				DoesNotHaveAnnotation(HibernatedAnnotationKey)
+		})
+	})
+
+	t.Run("reactivation", func(t *testing.T) {
+
+		t.Run("compliant username of the hibernated space is reused", func(t *testing.T) {
+			// given
+			userSignup := newDeactivatedUserSignup()
+			userSignup.Spec.IdentityClaims.PreferredUsername = "foo"
+			space := spacetest.NewSpace(test.HostOperatorNs, "foo",
+				spacetest.WithCreatorLabel(userSignup.Name),
+				spacetest.WithAnnotation(HibernatedAnnotationKey, "2024-01-01T00:00:00Z"))
+			r, _, _ := prepareReconcile(t, userSignup.Name, userSignup, space, commonconfig.NewToolchainConfigObjWithReset(t))
+			config, err := toolchainconfig.GetToolchainConfig(r.Client)
+			require.NoError(t, err)
+
+			// when
+			username, err := r.generateCompliantUsername(context.TODO(), config, userSignup)
+
+			// then
+			require.NoError(t, err)
+			assert.Equal(t, "foo", username)
+		})
+
+		t.Run("compliant username of the hibernated space of another user is not reused", func(t *testing.T) {
+			// given
+			userSignup := newDeactivatedUserSignup()
+			userSignup.Spec.IdentityClaims.PreferredUsername = "foo"
+			space := spacetest.NewSpace(test.HostOperatorNs, "foo",
+				spacetest.WithCreatorLabel("someone-else"),
+				spacetest.WithAnnotation(HibernatedAnnotationKey, "2024-01-01T00:00:00Z"))
+			r, _, _ := prepareReconcile(t, userSignup.Name, userSignup, space, commonconfig.NewToolchainConfigObjWithReset(t))
+			config, err := toolchainconfig.GetToolchainConfig(r.Client)
+			require.NoError(t, err)
+
+			// when
+			username, err := r.generateCompliantUsername(context.TODO(), config, userSignup)
+
+			// then
+			require.NoError(t, err)
+			assert.Equal(t, "foo-2", username)
+		})
+
+		t.Run("hibernated space is woken up", func(t *testing.T) {
+			// given
+			userSignup := newDeactivatedUserSignup()
+			mur := newMUR(userSignup)
+			space := spacetest.NewSpace(test.HostOperatorNs, "edward-jones",
+				spacetest.WithCreatorLabel(userSignup.Name),
+				spacetest.WithTierName(baseNSTemplateTier.Name),
+				spacetest.WithAnnotation(HibernatedAnnotationKey, "2024-01-01T00:00:00Z"))
+			r, _, _ := prepareReconcile(t, userSignup.Name, userSignup, mur, space, commonconfig.NewToolchainConfigObjWithReset(t), baseNSTemplateTier)
+			config, err := toolchainconfig.GetToolchainConfig(r.Client)
+			require.NoError(t, err)
+
+			// when
+			actual, created, err := r.ensureSpace(context.TODO(), userSignup, mur, baseNSTemplateTier, config)
+
+			// then
+			require.NoError(t, err)
+			assert.False(t, created)
+			assert.Equal(t, "edward-jones", actual.Name)
+			spacetest.AssertThatSpace(t, test.HostOperatorNs, "edward-jones", r.Client).
+				Exists().
+				HasSpecTargetCluster("member-1").
+				HasTier(baseNSTemplateTier.Name).
+				DoesNotHaveAnnotation(HibernatedAnnotationKey)
+			spacetest.AssertThatSpaces(t, r.Client).HaveCount(1)
+		})
+	})
+}
+
 func TestUserSignupVerificationRequired(t *testing.T) {
 	// given
 	userSignup := commonsignup.NewUserSignup(commonsignup.VerificationRequired())
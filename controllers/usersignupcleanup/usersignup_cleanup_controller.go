@@ -202,9 +202,10 @@ func (r *Reconciler) isUserBanned(ctx context.Context, userSignup *toolchainv1al
 				return false, err
 			}
 
-			// One last check to confirm that the e-mail addresses match also (in case of the infinitesimal chance of a hash collision)
+			// One last check to confirm that the e-mail addresses match also (in case of the infinitesimal chance of a hash collision).
+			// BannedUsers which were scrubbed during a user erasure only retain the hash of the e-mail address.
 			for _, bannedUser := range bannedUserList.Items {
-				if bannedUser.Spec.Email == userSignup.Spec.IdentityClaims.Email {
+				if bannedUser.Spec.Email == userSignup.Spec.IdentityClaims.Email || bannedUser.Spec.Email == emailHashLbl {
 					banned = true
 					break
 				}
@@ -0,0 +1,133 @@
+// gen-annotation-validators generates the registry of the validators of the ToolchainConfig annotations (see the validateAnnotations
+// func of the toolchainconfig package) from the `+validation=` markers in the doc comments of the annotation keys, so that the
+// validator of each annotation is declared next to its key:
+//
+//	// NotificationSMTPPortAnnotationKey is the ToolchainConfig annotation which configures the port of the SMTP server
+//	// +validation=intAnnotation(1, 65535)
+//	NotificationSMTPPortAnnotationKey = toolchainv1alpha1.LabelKeyPrefix + "notification-smtp-port"
+//
+// The keys documented as ToolchainConfig annotations must have a marker.
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+const (
+	marker = "+validation="
+
+	// toolchainConfigAnnotationDoc identifies the keys of the ToolchainConfig annotations in their doc comment
+	toolchainConfigAnnotationDoc = "is the ToolchainConfig annotation"
+)
+
+func main() {
+	dir := flag.String("dir", ".", "The directory of the package which contains the annotation keys.")
+	output := flag.String("output", "zz_generated.annotations.go", "The file to generate, relative to the directory of the package.")
+	flag.Parse()
+
+	src, err := generate(*dir, *output)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	if err := os.WriteFile(filepath.Join(*dir, *output), src, 0600); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+type entry struct {
+	key, validator string
+}
+
+// generate returns the source of the registry of the validators of the annotations whose keys are declared in the given package.
+// The output file (and the tests) are ignored.
+func generate(dir, output string) ([]byte, error) {
+	fset := token.NewFileSet()
+	pkgs, err := parser.ParseDir(fset, dir, func(info fs.FileInfo) bool {
+		return info.Name() != output && !strings.HasSuffix(info.Name(), "_test.go")
+	}, parser.ParseComments)
+	if err != nil {
+		return nil, err
+	}
+	if len(pkgs) != 1 {
+		return nil, fmt.Errorf("expected a single package in '%s', found %d", dir, len(pkgs))
+	}
+	var pkgName string
+	var entries []entry
+	for name, pkg := range pkgs {
+		pkgName = name
+		for _, file := range pkg.Files {
+			fileEntries, err := annotationEntries(fset, file)
+			if err != nil {
+				return nil, err
+			}
+			entries = append(entries, fileEntries...)
+		}
+	}
+	if len(entries) == 0 {
+		return nil, fmt.Errorf("no '%s' marker found in '%s'", marker, dir)
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].key < entries[j].key
+	})
+
+	buf := &bytes.Buffer{}
+	fmt.Fprintf(buf, `// Code generated by gen-annotation-validators. DO NOT EDIT.
+
+package %s
+
+// annotationValidators are the validators of the values of the annotations configuring the host operator,
+// generated from the %s markers of their keys
+var annotationValidators = map[string]annotationValidator{
+`, pkgName, "`"+marker+"`")
+	for _, e := range entries {
+		fmt.Fprintf(buf, "\t%s: %s,\n", e.key, e.validator)
+	}
+	buf.WriteString("}\n")
+	return format.Source(buf.Bytes())
+}
+
+// annotationEntries returns the validators declared with a marker on the constants of the given file
+func annotationEntries(fset *token.FileSet, file *ast.File) ([]entry, error) {
+	var entries []entry
+	for _, decl := range file.Decls {
+		gen, ok := decl.(*ast.GenDecl)
+		if !ok || gen.Tok != token.CONST {
+			continue
+		}
+		for _, spec := range gen.Specs {
+			value := spec.(*ast.ValueSpec)
+			if value.Doc == nil {
+				continue
+			}
+			validator := ""
+			for _, c := range value.Doc.List {
+				text := strings.TrimSpace(strings.TrimPrefix(c.Text, "//"))
+				if v, found := strings.CutPrefix(text, marker); found {
+					validator = strings.TrimSpace(v)
+				}
+			}
+			for _, name := range value.Names {
+				switch {
+				case validator != "":
+					entries = append(entries, entry{key: name.Name, validator: validator})
+				case strings.Contains(value.Doc.Text(), toolchainConfigAnnotationDoc):
+					return nil, fmt.Errorf("%s: missing '%s' marker on the '%s' annotation key", fset.Position(name.Pos()), marker, name.Name)
+				}
+			}
+		}
+	}
+	return entries, nil
+}
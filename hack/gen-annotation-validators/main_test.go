@@ -0,0 +1,106 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGenerate(t *testing.T) {
+	t.Run("generated registry is up to date", func(t *testing.T) {
+		// given
+		const dir = "../../controllers/toolchainconfig"
+		const output = "zz_generated.annotations.go"
+		expected, err := os.ReadFile(filepath.Join(dir, output))
+		require.NoError(t, err)
+
+		// when
+		src, err := generate(dir, output)
+
+		// then
+		require.NoError(t, err)
+		assert.Equal(t, string(expected), string(src), "run `make generate-annotation-validators`")
+	})
+
+	t.Run("registry of the markers", func(t *testing.T) {
+		// given
+		dir := t.TempDir()
+		writeFile(t, dir, "keys.go", `package config
+
+const (
+	// PortAnnotationKey is the ToolchainConfig annotation which configures the port
+	// +validation=intAnnotation(1, 65535)
+	PortAnnotationKey = "port"
+	// HostAnnotationKey is the ToolchainConfig annotation which configures the host
+	// +validation=anyAnnotation
+	HostAnnotationKey = "host"
+	// HashAnnotationKey is the annotation of the ConfigMaps which contains the hash
+	HashAnnotationKey = "hash"
+)
+`)
+		writeFile(t, dir, "keys_test.go", `package config
+
+const (
+	// TestAnnotationKey is the ToolchainConfig annotation of the tests
+	TestAnnotationKey = "test"
+)
+`)
+
+		// when
+		src, err := generate(dir, "zz_generated.annotations.go")
+
+		// then
+		require.NoError(t, err)
+		assert.Equal(t, `// Code generated by gen-annotation-validators. DO NOT EDIT.
+
+package config
+
+// annotationValidators are the validators of the values of the annotations configuring the host operator,
+// generated from the `+"`+validation=`"+` markers of their keys
+var annotationValidators = map[string]annotationValidator{
+	HostAnnotationKey: anyAnnotation,
+	PortAnnotationKey: intAnnotation(1, 65535),
+}
+`, string(src))
+	})
+
+	t.Run("missing marker", func(t *testing.T) {
+		// given
+		dir := t.TempDir()
+		writeFile(t, dir, "keys.go", `package config
+
+const (
+	// PortAnnotationKey is the ToolchainConfig annotation which configures the port
+	// +validation=intAnnotation(1, 65535)
+	PortAnnotationKey = "port"
+	// HostAnnotationKey is the ToolchainConfig annotation which configures the host
+	HostAnnotationKey = "host"
+)
+`)
+
+		// when
+		_, err := generate(dir, "zz_generated.annotations.go")
+
+		// then
+		require.ErrorContains(t, err, "keys.go:8:2: missing '+validation=' marker on the 'HostAnnotationKey' annotation key")
+	})
+
+	t.Run("no marker", func(t *testing.T) {
+		// given
+		dir := t.TempDir()
+		writeFile(t, dir, "keys.go", "package config\n")
+
+		// when
+		_, err := generate(dir, "zz_generated.annotations.go")
+
+		// then
+		require.ErrorContains(t, err, "no '+validation=' marker found")
+	})
+}
+
+func writeFile(t *testing.T, dir, name, content string) {
+	require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(content), 0600))
+}
@@ -18,7 +18,21 @@ $(OUT_DIR)/operator:
 		go build ${V_FLAG} \
 		-ldflags "-X ${GO_PACKAGE_PATH}/version.Commit=${GIT_COMMIT_ID} -X ${GO_PACKAGE_PATH}/version.BuildTime=${BUILD_TIME}" \
 		-o $(OUT_DIR)/bin/host-operator \
-		./cmd/main.go
+		./cmd/
+
+.PHONY: render-notification-templates
+## Renders all the notification templates (and their localized variants) with a sample context, to verify that they are valid
+## and only use known context keys
+render-notification-templates:
+	$(Q)set -e; for dir in deploy/templates/notificationtemplates/*/*/; do \
+		set=$$(basename $$(dirname $$dir)); tmpl=$$(basename $$dir); \
+		if [ "$$tmpl" != "partials" ]; then \
+			go run ./cmd render-notification --template-set $$set --template $$tmpl --output-dir $(OUT_DIR)/notifications/$$set; \
+			for locale in $$(ls $$dir | sed -n 's/^[a-z]*\.\([^.]*\)\.[a-z]*$$/\1/p' | sort -u); do \
+				go run ./cmd render-notification --template-set $$set --template $$tmpl --locale $$locale --output-dir $(OUT_DIR)/notifications/$$set; \
+			done; \
+		fi; \
+	done
 
 .PHONY: vendor
 vendor:
@@ -33,7 +47,7 @@ USERTEMPLATES_TEST_BASEDIR = test/templates/usertiers
 REGISTRATION_SERVICE_DIR=deploy/registration-service
 
 .PHONY: generate
-generate: install-go-bindata generate-metadata generate-assets 
+generate: install-go-bindata generate-metadata generate-assets generate-annotation-validators
 
 install-go-bindata:
 	@go install github.com/go-bindata/go-bindata/...
@@ -58,6 +72,11 @@ generate-assets: go-bindata
 	@$(GO_BINDATA) -pkg nstemplatetiers -o ./pkg/templates/nstemplatetiers/nstemplatetier_assets.go -nometadata -nocompress -prefix $(NSTEMPLATES_BASEDIR) $(NSTEMPLATES_BASEDIR)/...
 	
 
+.PHONY: generate-annotation-validators
+## Generates the registry of the validators of the ToolchainConfig annotations from the `+validation=` markers of their keys
+generate-annotation-validators:
+	$(Q)cd controllers/toolchainconfig && go generate ./
+
 .PHONY: verify-dependencies
 ## Runs commands to verify after the updated dependecies of toolchain-common/API(go mod replace), if the repo needs any changes to be made
 verify-dependencies: tidy vet build test lint-go-code
@@ -0,0 +1,371 @@
+package cluster
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"sync"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	runtimeclient "sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+// CircuitState is the state of the circuit breaker of a member cluster
+type CircuitState string
+
+const (
+	// CircuitClosed is the state of a circuit breaker letting all the requests through
+	CircuitClosed CircuitState = "Closed"
+	// CircuitOpen is the state of a circuit breaker rejecting all the requests, after too many consecutive failures
+	CircuitOpen CircuitState = "Open"
+	// CircuitHalfOpen is the state of a circuit breaker letting a single request through to probe the member cluster, once it
+	// has been open for long enough
+	CircuitHalfOpen CircuitState = "HalfOpen"
+)
+
+// CircuitBreakerSettings are the settings of the circuit breakers
+type CircuitBreakerSettings struct {
+	// FailureThreshold is the number of consecutive failures opening the circuit. 0 disables the circuit breaker.
+	FailureThreshold int
+	// OpenDuration is for how long the circuit stays open before the first probe
+	OpenDuration time.Duration
+	// MaxOpenDuration is the maximum duration the circuit stays open between two probes, as the duration is doubled each time a probe fails
+	MaxOpenDuration time.Duration
+}
+
+// CircuitBreakerStatus is a snapshot of the state of a circuit breaker
+type CircuitBreakerStatus struct {
+	State CircuitState
+	// Failures is the number of consecutive failures
+	Failures int
+	// LastError is the error of the last failure
+	LastError string
+	// RetryAt is when the next probe can be sent, if the circuit is open
+	RetryAt time.Time
+	// LastTransitionTime is when the state last changed
+	LastTransitionTime time.Time
+}
+
+// CircuitOpenError is the error of the requests rejected by an open circuit breaker
+type CircuitOpenError struct {
+	Cluster    string
+	RetryAfter time.Duration
+}
+
+func (e *CircuitOpenError) Error() string {
+	return fmt.Sprintf("the circuit breaker of the member cluster '%s' is open, retrying in %s", e.Cluster, e.RetryAfter)
+}
+
+// IsCircuitOpen returns true and the delay before the next attempt if the given error (or one of the errors it wraps) is a CircuitOpenError
+func IsCircuitOpen(err error) (time.Duration, bool) {
+	var circuitOpenErr *CircuitOpenError
+	if errors.As(err, &circuitOpenErr) {
+		return circuitOpenErr.RetryAfter, true
+	}
+	return 0, false
+}
+
+// CircuitBreaker stops sending requests to the API server of a member cluster after too many consecutive failures, so that the
+// reconciles fail fast instead of waiting for the client timeout while the member cluster is unreachable. Once the circuit has been
+// open for long enough, a single request is let through to probe the member cluster: the circuit closes if it succeeds, and opens
+// again for twice as long otherwise.
+type CircuitBreaker struct {
+	name     string
+	settings func() CircuitBreakerSettings
+	now      func() time.Time
+
+	mu                 sync.Mutex
+	state              CircuitState
+	failures           int
+	lastError          string
+	openDuration       time.Duration
+	retryAt            time.Time
+	lastTransitionTime time.Time
+	probing            bool
+}
+
+// NewCircuitBreaker returns a closed circuit breaker for the member cluster with the given name. The settings are read each time they're
+// needed, so that they can be changed at runtime.
+func NewCircuitBreaker(name string, settings func() CircuitBreakerSettings) *CircuitBreaker {
+	return &CircuitBreaker{
+		name:               name,
+		settings:           settings,
+		now:                time.Now,
+		state:              CircuitClosed,
+		lastTransitionTime: time.Now(),
+	}
+}
+
+// Do calls the given function unless the circuit is open, and records its outcome. A nil circuit breaker always calls the function.
+func (b *CircuitBreaker) Do(f func() error) error {
+	if b == nil {
+		return f()
+	}
+	if err := b.allow(); err != nil {
+		return err
+	}
+	err := f()
+	b.record(err)
+	return err
+}
+
+// Status returns the current state of the circuit breaker
+func (b *CircuitBreaker) Status() CircuitBreakerStatus {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return CircuitBreakerStatus{
+		State:              b.state,
+		Failures:           b.failures,
+		LastError:          b.lastError,
+		RetryAt:            b.retryAt,
+		LastTransitionTime: b.lastTransitionTime,
+	}
+}
+
+func (b *CircuitBreaker) allow() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.settings().FailureThreshold == 0 {
+		if b.state != CircuitClosed {
+			b.close()
+		}
+		return nil
+	}
+	now := b.now()
+	switch b.state {
+	case CircuitOpen:
+		if now.Before(b.retryAt) {
+			return &CircuitOpenError{Cluster: b.name, RetryAfter: b.retryAt.Sub(now)}
+		}
+		b.transition(CircuitHalfOpen)
+		b.probing = true
+		return nil
+	case CircuitHalfOpen:
+		if b.probing {
+			// another request is already probing the member cluster
+			return &CircuitOpenError{Cluster: b.name, RetryAfter: b.settings().OpenDuration}
+		}
+		b.probing = true
+		return nil
+	}
+	return nil
+}
+
+func (b *CircuitBreaker) record(err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	probe := b.probing
+	b.probing = false
+	if errors.Is(err, context.Canceled) {
+		// the outcome of a canceled request says nothing about the member cluster
+		return
+	}
+	if !isUnavailable(err) {
+		b.failures = 0
+		if b.state != CircuitClosed {
+			log.Log.Info("member cluster available again, closing the circuit breaker", "cluster", b.name)
+			b.close()
+		}
+		return
+	}
+	b.failures++
+	b.lastError = err.Error()
+	settings := b.settings()
+	switch {
+	case b.state == CircuitHalfOpen && probe:
+		b.open(min(2*b.openDuration, settings.MaxOpenDuration))
+	case b.state == CircuitClosed && settings.FailureThreshold > 0 && b.failures >= settings.FailureThreshold:
+		b.open(settings.OpenDuration)
+	}
+}
+
+func (b *CircuitBreaker) open(duration time.Duration) {
+	log.Log.Info("member cluster unavailable, opening the circuit breaker", "cluster", b.name, "failures", b.failures, "duration", duration, "error", b.lastError)
+	b.openDuration = duration
+	b.retryAt = b.now().Add(duration)
+	b.transition(CircuitOpen)
+}
+
+func (b *CircuitBreaker) close() {
+	b.failures = 0
+	b.lastError = ""
+	b.openDuration = 0
+	b.retryAt = time.Time{}
+	b.transition(CircuitClosed)
+}
+
+func (b *CircuitBreaker) transition(state CircuitState) {
+	if b.state != state {
+		b.state = state
+		b.lastTransitionTime = b.now()
+	}
+}
+
+// isUnavailable returns true if the given error means that the API server of the member cluster is unreachable or unable to serve
+// the requests. The other errors (eg. not found or conflict) are responses of a healthy API server.
+func isUnavailable(err error) bool {
+	if err == nil {
+		return false
+	}
+	if apierrors.IsTimeout(err) || apierrors.IsServerTimeout(err) || apierrors.IsServiceUnavailable(err) {
+		return true
+	}
+	var urlErr *url.Error
+	var netErr net.Error
+	return errors.Is(err, context.DeadlineExceeded) || errors.As(err, &urlErr) || errors.As(err, &netErr)
+}
+
+// CircuitBreakers are the circuit breakers of the member clusters, keyed by their names. The member clusters with namespaced and
+// cluster-scoped caches share the circuit breaker of their API server.
+type CircuitBreakers struct {
+	settings func() CircuitBreakerSettings
+
+	mu       sync.Mutex
+	breakers map[string]*CircuitBreaker
+}
+
+// NewCircuitBreakers returns the circuit breakers of the member clusters, which are created with the given settings on demand
+func NewCircuitBreakers(settings func() CircuitBreakerSettings) *CircuitBreakers {
+	return &CircuitBreakers{
+		settings: settings,
+		breakers: map[string]*CircuitBreaker{},
+	}
+}
+
+// Get returns the circuit breaker of the member cluster with the given name, which is created if needed
+func (c *CircuitBreakers) Get(name string) *CircuitBreaker {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	breaker, found := c.breakers[name]
+	if !found {
+		breaker = NewCircuitBreaker(name, c.settings)
+		c.breakers[name] = breaker
+	}
+	return breaker
+}
+
+// RequeueOnOpenCircuit returns a reconciler which requeues the request once the circuit breaker which rejected a request to a member
+// cluster lets the requests through again, instead of returning the error to the rate limiter of the controller which would retry it
+// right away. Since the circuit stays open for longer each time a probe fails, the requests are retried with a backoff.
+func RequeueOnOpenCircuit(r reconcile.Reconciler) reconcile.Reconciler {
+	return reconcile.Func(func(ctx context.Context, request reconcile.Request) (reconcile.Result, error) {
+		result, err := r.Reconcile(ctx, request)
+		if retryAfter, open := IsCircuitOpen(err); open {
+			log.FromContext(ctx).Info("member cluster unavailable, requeuing", "error", err.Error(), "retryAfter", retryAfter)
+			return reconcile.Result{RequeueAfter: retryAfter}, nil
+		}
+		return result, err
+	})
+}
+
+// NewCircuitBreakerClient returns a client sending the requests of the given client through the given circuit breaker. The reads of the
+// typed objects are served by the cache of the member cluster, which keeps serving them (maybe stale) while the member cluster is
+// unreachable, so they are not sent through the circuit breaker. The reads which are sent to the API server, ie. the reads of the
+// unstructured objects (which are not cached by default) and of the subresources, are sent through the circuit breaker, as the writes.
+func NewCircuitBreakerClient(cl runtimeclient.Client, breaker *CircuitBreaker) runtimeclient.Client {
+	return &circuitBreakerClient{Client: cl, breaker: breaker}
+}
+
+type circuitBreakerClient struct {
+	runtimeclient.Client
+	breaker *CircuitBreaker
+}
+
+func (c *circuitBreakerClient) Get(ctx context.Context, key runtimeclient.ObjectKey, obj runtimeclient.Object, opts ...runtimeclient.GetOption) error {
+	if !isUncached(obj) {
+		return c.Client.Get(ctx, key, obj, opts...)
+	}
+	return c.breaker.Do(func() error {
+		return c.Client.Get(ctx, key, obj, opts...)
+	})
+}
+
+func (c *circuitBreakerClient) List(ctx context.Context, list runtimeclient.ObjectList, opts ...runtimeclient.ListOption) error {
+	if !isUncached(list) {
+		return c.Client.List(ctx, list, opts...)
+	}
+	return c.breaker.Do(func() error {
+		return c.Client.List(ctx, list, opts...)
+	})
+}
+
+// isUncached returns true if the reads of the given object are not served by the cache, ie. if it's unstructured
+func isUncached(obj runtime.Object) bool {
+	_, unstructured := obj.(runtime.Unstructured)
+	return unstructured
+}
+
+func (c *circuitBreakerClient) Create(ctx context.Context, obj runtimeclient.Object, opts ...runtimeclient.CreateOption) error {
+	return c.breaker.Do(func() error {
+		return c.Client.Create(ctx, obj, opts...)
+	})
+}
+
+func (c *circuitBreakerClient) Update(ctx context.Context, obj runtimeclient.Object, opts ...runtimeclient.UpdateOption) error {
+	return c.breaker.Do(func() error {
+		return c.Client.Update(ctx, obj, opts...)
+	})
+}
+
+func (c *circuitBreakerClient) Patch(ctx context.Context, obj runtimeclient.Object, patch runtimeclient.Patch, opts ...runtimeclient.PatchOption) error {
+	return c.breaker.Do(func() error {
+		return c.Client.Patch(ctx, obj, patch, opts...)
+	})
+}
+
+func (c *circuitBreakerClient) Delete(ctx context.Context, obj runtimeclient.Object, opts ...runtimeclient.DeleteOption) error {
+	return c.breaker.Do(func() error {
+		return c.Client.Delete(ctx, obj, opts...)
+	})
+}
+
+func (c *circuitBreakerClient) DeleteAllOf(ctx context.Context, obj runtimeclient.Object, opts ...runtimeclient.DeleteAllOfOption) error {
+	return c.breaker.Do(func() error {
+		return c.Client.DeleteAllOf(ctx, obj, opts...)
+	})
+}
+
+func (c *circuitBreakerClient) Status() runtimeclient.SubResourceWriter {
+	return &circuitBreakerSubResourceClient{SubResourceWriter: c.Client.Status(), breaker: c.breaker}
+}
+
+func (c *circuitBreakerClient) SubResource(subResource string) runtimeclient.SubResourceClient {
+	subResourceClient := c.Client.SubResource(subResource)
+	return &circuitBreakerSubResourceClient{SubResourceReader: subResourceClient, SubResourceWriter: subResourceClient, breaker: c.breaker}
+}
+
+type circuitBreakerSubResourceClient struct {
+	runtimeclient.SubResourceReader
+	runtimeclient.SubResourceWriter
+	breaker *CircuitBreaker
+}
+
+func (c *circuitBreakerSubResourceClient) Get(ctx context.Context, obj runtimeclient.Object, subResource runtimeclient.Object, opts ...runtimeclient.SubResourceGetOption) error {
+	return c.breaker.Do(func() error {
+		return c.SubResourceReader.Get(ctx, obj, subResource, opts...)
+	})
+}
+
+func (c *circuitBreakerSubResourceClient) Create(ctx context.Context, obj runtimeclient.Object, subResource runtimeclient.Object, opts ...runtimeclient.SubResourceCreateOption) error {
+	return c.breaker.Do(func() error {
+		return c.SubResourceWriter.Create(ctx, obj, subResource, opts...)
+	})
+}
+
+func (c *circuitBreakerSubResourceClient) Update(ctx context.Context, obj runtimeclient.Object, opts ...runtimeclient.SubResourceUpdateOption) error {
+	return c.breaker.Do(func() error {
+		return c.SubResourceWriter.Update(ctx, obj, opts...)
+	})
+}
+
+func (c *circuitBreakerSubResourceClient) Patch(ctx context.Context, obj runtimeclient.Object, patch runtimeclient.Patch, opts ...runtimeclient.SubResourcePatchOption) error {
+	return c.breaker.Do(func() error {
+		return c.SubResourceWriter.Patch(ctx, obj, patch, opts...)
+	})
+}
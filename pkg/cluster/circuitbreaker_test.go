@@ -0,0 +1,303 @@
+package cluster
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"testing"
+	"time"
+
+	toolchainv1alpha1 "github.com/codeready-toolchain/api/api/v1alpha1"
+	"github.com/codeready-toolchain/toolchain-common/pkg/test"
+
+	errs "github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	runtimeclient "sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+func TestCircuitBreaker(t *testing.T) {
+	unreachable := &url.Error{Op: "Get", URL: "https://member", Err: fmt.Errorf("connection refused")}
+	settings := func() CircuitBreakerSettings {
+		return CircuitBreakerSettings{FailureThreshold: 3, OpenDuration: 10 * time.Second, MaxOpenDuration: 30 * time.Second}
+	}
+	newBreaker := func() (*CircuitBreaker, *fakeClock) {
+		clock := &fakeClock{now: time.Now()}
+		breaker := NewCircuitBreaker("member1", settings)
+		breaker.now = clock.Now
+		return breaker, clock
+	}
+	fail := func(b *CircuitBreaker, err error, times int) {
+		for i := 0; i < times; i++ {
+			_ = b.Do(func() error { return err })
+		}
+	}
+
+	t.Run("stays closed below the threshold", func(t *testing.T) {
+		// given
+		breaker, _ := newBreaker()
+
+		// when
+		fail(breaker, unreachable, 2)
+
+		// then
+		assert.Equal(t, CircuitClosed, breaker.Status().State)
+		assert.Equal(t, 2, breaker.Status().Failures)
+
+		t.Run("success resets the failures", func(t *testing.T) {
+			// when
+			err := breaker.Do(func() error { return nil })
+
+			// then
+			require.NoError(t, err)
+			assert.Equal(t, CircuitClosed, breaker.Status().State)
+			assert.Zero(t, breaker.Status().Failures)
+		})
+	})
+
+	t.Run("errors of a healthy API server are not failures", func(t *testing.T) {
+		// given
+		breaker, _ := newBreaker()
+
+		// when
+		fail(breaker, apierrors.NewNotFound(schema.GroupResource{Resource: "spaces"}, "john"), 5)
+		fail(breaker, apierrors.NewConflict(schema.GroupResource{Resource: "spaces"}, "john", fmt.Errorf("conflict")), 5)
+		fail(breaker, context.Canceled, 5)
+
+		// then
+		assert.Equal(t, CircuitClosed, breaker.Status().State)
+		assert.Zero(t, breaker.Status().Failures)
+	})
+
+	t.Run("opens, probes and closes", func(t *testing.T) {
+		// given
+		breaker, clock := newBreaker()
+
+		// when
+		fail(breaker, errs.Wrap(unreachable, "unable to create the NSTemplateSet"), 3)
+
+		// then
+		status := breaker.Status()
+		assert.Equal(t, CircuitOpen, status.State)
+		assert.Equal(t, 3, status.Failures)
+		assert.Equal(t, "unable to create the NSTemplateSet: Get \"https://member\": connection refused", status.LastError)
+		assert.Equal(t, clock.now.Add(10*time.Second), status.RetryAt)
+		assert.Equal(t, clock.now, status.LastTransitionTime)
+
+		t.Run("fails fast while open", func(t *testing.T) {
+			// given
+			clock.advance(4 * time.Second)
+			called := false
+
+			// when
+			err := breaker.Do(func() error {
+				called = true
+				return nil
+			})
+
+			// then
+			assert.False(t, called)
+			require.EqualError(t, err, "the circuit breaker of the member cluster 'member1' is open, retrying in 6s")
+			retryAfter, open := IsCircuitOpen(errs.Wrap(err, "unable to update the Space"))
+			assert.True(t, open)
+			assert.Equal(t, 6*time.Second, retryAfter)
+		})
+
+		t.Run("failed probe opens the circuit for longer", func(t *testing.T) {
+			// given
+			clock.advance(6 * time.Second)
+
+			// when
+			err := breaker.Do(func() error {
+				// only a single request probes the member cluster
+				_, open := IsCircuitOpen(breaker.Do(func() error { return nil }))
+				assert.True(t, open)
+				assert.Equal(t, CircuitHalfOpen, breaker.Status().State)
+				return unreachable
+			})
+
+			// then
+			require.Equal(t, unreachable, err)
+			assert.Equal(t, CircuitOpen, breaker.Status().State)
+			assert.Equal(t, clock.now.Add(20*time.Second), breaker.Status().RetryAt)
+		})
+
+		t.Run("open duration is capped", func(t *testing.T) {
+			// given
+			clock.advance(20 * time.Second)
+
+			// when
+			fail(breaker, unreachable, 1)
+
+			// then
+			assert.Equal(t, CircuitOpen, breaker.Status().State)
+			assert.Equal(t, clock.now.Add(30*time.Second), breaker.Status().RetryAt)
+		})
+
+		t.Run("successful probe closes the circuit", func(t *testing.T) {
+			// given
+			clock.advance(30 * time.Second)
+
+			// when
+			err := breaker.Do(func() error { return nil })
+
+			// then
+			require.NoError(t, err)
+			status := breaker.Status()
+			assert.Equal(t, CircuitClosed, status.State)
+			assert.Zero(t, status.Failures)
+			assert.Empty(t, status.LastError)
+			assert.Equal(t, clock.now, status.LastTransitionTime)
+
+			t.Run("opens again with the initial duration", func(t *testing.T) {
+				// when
+				fail(breaker, unreachable, 3)
+
+				// then
+				assert.Equal(t, CircuitOpen, breaker.Status().State)
+				assert.Equal(t, clock.now.Add(10*time.Second), breaker.Status().RetryAt)
+			})
+		})
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		// given
+		breaker := NewCircuitBreaker("member1", func() CircuitBreakerSettings {
+			return CircuitBreakerSettings{FailureThreshold: 0}
+		})
+
+		// when
+		fail(breaker, unreachable, 10)
+
+		// then
+		assert.Equal(t, CircuitClosed, breaker.Status().State)
+		assert.NoError(t, breaker.Do(func() error { return nil }))
+	})
+
+	t.Run("nil circuit breaker", func(t *testing.T) {
+		// given
+		var breaker *CircuitBreaker
+
+		// when
+		err := breaker.Do(func() error { return unreachable })
+
+		// then
+		require.Equal(t, unreachable, err)
+	})
+}
+
+func TestCircuitBreakers(t *testing.T) {
+	// given
+	breakers := NewCircuitBreakers(func() CircuitBreakerSettings {
+		return CircuitBreakerSettings{FailureThreshold: 1, OpenDuration: time.Second, MaxOpenDuration: time.Second}
+	})
+
+	// when
+	member1 := breakers.Get("member1")
+
+	// then
+	assert.Same(t, member1, breakers.Get("member1"))
+	assert.NotSame(t, member1, breakers.Get("member2"))
+}
+
+func TestCircuitBreakerClient(t *testing.T) {
+	// given
+	breaker := NewCircuitBreaker("member1", func() CircuitBreakerSettings {
+		return CircuitBreakerSettings{FailureThreshold: 1, OpenDuration: time.Minute, MaxOpenDuration: time.Minute}
+	})
+	space := &toolchainv1alpha1.Space{ObjectMeta: metav1.ObjectMeta{Name: "john", Namespace: test.HostOperatorNs}}
+	fakeClient := test.NewFakeClient(t, space)
+	fakeClient.MockUpdate = func(ctx context.Context, obj runtimeclient.Object, opts ...runtimeclient.UpdateOption) error {
+		return &url.Error{Op: "Put", URL: "https://member", Err: fmt.Errorf("connection refused")}
+	}
+	cl := NewCircuitBreakerClient(fakeClient, breaker)
+	require.Error(t, cl.Update(context.TODO(), space))
+	require.Equal(t, CircuitOpen, breaker.Status().State)
+
+	t.Run("writes fail fast", func(t *testing.T) {
+		for name, write := range map[string]func() error{
+			"create":        func() error { return cl.Create(context.TODO(), space.DeepCopy()) },
+			"update":        func() error { return cl.Update(context.TODO(), space) },
+			"patch":         func() error { return cl.Patch(context.TODO(), space, runtimeclient.MergeFrom(space)) },
+			"delete":        func() error { return cl.Delete(context.TODO(), space) },
+			"delete all":    func() error { return cl.DeleteAllOf(context.TODO(), &toolchainv1alpha1.Space{}) },
+			"status update": func() error { return cl.Status().Update(context.TODO(), space) },
+		} {
+			t.Run(name, func(t *testing.T) {
+				_, open := IsCircuitOpen(write())
+				assert.True(t, open)
+			})
+		}
+	})
+
+	t.Run("cached reads are served", func(t *testing.T) {
+		require.NoError(t, cl.Get(context.TODO(), runtimeclient.ObjectKeyFromObject(space), &toolchainv1alpha1.Space{}))
+		require.NoError(t, cl.List(context.TODO(), &toolchainv1alpha1.SpaceList{}))
+	})
+
+	t.Run("uncached reads fail fast", func(t *testing.T) {
+		unstructuredSpace := &unstructured.Unstructured{}
+		unstructuredSpace.SetGroupVersionKind(toolchainv1alpha1.GroupVersion.WithKind("Space"))
+		unstructuredSpaces := &unstructured.UnstructuredList{}
+		unstructuredSpaces.SetGroupVersionKind(toolchainv1alpha1.GroupVersion.WithKind("SpaceList"))
+		for name, read := range map[string]func() error{
+			"unstructured get": func() error {
+				return cl.Get(context.TODO(), runtimeclient.ObjectKeyFromObject(space), unstructuredSpace)
+			},
+			"unstructured list": func() error { return cl.List(context.TODO(), unstructuredSpaces) },
+			"subresource get":   func() error { return cl.SubResource("status").Get(context.TODO(), space, &toolchainv1alpha1.Space{}) },
+		} {
+			t.Run(name, func(t *testing.T) {
+				_, open := IsCircuitOpen(read())
+				assert.True(t, open)
+			})
+		}
+	})
+}
+
+func TestRequeueOnOpenCircuit(t *testing.T) {
+	t.Run("requeued once the circuit breaker lets the requests through", func(t *testing.T) {
+		// given
+		r := RequeueOnOpenCircuit(reconcile.Func(func(context.Context, reconcile.Request) (reconcile.Result, error) {
+			return reconcile.Result{}, errs.Wrap(&CircuitOpenError{Cluster: "member1", RetryAfter: 5 * time.Second}, "unable to create the NSTemplateSet")
+		}))
+
+		// when
+		result, err := r.Reconcile(context.TODO(), reconcile.Request{})
+
+		// then
+		require.NoError(t, err)
+		assert.Equal(t, reconcile.Result{RequeueAfter: 5 * time.Second}, result)
+	})
+
+	t.Run("other errors returned", func(t *testing.T) {
+		// given
+		r := RequeueOnOpenCircuit(reconcile.Func(func(context.Context, reconcile.Request) (reconcile.Result, error) {
+			return reconcile.Result{Requeue: true}, fmt.Errorf("mock error")
+		}))
+
+		// when
+		result, err := r.Reconcile(context.TODO(), reconcile.Request{})
+
+		// then
+		require.EqualError(t, err, "mock error")
+		assert.True(t, result.Requeue)
+	})
+}
+
+type fakeClock struct {
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	return c.now
+}
+
+func (c *fakeClock) advance(d time.Duration) {
+	c.now = c.now.Add(d)
+}
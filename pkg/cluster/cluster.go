@@ -18,6 +18,8 @@ type Cluster struct {
 	Client     runtimeclient.Client
 	RESTClient *rest.RESTClient
 	Cache      cache.Cache
+	// CircuitBreaker is the circuit breaker of the requests sent to the API server of the member cluster, if any
+	CircuitBreaker *CircuitBreaker
 }
 
 // LookupMember takes in a list of member clusters and the NamespacedName of the Object, it then searches for the member cluster that triggered the request.
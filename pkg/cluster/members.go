@@ -0,0 +1,152 @@
+package cluster
+
+import (
+	"context"
+	"sync"
+
+	commoncluster "github.com/codeready-toolchain/toolchain-common/pkg/cluster"
+	errs "github.com/pkg/errors"
+)
+
+// NewClusterFunc creates the member cluster of the given config, with its cache started. The returned function stops the cache.
+type NewClusterFunc func(config *commoncluster.Config) (Cluster, context.CancelFunc, error)
+
+// MemberClusterHandler is called for each member cluster added to the MemberClusters, eg. to watch resources in its cache.
+//
+// The watches can't be removed from the controllers, so each time a member cluster is re-created (see Ensure), the watches of the
+// previous member cluster remain registered. They don't receive any event anymore once its cache is stopped, and only keep a few
+// objects in memory, which is acceptable since the member clusters are only re-created when their connection details change.
+type MemberClusterHandler func(memberCluster Cluster) error
+
+// MemberClusters is the set of the member clusters, which changes at runtime when the ToolchainClusters are created, updated
+// or deleted. It's safe for concurrent use.
+type MemberClusters struct {
+	newCluster NewClusterFunc
+
+	// changes serializes the changes of the member clusters and of the handlers, so that the member clusters are created and set up
+	// by the handlers without holding mu, which would block the readers meanwhile
+	changes  sync.Mutex
+	handlers []MemberClusterHandler
+
+	mu       sync.RWMutex
+	clusters map[string]memberCluster
+}
+
+type memberCluster struct {
+	cluster Cluster
+	stop    context.CancelFunc
+}
+
+// NewMemberClusters returns an empty set of member clusters, which are created with the given function when they're added
+func NewMemberClusters(newCluster NewClusterFunc) *MemberClusters {
+	return &MemberClusters{
+		newCluster: newCluster,
+		clusters:   map[string]memberCluster{},
+	}
+}
+
+// NewStaticMemberClusters returns a set containing the given member clusters. No other member cluster can be added to it.
+func NewStaticMemberClusters(clusters map[string]Cluster) *MemberClusters {
+	m := NewMemberClusters(func(config *commoncluster.Config) (Cluster, context.CancelFunc, error) {
+		return Cluster{}, nil, errs.Errorf("unable to add the member cluster '%s' to a static set of member clusters", config.Name)
+	})
+	for name, c := range clusters {
+		m.clusters[name] = memberCluster{cluster: c}
+	}
+	return m
+}
+
+// Get returns the member cluster with the given name, if it exists
+func (m *MemberClusters) Get(name string) (Cluster, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	c, found := m.clusters[name]
+	return c.cluster, found
+}
+
+// All returns a snapshot of the member clusters, keyed by their names
+func (m *MemberClusters) All() map[string]Cluster {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	clusters := make(map[string]Cluster, len(m.clusters))
+	for name, c := range m.clusters {
+		clusters[name] = c.cluster
+	}
+	return clusters
+}
+
+// AddHandler calls the given handler for each current member cluster, and then for each member cluster added later.
+// The handler must not add, re-create or remove member clusters, nor add other handlers.
+func (m *MemberClusters) AddHandler(handler MemberClusterHandler) error {
+	m.changes.Lock()
+	defer m.changes.Unlock()
+	for _, c := range m.All() {
+		if err := handler(c); err != nil {
+			return err
+		}
+	}
+	m.handlers = append(m.handlers, handler)
+	return nil
+}
+
+// Ensure adds the member cluster of the given config, or re-creates it if its API endpoint, operator namespace or token changed.
+// It returns true if the member cluster was added or re-created. The new member cluster is created and set up by the handlers
+// before it replaces the previous one (if any), which remains available in the meantime.
+func (m *MemberClusters) Ensure(config *commoncluster.Config) (bool, error) {
+	m.changes.Lock()
+	defer m.changes.Unlock()
+	m.mu.RLock()
+	existing, found := m.clusters[config.Name]
+	m.mu.RUnlock()
+	if found && !changed(existing.cluster.Config, config) {
+		return false, nil
+	}
+	c, stop, err := m.newCluster(config)
+	if err != nil {
+		return false, errs.Wrapf(err, "unable to create the member cluster '%s'", config.Name)
+	}
+	for _, handler := range m.handlers {
+		if err := handler(c); err != nil {
+			// keep the previous member cluster (if any), so that the next attempt creates the member cluster again
+			stopCluster(memberCluster{cluster: c, stop: stop})
+			return false, errs.Wrapf(err, "unable to set up the member cluster '%s'", config.Name)
+		}
+	}
+	m.mu.Lock()
+	m.clusters[config.Name] = memberCluster{cluster: c, stop: stop}
+	m.mu.Unlock()
+	if found {
+		stopCluster(existing)
+	}
+	return true, nil
+}
+
+// Remove removes the member cluster with the given name and stops its cache. It returns false if there was no such member cluster.
+func (m *MemberClusters) Remove(name string) bool {
+	m.changes.Lock()
+	defer m.changes.Unlock()
+	m.mu.Lock()
+	existing, found := m.clusters[name]
+	delete(m.clusters, name)
+	m.mu.Unlock()
+	if !found {
+		return false
+	}
+	stopCluster(existing)
+	return true
+}
+
+func stopCluster(c memberCluster) {
+	if c.stop != nil {
+		c.stop()
+	}
+}
+
+func changed(current, config *commoncluster.Config) bool {
+	if current == nil || current.RestConfig == nil || config.RestConfig == nil {
+		return true
+	}
+	return current.APIEndpoint != config.APIEndpoint ||
+		current.OperatorNamespace != config.OperatorNamespace ||
+		current.RestConfig.BearerToken != config.RestConfig.BearerToken
+}
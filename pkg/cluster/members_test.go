@@ -0,0 +1,276 @@
+package cluster
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	commoncluster "github.com/codeready-toolchain/toolchain-common/pkg/cluster"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/client-go/rest"
+)
+
+func TestMemberClusters(t *testing.T) {
+	config := func(name, apiEndpoint string) *commoncluster.Config {
+		return &commoncluster.Config{
+			Name:              name,
+			APIEndpoint:       apiEndpoint,
+			OperatorNamespace: "toolchain-member-operator",
+			RestConfig:        &rest.Config{Host: apiEndpoint, BearerToken: "token"},
+		}
+	}
+
+	t.Run("add, update and remove", func(t *testing.T) {
+		// given
+		factory := newFakeFactory()
+		memberClusters := NewMemberClusters(factory.newCluster)
+		require.NoError(t, ensure(memberClusters, config("member1", "https://member1")))
+		var handled []string
+		err := memberClusters.AddHandler(func(memberCluster Cluster) error {
+			handled = append(handled, memberCluster.APIEndpoint)
+			return nil
+		})
+		require.NoError(t, err)
+		assert.Equal(t, []string{"https://member1"}, handled)
+
+		t.Run("add", func(t *testing.T) {
+			// when
+			added, err := memberClusters.Ensure(config("member2", "https://member2"))
+
+			// then
+			require.NoError(t, err)
+			assert.True(t, added)
+			assert.Equal(t, []string{"https://member1", "https://member2"}, handled)
+			assert.Equal(t, []string{"member1", "member2"}, names(memberClusters))
+			assert.Len(t, memberClusters.All(), 2)
+		})
+
+		t.Run("unchanged", func(t *testing.T) {
+			// when
+			added, err := memberClusters.Ensure(config("member2", "https://member2"))
+
+			// then
+			require.NoError(t, err)
+			assert.False(t, added)
+			assert.Equal(t, []string{"https://member1", "https://member2"}, handled)
+			assert.Empty(t, factory.stopped)
+		})
+
+		t.Run("token rotated", func(t *testing.T) {
+			// given
+			cfg := config("member2", "https://member2")
+			cfg.RestConfig.BearerToken = "rotated"
+
+			// when
+			added, err := memberClusters.Ensure(cfg)
+
+			// then
+			require.NoError(t, err)
+			assert.True(t, added)
+			assert.Equal(t, []string{"https://member1", "https://member2", "https://member2"}, handled)
+			assert.Equal(t, []string{"https://member2"}, factory.stopped)
+			memberCluster, found := memberClusters.Get("member2")
+			require.True(t, found)
+			assert.Equal(t, "rotated", memberCluster.RestConfig.BearerToken)
+		})
+
+		t.Run("remove", func(t *testing.T) {
+			// when
+			removed := memberClusters.Remove("member2")
+
+			// then
+			assert.True(t, removed)
+			assert.Equal(t, []string{"https://member2", "https://member2"}, factory.stopped)
+			_, found := memberClusters.Get("member2")
+			assert.False(t, found)
+			assert.Equal(t, []string{"member1"}, names(memberClusters))
+			assert.False(t, memberClusters.Remove("member2"))
+		})
+	})
+
+	t.Run("repeated ensure", func(t *testing.T) {
+		// given
+		factory := newFakeFactory()
+		memberClusters := NewMemberClusters(factory.newCluster)
+		handled := 0
+		require.NoError(t, memberClusters.AddHandler(func(_ Cluster) error {
+			handled++
+			return nil
+		}))
+
+		t.Run("same config", func(t *testing.T) {
+			// when
+			var wg sync.WaitGroup
+			var added atomic.Int32
+			for i := 0; i < 10; i++ {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					ok, err := memberClusters.Ensure(config("member1", "https://member1"))
+					assert.NoError(t, err)
+					if ok {
+						added.Add(1)
+					}
+				}()
+			}
+			wg.Wait()
+
+			// then
+			assert.Equal(t, int32(1), added.Load())
+			assert.Equal(t, []string{"https://member1"}, factory.created)
+			assert.Equal(t, 1, handled)
+			assert.Empty(t, factory.stopped)
+		})
+
+		t.Run("token rotated several times", func(t *testing.T) {
+			for i := 1; i <= 3; i++ {
+				// given
+				cfg := config("member1", "https://member1")
+				cfg.RestConfig.BearerToken = fmt.Sprintf("token-%d", i)
+
+				// when
+				added, err := memberClusters.Ensure(cfg)
+
+				// then
+				require.NoError(t, err)
+				assert.True(t, added)
+				memberCluster, found := memberClusters.Get("member1")
+				require.True(t, found)
+				assert.Equal(t, cfg.RestConfig.BearerToken, memberCluster.RestConfig.BearerToken)
+			}
+			// only the latest member cluster is kept
+			assert.Len(t, factory.created, 4)
+			assert.Len(t, factory.stopped, 3)
+			assert.Equal(t, 4, handled)
+			assert.Equal(t, []string{"member1"}, names(memberClusters))
+		})
+	})
+
+	t.Run("readers are not blocked while a member cluster is created", func(t *testing.T) {
+		// given
+		factory := newFakeFactory()
+		memberClusters := NewMemberClusters(factory.newCluster)
+		require.NoError(t, ensure(memberClusters, config("member1", "https://member1")))
+		factory.entered = make(chan struct{})
+		factory.release = make(chan struct{})
+		cfg := config("member1", "https://member1")
+		cfg.RestConfig.BearerToken = "rotated"
+		done := make(chan error)
+		go func() {
+			done <- ensure(memberClusters, cfg)
+		}()
+		<-factory.entered
+
+		// when
+		memberCluster, found := memberClusters.Get("member1")
+
+		// then
+		require.True(t, found)
+		assert.Equal(t, "token", memberCluster.RestConfig.BearerToken)
+		assert.Len(t, memberClusters.All(), 1)
+		close(factory.release)
+		require.NoError(t, <-done)
+		memberCluster, found = memberClusters.Get("member1")
+		require.True(t, found)
+		assert.Equal(t, "rotated", memberCluster.RestConfig.BearerToken)
+	})
+
+	t.Run("failures", func(t *testing.T) {
+		t.Run("unable to create the member cluster", func(t *testing.T) {
+			// given
+			factory := newFakeFactory()
+			factory.err = fmt.Errorf("mock error")
+			memberClusters := NewMemberClusters(factory.newCluster)
+
+			// when
+			added, err := memberClusters.Ensure(config("member1", "https://member1"))
+
+			// then
+			require.EqualError(t, err, "unable to create the member cluster 'member1': mock error")
+			assert.False(t, added)
+			assert.Empty(t, memberClusters.All())
+		})
+
+		t.Run("handler fails", func(t *testing.T) {
+			// given
+			factory := newFakeFactory()
+			memberClusters := NewMemberClusters(factory.newCluster)
+			require.NoError(t, ensure(memberClusters, config("member1", "https://member1")))
+			require.NoError(t, memberClusters.AddHandler(func(memberCluster Cluster) error {
+				if memberCluster.APIEndpoint == "https://member1-new" {
+					return fmt.Errorf("mock error")
+				}
+				return nil
+			}))
+
+			// when
+			added, err := memberClusters.Ensure(config("member1", "https://member1-new"))
+
+			// then
+			require.EqualError(t, err, "unable to set up the member cluster 'member1': mock error")
+			assert.False(t, added)
+			// the new member cluster is stopped, and the previous one is kept until the next attempt
+			assert.Equal(t, []string{"https://member1-new"}, factory.stopped)
+			memberCluster, found := memberClusters.Get("member1")
+			require.True(t, found)
+			assert.Equal(t, "https://member1", memberCluster.APIEndpoint)
+		})
+
+		t.Run("static member clusters", func(t *testing.T) {
+			// given
+			memberClusters := NewStaticMemberClusters(map[string]Cluster{"member1": {Config: config("member1", "https://member1")}})
+
+			// when
+			_, err := memberClusters.Ensure(config("member2", "https://member2"))
+
+			// then
+			require.EqualError(t, err, "unable to create the member cluster 'member2': unable to add the member cluster 'member2' to a static set of member clusters")
+			assert.Equal(t, []string{"member1"}, names(memberClusters))
+		})
+	})
+}
+
+func names(memberClusters *MemberClusters) []string {
+	var names []string
+	for name := range memberClusters.All() {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func ensure(memberClusters *MemberClusters, config *commoncluster.Config) error {
+	_, err := memberClusters.Ensure(config)
+	return err
+}
+
+type fakeFactory struct {
+	err     error
+	created []string
+	stopped []string
+	// entered and release, if set, block the creation of the member clusters until release is closed
+	entered, release chan struct{}
+}
+
+func newFakeFactory() *fakeFactory {
+	return &fakeFactory{}
+}
+
+func (f *fakeFactory) newCluster(config *commoncluster.Config) (Cluster, context.CancelFunc, error) {
+	if f.entered != nil {
+		f.entered <- struct{}{}
+		<-f.release
+	}
+	if f.err != nil {
+		return Cluster{}, nil, f.err
+	}
+	f.created = append(f.created, config.APIEndpoint)
+	return Cluster{Config: config}, func() {
+		f.stopped = append(f.stopped, config.APIEndpoint)
+	}, nil
+}
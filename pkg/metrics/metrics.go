@@ -37,6 +37,30 @@ var (
 
 	// UserSignupVerificationRequiredTotal is incremented only the first time a user signup requires verification, can be multiple times per user if they reactivate multiple times
 	UserSignupVerificationRequiredTotal prometheus.Counter
+
+	// SpaceQuarantineRestoredTotal is incremented each time a Space is restored from the quarantine, with a label for the reason ('binding' or 'admin')
+	SpaceQuarantineRestoredTotal *prometheus.CounterVec
+
+	// SpaceQuarantineDeletedTotal is incremented each time a Space is deleted after its quarantine period expired
+	SpaceQuarantineDeletedTotal prometheus.Counter
+
+	// NotificationDeliveryAttemptsTotal is incremented each time the delivery of a notification is attempted
+	NotificationDeliveryAttemptsTotal prometheus.Counter
+
+	// NotificationDeliveryFailuresTotal is incremented each time the delivery of a notification failed, with a label for the reason ('transient' or 'permanent')
+	NotificationDeliveryFailuresTotal *prometheus.CounterVec
+
+	// NotificationDeadLetteredTotal is incremented each time a notification is given up on after its delivery failed
+	NotificationDeadLetteredTotal prometheus.Counter
+
+	// NotificationDeliveryEventsTotal is incremented each time a delivery event is reported by the email provider, with a label for the event ('delivered', 'deferred', 'bounced' or 'complained')
+	NotificationDeliveryEventsTotal *prometheus.CounterVec
+
+	// NotificationRecipientSuppressedTotal is incremented each time a notification is not sent because its recipient is in the suppression list
+	NotificationRecipientSuppressedTotal prometheus.Counter
+
+	// NotificationThrottledTotal is incremented each time a notification is not sent because it's a duplicate or because of the rate limit, with a label for the reason ('duplicate' or 'rate-limited')
+	NotificationThrottledTotal *prometheus.CounterVec
 )
 
 // gauge with labels
@@ -49,6 +73,14 @@ var (
 	MasterUserRecordGaugeVec *prometheus.GaugeVec
 	// HostOperatorVersionGaugeVec reflects the current version of the host-operator (via the `version` label)
 	HostOperatorVersionGaugeVec *prometheus.GaugeVec
+	// ToolchainStatusAvailabilityGaugeVec reflects the percentage of time the toolchain components were ready, labelled with the component
+	// and the rolling window (eg. `24h`) over which the availability is computed
+	ToolchainStatusAvailabilityGaugeVec *prometheus.GaugeVec
+	// ToolchainStatusHistoryCoveredSecondsGaugeVec reflects the period covered by the readiness transitions kept in the ToolchainStatus history,
+	// labelled with the component. The availability over the longer windows only takes this period into account.
+	ToolchainStatusHistoryCoveredSecondsGaugeVec *prometheus.GaugeVec
+	// FeatureToggleEnabledSpacesGaugeVec reflects the number of Spaces with a feature enabled, labelled with the name of the feature toggle
+	FeatureToggleEnabledSpacesGaugeVec *prometheus.GaugeVec
 )
 
 // histograms
@@ -86,11 +118,22 @@ func initMetrics() {
 	UserSignupDeletedWithInitiatingVerificationTotal = newCounter("user_signups_deleted_with_initiating_verification_total", "Total number of UserSignups deleted after verification time trial and with verification initiated")
 	UserSignupDeletedWithoutInitiatingVerificationTotal = newCounter("user_signups_deleted_without_initiating_verification_total", "Total number of deleted UserSignups after verification time trial but without verification initiated")
 	UserSignupVerificationRequiredTotal = newCounter("user_signups_verification_required_total", "Total number of UserSignups that require verification, does not count verification attempts")
+	SpaceQuarantineRestoredTotal = newCounterVec("spaces_quarantine_restored_total", "Total number of Spaces restored from the quarantine, includes either 'binding' or 'admin' labels for the reason", "reason")
+	SpaceQuarantineDeletedTotal = newCounter("spaces_quarantine_deleted_total", "Total number of Spaces deleted after the quarantine period expired")
+	NotificationDeliveryAttemptsTotal = newCounter("notification_delivery_attempts_total", "Total number of notification delivery attempts")
+	NotificationDeliveryFailuresTotal = newCounterVec("notification_delivery_failures_total", "Total number of failed notification delivery attempts, includes either 'transient' or 'permanent' labels for the reason", "reason")
+	NotificationDeadLetteredTotal = newCounter("notifications_dead_lettered_total", "Total number of notifications given up on after their delivery failed")
+	NotificationDeliveryEventsTotal = newCounterVec("notification_delivery_events_total", "Total number of delivery events reported by the email provider, includes the 'delivered', 'deferred', 'bounced' or 'complained' labels for the event", "event")
+	NotificationRecipientSuppressedTotal = newCounter("notification_recipient_suppressed_total", "Total number of notifications not sent because their recipient is in the suppression list")
+	NotificationThrottledTotal = newCounterVec("notifications_throttled_total", "Total number of notifications not sent (or not sent yet) because of the deduplication or of the rate limit, includes either 'duplicate' or 'rate-limited' labels for the reason", "reason")
 	// Gauges with labels
 	SpaceGaugeVec = newGaugeVec("spaces_current", "Current number of Spaces (per member cluster)", "cluster_name")
 	UserSignupsPerActivationAndDomainGaugeVec = newGaugeVec("users_per_activations_and_domain", "Number of UserSignups per activations and domain", []string{"activations", "domain"}...)
 	MasterUserRecordGaugeVec = newGaugeVec("master_user_records", "Number of MasterUserRecords per email address domain ('internal' vs 'external')", "domain")
 	HostOperatorVersionGaugeVec = newGaugeVec("host_operator_version", "Current version of the host operator", "commit")
+	ToolchainStatusAvailabilityGaugeVec = newGaugeVec("toolchainstatus_availability_percent", "Percentage of time the toolchain components were ready over a rolling window (per component and window)", "component", "window")
+	ToolchainStatusHistoryCoveredSecondsGaugeVec = newGaugeVec("toolchainstatus_history_covered_seconds", "Period covered by the readiness transitions kept in the ToolchainStatus history (per component)", "component")
+	FeatureToggleEnabledSpacesGaugeVec = newGaugeVec("feature_toggle_enabled_spaces", "Number of Spaces with the feature enabled (per feature toggle)", "feature_toggle")
 	// Histograms
 	UserSignupProvisionTimeHistogram = newHistogram("user_signup_provision_time", "UserSignup provision time in seconds")
 	log.Info("custom metrics initialized")
@@ -0,0 +1,109 @@
+package space
+
+import (
+	"hash/fnv"
+	"strings"
+
+	toolchainv1alpha1 "github.com/codeready-toolchain/api/api/v1alpha1"
+	"github.com/codeready-toolchain/host-operator/controllers/toolchainconfig"
+)
+
+// FeatureToggleSubject is what the feature toggles are evaluated for
+type FeatureToggleSubject struct {
+	// UserID is the stable identifier of the user, on which the bucketing is based (the name of the UserSignup, which is kept
+	// when the user is deactivated and reactivated)
+	UserID string
+	// EmailDomain is the domain of the email address of the user
+	EmailDomain string
+	// Tier is the name of the NSTemplateTier of the Space
+	Tier string
+	// Cluster is the name of the member cluster the Space is provisioned to
+	Cluster string
+}
+
+// NewFeatureToggleSubject returns the subject of the feature toggles of the Space of the given user
+func NewFeatureToggleSubject(userSignup *toolchainv1alpha1.UserSignup, tier, cluster string) FeatureToggleSubject {
+	domain := ""
+	if i := strings.LastIndex(userSignup.Spec.IdentityClaims.Email, "@"); i >= 0 {
+		domain = strings.ToLower(userSignup.Spec.IdentityClaims.Email[i+1:])
+	}
+	return FeatureToggleSubject{
+		UserID:      userSignup.Name,
+		EmailDomain: domain,
+		Tier:        tier,
+		Cluster:     cluster,
+	}
+}
+
+// EnabledFeatureToggles returns the names of the given feature toggles which are enabled for the subject. A feature toggle is enabled
+// if the subject matches its targeting, and if the bucket of the user (between 0 and 99) is lower than its weight. The bucket is
+// computed from a hash of the user ID and of the name of the feature toggle, so that the result is the same each time the feature
+// toggles are evaluated, and so that raising the weight of a feature toggle only enables it for more users.
+func EnabledFeatureToggles(subject FeatureToggleSubject, toggles []toolchainconfig.FeatureToggle) []string {
+	var enabled []string
+	for _, t := range toggles {
+		if !matchesTargeting(subject, t.Targeting()) {
+			continue
+		}
+		if FeatureToggleBucket(t.Name(), subject.UserID) < t.Weight() {
+			enabled = append(enabled, t.Name())
+		}
+	}
+	return enabled
+}
+
+// FeatureToggleBucket returns the bucket (between 0 and 99) of the user for the given feature toggle
+func FeatureToggleBucket(toggleName, userID string) uint {
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(toggleName + "/" + userID))
+	return uint(h.Sum32() % 100)
+}
+
+func matchesTargeting(subject FeatureToggleSubject, targeting toolchainconfig.FeatureToggleTargeting) bool {
+	return matchesAny(subject.EmailDomain, targeting.EmailDomains) &&
+		matchesAny(subject.Tier, targeting.Tiers) &&
+		matchesAny(subject.Cluster, targeting.Clusters)
+}
+
+// matchesAny returns true if the list is empty, or if it contains the value (ignoring the case)
+func matchesAny(value string, list []string) bool {
+	if len(list) == 0 {
+		return true
+	}
+	for _, v := range list {
+		if strings.EqualFold(v, value) {
+			return true
+		}
+	}
+	return false
+}
+
+// ApplyFeatureToggles sets the feature annotation of the Space to the feature toggles enabled for the subject. The features of the
+// annotation which are not among the given feature toggles (eg. enabled manually, or whose toggle was removed) are kept. Returns true
+// if the annotation changed.
+func ApplyFeatureToggles(space *toolchainv1alpha1.Space, subject FeatureToggleSubject, toggles []toolchainconfig.FeatureToggle) bool {
+	features := EnabledFeatureToggles(subject, toggles)
+	known := make(map[string]bool, len(toggles))
+	for _, t := range toggles {
+		known[t.Name()] = true
+	}
+	current := space.GetAnnotations()[toolchainv1alpha1.FeatureToggleNameAnnotationKey]
+	for _, feature := range strings.Split(current, ",") {
+		if feature != "" && !known[feature] {
+			features = append(features, feature)
+		}
+	}
+	value := strings.Join(features, ",")
+	if value == current {
+		return false
+	}
+	if value == "" {
+		delete(space.Annotations, toolchainv1alpha1.FeatureToggleNameAnnotationKey)
+		return true
+	}
+	if space.Annotations == nil {
+		space.Annotations = map[string]string{}
+	}
+	space.Annotations[toolchainv1alpha1.FeatureToggleNameAnnotationKey] = value
+	return true
+}
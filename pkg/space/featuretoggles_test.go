@@ -0,0 +1,153 @@
+package space
+
+import (
+	"fmt"
+	"testing"
+
+	toolchainv1alpha1 "github.com/codeready-toolchain/api/api/v1alpha1"
+	"github.com/codeready-toolchain/host-operator/controllers/toolchainconfig"
+	"github.com/codeready-toolchain/toolchain-common/pkg/test"
+	spacetest "github.com/codeready-toolchain/toolchain-common/pkg/test/space"
+	commonsignup "github.com/codeready-toolchain/toolchain-common/pkg/test/usersignup"
+
+	"github.com/stretchr/testify/assert"
+	"k8s.io/utils/ptr"
+)
+
+func TestNewFeatureToggleSubject(t *testing.T) {
+	// given
+	userSignup := commonsignup.NewUserSignup(commonsignup.WithName("johny"), commonsignup.WithEmail("johny@ACME.com"))
+
+	// when
+	subject := NewFeatureToggleSubject(userSignup, "base", "member-1")
+
+	// then
+	assert.Equal(t, FeatureToggleSubject{UserID: "johny", EmailDomain: "acme.com", Tier: "base", Cluster: "member-1"}, subject)
+}
+
+func TestEnabledFeatureToggles(t *testing.T) {
+	toggle := func(name string, weight uint) toolchainconfig.FeatureToggle {
+		return toolchainconfig.NewFeatureToggle(toolchainv1alpha1.FeatureToggle{Name: name, Weight: ptr.To(weight)})
+	}
+
+	t.Run("raising the weight only enables the feature for more users", func(t *testing.T) {
+		enabledAt := map[string]uint{}
+		for weight := uint(0); weight <= 100; weight += 10 {
+			for i := 0; i < 100; i++ {
+				userID := fmt.Sprintf("user-%d", i)
+				enabled := EnabledFeatureToggles(FeatureToggleSubject{UserID: userID}, []toolchainconfig.FeatureToggle{toggle("feature", weight)})
+				previous, wasEnabled := enabledAt[userID]
+				if wasEnabled {
+					assert.Equal(t, []string{"feature"}, enabled, "feature enabled with the weight %d but not with %d for %s", previous, weight, userID)
+				} else if len(enabled) > 0 {
+					enabledAt[userID] = weight
+				}
+			}
+		}
+		assert.Len(t, enabledAt, 100)
+	})
+
+	t.Run("independent buckets per feature toggle", func(t *testing.T) {
+		var different bool
+		for i := 0; i < 100 && !different; i++ {
+			userID := fmt.Sprintf("user-%d", i)
+			different = FeatureToggleBucket("feature-1", userID) != FeatureToggleBucket("feature-2", userID)
+		}
+		assert.True(t, different)
+	})
+
+	t.Run("targeting", func(t *testing.T) {
+		// given
+		targeted := toolchainconfig.NewTargetedFeatureToggle(toolchainv1alpha1.FeatureToggle{Name: "targeted"}, toolchainconfig.FeatureToggleTargeting{
+			EmailDomains: []string{"acme.com", "example.com"},
+			Tiers:        []string{"advanced"},
+		})
+		toggles := []toolchainconfig.FeatureToggle{toggle("everyone", 100), targeted}
+
+		for name, tc := range map[string]struct {
+			subject  FeatureToggleSubject
+			expected []string
+		}{
+			"all matching": {
+				subject:  FeatureToggleSubject{UserID: "johny", EmailDomain: "example.com", Tier: "Advanced", Cluster: "member-1"},
+				expected: []string{"everyone", "targeted"},
+			},
+			"other domain": {
+				subject:  FeatureToggleSubject{UserID: "johny", EmailDomain: "redhat.com", Tier: "advanced", Cluster: "member-1"},
+				expected: []string{"everyone"},
+			},
+			"other tier": {
+				subject:  FeatureToggleSubject{UserID: "johny", EmailDomain: "acme.com", Tier: "base", Cluster: "member-1"},
+				expected: []string{"everyone"},
+			},
+		} {
+			t.Run(name, func(t *testing.T) {
+				// when
+				enabled := EnabledFeatureToggles(tc.subject, toggles)
+
+				// then
+				assert.Equal(t, tc.expected, enabled)
+			})
+		}
+	})
+}
+
+func TestApplyFeatureToggles(t *testing.T) {
+	// given
+	toggles := []toolchainconfig.FeatureToggle{
+		toolchainconfig.NewFeatureToggle(toolchainv1alpha1.FeatureToggle{Name: "feature-1", Weight: ptr.To[uint](100)}),
+		toolchainconfig.NewFeatureToggle(toolchainv1alpha1.FeatureToggle{Name: "feature-2", Weight: ptr.To[uint](0)}),
+	}
+	subject := FeatureToggleSubject{UserID: "johny"}
+
+	t.Run("added", func(t *testing.T) {
+		// given
+		space := spacetest.NewSpace(test.HostOperatorNs, "johny")
+
+		// when
+		changed := ApplyFeatureToggles(space, subject, toggles)
+
+		// then
+		assert.True(t, changed)
+		assert.Equal(t, "feature-1", space.Annotations[toolchainv1alpha1.FeatureToggleNameAnnotationKey])
+	})
+
+	t.Run("disabled removed and unknown kept", func(t *testing.T) {
+		// given
+		space := spacetest.NewSpace(test.HostOperatorNs, "johny",
+			spacetest.WithAnnotation(toolchainv1alpha1.FeatureToggleNameAnnotationKey, "feature-2,manual"))
+
+		// when
+		changed := ApplyFeatureToggles(space, subject, toggles)
+
+		// then
+		assert.True(t, changed)
+		assert.Equal(t, "feature-1,manual", space.Annotations[toolchainv1alpha1.FeatureToggleNameAnnotationKey])
+	})
+
+	t.Run("all removed", func(t *testing.T) {
+		// given
+		space := spacetest.NewSpace(test.HostOperatorNs, "johny",
+			spacetest.WithAnnotation(toolchainv1alpha1.FeatureToggleNameAnnotationKey, "feature-2"))
+
+		// when
+		changed := ApplyFeatureToggles(space, subject, toggles[1:])
+
+		// then
+		assert.True(t, changed)
+		assert.NotContains(t, space.Annotations, toolchainv1alpha1.FeatureToggleNameAnnotationKey)
+	})
+
+	t.Run("unchanged", func(t *testing.T) {
+		// given
+		space := spacetest.NewSpace(test.HostOperatorNs, "johny",
+			spacetest.WithAnnotation(toolchainv1alpha1.FeatureToggleNameAnnotationKey, "feature-1"))
+
+		// when
+		changed := ApplyFeatureToggles(space, subject, toggles)
+
+		// then
+		assert.False(t, changed)
+		assert.Equal(t, "feature-1", space.Annotations[toolchainv1alpha1.FeatureToggleNameAnnotationKey])
+	})
+}
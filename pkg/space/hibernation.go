@@ -0,0 +1,17 @@
+package space
+
+import (
+	toolchainv1alpha1 "github.com/codeready-toolchain/api/api/v1alpha1"
+)
+
+// HibernatedAnnotationKey is set on the home Space of a deactivated user (when the hibernation is enabled) instead of letting it
+// be deleted. The value is the time (RFC3339) at which the Space was hibernated. The annotation is propagated to the NSTemplateSet
+// so that the member cluster switches it to the hibernated mode: the workloads are scaled to zero and the quotas are minimized,
+// but the data is kept until the user comes back.
+const HibernatedAnnotationKey = toolchainv1alpha1.LabelKeyPrefix + "hibernated"
+
+// IsHibernated returns true if the given Space is hibernated
+func IsHibernated(space *toolchainv1alpha1.Space) bool {
+	_, found := space.Annotations[HibernatedAnnotationKey]
+	return found
+}
@@ -0,0 +1,43 @@
+package space
+
+import (
+	"time"
+
+	toolchainv1alpha1 "github.com/codeready-toolchain/api/api/v1alpha1"
+)
+
+const (
+	// PendingDeletionAnnotationKey is set on a Space which lost all its SpaceBindings. The value is the time (RFC3339) at which the
+	// Space entered the quarantine. The annotation is propagated to the NSTemplateSet so that the member cluster locks the namespaces
+	// until the Space is either restored or deleted.
+	PendingDeletionAnnotationKey = toolchainv1alpha1.LabelKeyPrefix + "pending-deletion"
+
+	// RetainAnnotationKey can be set by an admin (with the value "true") on a Space to restore it from the quarantine
+	// and to keep it even if it has no SpaceBinding
+	RetainAnnotationKey = toolchainv1alpha1.LabelKeyPrefix + "retain"
+)
+
+// IsPendingDeletion returns true if the given Space is in the quarantine
+func IsPendingDeletion(space *toolchainv1alpha1.Space) bool {
+	_, found := space.Annotations[PendingDeletionAnnotationKey]
+	return found
+}
+
+// PendingDeletionSince returns the time at which the Space entered the quarantine.
+// Returns false if the Space is not in the quarantine or if the annotation value is invalid.
+func PendingDeletionSince(space *toolchainv1alpha1.Space) (time.Time, bool) {
+	v, found := space.Annotations[PendingDeletionAnnotationKey]
+	if !found {
+		return time.Time{}, false
+	}
+	since, err := time.Parse(time.RFC3339, v)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return since, true
+}
+
+// IsRetained returns true if an admin asked to keep the Space, even without any SpaceBinding
+func IsRetained(space *toolchainv1alpha1.Space) bool {
+	return space.Annotations[RetainAnnotationKey] == "true"
+}
@@ -2,8 +2,6 @@ package space
 
 import (
 	"fmt"
-	"math/rand"
-	"strings"
 
 	toolchainv1alpha1 "github.com/codeready-toolchain/api/api/v1alpha1"
 	"github.com/codeready-toolchain/host-operator/controllers/toolchainconfig"
@@ -33,38 +31,14 @@ func NewSpace(userSignup *toolchainv1alpha1.UserSignup, targetClusterName string
 	return space
 }
 
-// NewSpaceWithFeatureToggles is the same as NewSpace() but also does a feature toggle lottery drawing
-// and adds the corresponding feature annotations for features which "won" and should be enabled for the space.
+// NewSpaceWithFeatureToggles is the same as NewSpace() but also evaluates the feature toggles for the user,
+// and adds the corresponding feature annotation for the features which should be enabled for the space.
 func NewSpaceWithFeatureToggles(userSignup *toolchainv1alpha1.UserSignup, targetClusterName string, compliantUserName, tier string, toggles []toolchainconfig.FeatureToggle) *toolchainv1alpha1.Space {
 	s := NewSpace(userSignup, targetClusterName, compliantUserName, tier)
-	addFeatureToggles(s, toggles)
+	ApplyFeatureToggles(s, NewFeatureToggleSubject(userSignup, tier, targetClusterName), toggles)
 	return s
 }
 
-// addFeatureToggles does "lottery" drawing for all given feature toggles according to their weights.
-// And it adds the corresponding feature annotation to the space for features that won and should be enabled for the space.
-func addFeatureToggles(space *toolchainv1alpha1.Space, toggles []toolchainconfig.FeatureToggle) {
-	var winners []string
-	for _, t := range toggles {
-		//the value of weight is not expected to go beyond 100, it won't overflow, hence its okay to ignore the overflow linter error
-		weight := int(t.Weight()) // nolint:gosec
-		// We generate a random number between 0 and 100. If the number is equal to or lower than the weight
-		// then the feature wins.
-		// We don't use recommended crypto/rand here because we don't need crypto grade random generator
-		// and math/rand with pseudo random numbers, which is much cheaper, is sufficient, so we are disabling the linter
-		if weight == 100 || (weight > 0 && rand.Intn(100) <= weight) { // nolint:gosec
-			// Winner!
-			winners = append(winners, t.Name())
-		}
-	}
-	if len(winners) > 0 {
-		if space.Annotations == nil {
-			space.Annotations = make(map[string]string)
-		}
-		space.Annotations[toolchainv1alpha1.FeatureToggleNameAnnotationKey] = strings.Join(winners, ",")
-	}
-}
-
 // NewSubSpace creates a space CR for a SpaceRequest object.
 func NewSubSpace(spaceRequest *toolchainv1alpha1.SpaceRequest, parentSpace *toolchainv1alpha1.Space) *toolchainv1alpha1.Space {
 	labels := map[string]string{
@@ -35,7 +35,6 @@ func TestNewSpace(t *testing.T) {
 
 func TestNewSpaceWithFeatureToggles(t *testing.T) {
 	// given
-	userSignup := commonsignup.NewUserSignup()
 	weight100 := uint(100)
 	weight0 := uint(0)
 	weight50 := uint(50)
@@ -55,11 +54,12 @@ func TestNewSpaceWithFeatureToggles(t *testing.T) {
 	}
 
 	// when
-	// create space 100 times and verify that the feature with weight 100 is always added
+	// create spaces for 100 users and verify that the feature with weight 100 is always added
 	// the feature with weight 0 never added
 	// and the feature with weight 50 is added at least once and not added at least once too
 	var myCoolFeatureCount, myNotSoCoolFeatureCount, mySoSoFeatureCount int
 	for i := 0; i < 100; i++ {
+		userSignup := commonsignup.NewUserSignup(commonsignup.WithName(fmt.Sprintf("johny-%d", i)))
 		space := NewSpaceWithFeatureToggles(userSignup, test.MemberClusterName, "johny", "advanced", featureToggles)
 
 		// then
@@ -82,6 +82,9 @@ func TestNewSpaceWithFeatureToggles(t *testing.T) {
 				assert.Fail(t, "unknown feature", feature)
 			}
 		}
+		// the same features are enabled each time for the same user
+		assert.Equal(t, features, NewSpaceWithFeatureToggles(userSignup, test.MemberClusterName, "johny", "advanced", featureToggles).
+			GetAnnotations()[toolchainv1alpha1.FeatureToggleNameAnnotationKey])
 	}
 
 	// then
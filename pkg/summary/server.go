@@ -0,0 +1,240 @@
+package summary
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/tls"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	toolchainv1alpha1 "github.com/codeready-toolchain/api/api/v1alpha1"
+
+	authnv1 "k8s.io/api/authentication/v1"
+	authzv1 "k8s.io/api/authorization/v1"
+	"sigs.k8s.io/controller-runtime/pkg/certwatcher"
+	runtimeclient "sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+const (
+	// Path is the path of the endpoint which returns the summary
+	Path = "/api/v1/summary"
+
+	// CertName and KeyName are the names of the files of the serving certificate and of its key in the certificate directory of the server
+	CertName = "tls.crt"
+	KeyName  = "tls.key"
+
+	// defaultReviewCacheTTL is for how long the result of the review of a token is cached by default
+	defaultReviewCacheTTL = 10 * time.Second
+)
+
+//+kubebuilder:rbac:groups=authentication.k8s.io,resources=tokenreviews,verbs=create
+//+kubebuilder:rbac:groups=authorization.k8s.io,resources=subjectaccessreviews,verbs=create
+
+// Server serves the summary of the toolchain as JSON, over TLS. The requests must have the bearer token of a user (or service account)
+// which is allowed to get the ToolchainStatus.
+type Server struct {
+	Client      runtimeclient.Client
+	Namespace   string
+	BindAddress string
+	// CertDir is the directory which contains the serving certificate and its key (see CertName and KeyName).
+	// As for the webhook server of the manager, the certificate is reloaded when the files change.
+	CertDir string
+	// ReviewCacheTTL is for how long the result of the review of a token is cached, so that the TokenReviews and SubjectAccessReviews
+	// are not created for every request (default: 10s)
+	ReviewCacheTTL time.Duration
+
+	reviewsMu sync.Mutex
+	reviews   map[string]cachedReview
+	now       func() time.Time
+}
+
+// cachedReview is the result of the review of a token, cached until it expires
+type cachedReview struct {
+	allowed   bool
+	expiresAt time.Time
+}
+
+// Handler returns the handler of the summary endpoint
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc(Path, s.handle)
+	return mux
+}
+
+// Start runs the server until the given context is done
+func (s *Server) Start(ctx context.Context) error {
+	listener, err := net.Listen("tcp", s.BindAddress)
+	if err != nil {
+		return err
+	}
+	return s.serve(ctx, listener)
+}
+
+func (s *Server) serve(ctx context.Context, listener net.Listener) error {
+	logger := log.FromContext(ctx).WithName("summary-server")
+	certWatcher, err := certwatcher.New(filepath.Join(s.CertDir, CertName), filepath.Join(s.CertDir, KeyName))
+	if err != nil {
+		return err
+	}
+	go func() {
+		if err := certWatcher.Start(ctx); err != nil {
+			logger.Error(err, "unable to watch the serving certificate of the summary server")
+		}
+	}()
+	srv := &http.Server{
+		Handler:           s.Handler(),
+		ReadHeaderTimeout: 10 * time.Second,
+		TLSConfig: &tls.Config{
+			GetCertificate: certWatcher.GetCertificate,
+			MinVersion:     tls.VersionTLS12,
+		},
+	}
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		if err := srv.Shutdown(shutdownCtx); err != nil {
+			logger.Error(err, "unable to shut down the summary server")
+		}
+	}()
+	logger.Info("starting the summary server", "address", listener.Addr().String())
+	if err := srv.ServeTLS(listener, "", ""); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+// NeedLeaderElection returns false, so that the summary is served by all the replicas
+func (s *Server) NeedLeaderElection() bool {
+	return false
+}
+
+func (s *Server) handle(w http.ResponseWriter, req *http.Request) {
+	logger := log.FromContext(req.Context()).WithName("summary-server")
+	if req.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	allowed, err := s.authorize(req)
+	if err != nil {
+		logger.Error(err, "unable to authorize the summary request")
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	if !allowed {
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+
+	summary, err := Build(req.Context(), s.Client, s.Namespace)
+	if err != nil {
+		logger.Error(err, "unable to build the summary")
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(summary); err != nil {
+		logger.Error(err, "unable to write the summary")
+	}
+}
+
+// authorize returns true if the bearer token of the request is valid, and if its user is allowed to get the ToolchainStatus.
+// The result is cached for the ReviewCacheTTL.
+func (s *Server) authorize(req *http.Request) (bool, error) {
+	token, found := strings.CutPrefix(req.Header.Get("Authorization"), "Bearer ")
+	if !found || token == "" {
+		return false, nil
+	}
+	sum := sha256.Sum256([]byte(token))
+	key := hex.EncodeToString(sum[:])
+	if allowed, found := s.cachedReview(key); found {
+		return allowed, nil
+	}
+	allowed, err := s.review(req.Context(), token)
+	if err != nil {
+		return false, err
+	}
+	s.cacheReview(key, allowed)
+	return allowed, nil
+}
+
+// review creates the TokenReview of the given token, and the SubjectAccessReview of its user
+func (s *Server) review(ctx context.Context, token string) (bool, error) {
+	review := &authnv1.TokenReview{
+		Spec: authnv1.TokenReviewSpec{Token: token},
+	}
+	if err := s.Client.Create(ctx, review); err != nil {
+		return false, err
+	}
+	if !review.Status.Authenticated {
+		return false, nil
+	}
+
+	extra := map[string]authzv1.ExtraValue{}
+	for k, v := range review.Status.User.Extra {
+		extra[k] = authzv1.ExtraValue(v)
+	}
+	access := &authzv1.SubjectAccessReview{
+		Spec: authzv1.SubjectAccessReviewSpec{
+			User:   review.Status.User.Username,
+			UID:    review.Status.User.UID,
+			Groups: review.Status.User.Groups,
+			Extra:  extra,
+			ResourceAttributes: &authzv1.ResourceAttributes{
+				Namespace: s.Namespace,
+				Verb:      "get",
+				Group:     toolchainv1alpha1.GroupVersion.Group,
+				Resource:  "toolchainstatuses",
+			},
+		},
+	}
+	if err := s.Client.Create(ctx, access); err != nil {
+		return false, err
+	}
+	return access.Status.Allowed, nil
+}
+
+func (s *Server) cachedReview(key string) (bool, bool) {
+	s.reviewsMu.Lock()
+	defer s.reviewsMu.Unlock()
+	review, found := s.reviews[key]
+	if !found || !s.currentTime().Before(review.expiresAt) {
+		return false, false
+	}
+	return review.allowed, true
+}
+
+// cacheReview caches the result of the review of the token with the given key, and drops the expired results
+func (s *Server) cacheReview(key string, allowed bool) {
+	s.reviewsMu.Lock()
+	defer s.reviewsMu.Unlock()
+	now := s.currentTime()
+	if s.reviews == nil {
+		s.reviews = map[string]cachedReview{}
+	}
+	for k, review := range s.reviews {
+		if !now.Before(review.expiresAt) {
+			delete(s.reviews, k)
+		}
+	}
+	ttl := s.ReviewCacheTTL
+	if ttl <= 0 {
+		ttl = defaultReviewCacheTTL
+	}
+	s.reviews[key] = cachedReview{allowed: allowed, expiresAt: now.Add(ttl)}
+}
+
+func (s *Server) currentTime() time.Time {
+	if s.now != nil {
+		return s.now()
+	}
+	return time.Now()
+}
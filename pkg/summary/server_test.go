@@ -0,0 +1,317 @@
+package summary
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	toolchainv1alpha1 "github.com/codeready-toolchain/api/api/v1alpha1"
+	"github.com/codeready-toolchain/host-operator/pkg/apis"
+	. "github.com/codeready-toolchain/host-operator/test"
+	"github.com/codeready-toolchain/toolchain-common/pkg/test"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	authnv1 "k8s.io/api/authentication/v1"
+	authzv1 "k8s.io/api/authorization/v1"
+	"k8s.io/client-go/kubernetes/scheme"
+	runtimeclient "sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func TestServer(t *testing.T) {
+	// given
+	require.NoError(t, apis.AddToScheme(scheme.Scheme))
+
+	t.Run("allowed", func(t *testing.T) {
+		// given
+		server, cl := newServer(t, true, true)
+
+		// when
+		resp := get(server, "Bearer t0k3n")
+
+		// then
+		require.Equal(t, http.StatusOK, resp.Code)
+		assert.Equal(t, "application/json", resp.Header().Get("Content-Type"))
+		summary := &Summary{}
+		require.NoError(t, json.Unmarshal(resp.Body.Bytes(), summary))
+		assert.Equal(t, SchemaVersion, summary.SchemaVersion)
+		assert.Equal(t, "jane", cl.access.Spec.User)
+		assert.Equal(t, &authzv1.ResourceAttributes{
+			Namespace: test.HostOperatorNs,
+			Verb:      "get",
+			Group:     toolchainv1alpha1.GroupVersion.Group,
+			Resource:  "toolchainstatuses",
+		}, cl.access.Spec.ResourceAttributes)
+	})
+
+	t.Run("not allowed", func(t *testing.T) {
+		// given
+		server, _ := newServer(t, true, false)
+
+		// when
+		resp := get(server, "Bearer t0k3n")
+
+		// then
+		assert.Equal(t, http.StatusForbidden, resp.Code)
+	})
+
+	t.Run("not authenticated", func(t *testing.T) {
+		// given
+		server, cl := newServer(t, false, true)
+
+		// when
+		resp := get(server, "Bearer t0k3n")
+
+		// then
+		assert.Equal(t, http.StatusForbidden, resp.Code)
+		assert.Nil(t, cl.access)
+	})
+
+	t.Run("no token", func(t *testing.T) {
+		// given
+		server, _ := newServer(t, true, true)
+
+		// when
+		resp := get(server, "")
+
+		// then
+		assert.Equal(t, http.StatusForbidden, resp.Code)
+	})
+
+	t.Run("not a GET request", func(t *testing.T) {
+		// given
+		server, _ := newServer(t, true, true)
+		req := httptest.NewRequest(http.MethodPost, Path, nil)
+		req.Header.Set("Authorization", "Bearer t0k3n")
+		resp := httptest.NewRecorder()
+
+		// when
+		server.Handler().ServeHTTP(resp, req)
+
+		// then
+		assert.Equal(t, http.StatusMethodNotAllowed, resp.Code)
+	})
+
+	t.Run("review fails", func(t *testing.T) {
+		// given
+		server, cl := newServer(t, true, true)
+		cl.MockCreate = func(_ context.Context, _ runtimeclient.Object, _ ...runtimeclient.CreateOption) error {
+			return fmt.Errorf("mock error")
+		}
+
+		// when
+		resp := get(server, "Bearer t0k3n")
+
+		// then
+		assert.Equal(t, http.StatusInternalServerError, resp.Code)
+	})
+
+	t.Run("review is cached", func(t *testing.T) {
+		// given
+		server, cl := newServer(t, true, true)
+		now := time.Now()
+		server.now = func() time.Time { return now }
+		server.ReviewCacheTTL = time.Minute
+
+		// when
+		first := get(server, "Bearer t0k3n")
+		second := get(server, "Bearer t0k3n")
+
+		// then
+		assert.Equal(t, http.StatusOK, first.Code)
+		assert.Equal(t, http.StatusOK, second.Code)
+		assert.Equal(t, 1, cl.tokenReviews)
+
+		t.Run("other token is reviewed", func(t *testing.T) {
+			// when
+			resp := get(server, "Bearer 0th3r")
+
+			// then
+			assert.Equal(t, http.StatusForbidden, resp.Code)
+			assert.Equal(t, 2, cl.tokenReviews)
+		})
+
+		t.Run("reviewed again once expired", func(t *testing.T) {
+			// given
+			now = now.Add(time.Minute)
+
+			// when
+			resp := get(server, "Bearer t0k3n")
+
+			// then
+			assert.Equal(t, http.StatusOK, resp.Code)
+			assert.Equal(t, 3, cl.tokenReviews)
+		})
+	})
+
+	t.Run("failed review is not cached", func(t *testing.T) {
+		// given
+		server, cl := newServer(t, true, true)
+		create := cl.MockCreate
+		cl.MockCreate = func(_ context.Context, _ runtimeclient.Object, _ ...runtimeclient.CreateOption) error {
+			return fmt.Errorf("mock error")
+		}
+		require.Equal(t, http.StatusInternalServerError, get(server, "Bearer t0k3n").Code)
+		cl.MockCreate = create
+
+		// when
+		resp := get(server, "Bearer t0k3n")
+
+		// then
+		assert.Equal(t, http.StatusOK, resp.Code)
+	})
+
+	t.Run("no ToolchainStatus", func(t *testing.T) {
+		// given
+		server, cl := newServer(t, true, true)
+		require.NoError(t, cl.Delete(context.TODO(), NewToolchainStatus()))
+
+		// when
+		resp := get(server, "Bearer t0k3n")
+
+		// then
+		assert.Equal(t, http.StatusInternalServerError, resp.Code)
+	})
+}
+
+// reviewClient is a fake client which completes the TokenReviews and SubjectAccessReviews with the given results
+type reviewClient struct {
+	*test.FakeClient
+	access       *authzv1.SubjectAccessReview
+	tokenReviews int
+}
+
+func TestServeTLS(t *testing.T) {
+	// given
+	require.NoError(t, apis.AddToScheme(scheme.Scheme))
+	server, _ := newServer(t, true, true)
+	server.CertDir = t.TempDir()
+	certPEM := writeSelfSignedCert(t, server.CertDir)
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	ctx, cancel := context.WithCancel(context.TODO())
+	defer cancel()
+	go func() {
+		_ = server.serve(ctx, listener)
+	}()
+	pool := x509.NewCertPool()
+	require.True(t, pool.AppendCertsFromPEM(certPEM))
+	client := &http.Client{
+		Timeout:   5 * time.Second,
+		Transport: &http.Transport{TLSClientConfig: &tls.Config{RootCAs: pool, MinVersion: tls.VersionTLS12}},
+	}
+
+	t.Run("served over TLS", func(t *testing.T) {
+		// given
+		req, err := http.NewRequest(http.MethodGet, "https://"+listener.Addr().String()+Path, nil)
+		require.NoError(t, err)
+		req.Header.Set("Authorization", "Bearer t0k3n")
+
+		// when
+		resp, err := client.Do(req)
+
+		// then
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+	})
+
+	t.Run("not served over plain HTTP", func(t *testing.T) {
+		// when
+		resp, err := http.Get("http://" + listener.Addr().String() + Path) // nolint:noctx
+		require.NoError(t, err)
+		defer resp.Body.Close()
+
+		// then
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	})
+
+	t.Run("missing certificate", func(t *testing.T) {
+		// given
+		server, _ := newServer(t, true, true)
+		server.CertDir = t.TempDir()
+		listener, err := net.Listen("tcp", "127.0.0.1:0")
+		require.NoError(t, err)
+		defer listener.Close()
+
+		// when
+		err = server.serve(context.TODO(), listener)
+
+		// then
+		require.Error(t, err)
+	})
+}
+
+// writeSelfSignedCert writes a self-signed certificate for 127.0.0.1 and its key in the given directory, and returns the certificate
+func writeSelfSignedCert(t *testing.T, dir string) []byte {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "summary"},
+		IPAddresses:           []net.IP{net.ParseIP("127.0.0.1")},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	require.NoError(t, os.WriteFile(filepath.Join(dir, CertName), certPEM, 0600))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, KeyName), pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0600))
+	return certPEM
+}
+
+func newServer(t *testing.T, authenticated, allowed bool) (*Server, *reviewClient) {
+	cl := &reviewClient{FakeClient: test.NewFakeClient(t, NewToolchainStatus())}
+	cl.MockCreate = func(ctx context.Context, obj runtimeclient.Object, opts ...runtimeclient.CreateOption) error {
+		switch obj := obj.(type) {
+		case *authnv1.TokenReview:
+			cl.tokenReviews++
+			if obj.Spec.Token == "t0k3n" {
+				obj.Status.Authenticated = authenticated
+				obj.Status.User = authnv1.UserInfo{Username: "jane"}
+			}
+			return nil
+		case *authzv1.SubjectAccessReview:
+			obj.Status.Allowed = allowed
+			cl.access = obj
+			return nil
+		}
+		return test.Create(ctx, cl.FakeClient, obj, opts...)
+	}
+	return &Server{
+		Client:    cl,
+		Namespace: test.HostOperatorNs,
+	}, cl
+}
+
+func get(server *Server, authorization string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, Path, nil)
+	if authorization != "" {
+		req.Header.Set("Authorization", authorization)
+	}
+	resp := httptest.NewRecorder()
+	server.Handler().ServeHTTP(resp, req)
+	return resp
+}
@@ -0,0 +1,225 @@
+package summary
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"sort"
+	"strings"
+	"time"
+
+	toolchainv1alpha1 "github.com/codeready-toolchain/api/api/v1alpha1"
+	"github.com/codeready-toolchain/host-operator/controllers/toolchainconfig"
+	"github.com/codeready-toolchain/host-operator/controllers/toolchainstatus"
+	"github.com/codeready-toolchain/host-operator/pkg/counter"
+	"github.com/codeready-toolchain/toolchain-common/pkg/condition"
+
+	errs "github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	runtimeclient "sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// SchemaVersion is the version of the schema of the Summary. Fields may be added within a version, but never renamed or removed.
+	SchemaVersion = "v1"
+
+	// RecentWindow is the window over which the recent approvals and deactivations are counted
+	RecentWindow = 24 * time.Hour
+)
+
+// Summary is a curated view of the state of the toolchain, for the dashboards and scripts which can't (or don't want to) parse
+// the ToolchainStatus resource
+type Summary struct {
+	// SchemaVersion is the version of the schema of the summary (see SchemaVersion)
+	SchemaVersion string `json:"schemaVersion"`
+	// GeneratedAt is when the summary was generated
+	GeneratedAt time.Time `json:"generatedAt"`
+	// Ready is the overall readiness of the toolchain, as reported by the ToolchainStatus
+	Ready bool `json:"ready"`
+	// Components is the readiness of the toolchain components
+	Components []Component `json:"components"`
+	// Clusters contains the space counts and capacity of the member clusters
+	Clusters []Cluster `json:"clusters"`
+	// PendingApprovals is the number of UserSignups pending approval
+	PendingApprovals int `json:"pendingApprovals"`
+	// Recent contains the number of approvals and deactivations during the RecentWindow
+	Recent Recent `json:"recent"`
+	// ConfigHash is the hash of the ToolchainConfig (spec and annotations), empty if there is no ToolchainConfig
+	ConfigHash string `json:"configHash"`
+}
+
+// Component is the readiness of a toolchain component
+type Component struct {
+	Name    string `json:"name"`
+	Ready   bool   `json:"ready"`
+	Reason  string `json:"reason,omitempty"`
+	Message string `json:"message,omitempty"`
+}
+
+// Cluster contains the space count and capacity of a member cluster
+type Cluster struct {
+	Name  string `json:"name"`
+	Ready bool   `json:"ready"`
+	// SpaceCount is the number of Spaces provisioned to the cluster, from the counter cache (or the ToolchainStatus until the cache is initialized)
+	SpaceCount int `json:"spaceCount"`
+	// MaxNumberOfSpaces is the maximum number of Spaces of the cluster, as configured in its SpaceProvisionerConfig (0 meaning no limit)
+	MaxNumberOfSpaces int `json:"maxNumberOfSpaces"`
+	// MaxMemoryUtilizationPercent is the memory usage threshold of the cluster, as configured in its SpaceProvisionerConfig
+	MaxMemoryUtilizationPercent int `json:"maxMemoryUtilizationPercent"`
+	// MemoryUsagePerNodeRole is the memory usage (in percents) of the nodes of the cluster, per role
+	MemoryUsagePerNodeRole map[string]int `json:"memoryUsagePerNodeRole,omitempty"`
+}
+
+// Recent contains the number of approvals and deactivations during the window
+type Recent struct {
+	Window        string `json:"window"`
+	Approvals     int    `json:"approvals"`
+	Deactivations int    `json:"deactivations"`
+}
+
+// Build returns the summary of the toolchain installed in the given namespace
+func Build(ctx context.Context, cl runtimeclient.Client, namespace string) (*Summary, error) {
+	summary := &Summary{
+		SchemaVersion: SchemaVersion,
+		GeneratedAt:   time.Now().UTC(),
+		Components:    []Component{},
+		Clusters:      []Cluster{},
+	}
+
+	toolchainStatus := &toolchainv1alpha1.ToolchainStatus{}
+	if err := cl.Get(ctx, types.NamespacedName{Namespace: namespace, Name: toolchainconfig.ToolchainStatusName}, toolchainStatus); err != nil {
+		return nil, errs.Wrap(err, "unable to get the ToolchainStatus")
+	}
+	summary.Ready = condition.IsTrue(toolchainStatus.Status.Conditions, toolchainv1alpha1.ConditionReady)
+	summary.Components = components(toolchainStatus)
+
+	clusters, err := clusters(ctx, cl, namespace, toolchainStatus)
+	if err != nil {
+		return nil, err
+	}
+	summary.Clusters = clusters
+
+	userSignups := &toolchainv1alpha1.UserSignupList{}
+	if err := cl.List(ctx, userSignups, runtimeclient.InNamespace(namespace)); err != nil {
+		return nil, errs.Wrap(err, "unable to list the UserSignups")
+	}
+	summary.PendingApprovals, summary.Recent = userSignupActivity(userSignups.Items, summary.GeneratedAt)
+
+	if summary.ConfigHash, err = configHash(ctx, cl, namespace); err != nil {
+		return nil, err
+	}
+	return summary, nil
+}
+
+// components returns the readiness of the components reported in the ToolchainStatus
+func components(toolchainStatus *toolchainv1alpha1.ToolchainStatus) []Component {
+	result := []Component{}
+	add := func(name string, conditions []toolchainv1alpha1.Condition) {
+		c, found := condition.FindConditionByType(conditions, toolchainv1alpha1.ConditionReady)
+		if !found {
+			return
+		}
+		result = append(result, Component{Name: name, Ready: c.Status == corev1.ConditionTrue, Reason: c.Reason, Message: c.Message})
+	}
+	if toolchainStatus.Status.HostOperator != nil {
+		add("hostOperator", toolchainStatus.Status.HostOperator.Conditions)
+	}
+	if toolchainStatus.Status.RegistrationService != nil {
+		add("registrationService.deployment", toolchainStatus.Status.RegistrationService.Deployment.Conditions)
+		add("registrationService.health", toolchainStatus.Status.RegistrationService.Health.Conditions)
+	}
+	add("hostRoutes", toolchainStatus.Status.HostRoutes.Conditions)
+	for _, member := range toolchainStatus.Status.Members {
+		add("member."+member.ClusterName, member.MemberStatus.Conditions)
+	}
+	for _, c := range toolchainStatus.Status.Conditions {
+		if name, found := strings.CutPrefix(string(c.Type), toolchainstatus.HealthCheckConditionTypePrefix); found {
+			result = append(result, Component{Name: "healthCheck." + name, Ready: c.Status == corev1.ConditionTrue, Reason: c.Reason, Message: c.Message})
+		}
+	}
+	return result
+}
+
+// clusters returns the space counts and capacity of the clusters which have a SpaceProvisionerConfig
+func clusters(ctx context.Context, cl runtimeclient.Client, namespace string, toolchainStatus *toolchainv1alpha1.ToolchainStatus) ([]Cluster, error) {
+	spcs := &toolchainv1alpha1.SpaceProvisionerConfigList{}
+	if err := cl.List(ctx, spcs, runtimeclient.InNamespace(namespace)); err != nil {
+		return nil, errs.Wrap(err, "unable to list the SpaceProvisionerConfigs")
+	}
+	// the counter cache is more up-to-date than the ToolchainStatus, if it's initialized
+	spaceCounts, err := counter.GetSpaceCountPerClusterSnapshot()
+	if err != nil {
+		spaceCounts = map[string]int{}
+		for _, member := range toolchainStatus.Status.Members {
+			spaceCounts[member.ClusterName] = member.SpaceCount
+		}
+	}
+
+	result := []Cluster{}
+	for _, spc := range spcs.Items {
+		c := Cluster{
+			Name:                        spc.Spec.ToolchainCluster,
+			Ready:                       condition.IsTrue(spc.Status.Conditions, toolchainv1alpha1.ConditionReady),
+			SpaceCount:                  spaceCounts[spc.Spec.ToolchainCluster],
+			MaxNumberOfSpaces:           int(spc.Spec.CapacityThresholds.MaxNumberOfSpaces),           // nolint:gosec
+			MaxMemoryUtilizationPercent: int(spc.Spec.CapacityThresholds.MaxMemoryUtilizationPercent), // nolint:gosec
+		}
+		for _, member := range toolchainStatus.Status.Members {
+			if member.ClusterName == c.Name {
+				c.MemoryUsagePerNodeRole = member.MemberStatus.ResourceUsage.MemoryUsagePerNodeRole
+			}
+		}
+		result = append(result, c)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Name < result[j].Name
+	})
+	return result, nil
+}
+
+// userSignupActivity returns the number of UserSignups pending approval, and of the UserSignups approved or deactivated recently
+func userSignupActivity(userSignups []toolchainv1alpha1.UserSignup, now time.Time) (int, Recent) {
+	pending := 0
+	recent := Recent{Window: RecentWindow.String()}
+	since := now.Add(-RecentWindow)
+	for _, userSignup := range userSignups {
+		if userSignup.Labels[toolchainv1alpha1.UserSignupStateLabelKey] == toolchainv1alpha1.UserSignupStateLabelValuePending {
+			pending++
+		}
+		if c, found := condition.FindConditionByType(userSignup.Status.Conditions, toolchainv1alpha1.UserSignupApproved); found &&
+			c.Status == corev1.ConditionTrue && c.LastTransitionTime.After(since) {
+			recent.Approvals++
+		}
+		if c, found := condition.FindConditionByType(userSignup.Status.Conditions, toolchainv1alpha1.UserSignupComplete); found &&
+			c.Reason == toolchainv1alpha1.UserSignupUserDeactivatedReason && c.LastTransitionTime.After(since) {
+			recent.Deactivations++
+		}
+	}
+	return pending, recent
+}
+
+// configHash returns the SHA-256 hash of the spec and annotations of the ToolchainConfig, or an empty string if there is no ToolchainConfig
+func configHash(ctx context.Context, cl runtimeclient.Client, namespace string) (string, error) {
+	config := &toolchainv1alpha1.ToolchainConfig{}
+	if err := cl.Get(ctx, types.NamespacedName{Namespace: namespace, Name: "config"}, config); err != nil {
+		if errors.IsNotFound(err) {
+			return "", nil
+		}
+		return "", errs.Wrap(err, "unable to get the ToolchainConfig")
+	}
+	data, err := json.Marshal(struct {
+		Spec        toolchainv1alpha1.ToolchainConfigSpec `json:"spec"`
+		Annotations map[string]string                     `json:"annotations,omitempty"`
+	}{
+		Spec:        config.Spec,
+		Annotations: config.Annotations,
+	})
+	if err != nil {
+		return "", errs.Wrap(err, "unable to marshal the ToolchainConfig")
+	}
+	hash := sha256.Sum256(data)
+	return hex.EncodeToString(hash[:]), nil
+}
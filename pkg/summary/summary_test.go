@@ -0,0 +1,137 @@
+package summary
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	toolchainv1alpha1 "github.com/codeready-toolchain/api/api/v1alpha1"
+	"github.com/codeready-toolchain/host-operator/controllers/toolchainstatus"
+	"github.com/codeready-toolchain/host-operator/pkg/apis"
+	. "github.com/codeready-toolchain/host-operator/test"
+	tspc "github.com/codeready-toolchain/host-operator/test/spaceprovisionerconfig"
+	"github.com/codeready-toolchain/toolchain-common/pkg/test"
+	. "github.com/codeready-toolchain/toolchain-common/pkg/test/spaceprovisionerconfig"
+	commonsignup "github.com/codeready-toolchain/toolchain-common/pkg/test/usersignup"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/scheme"
+	runtimeclient "sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func TestBuild(t *testing.T) {
+	// given
+	require.NoError(t, apis.AddToScheme(scheme.Scheme))
+	toolchainStatus := NewToolchainStatus(
+		WithHost(func(host *toolchainv1alpha1.HostOperatorStatus) {
+			host.Conditions = []toolchainv1alpha1.Condition{ToBeReady()}
+		}),
+		WithRegistrationService(WithDeploymentCondition(ToBeReady()), WithHealthCondition(ToBeNotReady())),
+		WithMember("member-1", WithSpaceCount(8), WithNodeRoleUsage("worker", 70)),
+		WithMember("member-2", WithSpaceCount(3)),
+	)
+	toolchainStatus.Status.Conditions = []toolchainv1alpha1.Condition{
+		ToBeNotReady(),
+		{Type: toolchainv1alpha1.ConditionType(toolchainstatus.HealthCheckConditionTypePrefix + "sso"), Status: corev1.ConditionFalse,
+			Reason: toolchainstatus.ToolchainStatusHealthCheckFailedReason, Message: "connection refused"},
+	}
+	objs := []runtimeclient.Object{
+		toolchainStatus,
+		tspc.NewEnabledValidTenantSPC("member-2", MaxNumberOfSpaces(100), MaxMemoryUtilizationPercent(80)),
+		tspc.NewEnabledValidTenantSPC("member-1", MaxNumberOfSpaces(10)),
+		commonsignup.NewUserSignup(commonsignup.WithName("pending"), commonsignup.WithStateLabel(toolchainv1alpha1.UserSignupStateLabelValuePending)),
+		commonsignup.NewUserSignup(commonsignup.WithName("approved-recently"), commonsignup.ApprovedAutomaticallyAgo(time.Hour)),
+		commonsignup.NewUserSignup(commonsignup.WithName("approved-long-ago"), commonsignup.ApprovedAutomaticallyAgo(48*time.Hour)),
+		commonsignup.NewUserSignup(commonsignup.WithName("deactivated-recently"), commonsignup.ApprovedAutomaticallyAgo(72*time.Hour),
+			commonsignup.DeactivatedAgo(2*time.Hour)),
+		&toolchainv1alpha1.ToolchainConfig{
+			ObjectMeta: metav1.ObjectMeta{Name: "config", Namespace: test.HostOperatorNs},
+		},
+	}
+
+	t.Run("with the space counts of the ToolchainStatus", func(t *testing.T) {
+		// given
+		cl := test.NewFakeClient(t, objs...)
+
+		// when
+		summary, err := Build(context.TODO(), cl, test.HostOperatorNs)
+
+		// then
+		require.NoError(t, err)
+		assert.Equal(t, SchemaVersion, summary.SchemaVersion)
+		assert.False(t, summary.Ready)
+		assert.Equal(t, []Component{
+			{Name: "hostOperator", Ready: true},
+			{Name: "registrationService.deployment", Ready: true},
+			{Name: "registrationService.health", Ready: false},
+			{Name: "healthCheck.sso", Ready: false, Reason: toolchainstatus.ToolchainStatusHealthCheckFailedReason, Message: "connection refused"},
+		}, summary.Components)
+		assert.Equal(t, []Cluster{
+			{Name: "member-1", Ready: true, SpaceCount: 8, MaxNumberOfSpaces: 10, MemoryUsagePerNodeRole: map[string]int{"worker": 70}},
+			{Name: "member-2", Ready: true, SpaceCount: 3, MaxNumberOfSpaces: 100, MaxMemoryUtilizationPercent: 80},
+		}, summary.Clusters)
+		assert.Equal(t, 1, summary.PendingApprovals)
+		assert.Equal(t, Recent{Window: "24h0m0s", Approvals: 1, Deactivations: 1}, summary.Recent)
+		assert.Len(t, summary.ConfigHash, 64)
+	})
+
+	t.Run("with the space counts of the counter cache", func(t *testing.T) {
+		// given
+		InitializeCountersWith(t, ClusterCount("member-1", 9), ClusterCount("member-2", 4))
+		cl := test.NewFakeClient(t, objs...)
+
+		// when
+		summary, err := Build(context.TODO(), cl, test.HostOperatorNs)
+
+		// then
+		require.NoError(t, err)
+		require.Len(t, summary.Clusters, 2)
+		assert.Equal(t, 9, summary.Clusters[0].SpaceCount)
+		assert.Equal(t, 4, summary.Clusters[1].SpaceCount)
+	})
+
+	t.Run("config hash changes with the config", func(t *testing.T) {
+		// given
+		cl := test.NewFakeClient(t, objs...)
+		before, err := Build(context.TODO(), cl, test.HostOperatorNs)
+		require.NoError(t, err)
+		config := &toolchainv1alpha1.ToolchainConfig{}
+		require.NoError(t, cl.Get(context.TODO(), test.NamespacedName(test.HostOperatorNs, "config"), config))
+		config.Annotations = map[string]string{toolchainv1alpha1.LabelKeyPrefix + "foo": "bar"}
+		require.NoError(t, cl.Update(context.TODO(), config))
+
+		// when
+		after, err := Build(context.TODO(), cl, test.HostOperatorNs)
+
+		// then
+		require.NoError(t, err)
+		assert.NotEqual(t, before.ConfigHash, after.ConfigHash)
+	})
+
+	t.Run("no config", func(t *testing.T) {
+		// given
+		cl := test.NewFakeClient(t, toolchainStatus)
+
+		// when
+		summary, err := Build(context.TODO(), cl, test.HostOperatorNs)
+
+		// then
+		require.NoError(t, err)
+		assert.Empty(t, summary.ConfigHash)
+		assert.Empty(t, summary.Clusters)
+	})
+
+	t.Run("no ToolchainStatus", func(t *testing.T) {
+		// given
+		cl := test.NewFakeClient(t)
+
+		// when
+		_, err := Build(context.TODO(), cl, test.HostOperatorNs)
+
+		// then
+		require.EqualError(t, err, `unable to get the ToolchainStatus: toolchainstatuses.toolchain.dev.openshift.com "toolchain-status" not found`)
+	})
+}
@@ -4,7 +4,10 @@ import (
 	"embed"
 	"fmt"
 	"io/fs"
+	"sort"
 	"strings"
+	"sync"
+	texttemplate "text/template"
 
 	"github.com/codeready-toolchain/host-operator/deploy"
 	"github.com/pkg/errors"
@@ -18,76 +21,304 @@ const (
 	UserDeactivatingTemplateName = "userdeactivating"
 	IdlerTriggeredTemplateName   = "idlertriggered"
 	rootDirectory                = "templates/notificationtemplates"
+
+	// PartialsDirectory is the directory of a template set which contains the partials shared by all the templates of the set,
+	// ie, the files which define named templates with `{{define "name"}}...{{end}}` to be used in the templates with `{{template "name" .}}`
+	PartialsDirectory = "partials"
 )
 
-var notificationTemplates map[string]NotificationTemplate
+var (
+	// notificationTemplates caches the embedded templates, per template set
+	notificationTemplates = map[string]map[string]NotificationTemplate{}
+	// notificationPartials caches the partials of the embedded templates, per template set
+	notificationPartials = map[string]string{}
+	// templateOverrides contains the templates which override the embedded ones, per template set
+	templateOverrides = map[string]map[string]NotificationTemplate{}
+	mu                sync.RWMutex
+)
 
 // NotificationTemplate contains the template subject and content
 type NotificationTemplate struct {
 	Subject string
 	Content string
-	Name    string
+	// TextContent is the optional plain text alternative of the (HTML) content
+	TextContent string
+	// Partials contains the definitions of the named templates which can be used in the subject and in the contents
+	Partials string
+	Name     string
 }
 
 // GetNotificationTemplate returns a NotificationTemplate with the given name for the specified templateSetName. An error will be returned if such a template is not found or there is an error in loading templates.
 // This function expects the templates to be organized under rootDirectory as rootDirectory/templateSetName/templateName/notification.html and rootDirectory/templateSetName/templateName/subject.txt
-// making the function dependent on the path length.
+// (and optionally rootDirectory/templateSetName/templateName/notification.txt for the plain text content) making the function dependent on the path length.
+// The partials in rootDirectory/templateSetName/PartialsDirectory are added to the returned template.
+// The embedded template is overlaid with the override set via SetTemplateOverrides (if any).
 func GetNotificationTemplate(name string, templateSetName string) (*NotificationTemplate, error) {
 	templates, err := loadTemplates(templateSetName)
-	if err != nil {
+	if err != nil && !hasTemplateOverrides(templateSetName) {
 		return nil, errors.Wrap(err, "unable to get notification templates")
 	}
-	template, found := templates[name]
+	template, found := overlay(templates[name], getTemplateOverride(name, templateSetName))
 	if !found {
 		return &template, fmt.Errorf("notification template %v not found in %v", name, templateSetName)
 	}
+	template.Partials = getPartials(templateSetName)
 	return &template, nil
 }
 
-func templatesForAssets(notificationFS embed.FS, root string, setName string) (map[string]NotificationTemplate, error) {
+// GetLocalizedNotificationTemplate returns the variant of the NotificationTemplate with the given name for the given locale (eg. `fr` or `pt-BR`).
+// The variants are organized next to the default template, as rootDirectory/templateSetName/templateName/notification.<locale>.html and
+// rootDirectory/templateSetName/templateName/subject.<locale>.txt, with the locale in lower case.
+// If there is no variant for the locale, then the variant for its language (eg. `pt`) is used, and then the default template.
+// A variant may contain only a subject or only a content, in which case the other part is taken from the default template.
+func GetLocalizedNotificationTemplate(name, templateSetName, locale string) (*NotificationTemplate, error) {
+	template, err := GetNotificationTemplate(name, templateSetName)
+	if err != nil {
+		return template, err
+	}
+	templates, _ := loadTemplates(templateSetName)
+	for _, candidate := range localeCandidates(locale) {
+		localizedName := LocalizedTemplateName(name, candidate)
+		if localized, found := overlay(templates[localizedName], getTemplateOverride(localizedName, templateSetName)); found {
+			result, _ := overlay(*template, localized)
+			result.Name = name
+			return &result, nil
+		}
+	}
+	return template, nil
+}
+
+// LocalizedTemplateName returns the name under which the variant of the given template for the given locale is stored (eg. `userprovisioned.fr`).
+// This is also the name to use when overriding a localized template.
+func LocalizedTemplateName(name, locale string) string {
+	return name + "." + locale
+}
+
+// localeCandidates returns the normalized locale and, if the locale contains a region, its language.
+// Eg: `pt_BR` returns `pt-br` and `pt`.
+func localeCandidates(locale string) []string {
+	locale = strings.ToLower(strings.ReplaceAll(strings.TrimSpace(locale), "_", "-"))
+	if locale == "" {
+		return nil
+	}
+	candidates := []string{locale}
+	if language, _, found := strings.Cut(locale, "-"); found && language != "" {
+		candidates = append(candidates, language)
+	}
+	return candidates
+}
+
+// SetTemplateOverrides activates the given templates for the given template set, in place of the embedded templates with the same name.
+// The templates are validated first: if any of them doesn't parse, then an error is returned and the previous overrides remain active.
+// An override may contain only a subject or only a content, in which case the other part is taken from the embedded template.
+// The localized templates are overridden with the names returned by LocalizedTemplateName.
+func SetTemplateOverrides(templateSetName string, templates map[string]NotificationTemplate) error {
+	defaults, _ := loadTemplates(templateSetName)
+	for name, template := range templates {
+		merged := defaults[name]
+		if base, _, localized := strings.Cut(name, "."); localized {
+			// a localized template falls back to the default template
+			merged, _ = overlay(defaults[base], templates[base])
+			merged, _ = overlay(merged, defaults[name])
+		}
+		merged, _ = overlay(merged, template)
+		merged.Name = name
+		merged.Partials = getPartials(templateSetName)
+		if err := ValidateNotificationTemplate(merged); err != nil {
+			return err
+		}
+	}
+	overrides := make(map[string]NotificationTemplate, len(templates))
+	for name, template := range templates {
+		template.Name = name
+		overrides[name] = template
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	templateOverrides[templateSetName] = overrides
+	return nil
+}
+
+// ClearTemplateOverrides removes the overrides of the given template set, so that only the embedded templates are used
+func ClearTemplateOverrides(templateSetName string) {
+	mu.Lock()
+	defer mu.Unlock()
+	delete(templateOverrides, templateSetName)
+}
+
+// TemplateOverrideSetNames returns the names of the template sets which have overrides
+func TemplateOverrideSetNames() []string {
+	mu.RLock()
+	defer mu.RUnlock()
+	names := make([]string, 0, len(templateOverrides))
+	for name := range templateOverrides {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ValidateNotificationTemplate returns an error if the subject or the content of the given template is empty or if any part of the template
+// can't be parsed
+func ValidateNotificationTemplate(template NotificationTemplate) error {
+	if template.Subject == "" || template.Content == "" {
+		return fmt.Errorf("notification template %v must have a subject and a content", template.Name)
+	}
+	partials, err := texttemplate.New("partials").Parse(template.Partials)
+	if err != nil {
+		return errors.Wrapf(err, "invalid partials of notification template %v", template.Name)
+	}
+	for part, definition := range map[string]string{"subject": template.Subject, "content": template.Content, "text content": template.TextContent} {
+		p, err := partials.Clone()
+		if err != nil {
+			return err
+		}
+		if _, err := p.New(part).Parse(definition); err != nil {
+			return errors.Wrapf(err, "invalid %s of notification template %v", part, template.Name)
+		}
+	}
+	return nil
+}
+
+func hasTemplateOverrides(templateSetName string) bool {
+	mu.RLock()
+	defer mu.RUnlock()
+	_, found := templateOverrides[templateSetName]
+	return found
+}
+
+func getTemplateOverride(name, templateSetName string) NotificationTemplate {
+	mu.RLock()
+	defer mu.RUnlock()
+	return templateOverrides[templateSetName][name]
+}
+
+// overlay returns the given template with its subject and/or content replaced by the ones of the override, if set.
+// Returns false if neither of the templates exists.
+func overlay(template, override NotificationTemplate) (NotificationTemplate, bool) {
+	if override.Name != "" {
+		template.Name = override.Name
+	}
+	if override.Subject != "" {
+		template.Subject = override.Subject
+	}
+	if override.Content != "" {
+		template.Content = override.Content
+	}
+	if override.TextContent != "" {
+		template.TextContent = override.TextContent
+	}
+	return template, template.Name != ""
+}
+
+// templatesForAssets returns the templates of the given set, along with the partials shared by these templates
+func templatesForAssets(notificationFS embed.FS, root string, setName string) (map[string]NotificationTemplate, string, error) {
 	paths, err := getAllFilenames(&notificationFS, root, setName)
 	if err != nil {
-		return nil, err
+		return nil, "", err
 	}
 	if len(paths) == 0 {
-		return nil, fmt.Errorf("could not find any emails templates for the environment %v", setName)
+		return nil, "", fmt.Errorf("could not find any emails templates for the environment %v", setName)
 	}
-	notificationTemplates = make(map[string]NotificationTemplate)
+	notificationTemplates := make(map[string]NotificationTemplate)
+	var partials strings.Builder
 	for _, path := range paths {
 		content, err := notificationFS.ReadFile(path)
 		if err != nil {
-			return nil, err
+			return nil, "", err
 		}
 		segments := strings.Split(path, "/")
 		if len(segments) != 5 {
-			return nil, errors.Wrapf(errors.New("path must contain directory and file"), "unable to load templates")
+			return nil, "", errors.Wrapf(errors.New("path must contain directory and file"), "unable to load templates")
 		}
 		directoryName := segments[3]
 		filename := segments[4]
 
-		template := notificationTemplates[directoryName]
-		template.Name = directoryName
-		switch filename {
-		case "notification.html":
+		if directoryName == PartialsDirectory {
+			partials.Write(content)
+			partials.WriteString("\n")
+			continue
+		}
+		part, locale, ok := parseTemplateFilename(filename)
+		if !ok {
+			return nil, "", errors.Wrapf(errors.New("must contain notification.html and subject.txt"), "unable to load templates")
+		}
+		name := directoryName
+		if locale != "" {
+			name = LocalizedTemplateName(directoryName, locale)
+		}
+		template := notificationTemplates[name]
+		template.Name = name
+		switch part {
+		case contentPart:
 			template.Content = string(content)
-			notificationTemplates[directoryName] = template
-		case "subject.txt":
+		case textContentPart:
+			template.TextContent = string(content)
+		case subjectPart:
 			template.Subject = string(content)
-			notificationTemplates[directoryName] = template
-		default:
-			return nil, errors.Wrapf(errors.New("must contain notification.html and subject.txt"), "unable to load templates")
 		}
+		notificationTemplates[name] = template
 	}
 
-	return notificationTemplates, nil
+	return notificationTemplates, partials.String(), nil
+}
+
+const (
+	contentPart     = "content"
+	textContentPart = "text content"
+	subjectPart     = "subject"
+)
+
+// templateFiles maps the name (without the locale) and the extension of the template files to the part of the template they contain
+var templateFiles = []struct {
+	name, extension, part string
+}{
+	{name: "notification", extension: ".html", part: contentPart},
+	{name: "notification", extension: ".txt", part: textContentPart},
+	{name: "subject", extension: ".txt", part: subjectPart},
+}
+
+// parseTemplateFilename returns the part of the template contained in the file with the given name,
+// along with the locale of the file, if any (eg. `fr` for `notification.fr.html`)
+func parseTemplateFilename(filename string) (string, string, bool) {
+	for _, f := range templateFiles {
+		if filename == f.name+f.extension {
+			return f.part, "", true
+		}
+		if strings.HasPrefix(filename, f.name+".") && strings.HasSuffix(filename, f.extension) {
+			locale := strings.TrimSuffix(strings.TrimPrefix(filename, f.name+"."), f.extension)
+			if locale != "" && !strings.Contains(locale, ".") {
+				return f.part, strings.ToLower(locale), true
+			}
+		}
+	}
+	return "", "", false
 }
 
 func loadTemplates(setName string) (map[string]NotificationTemplate, error) {
-	if notificationTemplates != nil {
-		return notificationTemplates, nil
+	mu.RLock()
+	templates, found := notificationTemplates[setName]
+	mu.RUnlock()
+	if found {
+		return templates, nil
+	}
+
+	templates, partials, err := templatesForAssets(deploy.NotificationTemplateFS, rootDirectory, setName)
+	if err != nil {
+		return nil, err
 	}
+	mu.Lock()
+	defer mu.Unlock()
+	notificationTemplates[setName] = templates
+	notificationPartials[setName] = partials
+	return templates, nil
+}
 
-	return templatesForAssets(deploy.NotificationTemplateFS, rootDirectory, setName)
+// getPartials returns the partials of the embedded templates of the given set
+func getPartials(setName string) string {
+	mu.RLock()
+	defer mu.RUnlock()
+	return notificationPartials[setName]
 }
 
 func getAllFilenames(notificationFS *embed.FS, root string, setName string) (files []string, err error) {
@@ -1,7 +1,9 @@
 package notificationtemplates
 
 import (
+	"strings"
 	"testing"
+	texttemplate "text/template"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -155,7 +157,7 @@ func TestTemplatesForAssets(t *testing.T) {
 			// given
 			defer resetNotificationTemplateCache()
 			// when
-			template, err := templatesForAssets(fakeTemplates, "testTemplates", "alpha")
+			template, _, err := templatesForAssets(fakeTemplates, "testTemplates", "alpha")
 
 			// then
 			require.Error(t, err)
@@ -166,7 +168,7 @@ func TestTemplatesForAssets(t *testing.T) {
 			// given
 			defer resetNotificationTemplateCache()
 			// when
-			template, err := templatesForAssets(fakeTemplates, "testTemplates", SandboxTemplateSetName)
+			template, _, err := templatesForAssets(fakeTemplates, "testTemplates", SandboxTemplateSetName)
 			// then
 			require.Error(t, err)
 			assert.Nil(t, template)
@@ -176,7 +178,7 @@ func TestTemplatesForAssets(t *testing.T) {
 			// given
 			defer resetNotificationTemplateCache()
 			// when
-			template, err := templatesForAssets(fakeTemplates, "testTemplates", AppstudioTemplateSetName)
+			template, _, err := templatesForAssets(fakeTemplates, "testTemplates", AppstudioTemplateSetName)
 			// then
 			require.Error(t, err)
 			assert.Nil(t, template)
@@ -185,6 +187,247 @@ func TestTemplatesForAssets(t *testing.T) {
 	})
 }
 
+func TestTemplateSetsAreCachedSeparately(t *testing.T) {
+	// given
+	defer resetNotificationTemplateCache()
+	_, err := GetNotificationTemplate(UserProvisionedTemplateName, SandboxTemplateSetName)
+	require.NoError(t, err)
+
+	// when
+	template, err := GetNotificationTemplate(UserProvisionedTemplateName, AppstudioTemplateSetName)
+
+	// then
+	require.NoError(t, err)
+	assert.Equal(t, "Welcome to Red Hat Trusted Application Pipeline!", template.Subject)
+	template, err = GetNotificationTemplate(UserProvisionedTemplateName, SandboxTemplateSetName)
+	require.NoError(t, err)
+	assert.Equal(t, "Notice: Your Developer Sandbox account is ready", template.Subject)
+}
+
+func TestTemplateOverrides(t *testing.T) {
+
+	t.Run("override subject only", func(t *testing.T) {
+		// given
+		defer resetNotificationTemplateCache()
+
+		// when
+		err := SetTemplateOverrides(SandboxTemplateSetName, map[string]NotificationTemplate{
+			UserProvisionedTemplateName: {Subject: "Your sandbox is ready, {{.FirstName}}"},
+		})
+
+		// then
+		require.NoError(t, err)
+		template, err := GetNotificationTemplate(UserProvisionedTemplateName, SandboxTemplateSetName)
+		require.NoError(t, err)
+		assert.Equal(t, "Your sandbox is ready, {{.FirstName}}", template.Subject)
+		assert.Contains(t, template.Content, "is now ready to use. Your account will be active for")
+		assert.Equal(t, UserProvisionedTemplateName, template.Name)
+		// the other set is not affected
+		template, err = GetNotificationTemplate(UserProvisionedTemplateName, AppstudioTemplateSetName)
+		require.NoError(t, err)
+		assert.Equal(t, "Welcome to Red Hat Trusted Application Pipeline!", template.Subject)
+		assert.Equal(t, []string{SandboxTemplateSetName}, TemplateOverrideSetNames())
+	})
+
+	t.Run("new template", func(t *testing.T) {
+		// given
+		defer resetNotificationTemplateCache()
+
+		// when
+		err := SetTemplateOverrides(SandboxTemplateSetName, map[string]NotificationTemplate{
+			"maintenance": {Subject: "Planned maintenance", Content: "<p>Hi {{.FirstName}}</p>"},
+		})
+
+		// then
+		require.NoError(t, err)
+		template, err := GetNotificationTemplate("maintenance", SandboxTemplateSetName)
+		require.NoError(t, err)
+		assert.Equal(t, "maintenance", template.Name)
+		assert.Equal(t, "Planned maintenance", template.Subject)
+		assert.Equal(t, "<p>Hi {{.FirstName}}</p>", template.Content)
+		assert.Contains(t, template.Partials, `{{define "header"}}`) // the partials of the set are available to the new templates
+	})
+
+	t.Run("new template set", func(t *testing.T) {
+		// given
+		defer resetNotificationTemplateCache()
+
+		// when
+		err := SetTemplateOverrides("konflux", map[string]NotificationTemplate{
+			UserProvisionedTemplateName: {Subject: "Welcome", Content: "<p>Hi</p>"},
+		})
+
+		// then
+		require.NoError(t, err)
+		template, err := GetNotificationTemplate(UserProvisionedTemplateName, "konflux")
+		require.NoError(t, err)
+		assert.Equal(t, "Welcome", template.Subject)
+	})
+
+	t.Run("invalid overrides are not activated", func(t *testing.T) {
+		// given
+		defer resetNotificationTemplateCache()
+		err := SetTemplateOverrides(SandboxTemplateSetName, map[string]NotificationTemplate{
+			UserProvisionedTemplateName: {Subject: "valid"},
+		})
+		require.NoError(t, err)
+
+		for name, override := range map[string]NotificationTemplate{
+			"invalid subject":           {Subject: "{{.FirstName"},
+			"invalid content":           {Content: "{{if}}"},
+			"new template without body": {Subject: "only a subject"},
+		} {
+			t.Run(name, func(t *testing.T) {
+				// when
+				templateName := UserProvisionedTemplateName
+				if name == "new template without body" {
+					templateName = "maintenance"
+				}
+				err := SetTemplateOverrides(SandboxTemplateSetName, map[string]NotificationTemplate{
+					UserDeactivatedTemplateName: {Subject: "also valid"},
+					templateName:                override,
+				})
+
+				// then
+				require.Error(t, err)
+				template, err := GetNotificationTemplate(UserProvisionedTemplateName, SandboxTemplateSetName)
+				require.NoError(t, err)
+				assert.Equal(t, "valid", template.Subject)
+				template, err = GetNotificationTemplate(UserDeactivatedTemplateName, SandboxTemplateSetName)
+				require.NoError(t, err)
+				assert.Equal(t, "Notice: Your Developer Sandbox account is deactivated", template.Subject)
+			})
+		}
+	})
+
+	t.Run("clear overrides", func(t *testing.T) {
+		// given
+		defer resetNotificationTemplateCache()
+		err := SetTemplateOverrides(SandboxTemplateSetName, map[string]NotificationTemplate{
+			UserProvisionedTemplateName: {Subject: "overridden"},
+		})
+		require.NoError(t, err)
+
+		// when
+		ClearTemplateOverrides(SandboxTemplateSetName)
+
+		// then
+		template, err := GetNotificationTemplate(UserProvisionedTemplateName, SandboxTemplateSetName)
+		require.NoError(t, err)
+		assert.Equal(t, "Notice: Your Developer Sandbox account is ready", template.Subject)
+		assert.Empty(t, TemplateOverrideSetNames())
+	})
+}
+
 func resetNotificationTemplateCache() {
-	notificationTemplates = nil
+	mu.Lock()
+	defer mu.Unlock()
+	notificationTemplates = map[string]map[string]NotificationTemplate{}
+	notificationPartials = map[string]string{}
+	templateOverrides = map[string]map[string]NotificationTemplate{}
+}
+
+func TestLocalizedTemplates(t *testing.T) {
+
+	t.Run("load localized templates from assets", func(t *testing.T) {
+		// when
+		templates, partials, err := templatesForAssets(fakeTemplates, "testTemplates", "localized")
+
+		// then
+		require.NoError(t, err)
+		assert.Equal(t, "{{define \"signature\"}}The team{{end}}\n", partials)
+		assert.Equal(t, map[string]NotificationTemplate{
+			"userprovisioned":       {Name: "userprovisioned", Subject: "Welcome", Content: "<p>Hello</p>", TextContent: "Hello\n{{template \"signature\"}}"},
+			"userprovisioned.fr":    {Name: "userprovisioned.fr", Content: "<p>Bonjour</p>"},
+			"userprovisioned.pt-br": {Name: "userprovisioned.pt-br", Subject: "Bem-vindo", Content: "<p>Olá</p>"},
+		}, templates)
+	})
+
+	t.Run("select localized template", func(t *testing.T) {
+		// given
+		defer resetNotificationTemplateCache()
+		err := SetTemplateOverrides(SandboxTemplateSetName, map[string]NotificationTemplate{
+			LocalizedTemplateName(UserProvisionedTemplateName, "fr"):    {Content: "<p>Bonjour {{.FirstName}}</p>"},
+			LocalizedTemplateName(UserProvisionedTemplateName, "pt-br"): {Subject: "Bem-vindo", Content: "<p>Olá {{.FirstName}}</p>"},
+		})
+		require.NoError(t, err)
+		defaultTemplate, err := GetNotificationTemplate(UserProvisionedTemplateName, SandboxTemplateSetName)
+		require.NoError(t, err)
+
+		for locale, expected := range map[string]NotificationTemplate{
+			"fr":    {Subject: defaultTemplate.Subject, Content: "<p>Bonjour {{.FirstName}}</p>"},
+			"fr-CA": {Subject: defaultTemplate.Subject, Content: "<p>Bonjour {{.FirstName}}</p>"},
+			"pt_BR": {Subject: "Bem-vindo", Content: "<p>Olá {{.FirstName}}</p>"},
+			"pt":    {Subject: defaultTemplate.Subject, Content: defaultTemplate.Content},
+			"de":    {Subject: defaultTemplate.Subject, Content: defaultTemplate.Content},
+			"":      {Subject: defaultTemplate.Subject, Content: defaultTemplate.Content},
+		} {
+			t.Run(locale, func(t *testing.T) {
+				// when
+				template, err := GetLocalizedNotificationTemplate(UserProvisionedTemplateName, SandboxTemplateSetName, locale)
+
+				// then
+				require.NoError(t, err)
+				expected.Name = UserProvisionedTemplateName
+				expected.Partials = defaultTemplate.Partials
+				assert.Equal(t, expected, *template)
+			})
+		}
+	})
+
+	t.Run("unknown template", func(t *testing.T) {
+		// given
+		defer resetNotificationTemplateCache()
+
+		// when
+		_, err := GetLocalizedNotificationTemplate("unknown", SandboxTemplateSetName, "fr")
+
+		// then
+		require.EqualError(t, err, "notification template unknown not found in sandbox")
+	})
+}
+
+func TestPartials(t *testing.T) {
+
+	for _, setName := range []string{SandboxTemplateSetName, AppstudioTemplateSetName} {
+		for _, name := range []string{UserProvisionedTemplateName, UserDeactivatingTemplateName, UserDeactivatedTemplateName, IdlerTriggeredTemplateName} {
+			t.Run(setName+"/"+name, func(t *testing.T) {
+				// given
+				defer resetNotificationTemplateCache()
+				template, err := GetNotificationTemplate(name, setName)
+				require.NoError(t, err)
+				require.NoError(t, ValidateNotificationTemplate(*template))
+				tmpl, err := texttemplate.New("partials").Parse(template.Partials)
+				require.NoError(t, err)
+				tmpl, err = tmpl.New(name).Parse(template.Content)
+				require.NoError(t, err)
+
+				// when
+				var buf strings.Builder
+				err = tmpl.Execute(&buf, map[string]string{})
+
+				// then
+				require.NoError(t, err)
+				assert.True(t, strings.HasPrefix(buf.String(), "<!DOCTYPE html>"))
+				assert.Regexp(t, `(?s)<title>\s*\S.*</title>`, buf.String())
+				assert.True(t, strings.HasSuffix(strings.TrimSpace(buf.String()), "</html>"))
+			})
+		}
+	}
+
+	t.Run("invalid partials", func(t *testing.T) {
+		// when
+		err := ValidateNotificationTemplate(NotificationTemplate{Name: "welcome", Subject: "Welcome", Content: "<p>Hi</p>", Partials: `{{define "header"}}`})
+
+		// then
+		require.EqualError(t, err, "invalid partials of notification template welcome: template: partials:1: unexpected EOF")
+	})
+
+	t.Run("invalid text content", func(t *testing.T) {
+		// when
+		err := ValidateNotificationTemplate(NotificationTemplate{Name: "welcome", Subject: "Welcome", Content: "<p>Hi</p>", TextContent: "{{.FirstName"})
+
+		// then
+		require.ErrorContains(t, err, "invalid text content of notification template welcome")
+	})
 }
@@ -0,0 +1,27 @@
+package config
+
+import (
+	toolchainv1alpha1 "github.com/codeready-toolchain/api/api/v1alpha1"
+	testconfig "github.com/codeready-toolchain/toolchain-common/pkg/test/config"
+)
+
+type annotationOption struct {
+	key   string
+	value string
+}
+
+func (o annotationOption) Apply(config *toolchainv1alpha1.ToolchainConfig) {
+	if config.Annotations == nil {
+		config.Annotations = map[string]string{}
+	}
+	config.Annotations[o.key] = o.value
+}
+
+// Annotation returns an option which sets the given annotation on the ToolchainConfig.
+// Used to configure the settings which are not (yet) part of the ToolchainConfig spec.
+func Annotation(key, value string) testconfig.ToolchainConfigOption {
+	return annotationOption{
+		key:   key,
+		value: value,
+	}
+}
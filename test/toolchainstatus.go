@@ -94,6 +94,12 @@ func WithSpaceCount(count int) MemberToolchainStatusOption {
 	}
 }
 
+func WithMemberStatusCondition(condition toolchainv1alpha1.Condition) MemberToolchainStatusOption {
+	return func(status *toolchainv1alpha1.Member) {
+		status.MemberStatus.Conditions = append(status.MemberStatus.Conditions, condition)
+	}
+}
+
 func WithNodeRoleUsage(role string, usage int) MemberToolchainStatusOption {
 	return func(status *toolchainv1alpha1.Member) {
 		if status.MemberStatus.ResourceUsage.MemoryUsagePerNodeRole == nil {